@@ -0,0 +1,481 @@
+package dbsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/ipc"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	dbsqlrows "github.com/databricks/databricks-sql-go/rows"
+	"github.com/pierrec/lz4/v4"
+	"github.com/pkg/errors"
+)
+
+var errArrowRowsNoSchema = "databricks: no arrow schema in result set metadata response"
+var errArrowRowsInvalidBatch = "databricks: unable to read arrow record batch"
+var errArrowRowsUnsupportedType = "databricks: unsupported arrow type %s in column %s"
+var errArrowRowsNotArrowResults = "databricks: results are not in Arrow format, Arrow batches must be enabled"
+var errArrowRowsInvalidRowIndex = "databricks: row index %d is not in the arrow result page"
+
+// arrowPage holds the decoded Arrow record batches of one result page.
+// Each batch of a TRowSet is an Arrow IPC record batch message, serialized without
+// the schema which is sent once in the result set metadata.
+type arrowPage struct {
+	rowSet  *cli_service.TRowSet
+	records []arrow.Record
+	// starting row index within the page of each record
+	offsets []int64
+	nRows   int64
+}
+
+// isArrowRowSet returns true if the row set holds Arrow batches or links to
+// Arrow files instead of Thrift columns
+func isArrowRowSet(rs *cli_service.TRowSet) bool {
+	return rs != nil && (rs.ArrowBatches != nil || len(rs.ResultLinks) > 0)
+}
+
+// newArrowPage decodes the Arrow batches of rowSet using the serialized arrowSchema.
+// When lz4Compressed is set each batch is an LZ4 frame.
+func newArrowPage(rowSet *cli_service.TRowSet, arrowSchema []byte, lz4Compressed bool) (*arrowPage, error) {
+	if len(arrowSchema) == 0 {
+		return nil, errors.New(errArrowRowsNoSchema)
+	}
+
+	page := &arrowPage{rowSet: rowSet}
+	for _, batch := range rowSet.ArrowBatches {
+		records, err := readArrowBatch(arrowSchema, batch.Batch, lz4Compressed)
+		if err != nil {
+			page.release()
+			return nil, err
+		}
+		page.addRecords(records)
+	}
+
+	return page, nil
+}
+
+// addRecords appends the records of one batch or file to the page
+func (p *arrowPage) addRecords(records []arrow.Record) {
+	for _, record := range records {
+		p.offsets = append(p.offsets, p.nRows)
+		p.records = append(p.records, record)
+		p.nRows += record.NumRows()
+	}
+}
+
+// readArrowBatch reads the records of one serialized batch, prefixed with the schema
+// so it forms a complete Arrow IPC stream
+func readArrowBatch(arrowSchema, batch []byte, lz4Compressed bool) ([]arrow.Record, error) {
+	return readArrowRecords(io.MultiReader(bytes.NewReader(arrowSchema), decompress(batch, lz4Compressed)))
+}
+
+// decompress returns a reader of data, decompressing it while reading when it is an LZ4 frame
+func decompress(data []byte, lz4Compressed bool) io.Reader {
+	if lz4Compressed {
+		return lz4.NewReader(bytes.NewReader(data))
+	}
+	return bytes.NewReader(data)
+}
+
+// readArrowRecords reads the records of a complete Arrow IPC stream
+func readArrowRecords(stream io.Reader) ([]arrow.Record, error) {
+	reader, err := ipc.NewReader(stream)
+	if err != nil {
+		return nil, wrapErr(err, errArrowRowsInvalidBatch)
+	}
+	defer reader.Release()
+
+	var records []arrow.Record
+	for reader.Next() {
+		record := reader.Record()
+		// the reader releases the current record on the next call to Next
+		record.Retain()
+		records = append(records, record)
+	}
+	if err := reader.Err(); err != nil {
+		for _, record := range records {
+			record.Release()
+		}
+		return nil, wrapErr(err, errArrowRowsInvalidBatch)
+	}
+
+	return records, nil
+}
+
+// release frees the memory held by the decoded records
+func (p *arrowPage) release() {
+	if p == nil {
+		return
+	}
+	for _, record := range p.records {
+		record.Release()
+	}
+	p.records = nil
+	p.offsets = nil
+}
+
+// scanRow populates dest with the values of the row at rowIndex within the page.
+// TIMESTAMP_NTZ values are materialized in ntzLocation and the other date/time values in location.
+// With zeroCopy the STRING and BINARY values are []byte views of the buffers of the page.
+func (p *arrowPage) scanRow(dest []driver.Value, rowIndex int64, columns []*cli_service.TColumnDesc, location, ntzLocation *time.Location, zeroCopy bool) error {
+	if rowIndex < 0 || rowIndex >= p.nRows {
+		return errors.Errorf(errArrowRowsInvalidRowIndex, rowIndex)
+	}
+
+	// find the record containing the row, pages hold few records so a linear scan is fine
+	recordIndex := len(p.offsets) - 1
+	for recordIndex > 0 && p.offsets[recordIndex] > rowIndex {
+		recordIndex--
+	}
+	record := p.records[recordIndex]
+	recordRow := int(rowIndex - p.offsets[recordIndex])
+
+	for i := range dest {
+		var columnName string
+		if i < len(columns) {
+			columnName = columns[i].ColumnName
+		}
+		if zeroCopy && i < len(columns) {
+			if val, ok := arrowView(record.Column(i), recordRow, columns[i]); ok {
+				dest[i] = val
+				continue
+			}
+		}
+		loc := location
+		if isNaiveTimestamp(record.Column(i).DataType()) {
+			loc = ntzLocation
+		}
+		val, err := arrowValue(record.Column(i), recordRow, columnName, loc)
+		if err != nil {
+			return err
+		}
+		dest[i] = val
+	}
+
+	return nil
+}
+
+// arrowValue converts the value at row of an Arrow array to the same Go type
+// returned for the column by the Thrift columnar results
+func arrowValue(arr arrow.Array, row int, columnName string, location *time.Location) (any, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	if arr.IsNull(row) {
+		return nil, nil
+	}
+
+	switch a := arr.(type) {
+	case *array.Null:
+		return nil, nil
+	case *array.Boolean:
+		return a.Value(row), nil
+	case *array.Int8:
+		return a.Value(row), nil
+	case *array.Int16:
+		return a.Value(row), nil
+	case *array.Int32:
+		return a.Value(row), nil
+	case *array.Int64:
+		return a.Value(row), nil
+	case *array.Float32:
+		return a.Value(row), nil
+	case *array.Float64:
+		return a.Value(row), nil
+	case *array.String:
+		return a.Value(row), nil
+	case *array.Binary:
+		// copy the value, the array's buffer is released with the page
+		val := a.Value(row)
+		b := make([]byte, len(val))
+		copy(b, val)
+		return b, nil
+	case *array.Decimal128:
+		// decimals are returned as strings, as in the Thrift columnar results
+		scale := a.DataType().(*arrow.Decimal128Type).Scale
+		return NewDecimal(a.Value(row).BigInt(), scale).String(), nil
+	case *array.Date32:
+		y, m, d := a.Value(row).ToTime().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, location), nil
+	case *array.Date64:
+		y, m, d := a.Value(row).ToTime().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, location), nil
+	case *array.Timestamp:
+		tsType := a.DataType().(*arrow.TimestampType)
+		t := a.Value(row).ToTime(tsType.Unit)
+		if tsType.TimeZone == "" {
+			// TIMESTAMP_NTZ values are a wall clock time, not an instant
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location), nil
+		}
+		return t.In(location), nil
+	default:
+		return nil, errors.Errorf(errArrowRowsUnsupportedType, arr.DataType(), columnName)
+	}
+}
+
+// arrowView returns the value at row of a STRING or BINARY column as a view of the buffer of the Arrow array,
+// ok is false for the other columns and for NULL values
+func arrowView(arr arrow.Array, row int, column *cli_service.TColumnDesc) (val []byte, ok bool) {
+	if arr.IsNull(row) {
+		return nil, false
+	}
+	switch a := arr.(type) {
+	case *array.String:
+		if !isStringColumn(column) {
+			return nil, false
+		}
+		offsets := a.ValueOffsets()
+		b := a.ValueBytes()
+		start, end := offsets[row]-offsets[0], offsets[row+1]-offsets[0]
+		return b[start:end:end], true
+	case *array.Binary:
+		val := a.Value(row)
+		return val[:len(val):len(val)], true
+	default:
+		return nil, false
+	}
+}
+
+// sparkSqlNameKey is the Arrow field metadata key holding the Spark SQL type of the column
+const sparkSqlNameKey = "Spark:DataType:SqlName"
+
+// parseArrowSchema decodes the Arrow schema serialized in the result set metadata
+func parseArrowSchema(arrowSchema []byte) (*arrow.Schema, error) {
+	reader, err := ipc.NewReader(bytes.NewReader(arrowSchema))
+	if err != nil {
+		return nil, wrapErr(err, errArrowRowsNoSchema)
+	}
+	defer reader.Release()
+	return reader.Schema(), nil
+}
+
+// isNaiveTimestamp returns true for Arrow timestamps without a time zone, which hold TIMESTAMP_NTZ values
+func isNaiveTimestamp(dt arrow.DataType) bool {
+	ts, ok := dt.(*arrow.TimestampType)
+	return ok && ts.TimeZone == ""
+}
+
+// timestampNTZColumns returns which columns hold TIMESTAMP_NTZ values. The Thrift column types don't
+// tell TIMESTAMP_NTZ apart from TIMESTAMP so the Arrow schema is used, all columns are reported as
+// TIMESTAMP when there isn't one.
+func timestampNTZColumns(metadata *cli_service.TGetResultSetMetadataResp) []bool {
+	ntzColumns := make([]bool, len(metadata.GetSchema().GetColumns()))
+	if len(metadata.GetArrowSchema()) == 0 {
+		return ntzColumns
+	}
+	schema, err := parseArrowSchema(metadata.ArrowSchema)
+	if err != nil {
+		return ntzColumns
+	}
+
+	for i, field := range schema.Fields() {
+		if i >= len(ntzColumns) {
+			break
+		}
+		if sqlName, ok := field.Metadata.GetValue(sparkSqlNameKey); ok {
+			ntzColumns[i] = strings.EqualFold(sqlName, "TIMESTAMP_NTZ")
+		} else {
+			ntzColumns[i] = isNaiveTimestamp(field.Type)
+		}
+	}
+	return ntzColumns
+}
+
+// variantColumns returns which columns hold VARIANT values. The server sends them as the JSON strings of STRING
+// columns, only the Spark type in the Arrow schema tells them apart, so no column is a VARIANT without one.
+func variantColumns(metadata *cli_service.TGetResultSetMetadataResp) []bool {
+	variants := make([]bool, len(metadata.GetSchema().GetColumns()))
+	if len(metadata.GetArrowSchema()) == 0 {
+		return variants
+	}
+	schema, err := parseArrowSchema(metadata.ArrowSchema)
+	if err != nil {
+		return variants
+	}
+
+	for i, field := range schema.Fields() {
+		if i >= len(variants) {
+			break
+		}
+		sqlName, ok := field.Metadata.GetValue(sparkSqlNameKey)
+		variants[i] = ok && strings.EqualFold(sqlName, "VARIANT")
+	}
+	return variants
+}
+
+// spatialTypes returns GEOMETRY or GEOGRAPHY for the columns of these types and an empty string for the other
+// columns. Like VARIANT columns they are sent as STRING or BINARY columns and only the Spark type in the Arrow
+// schema, e.g. GEOMETRY(4326), tells them apart.
+func spatialTypes(metadata *cli_service.TGetResultSetMetadataResp) []string {
+	types := make([]string, len(metadata.GetSchema().GetColumns()))
+	if len(metadata.GetArrowSchema()) == 0 {
+		return types
+	}
+	schema, err := parseArrowSchema(metadata.ArrowSchema)
+	if err != nil {
+		return types
+	}
+
+	for i, field := range schema.Fields() {
+		if i >= len(types) {
+			break
+		}
+		sqlName, _ := field.Metadata.GetValue(sparkSqlNameKey)
+		// the SRID follows the type in parentheses
+		sqlName, _, _ = strings.Cut(strings.ToUpper(strings.TrimSpace(sqlName)), "(")
+		if sqlName == "GEOMETRY" || sqlName == "GEOGRAPHY" {
+			types[i] = sqlName
+		}
+	}
+	return types
+}
+
+// GetArrowBatches returns an iterator over the Arrow record batches of the results not read yet
+func (r *rows) GetArrowBatches(ctx context.Context) (dbsqlrows.ArrowBatchIterator, error) {
+	err := isValidRows(r)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := r.getResultMetadata()
+	if err != nil {
+		return nil, err
+	}
+	isArrowFormat := metadata.GetResultFormat() == cli_service.TSparkRowSetType_ARROW_BASED_SET ||
+		metadata.GetResultFormat() == cli_service.TSparkRowSetType_URL_BASED_SET
+	if (metadata.IsSetResultFormat() && !isArrowFormat) || len(metadata.ArrowSchema) == 0 {
+		return nil, errors.New(errArrowRowsNotArrowResults)
+	}
+
+	return &arrowBatchIterator{ctx: ctx, r: r, metadata: metadata}, nil
+}
+
+// arrowBatchIterator hands out the records of the result pages, the caller owns each
+// record returned by Next
+type arrowBatchIterator struct {
+	ctx      context.Context
+	r        *rows
+	metadata *cli_service.TGetResultSetMetadataResp
+	records  []arrow.Record
+	err      error
+}
+
+var _ dbsqlrows.ArrowBatchIterator = (*arrowBatchIterator)(nil)
+
+func (it *arrowBatchIterator) Next() (arrow.Record, error) {
+	if !it.HasNext() {
+		return nil, io.EOF
+	}
+	if len(it.records) == 0 {
+		return nil, it.err
+	}
+
+	record := it.records[0]
+	it.records = it.records[1:]
+	return record, nil
+}
+
+func (it *arrowBatchIterator) HasNext() bool {
+	for len(it.records) == 0 && it.err == nil {
+		it.err = it.loadPage()
+	}
+	return len(it.records) > 0 || (it.err != nil && it.err != io.EOF)
+}
+
+func (it *arrowBatchIterator) Close() {
+	for _, record := range it.records {
+		record.Release()
+	}
+	it.records = nil
+	it.err = io.EOF
+}
+
+func (it *arrowBatchIterator) Schema() (*arrow.Schema, error) {
+	return parseArrowSchema(it.metadata.ArrowSchema)
+}
+
+// loadPage decodes the records of the page holding the next row not read yet,
+// fetching the page if necessary, and marks the rows of the page as read
+func (it *arrowBatchIterator) loadPage() error {
+	r := it.r
+	if r.shouldPrefetch() {
+		if err := r.startPrefetch(); err != nil {
+			return err
+		}
+	}
+	if err := r.checkResultLimits(); err != nil {
+		return err
+	}
+	if !r.isNextRowInPage() {
+		if err := r.fetchResultPage(); err != nil {
+			return err
+		}
+	}
+
+	// take over the records when the page was decoded already, e.g. by the prefetcher
+	page := r.arrowPage
+	if page != nil && page.rowSet == r.fetchResults.Results {
+		r.arrowPage = nil
+	} else {
+		var err error
+		page, err = r.decodeArrowPage(it.ctx, r.fetchResults.Results, it.metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the records of the rows read with rows.Next already are skipped
+	for i, record := range page.records {
+		if r.nextRowIndex >= page.offsets[i]+record.NumRows() {
+			record.Release()
+			continue
+		}
+		if skip := r.nextRowIndex - page.offsets[i]; skip > 0 {
+			sliced := record.NewSlice(skip, record.NumRows())
+			record.Release()
+			record = sliced
+		}
+		it.records = append(it.records, record)
+	}
+
+	// the records past the row limit are dropped, reading on returns a ResultTruncatedError
+	nRows := page.nRows - r.nextRowIndex
+	if r.cfg != nil && r.cfg.MaxRowsTotal > 0 && r.nextRowNumber+nRows > r.cfg.MaxRowsTotal {
+		nRows = r.cfg.MaxRowsTotal - r.nextRowNumber
+		it.records = truncateRecords(it.records, nRows)
+	}
+
+	r.nextRowNumber += nRows
+	r.nextRowIndex += nRows
+	return nil
+}
+
+// truncateRecords keeps the first nRows rows of records, releasing the records dropped
+func truncateRecords(records []arrow.Record, nRows int64) []arrow.Record {
+	for i, record := range records {
+		if nRows >= record.NumRows() {
+			nRows -= record.NumRows()
+			continue
+		}
+		var sliced arrow.Record
+		if nRows > 0 {
+			sliced = record.NewSlice(0, nRows)
+		}
+		for _, dropped := range records[i:] {
+			dropped.Release()
+		}
+		records = records[:i]
+		if sliced != nil {
+			records = append(records, sliced)
+		}
+		return records
+	}
+	return records
+}
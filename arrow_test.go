@@ -0,0 +1,633 @@
+package dbsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/decimal128"
+	"github.com/apache/arrow/go/v12/arrow/ipc"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrowRows(t *testing.T) {
+	t.Run("should scan rows of all batches", func(t *testing.T) {
+		rowSet, metadata := getArrowTestRows(t)
+		r := &rows{
+			client:               &client.TestClient{},
+			fetchResults:         &cli_service.TFetchResultsResp{Results: rowSet, HasMoreRows: boolPtr(false)},
+			fetchResultsMetadata: metadata,
+			closed:               true,
+		}
+		assert.Equal(t, int64(3), getNRows(rowSet))
+
+		ts := time.Date(2021, 7, 1, 5, 43, 28, 123456000, time.UTC)
+		date := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+		expected := [][]driver.Value{
+			{true, int8(1), int16(2), int32(3), int64(4), float32(1.5), float64(2.5), "s0", ts, []byte{1, 2}, date},
+			{nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+			{false, int8(-1), int16(-2), int32(-3), int64(-4), float32(-1.5), float64(-2.5), "s2", ts.Add(time.Hour), []byte{}, date.AddDate(0, 0, 1)},
+		}
+
+		row := make([]driver.Value, len(r.Columns()))
+		for i := range expected {
+			require.NoError(t, r.Next(row))
+			assert.Equal(t, expected[i], row)
+		}
+		assert.Equal(t, io.EOF, r.Next(row))
+		assert.NoError(t, r.Close())
+	})
+
+	t.Run("should return views of strings and binaries with zero-copy scans", func(t *testing.T) {
+		rowSet, metadata := getArrowTestRows(t)
+		cfg := config.WithDefaults()
+		cfg.ZeroCopyScan = true
+		r := &rows{
+			client:               &client.TestClient{},
+			cfg:                  cfg,
+			fetchResults:         &cli_service.TFetchResultsResp{Results: rowSet, HasMoreRows: boolPtr(false)},
+			fetchResultsMetadata: metadata,
+			closed:               true,
+		}
+		assert.Equal(t, scanTypeRawBytes, r.ColumnTypeScanType(7))
+
+		row := make([]driver.Value, len(r.Columns()))
+		require.NoError(t, r.Next(row))
+		assert.Equal(t, []byte("s0"), row[7])
+		assert.Equal(t, []byte{1, 2}, row[9])
+		require.NoError(t, r.Next(row))
+		assert.Nil(t, row[7])
+		require.NoError(t, r.Next(row))
+		assert.Equal(t, []byte("s2"), row[7])
+		assert.Equal(t, 2, cap(row[7].([]byte)), "appending to a view doesn't overwrite the buffer")
+	})
+
+	t.Run("should use location for dates and timestamps", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+		rowSet, metadata := getArrowTestRows(t)
+		r := &rows{
+			client:               &client.TestClient{},
+			location:             loc,
+			fetchResults:         &cli_service.TFetchResultsResp{Results: rowSet},
+			fetchResultsMetadata: metadata,
+		}
+
+		row := make([]driver.Value, len(r.Columns()))
+		require.NoError(t, r.Next(row))
+		assert.Equal(t, time.Date(2021, 7, 1, 1, 43, 28, 123456000, loc), row[8])
+		assert.Equal(t, time.Date(2021, 7, 1, 0, 0, 0, 0, loc), row[10])
+	})
+
+	t.Run("should decompress lz4 batches", func(t *testing.T) {
+		rowSet, metadata := getArrowTestRows(t)
+		for _, batch := range rowSet.ArrowBatches {
+			var buf bytes.Buffer
+			w := lz4.NewWriter(&buf)
+			_, err := w.Write(batch.Batch)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			batch.Batch = buf.Bytes()
+		}
+		metadata.Lz4Compressed = boolPtr(true)
+		r := &rows{
+			client:               &client.TestClient{},
+			fetchResults:         &cli_service.TFetchResultsResp{Results: rowSet, HasMoreRows: boolPtr(false)},
+			fetchResultsMetadata: metadata,
+			closed:               true,
+		}
+
+		row := make([]driver.Value, len(r.Columns()))
+		var strs []any
+		for r.Next(row) == nil {
+			strs = append(strs, row[7])
+		}
+		assert.Equal(t, []any{"s0", nil, "s2"}, strs)
+
+		// uncompressed batches can't be read as lz4 frames
+		rowSet, metadata = getArrowTestRows(t)
+		metadata.Lz4Compressed = boolPtr(true)
+		r = &rows{
+			client:               &client.TestClient{},
+			fetchResults:         &cli_service.TFetchResultsResp{Results: rowSet},
+			fetchResultsMetadata: metadata,
+		}
+		assert.ErrorContains(t, r.Next(row), errArrowRowsInvalidBatch)
+	})
+
+	t.Run("should return decimals as strings", func(t *testing.T) {
+		builder := array.NewDecimal128Builder(memory.DefaultAllocator, &arrow.Decimal128Type{Precision: 10, Scale: 2})
+		defer builder.Release()
+		builder.Append(decimal128.FromI64(-12345))
+		builder.AppendNull()
+		arr := builder.NewArray()
+		defer arr.Release()
+
+		val, err := arrowValue(arr, 0, "decimal_col", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "-123.45", val)
+		val, err = arrowValue(arr, 1, "decimal_col", nil)
+		assert.NoError(t, err)
+		assert.Nil(t, val)
+	})
+
+	t.Run("should fail without arrow schema", func(t *testing.T) {
+		rowSet, metadata := getArrowTestRows(t)
+		metadata.ArrowSchema = nil
+		r := &rows{
+			client:               &client.TestClient{},
+			fetchResults:         &cli_service.TFetchResultsResp{Results: rowSet},
+			fetchResultsMetadata: metadata,
+		}
+
+		row := make([]driver.Value, len(r.Columns()))
+		assert.EqualError(t, r.Next(row), errArrowRowsNoSchema)
+	})
+
+	t.Run("should fail on invalid batch", func(t *testing.T) {
+		rowSet, metadata := getArrowTestRows(t)
+		rowSet.ArrowBatches[0].Batch = []byte("not an arrow batch")
+		r := &rows{
+			client:               &client.TestClient{},
+			fetchResults:         &cli_service.TFetchResultsResp{Results: rowSet},
+			fetchResultsMetadata: metadata,
+		}
+
+		row := make([]driver.Value, len(r.Columns()))
+		assert.ErrorContains(t, r.Next(row), errArrowRowsInvalidBatch)
+	})
+}
+
+func TestCloudFetchRows(t *testing.T) {
+	rowSet, metadata := getArrowTestRows(t)
+	// result files are complete Arrow IPC streams
+	files := map[string][]byte{}
+	for i, batch := range rowSet.ArrowBatches {
+		files[fmt.Sprintf("/file%d", i)] = append(append([]byte{}, metadata.ArrowSchema...), batch.Batch...)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(files[r.URL.Path])
+	}))
+	defer server.Close()
+
+	expiry := time.Now().Add(time.Hour).Unix()
+	links := []*cli_service.TSparkArrowResultLink{
+		{FileLink: server.URL + "/file0", ExpiryTime: expiry, StartRowOffset: 0, RowCount: 2},
+		// expired, refreshed before downloading
+		{FileLink: server.URL + "/expired", ExpiryTime: time.Now().Add(-time.Minute).Unix(), StartRowOffset: 2, RowCount: 1},
+	}
+
+	var fetchReq *cli_service.TFetchResultsReq
+	testClient := &client.TestClient{
+		FnFetchResults: func(ctx context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+			fetchReq = req
+			return &cli_service.TFetchResultsResp{
+				Results: &cli_service.TRowSet{
+					ResultLinks: []*cli_service.TSparkArrowResultLink{
+						{FileLink: server.URL + "/file1", ExpiryTime: expiry, StartRowOffset: 2, RowCount: 1},
+					},
+				},
+			}, nil
+		},
+	}
+
+	r := &rows{
+		client:               testClient,
+		cfg:                  config.WithDefaults(),
+		fetchResults:         &cli_service.TFetchResultsResp{Results: &cli_service.TRowSet{ResultLinks: links}, HasMoreRows: boolPtr(false)},
+		fetchResultsMetadata: metadata,
+		closed:               true,
+	}
+	assert.Equal(t, int64(3), getNRows(r.fetchResults.Results))
+
+	row := make([]driver.Value, len(r.Columns()))
+	var strs []any
+	for {
+		err := r.Next(row)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		strs = append(strs, row[7])
+	}
+	assert.Equal(t, []any{"s0", nil, "s2"}, strs)
+	require.NotNil(t, fetchReq)
+	assert.Equal(t, cli_service.TFetchOrientation_FETCH_ABSOLUTE, fetchReq.Orientation)
+	assert.Equal(t, int64(2), fetchReq.GetStartRowOffset())
+	assert.NoError(t, r.Close())
+}
+
+func TestArrowBatches(t *testing.T) {
+	getRows := func(t *testing.T) *rows {
+		firstPage, metadata := getArrowTestRows(t)
+		secondPage, _ := getArrowTestRows(t)
+		secondPage.StartRowOffset = 3
+		var fetched bool
+		testClient := &client.TestClient{
+			FnFetchResults: func(ctx context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+				assert.False(t, fetched)
+				fetched = true
+				return &cli_service.TFetchResultsResp{Results: secondPage, HasMoreRows: boolPtr(false)}, nil
+			},
+		}
+		return &rows{
+			client:               testClient,
+			fetchResults:         &cli_service.TFetchResultsResp{Results: firstPage, HasMoreRows: boolPtr(true)},
+			fetchResultsMetadata: metadata,
+			closed:               true,
+		}
+	}
+
+	t.Run("should iterate over the batches of all pages", func(t *testing.T) {
+		r := getRows(t)
+		batches, err := r.GetArrowBatches(context.Background())
+		require.NoError(t, err)
+		defer batches.Close()
+
+		schema, err := batches.Schema()
+		require.NoError(t, err)
+		assert.Equal(t, "string_col", schema.Field(7).Name)
+
+		var nRows []int64
+		for batches.HasNext() {
+			record, err := batches.Next()
+			require.NoError(t, err)
+			nRows = append(nRows, record.NumRows())
+			record.Release()
+		}
+		assert.Equal(t, []int64{2, 1, 2, 1}, nRows)
+		_, err = batches.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("should skip rows already read", func(t *testing.T) {
+		r := getRows(t)
+		row := make([]driver.Value, len(r.Columns()))
+		require.NoError(t, r.Next(row))
+
+		batches, err := r.GetArrowBatches(context.Background())
+		require.NoError(t, err)
+		defer batches.Close()
+
+		record, err := batches.Next()
+		require.NoError(t, err)
+		defer record.Release()
+		assert.Equal(t, int64(1), record.NumRows())
+		assert.True(t, record.Column(7).IsNull(0))
+	})
+
+	t.Run("should stop at the row limit", func(t *testing.T) {
+		r := getRows(t)
+		r.cfg = &config.Config{MaxRowsTotal: 4}
+		batches, err := r.GetArrowBatches(context.Background())
+		require.NoError(t, err)
+		defer batches.Close()
+
+		var nRows []int64
+		for batches.HasNext() {
+			record, err := batches.Next()
+			if err != nil {
+				var truncatedErr *dbsqlerr.ResultTruncatedError
+				require.ErrorAs(t, err, &truncatedErr)
+				assert.Equal(t, dbsqlerr.ResultTruncatedError{MaxRowsTotal: 4, Rows: 4}, *truncatedErr)
+				break
+			}
+			nRows = append(nRows, record.NumRows())
+			record.Release()
+		}
+		assert.Equal(t, []int64{2, 1, 1}, nRows)
+	})
+
+	t.Run("rows.Next should stop at the row limit", func(t *testing.T) {
+		r := getRows(t)
+		r.cfg = &config.Config{MaxRowsTotal: 2}
+		row := make([]driver.Value, len(r.Columns()))
+		require.NoError(t, r.Next(row))
+		require.NoError(t, r.Next(row))
+		err := r.Next(row)
+		assert.EqualError(t, err, "databricks: results truncated after 2 rows, the limit is 2 rows")
+
+		r = getRows(t)
+		r.cfg = &config.Config{MaxRowsTotal: 6}
+		for i := 0; i < 6; i++ {
+			require.NoError(t, r.Next(row))
+		}
+		assert.Equal(t, io.EOF, r.Next(row), "the results fit the limit")
+	})
+
+	t.Run("rows.Next should stop at the byte limit", func(t *testing.T) {
+		r := getRows(t)
+		r.cfg = &config.Config{MaxBytesPerQuery: 1}
+		row := make([]driver.Value, len(r.Columns()))
+		for i := 0; i < 3; i++ {
+			require.NoError(t, r.Next(row))
+		}
+		err := r.Next(row)
+		var truncatedErr *dbsqlerr.ResultTruncatedError
+		require.ErrorAs(t, err, &truncatedErr)
+		assert.Equal(t, int64(1), truncatedErr.MaxBytesPerQuery)
+		assert.Equal(t, int64(3), truncatedErr.Rows)
+	})
+
+	t.Run("should fail for columnar results", func(t *testing.T) {
+		r := getRows(t)
+		r.fetchResultsMetadata.ResultFormat = cli_service.TSparkRowSetTypePtr(cli_service.TSparkRowSetType_COLUMN_BASED_SET)
+		_, err := r.GetArrowBatches(context.Background())
+		assert.EqualError(t, err, errArrowRowsNotArrowResults)
+	})
+}
+
+func TestTimestampNTZ(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	fields := []arrow.Field{
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "Etc/UTC"}, Nullable: true},
+		{Name: "ts_ntz", Type: &arrow.TimestampType{Unit: arrow.Microsecond}, Nullable: true},
+	}
+	schema := arrow.NewSchema(fields, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	ts := arrow.Timestamp(time.Date(2021, 7, 1, 5, 43, 28, 0, time.UTC).UnixMicro())
+	builder.Field(0).(*array.TimestampBuilder).Append(ts)
+	builder.Field(1).(*array.TimestampBuilder).Append(ts)
+	record := builder.NewRecord()
+	defer record.Release()
+	schemaBytes, batches := getArrowTestBatches(t, schema, record)
+
+	column := func(name string) *cli_service.TColumnDesc {
+		return &cli_service.TColumnDesc{
+			ColumnName: name,
+			TypeDesc: &cli_service.TTypeDesc{
+				Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: cli_service.TTypeId_TIMESTAMP_TYPE}}},
+			},
+		}
+	}
+	metadata := &cli_service.TGetResultSetMetadataResp{
+		Schema:      &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{column("ts"), column("ts_ntz")}},
+		ArrowSchema: schemaBytes,
+	}
+	getRows := func(rowSet *cli_service.TRowSet, ntzLocation *time.Location) *rows {
+		return &rows{
+			client:               &client.TestClient{},
+			location:             ny,
+			ntzLocation:          ntzLocation,
+			fetchResults:         &cli_service.TFetchResultsResp{Results: rowSet},
+			fetchResultsMetadata: metadata,
+		}
+	}
+	columnarRowSet := &cli_service.TRowSet{Columns: []*cli_service.TColumn{
+		// TIMESTAMP values are rendered in the session timezone
+		{StringVal: &cli_service.TStringColumn{Values: []string{"2021-07-01 01:43:28"}}},
+		{StringVal: &cli_service.TStringColumn{Values: []string{"2021-07-01 05:43:28"}}},
+	}}
+
+	for name, rowSet := range map[string]*cli_service.TRowSet{
+		"arrow":    {ArrowBatches: batches},
+		"columnar": columnarRowSet,
+	} {
+		t.Run(name+" results should keep the wall clock of naive timestamps", func(t *testing.T) {
+			r := getRows(rowSet, nil)
+			row := make([]driver.Value, 2)
+			require.NoError(t, r.Next(row))
+			assert.Equal(t, time.Date(2021, 7, 1, 1, 43, 28, 0, ny), row[0])
+			assert.Equal(t, time.Date(2021, 7, 1, 5, 43, 28, 0, ny), row[1])
+
+			r = getRows(rowSet, time.UTC)
+			require.NoError(t, r.Next(row))
+			assert.Equal(t, time.Date(2021, 7, 1, 1, 43, 28, 0, ny), row[0])
+			assert.Equal(t, time.Date(2021, 7, 1, 5, 43, 28, 0, time.UTC), row[1])
+		})
+	}
+
+	t.Run("should report TIMESTAMP_NTZ columns", func(t *testing.T) {
+		r := getRows(columnarRowSet, nil)
+		assert.Equal(t, "TIMESTAMP", r.ColumnTypeDatabaseTypeName(0))
+		assert.Equal(t, "TIMESTAMP_NTZ", r.ColumnTypeDatabaseTypeName(1))
+
+		// the Spark type in the field metadata takes precedence
+		fields[0].Metadata = arrow.NewMetadata([]string{sparkSqlNameKey}, []string{"TIMESTAMP_NTZ"})
+		schemaBytes, _ := getArrowTestBatches(t, arrow.NewSchema(fields, nil))
+		assert.Equal(t, []bool{true, true}, timestampNTZColumns(&cli_service.TGetResultSetMetadataResp{
+			Schema:      metadata.Schema,
+			ArrowSchema: schemaBytes,
+		}))
+
+		// without an Arrow schema all columns are TIMESTAMP
+		assert.Equal(t, []bool{false, false}, timestampNTZColumns(&cli_service.TGetResultSetMetadataResp{Schema: metadata.Schema}))
+	})
+}
+
+func TestDateTimeConversions(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	fields := []arrow.Field{
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "Etc/UTC"}, Nullable: true},
+		{Name: "ts_ntz", Type: &arrow.TimestampType{Unit: arrow.Microsecond}, Nullable: true},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+	}
+	schema := arrow.NewSchema(fields, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	instant := time.Date(2021, 7, 1, 5, 43, 28, 0, time.UTC)
+	builder.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(instant.UnixMicro()))
+	builder.Field(1).(*array.TimestampBuilder).Append(arrow.Timestamp(instant.UnixMicro()))
+	builder.Field(2).(*array.Date32Builder).Append(arrow.Date32FromTime(instant))
+	record := builder.NewRecord()
+	defer record.Release()
+	schemaBytes, batches := getArrowTestBatches(t, schema, record)
+
+	column := func(name string, typeID cli_service.TTypeId) *cli_service.TColumnDesc {
+		return &cli_service.TColumnDesc{
+			ColumnName: name,
+			TypeDesc: &cli_service.TTypeDesc{
+				Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: typeID}}},
+			},
+		}
+	}
+	metadata := &cli_service.TGetResultSetMetadataResp{
+		Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{
+			column("ts", cli_service.TTypeId_TIMESTAMP_TYPE),
+			column("ts_ntz", cli_service.TTypeId_TIMESTAMP_TYPE),
+			column("day", cli_service.TTypeId_DATE_TYPE),
+		}},
+		ArrowSchema: schemaBytes,
+	}
+	getRows := func(rowSet *cli_service.TRowSet, timestamps, dates string) *rows {
+		cfg := config.WithDefaults()
+		cfg.TimestampConversion = timestamps
+		cfg.DateConversion = dates
+		return &rows{
+			client:               &client.TestClient{},
+			cfg:                  cfg,
+			location:             ny,
+			fetchResults:         &cli_service.TFetchResultsResp{Results: rowSet},
+			fetchResultsMetadata: metadata,
+		}
+	}
+	columnarRowSet := &cli_service.TRowSet{Columns: []*cli_service.TColumn{
+		{StringVal: &cli_service.TStringColumn{Values: []string{"2021-07-01 01:43:28"}}},
+		{StringVal: &cli_service.TStringColumn{Values: []string{"2021-07-01 05:43:28"}}},
+		{StringVal: &cli_service.TStringColumn{Values: []string{"2021-07-01"}}},
+	}}
+
+	for name, rowSet := range map[string]*cli_service.TRowSet{
+		"arrow":    {ArrowBatches: batches},
+		"columnar": columnarRowSet,
+	} {
+		t.Run(name+" results should convert dates and timestamps", func(t *testing.T) {
+			row := make([]driver.Value, 3)
+			r := getRows(rowSet, config.TimestampInLocation, config.DateAsTime)
+			require.NoError(t, r.Next(row))
+			assert.Equal(t, []driver.Value{
+				time.Date(2021, 7, 1, 1, 43, 28, 0, ny),
+				time.Date(2021, 7, 1, 5, 43, 28, 0, ny),
+				time.Date(2021, 7, 1, 0, 0, 0, 0, ny),
+			}, row)
+
+			r = getRows(rowSet, config.TimestampInUTC, config.DateAsCivil)
+			require.NoError(t, r.Next(row))
+			assert.Equal(t, []driver.Value{instant, instant, Date{Year: 2021, Month: time.July, Day: 1}}, row)
+			assert.Equal(t, scanTypeDateTime, r.ColumnTypeScanType(0))
+			assert.Equal(t, scanTypeDate, r.ColumnTypeScanType(2))
+
+			r = getRows(rowSet, config.TimestampAsMicros, config.DateAsTime)
+			require.NoError(t, r.Next(row))
+			assert.Equal(t, []driver.Value{instant.UnixMicro(), instant.UnixMicro(), time.Date(2021, 7, 1, 0, 0, 0, 0, ny)}, row)
+			assert.Equal(t, scanTypeInt64, r.ColumnTypeScanType(1))
+		})
+	}
+}
+
+// getArrowTestRows returns a row set of two arrow batches, holding two and one rows,
+// and the matching result set metadata
+func getArrowTestRows(t *testing.T) (*cli_service.TRowSet, *cli_service.TGetResultSetMetadataResp) {
+	fields := []arrow.Field{
+		{Name: "bool_col", Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+		{Name: "tinyint_col", Type: arrow.PrimitiveTypes.Int8, Nullable: true},
+		{Name: "smallint_col", Type: arrow.PrimitiveTypes.Int16, Nullable: true},
+		{Name: "int_col", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "bigint_col", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "float_col", Type: arrow.PrimitiveTypes.Float32, Nullable: true},
+		{Name: "double_col", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "string_col", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "timestamp_col", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "Etc/UTC"}, Nullable: true},
+		{Name: "binary_col", Type: arrow.BinaryTypes.Binary, Nullable: true},
+		{Name: "date_col", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+	}
+	typeIds := []cli_service.TTypeId{
+		cli_service.TTypeId_BOOLEAN_TYPE,
+		cli_service.TTypeId_TINYINT_TYPE,
+		cli_service.TTypeId_SMALLINT_TYPE,
+		cli_service.TTypeId_INT_TYPE,
+		cli_service.TTypeId_BIGINT_TYPE,
+		cli_service.TTypeId_FLOAT_TYPE,
+		cli_service.TTypeId_DOUBLE_TYPE,
+		cli_service.TTypeId_STRING_TYPE,
+		cli_service.TTypeId_TIMESTAMP_TYPE,
+		cli_service.TTypeId_BINARY_TYPE,
+		cli_service.TTypeId_DATE_TYPE,
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	ts := time.Date(2021, 7, 1, 5, 43, 28, 123456000, time.UTC).UnixMicro()
+	date := arrow.Date32FromTime(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+
+	// first batch: a row of values and a row of nulls
+	builder.Field(0).(*array.BooleanBuilder).AppendValues([]bool{true, false}, []bool{true, false})
+	builder.Field(1).(*array.Int8Builder).AppendValues([]int8{1, 0}, []bool{true, false})
+	builder.Field(2).(*array.Int16Builder).AppendValues([]int16{2, 0}, []bool{true, false})
+	builder.Field(3).(*array.Int32Builder).AppendValues([]int32{3, 0}, []bool{true, false})
+	builder.Field(4).(*array.Int64Builder).AppendValues([]int64{4, 0}, []bool{true, false})
+	builder.Field(5).(*array.Float32Builder).AppendValues([]float32{1.5, 0}, []bool{true, false})
+	builder.Field(6).(*array.Float64Builder).AppendValues([]float64{2.5, 0}, []bool{true, false})
+	builder.Field(7).(*array.StringBuilder).AppendValues([]string{"s0", ""}, []bool{true, false})
+	builder.Field(8).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{arrow.Timestamp(ts), 0}, []bool{true, false})
+	builder.Field(9).(*array.BinaryBuilder).AppendValues([][]byte{{1, 2}, nil}, []bool{true, false})
+	builder.Field(10).(*array.Date32Builder).AppendValues([]arrow.Date32{date, 0}, []bool{true, false})
+	first := builder.NewRecord()
+	defer first.Release()
+
+	// second batch: one row
+	builder.Field(0).(*array.BooleanBuilder).Append(false)
+	builder.Field(1).(*array.Int8Builder).Append(-1)
+	builder.Field(2).(*array.Int16Builder).Append(-2)
+	builder.Field(3).(*array.Int32Builder).Append(-3)
+	builder.Field(4).(*array.Int64Builder).Append(-4)
+	builder.Field(5).(*array.Float32Builder).Append(-1.5)
+	builder.Field(6).(*array.Float64Builder).Append(-2.5)
+	builder.Field(7).(*array.StringBuilder).Append("s2")
+	builder.Field(8).(*array.TimestampBuilder).Append(arrow.Timestamp(ts + time.Hour.Microseconds()))
+	builder.Field(9).(*array.BinaryBuilder).Append([]byte{})
+	builder.Field(10).(*array.Date32Builder).Append(date + 1)
+	second := builder.NewRecord()
+	defer second.Release()
+
+	schemaBytes, batches := getArrowTestBatches(t, schema, first, second)
+
+	columns := make([]*cli_service.TColumnDesc, len(fields))
+	for i := range fields {
+		columns[i] = &cli_service.TColumnDesc{
+			ColumnName: fields[i].Name,
+			TypeDesc: &cli_service.TTypeDesc{
+				Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: typeIds[i]}}},
+			},
+		}
+	}
+	metadata := &cli_service.TGetResultSetMetadataResp{
+		Schema:      &cli_service.TTableSchema{Columns: columns},
+		ArrowSchema: schemaBytes,
+	}
+
+	return &cli_service.TRowSet{ArrowBatches: batches}, metadata
+}
+
+// getArrowTestBatches serializes the records the way the server sends them: the schema
+// as an IPC stream without records and each record as a single IPC message
+func getArrowTestBatches(t *testing.T, schema *arrow.Schema, records ...arrow.Record) ([]byte, []*cli_service.TSparkArrowBatch) {
+	var buf bytes.Buffer
+	w := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	require.NoError(t, w.Close())
+	// strip the end of stream marker
+	schemaBytes := buf.Bytes()[:buf.Len()-8]
+
+	batches := make([]*cli_service.TSparkArrowBatch, len(records))
+	for i, record := range records {
+		var buf bytes.Buffer
+		w := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+		require.NoError(t, w.Write(record))
+		batches[i] = &cli_service.TSparkArrowBatch{
+			Batch:    buf.Bytes()[len(schemaBytes):],
+			RowCount: record.NumRows(),
+		}
+	}
+
+	return schemaBytes, batches
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
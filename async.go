@@ -0,0 +1,298 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/pkg/errors"
+)
+
+var errQueryID = "databricks: invalid query id %q"
+var errQueryNotFinished = "databricks: query is %s"
+
+// Conn is implemented by the connections of the driver. Use sql.Conn.Raw to run queries asynchronously:
+//
+//	conn, err := db.Conn(ctx)
+//	defer conn.Close()
+//	err = conn.Raw(func(driverConn any) error {
+//		handle, err := driverConn.(dbsql.Conn).ExecuteAsync(ctx, "insert into t select * from s")
+//		if err != nil {
+//			return err
+//		}
+//		queryID = handle.ID()
+//		return nil
+//	})
+type Conn interface {
+	// ExecuteAsync starts running query and returns without waiting for it to finish
+	ExecuteAsync(ctx context.Context, query string, args ...any) (*QueryHandle, error)
+	// AttachQuery returns the handle of a query started with ExecuteAsync, from its id
+	AttachQuery(id string) (*QueryHandle, error)
+	// ExecBatch inserts rows with an INSERT query in as few statements as possible
+	ExecBatch(ctx context.Context, query string, rows [][]driver.Value) (driver.Result, error)
+	// ServerCapabilities returns the features supported by the server of the connection
+	ServerCapabilities() ServerCapabilities
+}
+
+var _ Conn = (*conn)(nil)
+
+// QueryState is the state of an asynchronous query
+type QueryState int
+
+const (
+	QueryPending  QueryState = iota // waiting to run
+	QueryRunning                    // running
+	QueryFinished                   // finished successfully, the results can be read
+	QueryCanceled                   // canceled
+	QueryClosed                     // closed, the results are no longer available
+	QueryFailed                     // failed or timed out
+)
+
+func (s QueryState) String() string {
+	switch s {
+	case QueryPending:
+		return "pending"
+	case QueryRunning:
+		return "running"
+	case QueryFinished:
+		return "finished"
+	case QueryCanceled:
+		return "canceled"
+	case QueryClosed:
+		return "closed"
+	default:
+		return "failed"
+	}
+}
+
+// Done returns true when the query is no longer running
+func (s QueryState) Done() bool {
+	return s != QueryPending && s != QueryRunning
+}
+
+// QueryHandle is the handle of a query started with ExecuteAsync. It uses the connection it was created
+// or attached with, so it must not be used concurrently with other queries of the connection.
+type QueryHandle struct {
+	conn     *conn
+	opHandle *cli_service.TOperationHandle
+}
+
+// ExecuteAsync starts running query and returns without waiting for it to finish. The query keeps running
+// when ctx is done after ExecuteAsync returned, use Cancel to stop it.
+func (c *conn) ExecuteAsync(ctx context.Context, query string, args ...any) (*QueryHandle, error) {
+	namedArgs, err := c.namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *cli_service.TExecuteStatementResp
+	err = c.intercept(ctx, query, namedArgs, func(ctx context.Context, query string, args []driver.NamedValue) error {
+		req, err := c.newExecuteStatementReq(ctx, query, args)
+		if err != nil {
+			return err
+		}
+		// keep the operation open until the results are read
+		req.RunAsync = true
+		req.GetDirectResults = nil
+
+		resp, err = c.submitStatement(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, wrapErrf(err, "failed to execute query")
+	}
+	if resp.GetOperationHandle() == nil {
+		return nil, newDriverError("databricks: query has no operation handle", nil)
+	}
+	return &QueryHandle{conn: c, opHandle: resp.OperationHandle}, nil
+}
+
+// AttachQuery returns the handle of a query from its id, e.g. to read its results in another process.
+func (c *conn) AttachQuery(id string) (*QueryHandle, error) {
+	guidStr, secretStr, _ := strings.Cut(id, ":")
+	guid, err := hex.DecodeString(strings.ReplaceAll(guidStr, "-", ""))
+	if err != nil || len(guid) != 16 {
+		return nil, newDriverError(fmt.Sprintf(errQueryID, id), nil)
+	}
+	secret, err := hex.DecodeString(secretStr)
+	if err != nil {
+		return nil, newDriverError(fmt.Sprintf(errQueryID, id), nil)
+	}
+
+	return &QueryHandle{conn: c, opHandle: &cli_service.TOperationHandle{
+		OperationId:   &cli_service.THandleIdentifier{GUID: guid, Secret: secret},
+		OperationType: cli_service.TOperationType_EXECUTE_STATEMENT,
+		HasResultSet:  true,
+	}}, nil
+}
+
+// namedValues converts the arguments of ExecuteAsync the way the sql package does
+func (c *conn) namedValues(args []any) ([]driver.NamedValue, error) {
+	namedArgs := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nv := driver.NamedValue{Ordinal: i + 1, Value: arg}
+		if named, ok := arg.(sql.NamedArg); ok {
+			nv.Name, nv.Value = named.Name, named.Value
+		}
+
+		err := c.CheckNamedValue(&nv)
+		if err == driver.ErrSkip {
+			nv.Value, err = driver.DefaultParameterConverter.ConvertValue(nv.Value)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "databricks: invalid query argument %d", i+1)
+		}
+		namedArgs[i] = nv
+	}
+	return namedArgs, nil
+}
+
+// ID returns the id of the query, to attach to it with AttachQuery
+func (h *QueryHandle) ID() string {
+	id := h.opHandle.GetOperationId()
+	if len(id.GetSecret()) == 0 {
+		return client.SprintGuid(id.GetGUID())
+	}
+	return client.SprintGuid(id.GetGUID()) + ":" + hex.EncodeToString(id.GetSecret())
+}
+
+// Poll returns the current state of the query. The error of failed queries is returned with QueryFailed.
+func (h *QueryHandle) Poll(ctx context.Context) (QueryState, error) {
+	ctx = driverctx.NewContextWithConnId(ctx, h.conn.id)
+	resp, err := h.conn.client.GetOperationStatus(ctx, &cli_service.TGetOperationStatusReq{
+		OperationHandle: h.opHandle,
+	})
+	if err != nil {
+		return QueryFailed, wrapErr(err, "failed to poll query state")
+	}
+
+	switch resp.GetOperationState() {
+	case cli_service.TOperationState_INITIALIZED_STATE, cli_service.TOperationState_PENDING_STATE:
+		return QueryPending, nil
+	case cli_service.TOperationState_RUNNING_STATE:
+		return QueryRunning, nil
+	case cli_service.TOperationState_FINISHED_STATE:
+		return QueryFinished, nil
+	case cli_service.TOperationState_CANCELED_STATE:
+		return QueryCanceled, nil
+	case cli_service.TOperationState_CLOSED_STATE:
+		return QueryClosed, nil
+	default:
+		return QueryFailed, h.conn.newExecutionError(ctx, h.opHandle, resp)
+	}
+}
+
+// Wait polls the query until it is done, and returns an error if it did not finish successfully.
+// The query keeps running if ctx is done first.
+func (h *QueryHandle) Wait(ctx context.Context) error {
+	for {
+		state, err := h.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if state == QueryFinished {
+			return nil
+		}
+		if state.Done() {
+			return errors.Errorf(errQueryNotFinished, state)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(h.conn.cfg.PollInterval):
+		}
+	}
+}
+
+// Cancel stops the query
+func (h *QueryHandle) Cancel(ctx context.Context) error {
+	log := logger.WithContext(h.conn.id, driverctx.CorrelationIdFromContext(ctx), client.SprintGuid(h.opHandle.OperationId.GUID))
+	log.Debug().Msg("databricks: canceling query")
+	ctx = driverctx.NewContextWithConnId(ctx, h.conn.id)
+	_, err := h.conn.client.CancelOperation(ctx, &cli_service.TCancelOperationReq{
+		OperationHandle: h.opHandle,
+	})
+	if err != nil {
+		return wrapErr(err, "failed to cancel query")
+	}
+	return nil
+}
+
+// Rows waits for the query to finish and returns its results. Closing the rows closes the query.
+func (h *QueryHandle) Rows(ctx context.Context) (driver.Rows, error) {
+	if err := h.Wait(ctx); err != nil {
+		return nil, err
+	}
+	rows := NewRows(h.conn.id, driverctx.CorrelationIdFromContext(ctx), h.conn.client, h.opHandle, h.conn.cfg, nil)
+	setRequestContext(rows, ctx)
+	setPrefetchClient(rows, h.conn.prefetchClient())
+	return rows, nil
+}
+
+// Close closes the query, releasing its results on the server. Close the query when its results are not read.
+func (h *QueryHandle) Close(ctx context.Context) error {
+	ctx = driverctx.NewContextWithConnId(ctx, h.conn.id)
+	_, err := h.conn.client.CloseOperation(ctx, &cli_service.TCloseOperationReq{
+		OperationHandle: h.opHandle,
+	})
+	if err != nil {
+		return wrapErr(err, "failed to close query")
+	}
+	return nil
+}
+
+// ResultFromID returns the results of a finished query from its id, e.g. to read the results of a query
+// started with ExecuteAsync, or to resume reading after a crash instead of running the query again. The
+// server keeps the position of the reader, so the rows continue after the last page fetched before.
+//
+// The rows hold a connection of db, close them to release it. They implement dbsqlrows.Rows to read
+// the results as Arrow record batches.
+func ResultFromID(ctx context.Context, db *sql.DB, statementID string) (driver.Rows, error) {
+	sqlConn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var r driver.Rows
+	err = sqlConn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*conn)
+		if !ok {
+			return newDriverError(fmt.Sprintf("databricks: unexpected connection type %T", driverConn), nil)
+		}
+		handle, err := c.AttachQuery(statementID)
+		if err != nil {
+			return err
+		}
+		r, err = handle.Rows(ctx)
+		return err
+	})
+	if err != nil {
+		_ = sqlConn.Close()
+		return nil, err
+	}
+
+	return &resultRows{rows: r.(*rows), sqlConn: sqlConn}, nil
+}
+
+// resultRows are rows returned by ResultFromID, closing them releases their connection
+type resultRows struct {
+	*rows
+	sqlConn *sql.Conn
+}
+
+func (r *resultRows) Close() error {
+	err := r.rows.Close()
+	if err1 := r.sqlConn.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryHandle(t *testing.T) {
+	opHandle := &cli_service.TOperationHandle{
+		OperationId: &cli_service.THandleIdentifier{
+			GUID:   []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54},
+			Secret: []byte{0xab, 0xcd},
+		},
+		OperationType: cli_service.TOperationType_EXECUTE_STATEMENT,
+		HasResultSet:  true,
+	}
+
+	getTestConn := func(states ...cli_service.TOperationState) (*conn, *client.TestClient) {
+		testClient := &client.TestClient{
+			FnGetOperationStatus: func(ctx context.Context, req *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
+				state := states[0]
+				if len(states) > 1 {
+					states = states[1:]
+				}
+				return &cli_service.TGetOperationStatusResp{
+					OperationState: &state,
+					DisplayMessage: strPtr("query failed"),
+				}, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.PollInterval = time.Millisecond
+		return &conn{session: getTestSession(), client: testClient, cfg: cfg}, testClient
+	}
+
+	t.Run("ExecuteAsync starts the query without direct results", func(t *testing.T) {
+		testConn, testClient := getTestConn(cli_service.TOperationState_RUNNING_STATE)
+		var req *cli_service.TExecuteStatementReq
+		testClient.FnExecuteStatement = func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+			req = r
+			return &cli_service.TExecuteStatementResp{OperationHandle: opHandle}, nil
+		}
+		testConn.session.ServerProtocolVersion = cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8
+
+		handle, err := testConn.ExecuteAsync(context.Background(), "select :a, :b", sql.Named("a", 1), sql.Named("b", "x"))
+		require.NoError(t, err)
+		assert.True(t, req.RunAsync)
+		assert.Nil(t, req.GetDirectResults)
+		assert.Equal(t, "BIGINT", req.Parameters[0].GetType())
+		assert.Equal(t, "b", req.Parameters[1].GetName())
+		assert.Equal(t, "01020304-0217-0402-0302-030404df2236:abcd", handle.ID())
+
+		state, err := handle.Poll(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, QueryRunning, state)
+	})
+
+	t.Run("AttachQuery returns the handle of a query id", func(t *testing.T) {
+		testConn, _ := getTestConn(cli_service.TOperationState_FINISHED_STATE)
+		handle, err := testConn.AttachQuery("01020304-0217-0402-0302-030404df2236:abcd")
+		require.NoError(t, err)
+		assert.Equal(t, opHandle, handle.opHandle)
+
+		for _, id := range []string{"", "01020304", "01020304-0217-0402-0302-030404df2236:xyz"} {
+			_, err = testConn.AttachQuery(id)
+			assert.Error(t, err, id)
+		}
+	})
+
+	t.Run("Wait polls until the query is done", func(t *testing.T) {
+		testConn, _ := getTestConn(
+			cli_service.TOperationState_PENDING_STATE,
+			cli_service.TOperationState_RUNNING_STATE,
+			cli_service.TOperationState_FINISHED_STATE,
+		)
+		handle := &QueryHandle{conn: testConn, opHandle: opHandle}
+		assert.NoError(t, handle.Wait(context.Background()))
+
+		rows, err := handle.Rows(context.Background())
+		assert.NoError(t, err)
+		assert.NotNil(t, rows)
+	})
+
+	t.Run("Wait returns an error when the query does not finish", func(t *testing.T) {
+		testConn, _ := getTestConn(cli_service.TOperationState_CANCELED_STATE)
+		handle := &QueryHandle{conn: testConn, opHandle: opHandle}
+		assert.EqualError(t, handle.Wait(context.Background()), "databricks: query is canceled")
+
+		testConn, _ = getTestConn(cli_service.TOperationState_ERROR_STATE)
+		handle = &QueryHandle{conn: testConn, opHandle: opHandle}
+		state, err := handle.Poll(context.Background())
+		assert.Equal(t, QueryFailed, state)
+		assert.EqualError(t, err, "query failed")
+		_, err = handle.Rows(context.Background())
+		assert.EqualError(t, err, "query failed")
+	})
+
+	t.Run("Wait returns when the context is done", func(t *testing.T) {
+		testConn, _ := getTestConn(cli_service.TOperationState_RUNNING_STATE)
+		handle := &QueryHandle{conn: testConn, opHandle: opHandle}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, handle.Wait(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("Cancel and Close send the operation handle", func(t *testing.T) {
+		testConn, testClient := getTestConn(cli_service.TOperationState_RUNNING_STATE)
+		var canceled, closed *cli_service.TOperationHandle
+		testClient.FnCancelOperation = func(ctx context.Context, req *cli_service.TCancelOperationReq) (*cli_service.TCancelOperationResp, error) {
+			canceled = req.OperationHandle
+			return &cli_service.TCancelOperationResp{}, nil
+		}
+		testClient.FnCloseOperation = func(ctx context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+			closed = req.OperationHandle
+			return &cli_service.TCloseOperationResp{}, nil
+		}
+		handle := &QueryHandle{conn: testConn, opHandle: opHandle}
+		assert.NoError(t, handle.Cancel(context.Background()))
+		assert.NoError(t, handle.Close(context.Background()))
+		assert.Equal(t, opHandle, canceled)
+		assert.Equal(t, opHandle, closed)
+	})
+}
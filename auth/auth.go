@@ -1,7 +1,73 @@
 package auth
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 type Authenticator interface {
 	Authenticate(*http.Request) error
 }
+
+// Token is a credential returned by a TokenProvider.
+type Token struct {
+	// AccessToken is sent in the Authorization header.
+	AccessToken string
+	// TokenType is the authorization scheme, "Bearer" when empty.
+	TokenType string
+	// Expiry is the time the token expires. A zero Expiry means the token never expires.
+	Expiry time.Time
+}
+
+// TokenProvider fetches tokens from an external source such as a secrets manager or a corporate SSO service.
+// Use it with tokenprovider.NewAuthenticator or the WithTokenProvider connector option.
+type TokenProvider interface {
+	// GetToken returns a new token. ctx is the context of the request that needs the token.
+	GetToken(ctx context.Context) (Token, error)
+}
+
+// AuthType identifies an authentication method that can be selected with the DSN authType parameter.
+type AuthType int
+
+const (
+	AuthTypeUnknown AuthType = iota
+	AuthTypePat
+	AuthTypeOauthU2M
+	AuthTypeOauthM2M
+	AuthTypeAzureServicePrincipal
+	AuthTypeAzureManagedIdentity
+	AuthTypeGCP
+	AuthTypeDefault
+)
+
+var authTypeNames = map[AuthType]string{
+	AuthTypeUnknown:               "unknown",
+	AuthTypePat:                   "pat",
+	AuthTypeOauthU2M:              "oauth-u2m",
+	AuthTypeOauthM2M:              "oauth-m2m",
+	AuthTypeAzureServicePrincipal: "azure-sp",
+	AuthTypeAzureManagedIdentity:  "azure-msi",
+	AuthTypeGCP:                   "gcp",
+	AuthTypeDefault:               "default",
+}
+
+func (at AuthType) String() string {
+	if name, ok := authTypeNames[at]; ok {
+		return name
+	}
+	return authTypeNames[AuthTypeUnknown]
+}
+
+// ParseAuthType returns the AuthType matching typeString. Matching is case insensitive.
+func ParseAuthType(typeString string) (AuthType, error) {
+	for at, name := range authTypeNames {
+		if at != AuthTypeUnknown && strings.EqualFold(name, typeString) {
+			return at, nil
+		}
+	}
+	return AuthTypeUnknown, errors.Errorf("unknown auth type: %s", typeString)
+}
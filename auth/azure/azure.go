@@ -0,0 +1,36 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// the application id of the Azure Databricks resource, tokens must be issued for it
+const databricksResourceID = "2ff814a6-3304-4ab8-85cb-cd0e6f879c1d"
+
+// the Azure AD authority, a variable so tests can point it at a local server
+var authorityHost = "https://login.microsoftonline.com"
+
+func tokenURL(tenantID string) string {
+	return fmt.Sprintf("%s/%s/oauth2/v2.0/token", authorityHost, tenantID)
+}
+
+var databricksScopes = []string{databricksResourceID + "/.default"}
+
+// tokenAuthenticator sets the bearer token from a reusable token source
+type tokenAuthenticator struct {
+	name        string
+	tokenSource oauth2.TokenSource
+}
+
+func (a *tokenAuthenticator) Authenticate(r *http.Request) error {
+	token, err := a.tokenSource.Token()
+	if err != nil {
+		return errors.Wrapf(err, "%s: failed to get token", a.name)
+	}
+	token.SetAuthHeader(r)
+	return nil
+}
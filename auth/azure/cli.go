@@ -0,0 +1,74 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/internal/expiry"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// max time for the Azure CLI to return a token
+const cliTimeout = 30 * time.Second
+
+// runs a command and returns its standard output, a variable so tests can replace the CLI
+var runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output() // #nosec G204 -- arguments are fixed by the caller
+}
+
+// NewCLIAuthenticator returns an authenticator that gets Azure Databricks tokens from the Azure CLI,
+// using the account signed in with `az login`.
+func NewCLIAuthenticator() auth.Authenticator {
+	return &tokenAuthenticator{
+		name:        "azure cli",
+		tokenSource: oauth2.ReuseTokenSourceWithExpiry(nil, &cliTokenSource{}, expiry.Delta),
+	}
+}
+
+type cliTokenSource struct{}
+
+type cliToken struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	// expires_on is the unix time of the expiry, older CLI versions only return the local time in expiresOn
+	ExpiresOn     int64  `json:"expires_on"`
+	ExpiresOnTime string `json:"expiresOn"`
+}
+
+func (s *cliTokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	out, err := runCommand(ctx, "az", "account", "get-access-token", "--resource", databricksResourceID, "--output", "json")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, errors.Errorf("azure cli: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, errors.Wrap(err, "azure cli: failed to get token")
+	}
+
+	var t cliToken
+	if err := json.Unmarshal(out, &t); err != nil {
+		return nil, errors.Wrap(err, "azure cli: invalid token output")
+	}
+	if t.AccessToken == "" {
+		return nil, errors.New("azure cli: token output is missing accessToken")
+	}
+
+	token := &oauth2.Token{
+		AccessToken: t.AccessToken,
+		TokenType:   t.TokenType,
+	}
+	if t.ExpiresOn > 0 {
+		token.Expiry = time.Unix(t.ExpiresOn, 0)
+	} else if expiry, err := time.ParseInLocation("2006-01-02 15:04:05.999999", t.ExpiresOnTime, time.Local); err == nil {
+		token.Expiry = expiry
+	}
+	return token, nil
+}
@@ -0,0 +1,58 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCLIAuthenticator(t *testing.T) {
+	defaultRunCommand := runCommand
+	defer func() { runCommand = defaultRunCommand }()
+
+	t.Run("token with unix expiry", func(t *testing.T) {
+		var calls [][]string
+		runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			calls = append(calls, append([]string{name}, args...))
+			return []byte(fmt.Sprintf(`{"accessToken":"cli-token","tokenType":"Bearer","expires_on":%d}`, time.Now().Add(time.Hour).Unix())), nil
+		}
+
+		authr := NewCLIAuthenticator()
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest(http.MethodPost, "https://adb-123.azuredatabricks.net", nil)
+			require.NoError(t, authr.Authenticate(req))
+			assert.Equal(t, "Bearer cli-token", req.Header.Get("Authorization"))
+		}
+		require.Len(t, calls, 1)
+		assert.Equal(t, []string{"az", "account", "get-access-token", "--resource", databricksResourceID, "--output", "json"}, calls[0])
+	})
+
+	t.Run("token with local time expiry", func(t *testing.T) {
+		expiresOn := time.Now().Add(time.Hour).Format("2006-01-02 15:04:05.000000")
+		runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte(fmt.Sprintf(`{"accessToken":"old-cli-token","tokenType":"Bearer","expiresOn":%q}`, expiresOn)), nil
+		}
+
+		src := &cliTokenSource{}
+		token, err := src.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "old-cli-token", token.AccessToken)
+		assert.Equal(t, expiresOn, token.Expiry.Format("2006-01-02 15:04:05.000000"))
+	})
+
+	t.Run("cli not logged in", func(t *testing.T) {
+		runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, &exec.ExitError{Stderr: []byte("ERROR: Please run 'az login' to setup account.")}
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, "https://adb-123.azuredatabricks.net", nil)
+		err := NewCLIAuthenticator().Authenticate(req)
+		assert.ErrorContains(t, err, "az login")
+	})
+}
@@ -0,0 +1,90 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/internal/expiry"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// the Azure Instance Metadata Service token endpoint, a variable so tests can point it at a local server
+var imdsEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// max time for a single request to the metadata service
+const imdsTimeout = 10 * time.Second
+
+// NewManagedIdentityAuthenticator returns an authenticator that acquires Azure Databricks tokens
+// for the managed identity of the Azure VM or AKS node the program runs on.
+// Set clientID to use a user-assigned identity, leave it empty for the system-assigned identity.
+func NewManagedIdentityAuthenticator(clientID string) auth.Authenticator {
+	src := &msiTokenSource{
+		clientID: clientID,
+		client:   &http.Client{Timeout: imdsTimeout},
+	}
+
+	return &tokenAuthenticator{
+		name:        "azure managed identity",
+		tokenSource: oauth2.ReuseTokenSourceWithExpiry(nil, src, expiry.Delta),
+	}
+}
+
+type msiTokenSource struct {
+	clientID string
+	client   *http.Client
+}
+
+type msiToken struct {
+	AccessToken string      `json:"access_token"`
+	TokenType   string      `json:"token_type"`
+	ExpiresOn   json.Number `json:"expires_on"`
+}
+
+func (s *msiTokenSource) Token() (*oauth2.Token, error) {
+	query := url.Values{
+		"api-version": {"2018-02-01"},
+		"resource":    {databricksResourceID},
+	}
+	if s.clientID != "" {
+		query.Set("client_id", s.clientID)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, imdsEndpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "azure managed identity: invalid request")
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "azure managed identity: metadata service is not reachable")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("azure managed identity: metadata service returned status %d", resp.StatusCode)
+	}
+
+	var t msiToken
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, errors.Wrap(err, "azure managed identity: invalid token response")
+	}
+	if t.AccessToken == "" {
+		return nil, errors.New("azure managed identity: token response is missing access_token")
+	}
+
+	token := &oauth2.Token{
+		AccessToken: t.AccessToken,
+		TokenType:   t.TokenType,
+	}
+	if expiresOn, err := strconv.ParseInt(t.ExpiresOn.String(), 10, 64); err == nil {
+		token.Expiry = time.Unix(expiresOn, 0)
+	}
+	return token, nil
+}
@@ -0,0 +1,56 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagedIdentityAuthenticator(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"msi-token","token_type":"Bearer","expires_on":"%d"}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer server.Close()
+
+	defaultEndpoint := imdsEndpoint
+	imdsEndpoint = server.URL
+	defer func() { imdsEndpoint = defaultEndpoint }()
+
+	t.Run("system assigned identity", func(t *testing.T) {
+		requests = nil
+		authr := NewManagedIdentityAuthenticator("")
+
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest(http.MethodPost, "https://adb-123.azuredatabricks.net", nil)
+			require.NoError(t, authr.Authenticate(req))
+			assert.Equal(t, "Bearer msi-token", req.Header.Get("Authorization"))
+		}
+
+		// the token is reused until it expires
+		require.Len(t, requests, 1)
+		assert.Equal(t, databricksResourceID, requests[0].URL.Query().Get("resource"))
+		assert.False(t, requests[0].URL.Query().Has("client_id"))
+	})
+
+	t.Run("user assigned identity", func(t *testing.T) {
+		requests = nil
+		authr := NewManagedIdentityAuthenticator("user-identity")
+
+		req, _ := http.NewRequest(http.MethodPost, "https://adb-123.azuredatabricks.net", nil)
+		require.NoError(t, authr.Authenticate(req))
+		require.Len(t, requests, 1)
+		assert.Equal(t, "user-identity", requests[0].URL.Query().Get("client_id"))
+	})
+}
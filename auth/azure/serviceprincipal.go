@@ -0,0 +1,173 @@
+package azure
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" // #nosec G505 -- x5t is defined as the SHA-1 thumbprint of the certificate
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/internal/expiry"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// NewServicePrincipalAuthenticator returns an authenticator for an Azure AD service principal
+// that acquires Azure Databricks tokens with a client secret.
+// Tokens are requested on first use and refreshed automatically before they expire.
+func NewServicePrincipalAuthenticator(tenantID, clientID, clientSecret string) (auth.Authenticator, error) {
+	if tenantID == "" || clientID == "" || clientSecret == "" {
+		return nil, errors.New("azure: tenant id, client id and client secret are required")
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenURL(tenantID),
+		Scopes:       databricksScopes,
+		AuthStyle:    oauth2.AuthStyleInParams,
+	}
+
+	return &tokenAuthenticator{
+		name:        "azure service principal",
+		tokenSource: oauth2.ReuseTokenSourceWithExpiry(nil, config.TokenSource(context.Background()), expiry.Delta),
+	}, nil
+}
+
+// NewCertificateAuthenticator returns an authenticator for an Azure AD service principal
+// that acquires Azure Databricks tokens with a certificate credential. Only RSA keys are supported.
+func NewCertificateAuthenticator(tenantID, clientID string, cert *x509.Certificate, key crypto.PrivateKey) (auth.Authenticator, error) {
+	if tenantID == "" || clientID == "" {
+		return nil, errors.New("azure: tenant id and client id are required")
+	}
+	if cert == nil {
+		return nil, errors.New("azure: missing certificate")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("azure: certificate key must be an RSA private key")
+	}
+
+	src := &certificateTokenSource{
+		clientID: clientID,
+		tokenURL: tokenURL(tenantID),
+		cert:     cert,
+		key:      rsaKey,
+	}
+
+	return &tokenAuthenticator{
+		name:        "azure service principal",
+		tokenSource: oauth2.ReuseTokenSourceWithExpiry(nil, src, expiry.Delta),
+	}, nil
+}
+
+// LoadCertificate reads a PEM file containing a certificate and its unencrypted private key,
+// for use with NewCertificateAuthenticator.
+func LoadCertificate(path string) (*x509.Certificate, crypto.PrivateKey, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is provided by the user
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "azure: unable to read certificate file")
+	}
+
+	var cert *x509.Certificate
+	var key crypto.PrivateKey
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "CERTIFICATE":
+			if cert == nil {
+				cert, err = x509.ParseCertificate(block.Bytes)
+			}
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "azure: invalid %s in certificate file", block.Type)
+		}
+	}
+
+	if cert == nil || key == nil {
+		return nil, nil, errors.New("azure: certificate file must contain a certificate and a private key")
+	}
+	return cert, key, nil
+}
+
+// certificateTokenSource requests tokens with a signed client assertion, a new assertion is built for every request
+type certificateTokenSource struct {
+	clientID string
+	tokenURL string
+	cert     *x509.Certificate
+	key      *rsa.PrivateKey
+}
+
+func (s *certificateTokenSource) Token() (*oauth2.Token, error) {
+	assertion, err := s.clientAssertion(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	config := clientcredentials.Config{
+		ClientID:  s.clientID,
+		TokenURL:  s.tokenURL,
+		Scopes:    databricksScopes,
+		AuthStyle: oauth2.AuthStyleInParams,
+		EndpointParams: url.Values{
+			"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+			"client_assertion":      {assertion},
+		},
+	}
+	return config.Token(context.Background())
+}
+
+// clientAssertion builds the RS256 signed JWT that proves possession of the certificate's key
+func (s *certificateTokenSource) clientAssertion(now time.Time) (string, error) {
+	thumbprint := sha1.Sum(s.cert.Raw) // #nosec G401
+	header := map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"x5t": base64.RawURLEncoding.EncodeToString(thumbprint[:]),
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", errors.Wrap(err, "azure: failed to generate assertion id")
+	}
+	claims := map[string]any{
+		"aud": s.tokenURL,
+		"iss": s.clientID,
+		"sub": s.clientID,
+		"jti": hex.EncodeToString(jti),
+		"nbf": now.Unix(),
+		"exp": now.Add(10 * time.Minute).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.Wrap(err, "azure: failed to sign client assertion")
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,106 @@
+package azure
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServicePrincipalAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	certDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "test"}}, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/tenant/oauth2/v2.0/token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "aad-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer server.Close()
+
+	defaultAuthority := authorityHost
+	authorityHost = server.URL
+	defer func() { authorityHost = defaultAuthority }()
+
+	t.Run("client secret", func(t *testing.T) {
+		authr, err := NewServicePrincipalAuthenticator("tenant", "client", "secret")
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest(http.MethodPost, "https://adb-123.azuredatabricks.net", nil)
+		require.NoError(t, authr.Authenticate(req))
+		assert.Equal(t, "Bearer aad-token", req.Header.Get("Authorization"))
+		assert.Equal(t, "client", form["client_id"][0])
+		assert.Equal(t, "secret", form["client_secret"][0])
+		assert.Equal(t, "2ff814a6-3304-4ab8-85cb-cd0e6f879c1d/.default", form["scope"][0])
+	})
+
+	t.Run("certificate", func(t *testing.T) {
+		authr, err := NewCertificateAuthenticator("tenant", "client", cert, key)
+		require.NoError(t, err)
+
+		req, _ := http.NewRequest(http.MethodPost, "https://adb-123.azuredatabricks.net", nil)
+		require.NoError(t, authr.Authenticate(req))
+		assert.Equal(t, "Bearer aad-token", req.Header.Get("Authorization"))
+		assert.Empty(t, form["client_secret"])
+		assert.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", form["client_assertion_type"][0])
+
+		// the assertion must be signed with the certificate key
+		parts := strings.Split(form["client_assertion"][0], ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+	})
+
+	t.Run("missing credentials", func(t *testing.T) {
+		_, err := NewServicePrincipalAuthenticator("tenant", "client", "")
+		assert.Error(t, err)
+		_, err = NewCertificateAuthenticator("tenant", "client", nil, key)
+		assert.Error(t, err)
+	})
+
+	t.Run("load certificate", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cert.pem")
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})...)
+		require.NoError(t, os.WriteFile(path, data, 0600))
+
+		loadedCert, loadedKey, err := LoadCertificate(path)
+		require.NoError(t, err)
+		assert.Equal(t, cert.Raw, loadedCert.Raw)
+		assert.True(t, key.Equal(loadedKey))
+	})
+}
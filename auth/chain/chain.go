@@ -0,0 +1,149 @@
+// Package chain provides an authenticator that tries a list of credential providers in order
+// and uses the first one that works, so the same configuration runs on a laptop and in CI.
+package chain
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/azure"
+	"github.com/databricks/databricks-sql-go/auth/oauth/m2m"
+	"github.com/databricks/databricks-sql-go/auth/pat"
+	"github.com/pkg/errors"
+)
+
+// Provider is a source of credentials in the chain.
+type Provider interface {
+	// Name identifies the provider in error messages.
+	Name() string
+	// Authenticator returns an authenticator for the workspace at hostName, or nil
+	// when the provider's credentials are not available in this environment.
+	Authenticator(hostName string) (auth.Authenticator, error)
+}
+
+type envToken struct{}
+
+// EnvToken uses the personal access token in the DATABRICKS_TOKEN environment variable.
+func EnvToken() Provider { return envToken{} }
+
+func (envToken) Name() string { return "env token" }
+
+func (envToken) Authenticator(string) (auth.Authenticator, error) {
+	token := os.Getenv("DATABRICKS_TOKEN")
+	if token == "" {
+		return nil, nil
+	}
+	return &pat.PATAuth{AccessToken: token}, nil
+}
+
+type envClientCredentials struct{}
+
+// EnvClientCredentials uses OAuth M2M with the service principal in the
+// DATABRICKS_CLIENT_ID and DATABRICKS_CLIENT_SECRET environment variables.
+func EnvClientCredentials() Provider { return envClientCredentials{} }
+
+func (envClientCredentials) Name() string { return "env oauth-m2m" }
+
+func (envClientCredentials) Authenticator(hostName string) (auth.Authenticator, error) {
+	clientID, clientSecret := os.Getenv("DATABRICKS_CLIENT_ID"), os.Getenv("DATABRICKS_CLIENT_SECRET")
+	if clientID == "" || clientSecret == "" {
+		return nil, nil
+	}
+	return m2m.NewAuthenticator(clientID, clientSecret, hostName), nil
+}
+
+type azureCLI struct{}
+
+// AzureCLI uses tokens of the account signed in with `az login`.
+func AzureCLI() Provider { return azureCLI{} }
+
+func (azureCLI) Name() string { return "azure cli" }
+
+func (azureCLI) Authenticator(string) (auth.Authenticator, error) {
+	if _, err := lookPath("az"); err != nil {
+		return nil, nil
+	}
+	return azure.NewCLIAuthenticator(), nil
+}
+
+type databricksCLI struct{}
+
+// DatabricksCLI uses tokens of the profile signed in with `databricks auth login`.
+func DatabricksCLI() Provider { return databricksCLI{} }
+
+func (databricksCLI) Name() string { return "databricks cli" }
+
+func (databricksCLI) Authenticator(hostName string) (auth.Authenticator, error) {
+	if _, err := lookPath("databricks"); err != nil {
+		return nil, nil
+	}
+	return newDatabricksCLIAuthenticator(hostName), nil
+}
+
+// DefaultProviders returns the providers used with authType=default, in order:
+// DATABRICKS_TOKEN, DATABRICKS_CLIENT_ID/DATABRICKS_CLIENT_SECRET, the Azure CLI and the Databricks CLI.
+func DefaultProviders() []Provider {
+	return []Provider{
+		EnvToken(),
+		EnvClientCredentials(),
+		AzureCLI(),
+		DatabricksCLI(),
+	}
+}
+
+// NewAuthenticator returns an authenticator for the workspace at hostName that tries the providers in order.
+// With no providers DefaultProviders is used. On the first request every available provider is tried
+// until one authenticates the request, that provider is then used for all later requests.
+func NewAuthenticator(hostName string, providers ...Provider) auth.Authenticator {
+	if len(providers) == 0 {
+		providers = DefaultProviders()
+	}
+	return &authenticator{
+		hostName:  hostName,
+		providers: providers,
+	}
+}
+
+type authenticator struct {
+	hostName  string
+	providers []Provider
+
+	mx       sync.Mutex
+	selected auth.Authenticator
+}
+
+func (a *authenticator) Authenticate(r *http.Request) error {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	if a.selected != nil {
+		return a.selected.Authenticate(r)
+	}
+
+	var failures []string
+	for _, p := range a.providers {
+		authr, err := p.Authenticator(a.hostName)
+		if err == nil && authr == nil {
+			continue
+		}
+		if err == nil {
+			err = authr.Authenticate(r)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", p.Name(), err))
+			continue
+		}
+
+		a.selected = authr
+		return nil
+	}
+
+	if len(failures) == 0 {
+		return errors.New("credential chain: no credentials found, set DATABRICKS_TOKEN or sign in with a CLI")
+	}
+	return errors.Errorf("credential chain: no provider could authenticate (%s)", strings.Join(failures, "; "))
+}
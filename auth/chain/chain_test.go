@@ -0,0 +1,119 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/pat"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type authenticatorFunc func(r *http.Request) error
+
+func (f authenticatorFunc) Authenticate(r *http.Request) error { return f(r) }
+
+type staticProvider struct {
+	name  string
+	authr auth.Authenticator
+	err   error
+}
+
+func (p staticProvider) Name() string { return p.name }
+
+func (p staticProvider) Authenticator(string) (auth.Authenticator, error) { return p.authr, p.err }
+
+func newRequest() *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.cloud.databricks.com", nil)
+	return req
+}
+
+func TestAuthenticator(t *testing.T) {
+	t.Run("uses the first provider that authenticates", func(t *testing.T) {
+		var failing int
+		authr := NewAuthenticator("example.cloud.databricks.com",
+			staticProvider{"unavailable", nil, nil},
+			staticProvider{"broken", nil, errors.New("misconfigured")},
+			staticProvider{"failing", authenticatorFunc(func(r *http.Request) error {
+				failing++
+				return errors.New("not logged in")
+			}), nil},
+			staticProvider{"working", &pat.PATAuth{AccessToken: "second"}, nil},
+			staticProvider{"unused", &pat.PATAuth{AccessToken: "third"}, nil},
+		)
+
+		for i := 0; i < 2; i++ {
+			req := newRequest()
+			require.NoError(t, authr.Authenticate(req))
+			assert.Equal(t, "Bearer second", req.Header.Get("Authorization"))
+		}
+		// the selected provider is kept, failed providers are not retried
+		assert.Equal(t, 1, failing)
+	})
+
+	t.Run("reports every failure", func(t *testing.T) {
+		authr := NewAuthenticator("example.cloud.databricks.com",
+			staticProvider{"first", nil, errors.New("first failure")},
+			staticProvider{"second", nil, errors.New("second failure")},
+		)
+		err := authr.Authenticate(newRequest())
+		assert.ErrorContains(t, err, "first: first failure")
+		assert.ErrorContains(t, err, "second: second failure")
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		authr := NewAuthenticator("example.cloud.databricks.com", staticProvider{"unavailable", nil, nil})
+		assert.ErrorContains(t, authr.Authenticate(newRequest()), "no credentials found")
+	})
+}
+
+func TestDefaultProviders(t *testing.T) {
+	defaultLookPath, defaultRunCommand := lookPath, runCommand
+	defer func() { lookPath, runCommand = defaultLookPath, defaultRunCommand }()
+	lookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+
+	t.Run("env token", func(t *testing.T) {
+		t.Setenv("DATABRICKS_TOKEN", "env-token")
+		req := newRequest()
+		require.NoError(t, NewAuthenticator("example.cloud.databricks.com").Authenticate(req))
+		assert.Equal(t, "Bearer env-token", req.Header.Get("Authorization"))
+	})
+
+	t.Run("env client credentials", func(t *testing.T) {
+		t.Setenv("DATABRICKS_TOKEN", "")
+		t.Setenv("DATABRICKS_CLIENT_ID", "id")
+		t.Setenv("DATABRICKS_CLIENT_SECRET", "secret")
+		authr, err := EnvClientCredentials().Authenticator("example.cloud.databricks.com")
+		require.NoError(t, err)
+		assert.NotNil(t, authr)
+	})
+
+	t.Run("databricks cli", func(t *testing.T) {
+		t.Setenv("DATABRICKS_TOKEN", "")
+		t.Setenv("DATABRICKS_CLIENT_ID", "")
+		lookPath = func(file string) (string, error) {
+			if file == "databricks" {
+				return "/usr/local/bin/databricks", nil
+			}
+			return "", exec.ErrNotFound
+		}
+
+		var args []string
+		runCommand = func(ctx context.Context, name string, a ...string) ([]byte, error) {
+			args = append([]string{name}, a...)
+			expiry := time.Now().Add(time.Hour).Format(time.RFC3339)
+			return []byte(fmt.Sprintf(`{"access_token":"cli-token","token_type":"Bearer","expiry":%q}`, expiry)), nil
+		}
+
+		req := newRequest()
+		require.NoError(t, NewAuthenticator("example.cloud.databricks.com").Authenticate(req))
+		assert.Equal(t, "Bearer cli-token", req.Header.Get("Authorization"))
+		assert.Equal(t, []string{"databricks", "auth", "token", "--host", "https://example.cloud.databricks.com"}, args)
+	})
+}
@@ -0,0 +1,82 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/internal/expiry"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// max time for the Databricks CLI to return a token
+const cliTimeout = 30 * time.Second
+
+// variables so tests can replace the CLI
+var (
+	lookPath   = exec.LookPath
+	runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, name, args...).Output() // #nosec G204 -- arguments are fixed by the caller
+	}
+)
+
+func newDatabricksCLIAuthenticator(hostName string) auth.Authenticator {
+	return &databricksCLIAuthenticator{
+		tokenSource: oauth2.ReuseTokenSourceWithExpiry(nil, &databricksCLITokenSource{host: workspaceURL(hostName)}, expiry.Delta),
+	}
+}
+
+type databricksCLIAuthenticator struct {
+	tokenSource oauth2.TokenSource
+}
+
+func (a *databricksCLIAuthenticator) Authenticate(r *http.Request) error {
+	token, err := a.tokenSource.Token()
+	if err != nil {
+		return err
+	}
+	token.SetAuthHeader(r)
+	return nil
+}
+
+type databricksCLITokenSource struct {
+	host string
+}
+
+func (s *databricksCLITokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cliTimeout)
+	defer cancel()
+
+	out, err := runCommand(ctx, "databricks", "auth", "token", "--host", s.host)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, errors.Errorf("databricks cli: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, errors.Wrap(err, "databricks cli: failed to get token")
+	}
+
+	var token oauth2.Token
+	if err := json.Unmarshal(out, &token); err != nil {
+		return nil, errors.Wrap(err, "databricks cli: invalid token output")
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("databricks cli: token output is missing access_token")
+	}
+	return &token, nil
+}
+
+// workspaceURL returns the base URL of the workspace, adding the https scheme if missing.
+func workspaceURL(hostName string) string {
+	hostName = strings.TrimSuffix(hostName, "/")
+	if strings.HasPrefix(hostName, "https://") || strings.HasPrefix(hostName, "http://") {
+		return hostName
+	}
+	return fmt.Sprintf("https://%s", hostName)
+}
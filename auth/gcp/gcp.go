@@ -0,0 +1,241 @@
+// Package gcp provides authenticators for Databricks on Google Cloud.
+//
+// Databricks on GCP accepts a Google ID token issued for the workspace URL, along with a
+// Google access token of the same service account that is sent in a separate header.
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/internal/expiry"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+// the header carrying the service account access token next to the ID token
+const accessTokenHeader = "X-Databricks-GCP-SA-Access-Token"
+
+// the default token endpoint for service account keys that don't name one
+const defaultTokenURL = "https://oauth2.googleapis.com/token"
+
+var cloudPlatformScopes = []string{"https://www.googleapis.com/auth/cloud-platform"}
+
+// the GCE metadata server, a variable so tests can point it at a local server
+var metadataHost = "http://metadata.google.internal"
+
+// max time for a single request to the metadata server
+const metadataTimeout = 10 * time.Second
+
+// NewAuthenticator returns an authenticator for the workspace at hostName.
+// The service account key at credentialsFile is used when set, otherwise the key named by the
+// GOOGLE_APPLICATION_CREDENTIALS environment variable, otherwise the ambient identity of the
+// GCE VM or GKE workload the program runs on.
+func NewAuthenticator(hostName, credentialsFile string) (auth.Authenticator, error) {
+	if credentialsFile == "" {
+		credentialsFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if credentialsFile == "" {
+		return NewWorkloadIdentityAuthenticator(hostName)
+	}
+
+	keyJSON, err := os.ReadFile(credentialsFile) // #nosec G304 -- path is provided by the user
+	if err != nil {
+		return nil, errors.Wrap(err, "gcp: unable to read credentials file")
+	}
+	return NewServiceAccountAuthenticator(hostName, keyJSON)
+}
+
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// NewServiceAccountAuthenticator returns an authenticator for the workspace at hostName
+// that signs token requests with a service account JSON key.
+func NewServiceAccountAuthenticator(hostName string, keyJSON []byte) (auth.Authenticator, error) {
+	if hostName == "" {
+		return nil, errors.New("gcp: missing host name")
+	}
+
+	var key serviceAccountKey
+	if err := json.Unmarshal(keyJSON, &key); err != nil {
+		return nil, errors.Wrap(err, "gcp: invalid service account key")
+	}
+	if key.Type != "service_account" {
+		return nil, errors.Errorf("gcp: unsupported credentials type %q", key.Type)
+	}
+	if key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, errors.New("gcp: service account key is missing client_email or private_key")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = defaultTokenURL
+	}
+
+	idConfig := &jwt.Config{
+		Email:         key.ClientEmail,
+		PrivateKey:    []byte(key.PrivateKey),
+		PrivateKeyID:  key.PrivateKeyID,
+		TokenURL:      key.TokenURI,
+		PrivateClaims: map[string]interface{}{"target_audience": workspaceURL(hostName)},
+		UseIDToken:    true,
+	}
+	accessConfig := &jwt.Config{
+		Email:        key.ClientEmail,
+		PrivateKey:   []byte(key.PrivateKey),
+		PrivateKeyID: key.PrivateKeyID,
+		TokenURL:     key.TokenURI,
+		Scopes:       cloudPlatformScopes,
+	}
+
+	return &authenticator{
+		idTokens:     oauth2.ReuseTokenSourceWithExpiry(nil, idConfig.TokenSource(context.Background()), expiry.Delta),
+		accessTokens: oauth2.ReuseTokenSourceWithExpiry(nil, accessConfig.TokenSource(context.Background()), expiry.Delta),
+	}, nil
+}
+
+// NewWorkloadIdentityAuthenticator returns an authenticator for the workspace at hostName
+// that uses the service account attached to the GCE VM or GKE workload, read from the metadata server.
+func NewWorkloadIdentityAuthenticator(hostName string) (auth.Authenticator, error) {
+	if hostName == "" {
+		return nil, errors.New("gcp: missing host name")
+	}
+
+	client := &http.Client{Timeout: metadataTimeout}
+	return &authenticator{
+		idTokens:     oauth2.ReuseTokenSourceWithExpiry(nil, &metadataIDTokenSource{client: client, audience: workspaceURL(hostName)}, expiry.Delta),
+		accessTokens: oauth2.ReuseTokenSourceWithExpiry(nil, &metadataAccessTokenSource{client: client}, expiry.Delta),
+	}, nil
+}
+
+type authenticator struct {
+	idTokens     oauth2.TokenSource
+	accessTokens oauth2.TokenSource
+}
+
+func (a *authenticator) Authenticate(r *http.Request) error {
+	idToken, err := a.idTokens.Token()
+	if err != nil {
+		return errors.Wrap(err, "gcp: failed to get ID token")
+	}
+	accessToken, err := a.accessTokens.Token()
+	if err != nil {
+		return errors.Wrap(err, "gcp: failed to get access token")
+	}
+
+	r.Header.Set("Authorization", "Bearer "+idToken.AccessToken)
+	r.Header.Set(accessTokenHeader, accessToken.AccessToken)
+	return nil
+}
+
+type metadataIDTokenSource struct {
+	client   *http.Client
+	audience string
+}
+
+func (s *metadataIDTokenSource) Token() (*oauth2.Token, error) {
+	query := url.Values{"audience": {s.audience}, "format": {"full"}}
+	body, err := getMetadata(s.client, "instance/service-accounts/default/identity?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	idToken := strings.TrimSpace(string(body))
+	expiry, err := jwtExpiry(idToken)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{AccessToken: idToken, TokenType: "Bearer", Expiry: expiry}, nil
+}
+
+type metadataAccessTokenSource struct {
+	client *http.Client
+}
+
+func (s *metadataAccessTokenSource) Token() (*oauth2.Token, error) {
+	body, err := getMetadata(s.client, "instance/service-accounts/default/token")
+	if err != nil {
+		return nil, err
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &t); err != nil {
+		return nil, errors.Wrap(err, "gcp: invalid metadata token response")
+	}
+	if t.AccessToken == "" {
+		return nil, errors.New("gcp: metadata token response is missing access_token")
+	}
+	return &oauth2.Token{
+		AccessToken: t.AccessToken,
+		TokenType:   t.TokenType,
+		Expiry:      time.Now().Add(time.Duration(t.ExpiresIn) * time.Second),
+	}, nil
+}
+
+func getMetadata(client *http.Client, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, metadataHost+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "gcp: invalid metadata request")
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "gcp: metadata server is not reachable")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("gcp: metadata server returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrap(err, "gcp: failed to read metadata response")
+	}
+	return body, nil
+}
+
+// jwtExpiry reads the exp claim of a JWT without verifying it
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("gcp: ID token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "gcp: invalid ID token payload")
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, errors.Wrap(err, "gcp: invalid ID token claims")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// workspaceURL returns the base URL of the workspace, which is the audience of the ID token
+func workspaceURL(hostName string) string {
+	hostName = strings.TrimSuffix(hostName, "/")
+	if strings.HasPrefix(hostName, "https://") || strings.HasPrefix(hostName, "http://") {
+		return hostName
+	}
+	return fmt.Sprintf("https://%s", hostName)
+}
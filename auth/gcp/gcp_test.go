@@ -0,0 +1,121 @@
+package gcp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testIDTokenExpiry = time.Now().Add(time.Hour).Unix()
+
+func testIDToken(audience string) string {
+	enc := base64.RawURLEncoding
+	claims := fmt.Sprintf(`{"aud":%q,"exp":%d}`, audience, testIDTokenExpiry)
+	return enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".c2ln"
+}
+
+func TestServiceAccountAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var assertions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assertion := r.PostForm.Get("assertion")
+		assertions = append(assertions, assertion)
+
+		payload, _ := base64.RawURLEncoding.DecodeString(strings.Split(assertion, ".")[1])
+		var claims map[string]any
+		_ = json.Unmarshal(payload, &claims)
+
+		w.Header().Set("Content-Type", "application/json")
+		if aud, ok := claims["target_audience"]; ok {
+			_ = json.NewEncoder(w).Encode(map[string]any{"id_token": testIDToken(aud.(string))})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "sa-access-token", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	keyJSON, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "sa@project.iam.gserviceaccount.com",
+		"private_key":  string(keyPEM),
+		"token_uri":    server.URL,
+	})
+
+	t.Run("key file", func(t *testing.T) {
+		assertions = nil
+		path := filepath.Join(t.TempDir(), "key.json")
+		require.NoError(t, os.WriteFile(path, keyJSON, 0600))
+
+		authr, err := NewAuthenticator("123.4.gcp.databricks.com", path)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest(http.MethodPost, "https://123.4.gcp.databricks.com", nil)
+			require.NoError(t, authr.Authenticate(req))
+			assert.Equal(t, "Bearer "+testIDToken("https://123.4.gcp.databricks.com"), req.Header.Get("Authorization"))
+			assert.Equal(t, "sa-access-token", req.Header.Get(accessTokenHeader))
+		}
+		// one request for each token, both are reused until they expire
+		assert.Len(t, assertions, 2)
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		_, err := NewServiceAccountAuthenticator("123.4.gcp.databricks.com", []byte(`{"type":"authorized_user"}`))
+		assert.Error(t, err)
+		_, err = NewServiceAccountAuthenticator("123.4.gcp.databricks.com", []byte(`{"type":"service_account"}`))
+		assert.Error(t, err)
+		_, err = NewServiceAccountAuthenticator("", keyJSON)
+		assert.Error(t, err)
+		_, err = NewAuthenticator("123.4.gcp.databricks.com", filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+}
+
+func TestWorkloadIdentityAuthenticator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/identity":
+			fmt.Fprint(w, testIDToken(r.URL.Query().Get("audience")))
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			fmt.Fprint(w, `{"access_token":"vm-access-token","token_type":"Bearer","expires_in":3600}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	defaultHost := metadataHost
+	metadataHost = server.URL
+	defer func() { metadataHost = defaultHost }()
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	authr, err := NewAuthenticator("123.4.gcp.databricks.com", "")
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodPost, "https://123.4.gcp.databricks.com", nil)
+	require.NoError(t, authr.Authenticate(req))
+	assert.Equal(t, "Bearer "+testIDToken("https://123.4.gcp.databricks.com"), req.Header.Get("Authorization"))
+	assert.Equal(t, "vm-access-token", req.Header.Get(accessTokenHeader))
+}
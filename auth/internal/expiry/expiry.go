@@ -0,0 +1,7 @@
+// Package expiry holds the token expiry settings shared by the authenticators.
+package expiry
+
+import "time"
+
+// Delta is how long before they expire tokens are refreshed, so in-flight requests don't use an expired token
+const Delta = 40 * time.Second
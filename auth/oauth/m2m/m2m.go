@@ -0,0 +1,70 @@
+package m2m
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/internal/expiry"
+	"github.com/databricks/databricks-sql-go/auth/oauth"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+var defaultScopes = []string{"all-apis"}
+
+// NewAuthenticator returns an authenticator for a service principal using the OAuth
+// client credentials grant against the workspace's token endpoint.
+// Tokens are requested on first use and refreshed automatically before they expire.
+func NewAuthenticator(clientID, clientSecret, hostName string) auth.Authenticator {
+	return &authClient{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		hostName:     hostName,
+		scopes:       defaultScopes,
+	}
+}
+
+type authClient struct {
+	clientID     string
+	clientSecret string
+	hostName     string
+	scopes       []string
+	tokenSource  oauth2.TokenSource
+	mx           sync.Mutex
+}
+
+// Authenticate sets the bearer token on the request, fetching a new token if needed.
+func (c *authClient) Authenticate(r *http.Request) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.tokenSource == nil {
+		if c.clientID == "" || c.clientSecret == "" {
+			return errors.New("oauth m2m: missing client id or client secret")
+		}
+
+		endpoint, err := oauth.GetEndpoint(r.Context(), c.hostName)
+		if err != nil {
+			return err
+		}
+
+		config := clientcredentials.Config{
+			ClientID:     c.clientID,
+			ClientSecret: c.clientSecret,
+			TokenURL:     endpoint.TokenURL,
+			Scopes:       c.scopes,
+		}
+		// the token source outlives the request so it must not use the request context
+		c.tokenSource = oauth2.ReuseTokenSourceWithExpiry(nil, config.TokenSource(context.Background()), expiry.Delta)
+	}
+
+	token, err := c.tokenSource.Token()
+	if err != nil {
+		return errors.Wrap(err, "oauth m2m: failed to get token")
+	}
+	token.SetAuthHeader(r)
+	return nil
+}
@@ -0,0 +1,61 @@
+package m2m
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestM2MAuthenticator(t *testing.T) {
+	var tokenRequests int
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/oidc/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"authorization_endpoint": server.URL + "/oidc/v1/authorize",
+			"token_endpoint":         server.URL + "/oidc/v1/token",
+		})
+	})
+	mux.HandleFunc("/oidc/v1/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "all-apis", r.Form.Get("scope"))
+		clientID, clientSecret, _ := r.BasicAuth()
+		assert.Equal(t, "id", clientID)
+		assert.Equal(t, "secret", clientSecret)
+
+		w.Header().Set("Content-Type", "application/json")
+		// a token that expires within the refresh window must be refreshed on every use
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", tokenRequests),
+			"token_type":   "Bearer",
+			"expires_in":   30,
+		})
+	})
+
+	t.Run("sets bearer token and refreshes before expiry", func(t *testing.T) {
+		authr := NewAuthenticator("id", "secret", server.URL)
+
+		req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+		require.NoError(t, authr.Authenticate(req))
+		assert.Equal(t, "Bearer token-1", req.Header.Get("Authorization"))
+
+		req, _ = http.NewRequest(http.MethodPost, server.URL, nil)
+		require.NoError(t, authr.Authenticate(req))
+		assert.Equal(t, "Bearer token-2", req.Header.Get("Authorization"))
+	})
+
+	t.Run("fails without credentials", func(t *testing.T) {
+		authr := NewAuthenticator("", "", server.URL)
+		req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+		assert.Error(t, authr.Authenticate(req))
+	})
+}
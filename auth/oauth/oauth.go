@@ -0,0 +1,66 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// the path of the OIDC discovery document relative to the workspace host
+const discoveryPath = "/oidc/.well-known/oauth-authorization-server"
+
+type oidcMetadata struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// GetEndpoint returns the authorization and token endpoints of the workspace
+// by reading the workspace's OIDC discovery document.
+func GetEndpoint(ctx context.Context, hostName string) (oauth2.Endpoint, error) {
+	if hostName == "" {
+		return oauth2.Endpoint{}, errors.New("oauth: missing host name")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, workspaceURL(hostName)+discoveryPath, nil)
+	if err != nil {
+		return oauth2.Endpoint{}, errors.Wrap(err, "oauth: invalid discovery request")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return oauth2.Endpoint{}, errors.Wrap(err, "oauth: failed to fetch OIDC discovery document")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return oauth2.Endpoint{}, errors.Errorf("oauth: OIDC discovery returned status %d", resp.StatusCode)
+	}
+
+	var md oidcMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&md); err != nil {
+		return oauth2.Endpoint{}, errors.Wrap(err, "oauth: invalid OIDC discovery document")
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" {
+		return oauth2.Endpoint{}, errors.New("oauth: OIDC discovery document is missing endpoints")
+	}
+
+	return oauth2.Endpoint{
+		AuthURL:   md.AuthorizationEndpoint,
+		TokenURL:  md.TokenEndpoint,
+		AuthStyle: oauth2.AuthStyleInParams,
+	}, nil
+}
+
+// workspaceURL returns the base URL of the workspace, adding the https scheme if missing.
+func workspaceURL(hostName string) string {
+	hostName = strings.TrimSuffix(hostName, "/")
+	if strings.HasPrefix(hostName, "https://") || strings.HasPrefix(hostName, "http://") {
+		return hostName
+	}
+	return fmt.Sprintf("https://%s", hostName)
+}
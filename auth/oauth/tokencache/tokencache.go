@@ -0,0 +1,196 @@
+// Package tokencache persists OAuth tokens between process runs so interactive
+// applications don't need a new browser login every time they start.
+package tokencache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// Cache stores tokens by key, e.g. the workspace host and OAuth client id.
+type Cache interface {
+	// Load returns the token stored for key, or nil if there is none.
+	Load(key string) (*oauth2.Token, error)
+	// Store saves token for key, replacing any previous token.
+	Store(key string, token *oauth2.Token) error
+}
+
+const (
+	// how long to wait for another process to release the lock, longer than staleLockAge so a lock left over by
+	// a crashed process is removed before giving up
+	lockTimeout = 15 * time.Second
+	// locks older than this are left over by a crashed process and are removed, a lock is only held while the
+	// cache file is read and written
+	staleLockAge = 10 * time.Second
+	lockRetry    = 50 * time.Millisecond
+)
+
+// DefaultPath returns the cache file used when no path is configured,
+// in the user's cache directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.Wrap(err, "token cache: unable to find user cache directory")
+	}
+	return filepath.Join(dir, "databricks-sql-go", "oauth-tokens.json"), nil
+}
+
+// NewFileCache returns a cache that keeps tokens in the file at path, readable only by the current user.
+// An empty path uses DefaultPath. When encryptionKey is set the file is encrypted with AES-GCM,
+// the key must be 16, 24 or 32 bytes long.
+// Access to the file is serialized between processes with a lock file next to it.
+func NewFileCache(path string, encryptionKey []byte) (Cache, error) {
+	if path == "" {
+		var err error
+		if path, err = DefaultPath(); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &fileCache{path: path}
+	if len(encryptionKey) > 0 {
+		block, err := aes.NewCipher(encryptionKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "token cache: invalid encryption key")
+		}
+		if c.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, errors.Wrap(err, "token cache: invalid encryption key")
+		}
+	}
+	return c, nil
+}
+
+type fileCache struct {
+	path string
+	aead cipher.AEAD
+}
+
+func (c *fileCache) Load(key string) (*oauth2.Token, error) {
+	var token *oauth2.Token
+	err := c.withLock(func() error {
+		tokens, err := c.read()
+		if err != nil {
+			return err
+		}
+		token = tokens[key]
+		return nil
+	})
+	return token, err
+}
+
+func (c *fileCache) Store(key string, token *oauth2.Token) error {
+	return c.withLock(func() error {
+		tokens, err := c.read()
+		if err != nil {
+			return err
+		}
+		tokens[key] = token
+		return c.write(tokens)
+	})
+}
+
+// withLock runs fn while holding the lock file, which is created exclusively so only one process can own it
+func (c *fileCache) withLock(fn func() error) error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return errors.Wrap(err, "token cache: unable to create cache directory")
+	}
+
+	lockPath := c.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			break
+		}
+		if !os.IsExist(err) {
+			return errors.Wrap(err, "token cache: unable to create lock file")
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("token cache: timed out waiting for lock %s", lockPath)
+		}
+		time.Sleep(lockRetry)
+	}
+	defer os.Remove(lockPath)
+
+	return fn()
+}
+
+func (c *fileCache) read() (map[string]*oauth2.Token, error) {
+	tokens := map[string]*oauth2.Token{}
+
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "token cache: unable to read cache file")
+	}
+	if len(data) == 0 {
+		return tokens, nil
+	}
+
+	if c.aead != nil {
+		nonceSize := c.aead.NonceSize()
+		if len(data) < nonceSize {
+			return nil, errors.New("token cache: cache file is corrupted")
+		}
+		data, err = c.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "token cache: unable to decrypt cache file")
+		}
+	}
+
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, errors.Wrap(err, "token cache: invalid cache file")
+	}
+	return tokens, nil
+}
+
+// write replaces the cache file atomically so a crash never leaves a partial file behind
+func (c *fileCache) write(tokens map[string]*oauth2.Token) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return errors.Wrap(err, "token cache: unable to encode tokens")
+	}
+
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return errors.Wrap(err, "token cache: unable to generate nonce")
+		}
+		data = c.aead.Seal(nonce, nonce, data, nil)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "token cache: unable to write cache file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "token cache: unable to set cache file permissions")
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "token cache: unable to write cache file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "token cache: unable to write cache file")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), c.path), "token cache: unable to write cache file")
+}
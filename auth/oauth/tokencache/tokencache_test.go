@@ -0,0 +1,102 @@
+package tokencache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestFileCache(t *testing.T) {
+	token := &oauth2.Token{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour).Round(time.Second),
+	}
+
+	t.Run("stores and loads tokens", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "tokens.json")
+		cache, err := NewFileCache(path, nil)
+		require.NoError(t, err)
+
+		loaded, err := cache.Load("host")
+		require.NoError(t, err)
+		assert.Nil(t, loaded)
+
+		require.NoError(t, cache.Store("host", token))
+		require.NoError(t, cache.Store("other-host", &oauth2.Token{AccessToken: "other"}))
+
+		// a new cache for the same file, as in a new process
+		cache, err = NewFileCache(path, nil)
+		require.NoError(t, err)
+		loaded, err = cache.Load("host")
+		require.NoError(t, err)
+		require.NotNil(t, loaded)
+		assert.Equal(t, token.AccessToken, loaded.AccessToken)
+		assert.Equal(t, token.RefreshToken, loaded.RefreshToken)
+		assert.True(t, token.Expiry.Equal(loaded.Expiry))
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		}
+		_, err = os.Stat(path + ".lock")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tokens.json")
+		key := bytes.Repeat([]byte{7}, 32)
+		cache, err := NewFileCache(path, key)
+		require.NoError(t, err)
+		require.NoError(t, cache.Store("host", token))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "refresh")
+
+		loaded, err := cache.Load("host")
+		require.NoError(t, err)
+		assert.Equal(t, "refresh", loaded.RefreshToken)
+
+		wrongKey, err := NewFileCache(path, bytes.Repeat([]byte{8}, 32))
+		require.NoError(t, err)
+		_, err = wrongKey.Load("host")
+		assert.Error(t, err)
+
+		_, err = NewFileCache(path, []byte("short"))
+		assert.Error(t, err)
+	})
+
+	t.Run("stale lock is removed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tokens.json")
+		require.NoError(t, os.WriteFile(path+".lock", nil, 0600))
+		old := time.Now().Add(-time.Minute)
+		require.NoError(t, os.Chtimes(path+".lock", old, old))
+
+		cache, err := NewFileCache(path, nil)
+		require.NoError(t, err)
+		assert.NoError(t, cache.Store("host", token))
+	})
+
+	t.Run("lock becoming stale while waiting is removed", func(t *testing.T) {
+		assert.Greater(t, lockTimeout, staleLockAge, "a lock left over by a crashed process becomes stale before the wait times out")
+
+		path := filepath.Join(t.TempDir(), "tokens.json")
+		require.NoError(t, os.WriteFile(path+".lock", nil, 0600))
+		recent := time.Now().Add(-staleLockAge + 200*time.Millisecond)
+		require.NoError(t, os.Chtimes(path+".lock", recent, recent))
+
+		cache, err := NewFileCache(path, nil)
+		require.NoError(t, err)
+		assert.NoError(t, cache.Store("host", token))
+	})
+}
@@ -0,0 +1,359 @@
+package u2m
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/internal/expiry"
+	"github.com/databricks/databricks-sql-go/auth/oauth"
+	"github.com/databricks/databricks-sql-go/auth/oauth/tokencache"
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// public client registered in every Databricks workspace for the SQL connectors
+	clientID     = "databricks-sql-connector"
+	listenerAddr = "localhost:8030"
+	redirectURL  = "http://localhost:8030"
+)
+
+var defaultScopes = []string{"sql", "offline_access"}
+
+// Option configures the authenticator returned by NewAuthenticator.
+type Option func(*u2mAuthenticator)
+
+// WithTokenCache persists tokens in cache so a login is reused by later processes
+// until its refresh token expires.
+func WithTokenCache(cache tokencache.Cache) Option {
+	return func(a *u2mAuthenticator) {
+		a.cache = cache
+	}
+}
+
+// WithLoginURLHandler calls fn with the URL of each browser login, in addition to opening the system browser,
+// e.g. to print it in a terminal or show it in the UI of an application where the browser can't be opened. By
+// default the URL is logged at info level, or at warning level when the browser can't be opened.
+func WithLoginURLHandler(fn func(url string)) Option {
+	return func(a *u2mAuthenticator) {
+		a.loginURLHandler = fn
+	}
+}
+
+// NewAuthenticator returns an authenticator that signs the user in through the system browser
+// using the OAuth authorization code flow with PKCE.
+// The timeout bounds how long to wait for the user to complete the login in the browser.
+// Tokens are kept in memory, or in a token cache when WithTokenCache is given, and refreshed automatically.
+func NewAuthenticator(hostName string, timeout time.Duration, opts ...Option) (auth.Authenticator, error) {
+	if hostName == "" {
+		return nil, errors.New("oauth u2m: missing host name")
+	}
+
+	a := &u2mAuthenticator{
+		clientID: clientID,
+		hostName: hostName,
+		timeout:  timeout,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a, nil
+}
+
+type u2mAuthenticator struct {
+	clientID    string
+	hostName    string
+	timeout     time.Duration
+	cache       tokencache.Cache
+	tokenSource oauth2.TokenSource
+	mx          sync.Mutex
+	// closed when the browser login in progress ends, nil when there is none
+	loggingIn chan struct{}
+	// shows the URL of a login to the user, nil logs it
+	loginURLHandler func(url string)
+}
+
+// Authenticate sets the bearer token on the request, starting a browser login if there
+// is no token yet or the current one can no longer be refreshed. The requests authenticated
+// during a browser login wait for it instead of starting their own.
+func (a *u2mAuthenticator) Authenticate(r *http.Request) error {
+	a.mx.Lock()
+	for {
+		if a.tokenSource == nil && a.cache != nil {
+			a.tokenSource = a.cachedTokenSource(r.Context())
+		}
+
+		if a.tokenSource != nil {
+			token, err := a.tokenSource.Token()
+			if err == nil {
+				a.mx.Unlock()
+				token.SetAuthHeader(r)
+				return nil
+			}
+			logger.Info().Msgf("oauth u2m: token refresh failed, a new login is required: %s", err)
+		}
+		if a.loggingIn == nil {
+			break
+		}
+
+		// the lock isn't held while the user signs in
+		loggingIn := a.loggingIn
+		a.mx.Unlock()
+		select {
+		case <-loggingIn:
+		case <-r.Context().Done():
+			return errors.Wrap(r.Context().Err(), "oauth u2m: canceled waiting for browser login")
+		}
+		a.mx.Lock()
+	}
+	loggingIn := make(chan struct{})
+	a.loggingIn = loggingIn
+	a.mx.Unlock()
+
+	tokenSource, err := a.login(r.Context())
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	a.loggingIn = nil
+	close(loggingIn)
+	if err != nil {
+		return err
+	}
+	a.tokenSource = a.withCache(tokenSource)
+
+	token, err := a.tokenSource.Token()
+	if err != nil {
+		return errors.Wrap(err, "oauth u2m: failed to get token")
+	}
+	token.SetAuthHeader(r)
+	return nil
+}
+
+type authResult struct {
+	code string
+	err  error
+}
+
+func (a *u2mAuthenticator) oauthConfig(ctx context.Context) (*oauth2.Config, error) {
+	endpoint, err := oauth.GetEndpoint(ctx, a.hostName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Config{
+		ClientID:    a.clientID,
+		Endpoint:    endpoint,
+		RedirectURL: redirectURL,
+		Scopes:      defaultScopes,
+	}, nil
+}
+
+// login runs the authorization code flow and returns a refreshing token source
+func (a *u2mAuthenticator) login(ctx context.Context) (oauth2.TokenSource, error) {
+	config, err := a.oauthConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomString(16)
+	if err != nil {
+		return nil, errors.Wrap(err, "oauth u2m: failed to generate state")
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	listener, err := net.Listen("tcp", listenerAddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "oauth u2m: unable to listen on %s", listenerAddr)
+	}
+
+	resultCh := make(chan authResult, 1)
+	server := &http.Server{
+		Handler:           callbackHandler(state, resultCh),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Close()
+
+	authURL := config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+	event := logger.Info()
+	if err := openBrowser(authURL); err != nil {
+		logger.Warn().Msgf("oauth u2m: unable to open browser: %s", err)
+		event = logger.Warn()
+	}
+	if a.loginURLHandler != nil {
+		a.loginURLHandler(authURL)
+	} else {
+		event.Msgf("oauth u2m: open the following URL in a browser to sign in to %s: %s", a.hostName, authURL)
+	}
+
+	timeoutCtx := ctx
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		timeoutCtx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+
+	var result authResult
+	select {
+	case result = <-resultCh:
+	case <-timeoutCtx.Done():
+		return nil, errors.Wrap(timeoutCtx.Err(), "oauth u2m: timed out waiting for browser login")
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	token, err := config.Exchange(ctx, result.code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, errors.Wrap(err, "oauth u2m: failed to exchange authorization code")
+	}
+
+	return newTokenSource(config, token), nil
+}
+
+func (a *u2mAuthenticator) cacheKey() string {
+	return a.hostName + ":" + a.clientID
+}
+
+// cachedTokenSource returns a refreshing token source for the cached token, or nil if there is none
+func (a *u2mAuthenticator) cachedTokenSource(ctx context.Context) oauth2.TokenSource {
+	token, err := a.cache.Load(a.cacheKey())
+	if err != nil {
+		logger.Warn().Msgf("oauth u2m: unable to load cached token: %s", err)
+		return nil
+	}
+	if token == nil {
+		return nil
+	}
+
+	config, err := a.oauthConfig(ctx)
+	if err != nil {
+		logger.Warn().Msgf("oauth u2m: unable to use cached token: %s", err)
+		return nil
+	}
+	return &cachingTokenSource{
+		src:   newTokenSource(config, token),
+		cache: a.cache,
+		key:   a.cacheKey(),
+		last:  token.AccessToken,
+	}
+}
+
+// newTokenSource returns a token source reusing token until it is about to expire, then refreshing it
+func newTokenSource(config *oauth2.Config, token *oauth2.Token) oauth2.TokenSource {
+	return oauth2.ReuseTokenSourceWithExpiry(token, &refreshingTokenSource{config: config, token: token}, expiry.Delta)
+}
+
+// refreshingTokenSource refreshes the token at each call. The token source of oauth2.Config reuses its token
+// until 10 seconds before it expires, which would defeat the expiry delta of newTokenSource.
+type refreshingTokenSource struct {
+	config *oauth2.Config
+	token  *oauth2.Token // last token, whose refresh token is used
+}
+
+func (s *refreshingTokenSource) Token() (*oauth2.Token, error) {
+	// the token source outlives the request that triggered the login,
+	// so it must not hold on to the request context
+	token, err := s.config.TokenSource(context.Background(), &oauth2.Token{RefreshToken: s.token.RefreshToken}).Token()
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+// withCache stores every new token returned by src in the token cache
+func (a *u2mAuthenticator) withCache(src oauth2.TokenSource) oauth2.TokenSource {
+	if a.cache == nil {
+		return src
+	}
+	return &cachingTokenSource{src: src, cache: a.cache, key: a.cacheKey()}
+}
+
+type cachingTokenSource struct {
+	src   oauth2.TokenSource
+	cache tokencache.Cache
+	key   string
+	// the access token last written to the cache
+	last string
+}
+
+func (s *cachingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken != s.last {
+		if err := s.cache.Store(s.key, token); err != nil {
+			logger.Warn().Msgf("oauth u2m: unable to cache token: %s", err)
+		}
+		s.last = token.AccessToken
+	}
+	return token, nil
+}
+
+// callbackHandler sends the result of the authorization response redirected to the listener to resultCh. The
+// requests to other paths, e.g. of the favicon, and without a code or an error, e.g. of a probe, are ignored.
+func callbackHandler(state string, resultCh chan<- authResult) http.Handler {
+	var once sync.Once
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/" || (query.Get("code") == "" && query.Get("error") == "") {
+			http.NotFound(w, r)
+			return
+		}
+
+		var result authResult
+		switch {
+		case query.Get("error") != "":
+			result.err = errors.Errorf("oauth u2m: login failed: %s %s", query.Get("error"), query.Get("error_description"))
+		case query.Get("state") != state:
+			result.err = errors.New("oauth u2m: state mismatch in authorization response")
+		default:
+			result.code = query.Get("code")
+		}
+
+		if result.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintln(w, "Login failed. You can close this window.")
+		} else {
+			_, _ = fmt.Fprintln(w, "Login successful. You can close this window.")
+		}
+
+		once.Do(func() { resultCh <- result })
+	})
+}
+
+// openBrowser opens url in the system browser
+var openBrowser = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,198 @@
+package u2m
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/oauth/tokencache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestAuthenticatorTokenCache(t *testing.T) {
+	var refreshes int
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oidc/.well-known/oauth-authorization-server":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"authorization_endpoint": server.URL + "/oidc/v1/authorize",
+				"token_endpoint":         server.URL + "/oidc/v1/token",
+			})
+		case "/oidc/v1/token":
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+			assert.Equal(t, "cached-refresh", r.PostForm.Get("refresh_token"))
+			refreshes++
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token":  "refreshed-access",
+				"refresh_token": "new-refresh",
+				"token_type":    "Bearer",
+				"expires_in":    3600,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := tokencache.NewFileCache(filepath.Join(t.TempDir(), "tokens.json"), nil)
+	require.NoError(t, err)
+	key := server.URL + ":" + clientID
+
+	t.Run("valid cached token is used without login", func(t *testing.T) {
+		require.NoError(t, cache.Store(key, &oauth2.Token{
+			AccessToken:  "cached-access",
+			RefreshToken: "cached-refresh",
+			TokenType:    "Bearer",
+			Expiry:       time.Now().Add(time.Hour),
+		}))
+
+		authr, err := NewAuthenticator(server.URL, time.Second, WithTokenCache(cache))
+		require.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+		require.NoError(t, authr.Authenticate(req))
+		assert.Equal(t, "Bearer cached-access", req.Header.Get("Authorization"))
+		assert.Equal(t, 0, refreshes)
+	})
+
+	t.Run("expired cached token is refreshed and stored", func(t *testing.T) {
+		require.NoError(t, cache.Store(key, &oauth2.Token{
+			AccessToken:  "expired-access",
+			RefreshToken: "cached-refresh",
+			TokenType:    "Bearer",
+			Expiry:       time.Now().Add(-time.Hour),
+		}))
+
+		authr, err := NewAuthenticator(server.URL, time.Second, WithTokenCache(cache))
+		require.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+		require.NoError(t, authr.Authenticate(req))
+		assert.Equal(t, "Bearer refreshed-access", req.Header.Get("Authorization"))
+		assert.Equal(t, 1, refreshes)
+
+		stored, err := cache.Load(key)
+		require.NoError(t, err)
+		assert.Equal(t, "refreshed-access", stored.AccessToken)
+		assert.Equal(t, "new-refresh", stored.RefreshToken)
+	})
+
+	t.Run("cached token expiring within the expiry delta is refreshed", func(t *testing.T) {
+		require.NoError(t, cache.Store(key, &oauth2.Token{
+			AccessToken:  "expiring-access",
+			RefreshToken: "cached-refresh",
+			TokenType:    "Bearer",
+			Expiry:       time.Now().Add(30 * time.Second),
+		}))
+
+		authr, err := NewAuthenticator(server.URL, time.Second, WithTokenCache(cache))
+		require.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+		require.NoError(t, authr.Authenticate(req))
+		assert.Equal(t, "Bearer refreshed-access", req.Header.Get("Authorization"))
+		assert.Equal(t, 2, refreshes)
+	})
+}
+
+func TestAuthenticatorLogin(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oidc/.well-known/oauth-authorization-server":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"authorization_endpoint": server.URL + "/oidc/v1/authorize",
+				"token_endpoint":         server.URL + "/oidc/v1/token",
+			})
+		case "/oidc/v1/token":
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "authorization_code", r.PostForm.Get("grant_type"))
+			assert.Equal(t, "login-code", r.PostForm.Get("code"))
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token": "login-access",
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	defer func(open func(string) error) { openBrowser = open }(openBrowser)
+	openBrowser = func(url string) error { return errors.New("no browser") }
+
+	var loginURL string
+	var logins int
+	var authr auth.Authenticator
+	waiting := make(chan error, 1)
+	authr, err := NewAuthenticator(server.URL, 5*time.Second, WithLoginURLHandler(func(url string) {
+		loginURL = url
+		logins++
+
+		// the other requests wait for the login without the lock of the authenticator
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, _ := http.NewRequestWithContext(canceled, http.MethodPost, server.URL, nil)
+		assert.ErrorIs(t, authr.Authenticate(req), context.Canceled)
+		go func() {
+			req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+			err := authr.Authenticate(req)
+			if err == nil && req.Header.Get("Authorization") != "Bearer login-access" {
+				err = errors.New("unexpected token")
+			}
+			waiting <- err
+		}()
+
+		// the user signs in and is redirected to the listener of the authenticator, the requests of the browser
+		// to other paths or without a code don't end the login
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		go func() {
+			for _, callbackURL := range []string{
+				redirectURL + "/favicon.ico",
+				redirectURL + "/",
+				redirectURL + "?code=login-code&state=" + parsed.Query().Get("state"),
+			} {
+				resp, err := http.Get(callbackURL)
+				if err == nil {
+					resp.Body.Close()
+				}
+			}
+		}()
+	}))
+	require.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+	require.NoError(t, authr.Authenticate(req))
+	assert.Equal(t, "Bearer login-access", req.Header.Get("Authorization"))
+	assert.True(t, strings.HasPrefix(loginURL, server.URL+"/oidc/v1/authorize?"), loginURL)
+	assert.NoError(t, <-waiting)
+	assert.Equal(t, 1, logins, "the waiting request uses the token of the login")
+}
+
+func TestCallbackHandler(t *testing.T) {
+	resultCh := make(chan authResult, 1)
+	handler := callbackHandler("state", resultCh)
+	for _, target := range []string{"/favicon.ico", "/", "/?state=state", "/favicon.ico?code=code&state=state"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, target)
+	}
+	assert.Empty(t, resultCh, "the requests are ignored")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?code=code&state=other", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.EqualError(t, (<-resultCh).err, "oauth u2m: state mismatch in authorization response")
+}
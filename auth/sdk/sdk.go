@@ -0,0 +1,67 @@
+// Package sdk shares authentication set up with the Databricks SDK for Go (github.com/databricks/databricks-sdk-go)
+// with SQL connections, so an application configures its credentials once.
+//
+// The adapters only depend on the shape of the SDK types, the driver does not import the SDK:
+//
+//	cfg := &config.Config{Profile: "DEFAULT"}
+//	connector, err := dbsql.NewConnector(
+//		dbsql.WithServerHostname(<hostname>),
+//		dbsql.WithHTTPPath(<http_path>),
+//		dbsql.WithAuthenticator(sdk.FromConfig(cfg)),
+//	)
+package sdk
+
+import (
+	"net/http"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/pkg/errors"
+)
+
+// Config is implemented by the SDK's *config.Config.
+type Config interface {
+	Authenticate(*http.Request) error
+}
+
+// CredentialsProvider is implemented by the SDK's credentials.CredentialsProvider.
+type CredentialsProvider interface {
+	SetHeaders(*http.Request) error
+}
+
+// FromConfig returns an authenticator that uses the credentials resolved by an SDK config,
+// including its token refresh.
+func FromConfig(cfg Config) auth.Authenticator {
+	return authenticatorFunc(func(r *http.Request) error {
+		if cfg == nil {
+			return errors.New("sdk: missing config")
+		}
+		return errors.Wrap(cfg.Authenticate(r), "sdk: authentication failed")
+	})
+}
+
+// FromCredentialsProvider returns an authenticator that sets the headers of an SDK credentials provider.
+func FromCredentialsProvider(provider CredentialsProvider) auth.Authenticator {
+	return authenticatorFunc(func(r *http.Request) error {
+		if provider == nil {
+			return errors.New("sdk: missing credentials provider")
+		}
+		return errors.Wrap(provider.SetHeaders(r), "sdk: authentication failed")
+	})
+}
+
+// FromVisitor returns an authenticator for a request visitor func, the form used by
+// older SDK versions for credentials providers.
+func FromVisitor(visitor func(*http.Request) error) auth.Authenticator {
+	return authenticatorFunc(func(r *http.Request) error {
+		if visitor == nil {
+			return errors.New("sdk: missing request visitor")
+		}
+		return errors.Wrap(visitor(r), "sdk: authentication failed")
+	})
+}
+
+type authenticatorFunc func(*http.Request) error
+
+func (f authenticatorFunc) Authenticate(r *http.Request) error {
+	return f(r)
+}
@@ -0,0 +1,57 @@
+package sdk
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConfig struct{ err error }
+
+func (c *fakeConfig) Authenticate(r *http.Request) error {
+	if c.err != nil {
+		return c.err
+	}
+	r.Header.Set("Authorization", "Bearer from-config")
+	return nil
+}
+
+type fakeProvider struct{}
+
+func (fakeProvider) SetHeaders(r *http.Request) error {
+	r.Header.Set("Authorization", "Bearer from-provider")
+	r.Header.Set("X-Databricks-Azure-SP-Management-Token", "management")
+	return nil
+}
+
+func TestAdapters(t *testing.T) {
+	t.Run("config", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "https://example.cloud.databricks.com", nil)
+		assert.NoError(t, FromConfig(&fakeConfig{}).Authenticate(req))
+		assert.Equal(t, "Bearer from-config", req.Header.Get("Authorization"))
+
+		err := FromConfig(&fakeConfig{err: errors.New("no credentials")}).Authenticate(req)
+		assert.ErrorContains(t, err, "no credentials")
+		assert.Error(t, FromConfig(nil).Authenticate(req))
+	})
+
+	t.Run("credentials provider", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "https://example.cloud.databricks.com", nil)
+		assert.NoError(t, FromCredentialsProvider(fakeProvider{}).Authenticate(req))
+		assert.Equal(t, "Bearer from-provider", req.Header.Get("Authorization"))
+		assert.Equal(t, "management", req.Header.Get("X-Databricks-Azure-SP-Management-Token"))
+	})
+
+	t.Run("visitor", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "https://example.cloud.databricks.com", nil)
+		visitor := func(r *http.Request) error {
+			r.Header.Set("Authorization", "Bearer from-visitor")
+			return nil
+		}
+		assert.NoError(t, FromVisitor(visitor).Authenticate(req))
+		assert.Equal(t, "Bearer from-visitor", req.Header.Get("Authorization"))
+		assert.Error(t, FromVisitor(nil).Authenticate(req))
+	})
+}
@@ -0,0 +1,117 @@
+// Package tokenprovider adapts an auth.TokenProvider to an auth.Authenticator.
+//
+// Tokens are cached and the provider is only called again once the current token is about to expire,
+// so the provider can be an expensive call to a secrets manager or an SSO service.
+package tokenprovider
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/internal/expiry"
+	"github.com/pkg/errors"
+)
+
+// Option configures the authenticator returned by NewAuthenticator.
+type Option func(*authenticator)
+
+// WithExpiryDelta sets how long before its expiry a token is refreshed. Defaults to 40 seconds.
+func WithExpiryDelta(delta time.Duration) Option {
+	return func(a *authenticator) {
+		if delta >= 0 {
+			a.expiryDelta = delta
+		}
+	}
+}
+
+// WithRefreshHook registers fn to be called with every new token returned by the provider.
+func WithRefreshHook(fn func(auth.Token)) Option {
+	return func(a *authenticator) {
+		if fn != nil {
+			a.onRefresh = append(a.onRefresh, fn)
+		}
+	}
+}
+
+// WithErrorHook registers fn to be called when the provider fails to return a token.
+func WithErrorHook(fn func(error)) Option {
+	return func(a *authenticator) {
+		if fn != nil {
+			a.onError = append(a.onError, fn)
+		}
+	}
+}
+
+// NewAuthenticator returns an authenticator that sets the token returned by provider on every request.
+func NewAuthenticator(provider auth.TokenProvider, opts ...Option) auth.Authenticator {
+	a := &authenticator{
+		provider:    provider,
+		expiryDelta: expiry.Delta,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+type authenticator struct {
+	provider    auth.TokenProvider
+	expiryDelta time.Duration
+	onRefresh   []func(auth.Token)
+	onError     []func(error)
+
+	mx    sync.Mutex
+	token *auth.Token
+}
+
+func (a *authenticator) Authenticate(r *http.Request) error {
+	token, err := a.getToken(r)
+	if err != nil {
+		return err
+	}
+
+	tokenType := token.TokenType
+	if tokenType == "" {
+		tokenType = "Bearer"
+	}
+	r.Header.Set("Authorization", tokenType+" "+token.AccessToken)
+	return nil
+}
+
+func (a *authenticator) getToken(r *http.Request) (auth.Token, error) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	if a.token != nil && !a.expired(*a.token) {
+		return *a.token, nil
+	}
+
+	if a.provider == nil {
+		return auth.Token{}, errors.New("token provider: missing provider")
+	}
+	token, err := a.provider.GetToken(r.Context())
+	if err == nil && token.AccessToken == "" {
+		err = errors.New("provider returned an empty token")
+	}
+	if err != nil {
+		for _, fn := range a.onError {
+			fn(err)
+		}
+		return auth.Token{}, errors.Wrap(err, "token provider: failed to get token")
+	}
+
+	a.token = &token
+	for _, fn := range a.onRefresh {
+		fn(token)
+	}
+	return token, nil
+}
+
+func (a *authenticator) expired(token auth.Token) bool {
+	if token.Expiry.IsZero() {
+		return false
+	}
+	return !time.Now().Add(a.expiryDelta).Before(token.Expiry)
+}
@@ -0,0 +1,95 @@
+package tokenprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type providerFunc func(ctx context.Context) (auth.Token, error)
+
+func (f providerFunc) GetToken(ctx context.Context) (auth.Token, error) {
+	return f(ctx)
+}
+
+func TestAuthenticator(t *testing.T) {
+	t.Run("caches tokens until they expire", func(t *testing.T) {
+		var calls int
+		var expiry time.Time
+		provider := providerFunc(func(ctx context.Context) (auth.Token, error) {
+			calls++
+			return auth.Token{AccessToken: fmt.Sprintf("token-%d", calls), Expiry: expiry}, nil
+		})
+
+		var refreshed []string
+		authr := NewAuthenticator(provider, WithRefreshHook(func(token auth.Token) {
+			refreshed = append(refreshed, token.AccessToken)
+		}))
+
+		expiry = time.Now().Add(time.Hour)
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest(http.MethodPost, "https://example.cloud.databricks.com", nil)
+			require.NoError(t, authr.Authenticate(req))
+			assert.Equal(t, "Bearer token-1", req.Header.Get("Authorization"))
+		}
+
+		// a token within the expiry delta is refreshed
+		authr.(*authenticator).token.Expiry = time.Now().Add(10 * time.Second)
+		req, _ := http.NewRequest(http.MethodPost, "https://example.cloud.databricks.com", nil)
+		require.NoError(t, authr.Authenticate(req))
+		assert.Equal(t, "Bearer token-2", req.Header.Get("Authorization"))
+		assert.Equal(t, []string{"token-1", "token-2"}, refreshed)
+	})
+
+	t.Run("tokens without expiry are reused", func(t *testing.T) {
+		var calls int
+		provider := providerFunc(func(ctx context.Context) (auth.Token, error) {
+			calls++
+			return auth.Token{AccessToken: "static", TokenType: "Custom"}, nil
+		})
+		authr := NewAuthenticator(provider)
+
+		for i := 0; i < 3; i++ {
+			req, _ := http.NewRequest(http.MethodPost, "https://example.cloud.databricks.com", nil)
+			require.NoError(t, authr.Authenticate(req))
+			assert.Equal(t, "Custom static", req.Header.Get("Authorization"))
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("provider gets the request context", func(t *testing.T) {
+		type ctxKey struct{}
+		provider := providerFunc(func(ctx context.Context) (auth.Token, error) {
+			return auth.Token{AccessToken: ctx.Value(ctxKey{}).(string)}, nil
+		})
+		authr := NewAuthenticator(provider)
+
+		req, _ := http.NewRequestWithContext(context.WithValue(context.Background(), ctxKey{}, "from-ctx"), http.MethodPost, "https://example.cloud.databricks.com", nil)
+		require.NoError(t, authr.Authenticate(req))
+		assert.Equal(t, "Bearer from-ctx", req.Header.Get("Authorization"))
+	})
+
+	t.Run("errors are reported", func(t *testing.T) {
+		var hookErrs []error
+		provider := providerFunc(func(ctx context.Context) (auth.Token, error) {
+			return auth.Token{}, errors.New("vault is sealed")
+		})
+		authr := NewAuthenticator(provider, WithErrorHook(func(err error) { hookErrs = append(hookErrs, err) }))
+
+		req, _ := http.NewRequest(http.MethodPost, "https://example.cloud.databricks.com", nil)
+		err := authr.Authenticate(req)
+		assert.ErrorContains(t, err, "vault is sealed")
+		assert.Len(t, hookErrs, 1)
+		assert.Empty(t, req.Header.Get("Authorization"))
+
+		empty := providerFunc(func(ctx context.Context) (auth.Token, error) { return auth.Token{}, nil })
+		assert.Error(t, NewAuthenticator(empty).Authenticate(req))
+	})
+}
@@ -0,0 +1,313 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var errBatchQuery = "databricks: batch query must be an INSERT with a VALUES tuple of positional parameter markers"
+var errBatchRow = "databricks: batch row %d has %d values, expected %d"
+var errBatchType = "databricks: invalid SQL type %q"
+
+// maxBatchStatementSize is the max size of the statements generated by ExecBatch, the warehouses accept
+// statements of up to 16 MiB
+var maxBatchStatementSize = 8 << 20
+
+// ExecBatch inserts rows with the INSERT query of the statement, e.g. INSERT INTO t (a, b) VALUES (?, ?).
+// The VALUES tuple is repeated for each row, with the values inlined as SQL literals, and the rows are sent in as
+// few statements as possible instead of one statement per row. It returns the total number of inserted rows.
+// The statements are not atomic, the rows of the statements which succeeded stay inserted when one fails.
+func (s *stmt) ExecBatch(ctx context.Context, rows [][]driver.Value) (driver.Result, error) {
+	return s.conn.ExecBatch(ctx, s.query, rows)
+}
+
+// ExecBatch inserts rows with an INSERT query, see stmt.ExecBatch
+func (c *conn) ExecBatch(ctx context.Context, query string, rows [][]driver.Value) (driver.Result, error) {
+	prefix, tuple, suffix, err := splitValuesTuple(query)
+	if err != nil {
+		return nil, err
+	}
+	numInput, _ := parseParameterMarkers(tuple)
+
+	total := &result{}
+	var b strings.Builder
+	flush := func() error {
+		if b.Len() == 0 {
+			return nil
+		}
+		res, err := c.ExecContext(ctx, b.String()+suffix, nil)
+		b.Reset()
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		total.AffectedRows += n
+		total.InsertId, _ = res.LastInsertId()
+		return nil
+	}
+
+	for i, row := range rows {
+		if len(row) != numInput {
+			return total, newDriverError(fmt.Sprintf(errBatchRow, i, len(row), numInput), nil)
+		}
+		values, err := c.bindTuple(tuple, row)
+		if err != nil {
+			return total, err
+		}
+		if b.Len() > 0 && b.Len()+len(values)+len(suffix) > maxBatchStatementSize {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+		if b.Len() == 0 {
+			b.WriteString(prefix)
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(values)
+	}
+	if err := flush(); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// splitValuesTuple splits an INSERT query around the tuple following its VALUES keyword
+func splitValuesTuple(query string) (prefix, tuple, suffix string, err error) {
+	positional, named := parseParameterMarkers(query)
+	if named || positional == 0 {
+		return "", "", "", newDriverError(errBatchQuery, nil)
+	}
+
+	start, end, depth := -1, -1, 0
+	afterValues := false
+	scanSQL(query, func(i int) {
+		if end >= 0 {
+			return
+		}
+		switch c := query[i]; {
+		case !afterValues:
+			if (c == 'v' || c == 'V') && i+6 <= len(query) && strings.EqualFold(query[i:i+6], "values") &&
+				(i == 0 || !isIdentifierChar(query[i-1])) && (i+6 == len(query) || !isIdentifierChar(query[i+6])) {
+				afterValues = true
+			}
+		case c == '(':
+			if depth == 0 && start < 0 {
+				start = i
+			}
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+			if depth == 0 {
+				end = i + 1
+			}
+		}
+	})
+	if start < 0 || end < 0 {
+		return "", "", "", newDriverError(errBatchQuery, nil)
+	}
+	tuple = query[start:end]
+	if n, _ := parseParameterMarkers(tuple); n != positional {
+		return "", "", "", newDriverError(errBatchQuery, nil)
+	}
+	return query[:start], tuple, query[end:], nil
+}
+
+func isIdentifierChar(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
+// bindTuple replaces the parameter markers of tuple with the SQL literals of row
+func (c *conn) bindTuple(tuple string, row []driver.Value) (string, error) {
+	literals := make([]string, len(row))
+	for i, v := range row {
+		nv := driver.NamedValue{Ordinal: i + 1, Value: v}
+		err := c.CheckNamedValue(&nv)
+		if err == driver.ErrSkip {
+			nv.Value, err = driver.DefaultParameterConverter.ConvertValue(v)
+			if err != nil {
+				return "", newDriverError(fmt.Sprintf(errParameterType, v), err)
+			}
+		} else if err != nil {
+			return "", err
+		}
+		literal, err := sqlLiteral(nv.Value)
+		if err != nil {
+			return "", err
+		}
+		literals[i] = literal
+	}
+
+	var b strings.Builder
+	last, n := 0, 0
+	scanSQL(tuple, func(i int) {
+		if tuple[i] == '?' {
+			b.WriteString(tuple[last:i])
+			b.WriteString(literals[n])
+			last, n = i+1, n+1
+		}
+	})
+	b.WriteString(tuple[last:])
+	return b.String(), nil
+}
+
+// sqlLiteral returns a value as a SQL literal of the type it has as a query parameter. Only the literals built by
+// the driver, e.g. of an Interval, are inlined as is, the strings of the other values are quoted and cast to their type.
+func sqlLiteral(val any) (string, error) {
+	switch v := val.(type) {
+	case []byte:
+		if v != nil {
+			return "X'" + hex.EncodeToString(v) + "'", nil
+		}
+	case Interval:
+		return v.String(), nil
+	}
+	sqlType, s, err := convertParameterValue(val)
+	if err != nil {
+		return "", err
+	}
+	if !isValidSQLType(sqlType) {
+		return "", newDriverError(fmt.Sprintf(errBatchType, sqlType), nil)
+	}
+
+	switch {
+	case s == nil && sqlType == "VOID":
+		return "NULL", nil
+	case s == nil:
+		return "CAST(NULL AS " + sqlType + ")", nil
+	case sqlType == "STRING":
+		return quoteLiteral(*s), nil
+	default:
+		return "CAST(" + quoteLiteral(*s) + " AS " + sqlType + ")", nil
+	}
+}
+
+// sqlTypeNames are the names of the SQL types without parameters
+var sqlTypeNames = map[string]bool{
+	"BOOLEAN": true, "TINYINT": true, "BYTE": true, "SMALLINT": true, "SHORT": true, "INT": true, "INTEGER": true,
+	"BIGINT": true, "LONG": true, "FLOAT": true, "REAL": true, "DOUBLE": true, "DATE": true, "TIMESTAMP": true,
+	"TIMESTAMP_LTZ": true, "TIMESTAMP_NTZ": true, "STRING": true, "BINARY": true, "VOID": true,
+	"DECIMAL": true, "DEC": true, "NUMERIC": true,
+}
+
+// isValidSQLType returns true if s is the name of a SQL type, e.g. DECIMAL(10,2), INTERVAL DAY TO SECOND or
+// ARRAY<STRUCT<a: INT, b: STRING>>. Only the tokens of the type grammar are accepted, so that a type can't end the
+// CAST of a literal.
+func isValidSQLType(s string) bool {
+	p := &sqlTypeParser{}
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ':
+			i++
+		case strings.IndexByte("<>(),:", c) >= 0:
+			p.tokens = append(p.tokens, s[i:i+1])
+			i++
+		case isIdentifierChar(c):
+			j := i
+			for j < len(s) && isIdentifierChar(s[j]) {
+				j++
+			}
+			p.tokens = append(p.tokens, strings.ToUpper(s[i:j]))
+			i = j
+		default:
+			return false
+		}
+	}
+	return p.parseType() && p.pos == len(p.tokens)
+}
+
+// sqlTypeParser parses the tokens of a SQL type, see isValidSQLType
+type sqlTypeParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *sqlTypeParser) next() string {
+	if p.pos == len(p.tokens) {
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+func (p *sqlTypeParser) accept(token string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos] == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlTypeParser) number() bool {
+	_, err := strconv.Atoi(p.next())
+	return err == nil
+}
+
+func (p *sqlTypeParser) identifier() bool {
+	name := p.next()
+	return name != "" && isIdentifierStart(name[0])
+}
+
+func (p *sqlTypeParser) parseType() bool {
+	switch name := p.next(); name {
+	case "DECIMAL", "DEC", "NUMERIC":
+		if p.accept("(") {
+			return p.number() && (!p.accept(",") || p.number()) && p.accept(")")
+		}
+		return true
+	case "CHAR", "VARCHAR":
+		return p.accept("(") && p.number() && p.accept(")")
+	case "INTERVAL":
+		return p.parseIntervalQualifier()
+	case "ARRAY":
+		return p.accept("<") && p.parseType() && p.accept(">")
+	case "MAP":
+		return p.accept("<") && p.parseType() && p.accept(",") && p.parseType() && p.accept(">")
+	case "STRUCT":
+		if !p.accept("<") {
+			return false
+		}
+		if p.accept(">") {
+			return true
+		}
+		for {
+			if !p.identifier() {
+				return false
+			}
+			p.accept(":")
+			if !p.parseType() {
+				return false
+			}
+			if !p.accept(",") {
+				return p.accept(">")
+			}
+		}
+	default:
+		return sqlTypeNames[name]
+	}
+}
+
+func (p *sqlTypeParser) parseIntervalQualifier() bool {
+	start := p.next()
+	fields := yearMonthFields
+	if indexOf(fields, start) < 0 {
+		fields = dayTimeFields
+	}
+	from := indexOf(fields, start)
+	if from < 0 {
+		return false
+	}
+	if p.accept("TO") {
+		return indexOf(fields, p.next()) > from
+	}
+	return true
+}
+
+// quoteLiteral returns s as a SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,169 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitValuesTuple(t *testing.T) {
+	prefix, tuple, suffix, err := splitValuesTuple("INSERT INTO t (a, b, c) values (?, concat(?, '('), current_date()) -- done")
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO t (a, b, c) values ", prefix)
+	assert.Equal(t, "(?, concat(?, '('), current_date())", tuple)
+	assert.Equal(t, " -- done", suffix)
+
+	for _, query := range []string{
+		"INSERT INTO t SELECT * FROM s",
+		"INSERT INTO t VALUES (1, 2)",
+		"INSERT INTO t VALUES (:a, :b)",
+		"INSERT INTO my_values SELECT ?",
+		"INSERT INTO t VALUES (?) WHERE ?",
+	} {
+		_, _, _, err := splitValuesTuple(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	ts := time.Date(2023, 1, 31, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{nil, "NULL"},
+		{"it's a \\ test", `'it\'s a \\ test'`},
+		{int64(42), "CAST('42' AS BIGINT)"},
+		{true, "CAST('true' AS BOOLEAN)"},
+		{ts, "CAST('2023-01-31T10:00:00Z' AS TIMESTAMP)"},
+		{[]byte{0xca, 0xfe}, "X'cafe'"},
+		{Interval{Months: 14}, "INTERVAL '1-2' YEAR TO MONTH"},
+		{Parameter{Type: "DATE", Value: "2023-01-31"}, "CAST('2023-01-31' AS DATE)"},
+		{Parameter{Type: "DATE"}, "CAST(NULL AS DATE)"},
+	}
+	for _, tt := range tests {
+		got, err := sqlLiteral(tt.value)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+
+	_, err := sqlLiteral(Parameter{Type: "DATE) --", Value: "x"})
+	assert.Error(t, err)
+}
+
+func TestSQLLiteralInjection(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{
+			Parameter{Type: "INTERVAL DAY", Value: "INTERVAL 1 DAY; DROP TABLE t --"},
+			`CAST('INTERVAL 1 DAY; DROP TABLE t --' AS INTERVAL DAY)`,
+		},
+		{
+			Parameter{Type: "INTERVAL YEAR TO MONTH", Value: Interval{Months: 14}},
+			`CAST('INTERVAL \'1-2\' YEAR TO MONTH' AS INTERVAL YEAR TO MONTH)`,
+		},
+		{"INTERVAL '1' DAY", `'INTERVAL \'1\' DAY'`},
+		{`\' OR 1=1 --`, `'\\\' OR 1=1 --'`},
+		{Parameter{Type: "DATE", Value: `2023-01-31\'); DROP TABLE t --`}, `CAST('2023-01-31\\\'); DROP TABLE t --' AS DATE)`},
+	}
+	for _, tt := range tests {
+		got, err := sqlLiteral(tt.value)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestIsValidSQLType(t *testing.T) {
+	for _, sqlType := range []string{
+		"INT", "bigint", "DECIMAL", "DECIMAL(10)", "DECIMAL(10,2)", "decimal(10, 2)", "VARCHAR(10)", "TIMESTAMP_NTZ",
+		"INTERVAL DAY", "INTERVAL YEAR TO MONTH", "interval day to second", "INTERVAL HOUR TO MINUTE",
+		"ARRAY<INT>", "MAP<STRING, ARRAY<DATE>>", "STRUCT<a: INT, b STRING>", "STRUCT<>",
+	} {
+		assert.True(t, isValidSQLType(sqlType), sqlType)
+	}
+	for _, sqlType := range []string{
+		"", "DATE) --", "INT) OR (1", "DECIMAL(10,2)) --", "STRING; DROP TABLE t", "INTERVAL DAY; --", "INTERVAL",
+		"INTERVAL MONTH TO YEAR", "INTERVAL YEAR TO SECOND", "INT, (SELECT 1)", "DATE AS", "ARRAY<INT", "MAP<INT>",
+		"STRUCT<a: INT,>", "CHAR", "DECIMAL(a)", "INT/**/", "TABLE", "DATE\nFROM",
+	} {
+		assert.False(t, isValidSQLType(sqlType), sqlType)
+	}
+}
+
+func TestConn_ExecBatch(t *testing.T) {
+	var statements []string
+	newConn := func() *conn {
+		statements = nil
+		return &conn{
+			session: getTestSession(),
+			client: &client.TestClient{
+				FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+					statements = append(statements, req.Statement)
+					numModifiedRows := int64(strings.Count(req.Statement, "), (") + 1)
+					return &cli_service.TExecuteStatementResp{
+						Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+						DirectResults: &cli_service.TSparkDirectResults{
+							OperationStatus: &cli_service.TGetOperationStatusResp{
+								OperationState:  cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+								NumModifiedRows: &numModifiedRows,
+							},
+						},
+					}, nil
+				},
+			},
+			cfg: config.WithDefaults(),
+		}
+	}
+	rows := [][]driver.Value{{int64(1), "a"}, {int64(2), nil}, {3, "c;d"}}
+
+	t.Run("rows are inserted with one statement", func(t *testing.T) {
+		res, err := newConn().ExecBatch(context.Background(), "INSERT INTO t (id, name) VALUES (?, ?)", rows)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"INSERT INTO t (id, name) VALUES (CAST('1' AS BIGINT), 'a'), (CAST('2' AS BIGINT), NULL), (CAST('3' AS BIGINT), 'c;d')",
+		}, statements)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(3), n)
+	})
+
+	t.Run("large batches are split", func(t *testing.T) {
+		defer func(size int) { maxBatchStatementSize = size }(maxBatchStatementSize)
+		maxBatchStatementSize = 80
+
+		c := newConn()
+		res, err := newStmt(c, "INSERT INTO t VALUES (?, ?)").ExecBatch(context.Background(), rows)
+		require.NoError(t, err)
+		assert.Len(t, statements, 2)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(3), n)
+	})
+
+	t.Run("values which can't be converted return an error", func(t *testing.T) {
+		_, err := newConn().ExecBatch(context.Background(), "INSERT INTO t VALUES (?, ?)", [][]driver.Value{{int64(1), failingValuer{}}})
+		assert.EqualError(t, err, "value error")
+		assert.Empty(t, statements)
+	})
+
+	t.Run("rows must have a value per parameter", func(t *testing.T) {
+		_, err := newConn().ExecBatch(context.Background(), "INSERT INTO t VALUES (?, ?)", [][]driver.Value{{int64(1)}})
+		assert.Error(t, err)
+		assert.Empty(t, statements)
+	})
+}
+
+type failingValuer struct{}
+
+func (failingValuer) Value() (driver.Value, error) {
+	return nil, errors.New("value error")
+}
@@ -0,0 +1,180 @@
+// Package dbsqlbuilder quotes identifiers and formats literals in the Databricks SQL dialect, for tools generating
+// SQL run with the databricks driver. Identifiers are quoted with backticks, names have up to three levels,
+// catalog.schema.table, and values are formatted as typed literals:
+//
+//	name := dbsqlbuilder.Name{Catalog: "main", Schema: "sales", Table: "order items"}
+//	day, err := dbsqlbuilder.Literal(dbsql.Date{Year: 2023, Month: 1, Day: 31})
+//	query := "select * from " + name.String() + " where " + dbsqlbuilder.QuoteIdentifier("day") + " = " + day
+//	// select * from `main`.`sales`.`order items` where `day` = DATE '2023-01-31'
+//
+// Prefer query parameters for values, literals are for the statements which can't have parameters, e.g. DDL.
+package dbsqlbuilder
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	"github.com/pkg/errors"
+)
+
+// Name is the name of a table, view or function, the catalog and the schema are optional
+type Name struct {
+	Catalog string
+	Schema  string
+	Table   string
+}
+
+// String returns the name with its parts quoted, the empty catalog and schema are left out
+func (n Name) String() string {
+	var parts []string
+	for _, part := range []string{n.Catalog, n.Schema} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return QualifiedName(append(parts, n.Table)...)
+}
+
+// ParseName parses a name of up to three dot separated parts, each of them optionally quoted with backticks, e.g.
+// main.sales.`order items`. A name of two parts is a schema and a table, a name of one part is a table.
+func ParseName(s string) (Name, error) {
+	var parts []string
+	var part strings.Builder
+	quoted, wasQuoted := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quoted && c == '`' && i+1 < len(s) && s[i+1] == '`':
+			part.WriteByte('`')
+			i++
+		case c == '`' && (quoted || part.Len() == 0 && !wasQuoted):
+			quoted = !quoted
+			wasQuoted = true
+		case !quoted && c == '.':
+			if part.Len() == 0 && !wasQuoted {
+				return Name{}, errors.Errorf("databricks: invalid name %q, it has an empty part", s)
+			}
+			parts = append(parts, part.String())
+			part.Reset()
+			wasQuoted = false
+		case !quoted && wasQuoted:
+			return Name{}, errors.Errorf("databricks: invalid name %q, characters follow a quoted part", s)
+		default:
+			part.WriteByte(c)
+		}
+	}
+	if quoted {
+		return Name{}, errors.Errorf("databricks: invalid name %q, a quote is not closed", s)
+	}
+	if part.Len() == 0 && !wasQuoted {
+		return Name{}, errors.Errorf("databricks: invalid name %q, it has an empty part", s)
+	}
+	parts = append(parts, part.String())
+	if len(parts) > 3 {
+		return Name{}, errors.Errorf("databricks: invalid name %q, it has more than 3 parts", s)
+	}
+
+	var n Name
+	for len(parts) < 3 {
+		parts = append([]string{""}, parts...)
+	}
+	n.Catalog, n.Schema, n.Table = parts[0], parts[1], parts[2]
+	return n, nil
+}
+
+// QuoteIdentifier returns name quoted with backticks, the backticks of name are doubled
+func QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// QualifiedName returns the quoted parts of a name joined with dots, e.g. `main`.`sales`.`orders`
+func QualifiedName(parts ...string) string {
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = QuoteIdentifier(part)
+	}
+	return strings.Join(quoted, ".")
+}
+
+// StringLiteral returns s as a string literal, quotes and backslashes are escaped with a backslash
+func StringLiteral(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// Literal returns the SQL literal of a value of one of the types of query parameters: nil is NULL, a bool is a
+// BOOLEAN, an integer a BIGINT, INT, SMALLINT or TINYINT, a float64 a DOUBLE, a float32 a FLOAT, a string a
+// STRING, a []byte a BINARY, a time.Time a TIMESTAMP, a dbsql.Date a DATE, a dbsql.Decimal a DECIMAL with the
+// precision and scale of the value and a dbsql.Interval an INTERVAL. A slice is an ARRAY of the literals of its
+// elements. The other types return an error.
+func Literal(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v)), nil
+	case int:
+		return strconv.FormatInt(int64(v), 10) + "L", nil
+	case int64:
+		return strconv.FormatInt(v, 10) + "L", nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10) + "S", nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10) + "Y", nil
+	case float64:
+		return floatLiteral(v, 64, "DOUBLE"), nil
+	case float32:
+		return floatLiteral(float64(v), 32, "FLOAT"), nil
+	case string:
+		return StringLiteral(v), nil
+	case []byte:
+		if v == nil {
+			return "NULL", nil
+		}
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	case time.Time:
+		return "TIMESTAMP '" + v.Format(time.RFC3339Nano) + "'", nil
+	case dbsql.Date:
+		return "DATE '" + v.String() + "'", nil
+	case dbsql.Decimal:
+		return v.String() + "BD", nil
+	case dbsql.Interval:
+		return v.String(), nil
+	}
+
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		elems := make([]string, rv.Len())
+		for i := range elems {
+			elem, err := Literal(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			elems[i] = elem
+		}
+		return "array(" + strings.Join(elems, ", ") + ")", nil
+	}
+	return "", errors.Errorf("databricks: no literal for values of type %T", value)
+}
+
+// floatLiteral returns the literal of a floating point number, the special values are cast from strings
+func floatLiteral(f float64, bitSize int, sqlType string) string {
+	switch {
+	case math.IsNaN(f):
+		return fmt.Sprintf("CAST('NaN' AS %s)", sqlType)
+	case math.IsInf(f, 1):
+		return fmt.Sprintf("CAST('Infinity' AS %s)", sqlType)
+	case math.IsInf(f, -1):
+		return fmt.Sprintf("CAST('-Infinity' AS %s)", sqlType)
+	case sqlType == "DOUBLE":
+		return strconv.FormatFloat(f, 'g', -1, bitSize) + "D"
+	default:
+		return fmt.Sprintf("CAST(%s AS %s)", strconv.FormatFloat(f, 'g', -1, bitSize), sqlType)
+	}
+}
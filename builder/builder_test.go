@@ -0,0 +1,74 @@
+package dbsqlbuilder
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "`main`.`sales`.`order items`", Name{Catalog: "main", Schema: "sales", Table: "order items"}.String())
+	assert.Equal(t, "`sales`.`orders`", Name{Schema: "sales", Table: "orders"}.String())
+	assert.Equal(t, "`my``table`", Name{Table: "my`table"}.String())
+	assert.Equal(t, "`a`.`b`", QualifiedName("a", "b"))
+
+	for s, expected := range map[string]Name{
+		"main.sales.orders":       {Catalog: "main", Schema: "sales", Table: "orders"},
+		"sales.`order items`":     {Schema: "sales", Table: "order items"},
+		"`a.b`":                   {Table: "a.b"},
+		"`my``table`":             {Table: "my`table"},
+		"``.sales.orders":         {Schema: "sales", Table: "orders"},
+		"`main`.`sales`.`orders`": {Catalog: "main", Schema: "sales", Table: "orders"},
+	} {
+		n, err := ParseName(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, n, s)
+	}
+
+	for _, s := range []string{"", "a..b", "a.b.", "`a", "`a`b", "a.b.c.d"} {
+		_, err := ParseName(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	for _, tc := range []struct {
+		value    any
+		expected string
+	}{
+		{nil, "NULL"},
+		{true, "TRUE"},
+		{1, "1L"},
+		{int64(-2), "-2L"},
+		{int32(3), "3"},
+		{int16(4), "4S"},
+		{int8(5), "5Y"},
+		{1.5, "1.5D"},
+		{1e20, "1e+20D"},
+		{math.Inf(-1), "CAST('-Infinity' AS DOUBLE)"},
+		{float32(0.25), "CAST(0.25 AS FLOAT)"},
+		{`it's a \ test`, `'it\'s a \\ test'`},
+		{[]byte{0x00, 0xff}, "X'00ff'"},
+		{[]byte(nil), "NULL"},
+		{time.Date(2023, 1, 31, 10, 30, 0, 5000, time.UTC), "TIMESTAMP '2023-01-31T10:30:00.000005Z'"},
+		{dbsql.Date{Year: 2023, Month: 1, Day: 31}, "DATE '2023-01-31'"},
+		{dbsql.NewDecimal(big.NewInt(-12345), 2), "-123.45BD"},
+		{dbsql.Interval{Months: 14}, "INTERVAL '1-2' YEAR TO MONTH"},
+		{[]string{"a", "b"}, "array('a', 'b')"},
+		{[]any{}, "array()"},
+	} {
+		literal, err := Literal(tc.value)
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.expected, literal, tc.value)
+	}
+
+	_, err := Literal(struct{}{})
+	assert.EqualError(t, err, "databricks: no literal for values of type struct {}")
+	_, err = Literal([]any{1, uint(2)})
+	assert.Error(t, err)
+}
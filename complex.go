@@ -0,0 +1,180 @@
+package dbsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/pkg/errors"
+)
+
+var errComplexScan = "databricks: unable to scan %T into %T"
+var errComplexScanDest = "databricks: complex value destination must be a non-nil pointer, got %T"
+
+// ComplexTypeScanner selects how ARRAY, MAP, STRUCT and VARIANT values are returned by rows.Next
+type ComplexTypeScanner int
+
+const (
+	// ComplexTypesAsString returns complex values as the JSON strings sent by the server. This is the default.
+	ComplexTypesAsString ComplexTypeScanner = iota
+	// ComplexTypesStructured decodes ARRAY values to []any, MAP values to map[any]any and STRUCT values
+	// to map[string]any. VARIANT values are decoded like JSON: objects to map[string]any, arrays to []any and
+	// strings, booleans and null to their Go values. Numbers are decoded to int64, or float64 when they are
+	// not integers.
+	ComplexTypesStructured
+)
+
+// isComplexType returns true for the types the server sends as JSON strings
+func isComplexType(typeID cli_service.TTypeId) bool {
+	return typeID == cli_service.TTypeId_ARRAY_TYPE ||
+		typeID == cli_service.TTypeId_MAP_TYPE ||
+		typeID == cli_service.TTypeId_STRUCT_TYPE
+}
+
+// decodeComplexValues replaces the JSON strings of the complex and VARIANT columns in dest with their decoded
+// values, variants tells which columns are VARIANT columns
+func decodeComplexValues(dest []driver.Value, columns []*cli_service.TColumnDesc, variants []bool) error {
+	for i := range dest {
+		if i >= len(columns) {
+			break
+		}
+		val, ok := dest[i].(string)
+		typeID := getDBTypeID(columns[i])
+		isVariant := i < len(variants) && variants[i]
+		if !ok || !isComplexType(typeID) && !isVariant {
+			continue
+		}
+
+		decoded, err := decodeComplexValue(val, typeID)
+		if err != nil {
+			typeName := getDBTypeName(columns[i])
+			if isVariant {
+				typeName = "VARIANT"
+			}
+			return wrapErrf(err, errRowsParseValue, typeName, val, columns[i].ColumnName)
+		}
+		dest[i] = decoded
+	}
+
+	return nil
+}
+
+// decodeComplexValue decodes the JSON value of an ARRAY, MAP, STRUCT or VARIANT column. JSON objects are
+// decoded to map[string]any, except MAP values which are decoded to map[any]any.
+func decodeComplexValue(val string, typeID cli_service.TTypeId) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(val))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	v = convertJSONNumbers(v)
+
+	if m, ok := v.(map[string]any); ok && typeID == cli_service.TTypeId_MAP_TYPE {
+		mapVal := make(map[any]any, len(m))
+		for k, e := range m {
+			mapVal[k] = e
+		}
+		return mapVal, nil
+	}
+
+	return v, nil
+}
+
+// convertJSONNumbers converts the numbers of a decoded JSON value to int64, or float64 when they are not integers
+func convertJSONNumbers(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case []any:
+		for i := range t {
+			t[i] = convertJSONNumbers(t[i])
+		}
+	case map[string]any:
+		for k := range t {
+			t[k] = convertJSONNumbers(t[k])
+		}
+	}
+	return v
+}
+
+// ScanComplex returns a sql.Scanner decoding an ARRAY, MAP, STRUCT or VARIANT value, or a STRING holding JSON,
+// into dest, a pointer to a slice, map, struct or json.RawMessage. Values are decoded like with encoding/json,
+// struct fields are matched by their json tag or name. It works with both complex type scanners.
+//
+//	var tags []string
+//	var address struct {
+//		City string `json:"city"`
+//	}
+//	var payload json.RawMessage
+//	err := rows.Scan(dbsql.ScanComplex(&tags), dbsql.ScanComplex(&address), dbsql.ScanComplex(&payload))
+func ScanComplex(dest any) sql.Scanner {
+	return complexScanner{dest: dest}
+}
+
+type complexScanner struct {
+	dest any
+}
+
+func (s complexScanner) Scan(src any) error {
+	rv := reflect.ValueOf(s.dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.Errorf(errComplexScanDest, s.dest)
+	}
+
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		// structured values are encoded back to JSON to be decoded into dest
+		var err error
+		data, err = json.Marshal(jsonCompatible(v))
+		if err != nil {
+			return wrapErrf(err, errComplexScan, src, s.dest)
+		}
+	}
+
+	if err := json.Unmarshal(data, s.dest); err != nil {
+		return wrapErrf(err, errComplexScan, src, s.dest)
+	}
+	return nil
+}
+
+// jsonCompatible converts the map[any]any values of MAP columns to map[string]any so they can be encoded to JSON
+func jsonCompatible(v any) any {
+	switch t := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = jsonCompatible(e)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[k] = jsonCompatible(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			s[i] = jsonCompatible(e)
+		}
+		return s
+	}
+	return v
+}
@@ -0,0 +1,254 @@
+package dbsql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeComplexValue(t *testing.T) {
+	cases := []struct {
+		name     string
+		typeID   cli_service.TTypeId
+		in       string
+		expected any
+	}{
+		{"array", cli_service.TTypeId_ARRAY_TYPE, `[1,2,null]`, []any{int64(1), int64(2), nil}},
+		{"array of arrays", cli_service.TTypeId_ARRAY_TYPE, `[["a"],[]]`, []any{[]any{"a"}, []any{}}},
+		{"map", cli_service.TTypeId_MAP_TYPE, `{"a":1.5,"b":true}`, map[any]any{"a": 1.5, "b": true}},
+		{"struct", cli_service.TTypeId_STRUCT_TYPE, `{"id":9007199254740993,"tags":{"x":"y"}}`, map[string]any{"id": int64(9007199254740993), "tags": map[string]any{"x": "y"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			val, err := decodeComplexValue(c.in, c.typeID)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, val)
+		})
+	}
+
+	_, err := decodeComplexValue(`[1,`, cli_service.TTypeId_ARRAY_TYPE)
+	assert.Error(t, err)
+}
+
+func TestComplexTypeRows(t *testing.T) {
+	getRows := func(decode bool) *rows {
+		column := func(name string, typeID cli_service.TTypeId) *cli_service.TColumnDesc {
+			return &cli_service.TColumnDesc{
+				ColumnName: name,
+				TypeDesc: &cli_service.TTypeDesc{
+					Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: typeID}}},
+				},
+			}
+		}
+		cfg := config.WithDefaults()
+		cfg.DecodeComplexTypes = decode
+		return &rows{
+			client: &client.TestClient{},
+			cfg:    cfg,
+			fetchResults: &cli_service.TFetchResultsResp{
+				Results: &cli_service.TRowSet{Columns: []*cli_service.TColumn{
+					{StringVal: &cli_service.TStringColumn{Values: []string{"abc"}}},
+					{StringVal: &cli_service.TStringColumn{Values: []string{`[1,2]`}}},
+					{StringVal: &cli_service.TStringColumn{Values: []string{`{"k":"v"}`}}},
+					{StringVal: &cli_service.TStringColumn{Values: []string{""}, Nulls: []byte{1}}},
+				}},
+				HasMoreRows: boolPtr(false),
+			},
+			fetchResultsMetadata: &cli_service.TGetResultSetMetadataResp{
+				Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{
+					column("string_col", cli_service.TTypeId_STRING_TYPE),
+					column("array_col", cli_service.TTypeId_ARRAY_TYPE),
+					column("map_col", cli_service.TTypeId_MAP_TYPE),
+					column("struct_col", cli_service.TTypeId_STRUCT_TYPE),
+				}},
+			},
+			closed: true,
+		}
+	}
+
+	t.Run("should return strings by default", func(t *testing.T) {
+		r := getRows(false)
+		row := make([]driver.Value, 4)
+		require.NoError(t, r.Next(row))
+		assert.Equal(t, []driver.Value{"abc", `[1,2]`, `{"k":"v"}`, nil}, row)
+		assert.Equal(t, scanTypeRawBytes, r.ColumnTypeScanType(1))
+	})
+
+	t.Run("should decode structured values", func(t *testing.T) {
+		r := getRows(true)
+		row := make([]driver.Value, 4)
+		require.NoError(t, r.Next(row))
+		assert.Equal(t, []driver.Value{"abc", []any{int64(1), int64(2)}, map[any]any{"k": "v"}, nil}, row)
+		assert.Equal(t, scanTypeString, r.ColumnTypeScanType(0))
+		assert.Equal(t, scanTypeArray, r.ColumnTypeScanType(1))
+		assert.Equal(t, scanTypeMap, r.ColumnTypeScanType(2))
+		assert.Equal(t, scanTypeStruct, r.ColumnTypeScanType(3))
+	})
+
+	t.Run("should fail on invalid values", func(t *testing.T) {
+		r := getRows(true)
+		r.fetchResults.Results.Columns[1].StringVal.Values[0] = "[1,"
+		row := make([]driver.Value, 4)
+		assert.ErrorContains(t, r.Next(row), "unable to parse ARRAY value '[1,' from column array_col")
+	})
+}
+
+func TestVariantRows(t *testing.T) {
+	getRows := func(t *testing.T, decode bool) *rows {
+		column := func(name string) *cli_service.TColumnDesc {
+			return &cli_service.TColumnDesc{
+				ColumnName: name,
+				TypeDesc: &cli_service.TTypeDesc{
+					Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: cli_service.TTypeId_STRING_TYPE}}},
+				},
+			}
+		}
+		schemaBytes, _ := getArrowTestBatches(t, arrow.NewSchema([]arrow.Field{
+			{Name: "string_col", Type: arrow.BinaryTypes.String, Nullable: true},
+			{Name: "variant_col", Type: arrow.BinaryTypes.String, Nullable: true,
+				Metadata: arrow.NewMetadata([]string{sparkSqlNameKey}, []string{"VARIANT"})},
+		}, nil))
+
+		cfg := config.WithDefaults()
+		cfg.DecodeComplexTypes = decode
+		return &rows{
+			client: &client.TestClient{},
+			cfg:    cfg,
+			fetchResults: &cli_service.TFetchResultsResp{
+				Results: &cli_service.TRowSet{Columns: []*cli_service.TColumn{
+					{StringVal: &cli_service.TStringColumn{Values: []string{`{"a":1}`, `{"a":1}`, `{"a":1}`}}},
+					{StringVal: &cli_service.TStringColumn{Values: []string{`{"id":7,"tags":["x"]}`, `"text"`, ""}, Nulls: []byte{4}}},
+				}},
+				HasMoreRows: boolPtr(false),
+			},
+			fetchResultsMetadata: &cli_service.TGetResultSetMetadataResp{
+				Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{
+					column("string_col"),
+					column("variant_col"),
+				}},
+				ArrowSchema: schemaBytes,
+			},
+			closed: true,
+		}
+	}
+
+	t.Run("should report VARIANT columns", func(t *testing.T) {
+		r := getRows(t, false)
+		assert.Equal(t, "STRING", r.ColumnTypeDatabaseTypeName(0))
+		assert.Equal(t, "VARIANT", r.ColumnTypeDatabaseTypeName(1))
+
+		// without an Arrow schema the variants are STRING columns
+		r.fetchResultsMetadata.ArrowSchema = nil
+		r.variants = nil
+		assert.Equal(t, "STRING", r.ColumnTypeDatabaseTypeName(1))
+	})
+
+	t.Run("should keep the JSON strings by default", func(t *testing.T) {
+		r := getRows(t, false)
+		row := make([]driver.Value, 2)
+		require.NoError(t, r.Next(row))
+		assert.Equal(t, []driver.Value{`{"a":1}`, `{"id":7,"tags":["x"]}`}, row)
+	})
+
+	t.Run("should decode structured values", func(t *testing.T) {
+		r := getRows(t, true)
+		assert.Equal(t, scanTypeString, r.ColumnTypeScanType(0))
+		assert.Equal(t, scanTypeVariant, r.ColumnTypeScanType(1))
+
+		row := make([]driver.Value, 2)
+		require.NoError(t, r.Next(row))
+		assert.Equal(t, []driver.Value{`{"a":1}`, map[string]any{"id": int64(7), "tags": []any{"x"}}}, row)
+		require.NoError(t, r.Next(row))
+		assert.Equal(t, "text", row[1])
+		require.NoError(t, r.Next(row))
+		assert.Nil(t, row[1])
+	})
+
+	t.Run("should fail on invalid values", func(t *testing.T) {
+		r := getRows(t, true)
+		r.fetchResults.Results.Columns[1].StringVal.Values[0] = "{"
+		row := make([]driver.Value, 2)
+		assert.ErrorContains(t, r.Next(row), "unable to parse VARIANT value '{' from column variant_col")
+	})
+}
+
+func TestSpatialColumns(t *testing.T) {
+	column := func(name string, typeID cli_service.TTypeId) *cli_service.TColumnDesc {
+		return &cli_service.TColumnDesc{
+			ColumnName: name,
+			TypeDesc: &cli_service.TTypeDesc{
+				Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: typeID}}},
+			},
+		}
+	}
+	sqlName := func(name string) arrow.Metadata {
+		return arrow.NewMetadata([]string{sparkSqlNameKey}, []string{name})
+	}
+	schemaBytes, _ := getArrowTestBatches(t, arrow.NewSchema([]arrow.Field{
+		{Name: "location", Type: arrow.BinaryTypes.String, Metadata: sqlName("GEOMETRY(4326)")},
+		{Name: "area", Type: arrow.BinaryTypes.Binary, Metadata: sqlName("geography")},
+		{Name: "name", Type: arrow.BinaryTypes.String, Metadata: sqlName("STRING")},
+	}, nil))
+	metadata := &cli_service.TGetResultSetMetadataResp{
+		Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{
+			column("location", cli_service.TTypeId_STRING_TYPE),
+			column("area", cli_service.TTypeId_BINARY_TYPE),
+			column("name", cli_service.TTypeId_STRING_TYPE),
+		}},
+		ArrowSchema: schemaBytes,
+	}
+
+	assert.Equal(t, []string{"GEOMETRY", "GEOGRAPHY", ""}, spatialTypes(metadata))
+	assert.Equal(t, []string{"", "", ""}, spatialTypes(&cli_service.TGetResultSetMetadataResp{Schema: metadata.Schema}))
+
+	r := &rows{client: &client.TestClient{}, fetchResultsMetadata: metadata}
+	assert.Equal(t, "GEOMETRY", r.ColumnTypeDatabaseTypeName(0))
+	assert.Equal(t, "GEOGRAPHY", r.ColumnTypeDatabaseTypeName(1))
+	assert.Equal(t, "STRING", r.ColumnTypeDatabaseTypeName(2))
+}
+
+func TestScanComplex(t *testing.T) {
+	type address struct {
+		City  string  `json:"city"`
+		Zip   int     `json:"zip"`
+		Total Decimal `json:"total"`
+	}
+
+	for _, src := range []any{
+		`{"city":"Amsterdam","zip":1011,"total":12.50}`,
+		[]byte(`{"city":"Amsterdam","zip":1011,"total":12.50}`),
+		map[string]any{"city": "Amsterdam", "zip": int64(1011), "total": 12.5},
+	} {
+		var a address
+		require.NoError(t, ScanComplex(&a).Scan(src))
+		assert.Equal(t, "Amsterdam", a.City)
+		assert.Equal(t, 1011, a.Zip)
+		assert.Equal(t, 12.5, a.Total.Float64())
+	}
+
+	var raw json.RawMessage
+	require.NoError(t, ScanComplex(&raw).Scan(`{"id":7}`))
+	assert.Equal(t, json.RawMessage(`{"id":7}`), raw)
+	require.NoError(t, ScanComplex(&raw).Scan(map[string]any{"id": int64(7)}))
+	assert.Equal(t, json.RawMessage(`{"id":7}`), raw)
+
+	var m map[int]string
+	require.NoError(t, ScanComplex(&m).Scan(map[any]any{"1": "a", "2": "b"}))
+	assert.Equal(t, map[int]string{1: "a", 2: "b"}, m)
+
+	tags := []string{"old"}
+	require.NoError(t, ScanComplex(&tags).Scan(nil))
+	assert.Nil(t, tags)
+	require.NoError(t, ScanComplex(&tags).Scan([]any{"a", "b"}))
+	assert.Equal(t, []string{"a", "b"}, tags)
+
+	assert.ErrorContains(t, ScanComplex(&tags).Scan(`{"a":1}`), "unable to scan string into *[]string")
+	assert.EqualError(t, ScanComplex(tags).Scan(`[]`), "databricks: complex value destination must be a non-nil pointer, got []string")
+}
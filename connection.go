@@ -2,15 +2,22 @@ package dbsql
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql/driver"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
 	"github.com/databricks/databricks-sql-go/internal/cli_service"
 	"github.com/databricks/databricks-sql-go/internal/client"
 	"github.com/databricks/databricks-sql-go/internal/config"
 	"github.com/databricks/databricks-sql-go/internal/sentinel"
 	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/databricks/databricks-sql-go/metrics"
 	"github.com/pkg/errors"
 )
 
@@ -19,28 +26,103 @@ type conn struct {
 	cfg     *config.Config
 	client  cli_service.TCLIService
 	session *cli_service.TOpenSessionResp
+	stmts   *stmtCache
+
+	// catalog, schema and SET statements of the session by param, it is reopened with them when it expires
+	catalog string
+	schema  string
+	params  map[string]string
+
+	mu            sync.Mutex // guards id and session read by the heartbeat
+	reopening     bool
+	stateChanged  bool         // set when a statement changed the session state, e.g. SET or temporary views
+	expired       atomic.Bool  // set when a heartbeat finds the session expired
+	lastUsed      atomic.Int64 // unix time in nanoseconds of the last statement
+	stopHeartbeat chan struct{}
+	idleTimer     *time.Timer // closes the session of the connection idle in the pool, see SessionIdleTimeout
+
+	outstanding *atomic.Int64 // running queries of the connections of the connector, nil if not counted
+	httpClient  *http.Client  // sends the requests of the connector, nil if the statement stats aren't available
+	lifecycle   *lifecycle    // shuts the connection down with its connector, nil if it isn't shut down
+	closed      atomic.Bool   // set when the session is closed, by Close or by the shutdown of the connector
+
+	heartbeatClient cli_service.TCLIService // sends the heartbeats of the session once it is opened, nil without heartbeats
+
+	identityColumns map[string]string // identity column by table of the INSERT statements, empty when there is none
 }
 
 // Prepare prepares a statement with the query bound to this connection.
+// Prepared statements are cached by the connection, preparing the same query again reuses the statement.
 func (c *conn) Prepare(query string) (driver.Stmt, error) {
-	return &stmt{conn: c, query: query}, nil
+	if c.cfg == nil || c.cfg.MaxPreparedStatements <= 0 {
+		return newStmt(c, query), nil
+	}
+	if c.stmts == nil {
+		c.stmts = newStmtCache(c.cfg.MaxPreparedStatements)
+	}
+	return c.stmts.get(query, func() *stmt { return newStmt(c, query) }), nil
 }
 
 // PrepareContext prepares a statement with the query bound to this connection.
 // Currently, PrepareContext does not use context and is functionally equivalent to Prepare.
 func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
-	return &stmt{conn: c, query: query}, nil
+	return c.Prepare(query)
 }
 
 // Close closes the session.
 // sql package maintains a free pool of connections and only calls Close when there's a surplus of idle connections.
 func (c *conn) Close() error {
-	log := logger.WithContext(c.id, "", "")
-	ctx := driverctx.NewContextWithConnId(context.Background(), c.id)
+	return c.close(c.client)
+}
 
-	_, err := c.client.CloseSession(ctx, &cli_service.TCloseSessionReq{
-		SessionHandle: c.session.SessionHandle,
+// closeConcurrently closes the session with a new client, while the connection may be in use by another goroutine
+// and its client busy
+func (c *conn) closeConcurrently() error {
+	var tclient cli_service.TCLIService
+	var err error
+	if c.cfg.UseRESTAPI {
+		tclient, err = client.InitRESTClient(c.cfg, c.httpClient)
+	} else {
+		tclient, err = client.InitThriftClient(c.cfg, c.httpClient)
+	}
+	if err != nil {
+		return wrapErr(err, "error initializing client")
+	}
+	return c.close(tclient)
+}
+
+// close closes the session with tclient, only the first call closes it
+func (c *conn) close(tclient cli_service.TCLIService) error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	c.mu.Lock()
+	id, session := c.id, c.session
+	stopHeartbeat := c.stopHeartbeat
+	c.stopHeartbeat = nil
+	idleTimer := c.idleTimer
+	c.idleTimer = nil
+	c.mu.Unlock()
+
+	log := logger.WithContext(id, "", "")
+	ctx := driverctx.NewContextWithConnId(context.Background(), id)
+
+	if stopHeartbeat != nil {
+		close(stopHeartbeat)
+	}
+	if idleTimer != nil {
+		idleTimer.Stop()
+	}
+	c.lifecycle.remove(c)
+	if session == nil {
+		// a lazy connection which didn't open its session
+		return nil
+	}
+	_, err := tclient.CloseSession(ctx, &cli_service.TCloseSessionReq{
+		SessionHandle: session.SessionHandle,
 	})
+	logger.UnregisterConnection(id)
+	metrics.Gauge(c.cfg.Metrics, metrics.OpenSessions, -1)
 
 	if err != nil {
 		log.Err(err).Msg("databricks: failed to close connection")
@@ -51,38 +133,139 @@ func (c *conn) Close() error {
 
 // Not supported in Databricks.
 func (c *conn) Begin() (driver.Tx, error) {
-	return nil, errors.New(ErrTransactionsNotSupported)
+	return nil, newDriverError(ErrTransactionsNotSupported, nil)
 }
 
 // Not supported in Databricks.
 func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
-	return nil, errors.New(ErrTransactionsNotSupported)
+	return nil, newDriverError(ErrTransactionsNotSupported, nil)
 }
 
-// Ping attempts to verify that the server is accessible.
-// Returns ErrBadConn if ping fails and consequently DB.Ping will remove the conn from the pool.
+// Ping verifies that the server is accessible and the session of the connection is valid, with a GetInfo request
+// on the session which doesn't run a statement on the warehouse. Authentication failures are returned as is, the
+// other failures as ErrBadConn and consequently DB.Ping removes the conn from the pool and tries another one.
 func (c *conn) Ping(ctx context.Context) error {
 	log := logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "")
-	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	// a ping probes the circuit breaker of the warehouse when it is open
+	ctx = client.NewContextWithPing(driverctx.NewContextWithConnId(ctx, c.id))
 	ctx1, cancel := context.WithTimeout(ctx, c.cfg.PingTimeout)
 	defer cancel()
-	_, err := c.QueryContext(ctx1, "select 1", nil)
-	if err != nil {
-		log.Err(err).Msg("databricks: failed to ping")
-		return driver.ErrBadConn
+	err := c.ping(ctx1)
+	if err == nil {
+		return nil
 	}
-	return nil
+	log.Err(err).Msg("databricks: failed to ping")
+	if isInvalidSession(err) {
+		// the next use of the connection reopens the session
+		c.expired.Store(true)
+	}
+	if isAuthError(err) {
+		return wrapErr(err, "databricks: failed to ping")
+	}
+	return driver.ErrBadConn
+}
+
+// ping sends a request on the session. The sessions of the REST API only exist in the client, so a statement
+// is run instead.
+func (c *conn) ping(ctx context.Context) error {
+	if c.cfg.UseRESTAPI {
+		rows, err := c.QueryContext(driverctx.NewContextWithResultCacheBypass(ctx), "select 1", nil)
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	}
+
+	if err := c.ensureSession(ctx); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	session := c.session
+	c.mu.Unlock()
+	if session == nil {
+		return errors.New("databricks: connection has no session")
+	}
+	_, err := c.client.GetInfo(ctx, &cli_service.TGetInfoReq{
+		SessionHandle: session.SessionHandle,
+		InfoType:      cli_service.TGetInfoType_CLI_SERVER_NAME,
+	})
+	return err
 }
 
-// ResetSession is called prior to executing a query on the connection.
-// The session with this driver does not have any important state to reset before re-use.
+// isAuthError returns true when the server rejected the credentials of a request
+func isAuthError(err error) bool {
+	var reqErr *dbsqlerr.RequestError
+	return errors.As(err, &reqErr) && (reqErr.HTTPStatusCode == http.StatusUnauthorized || reqErr.HTTPStatusCode == http.StatusForbidden)
+}
+
+// ResetSession is called before the connection is reused from the pool. An expired session is reopened, and with
+// the resetSession setting a session whose state was changed by the statements of the connection is replaced.
+// Returns ErrBadConn when the session can't be reopened, so that the pool discards the connection.
 func (c *conn) ResetSession(ctx context.Context) error {
+	if !c.stopIdleTimer() || c.closed.Load() {
+		// the session was closed while the connection was idle, the pool replaces the connection
+		return driver.ErrBadConn
+	}
+	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	var err error
+	if c.expired.Load() {
+		err = c.reopenSession(ctx)
+	} else if c.cfg.ResetSessions {
+		err = c.resetSession(ctx)
+	}
+	if err != nil {
+		logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "").Err(err).Msg("databricks: failed to reset session")
+		return driver.ErrBadConn
+	}
 	return nil
 }
 
-// IsValid signals whether a connection is valid or if it should be discarded.
+// IsValid signals whether a connection is valid or if it should be discarded, e.g. when its session expired and
+// couldn't be reopened. database/sql calls it when the connection is returned to the pool, which starts the timer
+// closing the session of an idle connection.
 func (c *conn) IsValid() bool {
-	return c.session.GetStatus().StatusCode == cli_service.TStatusCode_SUCCESS_STATUS
+	if c.closed.Load() || c.expired.Load() {
+		return false
+	}
+	if c.session == nil {
+		// a lazy connection opens its session with its first statement
+		return c.cfg.LazySession
+	}
+	status := c.session.GetStatus()
+	if status != nil && status.StatusCode != cli_service.TStatusCode_SUCCESS_STATUS {
+		return false
+	}
+	c.startIdleTimer()
+	return true
+}
+
+// startIdleTimer closes the session once the connection has been idle in the pool for the session idle timeout,
+// before the warehouse closes it
+func (c *conn) startIdleTimer() {
+	if c.cfg == nil || c.cfg.SessionIdleTimeout <= 0 {
+		return
+	}
+	timeout := c.cfg.SessionIdleTimeout
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+	}
+	connId := c.id
+	c.idleTimer = time.AfterFunc(timeout, func() {
+		logger.WithContext(connId, "", "").Info().Msgf("databricks: closing the session of a connection idle for %s", timeout)
+		_ = c.close(c.client)
+	})
+}
+
+// stopIdleTimer stops the idle timer when database/sql takes the connection from the pool, it returns false when
+// the timer already closed the session
+func (c *conn) stopIdleTimer() bool {
+	c.mu.Lock()
+	idleTimer := c.idleTimer
+	c.idleTimer = nil
+	c.mu.Unlock()
+	return idleTimer == nil || idleTimer.Stop()
 }
 
 // ExecContext executes a query that doesn't return rows, such
@@ -97,10 +280,25 @@ func (c *conn) ExecContext(ctx context.Context, query string, args []driver.Name
 	defer log.Duration(msg, start)
 
 	ctx = driverctx.NewContextWithConnId(ctx, c.id)
-	if len(args) > 0 {
-		return nil, errors.New(ErrParametersNotSupported)
+	if statements := splitStatements(query); len(statements) > 1 {
+		if err := c.checkReadOnly(statements...); err != nil {
+			return nil, err
+		}
+		return c.execScript(ctx, statements, args)
 	}
+	ctx, timing := c.startQueryTiming(ctx, query)
 	exStmtResp, opStatusResp, err := c.runQuery(ctx, query, args)
+	timing.finish(exStmtResp)
+	defer timing.report(err)
+
+	if err == nil && isStagingStatement(query) {
+		res, err := c.execStagingOperation(ctx, exStmtResp)
+		if err != nil {
+			log.Err(err).Msg("databricks: failed to execute staging operation")
+			return nil, wrapErrf(err, "failed to execute staging operation")
+		}
+		return res, nil
+	}
 
 	if exStmtResp != nil && exStmtResp.OperationHandle != nil {
 		// we have an operation id so update the logger
@@ -108,7 +306,7 @@ func (c *conn) ExecContext(ctx context.Context, query string, args []driver.Name
 
 		// since we have an operation handle we can close the operation if necessary
 		alreadyClosed := exStmtResp.DirectResults != nil && exStmtResp.DirectResults.CloseOperation != nil
-		newCtx := driverctx.NewContextWithCorrelationId(driverctx.NewContextWithConnId(context.Background(), c.id), corrId)
+		newCtx := newRequestContext(ctx, c.id, corrId)
 		if !alreadyClosed && (opStatusResp == nil || opStatusResp.GetOperationState() != cli_service.TOperationState_CLOSED_STATE) {
 			_, err1 := c.client.CloseOperation(newCtx, &cli_service.TCloseOperationReq{
 				OperationHandle: exStmtResp.OperationHandle,
@@ -123,11 +321,47 @@ func (c *conn) ExecContext(ctx context.Context, query string, args []driver.Name
 		return nil, wrapErrf(err, "failed to execute query")
 	}
 
-	res := result{AffectedRows: opStatusResp.GetNumModifiedRows()}
+	res := result{
+		AffectedRows: c.affectedRows(exStmtResp, opStatusResp),
+		cfg:          c.cfg,
+		httpClient:   c.httpClient,
+	}
+	if exStmtResp.GetOperationHandle() != nil {
+		res.queryId = client.SprintGuid(exStmtResp.OperationHandle.OperationId.GUID)
+	}
+	if c.cfg.LastInsertId && res.AffectedRows > 0 {
+		if table := insertTable(query); table != nil {
+			res.InsertId = c.lastInsertId(ctx, table)
+		}
+	}
 
 	return &res, nil
 }
 
+// affectedRows returns the rows modified by a statement from its status or, when the server doesn't report them,
+// from the num_affected_rows column of the DML results received with the statement
+func (c *conn) affectedRows(exStmtResp *cli_service.TExecuteStatementResp, opStatusResp *cli_service.TGetOperationStatusResp) int64 {
+	if opStatusResp.IsSetNumModifiedRows() {
+		return opStatusResp.GetNumModifiedRows()
+	}
+	directResults := exStmtResp.GetDirectResults()
+	if directResults == nil || directResults.ResultSet == nil || directResults.ResultSetMetadata == nil || directResults.ResultSetMetadata.Schema == nil {
+		return 0
+	}
+	columns := directResults.ResultSetMetadata.Schema.Columns
+	if len(columns) == 0 || columns[0].ColumnName != "num_affected_rows" {
+		return 0
+	}
+	// the operation is closed, only the rows received with the statement can be read
+	r := NewRows(c.id, "", c.client, nil, c.cfg, directResults).(*rows)
+	dest := make([]driver.Value, len(columns))
+	if !r.isNextRowInPage() || r.Next(dest) != nil {
+		return 0
+	}
+	rows, _ := dest[0].(int64)
+	return rows
+}
+
 // QueryContext executes a query that may return rows, such as a
 // SELECT.
 //
@@ -139,12 +373,32 @@ func (c *conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 	msg, start := log.Track("QueryContext")
 
 	ctx = driverctx.NewContextWithConnId(ctx, c.id)
-	if len(args) > 0 {
-		return nil, errors.New(ErrParametersNotSupported)
+	if statements := splitStatements(query); len(statements) > 1 {
+		// the statements of a script run one result set at a time, they are all checked before the first one runs
+		if err := c.checkReadOnly(statements...); err != nil {
+			return nil, err
+		}
+		return c.queryScript(ctx, statements, args)
+	}
+	var cacheKey string
+	if c.cfg.ResultCache != nil && isCacheableQuery(query) {
+		cacheKey = c.resultCacheKey(ctx, query, args)
+	}
+	if cacheKey != "" {
+		if !driverctx.ResultCacheBypassFromContext(ctx) {
+			if result, ok := c.cfg.ResultCache.Get(ctx, cacheKey); ok {
+				metrics.Counter(c.cfg.Metrics, metrics.CacheHits, 1)
+				log.Duration(msg, start)
+				return &cachedRows{result: result}, nil
+			}
+		}
+		metrics.Counter(c.cfg.Metrics, metrics.CacheMisses, 1)
 	}
 	// first we try to get the results synchronously.
 	// at any point in time that the context is done we must cancel and return
+	ctx, timing := c.startQueryTiming(ctx, query)
 	exStmtResp, _, err := c.runQuery(ctx, query, args)
+	timing.finish(exStmtResp)
 
 	if exStmtResp != nil && exStmtResp.OperationHandle != nil {
 		log = logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), client.SprintGuid(exStmtResp.OperationHandle.OperationId.GUID))
@@ -153,18 +407,62 @@ func (c *conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 
 	if err != nil {
 		log.Err(err).Msg("databricks: failed to run query") // To log query we need to redact credentials
+		timing.report(err)
 		return nil, wrapErrf(err, "failed to run query")
 	}
 	// hold on to the operation handle
 	opHandle := exStmtResp.OperationHandle
 
-	rows := NewRows(c.id, corrId, c.client, opHandle, int64(c.cfg.MaxRows), c.cfg.Location, exStmtResp.DirectResults)
+	rows := NewRows(c.id, corrId, c.client, opHandle, c.cfg, exStmtResp.DirectResults)
+	setQueryTiming(rows, timing)
+	setLifecycle(rows, c.lifecycle)
+	setRequestContext(rows, ctx)
+	setPrefetchClient(rows, c.prefetchClient())
+	if cacheKey != "" {
+		return c.newCachingRows(ctx, cacheKey, rows), nil
+	}
 
 	return rows, nil
 
 }
 
 func (c *conn) runQuery(ctx context.Context, query string, args []driver.NamedValue) (*cli_service.TExecuteStatementResp, *cli_service.TGetOperationStatusResp, error) {
+	// the query is canceled when the connector is closed
+	ctx, done, err := c.lifecycle.begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer done()
+	defer metrics.Duration(c.cfg.Metrics, metrics.QueryDuration, time.Now())
+	metrics.Gauge(c.cfg.Metrics, metrics.ActiveQueries, 1)
+	defer metrics.Gauge(c.cfg.Metrics, metrics.ActiveQueries, -1)
+	if c.outstanding != nil {
+		c.outstanding.Add(1)
+		defer c.outstanding.Add(-1)
+	}
+	if len(c.cfg.StatementInterceptors) == 0 {
+		exStmtResp, opStatusResp, err := c.runStatement(ctx, query, args)
+		if err == nil {
+			c.trackStatement(query)
+		}
+		return exStmtResp, opStatusResp, err
+	}
+
+	var exStmtResp *cli_service.TExecuteStatementResp
+	var opStatusResp *cli_service.TGetOperationStatusResp
+	err = c.intercept(ctx, query, args, func(ctx context.Context, query string, args []driver.NamedValue) error {
+		var err error
+		exStmtResp, opStatusResp, err = c.runStatement(ctx, query, args)
+		if err == nil {
+			c.trackStatement(query)
+		}
+		return err
+	})
+	return exStmtResp, opStatusResp, err
+}
+
+// runStatement executes a statement and waits for it to finish
+func (c *conn) runStatement(ctx context.Context, query string, args []driver.NamedValue) (*cli_service.TExecuteStatementResp, *cli_service.TGetOperationStatusResp, error) {
 	log := logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "")
 	// first we try to get the results synchronously.
 	// at any point in time that the context is done we must cancel and return
@@ -183,6 +481,10 @@ func (c *conn) runQuery(ctx context.Context, query string, args []driver.NamedVa
 
 	if exStmtResp.DirectResults != nil {
 		opStatus := exStmtResp.DirectResults.GetOperationStatus()
+		observeQueryState(ctx, opStatus.GetOperationState())
+		if callback := driverctx.ProgressCallbackFromContext(ctx); callback != nil && opStatus != nil && opHandle != nil {
+			callback(newQueryProgress(opHandle, opStatus))
+		}
 
 		switch opStatus.GetOperationState() {
 		// terminal states
@@ -195,7 +497,7 @@ func (c *conn) runQuery(ctx context.Context, query string, args []driver.NamedVa
 			cli_service.TOperationState_ERROR_STATE,
 			cli_service.TOperationState_TIMEDOUT_STATE:
 			logBadQueryState(log, opStatus)
-			return exStmtResp, opStatus, errors.New(opStatus.GetDisplayMessage())
+			return exStmtResp, opStatus, c.newExecutionError(ctx, opHandle, opStatus)
 		// live states
 		case cli_service.TOperationState_INITIALIZED_STATE,
 			cli_service.TOperationState_PENDING_STATE,
@@ -215,7 +517,7 @@ func (c *conn) runQuery(ctx context.Context, query string, args []driver.NamedVa
 				cli_service.TOperationState_ERROR_STATE,
 				cli_service.TOperationState_TIMEDOUT_STATE:
 				logBadQueryState(log, statusResp)
-				return exStmtResp, statusResp, errors.New(statusResp.GetDisplayMessage())
+				return exStmtResp, statusResp, c.newExecutionError(ctx, opHandle, statusResp)
 				// live states
 			default:
 				logBadQueryState(log, statusResp)
@@ -243,7 +545,7 @@ func (c *conn) runQuery(ctx context.Context, query string, args []driver.NamedVa
 			cli_service.TOperationState_ERROR_STATE,
 			cli_service.TOperationState_TIMEDOUT_STATE:
 			logBadQueryState(log, statusResp)
-			return exStmtResp, statusResp, errors.New(statusResp.GetDisplayMessage())
+			return exStmtResp, statusResp, c.newExecutionError(ctx, opHandle, statusResp)
 			// live states
 		default:
 			logBadQueryState(log, statusResp)
@@ -258,48 +560,181 @@ func logBadQueryState(log *logger.DBSQLLogger, opStatus *cli_service.TGetOperati
 }
 
 func (c *conn) executeStatement(ctx context.Context, query string, args []driver.NamedValue) (*cli_service.TExecuteStatementResp, error) {
-	corrId := driverctx.CorrelationIdFromContext(ctx)
-	log := logger.WithContext(c.id, corrId, "")
+	c.lastUsed.Store(time.Now().UnixNano())
+	if c.expired.Load() {
+		if err := c.reopenSession(ctx); err != nil {
+			return nil, err
+		}
+	}
+	req, err := c.newExecuteStatementReq(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	// the server stops the query when the client stops waiting for it, asynchronous queries outlive their context
+	// so they keep the query timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout := timeoutSeconds(time.Until(deadline)); timeout > 0 && (req.QueryTimeout == 0 || timeout < req.QueryTimeout) {
+			req.QueryTimeout = timeout
+		}
+	}
+	resp, err := c.submitStatement(ctx, req)
+	// the statement didn't run when the session is invalid, so it runs again in a new session
+	if isInvalidSession(err) && !c.reopening {
+		if err1 := c.reopenSession(ctx); err1 != nil {
+			return resp, err
+		}
+		req.SessionHandle = c.session.SessionHandle
+		resp, err = c.submitStatement(ctx, req)
+	}
+	return resp, err
+}
+
+// newExecuteStatementReq returns the request executing query with the connection settings,
+// overridden by the query timeout, statement tags, catalog and schema of ctx
+func (c *conn) newExecuteStatementReq(ctx context.Context, query string, args []driver.NamedValue) (*cli_service.TExecuteStatementReq, error) {
+	if err := c.checkReadOnly(query); err != nil {
+		return nil, err
+	}
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	queryTimeout := c.cfg.QueryTimeout
+	if timeout, ok := driverctx.QueryTimeoutFromContext(ctx); ok {
+		queryTimeout = timeout
+	}
 
-	req := cli_service.TExecuteStatementReq{
+	// the features the server doesn't support aren't requested
+	caps := c.ServerCapabilities()
+	req := &cli_service.TExecuteStatementReq{
 		SessionHandle: c.session.SessionHandle,
 		Statement:     query,
 		RunAsync:      c.cfg.RunAsync,
-		QueryTimeout:  int64(c.cfg.QueryTimeout / time.Second),
-		GetDirectResults: &cli_service.TSparkGetDirectResults{
+		QueryTimeout:  timeoutSeconds(queryTimeout),
+	}
+	if caps.DirectResults {
+		req.GetDirectResults = &cli_service.TSparkGetDirectResults{
 			MaxRows: int64(c.cfg.MaxRows),
-		},
+		}
+	}
+
+	// the operation id generated by the driver is the idempotency token of the statement, a request retried after
+	// a transport error has the same id so the server doesn't run the statement twice
+	guid := make([]byte, 16)
+	if _, err := rand.Read(guid); err != nil {
+		return nil, errors.Wrap(err, "databricks: failed to generate operation id")
+	}
+	req.OperationId = &cli_service.THandleIdentifier{GUID: guid, Secret: []byte{}}
+
+	// the conf of the statement only, the session params of the connection don't change
+	if c.cfg.StatementConf != nil {
+		for k, v := range c.cfg.StatementConf(ctx) {
+			if req.ConfOverlay == nil {
+				req.ConfOverlay = make(map[string]string)
+			}
+			req.ConfOverlay[k] = v
+		}
+	}
+	if tags := statementTags(ctx, c.cfg.StatementTags); len(tags) > 0 {
+		if req.ConfOverlay == nil {
+			req.ConfOverlay = make(map[string]string)
+		}
+		req.ConfOverlay[queryTagsConf] = formatQueryTags(tags)
 	}
 
+	// the namespace of the statement only, the current namespace of the session doesn't change
+	catalog, schema := driverctx.CatalogFromContext(ctx), driverctx.SchemaFromContext(ctx)
+	if catalog != "" || schema != "" {
+		req.SessionConf = &cli_service.TDBSqlSessionConf{}
+		if catalog != "" {
+			req.SessionConf.CurrentCatalog = &catalog
+		}
+		if schema != "" {
+			req.SessionConf.CurrentDatabase = &schema
+		}
+	}
+
+	if len(args) > 0 {
+		query, args = expandListParameters(query, args)
+		// parameter markers are bound by the server, which supports them from protocol V8
+		if !caps.Parameters {
+			if !c.cfg.InterpolateParams {
+				return nil, newDriverError(ErrParametersNotSupported, nil)
+			}
+			statement, err := interpolateParameters(query, args)
+			if err != nil {
+				return nil, err
+			}
+			req.Statement = statement
+		} else {
+			params, err := convertParameters(args)
+			if err != nil {
+				return nil, err
+			}
+			req.Parameters = params
+			req.Statement = bindBinaryParameters(query, args)
+		}
+	}
+
+	if c.cfg.UseArrowBatches && caps.ArrowResults {
+		// only timestamps are sent as native Arrow types, the other types are sent as
+		// strings the same way as in columnar results
+		req.CanReadArrowResult_ = thrift.BoolPtr(true)
+		req.UseArrowNativeTypes = &cli_service.TSparkArrowTypes{
+			TimestampAsArrow:     thrift.BoolPtr(true),
+			DecimalAsArrow:       thrift.BoolPtr(false),
+			ComplexTypesAsArrow:  thrift.BoolPtr(false),
+			IntervalTypesAsArrow: thrift.BoolPtr(false),
+		}
+		if c.cfg.UseCloudFetch && caps.CloudFetch {
+			req.CanDownloadResult_ = thrift.BoolPtr(true)
+		}
+		if c.cfg.UseLz4Compression && caps.LZ4Compression {
+			req.CanDecompressLZ4Result_ = thrift.BoolPtr(true)
+		}
+	}
+
+	return req, nil
+}
+
+// submitStatement sends an execute statement request, the operation is canceled if the context is done meanwhile
+func (c *conn) submitStatement(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+	corrId := driverctx.CorrelationIdFromContext(ctx)
+	log := logger.WithContext(c.id, corrId, "")
+
 	ctx = driverctx.NewContextWithConnId(ctx, c.id)
-	resp, err := c.client.ExecuteStatement(ctx, &req)
+	if !c.cfg.RetryNonIdempotent && !isReadOnlyStatement(req.Statement) {
+		ctx = client.NewContextWithoutUnsafeRetries(ctx)
+	}
+	resp, err := c.client.ExecuteStatement(ctx, req)
+
+	if err == nil && resp.GetOperationHandle() != nil && resp.OperationHandle.OperationId != nil {
+		if callback := driverctx.QueryIdCallbackFromContext(ctx); callback != nil {
+			callback(client.SprintGuid(resp.OperationHandle.OperationId.GUID))
+		}
+	}
 
 	var shouldCancel = func(resp *cli_service.TExecuteStatementResp) bool {
 		if resp == nil {
 			return false
 		}
 		hasHandle := resp.OperationHandle != nil
-		isOpen := resp.DirectResults != nil && resp.DirectResults.CloseOperation == nil
+		isOpen := resp.DirectResults == nil || resp.DirectResults.CloseOperation == nil
 		return hasHandle && isOpen
 	}
 
 	select {
 	default:
 	case <-ctx.Done():
-		newCtx := driverctx.NewContextWithCorrelationId(driverctx.NewContextWithConnId(context.Background(), c.id), corrId)
 		// in case context is done, we need to cancel the operation if necessary
 		if err == nil && shouldCancel(resp) {
-			log.Debug().Msg("databricks: canceling query")
-			_, err1 := c.client.CancelOperation(newCtx, &cli_service.TCancelOperationReq{
-				OperationHandle: resp.GetOperationHandle(),
-			})
-
-			if err1 != nil {
-				log.Err(err).Msgf("databricks: cancel failed")
-			} else {
-				log.Debug().Msgf("databricks: cancel success")
-			}
-
+			_ = c.cancelOperation(corrId, resp.GetOperationHandle(), ctx.Err())
+		} else if err != nil && !req.RunAsync && req.OperationId != nil {
+			// a synchronous statement runs until the response is sent, it is canceled by the operation id
+			// generated by the driver
+			_ = c.cancelOperation(corrId, &cli_service.TOperationHandle{
+				OperationId:   req.OperationId,
+				OperationType: cli_service.TOperationType_EXECUTE_STATEMENT,
+			}, ctx.Err())
 		} else {
 			log.Debug().Msg("databricks: query did not need cancellation")
 		}
@@ -314,7 +749,8 @@ func (c *conn) pollOperation(ctx context.Context, opHandle *cli_service.TOperati
 	log := logger.WithContext(c.id, corrId, client.SprintGuid(opHandle.OperationId.GUID))
 	var statusResp *cli_service.TGetOperationStatusResp
 	ctx = driverctx.NewContextWithConnId(ctx, c.id)
-	newCtx := driverctx.NewContextWithCorrelationId(driverctx.NewContextWithConnId(context.Background(), c.id), corrId)
+	newCtx := newRequestContext(ctx, c.id, corrId)
+	progressCallback := driverctx.ProgressCallbackFromContext(ctx)
 	pollSentinel := sentinel.Sentinel{
 		OnDoneFn: func(statusResp any) (any, error) {
 			return statusResp, nil
@@ -322,11 +758,19 @@ func (c *conn) pollOperation(ctx context.Context, opHandle *cli_service.TOperati
 		StatusFn: func() (sentinel.Done, any, error) {
 			var err error
 			log.Debug().Msg("databricks: polling status")
-			statusResp, err = c.client.GetOperationStatus(newCtx, &cli_service.TGetOperationStatusReq{
+			statusReq := &cli_service.TGetOperationStatusReq{
 				OperationHandle: opHandle,
-			})
+			}
+			if progressCallback != nil {
+				statusReq.GetProgressUpdate = thrift.BoolPtr(true)
+			}
+			statusResp, err = c.client.GetOperationStatus(newCtx, statusReq)
 			if statusResp != nil && statusResp.OperationState != nil {
 				log.Debug().Msgf("databricks: status %s", statusResp.GetOperationState().String())
+				observeQueryState(ctx, statusResp.GetOperationState())
+			}
+			if err == nil && progressCallback != nil {
+				progressCallback(newQueryProgress(opHandle, statusResp))
 			}
 			return func() bool {
 				if err != nil {
@@ -344,11 +788,7 @@ func (c *conn) pollOperation(ctx context.Context, opHandle *cli_service.TOperati
 			}, statusResp, err
 		},
 		OnCancelFn: func() (any, error) {
-			log.Debug().Msg("databricks: canceling query")
-			ret, err := c.client.CancelOperation(newCtx, &cli_service.TCancelOperationReq{
-				OperationHandle: opHandle,
-			})
-			return ret, err
+			return nil, c.cancelOperation(corrId, opHandle, ctx.Err())
 		},
 	}
 	_, resp, err := pollSentinel.Watch(ctx, c.cfg.PollInterval, 0)
@@ -362,6 +802,93 @@ func (c *conn) pollOperation(ctx context.Context, opHandle *cli_service.TOperati
 	return statusResp, nil
 }
 
+// newQueryProgress converts an operation status to the progress reported to the progress callback
+func newQueryProgress(opHandle *cli_service.TOperationHandle, statusResp *cli_service.TGetOperationStatusResp) driverctx.QueryProgress {
+	progress := driverctx.QueryProgress{
+		QueryId:    client.SprintGuid(opHandle.GetOperationId().GetGUID()),
+		State:      statusResp.GetOperationState().String(),
+		Progress:   -1,
+		TaskStatus: statusResp.GetTaskStatus(),
+		Message:    statusResp.GetDisplayMessage(),
+	}
+	if progress.Message == "" {
+		progress.Message = statusResp.GetErrorMessage()
+	}
+	if update := statusResp.GetProgressUpdateResponse(); update != nil {
+		progress.Progress = update.ProgressedPercentage
+	}
+	if statusResp.IsSetOperationStarted() {
+		progress.Started = time.UnixMilli(statusResp.GetOperationStarted())
+	}
+	return progress
+}
+
+// prefetchClient returns the function opening the client of the rows prefetching the results of a query, nil
+// when the client of the connection is safe for concurrent use
+func (c *conn) prefetchClient() func() (cli_service.TCLIService, error) {
+	if c.cfg.UseRESTAPI || c.httpClient == nil {
+		return nil
+	}
+	return func() (cli_service.TCLIService, error) {
+		tclient, err := client.InitThriftClient(c.cfg, c.httpClient)
+		if err != nil {
+			return nil, wrapErr(err, "error initializing thrift client")
+		}
+		return tclient, nil
+	}
+}
+
+// detachedContext has the values of the context of a query, e.g. its correlation id and user agent tag, without
+// its deadline and cancellation. The requests polling a query, fetching its results and closing it use it, they
+// run after the query context is done or are stopped by the driver.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+func (c detachedContext) Value(key any) any         { return c.parent.Value(key) }
+
+// newRequestContext returns the context of the requests of a query run with ctx on connection connId
+func newRequestContext(ctx context.Context, connId, corrId string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	} else {
+		ctx = detachedContext{parent: ctx}
+	}
+	return driverctx.NewContextWithCorrelationId(driverctx.NewContextWithConnId(ctx, connId), corrId)
+}
+
+// cancelOperation stops a query on the server after its context is done with cause. The query context can't
+// be used for the request, so it runs with a new context limited by the cancel grace period.
+func (c *conn) cancelOperation(corrId string, opHandle *cli_service.TOperationHandle, cause error) error {
+	log := logger.WithContext(c.id, corrId, client.SprintGuid(opHandle.GetOperationId().GetGUID()))
+	ctx := driverctx.NewContextWithCorrelationId(driverctx.NewContextWithConnId(context.Background(), c.id), corrId)
+	if c.cfg.CancelGracePeriod > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.CancelGracePeriod)
+		defer cancel()
+	}
+
+	log.Debug().Msg("databricks: canceling query")
+	start := time.Now()
+	_, err := c.client.CancelOperation(ctx, &cli_service.TCancelOperationReq{
+		OperationHandle: opHandle,
+	})
+	event := log.Info()
+	if err != nil {
+		event = log.Warn().Err(err)
+	}
+	event.Str("event", "cancel").AnErr("cause", cause).Bool("canceled", err == nil).Dur("elapsed", time.Since(start))
+	if err != nil {
+		event.Msg("databricks: failed to cancel query")
+		return wrapErr(err, "failed to cancel query")
+	}
+	event.Msg("databricks: query canceled")
+	return nil
+}
+
 var _ driver.Conn = (*conn)(nil)
 var _ driver.Pinger = (*conn)(nil)
 var _ driver.SessionResetter = (*conn)(nil)
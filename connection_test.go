@@ -4,15 +4,22 @@ import (
 	"context"
 	"database/sql/driver"
 	"fmt"
+	"net/http"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 
+	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
 	"github.com/databricks/databricks-sql-go/internal/cli_service"
 	"github.com/databricks/databricks-sql-go/internal/client"
 	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/databricks/databricks-sql-go/metrics"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConn_executeStatement(t *testing.T) {
@@ -36,6 +43,27 @@ func TestConn_executeStatement(t *testing.T) {
 		assert.Equal(t, 1, executeStatementCount)
 	})
 
+	t.Run("executeStatement should send a new operation id with each statement", func(t *testing.T) {
+		var operationIds [][]byte
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (r *cli_service.TExecuteStatementResp, err error) {
+				require.NotNil(t, req.OperationId)
+				operationIds = append(operationIds, req.OperationId.GUID)
+				return nil, fmt.Errorf("error")
+			},
+		}
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     config.WithDefaults(),
+		}
+		_, _ = testConn.executeStatement(context.Background(), "insert into t values (1)", []driver.NamedValue{})
+		_, _ = testConn.executeStatement(context.Background(), "insert into t values (1)", []driver.NamedValue{})
+		require.Len(t, operationIds, 2)
+		assert.Len(t, operationIds[0], 16)
+		assert.NotEqual(t, operationIds[0], operationIds[1])
+	})
+
 	t.Run("executeStatement should return TExecuteStatementResp on success", func(t *testing.T) {
 		var executeStatementCount int
 		executeStatement := func(ctx context.Context, req *cli_service.TExecuteStatementReq) (r *cli_service.TExecuteStatementResp, err error) {
@@ -87,6 +115,401 @@ func TestConn_executeStatement(t *testing.T) {
 		assert.Equal(t, 1, executeStatementCount)
 	})
 
+	t.Run("executeStatement should request arrow results when enabled", func(t *testing.T) {
+		for _, useArrowBatches := range []bool{true, false} {
+			var req *cli_service.TExecuteStatementReq
+			testClient := &client.TestClient{
+				FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+					req = r
+					return &cli_service.TExecuteStatementResp{}, nil
+				},
+			}
+			cfg := config.WithDefaults()
+			cfg.UseArrowBatches = useArrowBatches
+			testConn := &conn{
+				session: getTestSessionV8(),
+				client:  testClient,
+				cfg:     cfg,
+			}
+			_, err := testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+			assert.NoError(t, err)
+			assert.Equal(t, useArrowBatches, req.GetCanReadArrowResult_())
+			assert.Equal(t, useArrowBatches, req.IsSetUseArrowNativeTypes())
+			assert.False(t, req.IsSetCanDownloadResult_())
+			assert.Equal(t, useArrowBatches, req.GetCanDecompressLZ4Result_())
+		}
+	})
+
+	t.Run("executeStatement should request result links when cloud fetch is enabled", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				req = r
+				return &cli_service.TExecuteStatementResp{}, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.UseCloudFetch = true
+		testConn := &conn{
+			session: getTestSessionV8(),
+			client:  testClient,
+			cfg:     cfg,
+		}
+		_, err := testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.True(t, req.GetCanReadArrowResult_())
+		assert.True(t, req.GetCanDownloadResult_())
+	})
+
+	t.Run("executeStatement should not accept lz4 results when compression is disabled", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				req = r
+				return &cli_service.TExecuteStatementResp{}, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.UseLz4Compression = false
+		testConn := &conn{
+			session: getTestSessionV8(),
+			client:  testClient,
+			cfg:     cfg,
+		}
+		_, err := testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.True(t, req.GetCanReadArrowResult_())
+		assert.False(t, req.IsSetCanDecompressLZ4Result_())
+	})
+
+	t.Run("executeStatement should not request the features the server doesn't support", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				req = r
+				return &cli_service.TExecuteStatementResp{}, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.UseCloudFetch = true
+		session := getTestSession()
+		session.ServerProtocolVersion = cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V4
+		testConn := &conn{
+			session: session,
+			client:  testClient,
+			cfg:     cfg,
+		}
+		_, err := testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.False(t, req.IsSetCanReadArrowResult_())
+		assert.False(t, req.IsSetCanDownloadResult_())
+		assert.False(t, req.IsSetCanDecompressLZ4Result_())
+		assert.NotNil(t, req.GetDirectResults)
+
+		session.ServerProtocolVersion = cli_service.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V10
+		_, err = testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Nil(t, req.GetDirectResults)
+	})
+
+	t.Run("executeStatement should send query parameters", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				req = r
+				return &cli_service.TExecuteStatementResp{}, nil
+			},
+		}
+		session := getTestSession()
+		session.ServerProtocolVersion = cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8
+		testConn := &conn{
+			session: session,
+			client:  testClient,
+			cfg:     config.WithDefaults(),
+		}
+		_, err := testConn.executeStatement(context.Background(), "select :id, :name", []driver.NamedValue{
+			{Name: "id", Ordinal: 1, Value: int64(1)},
+			{Name: "name", Ordinal: 2, Value: "o'brien"},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, req.GetParameters(), 2)
+		assert.Equal(t, "id", req.Parameters[0].GetName())
+		assert.Equal(t, "BIGINT", req.Parameters[0].GetType())
+		assert.Equal(t, "1", req.Parameters[0].GetValue().GetStringValue())
+		assert.Equal(t, "name", req.Parameters[1].GetName())
+		assert.Equal(t, "o'brien", req.Parameters[1].GetValue().GetStringValue())
+		assert.Equal(t, "select :id, :name", req.Statement)
+
+		_, err = testConn.executeStatement(context.Background(), "insert into blobs values (?, ?)", []driver.NamedValue{
+			{Ordinal: 1, Value: int64(1)},
+			{Ordinal: 2, Value: []byte{0x89, 0x50, 0x4e, 0x47}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "insert into blobs values (?, unhex(?))", req.Statement)
+		assert.Equal(t, "STRING", req.Parameters[1].GetType())
+		assert.Equal(t, "89504e47", req.Parameters[1].GetValue().GetStringValue())
+
+		_, err = testConn.executeStatement(context.Background(), "select * from orders where id in (:ids)", []driver.NamedValue{
+			{Name: "ids", Ordinal: 1, Value: parameterList{int64(1), int64(2)}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "select * from orders where id in (:ids__1, :ids__2)", req.Statement)
+		assert.Len(t, req.GetParameters(), 2)
+		assert.Equal(t, "ids__2", req.Parameters[1].GetName())
+		assert.Equal(t, "2", req.Parameters[1].GetValue().GetStringValue())
+
+		_, err = testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.False(t, req.IsSetParameters())
+	})
+
+	t.Run("executeStatement should interpolate query parameters for older servers when enabled", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				req = r
+				return &cli_service.TExecuteStatementResp{}, nil
+			},
+		}
+		session := getTestSession()
+		session.ServerProtocolVersion = cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V7
+		testConn := &conn{
+			session: session,
+			client:  testClient,
+			cfg:     config.WithDefaults(),
+		}
+		args := []driver.NamedValue{
+			{Ordinal: 1, Value: "o'brien"},
+			{Ordinal: 2, Value: parameterList{int64(1), int64(2)}},
+		}
+		_, err := testConn.executeStatement(context.Background(), "select * from orders where customer = ? and id in (?)", args)
+		assert.ErrorContains(t, err, ErrParametersNotSupported)
+
+		testConn.cfg.InterpolateParams = true
+		_, err = testConn.executeStatement(context.Background(), "select * from orders where customer = ? and id in (?)", args)
+		assert.NoError(t, err)
+		assert.Equal(t, `select * from orders where customer = 'o\'brien' and id in (CAST('1' AS BIGINT), CAST('2' AS BIGINT))`, req.Statement)
+		assert.False(t, req.IsSetParameters())
+	})
+
+	t.Run("executeStatement should call the query id callback", func(t *testing.T) {
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				return &cli_service.TExecuteStatementResp{
+					OperationHandle: &cli_service.TOperationHandle{
+						OperationId: &cli_service.THandleIdentifier{
+							GUID: []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54},
+						},
+					},
+				}, nil
+			},
+		}
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     config.WithDefaults(),
+		}
+		var queryId string
+		ctx := driverctx.NewContextWithQueryIdCallback(context.Background(), func(id string) { queryId = id })
+		_, err := testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, "01020304-0217-0402-0302-030404df2236", queryId)
+	})
+
+	t.Run("executeStatement should cancel within the grace period when the context is done", func(t *testing.T) {
+		var cancelDeadline time.Time
+		var cancel context.CancelFunc
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				cancel()
+				return &cli_service.TExecuteStatementResp{
+					OperationHandle: &cli_service.TOperationHandle{
+						OperationId: &cli_service.THandleIdentifier{
+							GUID: []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54},
+						},
+					},
+				}, nil
+			},
+			FnCancelOperation: func(ctx context.Context, req *cli_service.TCancelOperationReq) (*cli_service.TCancelOperationResp, error) {
+				assert.NoError(t, ctx.Err())
+				cancelDeadline, _ = ctx.Deadline()
+				return &cli_service.TCancelOperationResp{}, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.CancelGracePeriod = time.Minute
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     cfg,
+		}
+		ctx, cancelFn := context.WithCancel(context.Background())
+		cancel = cancelFn
+		start := time.Now()
+		_, err := testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.WithinDuration(t, start.Add(time.Minute), cancelDeadline, 5*time.Second)
+	})
+
+	t.Run("executeStatement should use the query timeout, statement tags and correlation id of the context", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				req = r
+				return &cli_service.TExecuteStatementResp{}, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.QueryTimeout = time.Hour
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     cfg,
+		}
+
+		_, err := testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3600), req.QueryTimeout)
+		assert.Nil(t, req.ConfOverlay)
+
+		ctx := driverctx.NewContextWithQueryTimeout(context.Background(), 1500*time.Millisecond)
+		ctx = driverctx.NewContextWithStatementTags(ctx, map[string]string{"workload": "interactive", "team": "a,b:c", "adhoc": ""})
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), req.QueryTimeout)
+		assert.Equal(t, map[string]string{"query_tags": `adhoc,team:a\,b\:c,workload:interactive`}, req.ConfOverlay)
+
+		// the correlation id is a tag, unless a statement tag has the same key
+		ctx = driverctx.NewContextWithCorrelationId(ctx, "req-1")
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"query_tags": `adhoc,correlation_id:req-1,team:a\,b\:c,workload:interactive`}, req.ConfOverlay)
+		_, err = testConn.executeStatement(driverctx.NewContextWithStatementTags(ctx, map[string]string{"correlation_id": "trace-1"}), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"query_tags": `adhoc,correlation_id:trace-1,team:a\,b\:c,workload:interactive`}, req.ConfOverlay)
+
+		// the tags of the connector are overridden by the statement tags
+		testConn.cfg.StatementTags = map[string]string{"service": "billing", "workload": "batch"}
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"query_tags": `adhoc,correlation_id:req-1,service:billing,team:a\,b\:c,workload:interactive`}, req.ConfOverlay)
+		testConn.cfg.StatementTags = map[string]string{"correlation_id": "job-7"}
+		_, err = testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"query_tags": "correlation_id:job-7"}, req.ConfOverlay)
+		testConn.cfg.StatementTags = nil
+
+		ctx = driverctx.NewContextWithQueryTimeout(context.Background(), 0)
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), req.QueryTimeout)
+	})
+
+	t.Run("executeStatement should send the conf computed from the context", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				req = r
+				return &cli_service.TExecuteStatementResp{}, nil
+			},
+		}
+		type tenantKey struct{}
+		cfg := config.WithDefaults()
+		WithStatementConf(func(ctx context.Context) map[string]string {
+			tenant, ok := ctx.Value(tenantKey{}).(string)
+			if !ok {
+				return nil
+			}
+			return map[string]string{"tenant_id": tenant, "query_tags": "ignored"}
+		})(cfg)
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     cfg,
+		}
+
+		_, err := testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Nil(t, req.ConfOverlay)
+
+		ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"tenant_id": "acme", "query_tags": "ignored"}, req.ConfOverlay)
+
+		// the query tags replace the query_tags conf
+		ctx = driverctx.NewContextWithStatementTags(ctx, map[string]string{"workload": "interactive"})
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"tenant_id": "acme", "query_tags": "workload:interactive"}, req.ConfOverlay)
+	})
+
+	t.Run("executeStatement should send the deadline of the context as the query timeout when it is sooner", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				req = r
+				return &cli_service.TExecuteStatementResp{}, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.QueryTimeout = time.Hour
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     cfg,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, err := testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(30), req.QueryTimeout)
+
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Hour)
+		defer cancel()
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3600), req.QueryTimeout)
+
+		cfg.QueryTimeout = 0
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7200), req.QueryTimeout)
+	})
+
+	t.Run("executeStatement should use the catalog and schema of the context", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testConn := &conn{
+			session: getTestSession(),
+			client: &client.TestClient{
+				FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+					req = r
+					return &cli_service.TExecuteStatementResp{}, nil
+				},
+			},
+			cfg: config.WithDefaults(),
+		}
+
+		_, err := testConn.executeStatement(context.Background(), "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		assert.Nil(t, req.SessionConf)
+
+		ctx := driverctx.NewContextWithSchema(driverctx.NewContextWithCatalog(context.Background(), "tenant_a"), "sales")
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		require.NotNil(t, req.SessionConf)
+		assert.Equal(t, "tenant_a", req.SessionConf.GetCurrentCatalog())
+		assert.Equal(t, "sales", req.SessionConf.GetCurrentDatabase())
+
+		ctx = driverctx.NewContextWithSchema(context.Background(), "sales")
+		_, err = testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+		assert.NoError(t, err)
+		require.NotNil(t, req.SessionConf)
+		assert.Nil(t, req.SessionConf.CurrentCatalog)
+		assert.Equal(t, "sales", req.SessionConf.GetCurrentDatabase())
+	})
+
 	t.Run("ExecStatement should close operation on success", func(t *testing.T) {
 		var executeStatementCount, closeOperationCount int
 		executeStatementResp := &cli_service.TExecuteStatementResp{
@@ -321,6 +744,34 @@ func TestConn_executeStatement(t *testing.T) {
 		assert.Equal(t, 1, cancelOperationCount)
 	})
 
+	t.Run("a synchronous statement is canceled by its operation id", func(t *testing.T) {
+		var operationId, canceled *cli_service.THandleIdentifier
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				operationId = req.OperationId
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			FnCancelOperation: func(ctx context.Context, req *cli_service.TCancelOperationReq) (*cli_service.TCancelOperationResp, error) {
+				canceled = req.OperationHandle.OperationId
+				return &cli_service.TCancelOperationResp{}, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.RunAsync = false
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     cfg,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := testConn.executeStatement(ctx, "select 1", []driver.NamedValue{})
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotNil(t, canceled)
+		assert.Equal(t, operationId, canceled)
+	})
 }
 
 func TestConn_pollOperation(t *testing.T) {
@@ -644,6 +1095,48 @@ func TestConn_pollOperation(t *testing.T) {
 		assert.GreaterOrEqual(t, 1, cancelOperationCount)
 		assert.Nil(t, res)
 	})
+
+	t.Run("pollOperation reports the progress to the progress callback", func(t *testing.T) {
+		states := []cli_service.TOperationState{cli_service.TOperationState_RUNNING_STATE, cli_service.TOperationState_FINISHED_STATE}
+		var requests []*cli_service.TGetOperationStatusReq
+		getOperationStatus := func(ctx context.Context, req *cli_service.TGetOperationStatusReq) (r *cli_service.TGetOperationStatusResp, err error) {
+			requests = append(requests, req)
+			resp := &cli_service.TGetOperationStatusResp{
+				OperationState:   cli_service.TOperationStatePtr(states[len(requests)-1]),
+				TaskStatus:       thrift.StringPtr("stage 1"),
+				OperationStarted: thrift.Int64Ptr(1675072800000),
+			}
+			if len(requests) == 1 {
+				resp.ProgressUpdateResponse = &cli_service.TProgressUpdateResp{ProgressedPercentage: 0.5}
+				resp.DisplayMessage = thrift.StringPtr("scanning orders")
+			}
+			return resp, nil
+		}
+		cfg := config.WithDefaults()
+		cfg.PollInterval = time.Millisecond
+		testConn := &conn{
+			session: getTestSession(),
+			client:  &client.TestClient{FnGetOperationStatus: getOperationStatus},
+			cfg:     cfg,
+		}
+		var progress []driverctx.QueryProgress
+		ctx := driverctx.NewContextWithProgressCallback(context.Background(), func(p driverctx.QueryProgress) {
+			progress = append(progress, p)
+		})
+		_, err := testConn.pollOperation(ctx, &cli_service.TOperationHandle{
+			OperationId: &cli_service.THandleIdentifier{
+				GUID:   []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54},
+				Secret: []byte("b"),
+			},
+		})
+		assert.NoError(t, err)
+		assert.True(t, requests[0].GetGetProgressUpdate())
+		started := time.Date(2023, 1, 30, 10, 0, 0, 0, time.UTC)
+		assert.Equal(t, []driverctx.QueryProgress{
+			{QueryId: "01020304-0217-0402-0302-030404df2236", State: "RUNNING_STATE", Progress: 0.5, TaskStatus: "stage 1", Message: "scanning orders", Started: started.Local()},
+			{QueryId: "01020304-0217-0402-0302-030404df2236", State: "FINISHED_STATE", Progress: -1, TaskStatus: "stage 1", Started: started.Local()},
+		}, progress)
+	})
 }
 
 func TestConn_runQuery(t *testing.T) {
@@ -1018,9 +1511,53 @@ func TestConn_runQuery(t *testing.T) {
 	})
 }
 
+func TestConn_runQueryExecutionError(t *testing.T) {
+	errorState := cli_service.TOperationState_ERROR_STATE
+	testClient := &client.TestClient{
+		FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+			return &cli_service.TExecuteStatementResp{
+				Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+				OperationHandle: &cli_service.TOperationHandle{
+					OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, Secret: []byte("b")},
+				},
+				DirectResults: &cli_service.TSparkDirectResults{
+					OperationStatus: &cli_service.TGetOperationStatusResp{
+						OperationState: &errorState,
+						SqlState:       strPtr("42P01"),
+						DisplayMessage: strPtr("[TABLE_OR_VIEW_NOT_FOUND] The table or view `t` cannot be found."),
+					},
+					CloseOperation: &cli_service.TCloseOperationResp{},
+				},
+			}, nil
+		},
+	}
+	testConn := &conn{
+		id:      "conn1",
+		session: getTestSession(),
+		client:  testClient,
+		cfg:     config.WithDefaults(),
+	}
+
+	ctx := driverctx.NewContextWithCorrelationId(context.Background(), "corr1")
+	_, err := testConn.ExecContext(ctx, "select * from t", nil)
+	assert.ErrorIs(t, err, dbsqlerr.ErrExecution)
+	var execErr *dbsqlerr.ExecutionError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, "42P01", execErr.SQLState)
+	assert.Equal(t, "TABLE_OR_VIEW_NOT_FOUND", execErr.ErrorClass)
+	assert.Equal(t, "ERROR_STATE", execErr.QueryState)
+	assert.Equal(t, "01020304-0506-0708-090a-0b0c0d0e0f10", execErr.QueryId)
+	assert.Equal(t, "conn1", execErr.ConnectionId)
+	assert.Equal(t, "corr1", execErr.CorrelationId)
+	assert.False(t, dbsqlerr.IsRetryable(err))
+
+	_, err = testConn.BeginTx(ctx, driver.TxOptions{})
+	assert.ErrorIs(t, err, dbsqlerr.ErrDriver)
+}
+
 func TestConn_ExecContext(t *testing.T) {
 	t.Parallel()
-	t.Run("ExecContext currently does not support query parameters", func(t *testing.T) {
+	t.Run("ExecContext returns err when the server does not support query parameters", func(t *testing.T) {
 		var executeStatementCount int
 
 		testClient := &client.TestClient{}
@@ -1125,6 +1662,53 @@ func TestConn_ExecContext(t *testing.T) {
 		assert.Equal(t, int64(10), rowsAffected)
 		assert.Equal(t, 1, executeStatementCount)
 	})
+	t.Run("ExecContext reads the rows modified from the DML results when the status doesn't have them", func(t *testing.T) {
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				return &cli_service.TExecuteStatementResp{
+					Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+					OperationHandle: &cli_service.TOperationHandle{
+						OperationId: &cli_service.THandleIdentifier{
+							GUID:   []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54},
+							Secret: []byte("b"),
+						},
+					},
+					DirectResults: &cli_service.TSparkDirectResults{
+						OperationStatus: &cli_service.TGetOperationStatusResp{
+							OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+						},
+						ResultSetMetadata: &cli_service.TGetResultSetMetadataResp{
+							Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{{
+								ColumnName: "num_affected_rows",
+								TypeDesc: &cli_service.TTypeDesc{
+									Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: cli_service.TTypeId_BIGINT_TYPE}}},
+								},
+							}}},
+						},
+						ResultSet: &cli_service.TFetchResultsResp{
+							Results: &cli_service.TRowSet{
+								Columns: []*cli_service.TColumn{{I64Val: &cli_service.TI64Column{Values: []int64{7}}}},
+							},
+						},
+						CloseOperation: &cli_service.TCloseOperationResp{},
+					},
+				}, nil
+			},
+		}
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     config.WithDefaults(),
+		}
+		res, err := testConn.ExecContext(context.Background(), "delete from t where id < 8", nil)
+		require.NoError(t, err)
+		rowsAffected, _ := res.RowsAffected()
+		assert.Equal(t, int64(7), rowsAffected)
+		assert.Equal(t, "01020304-0217-0402-0302-030404df2236", res.(Result).QueryId())
+
+		_, err = res.(Result).Stats(context.Background())
+		assert.ErrorContains(t, err, ErrStatsNotAvailable, "the connection has no HTTP client")
+	})
 	t.Run("ExecContext uses new context to close operation", func(t *testing.T) {
 		var executeStatementCount, getOperationStatusCount, closeOperationCount, cancelOperationCount int
 		var cancel context.CancelFunc
@@ -1193,7 +1777,7 @@ func TestConn_ExecContext(t *testing.T) {
 
 func TestConn_QueryContext(t *testing.T) {
 	t.Parallel()
-	t.Run("QueryContext currently does not support query parameters", func(t *testing.T) {
+	t.Run("QueryContext returns err when the server does not support query parameters", func(t *testing.T) {
 		var executeStatementCount int
 
 		testClient := &client.TestClient{}
@@ -1286,30 +1870,85 @@ func TestConn_QueryContext(t *testing.T) {
 		assert.NotNil(t, rows)
 		assert.Equal(t, 1, executeStatementCount)
 	})
+
+	t.Run("the results of a short query are returned with the response of the statement", func(t *testing.T) {
+		var runAsync bool
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				runAsync = req.RunAsync
+				require.NotNil(t, req.GetDirectResults)
+				resp := finishedStatement()
+				resp.DirectResults.ResultSetMetadata = &cli_service.TGetResultSetMetadataResp{
+					Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+					Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{{
+						ColumnName: "id",
+						TypeDesc: &cli_service.TTypeDesc{Types: []*cli_service.TTypeEntry{{
+							PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: cli_service.TTypeId_INT_TYPE},
+						}}},
+					}}},
+				}
+				resp.DirectResults.ResultSet = &cli_service.TFetchResultsResp{
+					Status:      &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+					HasMoreRows: thrift.BoolPtr(false),
+					Results: &cli_service.TRowSet{Columns: []*cli_service.TColumn{
+						{I32Val: &cli_service.TI32Column{Values: []int32{1, 2}}},
+					}},
+				}
+				return resp, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.RunAsync = false
+		testConn := &conn{
+			session: getTestSessionV8(),
+			client:  testClient,
+			cfg:     cfg,
+		}
+		rows, err := testConn.QueryContext(context.Background(), "select id from orders", nil)
+		require.NoError(t, err)
+		assert.False(t, runAsync)
+
+		// the other requests of the test client fail
+		var ids []driver.Value
+		dest := make([]driver.Value, 1)
+		for rows.Next(dest) == nil {
+			ids = append(ids, dest[0])
+		}
+		assert.Equal(t, []driver.Value{int32(1), int32(2)}, ids)
+		assert.NoError(t, rows.Close())
+	})
 }
 
 func TestConn_Ping(t *testing.T) {
-	t.Run("ping returns ErrBadConn when executeStatement fails", func(t *testing.T) {
-		var executeStatementCount int
-		executeStatement := func(ctx context.Context, req *cli_service.TExecuteStatementReq) (r *cli_service.TExecuteStatementResp, err error) {
-			executeStatementCount++
-			executeStatementResp := &cli_service.TExecuteStatementResp{
-				Status: &cli_service.TStatus{
-					StatusCode: cli_service.TStatusCode_ERROR_STATUS,
-				},
-				OperationHandle: &cli_service.TOperationHandle{
-					OperationId: &cli_service.THandleIdentifier{
-						GUID:   []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54},
-						Secret: []byte("b"),
-					},
-				},
-			}
-			return executeStatementResp, nil
-		}
+	getInfo := func(err error) (*client.TestClient, *[]*cli_service.TGetInfoReq) {
+		var reqs []*cli_service.TGetInfoReq
+		return &client.TestClient{
+			FnGetInfo: func(ctx context.Context, req *cli_service.TGetInfoReq) (*cli_service.TGetInfoResp, error) {
+				reqs = append(reqs, req)
+				if err != nil {
+					return nil, err
+				}
+				return &cli_service.TGetInfoResp{Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS}}, nil
+			},
+		}, &reqs
+	}
 
-		testClient := &client.TestClient{
-			FnExecuteStatement: executeStatement,
+	t.Run("ping returns nil error when the session is valid", func(t *testing.T) {
+		testClient, reqs := getInfo(nil)
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     config.WithDefaults(),
 		}
+		err := testConn.Ping(context.Background())
+
+		assert.Nil(t, err)
+		require.Len(t, *reqs, 1)
+		assert.Equal(t, testConn.session.SessionHandle, (*reqs)[0].SessionHandle)
+	})
+
+	t.Run("ping returns ErrBadConn when the request fails", func(t *testing.T) {
+		testClient, _ := getInfo(errors.New("connection reset"))
 		testConn := &conn{
 			session: getTestSession(),
 			client:  testClient,
@@ -1317,50 +1956,66 @@ func TestConn_Ping(t *testing.T) {
 		}
 		err := testConn.Ping(context.Background())
 
-		assert.Error(t, err)
 		assert.Equal(t, driver.ErrBadConn, err)
-		assert.Equal(t, 1, executeStatementCount)
+		assert.False(t, testConn.expired.Load())
 	})
 
-	t.Run("ping returns nil error when driver can establish connection", func(t *testing.T) {
-		var executeStatementCount int
-		executeStatement := func(ctx context.Context, req *cli_service.TExecuteStatementReq) (r *cli_service.TExecuteStatementResp, err error) {
-			executeStatementCount++
-			executeStatementResp := &cli_service.TExecuteStatementResp{
-				Status: &cli_service.TStatus{
-					StatusCode: cli_service.TStatusCode_SUCCESS_STATUS,
-				},
-				OperationHandle: &cli_service.TOperationHandle{
-					OperationId: &cli_service.THandleIdentifier{
-						GUID:   []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54},
-						Secret: []byte("b"),
-					},
-				},
-			}
-			return executeStatementResp, nil
+	t.Run("ping marks an expired session", func(t *testing.T) {
+		testClient, _ := getInfo(errors.WithStack(&dbsqlerr.RequestError{Msg: "thrift: invalid handle", StatusCode: cli_service.TStatusCode_INVALID_HANDLE_STATUS.String()}))
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     config.WithDefaults(),
 		}
+		err := testConn.Ping(context.Background())
 
-		getOperationStatus := func(ctx context.Context, req *cli_service.TGetOperationStatusReq) (r *cli_service.TGetOperationStatusResp, err error) {
-			getOperationStatusResp := &cli_service.TGetOperationStatusResp{
-				OperationState:  cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
-				NumModifiedRows: thrift.Int64Ptr(10),
-			}
-			return getOperationStatusResp, nil
+		assert.Equal(t, driver.ErrBadConn, err)
+		assert.True(t, testConn.expired.Load())
+		assert.False(t, testConn.IsValid())
+	})
+
+	t.Run("ping returns authentication failures", func(t *testing.T) {
+		testClient, _ := getInfo(errors.WithStack(&dbsqlerr.RequestError{Msg: "invalid access token", HTTPStatusCode: http.StatusUnauthorized}))
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     config.WithDefaults(),
 		}
+		err := testConn.Ping(context.Background())
 
+		var reqErr *dbsqlerr.RequestError
+		require.ErrorAs(t, err, &reqErr)
+		assert.Equal(t, http.StatusUnauthorized, reqErr.HTTPStatusCode)
+	})
+
+	t.Run("ping runs a statement with the REST API", func(t *testing.T) {
+		var executeStatementCount int
 		testClient := &client.TestClient{
-			FnExecuteStatement:   executeStatement,
-			FnGetOperationStatus: getOperationStatus,
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				executeStatementCount++
+				return &cli_service.TExecuteStatementResp{
+					Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+					OperationHandle: &cli_service.TOperationHandle{
+						OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54}, Secret: []byte("b")},
+					},
+					DirectResults: &cli_service.TSparkDirectResults{
+						OperationStatus: &cli_service.TGetOperationStatusResp{
+							OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+						},
+						CloseOperation: &cli_service.TCloseOperationResp{},
+					},
+				}, nil
+			},
 		}
-
+		cfg := config.WithDefaults()
+		cfg.UseRESTAPI = true
 		testConn := &conn{
 			session: getTestSession(),
 			client:  testClient,
-			cfg:     config.WithDefaults(),
+			cfg:     cfg,
 		}
-		err := testConn.Ping(context.Background())
 
-		assert.Nil(t, err)
+		assert.NoError(t, testConn.Ping(context.Background()))
 		assert.Equal(t, 1, executeStatementCount)
 	})
 }
@@ -1392,15 +2047,88 @@ func TestConn_BeginTx(t *testing.T) {
 }
 
 func TestConn_ResetSession(t *testing.T) {
-	t.Run("ResetSession not currently supported", func(t *testing.T) {
+	t.Run("ResetSession keeps an unchanged session", func(t *testing.T) {
+		cfg := config.WithDefaults()
+		cfg.ResetSessions = true
 		testConn := &conn{
 			session: getTestSession(),
 			client:  &client.TestClient{},
-			cfg:     config.WithDefaults(),
+			cfg:     cfg,
 		}
 		res := testConn.ResetSession(context.Background())
 		assert.Nil(t, res)
 	})
+
+	t.Run("ResetSession replaces a changed session when enabled", func(t *testing.T) {
+		for _, enabled := range []bool{true, false} {
+			var opened, closed int
+			cfg := config.WithDefaults()
+			cfg.ResetSessions = enabled
+			cfg.Schema = "default"
+			testConn := &conn{
+				session: getTestSession(),
+				schema:  "default",
+				cfg:     cfg,
+				client: &client.TestClient{
+					FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+						return finishedStatement(), nil
+					},
+					FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+						opened++
+						assert.Equal(t, "default", string(*req.InitialNamespace.SchemaName))
+						return newTestSession(2), nil
+					},
+					FnCloseSession: func(ctx context.Context, req *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+						closed++
+						assert.Equal(t, getTestSession().SessionHandle, req.SessionHandle)
+						return &cli_service.TCloseSessionResp{}, nil
+					},
+				},
+			}
+			_, err := testConn.ExecContext(context.Background(), "USE SCHEMA sales", nil)
+			require.NoError(t, err)
+			_, err = testConn.ExecContext(context.Background(), "CREATE OR REPLACE TEMP VIEW recent AS SELECT 1", nil)
+			require.NoError(t, err)
+
+			assert.NoError(t, testConn.ResetSession(context.Background()))
+			if enabled {
+				assert.Equal(t, 1, opened)
+				assert.Equal(t, 1, closed)
+				assert.Equal(t, "default", testConn.schema)
+				assert.False(t, testConn.stateChanged)
+			} else {
+				assert.Zero(t, opened)
+				assert.Equal(t, "sales", testConn.schema)
+			}
+		}
+	})
+
+	t.Run("ResetSession returns ErrBadConn when an expired session can't be reopened", func(t *testing.T) {
+		testConn := &conn{
+			session: getTestSession(),
+			cfg:     config.WithDefaults(),
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					return nil, fmt.Errorf("warehouse is stopped")
+				},
+			},
+		}
+		testConn.expired.Store(true)
+		assert.Equal(t, driver.ErrBadConn, testConn.ResetSession(context.Background()))
+		assert.False(t, testConn.IsValid())
+	})
+}
+
+func TestConn_IsValid(t *testing.T) {
+	testConn := &conn{session: getTestSession()}
+	assert.True(t, testConn.IsValid())
+
+	testConn.session.Status = &cli_service.TStatus{StatusCode: cli_service.TStatusCode_ERROR_STATUS}
+	assert.False(t, testConn.IsValid())
+
+	testConn = &conn{session: getTestSession()}
+	testConn.expired.Store(true)
+	assert.False(t, testConn.IsValid())
 }
 
 func TestConn_Close(t *testing.T) {
@@ -1431,6 +2159,24 @@ func TestConn_Close(t *testing.T) {
 		assert.Equal(t, 1, closeSessionCount)
 	})
 
+	t.Run("Close decrements the open sessions", func(t *testing.T) {
+		testClient := &client.TestClient{
+			FnCloseSession: func(ctx context.Context, req *cli_service.TCloseSessionReq) (r *cli_service.TCloseSessionResp, err error) {
+				return &cli_service.TCloseSessionResp{}, nil
+			},
+		}
+		collector := newTestCollector()
+		cfg := config.WithDefaults()
+		cfg.Metrics = collector
+		testConn := &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     cfg,
+		}
+		assert.NoError(t, testConn.Close())
+		assert.Equal(t, float64(-1), collector.gauges[metrics.OpenSessions])
+	})
+
 	t.Run("Close will err when CloseSession fails", func(t *testing.T) {
 		var closeSessionCount int
 
@@ -1471,6 +2217,41 @@ func TestConn_Prepare(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, stmt)
 	})
+	t.Run("Prepare reuses cached statements", func(t *testing.T) {
+		cfg := config.WithDefaults()
+		cfg.MaxPreparedStatements = 2
+		testConn := &conn{
+			session: getTestSession(),
+			client:  &client.TestClient{},
+			cfg:     cfg,
+		}
+		stmt1, _ := testConn.Prepare("select 1")
+		stmt2, _ := testConn.Prepare("select 2")
+		stmt1Again, _ := testConn.Prepare("select 1")
+		assert.Same(t, stmt1, stmt1Again)
+
+		// select 2 is the least recently used statement
+		_, _ = testConn.Prepare("select 3")
+		assert.Equal(t, 2, testConn.stmts.len())
+		stmt2Again, _ := testConn.Prepare("select 2")
+		assert.NotSame(t, stmt2, stmt2Again)
+		stmt1Again, _ = testConn.Prepare("select 1")
+		assert.NotSame(t, stmt1, stmt1Again)
+	})
+
+	t.Run("Prepare does not cache statements when the cache is disabled", func(t *testing.T) {
+		cfg := config.WithDefaults()
+		cfg.MaxPreparedStatements = 0
+		testConn := &conn{
+			session: getTestSession(),
+			client:  &client.TestClient{},
+			cfg:     cfg,
+		}
+		stmt1, _ := testConn.Prepare("select 1")
+		stmt2, _ := testConn.Prepare("select 1")
+		assert.NotSame(t, stmt1, stmt2)
+		assert.Nil(t, testConn.stmts)
+	})
 }
 
 func TestConn_PrepareContext(t *testing.T) {
@@ -1494,3 +2275,44 @@ func getTestSession() *cli_service.TOpenSessionResp {
 		},
 	}}
 }
+
+// getTestSessionV8 returns a session of a server supporting all the features of the driver
+func getTestSessionV8() *cli_service.TOpenSessionResp {
+	session := getTestSession()
+	session.ServerProtocolVersion = cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8
+	return session
+}
+
+// testCollector records the metrics reported by the driver
+type testCollector struct {
+	mx         sync.Mutex
+	counters   map[string]float64
+	histograms map[string][]float64
+	gauges     map[string]float64
+}
+
+func newTestCollector() *testCollector {
+	return &testCollector{
+		counters:   map[string]float64{},
+		histograms: map[string][]float64{},
+		gauges:     map[string]float64{},
+	}
+}
+
+func (c *testCollector) Counter(name string, value float64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.counters[name] += value
+}
+
+func (c *testCollector) Histogram(name string, value float64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.histograms[name] = append(c.histograms[name], value)
+}
+
+func (c *testCollector) Gauge(name string, delta float64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.gauges[name] += delta
+}
@@ -1,11 +1,35 @@
 package dbsql
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/oauth/m2m"
 	"github.com/databricks/databricks-sql-go/auth/pat"
+	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
 	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/databricks/databricks-sql-go/resultcache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -33,6 +57,46 @@ func TestNewConnector(t *testing.T) {
 			WithUserAgentEntry(userAgentEntry),
 			WithSessionParams(sessionParams),
 			WithRetries(10, 3*time.Second, 60*time.Second),
+			WithArrowBatches(false),
+			WithCloudFetch(true),
+			WithMaxDownloadThreads(4),
+			WithDownloadBandwidthLimit(1<<20),
+			WithLz4Compression(false),
+			WithGzipCompression(false),
+			WithRESTAPI(true),
+			WithPrefetch(4, 1<<30),
+			WithMaxPageBytes(64<<20),
+			WithMaxRowsTotal(1000000),
+			WithMaxBytesPerQuery(1<<30),
+			WithReadOnly(true),
+			WithRetryNonIdempotent(false),
+			WithLastInsertId(true),
+			WithResultCache(resultcache.NewMemoryStore(1<<20), time.Minute, 1<<10),
+			WithComplexTypeScanner(ComplexTypesStructured),
+			WithNaiveTimestampLocation(time.UTC),
+			WithTimestampConversion(TimestampInUTC),
+			WithDateConversion(DateAsCivil),
+			WithZeroCopyScan(true),
+			WithPreparedStatementCache(20),
+			WithSessionReset(true),
+			WithParameterInterpolation(true),
+			WithLazySession(true),
+			WithStatementTags(map[string]string{"team": "growth"}),
+			WithRunAsync(false),
+			WithCancelGracePeriod(time.Second),
+			WithHeartbeatInterval(5*time.Minute),
+			WithSessionIdleTimeout(30*time.Minute),
+			WithWarehouseStartTimeout(10*time.Minute),
+			WithConnectTimeout(10*time.Second),
+			WithSlowQueryLog(2*time.Second, nil),
+			WithCircuitBreaker(5, time.Minute),
+			WithFailover(Endpoint{HTTPPath: "/sql/1.0/warehouses/standby"}),
+			WithLoadBalancing(LeastOutstanding, Endpoint{Host: "other-host", HTTPPath: "/sql/1.0/warehouses/b"}),
+			WithLogLevel(logger.DebugLevel),
+			WithProxy(&url.URL{Scheme: "socks5", Host: "proxy.internal:1080"}),
+			WithIdleConnections(200, 50, time.Minute),
+			WithTLSHandshakeTimeout(5*time.Second),
+			WithHTTP2(false),
 		)
 		expectedUserConfig := config.UserConfig{
 			Host:           host,
@@ -53,10 +117,65 @@ func TestNewConnector(t *testing.T) {
 		}
 		expectedCfg := config.WithDefaults()
 		expectedCfg.UserConfig = expectedUserConfig
+		expectedCfg.UseArrowBatches = false
+		expectedCfg.UseCloudFetch = true
+		expectedCfg.MaxDownloadThreads = 4
+		expectedCfg.DownloadBandwidthLimit = 1 << 20
+		expectedCfg.UseLz4Compression = false
+		expectedCfg.UseGzipCompression = false
+		expectedCfg.UseRESTAPI = true
+		expectedCfg.MaxPrefetchPages = 4
+		expectedCfg.PrefetchMemoryLimit = 1 << 30
+		expectedCfg.MaxPageBytes = 64 << 20
+		expectedCfg.MaxRowsTotal = 1000000
+		expectedCfg.MaxBytesPerQuery = 1 << 30
+		expectedCfg.ReadOnly = true
+		expectedCfg.RetryNonIdempotent = false
+		expectedCfg.LastInsertId = true
+		expectedCfg.ResultCache = resultcache.NewMemoryStore(1 << 20)
+		expectedCfg.ResultCacheTTL = time.Minute
+		expectedCfg.ResultCacheMaxBytes = 1 << 10
+		expectedCfg.DecodeComplexTypes = true
+		expectedCfg.NaiveTimestampLocation = time.UTC
+		expectedCfg.TimestampConversion = config.TimestampInUTC
+		expectedCfg.DateConversion = config.DateAsCivil
+		expectedCfg.ZeroCopyScan = true
+		expectedCfg.MaxPreparedStatements = 20
+		expectedCfg.ResetSessions = true
+		expectedCfg.InterpolateParams = true
+		expectedCfg.LazySession = true
+		expectedCfg.StatementTags = map[string]string{"team": "growth"}
+		expectedCfg.RunAsync = false
+		expectedCfg.CancelGracePeriod = time.Second
+		expectedCfg.HeartbeatInterval = 5 * time.Minute
+		expectedCfg.SessionIdleTimeout = 30 * time.Minute
+		expectedCfg.WarehouseStartTimeout = 10 * time.Minute
+		expectedCfg.ConnectTimeout = 10 * time.Second
+		expectedCfg.SlowQueryThreshold = 2 * time.Second
+		expectedCfg.CircuitBreakerThreshold = 5
+		expectedCfg.CircuitBreakerCooldown = time.Minute
+		expectedCfg.Failover = []config.Endpoint{{HTTPPath: "/sql/1.0/warehouses/standby"}}
+		expectedCfg.LoadBalanced = []config.Endpoint{{Host: "other-host", HTTPPath: "/sql/1.0/warehouses/b"}}
+		expectedCfg.LoadBalancing = config.LeastOutstanding
+		expectedCfg.LogLevel = "debug"
+		expectedCfg.Proxy = &url.URL{Scheme: "socks5", Host: "proxy.internal:1080"}
+		expectedCfg.MaxIdleConns = 200
+		expectedCfg.MaxIdleConnsPerHost = 50
+		expectedCfg.IdleConnTimeout = time.Minute
+		expectedCfg.TLSHandshakeTimeout = 5 * time.Second
+		expectedCfg.UseHTTP2 = false
 		coni, ok := con.(*connector)
 		require.True(t, ok)
 		assert.Nil(t, err)
+		require.NotNil(t, coni.cfg.Transport)
+		expectedCfg.Transport = coni.cfg.Transport
+		expectedCfg.Metrics = coni.cfg.Metrics
 		assert.Equal(t, expectedCfg, coni.cfg)
+		assert.Equal(t, 200, coni.cfg.Transport.MaxIdleConns)
+		assert.Equal(t, 50, coni.cfg.Transport.MaxIdleConnsPerHost)
+		assert.Equal(t, time.Minute, coni.cfg.Transport.IdleConnTimeout)
+		assert.Equal(t, 5*time.Second, coni.cfg.Transport.TLSHandshakeTimeout)
+		assert.False(t, coni.cfg.Transport.ForceAttemptHTTP2)
 	})
 	t.Run("Connector initialized minimal settings", func(t *testing.T) {
 		host := "databricks-host"
@@ -88,6 +207,9 @@ func TestNewConnector(t *testing.T) {
 		coni, ok := con.(*connector)
 		require.True(t, ok)
 		assert.Nil(t, err)
+		require.NotNil(t, coni.cfg.Transport)
+		expectedCfg.Transport = coni.cfg.Transport
+		expectedCfg.Metrics = coni.cfg.Metrics
 		assert.Equal(t, expectedCfg, coni.cfg)
 	})
 	t.Run("Connector initialized with retries turned off", func(t *testing.T) {
@@ -121,6 +243,349 @@ func TestNewConnector(t *testing.T) {
 		coni, ok := con.(*connector)
 		require.True(t, ok)
 		assert.Nil(t, err)
+		require.NotNil(t, coni.cfg.Transport)
+		expectedCfg.Transport = coni.cfg.Transport
+		expectedCfg.Metrics = coni.cfg.Metrics
 		assert.Equal(t, expectedCfg, coni.cfg)
 	})
+	t.Run("Connector initialized with environment variables", func(t *testing.T) {
+		t.Setenv("DATABRICKS_HOST", "https://env-host")
+		t.Setenv("DATABRICKS_HTTP_PATH", "/env-path")
+		t.Setenv("DATABRICKS_TOKEN", "env-token")
+		t.Setenv("DATABRICKS_CATALOG", "env-catalog")
+		t.Setenv("DATABRICKS_SCHEMA", "")
+		con, err := NewConnector(
+			WithHTTPPath("option-path"),
+		)
+		expectedUserConfig := config.UserConfig{
+			Host:          "env-host",
+			Port:          443,
+			Protocol:      "https",
+			AccessToken:   "env-token",
+			Authenticator: &pat.PATAuth{AccessToken: "env-token"},
+			HTTPPath:      "/option-path",
+			Catalog:       "env-catalog",
+			MaxRows:       100000,
+			SessionParams: map[string]string{},
+			RetryMax:      4,
+			RetryWaitMin:  1 * time.Second,
+			RetryWaitMax:  30 * time.Second,
+		}
+		expectedCfg := config.WithDefaults()
+		expectedCfg.UserConfig = expectedUserConfig
+		coni, ok := con.(*connector)
+		require.True(t, ok)
+		assert.Nil(t, err)
+		require.NotNil(t, coni.cfg.Transport)
+		expectedCfg.Transport = coni.cfg.Transport
+		expectedCfg.Metrics = coni.cfg.Metrics
+		assert.Equal(t, expectedCfg, coni.cfg)
+	})
+	t.Run("Connector initialized with a custom transport", func(t *testing.T) {
+		rt := &http.Transport{}
+		con, err := NewConnector(
+			WithServerHostname("databricks-host"),
+			WithAccessToken("token"),
+			WithTransport(rt),
+		)
+		require.NoError(t, err)
+		coni, ok := con.(*connector)
+		require.True(t, ok)
+		require.NotNil(t, coni.cfg.WrapTransport)
+		assert.Same(t, rt, coni.cfg.WrapTransport(http.DefaultTransport))
+	})
+	t.Run("Connector initialized with an HTTP client", func(t *testing.T) {
+		hc := &http.Client{Timeout: time.Minute}
+		con, err := NewConnector(
+			WithServerHostname("databricks-host"),
+			WithAccessToken("token"),
+			WithHTTPClient(hc),
+		)
+		require.NoError(t, err)
+		coni, ok := con.(*connector)
+		require.True(t, ok)
+		assert.Same(t, hc, coni.cfg.HTTPClient)
+		assert.Nil(t, coni.cfg.Transport, "no transport is pooled for the client")
+	})
+	t.Run("Connector initialized with request headers", func(t *testing.T) {
+		con, err := NewConnector(
+			WithServerHostname("databricks-host"),
+			WithAccessToken("token"),
+			WithHeaders(map[string]string{"x-gateway": "corp", "X-Team": "growth"}),
+			WithHeaderFunc(func(ctx context.Context, header http.Header) {
+				header.Set("X-Request-ID", driverctx.CorrelationIdFromContext(ctx))
+				header.Set("X-Team", header.Get("X-Team")+"-emea")
+			}),
+			WithHeaderFunc(nil),
+		)
+		require.NoError(t, err)
+		coni, ok := con.(*connector)
+		require.True(t, ok)
+		require.Len(t, coni.cfg.RequestHeaders, 2)
+
+		header := http.Header{}
+		for _, addHeaders := range coni.cfg.RequestHeaders {
+			addHeaders(driverctx.NewContextWithCorrelationId(context.Background(), "req-1"), header)
+		}
+		assert.Equal(t, http.Header{"X-Gateway": {"corp"}, "X-Team": {"growth-emea"}, "X-Request-Id": {"req-1"}}, header)
+	})
+	t.Run("Connector initialized with TLS options", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+		con, err := NewConnector(
+			WithServerHostname("databricks-host"),
+			WithAccessToken("token"),
+			WithCACertFile(caFile),
+			WithTLSServerName("proxy.internal"),
+			WithInsecureSkipVerify(true),
+		)
+		require.NoError(t, err)
+		coni, ok := con.(*connector)
+		require.True(t, ok)
+		tlsConfig := coni.cfg.TLSConfig
+		require.NotNil(t, tlsConfig.RootCAs)
+		_, err = server.Certificate().Verify(x509.VerifyOptions{Roots: tlsConfig.RootCAs})
+		assert.NoError(t, err)
+		assert.Equal(t, "proxy.internal", tlsConfig.ServerName)
+		assert.True(t, tlsConfig.InsecureSkipVerify)
+		assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+
+	})
+
+	t.Run("Connector with an invalid CA bundle should fail", func(t *testing.T) {
+		_, err := NewConnector(WithServerHostname("databricks-host"), WithCACertFile(filepath.Join(t.TempDir(), "missing.pem")))
+		assert.ErrorContains(t, err, "databricks: CA bundle not loaded: unable to read CA bundle")
+
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+		_, err = NewConnector(WithServerHostname("databricks-host"), WithCACertFile(caFile))
+		assert.ErrorContains(t, err, "databricks: CA bundle not loaded: no certificates found in CA bundle")
+	})
+
+	t.Run("Connector with OAuth M2M applied before the host should authenticate against the host", func(t *testing.T) {
+		con, err := NewConnector(
+			WithClientCredentials("client-id", "client-secret"),
+			WithServerHostname("databricks-host"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, m2m.NewAuthenticator("client-id", "client-secret", "databricks-host"), con.(*connector).cfg.Authenticator)
+
+		con, err = NewConnector(
+			WithServerHostname("databricks-host"),
+			WithClientCredentials("client-id", "client-secret"),
+			WithAccessToken("token"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, &pat.PATAuth{AccessToken: "token"}, con.(*connector).cfg.Authenticator, "the last option applies")
+	})
+
+	t.Run("Connector with a credential chain applied before the host should authenticate against the host", func(t *testing.T) {
+		provider := &hostRecordingProvider{}
+		con, err := NewConnector(
+			WithCredentialChain(provider),
+			WithServerHostname("databricks-host"),
+		)
+		require.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodPost, "https://databricks-host", nil)
+		require.NoError(t, con.(*connector).cfg.Authenticator.Authenticate(req))
+		assert.Equal(t, "databricks-host", provider.hostName)
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+	})
+
+	t.Run("Connector with invalid GCP credentials should fail", func(t *testing.T) {
+		_, err := NewConnector(
+			WithGCPCredentials(filepath.Join(t.TempDir(), "missing.json")),
+			WithServerHostname("databricks-host"),
+		)
+		assert.ErrorContains(t, err, "gcp credentials not set up")
+	})
+
+	t.Run("Connector with an invalid Azure service principal should fail", func(t *testing.T) {
+		_, err := NewConnector(
+			WithServerHostname("databricks-host"),
+			WithAzureServicePrincipal("", "client-id", "client-secret"),
+		)
+		assert.ErrorContains(t, err, "azure service principal not set up")
+
+		con, err := NewConnector(
+			WithServerHostname("databricks-host"),
+			WithAzureServicePrincipal("tenant-id", "client-id", "client-secret"),
+		)
+		require.NoError(t, err)
+		_, deferred := con.(*connector).cfg.Authenticator.(*deferredAuth)
+		assert.False(t, deferred)
+	})
+}
+
+func TestConnector_RESTAPI(t *testing.T) {
+	var statements []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "/api/2.0/sql/statements", r.URL.Path)
+		var body struct {
+			Statement string `json:"statement"`
+			Catalog   string `json:"catalog"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "main", body.Catalog)
+		statements = append(statements, body.Statement)
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(body.Statement, "INSERT") {
+			_, _ = w.Write([]byte(`{"statement_id": "01ed9db9-24c4-1cb6-a320-fb6ba623bdd3", "status": {"state": "SUCCEEDED"},
+				"manifest": {"format": "JSON_ARRAY", "schema": {"columns": [{"name": "num_affected_rows", "type_name": "LONG"}]}, "total_chunk_count": 1},
+				"result": {"row_count": 1, "data_array": [["2"]]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"statement_id": "01ed9db9-24c4-1cb6-a320-fb6ba623bdd2", "status": {"state": "SUCCEEDED"},
+			"manifest": {"format": "JSON_ARRAY", "schema": {"columns": [{"name": "id", "type_name": "INT"}, {"name": "created", "type_name": "DATE"}]}, "total_chunk_count": 1},
+			"result": {"row_count": 2, "data_array": [["1", "2023-01-30"], ["2", null]]}}`))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	require.NoError(t, err)
+
+	connector, err := NewConnector(
+		WithServerHostname("localhost"),
+		WithPort(port),
+		WithHTTPPath("/sql/1.0/warehouses/abc123"),
+		WithAccessToken("token"),
+		WithInitialNamespace("main", ""),
+		WithRESTAPI(true),
+	)
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, created FROM orders")
+	require.NoError(t, err)
+	var ids []int32
+	var dates []sql.NullTime
+	for rows.Next() {
+		var id int32
+		var created sql.NullTime
+		require.NoError(t, rows.Scan(&id, &created))
+		ids = append(ids, id)
+		dates = append(dates, created)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []int32{1, 2}, ids)
+	assert.Equal(t, time.Date(2023, 1, 30, 0, 0, 0, 0, time.UTC), dates[0].Time)
+	assert.False(t, dates[1].Valid)
+
+	res, err := db.Exec("INSERT INTO orders VALUES (3, current_date())")
+	require.NoError(t, err)
+	affected, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+	assert.Equal(t, []string{"SELECT id, created FROM orders", "INSERT INTO orders VALUES (3, current_date())"}, statements)
+}
+
+func TestConnector_cluster(t *testing.T) {
+	const clusterPath = "/sql/protocolv1/o/1234567890123456/0123-456789-abcdefgh"
+
+	_, err := NewConnector(WithServerHostname("databricks-host"), WithHTTPPath(clusterPath), WithRESTAPI(true))
+	assert.EqualError(t, err, "databricks: the Statement Execution API is only available on SQL warehouses, "+clusterPath+" is the HTTP path of an all-purpose cluster")
+	_, err = NewConnector(WithServerHostname("databricks-host"), WithHTTPPath("/sql/1.0/warehouses/a"), WithRESTAPI(true),
+		WithFailover(Endpoint{HTTPPath: clusterPath}))
+	assert.Error(t, err, "the standby is a cluster")
+
+	// the clusters are supported with Thrift
+	_, err = NewConnector(WithServerHostname("databricks-host"), WithHTTPPath(clusterPath))
+	assert.NoError(t, err)
+}
+
+func TestIsUnreachable(t *testing.T) {
+	cases := []struct {
+		err         error
+		unreachable bool
+	}{
+		{&dbsqlerr.RequestError{Msg: "internal error", HTTPStatusCode: http.StatusInternalServerError}, true},
+		{&dbsqlerr.RequestError{Msg: "unavailable", HTTPStatusCode: http.StatusServiceUnavailable}, true},
+		{&dbsqlerr.RequestError{Msg: "dial", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, true},
+		{&dbsqlerr.RequestError{Msg: "circuit open", Err: &dbsqlerr.CircuitOpenError{Host: "host", Failures: 5}}, true},
+		{&dbsqlerr.RequestError{Msg: "unauthorized", HTTPStatusCode: http.StatusUnauthorized}, false},
+		{dbsqlerr.NewDriverError("invalid argument", nil), false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.unreachable, isUnreachable(c.err), "%v", c.err)
+	}
+}
+
+func TestLoadBalancing(t *testing.T) {
+	newConnector := func(policy LoadBalancingPolicy) *connector {
+		coni, err := NewConnector(
+			WithServerHostname("primary-host"),
+			WithHTTPPath("/sql/1.0/warehouses/a"),
+			WithAccessToken("token"),
+			WithLoadBalancing(policy, Endpoint{HTTPPath: "/sql/1.0/warehouses/b"}, Endpoint{Host: "other-host", HTTPPath: "/sql/1.0/warehouses/c"}),
+		)
+		require.NoError(t, err)
+		return coni.(*connector)
+	}
+	pick := func(c *connector) string {
+		picked := c.pick()
+		return picked.cfg.Host + picked.cfg.HTTPPath
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		c := newConnector(RoundRobin)
+		var picked []string
+		for i := 0; i < 4; i++ {
+			picked = append(picked, pick(c))
+		}
+		assert.Equal(t, []string{
+			"primary-host/sql/1.0/warehouses/a",
+			"primary-host/sql/1.0/warehouses/b",
+			"other-host/sql/1.0/warehouses/c",
+			"primary-host/sql/1.0/warehouses/a",
+		}, picked)
+	})
+
+	t.Run("least outstanding", func(t *testing.T) {
+		c := newConnector(LeastOutstanding)
+		c.balanced[0].outstanding.Store(3)
+		c.balanced[1].outstanding.Store(1)
+		c.balanced[2].outstanding.Store(1)
+		assert.Equal(t, "primary-host/sql/1.0/warehouses/b", pick(c))
+		assert.Equal(t, "primary-host/sql/1.0/warehouses/b", pick(c))
+		assert.Equal(t, "other-host/sql/1.0/warehouses/c", pick(c), "ties are broken in round robin order")
+		c.balanced[0].outstanding.Store(0)
+		assert.Equal(t, "primary-host/sql/1.0/warehouses/a", pick(c))
+	})
+
+	t.Run("running queries are outstanding", func(t *testing.T) {
+		var outstanding atomic.Int64
+		testConn := &conn{
+			session:     getTestSession(),
+			cfg:         config.WithDefaults(),
+			outstanding: &outstanding,
+			client: &client.TestClient{
+				FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+					assert.Equal(t, int64(1), outstanding.Load())
+					return nil, errors.New("error")
+				},
+			},
+		}
+		_, _, err := testConn.runQuery(context.Background(), "select 1", nil)
+		assert.Error(t, err)
+		assert.Zero(t, outstanding.Load())
+	})
+}
+
+// hostRecordingProvider is a credential chain provider recording the host it authenticates against
+type hostRecordingProvider struct {
+	hostName string
+}
+
+func (p *hostRecordingProvider) Name() string { return "host recording" }
+
+func (p *hostRecordingProvider) Authenticator(hostName string) (auth.Authenticator, error) {
+	p.hostName = hostName
+	return &pat.PATAuth{AccessToken: "token"}, nil
 }
@@ -0,0 +1,152 @@
+package dbsql
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/databricks/databricks-sql-go/logger"
+	dbsqlvolumes "github.com/databricks/databricks-sql-go/volumes"
+	"github.com/pkg/errors"
+)
+
+var errCopyStagingPath = "databricks: COPY INTO needs a staging path in a volume, set it with CopyStagingPath"
+
+// CopyFormat is the file format of the data loaded by CopyInto
+type CopyFormat string
+
+const (
+	CopyCSV     CopyFormat = "CSV"
+	CopyJSON    CopyFormat = "JSON"
+	CopyParquet CopyFormat = "PARQUET"
+)
+
+type copyConfig struct {
+	stagingPath   string
+	columns       []string
+	formatOptions map[string]string
+	copyOptions   map[string]string
+}
+
+// CopyOption sets an option of CopyInto
+type CopyOption func(*copyConfig)
+
+// CopyStagingPath sets the directory of a volume where the data is staged while it is loaded,
+// e.g. /Volumes/main/default/staging. Required.
+func CopyStagingPath(path string) CopyOption {
+	return func(c *copyConfig) {
+		c.stagingPath = strings.TrimSuffix(path, "/")
+	}
+}
+
+// CopyColumns maps the columns of the data to the columns of the table with SELECT expressions,
+// e.g. "_c0::int AS id" for CSV data without header. Default loads the columns with the same names.
+func CopyColumns(exprs ...string) CopyOption {
+	return func(c *copyConfig) {
+		c.columns = append(c.columns, exprs...)
+	}
+}
+
+// CopyFormatOptions sets the FORMAT_OPTIONS of COPY INTO, e.g. "header": "true" for CSV data with a header
+func CopyFormatOptions(options map[string]string) CopyOption {
+	return func(c *copyConfig) {
+		for k, v := range options {
+			c.formatOptions[k] = v
+		}
+	}
+}
+
+// CopyOptions sets the COPY_OPTIONS of COPY INTO, e.g. "mergeSchema": "true"
+func CopyOptions(options map[string]string) CopyOption {
+	return func(c *copyConfig) {
+		for k, v := range options {
+			c.copyOptions[k] = v
+		}
+	}
+}
+
+// CopyInto loads the data of r into table with COPY INTO. The data is uploaded to a file of the staging path,
+// which is deleted when the load finished, successfully or not. It returns the number of loaded rows.
+// table is used as is in the statement, quote its parts with backticks if needed.
+func CopyInto(ctx context.Context, db dbsqlvolumes.Querier, table string, r io.Reader, format CopyFormat, opts ...CopyOption) (int64, error) {
+	cfg := &copyConfig{formatOptions: map[string]string{}, copyOptions: map[string]string{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.stagingPath == "" {
+		return 0, newDriverError(errCopyStagingPath, nil)
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return 0, errors.Wrap(err, "databricks: failed to name staged file")
+	}
+	path := fmt.Sprintf("%s/dbsql-copy-%s.%s", cfg.stagingPath, hex.EncodeToString(name), strings.ToLower(string(format)))
+
+	volumes := dbsqlvolumes.New(db)
+	if err := volumes.Upload(ctx, path, r, -1, false); err != nil {
+		return 0, err
+	}
+	defer func() {
+		// the context of the load may be done, the staged file is deleted anyway
+		if err := volumes.Delete(context.Background(), path); err != nil {
+			logger.Warn().Err(err).Msgf("databricks: failed to delete staged file %s", path)
+		}
+	}()
+
+	res, err := db.ExecContext(ctx, copyIntoStatement(table, path, format, cfg))
+	if err != nil {
+		return 0, errors.Wrapf(err, "databricks: failed to copy into %s", table)
+	}
+	return res.RowsAffected()
+}
+
+// copyIntoStatement returns the COPY INTO statement loading the file at path into table
+func copyIntoStatement(table, path string, format CopyFormat, cfg *copyConfig) string {
+	var b strings.Builder
+	b.WriteString("COPY INTO ")
+	b.WriteString(table)
+	b.WriteString(" FROM ")
+	if len(cfg.columns) > 0 {
+		b.WriteString("(SELECT ")
+		b.WriteString(strings.Join(cfg.columns, ", "))
+		b.WriteString(" FROM ")
+		b.WriteString(quoteLiteral(path))
+		b.WriteString(")")
+	} else {
+		b.WriteString(quoteLiteral(path))
+	}
+	b.WriteString(" FILEFORMAT = ")
+	b.WriteString(string(format))
+	writeCopyOptions(&b, " FORMAT_OPTIONS ", cfg.formatOptions)
+	writeCopyOptions(&b, " COPY_OPTIONS ", cfg.copyOptions)
+	return b.String()
+}
+
+// writeCopyOptions writes the options sorted by key, e.g. FORMAT_OPTIONS ('header' = 'true')
+func writeCopyOptions(b *strings.Builder, clause string, options map[string]string) {
+	if len(options) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b.WriteString(clause)
+	b.WriteString("(")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(quoteLiteral(k))
+		b.WriteString(" = ")
+		b.WriteString(quoteLiteral(options[k]))
+	}
+	b.WriteString(")")
+}
@@ -0,0 +1,82 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyQuerier records the statements of CopyInto
+type copyQuerier struct {
+	statements []string
+	staged     string
+	copyErr    error
+}
+
+func (q *copyQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	q.statements = append(q.statements, query)
+	if r, size := driverctx.StagingReaderFromContext(ctx); r != nil {
+		b, _ := io.ReadAll(io.LimitReader(r, size))
+		q.staged = string(b)
+	}
+	if strings.HasPrefix(query, "COPY INTO") {
+		if q.copyErr != nil {
+			return nil, q.copyErr
+		}
+		return driver.RowsAffected(2), nil
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (q *copyQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+func TestCopyInto(t *testing.T) {
+	stagedFile := regexp.MustCompile(`/Volumes/main/default/staging/dbsql-copy-[0-9a-f]{32}\.csv`)
+
+	t.Run("data is staged, loaded and deleted", func(t *testing.T) {
+		q := &copyQuerier{}
+		n, err := CopyInto(context.Background(), q, "main.default.sales", strings.NewReader("id,amount\n1,2.5\n2,3\n"), CopyCSV,
+			CopyStagingPath("/Volumes/main/default/staging/"),
+			CopyColumns("id::int AS id", "amount::double AS amount"),
+			CopyFormatOptions(map[string]string{"header": "true", "sep": ","}),
+			CopyOptions(map[string]string{"mergeSchema": "true"}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		assert.Equal(t, "id,amount\n1,2.5\n2,3\n", q.staged)
+
+		require.Len(t, q.statements, 3)
+		file := stagedFile.FindString(q.statements[0])
+		require.NotEmpty(t, file)
+		assert.Equal(t, "PUT '__input_stream__' INTO '"+file+"'", q.statements[0])
+		assert.Equal(t, "COPY INTO main.default.sales FROM (SELECT id::int AS id, amount::double AS amount FROM '"+file+"') "+
+			"FILEFORMAT = CSV FORMAT_OPTIONS ('header' = 'true', 'sep' = ',') COPY_OPTIONS ('mergeSchema' = 'true')", q.statements[1])
+		assert.Equal(t, "REMOVE '"+file+"'", q.statements[2])
+	})
+
+	t.Run("staged file is deleted when the load fails", func(t *testing.T) {
+		q := &copyQuerier{copyErr: errors.New("table not found")}
+		_, err := CopyInto(context.Background(), q, "sales", strings.NewReader("{}"), CopyJSON, CopyStagingPath("/Volumes/main/default/staging"))
+		assert.ErrorContains(t, err, "table not found")
+		require.Len(t, q.statements, 3)
+		assert.True(t, strings.HasPrefix(q.statements[2], "REMOVE '/Volumes/main/default/staging/dbsql-copy-"))
+	})
+
+	t.Run("staging path is required", func(t *testing.T) {
+		q := &copyQuerier{}
+		_, err := CopyInto(context.Background(), q, "sales", strings.NewReader(""), CopyParquet)
+		assert.ErrorContains(t, err, errCopyStagingPath)
+		assert.Empty(t, q.statements)
+	})
+}
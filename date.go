@@ -0,0 +1,86 @@
+package dbsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var errDateInvalid = "databricks: invalid date value %q"
+var errDateScan = "databricks: unable to scan type %T into Date"
+
+// Date is a DATE value as a calendar date, without a time or a location. DATE columns are returned as Date values
+// with WithDateConversion(DateAsCivil), and a Date can be scanned from the time.Time values returned by default:
+//
+//	var day dbsql.Date
+//	err := db.QueryRowContext(ctx, "select day from events where id = 1").Scan(&day)
+//
+// A Date query parameter is sent as a DATE.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+var _ sql.Scanner = (*Date)(nil)
+var _ driver.Valuer = Date{}
+
+// DateOf returns the date of t in the location of t
+func DateOf(t time.Time) Date {
+	var d Date
+	d.Year, d.Month, d.Day = t.Date()
+	return d
+}
+
+// ParseDate parses a date in the yyyy-mm-dd format
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return Date{}, errors.Errorf(errDateInvalid, s)
+	}
+	return DateOf(t), nil
+}
+
+// String returns d in the yyyy-mm-dd format
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// In returns the time of the midnight starting d in loc
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// IsZero returns true for the zero Date, which isn't a valid date
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// Scan implements sql.Scanner
+func (d *Date) Scan(src any) error {
+	switch v := src.(type) {
+	case Date:
+		*d = v
+	case time.Time:
+		*d = DateOf(v)
+	case string:
+		date, err := ParseDate(v)
+		if err != nil {
+			return err
+		}
+		*d = date
+	case []byte:
+		return d.Scan(string(v))
+	default:
+		return errors.Errorf(errDateScan, src)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer, the date is sent as a DATE
+func (d Date) Value() (driver.Value, error) {
+	return d, nil
+}
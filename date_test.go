@@ -0,0 +1,48 @@
+package dbsql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDate(t *testing.T) {
+	day := Date{Year: 2023, Month: time.January, Day: 31}
+
+	t.Run("should parse and format dates", func(t *testing.T) {
+		d, err := ParseDate("2023-01-31")
+		require.NoError(t, err)
+		assert.Equal(t, day, d)
+		assert.Equal(t, "2023-01-31", d.String())
+		assert.Equal(t, "0099-02-03", Date{Year: 99, Month: time.February, Day: 3}.String())
+
+		for _, in := range []string{"", "2023-1-31", "2023-02-30", "2023-01-31 10:00:00"} {
+			_, err := ParseDate(in)
+			assert.Error(t, err, in)
+		}
+	})
+
+	t.Run("should convert times", func(t *testing.T) {
+		ny, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+		// the date of the time in its own location, not in UTC
+		assert.Equal(t, day, DateOf(time.Date(2023, 1, 31, 23, 0, 0, 0, ny)))
+		assert.Equal(t, time.Date(2023, 1, 31, 0, 0, 0, 0, ny), day.In(ny))
+		assert.True(t, Date{}.IsZero())
+		assert.False(t, day.IsZero())
+	})
+
+	t.Run("should scan dates", func(t *testing.T) {
+		for _, src := range []any{day, time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC), "2023-01-31", []byte("2023-01-31")} {
+			var d Date
+			require.NoError(t, d.Scan(src), src)
+			assert.Equal(t, day, d)
+		}
+
+		var d Date
+		assert.Error(t, d.Scan(int64(1)))
+		assert.Error(t, d.Scan("yesterday"))
+	})
+}
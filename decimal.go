@@ -0,0 +1,152 @@
+package dbsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var errDecimalInvalid = "databricks: invalid decimal value %q"
+var errDecimalScan = "databricks: unable to scan type %T into Decimal"
+
+// Decimal is an exact decimal number, the value unscaled * 10^-scale. DECIMAL(p,s) columns
+// can be scanned into a Decimal without the precision lost when scanning into a float64:
+//
+//	var price dbsql.Decimal
+//	err := db.QueryRowContext(ctx, "select price from orders where id = 1").Scan(&price)
+//
+// Use Rat to compute with the value. The zero value is 0.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+var _ sql.Scanner = (*Decimal)(nil)
+var _ driver.Valuer = Decimal{}
+var _ json.Marshaler = Decimal{}
+var _ json.Unmarshaler = (*Decimal)(nil)
+
+// NewDecimal returns the decimal unscaled * 10^-scale
+func NewDecimal(unscaled *big.Int, scale int32) Decimal {
+	if scale < 0 {
+		// keep the scale positive, as in DECIMAL(p,s) values
+		unscaled = new(big.Int).Mul(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil))
+		scale = 0
+	}
+	return Decimal{unscaled: new(big.Int).Set(unscaled), scale: scale}
+}
+
+// ParseDecimal parses a decimal number in the format returned by the server, e.g. "-123.45"
+func ParseDecimal(s string) (Decimal, error) {
+	digits := strings.TrimSpace(s)
+	var scale int32
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		fraction := digits[i+1:]
+		if fraction == "" || strings.ContainsAny(fraction, "+-") {
+			return Decimal{}, errors.Errorf(errDecimalInvalid, s)
+		}
+		scale = int32(len(fraction))
+		digits = digits[:i] + fraction
+	}
+
+	unscaled, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, errors.Errorf(errDecimalInvalid, s)
+	}
+	return Decimal{unscaled: unscaled, scale: scale}, nil
+}
+
+// Unscaled returns the unscaled value of d
+func (d Decimal) Unscaled() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(d.unscaled)
+}
+
+// Scale returns the number of digits after the decimal point
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Rat returns the exact value of d
+func (d Decimal) Rat() *big.Rat {
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil)
+	return new(big.Rat).SetFrac(d.Unscaled(), denom)
+}
+
+// Float64 returns the nearest float64 value of d
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String returns d with scale digits after the decimal point, e.g. "-123.45"
+func (d Decimal) String() string {
+	unscaled := d.Unscaled()
+	digits := new(big.Int).Abs(unscaled).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		point := len(digits) - int(d.scale)
+		digits = digits[:point] + "." + digits[point:]
+	}
+	if unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Scan implements sql.Scanner, DECIMAL values are returned by the driver as strings
+func (d *Decimal) Scan(src any) error {
+	var val Decimal
+	var err error
+	switch v := src.(type) {
+	case string:
+		val, err = ParseDecimal(v)
+	case []byte:
+		val, err = ParseDecimal(string(v))
+	case Decimal:
+		val = NewDecimal(v.Unscaled(), v.scale)
+	case int64:
+		val = NewDecimal(big.NewInt(v), 0)
+	case float64:
+		val, err = ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		err = errors.Errorf(errDecimalScan, src)
+	}
+	if err != nil {
+		return err
+	}
+
+	*d = val
+	return nil
+}
+
+// Value implements driver.Valuer, the decimal is passed as a string
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// MarshalJSON encodes d as a JSON number
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number or string, e.g. a DECIMAL field of a STRUCT value
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	return d.Scan(s)
+}
@@ -0,0 +1,103 @@
+package dbsql
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecimal(t *testing.T) {
+	t.Run("should parse and format decimals", func(t *testing.T) {
+		cases := []struct {
+			in       string
+			out      string
+			unscaled int64
+			scale    int32
+		}{
+			{"123.45", "123.45", 12345, 2},
+			{"-123.45", "-123.45", -12345, 2},
+			{"0.001", "0.001", 1, 3},
+			{"-0.5", "-0.5", -5, 1},
+			{"42", "42", 42, 0},
+			{"1.10", "1.10", 110, 2},
+			{"+7.0", "7.0", 70, 1},
+		}
+		for _, c := range cases {
+			d, err := ParseDecimal(c.in)
+			require.NoError(t, err, c.in)
+			assert.Equal(t, c.out, d.String())
+			assert.Equal(t, big.NewInt(c.unscaled), d.Unscaled())
+			assert.Equal(t, c.scale, d.Scale())
+		}
+
+		for _, in := range []string{"", "abc", "1.", "1.2.3", "1.-2", "1e5"} {
+			_, err := ParseDecimal(in)
+			assert.Error(t, err, in)
+		}
+	})
+
+	t.Run("should keep precision", func(t *testing.T) {
+		d, err := ParseDecimal("12345678901234567890.123456789012345678")
+		require.NoError(t, err)
+		assert.Equal(t, "12345678901234567890.123456789012345678", d.String())
+
+		r, ok := new(big.Rat).SetString("12345678901234567890123456789012345678/1000000000000000000")
+		require.True(t, ok)
+		assert.Equal(t, 0, r.Cmp(d.Rat()))
+		assert.Equal(t, 12345678901234567890.123456789012345678, d.Float64())
+	})
+
+	t.Run("should handle negative scales", func(t *testing.T) {
+		d := NewDecimal(big.NewInt(-12), -2)
+		assert.Equal(t, "-1200", d.String())
+		assert.Equal(t, int32(0), d.Scale())
+	})
+
+	t.Run("zero value is zero", func(t *testing.T) {
+		var d Decimal
+		assert.Equal(t, "0", d.String())
+		assert.Equal(t, 0, d.Rat().Sign())
+	})
+
+	t.Run("should scan values", func(t *testing.T) {
+		var d Decimal
+		for src, expected := range map[any]string{
+			"1.25":                       "1.25",
+			int64(-3):                    "-3",
+			float64(0.125):               "0.125",
+			NewDecimal(big.NewInt(5), 1): "0.5",
+		} {
+			require.NoError(t, d.Scan(src))
+			assert.Equal(t, expected, d.String())
+		}
+		require.NoError(t, d.Scan([]byte("9.99")))
+		assert.Equal(t, "9.99", d.String())
+
+		assert.EqualError(t, d.Scan(true), "databricks: unable to scan type bool into Decimal")
+		assert.Error(t, d.Scan("x"))
+
+		v, err := d.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "9.99", v)
+	})
+	t.Run("should encode and decode JSON", func(t *testing.T) {
+		var v struct {
+			Price  Decimal  `json:"price"`
+			Quoted Decimal  `json:"quoted"`
+			Empty  *Decimal `json:"empty"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(`{"price":123.4500,"quoted":"-1.5","empty":null}`), &v))
+		assert.Equal(t, "123.4500", v.Price.String())
+		assert.Equal(t, "-1.5", v.Quoted.String())
+		assert.Nil(t, v.Empty)
+
+		data, err := json.Marshal(v.Price)
+		require.NoError(t, err)
+		assert.Equal(t, "123.4500", string(data))
+
+		assert.Error(t, json.Unmarshal([]byte(`{"price":true}`), &v))
+	})
+}
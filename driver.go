@@ -28,11 +28,17 @@ func (d *databricksDriver) Open(dsn string) (driver.Conn, error) {
 // OpenConnector returns a new Connector.
 // Used by sql.DB to obtain a Connector and invoke its Connect method to obtain each needed connection.
 func (d *databricksDriver) OpenConnector(dsn string) (driver.Connector, error) {
-	ucfg, err := config.ParseDSN(dsn)
+	cfg, err := config.ParseDSN(dsn)
 	if err != nil {
 		return nil, err
 	}
-	return NewConnector(withUserConfig(ucfg))
+	// DATABRICKS_* environment variables fill in values missing from the DSN
+	env, err := config.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	cfg.UserConfig = cfg.UserConfig.WithFallback(env)
+	return NewConnector(withConfig(cfg))
 }
 
 var _ driver.Driver = (*databricksDriver)(nil)
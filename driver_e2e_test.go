@@ -5,13 +5,16 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/databricks/databricks-sql-go/driverctx"
 	"github.com/databricks/databricks-sql-go/internal/cli_service"
 	"github.com/databricks/databricks-sql-go/internal/client"
@@ -292,6 +295,152 @@ func TestContextTimeoutExample(t *testing.T) {
 
 }
 
+func TestContextTimeoutSynchronousStatement(t *testing.T) {
+	state := &callState{}
+	// load basic responses
+	loadTestData(t, "OpenSessionSuccess.json", &state.openSessionResp)
+	loadTestData(t, "CloseSessionSuccess.json", &state.closeSessionResp)
+	loadTestData(t, "CloseOperationSuccess.json", &state.closeOperationResp)
+	loadTestData(t, "ExecuteStatement1.json", &state.executeStatementResp)
+	loadTestData(t, "CancelOperationSuccess.json", &state.cancelOperationResp)
+	state.executeStatementSleep = time.Second
+
+	ts := getServer(state)
+
+	defer ts.Close()
+	r, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(r.Port())
+	require.NoError(t, err)
+
+	connector, err := NewConnector(
+		WithServerHostname("localhost"),
+		WithPort(port),
+		WithRunAsync(false),
+	)
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, `SELECT id FROM RANGE(100000000) ORDER BY RANDOM() + 2 asc`)
+	require.ErrorContains(t, err, context.DeadlineExceeded.Error())
+	require.Nil(t, rows)
+	assert.Less(t, time.Since(start), state.executeStatementSleep, "the execute request ends with the context")
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	assert.Equal(t, 1, state.executeStatementCalls)
+	assert.False(t, state.executeStatementReq.RunAsync)
+	assert.Equal(t, 1, state.cancelOperationCalls)
+	assert.Equal(t, state.executeStatementReq.OperationId.GUID, state.cancelOperationReq.OperationHandle.OperationId.GUID,
+		"the statement is canceled by the operation id generated by the driver")
+}
+
+// runRequestsOfQueries runs a query which is polled and closed, and a query whose results are fetched, with ctx
+func runRequestsOfQueries(t *testing.T, db *sql.DB, state *callState, ctx context.Context) {
+	state.executeStatementResp = cli_service.TExecuteStatementResp{}
+	loadTestData(t, "ExecuteStatement21.json", &state.executeStatementResp)
+	loadTestData(t, "GetOperationStatusFinished.json", &state.getOperationStatusResp)
+	rows, err := db.QueryContext(ctx, `SELECT id FROM RANGE(100000000) ORDER BY RANDOM() + 2 asc`)
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	state.executeStatementResp = cli_service.TExecuteStatementResp{}
+	loadTestData(t, "ExecuteStatement7.json", &state.executeStatementResp)
+	loadTestData(t, "FetchResults8.json", &state.fetchResultsResp)
+	rows, err = db.QueryContext(ctx, "select * from diamonds limit 19")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+
+	for _, method := range []string{"ExecuteStatement", "GetOperationStatus", "FetchResults", "CloseOperation"} {
+		require.Contains(t, state.requestHeaders, method)
+	}
+}
+
+func TestUserAgentTag(t *testing.T) {
+	state := &callState{}
+	// load basic responses
+	loadTestData(t, "OpenSessionSuccess.json", &state.openSessionResp)
+	loadTestData(t, "CloseSessionSuccess.json", &state.closeSessionResp)
+	loadTestData(t, "CloseOperationSuccess.json", &state.closeOperationResp)
+
+	ts := getServer(state)
+
+	defer ts.Close()
+
+	db, err := sql.Open("databricks", ts.URL)
+	require.NoError(t, err)
+	defer db.Close()
+
+	runRequestsOfQueries(t, db, state, driverctx.NewContextWithUserAgentTag(context.Background(), "dashboards"))
+	for method, header := range state.requestHeaders {
+		assert.Contains(t, header.Get("User-Agent"), "(dashboards)", method)
+	}
+}
+
+func TestCorrelationIdHeader(t *testing.T) {
+	state := &callState{}
+	// load basic responses
+	loadTestData(t, "OpenSessionSuccess.json", &state.openSessionResp)
+	loadTestData(t, "CloseSessionSuccess.json", &state.closeSessionResp)
+	loadTestData(t, "CloseOperationSuccess.json", &state.closeOperationResp)
+
+	ts := getServer(state)
+
+	defer ts.Close()
+
+	db, err := sql.Open("databricks", ts.URL)
+	require.NoError(t, err)
+	defer db.Close()
+
+	runRequestsOfQueries(t, db, state, driverctx.NewContextWithCorrelationId(context.Background(), "trace-1"))
+	assert.Equal(t, "trace-1", state.requestHeaders["ExecuteStatement"].Get("X-Correlation-ID"))
+	for method, header := range state.requestHeaders {
+		assert.Equal(t, "trace-1", header.Get("X-Correlation-ID"), method)
+	}
+}
+
+func TestHeaderFunc(t *testing.T) {
+	state := &callState{}
+	// load basic responses
+	loadTestData(t, "OpenSessionSuccess.json", &state.openSessionResp)
+	loadTestData(t, "CloseSessionSuccess.json", &state.closeSessionResp)
+	loadTestData(t, "CloseOperationSuccess.json", &state.closeOperationResp)
+
+	ts := getServer(state)
+
+	defer ts.Close()
+	r, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(r.Port())
+	require.NoError(t, err)
+
+	type requestIdKey struct{}
+	connector, err := NewConnector(
+		WithServerHostname("localhost"),
+		WithPort(port),
+		WithHeaderFunc(func(ctx context.Context, header http.Header) {
+			if id, ok := ctx.Value(requestIdKey{}).(string); ok {
+				header.Set("X-Request-ID", id)
+			}
+		}),
+	)
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	runRequestsOfQueries(t, db, state, context.WithValue(context.Background(), requestIdKey{}, "req-1"))
+	for method, header := range state.requestHeaders {
+		assert.Equal(t, "req-1", header.Get("X-Request-ID"), method)
+	}
+}
+
 func TestRetries(t *testing.T) {
 	t.Run("it should retry on 503 and respect retry-after", func(t *testing.T) {
 
@@ -315,7 +464,8 @@ func TestRetries(t *testing.T) {
 
 		err = db.Ping()
 		require.NoError(t, err)
-		assert.Equal(t, 1, state.executeStatementCalls)
+		assert.Equal(t, 1, state.getInfoCalls)
+		assert.Zero(t, state.executeStatementCalls, "a ping doesn't run a statement")
 	})
 
 	t.Run("it should retry on 429 and respect retry-after", func(t *testing.T) {
@@ -340,7 +490,8 @@ func TestRetries(t *testing.T) {
 
 		err = db.Ping()
 		require.NoError(t, err)
-		assert.Equal(t, 1, state.executeStatementCalls)
+		assert.Equal(t, 1, state.getInfoCalls)
+		assert.Zero(t, state.executeStatementCalls, "a ping doesn't run a statement")
 	})
 
 	t.Run("it should fail after maxRetries", func(t *testing.T) {
@@ -413,6 +564,88 @@ func TestRetries(t *testing.T) {
 
 }
 
+func TestFailover(t *testing.T) {
+	state := &callState{}
+	loadTestData(t, "OpenSessionSuccess.json", &state.openSessionResp)
+	loadTestData(t, "CloseSessionSuccess.json", &state.closeSessionResp)
+	loadTestData(t, "CloseOperationSuccess.json", &state.closeOperationResp)
+	loadTestData(t, "ExecuteStatement1.json", &state.executeStatementResp)
+
+	ts := getServer(state)
+	defer ts.Close()
+	r, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(r.Port())
+	require.NoError(t, err)
+
+	connector, err := NewConnector(
+		WithServerHostname("localhost"),
+		WithHTTPPath("/500-5-retries"),
+		WithPort(port),
+		WithRetries(-1, 0, 0),
+		WithFailover(Endpoint{HTTPPath: "/sql/1.0/warehouses/standby"}),
+	)
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	require.NoError(t, db.Ping())
+	assert.Equal(t, 1, state.openSessionCalls, "the session is opened on the standby warehouse")
+
+	sqlConn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer sqlConn.Close()
+	require.NoError(t, sqlConn.Raw(func(driverConn any) error {
+		assert.Equal(t, "/sql/1.0/warehouses/standby", driverConn.(*conn).cfg.HTTPPath)
+		return nil
+	}))
+}
+
+func TestResultFromID(t *testing.T) {
+	state := &callState{}
+	loadTestData(t, "OpenSessionSuccess.json", &state.openSessionResp)
+	loadTestData(t, "CloseSessionSuccess.json", &state.closeSessionResp)
+	loadTestData(t, "CloseOperationSuccess.json", &state.closeOperationResp)
+	loadTestData(t, "GetOperationStatusFinished.json", &state.getOperationStatusResp)
+
+	ts := getServer(state)
+	defer ts.Close()
+	r, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(r.Port())
+	require.NoError(t, err)
+
+	connector, err := NewConnector(
+		WithServerHostname("localhost"),
+		WithPort(port),
+		WithHTTPPath(""),
+		WithAccessToken(""),
+	)
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	rows, err := ResultFromID(context.Background(), db, "01020304-0217-0402-0302-030404df2236:abcd")
+	require.NoError(t, err)
+	assert.Equal(t, 1, state.getOperationStatusCalls)
+	assert.Equal(t, 0, state.executeStatementCalls)
+
+	// the connection is released when the rows are closed
+	require.NoError(t, rows.Close())
+	assert.Equal(t, 1, state.closeOperationCalls)
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	_, err = ResultFromID(context.Background(), db, "not an id")
+	assert.EqualError(t, err, `databricks: invalid query id "not an id"`)
+
+	state.getOperationStatusResp.OperationState = cli_service.TOperationStatePtr(cli_service.TOperationState_CLOSED_STATE)
+	_, err = ResultFromID(context.Background(), db, "01020304-0217-0402-0302-030404df2236")
+	assert.EqualError(t, err, "databricks: query is closed")
+}
+
 // TODO: add tests for x-databricks headers
 
 func strPtr(s string) *string {
@@ -430,6 +663,9 @@ func loadTestData(t *testing.T, name string, v any) {
 }
 
 type callState struct {
+	// mu guards the state, the requests of a statement may run concurrently, e.g. its execution and its cancellation
+	mu sync.Mutex
+
 	openSessionCalls int
 	openSessionResp  cli_service.TOpenSessionResp
 	openSessionError error
@@ -440,10 +676,12 @@ type callState struct {
 
 	executeStatementCalls int
 	executeStatementSleep time.Duration
+	executeStatementReq   *cli_service.TExecuteStatementReq
 	executeStatementResp  cli_service.TExecuteStatementResp
 	executeStatementError error
 
 	cancelOperationCalls int
+	cancelOperationReq   *cli_service.TCancelOperationReq
 	cancelOperationResp  cli_service.TCancelOperationResp
 	cancelOperationError error
 
@@ -462,44 +700,92 @@ type callState struct {
 	getOperationStatusCalls int
 	getOperationStatusResp  cli_service.TGetOperationStatusResp
 	getOperationStatusError error
+
+	getInfoCalls int
+	getInfoError error
+
+	// header of the last request of each thrift method
+	requestHeaders map[string]http.Header
+}
+
+// recordHeader records the header of a request, with mu held
+func (s *callState) recordHeader(ctx context.Context, method string) {
+	if s.requestHeaders == nil {
+		s.requestHeaders = make(map[string]http.Header)
+	}
+	s.requestHeaders[method] = requestHeaderFromContext(ctx)
 }
 
 func getServer(state *callState) *httptest.Server {
 	return initThriftTestServer(&client.TestClient{
 		FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+			state.mu.Lock()
+			defer state.mu.Unlock()
 			state.openSessionCalls++
 			return &state.openSessionResp, state.openSessionError
 		},
 		FnCloseSession: func(ctx context.Context, req *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+			state.mu.Lock()
+			defer state.mu.Unlock()
 			state.closeSessionCalls++
 			return &state.closeSessionResp, state.closeSessionError
 		},
 		FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+			state.mu.Lock()
+			defer state.mu.Unlock()
 			state.executeStatementCalls++
-			if state.executeStatementSleep != 0 {
-				time.Sleep(state.executeStatementSleep)
+			state.executeStatementReq = req
+			state.recordHeader(ctx, "ExecuteStatement")
+			if sleep := state.executeStatementSleep; sleep != 0 {
+				state.mu.Unlock()
+				time.Sleep(sleep)
+				state.mu.Lock()
 			}
 			return &state.executeStatementResp, state.executeStatementError
 		},
 		FnGetOperationStatus: func(ctx context.Context, req *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
+			state.mu.Lock()
+			defer state.mu.Unlock()
 			state.getOperationStatusCalls++
+			state.recordHeader(ctx, "GetOperationStatus")
 			return &state.getOperationStatusResp, state.getOperationStatusError
 		},
 		FnCloseOperation: func(ctx context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+			state.mu.Lock()
+			defer state.mu.Unlock()
 			state.closeOperationCalls++
+			state.recordHeader(ctx, "CloseOperation")
 			return &state.closeOperationResp, state.closeOperationError
 		},
 		FnCancelOperation: func(ctx context.Context, req *cli_service.TCancelOperationReq) (*cli_service.TCancelOperationResp, error) {
+			state.mu.Lock()
+			defer state.mu.Unlock()
 			state.cancelOperationCalls++
+			state.cancelOperationReq = req
+			state.recordHeader(ctx, "CancelOperation")
 			return &state.cancelOperationResp, state.cancelOperationError
 		},
 		FnFetchResults: func(ctx context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+			state.mu.Lock()
+			defer state.mu.Unlock()
 			state.fetchResultsCalls++
+			state.recordHeader(ctx, "FetchResults")
 			return &state.fetchResultsResp, state.fetchResultsError
 		},
 		FnGetResultSetMetadata: func(ctx context.Context, req *cli_service.TGetResultSetMetadataReq) (*cli_service.TGetResultSetMetadataResp, error) {
+			state.mu.Lock()
+			defer state.mu.Unlock()
 			state.getResultSetMetadataCalls++
 			return &state.getResultSetMetadataResp, state.getResultSetMetadataError
 		},
+		FnGetInfo: func(ctx context.Context, req *cli_service.TGetInfoReq) (*cli_service.TGetInfoResp, error) {
+			state.mu.Lock()
+			defer state.mu.Unlock()
+			state.getInfoCalls++
+			return &cli_service.TGetInfoResp{
+				Status:    &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+				InfoValue: &cli_service.TGetInfoValue{StringValue: thrift.StringPtr("Spark SQL")},
+			}, state.getInfoError
+		},
 	})
 }
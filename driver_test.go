@@ -31,6 +31,9 @@ func TestOOpenConnector(t *testing.T) {
 		require.NoError(t, err)
 		coni, ok := c.(*connector)
 		require.True(t, ok)
+		require.NotNil(t, coni.cfg.Transport)
+		expectedCfg.Transport = coni.cfg.Transport
+		expectedCfg.Metrics = coni.cfg.Metrics
 		assert.Equal(t, expectedCfg, coni.cfg)
 		assert.NotNil(t, coni.client)
 	})
@@ -2,6 +2,8 @@ package driverctx
 
 import (
 	"context"
+	"io"
+	"time"
 )
 
 // Key name to look for Correlation Id in context
@@ -11,8 +13,41 @@ type contextKey int
 const (
 	CorrelationIdContextKey contextKey = iota
 	ConnIdContextKey
+	QueryIdCallbackContextKey
+	QueryTimeoutContextKey
+	StatementTagsContextKey
+	StagingPathsContextKey
+	StagingReaderContextKey
+	StagingWriterContextKey
+	CatalogContextKey
+	SchemaContextKey
+	ProgressCallbackContextKey
+	ResultCacheBypassContextKey
+	UserAgentTagContextKey
 )
 
+// IdCallbackFunc is called with the id of an object created by the driver
+type IdCallbackFunc func(string)
+
+// QueryProgress is the status of a running query, as reported by the server each time the driver polls it
+type QueryProgress struct {
+	// QueryId is the id of the query
+	QueryId string
+	// State is the state of the query, e.g. PENDING_STATE, RUNNING_STATE or FINISHED_STATE
+	State string
+	// Progress is the fraction of the query tasks done, from 0 to 1, or -1 if the server doesn't report it
+	Progress float64
+	// TaskStatus is the status of the query tasks, as reported by the server
+	TaskStatus string
+	// Message is the display message or the error message of the query so far
+	Message string
+	// Started is the time the query started running, zero if the server doesn't report it
+	Started time.Time
+}
+
+// ProgressCallbackFunc is called with the status of a query each time it is polled
+type ProgressCallbackFunc func(QueryProgress)
+
 // NewContextWithCorrelationId creates a new context with correlationId value. Used by Logger to populate field corrId.
 func NewContextWithCorrelationId(ctx context.Context, correlationId string) context.Context {
 	return context.WithValue(ctx, CorrelationIdContextKey, correlationId)
@@ -40,3 +75,165 @@ func ConnIdFromContext(ctx context.Context) string {
 	}
 	return connId
 }
+
+// NewContextWithQueryIdCallback creates a new context with a callback called with the query id as soon as
+// the server starts running a query, before its results are available.
+func NewContextWithQueryIdCallback(ctx context.Context, callback IdCallbackFunc) context.Context {
+	return context.WithValue(ctx, QueryIdCallbackContextKey, callback)
+}
+
+// QueryIdCallbackFromContext retrieves the query id callback stored in context, nil if there is none.
+func QueryIdCallbackFromContext(ctx context.Context) IdCallbackFunc {
+	callback, ok := ctx.Value(QueryIdCallbackContextKey).(IdCallbackFunc)
+	if !ok {
+		return nil
+	}
+	return callback
+}
+
+// NewContextWithQueryTimeout creates a new context with the timeout of the queries run with it on the server,
+// overriding the timeout of the connection. A zero timeout disables the timeout.
+func NewContextWithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, QueryTimeoutContextKey, timeout)
+}
+
+// QueryTimeoutFromContext retrieves the query timeout stored in context, ok is false if there is none.
+func QueryTimeoutFromContext(ctx context.Context) (timeout time.Duration, ok bool) {
+	timeout, ok = ctx.Value(QueryTimeoutContextKey).(time.Duration)
+	return timeout, ok
+}
+
+// NewContextWithStatementTags creates a new context with tags attached to the queries run with it, e.g. to
+// tell interactive and batch queries apart in the Query History. The tags are added to the tags of ctx.
+func NewContextWithStatementTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	for k, v := range StatementTagsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, StatementTagsContextKey, merged)
+}
+
+// StatementTagsFromContext retrieves the statement tags stored in context.
+func StatementTagsFromContext(ctx context.Context) map[string]string {
+	tags, ok := ctx.Value(StatementTagsContextKey).(map[string]string)
+	if !ok {
+		return nil
+	}
+	return tags
+}
+
+// NewContextWithCatalog creates a new context with the catalog of the statements run with it, instead of the
+// current catalog of the session. The session is not changed, so connections of a pool can be shared by
+// statements running in different catalogs.
+func NewContextWithCatalog(ctx context.Context, catalog string) context.Context {
+	return context.WithValue(ctx, CatalogContextKey, catalog)
+}
+
+// CatalogFromContext retrieves the catalog stored in context, empty if there is none.
+func CatalogFromContext(ctx context.Context) string {
+	catalog, _ := ctx.Value(CatalogContextKey).(string)
+	return catalog
+}
+
+// NewContextWithSchema creates a new context with the schema of the statements run with it, instead of the
+// current schema of the session. The session is not changed.
+func NewContextWithSchema(ctx context.Context, schema string) context.Context {
+	return context.WithValue(ctx, SchemaContextKey, schema)
+}
+
+// SchemaFromContext retrieves the schema stored in context, empty if there is none.
+func SchemaFromContext(ctx context.Context) string {
+	schema, _ := ctx.Value(SchemaContextKey).(string)
+	return schema
+}
+
+// NewContextWithProgressCallback creates a new context with a callback called with the status of the queries
+// run with it each time they are polled, e.g. to render a progress bar or log the query phases. The callback
+// runs on the goroutine running the query, so it should return quickly.
+func NewContextWithProgressCallback(ctx context.Context, callback ProgressCallbackFunc) context.Context {
+	return context.WithValue(ctx, ProgressCallbackContextKey, callback)
+}
+
+// ProgressCallbackFromContext retrieves the progress callback stored in context, nil if there is none.
+func ProgressCallbackFromContext(ctx context.Context) ProgressCallbackFunc {
+	callback, _ := ctx.Value(ProgressCallbackContextKey).(ProgressCallbackFunc)
+	return callback
+}
+
+// NewContextWithResultCacheBypass creates a new context whose queries run on the warehouse even when their result
+// is cached. Their fresh results replace the cached ones.
+func NewContextWithResultCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ResultCacheBypassContextKey, true)
+}
+
+// ResultCacheBypassFromContext retrieves whether the queries run with context bypass the result cache.
+func ResultCacheBypassFromContext(ctx context.Context) bool {
+	bypass, _ := ctx.Value(ResultCacheBypassContextKey).(bool)
+	return bypass
+}
+
+// NewContextWithUserAgentTag creates a new context whose requests have tag appended to their User-Agent header
+// after the user agent entry, e.g. the feature of a partner application running the queries.
+func NewContextWithUserAgentTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, UserAgentTagContextKey, tag)
+}
+
+// UserAgentTagFromContext retrieves the User-Agent tag of the requests run with context.
+func UserAgentTagFromContext(ctx context.Context) string {
+	tag, _ := ctx.Value(UserAgentTagContextKey).(string)
+	return tag
+}
+
+// NewContextWithStagingInfo creates a new context with the local paths that the PUT, GET and REMOVE staging
+// statements run with it may read or write. Files outside of these paths are rejected.
+func NewContextWithStagingInfo(ctx context.Context, allowedLocalPaths []string) context.Context {
+	return context.WithValue(ctx, StagingPathsContextKey, allowedLocalPaths)
+}
+
+// StagingPathsFromContext retrieves the local paths allowed for staging statements stored in context.
+func StagingPathsFromContext(ctx context.Context) []string {
+	paths, ok := ctx.Value(StagingPathsContextKey).([]string)
+	if !ok {
+		return nil
+	}
+	return paths
+}
+
+type stagingReader struct {
+	r    io.Reader
+	size int64
+}
+
+// NewContextWithStagingReader creates a new context with the content uploaded by the PUT staging statements run
+// with it instead of their local file. size is the number of bytes of r. An io.ReadSeeker can be sent again when
+// the upload is retried, other readers are buffered in memory.
+func NewContextWithStagingReader(ctx context.Context, r io.Reader, size int64) context.Context {
+	return context.WithValue(ctx, StagingReaderContextKey, stagingReader{r: r, size: size})
+}
+
+// StagingReaderFromContext retrieves the staging reader stored in context and its size, nil if there is none.
+func StagingReaderFromContext(ctx context.Context) (io.Reader, int64) {
+	sr, ok := ctx.Value(StagingReaderContextKey).(stagingReader)
+	if !ok {
+		return nil, 0
+	}
+	return sr.r, sr.size
+}
+
+// NewContextWithStagingWriter creates a new context with the writer receiving the content downloaded by the GET
+// staging statements run with it instead of their local file.
+func NewContextWithStagingWriter(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, StagingWriterContextKey, w)
+}
+
+// StagingWriterFromContext retrieves the staging writer stored in context, nil if there is none.
+func StagingWriterFromContext(ctx context.Context) io.Writer {
+	w, ok := ctx.Value(StagingWriterContextKey).(io.Writer)
+	if !ok {
+		return nil
+	}
+	return w
+}
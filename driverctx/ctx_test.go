@@ -2,6 +2,7 @@ package driverctx
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -36,3 +37,90 @@ func TestNewContextWithCorrelationId(t *testing.T) {
 	})
 
 }
+
+func TestNewContextWithQueryIdCallback(t *testing.T) {
+	t.Run("base case", func(t *testing.T) {
+		var queryId string
+		ctx := NewContextWithQueryIdCallback(context.Background(), func(id string) { queryId = id })
+		callback := QueryIdCallbackFromContext(ctx)
+		assert.NotNil(t, callback)
+		callback("abc")
+		assert.Equal(t, "abc", queryId)
+	})
+	t.Run("no callback", func(t *testing.T) {
+		assert.Nil(t, QueryIdCallbackFromContext(context.Background()))
+		ctx := NewContextWithQueryIdCallback(context.Background(), nil)
+		assert.Nil(t, QueryIdCallbackFromContext(ctx))
+	})
+}
+
+func TestNewContextWithProgressCallback(t *testing.T) {
+	assert.Nil(t, ProgressCallbackFromContext(context.Background()))
+	var state string
+	ctx := NewContextWithProgressCallback(context.Background(), func(p QueryProgress) { state = p.State })
+	callback := ProgressCallbackFromContext(ctx)
+	assert.NotNil(t, callback)
+	callback(QueryProgress{State: "RUNNING_STATE"})
+	assert.Equal(t, "RUNNING_STATE", state)
+}
+
+func TestNewContextWithResultCacheBypass(t *testing.T) {
+	assert.False(t, ResultCacheBypassFromContext(context.Background()))
+	assert.True(t, ResultCacheBypassFromContext(NewContextWithResultCacheBypass(context.Background())))
+}
+
+func TestNewContextWithUserAgentTag(t *testing.T) {
+	assert.Empty(t, UserAgentTagFromContext(context.Background()))
+	assert.Equal(t, "dashboards", UserAgentTagFromContext(NewContextWithUserAgentTag(context.Background(), "dashboards")))
+}
+
+func TestNewContextWithQueryTimeout(t *testing.T) {
+	_, ok := QueryTimeoutFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := NewContextWithQueryTimeout(context.Background(), time.Minute)
+	timeout, ok := QueryTimeoutFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, timeout)
+}
+
+func TestNewContextWithStatementTags(t *testing.T) {
+	assert.Nil(t, StatementTagsFromContext(context.Background()))
+
+	tags := map[string]string{"team": "growth", "workload": "batch"}
+	ctx := NewContextWithStatementTags(context.Background(), tags)
+	ctx1 := NewContextWithStatementTags(ctx, map[string]string{"workload": "interactive"})
+	assert.Equal(t, tags, StatementTagsFromContext(ctx))
+	assert.Equal(t, map[string]string{"team": "growth", "workload": "interactive"}, StatementTagsFromContext(ctx1))
+}
+
+func TestNewContextWithNamespace(t *testing.T) {
+	assert.Empty(t, CatalogFromContext(context.Background()))
+	assert.Empty(t, SchemaFromContext(context.Background()))
+
+	ctx := NewContextWithSchema(NewContextWithCatalog(context.Background(), "tenant_a"), "sales")
+	assert.Equal(t, "tenant_a", CatalogFromContext(ctx))
+	assert.Equal(t, "sales", SchemaFromContext(ctx))
+}
+
+func TestNewContextWithStagingInfo(t *testing.T) {
+	assert.Nil(t, StagingPathsFromContext(context.Background()))
+
+	ctx := NewContextWithStagingInfo(context.Background(), []string{"/tmp/staging"})
+	assert.Equal(t, []string{"/tmp/staging"}, StagingPathsFromContext(ctx))
+}
+
+func TestNewContextWithStagingStreams(t *testing.T) {
+	r, _ := StagingReaderFromContext(context.Background())
+	assert.Nil(t, r)
+	assert.Nil(t, StagingWriterFromContext(context.Background()))
+
+	ctx := NewContextWithStagingReader(context.Background(), strings.NewReader("data"), 4)
+	r, size := StagingReaderFromContext(ctx)
+	assert.NotNil(t, r)
+	assert.Equal(t, int64(4), size)
+
+	var buf strings.Builder
+	ctx = NewContextWithStagingWriter(context.Background(), &buf)
+	assert.Same(t, &buf, StagingWriterFromContext(ctx))
+}
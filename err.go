@@ -1,12 +1,24 @@
 package dbsql
 
 import (
+	"context"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
 	"github.com/pkg/errors"
 )
 
 var ErrNotImplemented = "databricks: not implemented"
 var ErrTransactionsNotSupported = "databricks: transactions are not supported"
-var ErrParametersNotSupported = "databricks: query parameters are not supported"
+var ErrParametersNotSupported = "databricks: query parameters are not supported by the server"
+var ErrStagingPathNotAllowed = "databricks: local file is not in the staging allowed local paths"
+var ErrReadOnlyStatement = "databricks: statement is not allowed on a read-only connection"
+var ErrCachedResultArrowBatches = "databricks: arrow batches are not available for cached results"
+var ErrStatsNotAvailable = "databricks: statement stats are not available"
+var ErrConnectTimeout = "databricks: connection not opened within the connect timeout of %s"
+var ErrConnectorClosed = "databricks: connector is closed"
 
 type stackTracer interface {
 	StackTrace() errors.StackTrace
@@ -33,3 +45,38 @@ func wrapErrf(err error, format string, args ...interface{}) error {
 type causer interface {
 	Cause() error
 }
+
+// newDriverError returns a dbsqlerr.DriverError with a stack trace
+func newDriverError(msg string, err error) error {
+	return errors.WithStack(dbsqlerr.NewDriverError(msg, err))
+}
+
+// newExecutionError returns the dbsqlerr.ExecutionError of a query which did not finish successfully
+func (c *conn) newExecutionError(ctx context.Context, opHandle *cli_service.TOperationHandle, status *cli_service.TGetOperationStatusResp) error {
+	msg := status.GetDisplayMessage()
+	if msg == "" {
+		// the error message may have the stack trace of the server, it is kept in the details
+		msg = dbsqlerr.FirstLine(status.GetErrorMessage())
+	}
+	if msg == "" {
+		msg = "query state: " + status.GetOperationState().String()
+	}
+	execErr := &dbsqlerr.ExecutionError{
+		Msg:           msg,
+		SQLState:      status.GetSqlState(),
+		ErrorClass:    dbsqlerr.ErrorClass(msg),
+		ErrorCode:     status.GetErrorCode(),
+		QueryState:    status.GetOperationState().String(),
+		ConnectionId:  c.id,
+		CorrelationId: driverctx.CorrelationIdFromContext(ctx),
+	}
+	var infoMessages []string
+	if status.Status != nil {
+		infoMessages = status.Status.InfoMessages
+	}
+	execErr.Server = client.NewServerDetails(status.GetErrorMessage(), infoMessages, status.GetDiagnosticInfo())
+	if opHandle != nil && opHandle.OperationId != nil {
+		execErr.QueryId = client.SprintGuid(opHandle.OperationId.GUID)
+	}
+	return errors.WithStack(execErr)
+}
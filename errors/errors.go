@@ -0,0 +1,308 @@
+// Package dbsqlerr defines the errors returned by the driver, so they can be told apart with errors.Is and
+// errors.As instead of matching their messages:
+//
+//	var execErr *dbsqlerr.ExecutionError
+//	if errors.As(err, &execErr) && execErr.SQLState == "42P01" {
+//		// the table does not exist
+//	}
+//	if dbsqlerr.IsRetryable(err) {
+//		// run the query again
+//	}
+//
+// The messages of the errors of the server are their display message, or the first line of their error message.
+// The full error message and the stack trace of the server are in the Server details of the error, and are added to
+// the messages with SetVerbose(true).
+package dbsqlerr
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Targets of errors.Is for the kinds of errors of the driver
+var (
+	ErrDriver    = errors.New("databricks: driver error")
+	ErrRequest   = errors.New("databricks: request error")
+	ErrExecution = errors.New("databricks: execution error")
+)
+
+// DriverError is an error of the driver itself, e.g. an invalid argument or an unsupported feature
+type DriverError struct {
+	Msg string
+	Err error // cause, may be nil
+}
+
+// NewDriverError returns a DriverError with msg and its cause err, which may be nil
+func NewDriverError(msg string, err error) *DriverError {
+	return &DriverError{Msg: msg, Err: err}
+}
+
+func (e *DriverError) Error() string {
+	return message(e.Msg, e.Err)
+}
+
+func (e *DriverError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DriverError) Is(target error) bool {
+	return target == ErrDriver
+}
+
+// IsRetryable returns false, running a query again does not fix errors of the driver
+func (e *DriverError) IsRetryable() bool {
+	return false
+}
+
+// RequestError is the error of a failed request to the server, e.g. a network error or an error status
+type RequestError struct {
+	Msg            string
+	HTTPStatusCode int    // status code of the HTTP response, 0 if there was none
+	StatusCode     string // Thrift status code, e.g. ERROR_STATUS
+	ErrorCode      int32  // Thrift error code
+	SQLState       string
+	ConnectionId   string
+	CorrelationId  string
+	QueryId        string
+	Retryable      bool           // the request may succeed when sent again
+	Server         *ServerDetails // details of the error status of the server, nil if there was none
+	Err            error          // cause, may be nil
+}
+
+func (e *RequestError) Error() string {
+	return message(e.Msg, e.Err) + e.Server.verbose()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RequestError) Is(target error) bool {
+	return target == ErrRequest
+}
+
+// IsRetryable returns true when the request may succeed when sent again, e.g. after a timeout or when the
+// server is rate limiting requests
+func (e *RequestError) IsRetryable() bool {
+	if e.Retryable || e.HTTPStatusCode == http.StatusTooManyRequests || e.HTTPStatusCode == http.StatusServiceUnavailable {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// RateLimitError is the error of a request which the server kept rejecting with HTTP 429 or 503, e.g. while
+// a serverless warehouse is scaling up, until the retries ran out. Its cause is the RequestError of the last response.
+type RateLimitError struct {
+	RetryAfter time.Duration // wait asked by the Retry-After header of the last response, 0 if it had none
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	msg := "databricks: rate limited by the server"
+	if e.RetryAfter > 0 {
+		msg += ", retry after " + e.RetryAfter.String()
+	}
+	return message(msg, e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable returns true, the request may succeed after waiting RetryAfter
+func (e *RateLimitError) IsRetryable() bool {
+	return true
+}
+
+// WarehouseStartingError is the error of a request which the server rejected with HTTP 503 TEMPORARILY_UNAVAILABLE
+// while the warehouse is starting, e.g. after it was stopped for being idle, when the warehouse didn't start within
+// the warehouse start timeout. Its cause is the RequestError of the last response.
+type WarehouseStartingError struct {
+	Waited time.Duration // time the driver waited for the warehouse to start, 0 if it didn't wait
+	Err    error
+}
+
+func (e *WarehouseStartingError) Error() string {
+	msg := "databricks: the warehouse is starting"
+	if e.Waited > 0 {
+		msg += ", waited " + e.Waited.Round(time.Second).String()
+	}
+	return message(msg, e.Err)
+}
+
+func (e *WarehouseStartingError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable returns true, the request succeeds once the warehouse started
+func (e *WarehouseStartingError) IsRetryable() bool {
+	return true
+}
+
+// CircuitOpenError is the error of a request which was not sent because the circuit breaker of the warehouse is
+// open after consecutive failed requests, see dbsql.WithCircuitBreaker
+type CircuitOpenError struct {
+	Host       string
+	HTTPPath   string
+	Failures   int           // consecutive failed requests
+	RetryAfter time.Duration // time until the breaker lets a probe request through
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("databricks: circuit breaker is open for %s%s after %d failed requests, retry after %s",
+		e.Host, e.HTTPPath, e.Failures, e.RetryAfter.Round(time.Second))
+}
+
+// IsRetryable returns true, the request may succeed once the breaker closed
+func (e *CircuitOpenError) IsRetryable() bool {
+	return true
+}
+
+// ExecutionError is the error of a query which failed on the server
+type ExecutionError struct {
+	Msg           string
+	SQLState      string
+	ErrorClass    string // Databricks error class, e.g. TABLE_OR_VIEW_NOT_FOUND
+	ErrorCode     int32
+	QueryState    string // state of the operation, e.g. ERROR_STATE
+	ConnectionId  string
+	CorrelationId string
+	QueryId       string
+	Server        *ServerDetails // details of the error of the server, nil if there are none
+	Err           error          // cause, may be nil
+}
+
+func (e *ExecutionError) Error() string {
+	return message(e.Msg, e.Err) + e.Server.verbose()
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExecutionError) Is(target error) bool {
+	return target == ErrExecution
+}
+
+// IsRetryable returns true for the queries which timed out or failed because of a connection exception
+// (SQLSTATE class 08)
+func (e *ExecutionError) IsRetryable() bool {
+	return e.QueryState == "TIMEDOUT_STATE" || (len(e.SQLState) == 5 && e.SQLState[:2] == "08")
+}
+
+// ResultTruncatedError is returned when reading the results of a query past the result limits of the
+// connection, see dbsql.WithMaxRowsTotal and dbsql.WithMaxBytesPerQuery. The rows read before it are valid.
+type ResultTruncatedError struct {
+	MaxRowsTotal     int64 // limit on the rows exceeded by the results, 0 if the byte limit was exceeded
+	MaxBytesPerQuery int64 // limit on the bytes exceeded by the results, 0 if the row limit was exceeded
+	Rows             int64 // rows read before the results were truncated
+	QueryId          string
+}
+
+func (e *ResultTruncatedError) Error() string {
+	if e.MaxRowsTotal > 0 {
+		return fmt.Sprintf("databricks: results truncated after %d rows, the limit is %d rows", e.Rows, e.MaxRowsTotal)
+	}
+	return fmt.Sprintf("databricks: results truncated after %d rows, the limit is %d bytes", e.Rows, e.MaxBytesPerQuery)
+}
+
+// ServerDetails are the diagnostics of an error of the server, which may be long
+type ServerDetails struct {
+	ErrorMessage   string   // full error message, it may have the stack trace of the server
+	InfoMessages   []string // info messages of the status, e.g. the frames of the stack trace of the server
+	DiagnosticInfo string   // diagnostic info of a failed query, usually the stack trace of the server
+}
+
+// StackTrace returns the stack trace of the server, from the diagnostic info, the info messages or the lines of
+// the error message following the first one. It is empty if there is none.
+func (d *ServerDetails) StackTrace() string {
+	switch {
+	case d == nil:
+		return ""
+	case d.DiagnosticInfo != "":
+		return d.DiagnosticInfo
+	case len(d.InfoMessages) > 0:
+		return strings.Join(d.InfoMessages, "\n")
+	}
+	if i := strings.IndexByte(d.ErrorMessage, '\n'); i >= 0 {
+		return strings.TrimSpace(d.ErrorMessage[i+1:])
+	}
+	return ""
+}
+
+// String returns the full error message and the stack trace
+func (d *ServerDetails) String() string {
+	if d == nil {
+		return ""
+	}
+	s := FirstLine(d.ErrorMessage)
+	if trace := d.StackTrace(); trace != "" {
+		s += "\n" + trace
+	}
+	return s
+}
+
+// verbose returns the details appended to the messages of errors in verbose mode, with a leading new line
+func (d *ServerDetails) verbose() string {
+	if d == nil || !verbose.Load() {
+		return ""
+	}
+	if s := d.String(); s != "" {
+		return "\n" + s
+	}
+	return ""
+}
+
+var verbose atomic.Bool
+
+// SetVerbose sets whether the messages of the errors of the server have their full error message and the stack
+// trace of the server, see ServerDetails. Default is false.
+func SetVerbose(enabled bool) {
+	verbose.Store(enabled)
+}
+
+// FirstLine returns the first line of an error message of the server, without the stack trace which may follow it
+func FirstLine(msg string) string {
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	return strings.TrimSpace(msg)
+}
+
+var errorClassPattern = regexp.MustCompile(`^\[([A-Z][A-Z0-9_.]*)\]`)
+
+// ErrorClass returns the Databricks error class at the start of a message, e.g. TABLE_OR_VIEW_NOT_FOUND
+// for "[TABLE_OR_VIEW_NOT_FOUND] The table or view `t` cannot be found.", or an empty string
+func ErrorClass(msg string) string {
+	if m := errorClassPattern.FindStringSubmatch(msg); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// IsRetryable returns true if an error of the chain of err may not happen again when the query is run again
+func IsRetryable(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if r, ok := err.(interface{ IsRetryable() bool }); ok && r.IsRetryable() {
+			return true
+		}
+	}
+	return false
+}
+
+func message(msg string, err error) string {
+	if err == nil {
+		return msg
+	}
+	if msg == "" {
+		return err.Error()
+	}
+	return msg + ": " + err.Error()
+}
@@ -0,0 +1,117 @@
+package dbsqlerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrors(t *testing.T) {
+	t.Run("errors can be told apart with Is and As", func(t *testing.T) {
+		execErr := fmt.Errorf("failed to run query: %w", pkgerrors.WithStack(&ExecutionError{Msg: "[DIVIDE_BY_ZERO] Division by zero", SQLState: "22012", QueryId: "q1"}))
+		assert.ErrorIs(t, execErr, ErrExecution)
+		assert.NotErrorIs(t, execErr, ErrRequest)
+		var target *ExecutionError
+		require.ErrorAs(t, execErr, &target)
+		assert.Equal(t, "22012", target.SQLState)
+		assert.Equal(t, "q1", target.QueryId)
+		assert.Equal(t, "failed to run query: [DIVIDE_BY_ZERO] Division by zero", execErr.Error())
+
+		reqErr := &RequestError{Msg: "execute statement request error", Err: context.DeadlineExceeded}
+		assert.ErrorIs(t, reqErr, ErrRequest)
+		assert.ErrorIs(t, reqErr, context.DeadlineExceeded)
+		assert.Equal(t, "execute statement request error: context deadline exceeded", reqErr.Error())
+
+		driverErr := NewDriverError("databricks: transactions are not supported", nil)
+		assert.ErrorIs(t, driverErr, ErrDriver)
+		assert.Equal(t, "databricks: transactions are not supported", driverErr.Error())
+	})
+
+	t.Run("the details of the server are added to the messages in verbose mode", func(t *testing.T) {
+		details := &ServerDetails{
+			ErrorMessage:   "[DIVIDE_BY_ZERO] Division by zero\n\tat Foo.bar(Foo.scala:1)",
+			DiagnosticInfo: "org.apache.spark.SparkArithmeticException: [DIVIDE_BY_ZERO] Division by zero\n\tat Foo.bar(Foo.scala:1)",
+		}
+		err := &ExecutionError{Msg: "[DIVIDE_BY_ZERO] Division by zero", Server: details}
+		assert.Equal(t, "[DIVIDE_BY_ZERO] Division by zero", err.Error())
+
+		SetVerbose(true)
+		defer SetVerbose(false)
+		assert.Equal(t, "[DIVIDE_BY_ZERO] Division by zero\n[DIVIDE_BY_ZERO] Division by zero\n"+details.DiagnosticInfo, err.Error())
+		reqErr := &RequestError{Msg: "failed", Server: &ServerDetails{ErrorMessage: "failed\n\tat Foo.bar(Foo.scala:1)"}}
+		assert.Equal(t, "failed\nfailed\nat Foo.bar(Foo.scala:1)", reqErr.Error())
+		assert.Equal(t, "failed", (&RequestError{Msg: "failed"}).Error())
+	})
+
+	t.Run("the stack trace of the server is read from the details", func(t *testing.T) {
+		assert.Equal(t, "trace", (&ServerDetails{DiagnosticInfo: "trace", InfoMessages: []string{"a"}}).StackTrace())
+		assert.Equal(t, "a\nb", (&ServerDetails{ErrorMessage: "msg\nc", InfoMessages: []string{"a", "b"}}).StackTrace())
+		assert.Equal(t, "at c", (&ServerDetails{ErrorMessage: "msg\n  at c"}).StackTrace())
+		assert.Empty(t, (&ServerDetails{ErrorMessage: "msg"}).StackTrace())
+		assert.Empty(t, (*ServerDetails)(nil).StackTrace())
+		assert.Equal(t, "msg", FirstLine(" msg \n at c"))
+	})
+
+	t.Run("rate limit errors are request errors", func(t *testing.T) {
+		err := pkgerrors.WithStack(&RateLimitError{RetryAfter: 30 * time.Second, Err: &RequestError{Msg: "too many requests", HTTPStatusCode: http.StatusTooManyRequests}})
+		assert.ErrorIs(t, err, ErrRequest)
+		var rateLimitErr *RateLimitError
+		require.ErrorAs(t, err, &rateLimitErr)
+		assert.Equal(t, 30*time.Second, rateLimitErr.RetryAfter)
+		assert.Equal(t, "databricks: rate limited by the server, retry after 30s: too many requests", err.Error())
+		assert.True(t, IsRetryable(err))
+	})
+
+	t.Run("warehouse starting errors are request errors", func(t *testing.T) {
+		err := pkgerrors.WithStack(&WarehouseStartingError{Waited: 90 * time.Second, Err: &RequestError{Msg: "unavailable", HTTPStatusCode: http.StatusServiceUnavailable}})
+		assert.ErrorIs(t, err, ErrRequest)
+		var startErr *WarehouseStartingError
+		require.ErrorAs(t, err, &startErr)
+		assert.Equal(t, "databricks: the warehouse is starting, waited 1m30s: unavailable", err.Error())
+		assert.True(t, IsRetryable(err))
+	})
+
+	t.Run("IsRetryable", func(t *testing.T) {
+		timeout := &net.OpError{Op: "dial", Err: &timeoutError{}}
+		cases := []struct {
+			err       error
+			retryable bool
+		}{
+			{nil, false},
+			{errors.New("unknown"), false},
+			{NewDriverError("invalid argument", nil), false},
+			{&RequestError{Msg: "bad request", HTTPStatusCode: http.StatusBadRequest}, false},
+			{&RequestError{Msg: "rate limited", HTTPStatusCode: http.StatusTooManyRequests}, true},
+			{&RequestError{Msg: "outer", Err: &RequestError{Msg: "unavailable", HTTPStatusCode: http.StatusServiceUnavailable}}, true},
+			{pkgerrors.WithStack(&RequestError{Msg: "timeout", Err: timeout}), true},
+			{&ExecutionError{Msg: "syntax error", SQLState: "42601", QueryState: "ERROR_STATE"}, false},
+			{&ExecutionError{Msg: "timed out", QueryState: "TIMEDOUT_STATE"}, true},
+			{&ExecutionError{Msg: "connection lost", SQLState: "08S01", QueryState: "ERROR_STATE"}, true},
+			{&ResultTruncatedError{MaxRowsTotal: 10, Rows: 10}, false},
+			{&RequestError{Msg: "circuit open", Err: &CircuitOpenError{Host: "host", Failures: 5}}, true},
+		}
+		for _, c := range cases {
+			assert.Equal(t, c.retryable, IsRetryable(c.err), "%v", c.err)
+		}
+	})
+
+	t.Run("ErrorClass", func(t *testing.T) {
+		assert.Equal(t, "TABLE_OR_VIEW_NOT_FOUND", ErrorClass("[TABLE_OR_VIEW_NOT_FOUND] The table or view `t` cannot be found."))
+		assert.Equal(t, "INVALID_PARAMETER_VALUE.DATETIME_UNIT", ErrorClass("[INVALID_PARAMETER_VALUE.DATETIME_UNIT] invalid unit"))
+		assert.Equal(t, "", ErrorClass("Table or view not found: t"))
+	})
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	dbsqlrows "github.com/databricks/databricks-sql-go/rows"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Opening a driver typically will not attempt to connect to the database.
+	err := godotenv.Load()
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	port, err := strconv.Atoi(os.Getenv("DATABRICKS_PORT"))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	connector, err := dbsql.NewConnector(
+		dbsql.WithServerHostname(os.Getenv("DATABRICKS_HOST")),
+		dbsql.WithPort(port),
+		dbsql.WithHTTPPath(os.Getenv("DATABRICKS_HTTPPATH")),
+		dbsql.WithAccessToken(os.Getenv("DATABRICKS_ACCESSTOKEN")),
+	)
+	if err != nil {
+		// This will not be a connection error, but a DSN parse error or
+		// another initialization error.
+		log.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the Arrow batches are only available on the rows of the driver connection
+	err = conn.Raw(func(driverConn any) error {
+		rows, err := driverConn.(driver.QueryerContext).QueryContext(ctx, `select * from samples.nyctaxi.trips`, nil)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		batches, err := rows.(dbsqlrows.Rows).GetArrowBatches(ctx)
+		if err != nil {
+			return err
+		}
+		defer batches.Close()
+
+		schema, err := batches.Schema()
+		if err != nil {
+			return err
+		}
+		fmt.Println(schema)
+
+		var nRows int64
+		for batches.HasNext() {
+			record, err := batches.Next()
+			if err != nil {
+				return err
+			}
+			nRows += record.NumRows()
+			record.Release()
+		}
+		fmt.Printf("%d rows\n", nRows)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,152 @@
+// Package dbsqlexport writes query results to Parquet and CSV files. The results are read as Arrow record
+// batches and written a batch at a time, so results of any size are exported with the memory of a few batches.
+//
+// database/sql doesn't expose the driver's rows, so the query has to be run on the driver connection:
+//
+//	conn, _ := db.Conn(ctx)
+//	defer conn.Close()
+//
+//	err := conn.Raw(func(driverConn any) error {
+//		r, err := driverConn.(driver.QueryerContext).QueryContext(ctx, "select * from samples.nyctaxi.trips", nil)
+//		if err != nil {
+//			return err
+//		}
+//		defer r.Close()
+//
+//		return dbsqlexport.ToParquet(ctx, r.(dbsqlrows.Rows), file)
+//	})
+//
+// The results must be Arrow batches, which is the default, the export fails when Arrow batches are disabled
+// on the connector.
+package dbsqlexport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/parquet"
+	"github.com/apache/arrow/go/v12/parquet/compress"
+	"github.com/apache/arrow/go/v12/parquet/pqarrow"
+	dbsqlrows "github.com/databricks/databricks-sql-go/rows"
+	"github.com/pkg/errors"
+)
+
+// ToParquet writes the rows not read yet to w as a Parquet file compressed with Snappy, with a row group per
+// record batch. The schema of the file is the Arrow schema of the results. w is not closed.
+func ToParquet(ctx context.Context, rows dbsqlrows.Rows, w io.Writer) error {
+	batches, err := rows.GetArrowBatches(ctx)
+	if err != nil {
+		return err
+	}
+	defer batches.Close()
+
+	schema, err := batches.Schema()
+	if err != nil {
+		return err
+	}
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	// the parquet writer closes writers which are closers, hide Close from it
+	fw, err := pqarrow.NewFileWriter(schema, struct{ io.Writer }{w}, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return errors.Wrap(err, "databricks: failed to create parquet writer")
+	}
+
+	err = forEachBatch(ctx, batches, func(record arrow.Record) error {
+		return errors.Wrap(fw.Write(record), "databricks: failed to write parquet row group")
+	})
+	if err != nil {
+		fw.Close()
+		return err
+	}
+	return errors.Wrap(fw.Close(), "databricks: failed to write parquet footer")
+}
+
+// ToCSV writes the rows not read yet to w as CSV, with a header of the column names. NULL values are written as
+// empty fields, binary values are base64 encoded and ARRAY, MAP and STRUCT values are written as JSON.
+// Timestamps are written in UTC, as yyyy-mm-dd hh:mm:ss with the fractional seconds.
+func ToCSV(ctx context.Context, rows dbsqlrows.Rows, w io.Writer) error {
+	batches, err := rows.GetArrowBatches(ctx)
+	if err != nil {
+		return err
+	}
+	defer batches.Close()
+
+	schema, err := batches.Schema()
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	fields := schema.Fields()
+	line := make([]string, len(fields))
+	for i, field := range fields {
+		line[i] = field.Name
+	}
+	if err := cw.Write(line); err != nil {
+		return errors.Wrap(err, "databricks: failed to write csv")
+	}
+
+	err = forEachBatch(ctx, batches, func(record arrow.Record) error {
+		columns := record.Columns()
+		for row := 0; row < int(record.NumRows()); row++ {
+			for i, column := range columns {
+				s, err := formatValue(column, row)
+				if err != nil {
+					return err
+				}
+				line[i] = s
+			}
+			if err := cw.Write(line); err != nil {
+				return errors.Wrap(err, "databricks: failed to write csv")
+			}
+		}
+		// flush a batch at a time, the csv writer buffers 4KB only
+		cw.Flush()
+		return errors.Wrap(cw.Error(), "databricks: failed to write csv")
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return errors.Wrap(cw.Error(), "databricks: failed to write csv")
+}
+
+// forEachBatch calls write with each record batch and releases it
+func forEachBatch(ctx context.Context, batches dbsqlrows.ArrowBatchIterator, write func(arrow.Record) error) error {
+	for batches.HasNext() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, err := batches.Next()
+		if err != nil {
+			return err
+		}
+		err = write(record)
+		record.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatValue returns the CSV field of the value of column at row
+func formatValue(column arrow.Array, row int) (string, error) {
+	if column.IsNull(row) {
+		return "", nil
+	}
+	switch c := column.(type) {
+	case *array.String:
+		return c.Value(row), nil
+	case *array.Binary:
+		return base64.StdEncoding.EncodeToString(c.Value(row)), nil
+	case *array.Struct, *array.List, *array.Map:
+		b, err := json.Marshal(c.GetOneForMarshal(row))
+		return string(b), errors.Wrap(err, "databricks: failed to format complex value")
+	}
+	return column.ValueStr(row), nil
+}
@@ -0,0 +1,132 @@
+package dbsqlexport
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	"github.com/apache/arrow/go/v12/parquet/pqarrow"
+	dbsqlrows "github.com/databricks/databricks-sql-go/rows"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "created_at", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}},
+	{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+}, nil)
+
+// testRows hands out fixed record batches
+type testRows struct {
+	records []arrow.Record
+}
+
+func (r *testRows) Columns() []string              { return nil }
+func (r *testRows) Close() error                   { return nil }
+func (r *testRows) Next(dest []driver.Value) error { return io.EOF }
+
+func (r *testRows) GetArrowBatches(ctx context.Context) (dbsqlrows.ArrowBatchIterator, error) {
+	return &testBatches{records: r.records}, nil
+}
+
+type testBatches struct {
+	records []arrow.Record
+}
+
+func (b *testBatches) HasNext() bool { return len(b.records) > 0 }
+func (b *testBatches) Next() (arrow.Record, error) {
+	if len(b.records) == 0 {
+		return nil, io.EOF
+	}
+	record := b.records[0]
+	b.records = b.records[1:]
+	return record, nil
+}
+func (b *testBatches) Close()                         {}
+func (b *testBatches) Schema() (*arrow.Schema, error) { return testSchema, nil }
+
+// closeRecorder records whether the export closed the writer
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (w *closeRecorder) Close() error {
+	w.closed = true
+	return nil
+}
+
+func newTestRows(t *testing.T) *testRows {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	t.Cleanup(func() { mem.AssertSize(t, 0) })
+
+	b := array.NewRecordBuilder(mem, testSchema)
+	defer b.Release()
+	newRecord := func(id int64, name string, tags ...string) arrow.Record {
+		b.Field(0).(*array.Int64Builder).Append(id)
+		if name == "" {
+			b.Field(1).AppendNull()
+		} else {
+			b.Field(1).(*array.StringBuilder).Append(name)
+		}
+		b.Field(2).(*array.TimestampBuilder).Append(arrow.Timestamp(1675072800000000 + id))
+		if tags == nil {
+			b.Field(3).AppendNull()
+		} else {
+			lb := b.Field(3).(*array.ListBuilder)
+			lb.Append(true)
+			for _, tag := range tags {
+				lb.ValueBuilder().(*array.StringBuilder).Append(tag)
+			}
+		}
+		return b.NewRecord()
+	}
+	return &testRows{records: []arrow.Record{
+		newRecord(1, "north, east", "new", "gift"),
+		newRecord(2, ""),
+	}}
+}
+
+func TestToCSV(t *testing.T) {
+	var w closeRecorder
+	require.NoError(t, ToCSV(context.Background(), newTestRows(t), &w))
+	assert.Equal(t, "id,name,created_at,tags\n"+
+		"1,\"north, east\",2023-01-30 10:00:00.000001,\"[\"\"new\"\",\"\"gift\"\"]\"\n"+
+		"2,,2023-01-30 10:00:00.000002,\n", w.String())
+	assert.False(t, w.closed)
+}
+
+func TestToParquet(t *testing.T) {
+	var w closeRecorder
+	require.NoError(t, ToParquet(context.Background(), newTestRows(t), &w))
+	assert.False(t, w.closed)
+
+	table, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(w.Bytes()), nil, pqarrow.ArrowReadProperties{}, memory.NewGoAllocator())
+	require.NoError(t, err)
+	defer table.Release()
+	assert.Equal(t, int64(2), table.NumRows())
+	assert.Equal(t, []string{"id", "name", "created_at", "tags"}, []string{
+		table.Schema().Field(0).Name, table.Schema().Field(1).Name, table.Schema().Field(2).Name, table.Schema().Field(3).Name,
+	})
+
+	names := table.Column(1).Data().Chunk(0).(*array.String)
+	assert.Equal(t, "north, east", names.Value(0))
+	assert.True(t, names.IsNull(1))
+}
+
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rows := newTestRows(t)
+	assert.ErrorIs(t, ToCSV(ctx, rows, io.Discard), context.Canceled)
+	for _, record := range rows.records {
+		record.Release()
+	}
+}
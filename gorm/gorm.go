@@ -0,0 +1,246 @@
+// Package dbsqlgorm is a GORM dialector for Databricks SQL warehouses, built on the databricks driver:
+//
+//	db, err := gorm.Open(dbsqlgorm.Open("token:<token>@<hostname>:443/<http_path>"), &gorm.Config{})
+//
+//	var orders []Order
+//	err = db.Where("region = ?", region).Order("created_at desc").Limit(100).Offset(200).Find(&orders).Error
+//
+// Use New to run GORM on a database handle opened with a connector and its options:
+//
+//	connector, err := dbsql.NewConnector(dbsql.WithServerHostname(<hostname>), ...)
+//	db, err := gorm.Open(dbsqlgorm.New(dbsqlgorm.Config{Conn: sql.OpenDB(connector)}), &gorm.Config{})
+//
+// Warehouses have no transactions, so GORM doesn't wrap creates, updates and deletes in transactions, and
+// db.Transaction fails. Tables are created as Delta tables: auto increment fields are identity columns, whose
+// generated values are not returned by inserts, and indexes and unique constraints, which Delta tables don't
+// have, are skipped by the migrator. Primary and foreign keys are informational constraints of Unity Catalog,
+// they are not enforced. The migrator looks up tables and columns in information_schema, which needs Unity Catalog.
+//
+// With TranslateError enabled in the GORM config, errors of the integrity constraint violation class of SQLSTATE
+// are translated to gorm.ErrDuplicatedKey and gorm.ErrForeignKeyViolated, and the failure to begin a
+// transaction to gorm.ErrNotImplemented.
+package dbsqlgorm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// Config is the database the dialector connects to
+type Config struct {
+	DSN  string        // DSN of the databricks driver, used when Conn is nil
+	Conn gorm.ConnPool // database handle, e.g. a *sql.DB opened with a connector
+}
+
+// Dialector is the GORM dialector of Databricks SQL
+type Dialector struct {
+	*Config
+}
+
+var _ gorm.Dialector = Dialector{}
+var _ gorm.ErrorTranslator = Dialector{}
+
+// Open returns a dialector connecting to the warehouse of dsn, in the format of the databricks driver
+func Open(dsn string) gorm.Dialector {
+	return &Dialector{Config: &Config{DSN: dsn}}
+}
+
+// New returns a dialector connecting with config
+func New(config Config) gorm.Dialector {
+	return &Dialector{Config: &config}
+}
+
+func (d Dialector) Name() string {
+	return "databricks"
+}
+
+func (d Dialector) Initialize(db *gorm.DB) error {
+	// warehouses have no transactions
+	db.SkipDefaultTransaction = true
+
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{
+		// rows are not locked, there is no FOR clause
+		QueryClauses: []string{"SELECT", "FROM", "WHERE", "GROUP BY", "ORDER BY", "LIMIT"},
+	})
+
+	if d.Conn != nil {
+		db.ConnPool = d.Conn
+		return nil
+	}
+	conn, err := sql.Open("databricks", d.DSN)
+	if err != nil {
+		return err
+	}
+	db.ConnPool = conn
+	return nil
+}
+
+func (d Dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return Migrator{migrator.Migrator{Config: migrator.Config{
+		DB:        db,
+		Dialector: d,
+		// indexes are created, as no-ops, after the table instead of in CREATE TABLE
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
+
+// DataTypeOf returns the Databricks SQL type of a field
+func (d Dialector) DataTypeOf(field *schema.Field) string {
+	switch field.DataType {
+	case schema.Bool:
+		return "BOOLEAN"
+	case schema.Int, schema.Uint:
+		if field.AutoIncrement {
+			return "BIGINT GENERATED BY DEFAULT AS IDENTITY"
+		}
+		size := field.Size
+		if field.DataType == schema.Uint {
+			// unsigned values need the next larger type
+			size *= 2
+		}
+		switch {
+		case size <= 8:
+			return "TINYINT"
+		case size <= 16:
+			return "SMALLINT"
+		case size <= 32:
+			return "INT"
+		case size <= 64:
+			return "BIGINT"
+		default:
+			return "DECIMAL(20,0)"
+		}
+	case schema.Float:
+		if field.Precision > 0 {
+			return fmt.Sprintf("DECIMAL(%d,%d)", field.Precision, field.Scale)
+		}
+		if field.Size <= 32 {
+			return "FLOAT"
+		}
+		return "DOUBLE"
+	case schema.String:
+		return "STRING"
+	case schema.Time:
+		return "TIMESTAMP"
+	case schema.Bytes:
+		return "BINARY"
+	}
+	return string(field.DataType)
+}
+
+func (d Dialector) DefaultValueOf(field *schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (d Dialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte('?')
+}
+
+// QuoteTo quotes an identifier with backticks, each part of a qualified name like catalog.schema.table separately
+func (d Dialector) QuoteTo(writer clause.Writer, str string) {
+	writer.WriteByte('`')
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '.':
+			writer.WriteString("`.`")
+		case '`':
+			writer.WriteString("``")
+		default:
+			writer.WriteByte(str[i])
+		}
+	}
+	writer.WriteByte('`')
+}
+
+func (d Dialector) Explain(sql string, vars ...interface{}) string {
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
+}
+
+// Translate returns the GORM error of the errors of the driver which have one, otherwise err
+func (d Dialector) Translate(err error) error {
+	var execErr *dbsqlerr.ExecutionError
+	if errors.As(err, &execErr) {
+		switch execErr.SQLState {
+		case "23505":
+			return gorm.ErrDuplicatedKey
+		case "23503":
+			return gorm.ErrForeignKeyViolated
+		}
+		return err
+	}
+	var driverErr *dbsqlerr.DriverError
+	if errors.As(err, &driverErr) && driverErr.Msg == dbsql.ErrTransactionsNotSupported {
+		return gorm.ErrNotImplemented
+	}
+	return err
+}
+
+// Migrator creates and changes Delta tables
+type Migrator struct {
+	migrator.Migrator
+}
+
+// FullDataTypeOf returns the type of the column of a field with its constraints. The columns of primary keys
+// are NOT NULL, UNIQUE is skipped.
+func (m Migrator) FullDataTypeOf(field *schema.Field) (expr clause.Expr) {
+	expr.SQL = m.DataTypeOf(field)
+	if field.NotNull || field.PrimaryKey {
+		expr.SQL += " NOT NULL"
+	}
+	if field.HasDefaultValue && (field.DefaultValueInterface != nil || field.DefaultValue != "") {
+		if field.DefaultValueInterface != nil {
+			defaultStmt := &gorm.Statement{Vars: []interface{}{field.DefaultValueInterface}}
+			m.Dialector.BindVarTo(defaultStmt, defaultStmt, field.DefaultValueInterface)
+			expr.SQL += " DEFAULT " + m.Dialector.Explain(defaultStmt.SQL.String(), field.DefaultValueInterface)
+		} else if field.DefaultValue != "(-)" {
+			expr.SQL += " DEFAULT " + field.DefaultValue
+		}
+	}
+	return expr
+}
+
+// CurrentDatabase returns the current schema
+func (m Migrator) CurrentDatabase() (name string) {
+	m.DB.Raw("SELECT current_schema()").Row().Scan(&name)
+	return name
+}
+
+// HasTable returns whether the table of value exists in the current schema, views excluded
+func (m Migrator) HasTable(value interface{}) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Raw("SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ? AND table_type <> ?",
+			m.CurrentDatabase(), stmt.Table, "VIEW").Row().Scan(&count)
+	})
+	return count > 0
+}
+
+// CreateIndex does nothing, Delta tables have no indexes
+func (m Migrator) CreateIndex(value interface{}, name string) error {
+	return nil
+}
+
+// DropIndex does nothing, Delta tables have no indexes
+func (m Migrator) DropIndex(value interface{}, name string) error {
+	return nil
+}
+
+// HasIndex returns false, Delta tables have no indexes
+func (m Migrator) HasIndex(value interface{}, name string) bool {
+	return false
+}
+
+// RenameIndex does nothing, Delta tables have no indexes
+func (m Migrator) RenameIndex(value interface{}, oldName, newName string) error {
+	return nil
+}
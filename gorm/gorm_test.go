@@ -0,0 +1,183 @@
+package dbsqlgorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testConn records the statements and answers the queries with the rows of rows
+type testConn struct {
+	statements []string
+	args       [][]driver.Value
+	rows       func(query string) *testRows
+}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *testConn) Close() error                              { return nil }
+func (c *testConn) Begin() (driver.Tx, error) {
+	return nil, dbsqlerr.NewDriverError(dbsql.ErrTransactionsNotSupported, nil)
+}
+
+func (c *testConn) record(query string, args []driver.NamedValue) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	c.statements = append(c.statements, query)
+	c.args = append(c.args, values)
+}
+
+func (c *testConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query, args)
+	return testResult{}, nil
+}
+
+// testResult is the result of the statements of the driver, which has no last insert id
+type testResult struct{}
+
+func (testResult) LastInsertId() (int64, error) { return 0, nil }
+func (testResult) RowsAffected() (int64, error) { return 1, nil }
+
+func (c *testConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query, args)
+	if c.rows != nil {
+		if rows := c.rows(query); rows != nil {
+			return rows, nil
+		}
+	}
+	return &testRows{}, nil
+}
+
+type testRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *testRows) Columns() []string { return r.columns }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+type testConnector struct {
+	conn *testConn
+}
+
+func (c testConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c testConnector) Driver() driver.Driver                        { return nil }
+
+func openTestDB(t *testing.T, conn *testConn) *gorm.DB {
+	sqlDB := sql.OpenDB(testConnector{conn})
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(New(Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard, TranslateError: true})
+	require.NoError(t, err)
+	return db
+}
+
+type Order struct {
+	ID        int64   `gorm:"primaryKey;autoIncrement"`
+	Region    string  `gorm:"index"`
+	Amount    float64 `gorm:"type:DECIMAL(10,2)"`
+	Quantity  int16
+	Units     uint32
+	Express   bool
+	Code      string `gorm:"unique"`
+	Label     []byte
+	CreatedAt time.Time
+}
+
+func TestDialector(t *testing.T) {
+	t.Run("queries", func(t *testing.T) {
+		conn := &testConn{rows: func(query string) *testRows {
+			return &testRows{columns: []string{"id", "region"}, values: [][]driver.Value{{int64(1), "emea"}, {int64(2), "emea"}}}
+		}}
+		db := openTestDB(t, conn)
+
+		var orders []Order
+		require.NoError(t, db.Where("region = ?", "emea").Order("id").Limit(10).Offset(20).Find(&orders).Error)
+		assert.Equal(t, []string{"SELECT * FROM `orders` WHERE region = ? ORDER BY id LIMIT 10 OFFSET 20"}, conn.statements)
+		assert.Equal(t, [][]driver.Value{{"emea"}}, conn.args)
+		require.Len(t, orders, 2)
+		assert.Equal(t, int64(2), orders[1].ID)
+		assert.Equal(t, "emea", orders[1].Region)
+
+		conn.statements = nil
+		require.NoError(t, db.Table("main.sales.orders").Offset(5).Find(&orders).Error)
+		assert.Equal(t, []string{"SELECT * FROM `main`.`sales`.`orders` OFFSET 5"}, conn.statements)
+	})
+
+	t.Run("writes run without transactions", func(t *testing.T) {
+		conn := &testConn{}
+		db := openTestDB(t, conn)
+
+		require.NoError(t, db.Omit("CreatedAt").Create(&Order{Region: "emea", Amount: 10.5, Code: "a"}).Error)
+		require.NoError(t, db.Model(&Order{}).Where("id = ?", 1).Update("region", "apac").Error)
+		require.NoError(t, db.Delete(&Order{}, 1).Error)
+		assert.Equal(t, []string{
+			"INSERT INTO `orders` (`region`,`amount`,`quantity`,`units`,`express`,`code`,`label`) VALUES (?,?,?,?,?,?,?)",
+			"UPDATE `orders` SET `region`=? WHERE id = ?",
+			"DELETE FROM `orders` WHERE `orders`.`id` = ?",
+		}, conn.statements)
+
+		err := db.Transaction(func(tx *gorm.DB) error { return nil })
+		assert.ErrorIs(t, err, gorm.ErrNotImplemented)
+	})
+
+	t.Run("migrations create delta tables", func(t *testing.T) {
+		conn := &testConn{rows: func(query string) *testRows {
+			switch {
+			case query == "SELECT current_schema()":
+				return &testRows{columns: []string{"current_schema()"}, values: [][]driver.Value{{"sales"}}}
+			case strings.HasPrefix(query, "SELECT count(*)"):
+				return &testRows{columns: []string{"count(1)"}, values: [][]driver.Value{{int64(0)}}}
+			}
+			return nil
+		}}
+		db := openTestDB(t, conn)
+
+		require.NoError(t, db.AutoMigrate(&Order{}))
+		assert.Equal(t, []string{
+			"SELECT current_schema()",
+			"SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ? AND table_type <> ?",
+			"CREATE TABLE `orders` (`id` BIGINT GENERATED BY DEFAULT AS IDENTITY NOT NULL,`region` STRING,`amount` DECIMAL(10,2)," +
+				"`quantity` SMALLINT,`units` BIGINT,`express` BOOLEAN,`code` STRING,`label` BINARY,`created_at` TIMESTAMP,PRIMARY KEY (`id`))",
+		}, conn.statements)
+		assert.Equal(t, []driver.Value{"sales", "orders", "VIEW"}, conn.args[1])
+	})
+}
+
+func TestTranslate(t *testing.T) {
+	d := Dialector{Config: &Config{}}
+	assert.Equal(t, gorm.ErrDuplicatedKey, d.Translate(&dbsqlerr.ExecutionError{SQLState: "23505"}))
+	assert.Equal(t, gorm.ErrForeignKeyViolated, d.Translate(&dbsqlerr.ExecutionError{SQLState: "23503"}))
+
+	notFound := &dbsqlerr.ExecutionError{SQLState: "42P01", ErrorClass: "TABLE_OR_VIEW_NOT_FOUND"}
+	assert.Same(t, notFound, d.Translate(notFound))
+	other := errors.New("connection refused")
+	assert.Same(t, other, d.Translate(other))
+}
+
+func TestQuoteTo(t *testing.T) {
+	var b strings.Builder
+	d := Dialector{Config: &Config{}}
+	d.QuoteTo(&b, "main.sales.order`s")
+	assert.Equal(t, "`main`.`sales`.`order``s`", b.String())
+}
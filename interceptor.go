@@ -0,0 +1,39 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/databricks/databricks-sql-go/internal/config"
+)
+
+// StatementHandler runs a statement until it finished, it returns the error of failed statements.
+// The rows of queries are read after the handler returned.
+type StatementHandler func(ctx context.Context, query string, args []driver.NamedValue) error
+
+// StatementInterceptor wraps the execution of statements, similar to a gRPC interceptor. It calls next to run the
+// statement, possibly with another context, query or arguments, or returns an error instead to reject it:
+//
+//	audit := func(ctx context.Context, query string, args []driver.NamedValue, next dbsql.StatementHandler) error {
+//		start := time.Now()
+//		err := next(ctx, query, args)
+//		log.Printf("query %q took %s: %v", query, time.Since(start), err)
+//		return err
+//	}
+//	connector, err := dbsql.NewConnector(..., dbsql.WithStatementInterceptor(audit))
+//
+// Each statement of a script is intercepted separately. Queries started with ExecuteAsync are intercepted
+// until they are submitted.
+type StatementInterceptor func(ctx context.Context, query string, args []driver.NamedValue, next StatementHandler) error
+
+// intercept runs a statement with run, wrapped by the interceptors of the connection
+func (c *conn) intercept(ctx context.Context, query string, args []driver.NamedValue, run config.StatementHandler) error {
+	handler := run
+	for i := len(c.cfg.StatementInterceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.cfg.StatementInterceptors[i], handler
+		handler = func(ctx context.Context, query string, args []driver.NamedValue) error {
+			return interceptor(ctx, query, args, next)
+		}
+	}
+	return handler(ctx, query, args)
+}
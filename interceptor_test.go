@@ -0,0 +1,89 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementInterceptor(t *testing.T) {
+	var statements []string
+	finished := cli_service.TOperationState_FINISHED_STATE
+	testClient := &client.TestClient{
+		FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+			statements = append(statements, req.Statement)
+			return &cli_service.TExecuteStatementResp{
+				Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+				OperationHandle: &cli_service.TOperationHandle{
+					OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, Secret: []byte("b")},
+				},
+				DirectResults: &cli_service.TSparkDirectResults{
+					OperationStatus: &cli_service.TGetOperationStatusResp{OperationState: &finished},
+					CloseOperation:  &cli_service.TCloseOperationResp{},
+				},
+			}, nil
+		},
+	}
+
+	newConn := func(interceptors ...StatementInterceptor) *conn {
+		cfg := config.WithDefaults()
+		for _, interceptor := range interceptors {
+			WithStatementInterceptor(interceptor)(cfg)
+		}
+		return &conn{session: getTestSession(), client: testClient, cfg: cfg}
+	}
+
+	t.Run("interceptors run in order around the statement", func(t *testing.T) {
+		statements = nil
+		var calls []string
+		trace := func(name string) StatementInterceptor {
+			return func(ctx context.Context, query string, args []driver.NamedValue, next StatementHandler) error {
+				calls = append(calls, name+" before")
+				err := next(ctx, query, args)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+
+		_, err := newConn(trace("first"), trace("second")).ExecContext(context.Background(), "insert into t values (1)", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first before", "second before", "second after", "first after"}, calls)
+		assert.Equal(t, []string{"insert into t values (1)"}, statements)
+	})
+
+	t.Run("interceptors can rewrite queries", func(t *testing.T) {
+		statements = nil
+		tenant := func(ctx context.Context, query string, args []driver.NamedValue, next StatementHandler) error {
+			return next(ctx, strings.ReplaceAll(query, "{tenant}", "tenant_1"), args)
+		}
+
+		rows, err := newConn(tenant).QueryContext(context.Background(), "select * from {tenant}.sales", nil)
+		require.NoError(t, err)
+		defer rows.Close()
+		assert.Equal(t, []string{"select * from tenant_1.sales"}, statements)
+	})
+
+	t.Run("interceptors can reject statements", func(t *testing.T) {
+		statements = nil
+		errReadOnly := errors.New("read only")
+		readOnly := func(ctx context.Context, query string, args []driver.NamedValue, next StatementHandler) error {
+			return errReadOnly
+		}
+
+		_, err := newConn(readOnly).ExecContext(context.Background(), "delete from t", nil)
+		assert.ErrorIs(t, err, errReadOnly)
+		assert.Empty(t, statements)
+
+		_, err = newConn(readOnly).ExecuteAsync(context.Background(), "delete from t")
+		assert.ErrorIs(t, err, errReadOnly)
+		assert.Empty(t, statements)
+	})
+}
@@ -40,6 +40,8 @@ const (
   TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V4 TProtocolVersion = 42244
   TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V5 TProtocolVersion = 42245
   TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V6 TProtocolVersion = 42246
+  TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V7 TProtocolVersion = 42247
+  TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8 TProtocolVersion = 42248
 )
 
 func (p TProtocolVersion) String() string {
@@ -62,6 +64,8 @@ func (p TProtocolVersion) String() string {
   case TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V4: return "SPARK_CLI_SERVICE_PROTOCOL_V4"
   case TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V5: return "SPARK_CLI_SERVICE_PROTOCOL_V5"
   case TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V6: return "SPARK_CLI_SERVICE_PROTOCOL_V6"
+  case TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V7: return "SPARK_CLI_SERVICE_PROTOCOL_V7"
+  case TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8: return "SPARK_CLI_SERVICE_PROTOCOL_V8"
   }
   return "<UNSET>"
 }
@@ -86,6 +90,8 @@ func TProtocolVersionFromString(s string) (TProtocolVersion, error) {
   case "SPARK_CLI_SERVICE_PROTOCOL_V4": return TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V4, nil 
   case "SPARK_CLI_SERVICE_PROTOCOL_V5": return TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V5, nil 
   case "SPARK_CLI_SERVICE_PROTOCOL_V6": return TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V6, nil 
+  case "SPARK_CLI_SERVICE_PROTOCOL_V7": return TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V7, nil 
+  case "SPARK_CLI_SERVICE_PROTOCOL_V8": return TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8, nil 
   }
   return TProtocolVersion(0), fmt.Errorf("not a valid TProtocolVersion string")
 }
@@ -12467,6 +12473,486 @@ func (p *TSparkArrowTypes) String() string {
   return fmt.Sprintf("TSparkArrowTypes(%+v)", *p)
 }
 
+// Attributes:
+//  - StringValue
+//  - DoubleValue
+//  - BooleanValue
+type TSparkParameterValue struct {
+  StringValue *string `thrift:"stringValue,1" db:"stringValue" json:"stringValue,omitempty"`
+  DoubleValue *float64 `thrift:"doubleValue,2" db:"doubleValue" json:"doubleValue,omitempty"`
+  BooleanValue *bool `thrift:"booleanValue,3" db:"booleanValue" json:"booleanValue,omitempty"`
+}
+
+func NewTSparkParameterValue() *TSparkParameterValue {
+  return &TSparkParameterValue{}
+}
+
+var TSparkParameterValue_StringValue_DEFAULT string
+func (p *TSparkParameterValue) GetStringValue() string {
+  if !p.IsSetStringValue() {
+    return TSparkParameterValue_StringValue_DEFAULT
+  }
+return *p.StringValue
+}
+var TSparkParameterValue_DoubleValue_DEFAULT float64
+func (p *TSparkParameterValue) GetDoubleValue() float64 {
+  if !p.IsSetDoubleValue() {
+    return TSparkParameterValue_DoubleValue_DEFAULT
+  }
+return *p.DoubleValue
+}
+var TSparkParameterValue_BooleanValue_DEFAULT bool
+func (p *TSparkParameterValue) GetBooleanValue() bool {
+  if !p.IsSetBooleanValue() {
+    return TSparkParameterValue_BooleanValue_DEFAULT
+  }
+return *p.BooleanValue
+}
+func (p *TSparkParameterValue) IsSetStringValue() bool {
+  return p.StringValue != nil
+}
+
+func (p *TSparkParameterValue) IsSetDoubleValue() bool {
+  return p.DoubleValue != nil
+}
+
+func (p *TSparkParameterValue) IsSetBooleanValue() bool {
+  return p.BooleanValue != nil
+}
+
+func (p *TSparkParameterValue) Read(ctx context.Context, iprot thrift.TProtocol) error {
+  if _, err := iprot.ReadStructBegin(ctx); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T read error: ", p), err)
+  }
+
+
+  for {
+    _, fieldTypeId, fieldId, err := iprot.ReadFieldBegin(ctx)
+    if err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T field %d read error: ", p, fieldId), err)
+    }
+    if fieldTypeId == thrift.STOP { break; }
+    switch fieldId {
+    case 1:
+      if fieldTypeId == thrift.STRING {
+        if err := p.ReadField1(ctx, iprot); err != nil {
+          return err
+        }
+      } else {
+        if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+          return err
+        }
+      }
+    case 2:
+      if fieldTypeId == thrift.DOUBLE {
+        if err := p.ReadField2(ctx, iprot); err != nil {
+          return err
+        }
+      } else {
+        if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+          return err
+        }
+      }
+    case 3:
+      if fieldTypeId == thrift.BOOL {
+        if err := p.ReadField3(ctx, iprot); err != nil {
+          return err
+        }
+      } else {
+        if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+          return err
+        }
+      }
+    default:
+      if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+        return err
+      }
+    }
+    if err := iprot.ReadFieldEnd(ctx); err != nil {
+      return err
+    }
+  }
+  if err := iprot.ReadStructEnd(ctx); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T read struct end error: ", p), err)
+  }
+  return nil
+}
+
+func (p *TSparkParameterValue)  ReadField1(ctx context.Context, iprot thrift.TProtocol) error {
+  if v, err := iprot.ReadString(ctx); err != nil {
+  return thrift.PrependError("error reading field 1: ", err)
+} else {
+  p.StringValue = &v
+}
+  return nil
+}
+
+func (p *TSparkParameterValue)  ReadField2(ctx context.Context, iprot thrift.TProtocol) error {
+  if v, err := iprot.ReadDouble(ctx); err != nil {
+  return thrift.PrependError("error reading field 2: ", err)
+} else {
+  p.DoubleValue = &v
+}
+  return nil
+}
+
+func (p *TSparkParameterValue)  ReadField3(ctx context.Context, iprot thrift.TProtocol) error {
+  if v, err := iprot.ReadBool(ctx); err != nil {
+  return thrift.PrependError("error reading field 3: ", err)
+} else {
+  p.BooleanValue = &v
+}
+  return nil
+}
+
+func (p *TSparkParameterValue) Write(ctx context.Context, oprot thrift.TProtocol) error {
+  if err := oprot.WriteStructBegin(ctx, "TSparkParameterValue"); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T write struct begin error: ", p), err) }
+  if p != nil {
+    if err := p.writeField1(ctx, oprot); err != nil { return err }
+    if err := p.writeField2(ctx, oprot); err != nil { return err }
+    if err := p.writeField3(ctx, oprot); err != nil { return err }
+  }
+  if err := oprot.WriteFieldStop(ctx); err != nil {
+    return thrift.PrependError("write field stop error: ", err) }
+  if err := oprot.WriteStructEnd(ctx); err != nil {
+    return thrift.PrependError("write struct stop error: ", err) }
+  return nil
+}
+
+func (p *TSparkParameterValue) writeField1(ctx context.Context, oprot thrift.TProtocol) (err error) {
+  if p.IsSetStringValue() {
+    if err := oprot.WriteFieldBegin(ctx, "stringValue", thrift.STRING, 1); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field begin error 1:stringValue: ", p), err) }
+    if err := oprot.WriteString(ctx, string(*p.StringValue)); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T.stringValue (1) field write error: ", p), err) }
+    if err := oprot.WriteFieldEnd(ctx); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field end error 1:stringValue: ", p), err) }
+  }
+  return err
+}
+
+func (p *TSparkParameterValue) writeField2(ctx context.Context, oprot thrift.TProtocol) (err error) {
+  if p.IsSetDoubleValue() {
+    if err := oprot.WriteFieldBegin(ctx, "doubleValue", thrift.DOUBLE, 2); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field begin error 2:doubleValue: ", p), err) }
+    if err := oprot.WriteDouble(ctx, float64(*p.DoubleValue)); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T.doubleValue (2) field write error: ", p), err) }
+    if err := oprot.WriteFieldEnd(ctx); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field end error 2:doubleValue: ", p), err) }
+  }
+  return err
+}
+
+func (p *TSparkParameterValue) writeField3(ctx context.Context, oprot thrift.TProtocol) (err error) {
+  if p.IsSetBooleanValue() {
+    if err := oprot.WriteFieldBegin(ctx, "booleanValue", thrift.BOOL, 3); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field begin error 3:booleanValue: ", p), err) }
+    if err := oprot.WriteBool(ctx, bool(*p.BooleanValue)); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T.booleanValue (3) field write error: ", p), err) }
+    if err := oprot.WriteFieldEnd(ctx); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field end error 3:booleanValue: ", p), err) }
+  }
+  return err
+}
+
+func (p *TSparkParameterValue) Equals(other *TSparkParameterValue) bool {
+  if p == other {
+    return true
+  } else if p == nil || other == nil {
+    return false
+  }
+  if p.StringValue != other.StringValue {
+    if p.StringValue == nil || other.StringValue == nil {
+      return false
+    }
+    if (*p.StringValue) != (*other.StringValue) { return false }
+  }
+  if p.DoubleValue != other.DoubleValue {
+    if p.DoubleValue == nil || other.DoubleValue == nil {
+      return false
+    }
+    if (*p.DoubleValue) != (*other.DoubleValue) { return false }
+  }
+  if p.BooleanValue != other.BooleanValue {
+    if p.BooleanValue == nil || other.BooleanValue == nil {
+      return false
+    }
+    if (*p.BooleanValue) != (*other.BooleanValue) { return false }
+  }
+  return true
+}
+
+func (p *TSparkParameterValue) String() string {
+  if p == nil {
+    return "<nil>"
+  }
+  return fmt.Sprintf("TSparkParameterValue(%+v)", *p)
+}
+
+// Attributes:
+//  - Ordinal
+//  - Name
+//  - Type
+//  - Value
+type TSparkParameter struct {
+  Ordinal *int32 `thrift:"ordinal,1" db:"ordinal" json:"ordinal,omitempty"`
+  Name *string `thrift:"name,2" db:"name" json:"name,omitempty"`
+  Type *string `thrift:"type,3" db:"type" json:"type,omitempty"`
+  Value *TSparkParameterValue `thrift:"value,4" db:"value" json:"value,omitempty"`
+}
+
+func NewTSparkParameter() *TSparkParameter {
+  return &TSparkParameter{}
+}
+
+var TSparkParameter_Ordinal_DEFAULT int32
+func (p *TSparkParameter) GetOrdinal() int32 {
+  if !p.IsSetOrdinal() {
+    return TSparkParameter_Ordinal_DEFAULT
+  }
+return *p.Ordinal
+}
+var TSparkParameter_Name_DEFAULT string
+func (p *TSparkParameter) GetName() string {
+  if !p.IsSetName() {
+    return TSparkParameter_Name_DEFAULT
+  }
+return *p.Name
+}
+var TSparkParameter_Type_DEFAULT string
+func (p *TSparkParameter) GetType() string {
+  if !p.IsSetType() {
+    return TSparkParameter_Type_DEFAULT
+  }
+return *p.Type
+}
+var TSparkParameter_Value_DEFAULT *TSparkParameterValue
+func (p *TSparkParameter) GetValue() *TSparkParameterValue {
+  if !p.IsSetValue() {
+    return TSparkParameter_Value_DEFAULT
+  }
+return p.Value
+}
+func (p *TSparkParameter) IsSetOrdinal() bool {
+  return p.Ordinal != nil
+}
+
+func (p *TSparkParameter) IsSetName() bool {
+  return p.Name != nil
+}
+
+func (p *TSparkParameter) IsSetType() bool {
+  return p.Type != nil
+}
+
+func (p *TSparkParameter) IsSetValue() bool {
+  return p.Value != nil
+}
+
+func (p *TSparkParameter) Read(ctx context.Context, iprot thrift.TProtocol) error {
+  if _, err := iprot.ReadStructBegin(ctx); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T read error: ", p), err)
+  }
+
+
+  for {
+    _, fieldTypeId, fieldId, err := iprot.ReadFieldBegin(ctx)
+    if err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T field %d read error: ", p, fieldId), err)
+    }
+    if fieldTypeId == thrift.STOP { break; }
+    switch fieldId {
+    case 1:
+      if fieldTypeId == thrift.I32 {
+        if err := p.ReadField1(ctx, iprot); err != nil {
+          return err
+        }
+      } else {
+        if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+          return err
+        }
+      }
+    case 2:
+      if fieldTypeId == thrift.STRING {
+        if err := p.ReadField2(ctx, iprot); err != nil {
+          return err
+        }
+      } else {
+        if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+          return err
+        }
+      }
+    case 3:
+      if fieldTypeId == thrift.STRING {
+        if err := p.ReadField3(ctx, iprot); err != nil {
+          return err
+        }
+      } else {
+        if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+          return err
+        }
+      }
+    case 4:
+      if fieldTypeId == thrift.STRUCT {
+        if err := p.ReadField4(ctx, iprot); err != nil {
+          return err
+        }
+      } else {
+        if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+          return err
+        }
+      }
+    default:
+      if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+        return err
+      }
+    }
+    if err := iprot.ReadFieldEnd(ctx); err != nil {
+      return err
+    }
+  }
+  if err := iprot.ReadStructEnd(ctx); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T read struct end error: ", p), err)
+  }
+  return nil
+}
+
+func (p *TSparkParameter)  ReadField1(ctx context.Context, iprot thrift.TProtocol) error {
+  if v, err := iprot.ReadI32(ctx); err != nil {
+  return thrift.PrependError("error reading field 1: ", err)
+} else {
+  p.Ordinal = &v
+}
+  return nil
+}
+
+func (p *TSparkParameter)  ReadField2(ctx context.Context, iprot thrift.TProtocol) error {
+  if v, err := iprot.ReadString(ctx); err != nil {
+  return thrift.PrependError("error reading field 2: ", err)
+} else {
+  p.Name = &v
+}
+  return nil
+}
+
+func (p *TSparkParameter)  ReadField3(ctx context.Context, iprot thrift.TProtocol) error {
+  if v, err := iprot.ReadString(ctx); err != nil {
+  return thrift.PrependError("error reading field 3: ", err)
+} else {
+  p.Type = &v
+}
+  return nil
+}
+
+func (p *TSparkParameter)  ReadField4(ctx context.Context, iprot thrift.TProtocol) error {
+  p.Value = &TSparkParameterValue{}
+  if err := p.Value.Read(ctx, iprot); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T error reading struct: ", p.Value), err)
+  }
+  return nil
+}
+
+func (p *TSparkParameter) Write(ctx context.Context, oprot thrift.TProtocol) error {
+  if err := oprot.WriteStructBegin(ctx, "TSparkParameter"); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T write struct begin error: ", p), err) }
+  if p != nil {
+    if err := p.writeField1(ctx, oprot); err != nil { return err }
+    if err := p.writeField2(ctx, oprot); err != nil { return err }
+    if err := p.writeField3(ctx, oprot); err != nil { return err }
+    if err := p.writeField4(ctx, oprot); err != nil { return err }
+  }
+  if err := oprot.WriteFieldStop(ctx); err != nil {
+    return thrift.PrependError("write field stop error: ", err) }
+  if err := oprot.WriteStructEnd(ctx); err != nil {
+    return thrift.PrependError("write struct stop error: ", err) }
+  return nil
+}
+
+func (p *TSparkParameter) writeField1(ctx context.Context, oprot thrift.TProtocol) (err error) {
+  if p.IsSetOrdinal() {
+    if err := oprot.WriteFieldBegin(ctx, "ordinal", thrift.I32, 1); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field begin error 1:ordinal: ", p), err) }
+    if err := oprot.WriteI32(ctx, int32(*p.Ordinal)); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T.ordinal (1) field write error: ", p), err) }
+    if err := oprot.WriteFieldEnd(ctx); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field end error 1:ordinal: ", p), err) }
+  }
+  return err
+}
+
+func (p *TSparkParameter) writeField2(ctx context.Context, oprot thrift.TProtocol) (err error) {
+  if p.IsSetName() {
+    if err := oprot.WriteFieldBegin(ctx, "name", thrift.STRING, 2); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field begin error 2:name: ", p), err) }
+    if err := oprot.WriteString(ctx, string(*p.Name)); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T.name (2) field write error: ", p), err) }
+    if err := oprot.WriteFieldEnd(ctx); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field end error 2:name: ", p), err) }
+  }
+  return err
+}
+
+func (p *TSparkParameter) writeField3(ctx context.Context, oprot thrift.TProtocol) (err error) {
+  if p.IsSetType() {
+    if err := oprot.WriteFieldBegin(ctx, "type", thrift.STRING, 3); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field begin error 3:type: ", p), err) }
+    if err := oprot.WriteString(ctx, string(*p.Type)); err != nil {
+    return thrift.PrependError(fmt.Sprintf("%T.type (3) field write error: ", p), err) }
+    if err := oprot.WriteFieldEnd(ctx); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field end error 3:type: ", p), err) }
+  }
+  return err
+}
+
+func (p *TSparkParameter) writeField4(ctx context.Context, oprot thrift.TProtocol) (err error) {
+  if p.IsSetValue() {
+    if err := oprot.WriteFieldBegin(ctx, "value", thrift.STRUCT, 4); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field begin error 4:value: ", p), err) }
+    if err := p.Value.Write(ctx, oprot); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T error writing struct: ", p.Value), err)
+    }
+    if err := oprot.WriteFieldEnd(ctx); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field end error 4:value: ", p), err) }
+  }
+  return err
+}
+
+func (p *TSparkParameter) Equals(other *TSparkParameter) bool {
+  if p == other {
+    return true
+  } else if p == nil || other == nil {
+    return false
+  }
+  if p.Ordinal != other.Ordinal {
+    if p.Ordinal == nil || other.Ordinal == nil {
+      return false
+    }
+    if (*p.Ordinal) != (*other.Ordinal) { return false }
+  }
+  if p.Name != other.Name {
+    if p.Name == nil || other.Name == nil {
+      return false
+    }
+    if (*p.Name) != (*other.Name) { return false }
+  }
+  if p.Type != other.Type {
+    if p.Type == nil || other.Type == nil {
+      return false
+    }
+    if (*p.Type) != (*other.Type) { return false }
+  }
+  if !p.Value.Equals(other.Value) { return false }
+  return true
+}
+
+func (p *TSparkParameter) String() string {
+  if p == nil {
+    return "<nil>"
+  }
+  return fmt.Sprintf("TSparkParameter(%+v)", *p)
+}
+
 // Attributes:
 //  - SessionHandle
 //  - Statement
@@ -12479,6 +12965,7 @@ func (p *TSparkArrowTypes) String() string {
 //  - CanDecompressLZ4Result_
 //  - MaxBytesPerFile
 //  - UseArrowNativeTypes
+//  - Parameters
 //  - OperationId
 //  - SessionConf
 //  - RejectHighCostQueries
@@ -12497,7 +12984,9 @@ type TExecuteStatementReq struct {
   CanDecompressLZ4Result_ *bool `thrift:"canDecompressLZ4Result,1284" db:"canDecompressLZ4Result" json:"canDecompressLZ4Result,omitempty"`
   MaxBytesPerFile *int64                   `thrift:"maxBytesPerFile,1285" db:"maxBytesPerFile" json:"maxBytesPerFile,omitempty"`
   UseArrowNativeTypes *TSparkArrowTypes    `thrift:"useArrowNativeTypes,1286" db:"useArrowNativeTypes" json:"useArrowNativeTypes,omitempty"`
-  // unused fields # 1287 to 3328
+  // unused field # 1287
+  Parameters []*TSparkParameter `thrift:"parameters,1288" db:"parameters" json:"parameters,omitempty"`
+  // unused fields # 1289 to 3328
   OperationId *THandleIdentifier `thrift:"operationId,3329" db:"operationId" json:"operationId,omitempty"`
   SessionConf *TDBSqlSessionConf `thrift:"sessionConf,3330" db:"sessionConf" json:"sessionConf,omitempty"`
   RejectHighCostQueries *bool    `thrift:"rejectHighCostQueries,3331" db:"rejectHighCostQueries" json:"rejectHighCostQueries,omitempty"`
@@ -12577,6 +13066,11 @@ func (p *TExecuteStatementReq) GetUseArrowNativeTypes() *TSparkArrowTypes {
   }
 return p.UseArrowNativeTypes
 }
+var TExecuteStatementReq_Parameters_DEFAULT []*TSparkParameter
+
+func (p *TExecuteStatementReq) GetParameters() []*TSparkParameter {
+  return p.Parameters
+}
 var TExecuteStatementReq_OperationId_DEFAULT *THandleIdentifier
 func (p *TExecuteStatementReq) GetOperationId() *THandleIdentifier {
   if !p.IsSetOperationId() {
@@ -12652,6 +13146,10 @@ func (p *TExecuteStatementReq) IsSetUseArrowNativeTypes() bool {
   return p.UseArrowNativeTypes != nil
 }
 
+func (p *TExecuteStatementReq) IsSetParameters() bool {
+  return p.Parameters != nil
+}
+
 func (p *TExecuteStatementReq) IsSetOperationId() bool {
   return p.OperationId != nil
 }
@@ -12799,6 +13297,16 @@ func (p *TExecuteStatementReq) Read(ctx context.Context, iprot thrift.TProtocol)
           return err
         }
       }
+    case 1288:
+      if fieldTypeId == thrift.LIST {
+        if err := p.ReadField1288(ctx, iprot); err != nil {
+          return err
+        }
+      } else {
+        if err := iprot.Skip(ctx, fieldTypeId); err != nil {
+          return err
+        }
+      }
     case 3329:
       if fieldTypeId == thrift.STRUCT {
         if err := p.ReadField3329(ctx, iprot); err != nil {
@@ -12985,6 +13493,26 @@ func (p *TExecuteStatementReq)  ReadField1286(ctx context.Context, iprot thrift.
   return nil
 }
 
+func (p *TExecuteStatementReq)  ReadField1288(ctx context.Context, iprot thrift.TProtocol) error {
+  _, size, err := iprot.ReadListBegin(ctx)
+  if err != nil {
+    return thrift.PrependError("error reading list begin: ", err)
+  }
+  tSlice := make([]*TSparkParameter, 0, size)
+  p.Parameters =  tSlice
+  for i := 0; i < size; i ++ {
+    _elem := &TSparkParameter{}
+    if err := _elem.Read(ctx, iprot); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T error reading struct: ", _elem), err)
+    }
+    p.Parameters = append(p.Parameters, _elem)
+  }
+  if err := iprot.ReadListEnd(ctx); err != nil {
+    return thrift.PrependError("error reading list end: ", err)
+  }
+  return nil
+}
+
 func (p *TExecuteStatementReq)  ReadField3329(ctx context.Context, iprot thrift.TProtocol) error {
   p.OperationId = &THandleIdentifier{}
   if err := p.OperationId.Read(ctx, iprot); err != nil {
@@ -13043,6 +13571,7 @@ func (p *TExecuteStatementReq) Write(ctx context.Context, oprot thrift.TProtocol
     if err := p.writeField1284(ctx, oprot); err != nil { return err }
     if err := p.writeField1285(ctx, oprot); err != nil { return err }
     if err := p.writeField1286(ctx, oprot); err != nil { return err }
+    if err := p.writeField1288(ctx, oprot); err != nil { return err }
     if err := p.writeField3329(ctx, oprot); err != nil { return err }
     if err := p.writeField3330(ctx, oprot); err != nil { return err }
     if err := p.writeField3331(ctx, oprot); err != nil { return err }
@@ -13197,6 +13726,27 @@ func (p *TExecuteStatementReq) writeField1286(ctx context.Context, oprot thrift.
   return err
 }
 
+func (p *TExecuteStatementReq) writeField1288(ctx context.Context, oprot thrift.TProtocol) (err error) {
+  if p.IsSetParameters() {
+    if err := oprot.WriteFieldBegin(ctx, "parameters", thrift.LIST, 1288); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field begin error 1288:parameters: ", p), err) }
+    if err := oprot.WriteListBegin(ctx, thrift.STRUCT, len(p.Parameters)); err != nil {
+      return thrift.PrependError("error writing list begin: ", err)
+    }
+    for _, v := range p.Parameters {
+      if err := v.Write(ctx, oprot); err != nil {
+        return thrift.PrependError(fmt.Sprintf("%T error writing struct: ", v), err)
+      }
+    }
+    if err := oprot.WriteListEnd(ctx); err != nil {
+      return thrift.PrependError("error writing list end: ", err)
+    }
+    if err := oprot.WriteFieldEnd(ctx); err != nil {
+      return thrift.PrependError(fmt.Sprintf("%T write field end error 1288:parameters: ", p), err) }
+  }
+  return err
+}
+
 func (p *TExecuteStatementReq) writeField3329(ctx context.Context, oprot thrift.TProtocol) (err error) {
   if p.IsSetOperationId() {
     if err := oprot.WriteFieldBegin(ctx, "operationId", thrift.STRUCT, 3329); err != nil {
@@ -13300,6 +13850,11 @@ func (p *TExecuteStatementReq) Equals(other *TExecuteStatementReq) bool {
     if (*p.MaxBytesPerFile) != (*other.MaxBytesPerFile) { return false }
   }
   if !p.UseArrowNativeTypes.Equals(other.UseArrowNativeTypes) { return false }
+  if len(p.Parameters) != len(other.Parameters) { return false }
+  for i, _tgt := range p.Parameters {
+    _src := other.Parameters[i]
+    if !_tgt.Equals(_src) { return false }
+  }
   if !p.OperationId.Equals(other.OperationId) { return false }
   if !p.SessionConf.Equals(other.SessionConf) { return false }
   if p.RejectHighCostQueries != other.RejectHighCostQueries {
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/databricks/databricks-sql-go/logger"
+)
+
+// circuitBreakers are the circuit breakers by warehouse and settings, shared by the connectors of a warehouse
+var circuitBreakers = struct {
+	sync.Mutex
+	m map[string]*circuitBreaker
+}{m: map[string]*circuitBreaker{}}
+
+// circuitBreaker fails the requests to a warehouse fast after threshold consecutive requests failed. When it is
+// open, a single probe request is let through after the cooldown, or when a connection is pinged, and the breaker
+// closes when the probe succeeds or opens again for another cooldown when it fails.
+type circuitBreaker struct {
+	host      string
+	httpPath  string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int       // consecutive failed requests
+	openedAt time.Time // zero when the breaker is closed
+	probing  bool      // a probe request is in flight
+}
+
+// getCircuitBreaker returns the circuit breaker of the warehouse of cfg, nil when it is disabled
+func getCircuitBreaker(cfg *config.Config) *circuitBreaker {
+	if cfg.CircuitBreakerThreshold <= 0 {
+		return nil
+	}
+	key := fmt.Sprintf("%s:%d%s %d %s", cfg.Host, cfg.Port, cfg.HTTPPath, cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown)
+
+	circuitBreakers.Lock()
+	defer circuitBreakers.Unlock()
+	b, ok := circuitBreakers.m[key]
+	if !ok {
+		b = &circuitBreaker{
+			host:      cfg.Host,
+			httpPath:  cfg.HTTPPath,
+			threshold: cfg.CircuitBreakerThreshold,
+			cooldown:  cfg.CircuitBreakerCooldown,
+		}
+		circuitBreakers.m[key] = b
+	}
+	return b
+}
+
+// allow returns whether a request is a probe of the open breaker, or a CircuitOpenError when the request
+// can't be sent. ping is true for the requests of a ping, which probe the breaker before the end of the cooldown.
+func (b *circuitBreaker) allow(ping bool) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return false, nil
+	}
+	wait := b.cooldown - time.Since(b.openedAt)
+	if !b.probing && (ping || wait <= 0) {
+		b.probing = true
+		return true, nil
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return false, &dbsqlerr.CircuitOpenError{Host: b.host, HTTPPath: b.httpPath, Failures: b.failures, RetryAfter: wait}
+}
+
+// done records the outcome of a request, canceled is true when its context was done before it completed
+func (b *circuitBreaker) done(probe, failed, canceled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if canceled {
+		return
+	}
+	if !failed {
+		if !b.openedAt.IsZero() {
+			logger.Info().Msgf("databricks: circuit breaker closed for %s%s", b.host, b.httpPath)
+		}
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+	b.failures++
+	if probe || (b.openedAt.IsZero() && b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		logger.Warn().Msgf("databricks: circuit breaker opened for %s%s after %d failed requests", b.host, b.httpPath, b.failures)
+	}
+}
+
+// circuitBreakerTransport sends the requests allowed by its breaker and records their outcome
+type circuitBreakerTransport struct {
+	Base    http.RoundTripper
+	Breaker *circuitBreaker
+}
+
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ping, _ := req.Context().Value(pingKey{}).(bool)
+	probe, err := t.Breaker.allow(ping)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.Base.RoundTrip(req)
+	// a warehouse which is starting (503 with TEMPORARILY_UNAVAILABLE) or rate limiting the requests (429) is
+	// not failing, any other 5xx including a plain 503 is a failure
+	failed := err != nil || (resp.StatusCode >= 500 && !isWarehouseStarting(resp))
+	t.Breaker.done(probe, failed, err != nil && req.Context().Err() != nil)
+	return resp, err
+}
+
+type pingKey struct{}
+
+// NewContextWithPing returns a context whose requests probe an open circuit breaker before the end of its cooldown
+func NewContextWithPing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pingKey{}, true)
+}
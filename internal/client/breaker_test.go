@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth/pat"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("the breaker opens after consecutive failures and lets a probe through after the cooldown", func(t *testing.T) {
+		b := &circuitBreaker{host: "host", httpPath: "/sql/1.0/warehouses/abc", threshold: 2, cooldown: time.Hour}
+		b.done(false, true, false)
+		b.done(false, false, false)
+		b.done(false, true, false)
+		_, err := b.allow(false)
+		assert.NoError(t, err, "the failures are not consecutive")
+
+		b.done(false, true, false)
+		_, err = b.allow(false)
+		var openErr *dbsqlerr.CircuitOpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, 2, openErr.Failures)
+		assert.InDelta(t, time.Hour, openErr.RetryAfter, float64(time.Minute))
+
+		b.openedAt = time.Now().Add(-2 * time.Hour)
+		probe, err := b.allow(false)
+		assert.NoError(t, err)
+		assert.True(t, probe)
+		_, err = b.allow(false)
+		assert.Error(t, err, "a single probe is in flight")
+
+		b.done(true, true, false)
+		_, err = b.allow(false)
+		assert.Error(t, err, "the failed probe opens the breaker again")
+
+		probe, err = b.allow(true)
+		assert.NoError(t, err, "a ping probes the breaker before the end of the cooldown")
+		assert.True(t, probe)
+		b.done(true, false, false)
+		probe, err = b.allow(false)
+		assert.NoError(t, err)
+		assert.False(t, probe, "the breaker closed")
+	})
+
+	t.Run("a canceled probe lets another probe through", func(t *testing.T) {
+		b := &circuitBreaker{threshold: 1, cooldown: time.Hour}
+		b.done(false, true, false)
+		probe, err := b.allow(true)
+		require.NoError(t, err)
+		b.done(probe, true, true)
+		_, err = b.allow(true)
+		assert.NoError(t, err)
+	})
+
+	t.Run("a plain 503 is a failure but a starting or rate limiting warehouse is not", func(t *testing.T) {
+		var requests int
+		var header http.Header
+		status := http.StatusServiceUnavailable
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		b := &circuitBreaker{threshold: 2, cooldown: time.Hour}
+		httpClient := &http.Client{Transport: &circuitBreakerTransport{Base: http.DefaultTransport, Breaker: b}}
+		get := func() error {
+			resp, err := httpClient.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			return err
+		}
+
+		header = http.Header{"X-Databricks-Reason-Phrase": {"TEMPORARILY_UNAVAILABLE"}}
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, get())
+		}
+		status, header = http.StatusTooManyRequests, nil
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, get())
+		}
+		assert.Equal(t, 6, requests)
+
+		status = http.StatusServiceUnavailable
+		assert.NoError(t, get())
+		assert.NoError(t, get())
+		var openErr *dbsqlerr.CircuitOpenError
+		assert.ErrorAs(t, get(), &openErr)
+		assert.Equal(t, 8, requests)
+	})
+
+	t.Run("requests fail fast without retries while the breaker is open", func(t *testing.T) {
+		var requests int
+		status := http.StatusInternalServerError
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+		serverURL, _ := url.Parse(server.URL)
+
+		cfg := config.WithDefaults()
+		cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+		cfg.Host = serverURL.Hostname()
+		cfg.Port, _ = strconv.Atoi(serverURL.Port())
+		cfg.HTTPPath = "/sql/1.0/warehouses/abc"
+		cfg.RetryMax = 4
+		cfg.RetryWaitMin = time.Millisecond
+		cfg.RetryWaitMax = time.Millisecond
+		cfg.CircuitBreakerThreshold = 2
+		cfg.CircuitBreakerCooldown = time.Hour
+		httpClient := RetryableClient(cfg)
+		get := func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			resp, err := httpClient.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			return err
+		}
+
+		err := get(context.Background())
+		var openErr *dbsqlerr.CircuitOpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, 2, requests, "the retries stop when the breaker opens")
+		assert.Equal(t, cfg.Host, openErr.Host)
+		assert.True(t, dbsqlerr.IsRetryable(err))
+
+		err = get(context.Background())
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, 2, requests)
+
+		status = http.StatusOK
+		assert.NoError(t, get(NewContextWithPing(context.Background())))
+		assert.Equal(t, 3, requests)
+		assert.NoError(t, get(context.Background()))
+		assert.Equal(t, 4, requests)
+
+		assert.Same(t, getCircuitBreaker(cfg), getCircuitBreaker(cfg.DeepCopy()), "the connectors of a warehouse share the breaker")
+	})
+}
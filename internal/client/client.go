@@ -1,24 +1,33 @@
 package client
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"net"
 	"net/http"
 	"net/http/httptrace"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/databricks/databricks-sql-go/auth"
 	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
 	"github.com/databricks/databricks-sql-go/internal/cli_service"
 	"github.com/databricks/databricks-sql-go/internal/config"
 	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/databricks/databricks-sql-go/metrics"
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
 )
 
 // RecordResults is used to generate test data. Developer should change this manually
@@ -35,7 +44,7 @@ func (tsc *ThriftServiceClient) OpenSession(ctx context.Context, req *cli_servic
 	msg, start := logger.Track("OpenSession")
 	resp, err := tsc.TCLIServiceClient.OpenSession(ctx, req)
 	if err != nil {
-		return nil, errors.Wrap(err, "open session request error")
+		return nil, newRequestError(ctx, "open session request error", "", err)
 	}
 	log := logger.WithContext(SprintGuid(resp.SessionHandle.SessionId.GUID), driverctx.CorrelationIdFromContext(ctx), "")
 	defer log.Duration(msg, start)
@@ -44,7 +53,7 @@ func (tsc *ThriftServiceClient) OpenSession(ctx context.Context, req *cli_servic
 		_ = os.WriteFile(fmt.Sprintf("OpenSession%d.json", resultIndex), j, 0600)
 		resultIndex++
 	}
-	return resp, CheckStatus(resp)
+	return resp, requestErrorContext(ctx, "", CheckStatus(resp))
 }
 
 // CloseSession is a wrapper around the thrift operation CloseSession
@@ -54,57 +63,76 @@ func (tsc *ThriftServiceClient) CloseSession(ctx context.Context, req *cli_servi
 	defer log.Duration(logger.Track("CloseSession"))
 	resp, err := tsc.TCLIServiceClient.CloseSession(ctx, req)
 	if err != nil {
-		return resp, errors.Wrap(err, "close session request error")
+		return resp, newRequestError(ctx, "close session request error", "", err)
 	}
 	if RecordResults {
 		j, _ := json.MarshalIndent(resp, "", " ")
 		_ = os.WriteFile(fmt.Sprintf("CloseSession%d.json", resultIndex), j, 0600)
 		resultIndex++
 	}
-	return resp, CheckStatus(resp)
+	return resp, requestErrorContext(ctx, "", CheckStatus(resp))
+}
+
+// GetInfo is a wrapper around the thrift operation GetInfo
+// If RecordResults is true, the results will be marshalled to JSON format and written to GetInfo<index>.json
+func (tsc *ThriftServiceClient) GetInfo(ctx context.Context, req *cli_service.TGetInfoReq) (*cli_service.TGetInfoResp, error) {
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), "")
+	defer log.Duration(logger.Track("GetInfo"))
+	resp, err := tsc.TCLIServiceClient.GetInfo(ctx, req)
+	if err != nil {
+		return resp, newRequestError(ctx, "get info request error", "", err)
+	}
+	if RecordResults {
+		j, _ := json.MarshalIndent(resp, "", " ")
+		_ = os.WriteFile(fmt.Sprintf("GetInfo%d.json", resultIndex), j, 0600)
+		resultIndex++
+	}
+	return resp, requestErrorContext(ctx, "", CheckStatus(resp))
 }
 
 // FetchResults is a wrapper around the thrift operation FetchResults
 // If RecordResults is true, the results will be marshalled to JSON format and written to FetchResults<index>.json
 func (tsc *ThriftServiceClient) FetchResults(ctx context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
-	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), SprintGuid(req.OperationHandle.OperationId.GUID))
+	queryId := SprintGuid(req.OperationHandle.OperationId.GUID)
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), queryId)
 	defer log.Duration(logger.Track("FetchResults"))
 	resp, err := tsc.TCLIServiceClient.FetchResults(ctx, req)
 	if err != nil {
-		return resp, errors.Wrap(err, "fetch results request error")
+		return resp, newRequestError(ctx, "fetch results request error", queryId, err)
 	}
 	if RecordResults {
 		j, _ := json.MarshalIndent(resp, "", " ")
 		_ = os.WriteFile(fmt.Sprintf("FetchResults%d.json", resultIndex), j, 0600)
 		resultIndex++
 	}
-	return resp, CheckStatus(resp)
+	return resp, requestErrorContext(ctx, queryId, CheckStatus(resp))
 }
 
 // GetResultSetMetadata is a wrapper around the thrift operation GetResultSetMetadata
 // If RecordResults is true, the results will be marshalled to JSON format and written to GetResultSetMetadata<index>.json
 func (tsc *ThriftServiceClient) GetResultSetMetadata(ctx context.Context, req *cli_service.TGetResultSetMetadataReq) (*cli_service.TGetResultSetMetadataResp, error) {
-	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), SprintGuid(req.OperationHandle.OperationId.GUID))
+	queryId := SprintGuid(req.OperationHandle.OperationId.GUID)
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), queryId)
 	defer log.Duration(logger.Track("GetResultSetMetadata"))
 	resp, err := tsc.TCLIServiceClient.GetResultSetMetadata(ctx, req)
 	if err != nil {
-		return resp, errors.Wrap(err, "get result set metadata request error")
+		return resp, newRequestError(ctx, "get result set metadata request error", queryId, err)
 	}
 	if RecordResults {
 		j, _ := json.MarshalIndent(resp, "", " ")
 		_ = os.WriteFile(fmt.Sprintf("ExecuteStatement%d.json", resultIndex), j, 0600)
 		resultIndex++
 	}
-	return resp, CheckStatus(resp)
+	return resp, requestErrorContext(ctx, queryId, CheckStatus(resp))
 }
 
 // ExecuteStatement is a wrapper around the thrift operation ExecuteStatement
 // If RecordResults is true, the results will be marshalled to JSON format and written to ExecuteStatement<index>.json
 func (tsc *ThriftServiceClient) ExecuteStatement(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
 	msg, start := logger.Track("ExecuteStatement")
-	resp, err := tsc.TCLIServiceClient.ExecuteStatement(context.Background(), req)
+	resp, err := tsc.TCLIServiceClient.ExecuteStatement(ctx, req)
 	if err != nil {
-		return resp, errors.Wrap(err, "execute statement request error")
+		return resp, newRequestError(ctx, "execute statement request error", "", err)
 	}
 	if RecordResults {
 		j, _ := json.MarshalIndent(resp, "", " ")
@@ -114,62 +142,204 @@ func (tsc *ThriftServiceClient) ExecuteStatement(ctx context.Context, req *cli_s
 		// json.Unmarshal(f, &resp2)
 		resultIndex++
 	}
+	var queryId string
 	if resp != nil && resp.OperationHandle != nil {
-		log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), SprintGuid(resp.OperationHandle.OperationId.GUID))
+		queryId = SprintGuid(resp.OperationHandle.OperationId.GUID)
+		log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), queryId)
 		defer log.Duration(msg, start)
 	}
-	return resp, CheckStatus(resp)
+	// an error status means the statement was rejected by the server, e.g. because of a syntax error
+	return resp, executionErrorContext(ctx, queryId, CheckStatus(resp))
 }
 
 // GetOperationStatus is a wrapper around the thrift operation GetOperationStatus
 // If RecordResults is true, the results will be marshalled to JSON format and written to GetOperationStatus<index>.json
 func (tsc *ThriftServiceClient) GetOperationStatus(ctx context.Context, req *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
-	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), SprintGuid(req.OperationHandle.OperationId.GUID))
+	queryId := SprintGuid(req.OperationHandle.OperationId.GUID)
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), queryId)
 	defer log.Duration(logger.Track("GetOperationStatus"))
 	resp, err := tsc.TCLIServiceClient.GetOperationStatus(ctx, req)
 	if err != nil {
-		return resp, errors.Wrap(err, "get operation status request error")
+		return resp, newRequestError(ctx, "get operation status request error", queryId, err)
 	}
 	if RecordResults {
 		j, _ := json.MarshalIndent(resp, "", " ")
 		_ = os.WriteFile(fmt.Sprintf("GetOperationStatus%d.json", resultIndex), j, 0600)
 		resultIndex++
 	}
-	return resp, CheckStatus(resp)
+	return resp, requestErrorContext(ctx, queryId, CheckStatus(resp))
 }
 
 // CloseOperation is a wrapper around the thrift operation CloseOperation
 // If RecordResults is true, the results will be marshalled to JSON format and written to CloseOperation<index>.json
 func (tsc *ThriftServiceClient) CloseOperation(ctx context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
-	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), SprintGuid(req.OperationHandle.OperationId.GUID))
+	queryId := SprintGuid(req.OperationHandle.OperationId.GUID)
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), queryId)
 	defer log.Duration(logger.Track("CloseOperation"))
 	resp, err := tsc.TCLIServiceClient.CloseOperation(ctx, req)
 	if err != nil {
-		return resp, errors.Wrap(err, "close operation request error")
+		return resp, newRequestError(ctx, "close operation request error", queryId, err)
 	}
 	if RecordResults {
 		j, _ := json.MarshalIndent(resp, "", " ")
 		_ = os.WriteFile(fmt.Sprintf("CloseOperation%d.json", resultIndex), j, 0600)
 		resultIndex++
 	}
-	return resp, CheckStatus(resp)
+	return resp, requestErrorContext(ctx, queryId, CheckStatus(resp))
 }
 
 // CancelOperation is a wrapper around the thrift operation CancelOperation
 // If RecordResults is true, the results will be marshalled to JSON format and written to CancelOperation<index>.json
 func (tsc *ThriftServiceClient) CancelOperation(ctx context.Context, req *cli_service.TCancelOperationReq) (*cli_service.TCancelOperationResp, error) {
-	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), SprintGuid(req.OperationHandle.OperationId.GUID))
+	queryId := SprintGuid(req.OperationHandle.OperationId.GUID)
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), queryId)
 	defer log.Duration(logger.Track("CancelOperation"))
 	resp, err := tsc.TCLIServiceClient.CancelOperation(ctx, req)
 	if err != nil {
-		return resp, errors.Wrap(err, "cancel operation request error")
+		return resp, newRequestError(ctx, "cancel operation request error", queryId, err)
 	}
 	if RecordResults {
 		j, _ := json.MarshalIndent(resp, "", " ")
 		_ = os.WriteFile(fmt.Sprintf("CancelOperation%d.json", resultIndex), j, 0600)
 		resultIndex++
 	}
-	return resp, CheckStatus(resp)
+	return resp, requestErrorContext(ctx, queryId, CheckStatus(resp))
+}
+
+// GetTypeInfo is a wrapper around the thrift operation GetTypeInfo
+// If RecordResults is true, the results will be marshalled to JSON format and written to GetTypeInfo<index>.json
+func (tsc *ThriftServiceClient) GetTypeInfo(ctx context.Context, req *cli_service.TGetTypeInfoReq) (*cli_service.TGetTypeInfoResp, error) {
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), "")
+	defer log.Duration(logger.Track("GetTypeInfo"))
+	resp, err := tsc.TCLIServiceClient.GetTypeInfo(ctx, req)
+	if err != nil {
+		return resp, newRequestError(ctx, "get type info request error", "", err)
+	}
+	if RecordResults {
+		j, _ := json.MarshalIndent(resp, "", " ")
+		_ = os.WriteFile(fmt.Sprintf("GetTypeInfo%d.json", resultIndex), j, 0600)
+		resultIndex++
+	}
+	return resp, executionErrorContext(ctx, "", CheckStatus(resp))
+}
+
+// GetCatalogs is a wrapper around the thrift operation GetCatalogs
+// If RecordResults is true, the results will be marshalled to JSON format and written to GetCatalogs<index>.json
+func (tsc *ThriftServiceClient) GetCatalogs(ctx context.Context, req *cli_service.TGetCatalogsReq) (*cli_service.TGetCatalogsResp, error) {
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), "")
+	defer log.Duration(logger.Track("GetCatalogs"))
+	resp, err := tsc.TCLIServiceClient.GetCatalogs(ctx, req)
+	if err != nil {
+		return resp, newRequestError(ctx, "get catalogs request error", "", err)
+	}
+	if RecordResults {
+		j, _ := json.MarshalIndent(resp, "", " ")
+		_ = os.WriteFile(fmt.Sprintf("GetCatalogs%d.json", resultIndex), j, 0600)
+		resultIndex++
+	}
+	return resp, executionErrorContext(ctx, "", CheckStatus(resp))
+}
+
+// GetSchemas is a wrapper around the thrift operation GetSchemas
+// If RecordResults is true, the results will be marshalled to JSON format and written to GetSchemas<index>.json
+func (tsc *ThriftServiceClient) GetSchemas(ctx context.Context, req *cli_service.TGetSchemasReq) (*cli_service.TGetSchemasResp, error) {
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), "")
+	defer log.Duration(logger.Track("GetSchemas"))
+	resp, err := tsc.TCLIServiceClient.GetSchemas(ctx, req)
+	if err != nil {
+		return resp, newRequestError(ctx, "get schemas request error", "", err)
+	}
+	if RecordResults {
+		j, _ := json.MarshalIndent(resp, "", " ")
+		_ = os.WriteFile(fmt.Sprintf("GetSchemas%d.json", resultIndex), j, 0600)
+		resultIndex++
+	}
+	return resp, executionErrorContext(ctx, "", CheckStatus(resp))
+}
+
+// GetTables is a wrapper around the thrift operation GetTables
+// If RecordResults is true, the results will be marshalled to JSON format and written to GetTables<index>.json
+func (tsc *ThriftServiceClient) GetTables(ctx context.Context, req *cli_service.TGetTablesReq) (*cli_service.TGetTablesResp, error) {
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), "")
+	defer log.Duration(logger.Track("GetTables"))
+	resp, err := tsc.TCLIServiceClient.GetTables(ctx, req)
+	if err != nil {
+		return resp, newRequestError(ctx, "get tables request error", "", err)
+	}
+	if RecordResults {
+		j, _ := json.MarshalIndent(resp, "", " ")
+		_ = os.WriteFile(fmt.Sprintf("GetTables%d.json", resultIndex), j, 0600)
+		resultIndex++
+	}
+	return resp, executionErrorContext(ctx, "", CheckStatus(resp))
+}
+
+// GetColumns is a wrapper around the thrift operation GetColumns
+// If RecordResults is true, the results will be marshalled to JSON format and written to GetColumns<index>.json
+func (tsc *ThriftServiceClient) GetColumns(ctx context.Context, req *cli_service.TGetColumnsReq) (*cli_service.TGetColumnsResp, error) {
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), "")
+	defer log.Duration(logger.Track("GetColumns"))
+	resp, err := tsc.TCLIServiceClient.GetColumns(ctx, req)
+	if err != nil {
+		return resp, newRequestError(ctx, "get columns request error", "", err)
+	}
+	if RecordResults {
+		j, _ := json.MarshalIndent(resp, "", " ")
+		_ = os.WriteFile(fmt.Sprintf("GetColumns%d.json", resultIndex), j, 0600)
+		resultIndex++
+	}
+	return resp, executionErrorContext(ctx, "", CheckStatus(resp))
+}
+
+// GetFunctions is a wrapper around the thrift operation GetFunctions
+// If RecordResults is true, the results will be marshalled to JSON format and written to GetFunctions<index>.json
+func (tsc *ThriftServiceClient) GetFunctions(ctx context.Context, req *cli_service.TGetFunctionsReq) (*cli_service.TGetFunctionsResp, error) {
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), "")
+	defer log.Duration(logger.Track("GetFunctions"))
+	resp, err := tsc.TCLIServiceClient.GetFunctions(ctx, req)
+	if err != nil {
+		return resp, newRequestError(ctx, "get functions request error", "", err)
+	}
+	if RecordResults {
+		j, _ := json.MarshalIndent(resp, "", " ")
+		_ = os.WriteFile(fmt.Sprintf("GetFunctions%d.json", resultIndex), j, 0600)
+		resultIndex++
+	}
+	return resp, executionErrorContext(ctx, "", CheckStatus(resp))
+}
+
+// GetPrimaryKeys is a wrapper around the thrift operation GetPrimaryKeys
+// If RecordResults is true, the results will be marshalled to JSON format and written to GetPrimaryKeys<index>.json
+func (tsc *ThriftServiceClient) GetPrimaryKeys(ctx context.Context, req *cli_service.TGetPrimaryKeysReq) (*cli_service.TGetPrimaryKeysResp, error) {
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), "")
+	defer log.Duration(logger.Track("GetPrimaryKeys"))
+	resp, err := tsc.TCLIServiceClient.GetPrimaryKeys(ctx, req)
+	if err != nil {
+		return resp, newRequestError(ctx, "get primary keys request error", "", err)
+	}
+	if RecordResults {
+		j, _ := json.MarshalIndent(resp, "", " ")
+		_ = os.WriteFile(fmt.Sprintf("GetPrimaryKeys%d.json", resultIndex), j, 0600)
+		resultIndex++
+	}
+	return resp, executionErrorContext(ctx, "", CheckStatus(resp))
+}
+
+// GetCrossReference is a wrapper around the thrift operation GetCrossReference
+// If RecordResults is true, the results will be marshalled to JSON format and written to GetCrossReference<index>.json
+func (tsc *ThriftServiceClient) GetCrossReference(ctx context.Context, req *cli_service.TGetCrossReferenceReq) (*cli_service.TGetCrossReferenceResp, error) {
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), "")
+	defer log.Duration(logger.Track("GetCrossReference"))
+	resp, err := tsc.TCLIServiceClient.GetCrossReference(ctx, req)
+	if err != nil {
+		return resp, newRequestError(ctx, "get cross reference request error", "", err)
+	}
+	if RecordResults {
+		j, _ := json.MarshalIndent(resp, "", " ")
+		_ = os.WriteFile(fmt.Sprintf("GetCrossReference%d.json", resultIndex), j, 0600)
+		resultIndex++
+	}
+	return resp, executionErrorContext(ctx, "", CheckStatus(resp))
 }
 
 // InitThriftClient is a wrapper of the http transport, so we can have access to response code and headers.
@@ -178,6 +348,10 @@ func InitThriftClient(cfg *config.Config, httpclient *http.Client) (*ThriftServi
 	endpoint := cfg.ToEndpointURL()
 	tcfg := &thrift.TConfiguration{
 		TLSConfig: cfg.TLSConfig,
+		// results may hold BINARY and STRING values larger than the default limit of 100 MB, the messages
+		// are HTTP bodies and not frames, so the size of a value is only bounded by its int32 length
+		MaxMessageSize: math.MaxInt32,
+		MaxFrameSize:   math.MaxInt32,
 	}
 
 	var protocolFactory thrift.TProtocolFactory
@@ -214,11 +388,7 @@ func InitThriftClient(cfg *config.Config, httpclient *http.Client) (*ThriftServi
 		tTrans, err = thrift.NewTHttpClientWithOptions(endpoint, thrift.THttpClientOptions{Client: httpclient})
 
 		thriftHttpClient := tTrans.(*thrift.THttpClient)
-		userAgent := fmt.Sprintf("%s/%s", cfg.DriverName, cfg.DriverVersion)
-		if cfg.UserAgentEntry != "" {
-			userAgent = fmt.Sprintf("%s/%s (%s)", cfg.DriverName, cfg.DriverVersion, cfg.UserAgentEntry)
-		}
-		thriftHttpClient.SetHeader("User-Agent", userAgent)
+		thriftHttpClient.SetHeader("User-Agent", userAgent(cfg, ""))
 
 	default:
 		return nil, errors.Errorf("unsupported transport `%s`", cfg.ThriftTransport)
@@ -236,6 +406,32 @@ func InitThriftClient(cfg *config.Config, httpclient *http.Client) (*ThriftServi
 	return tsClient, nil
 }
 
+// userAgent returns the User-Agent header of the requests: the driver name and version, followed by the user
+// agent entry and the tag of the request in parentheses, e.g. godatabrickssqlconnector/1.0.0 (acme+bi; dashboards)
+func userAgent(cfg *config.Config, tag string) string {
+	var comments []string
+	for _, c := range []string{cfg.UserAgentEntry, tag} {
+		if c = sanitizeUserAgent(c); c != "" {
+			comments = append(comments, c)
+		}
+	}
+	if len(comments) == 0 {
+		return fmt.Sprintf("%s/%s", cfg.DriverName, cfg.DriverVersion)
+	}
+	return fmt.Sprintf("%s/%s (%s)", cfg.DriverName, cfg.DriverVersion, strings.Join(comments, "; "))
+}
+
+// sanitizeUserAgent replaces the characters of s which aren't printable ASCII or would end the comment of the
+// User-Agent header, the parentheses, the semicolon and the backslash, by an underscore
+func sanitizeUserAgent(s string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' || r == '(' || r == ')' || r == ';' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, s))
+}
+
 // ThriftResponse represents the thrift rpc response
 type ThriftResponse interface {
 	GetStatus() *cli_service.TStatus
@@ -248,10 +444,24 @@ func CheckStatus(resp interface{}) error {
 	if ok {
 		status := rpcresp.GetStatus()
 		if status.StatusCode == cli_service.TStatusCode_ERROR_STATUS {
-			return errors.New(status.GetErrorMessage())
+			// the error message may have the stack trace of the server, it is kept in the details
+			msg := status.GetDisplayMessage()
+			if msg == "" {
+				msg = dbsqlerr.FirstLine(status.GetErrorMessage())
+			}
+			return errors.WithStack(&dbsqlerr.RequestError{
+				Msg:        msg,
+				StatusCode: status.StatusCode.String(),
+				ErrorCode:  status.GetErrorCode(),
+				SQLState:   status.GetSqlState(),
+				Server:     NewServerDetails(status.GetErrorMessage(), status.InfoMessages, ""),
+			})
 		}
 		if status.StatusCode == cli_service.TStatusCode_INVALID_HANDLE_STATUS {
-			return errors.New("thrift: invalid handle")
+			return errors.WithStack(&dbsqlerr.RequestError{
+				Msg:        "thrift: invalid handle",
+				StatusCode: status.StatusCode.String(),
+			})
 		}
 
 		// SUCCESS, SUCCESS_WITH_INFO, STILL_EXECUTING are ok
@@ -261,6 +471,54 @@ func CheckStatus(resp interface{}) error {
 	return errors.New("thrift: invalid response")
 }
 
+// NewServerDetails returns the details of an error of the server, nil if it has none
+func NewServerDetails(errorMessage string, infoMessages []string, diagnosticInfo string) *dbsqlerr.ServerDetails {
+	if errorMessage == "" && len(infoMessages) == 0 && diagnosticInfo == "" {
+		return nil
+	}
+	return &dbsqlerr.ServerDetails{ErrorMessage: errorMessage, InfoMessages: infoMessages, DiagnosticInfo: diagnosticInfo}
+}
+
+// newRequestError returns the error of a failed request of the query queryId, which may be empty
+func newRequestError(ctx context.Context, msg string, queryId string, err error) error {
+	reqErr := &dbsqlerr.RequestError{Msg: msg, Err: err}
+	// keep the status code of the HTTP response set by errorHandler
+	var httpErr *dbsqlerr.RequestError
+	if errors.As(err, &httpErr) {
+		reqErr.HTTPStatusCode = httpErr.HTTPStatusCode
+	}
+	return requestErrorContext(ctx, queryId, errors.WithStack(reqErr))
+}
+
+// requestErrorContext sets the ids of the connection, the correlation and the query of a request error
+func requestErrorContext(ctx context.Context, queryId string, err error) error {
+	var reqErr *dbsqlerr.RequestError
+	if errors.As(err, &reqErr) {
+		reqErr.ConnectionId = driverctx.ConnIdFromContext(ctx)
+		reqErr.CorrelationId = driverctx.CorrelationIdFromContext(ctx)
+		reqErr.QueryId = queryId
+	}
+	return err
+}
+
+// executionErrorContext converts the error status of a statement to an execution error
+func executionErrorContext(ctx context.Context, queryId string, err error) error {
+	var reqErr *dbsqlerr.RequestError
+	if !errors.As(err, &reqErr) || reqErr.StatusCode != cli_service.TStatusCode_ERROR_STATUS.String() {
+		return requestErrorContext(ctx, queryId, err)
+	}
+	return errors.WithStack(&dbsqlerr.ExecutionError{
+		Msg:           reqErr.Msg,
+		SQLState:      reqErr.SQLState,
+		ErrorClass:    dbsqlerr.ErrorClass(reqErr.Msg),
+		ErrorCode:     reqErr.ErrorCode,
+		ConnectionId:  driverctx.ConnIdFromContext(ctx),
+		CorrelationId: driverctx.CorrelationIdFromContext(ctx),
+		QueryId:       queryId,
+		Server:        reqErr.Server,
+	})
+}
+
 // SprintGuid is a convenience function to format a byte array into GUID.
 func SprintGuid(bts []byte) string {
 	if len(bts) == 16 {
@@ -271,11 +529,17 @@ func SprintGuid(bts []byte) string {
 }
 
 type Transport struct {
-	Base  *http.Transport
+	Base  http.RoundTripper
 	Authr auth.Authenticator
 	trace bool
+	// cfg sets the User-Agent header of the requests with the tag of NewContextWithUserAgentTag, and the
+	// headers of cfg.RequestHeaders
+	cfg *config.Config
 }
 
+// correlationIdHeader is the header of the requests with the correlation id of their context
+const correlationIdHeader = "X-Correlation-ID"
+
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if t.trace {
 		trace := &httptrace.ClientTrace{
@@ -296,6 +560,18 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	}
 
 	req2 := cloneRequest(req) // per RoundTripper contract
+	if corrId := driverctx.CorrelationIdFromContext(req.Context()); corrId != "" {
+		req2.Header.Set(correlationIdHeader, corrId)
+	}
+	if t.cfg != nil {
+		if tag := driverctx.UserAgentTagFromContext(req.Context()); tag != "" {
+			req2.Header.Set("User-Agent", userAgent(t.cfg, tag))
+		}
+		// the headers are added before authentication, which sets the Authorization header
+		for _, addHeaders := range t.cfg.RequestHeaders {
+			addHeaders(req.Context(), req2.Header)
+		}
+	}
 
 	err := t.Authr.Authenticate(req2)
 
@@ -322,38 +598,103 @@ func RetryableClient(cfg *config.Config) *http.Client {
 		RetryWaitMax: cfg.RetryWaitMax,
 		RetryMax:     cfg.RetryMax,
 		ErrorHandler: errorHandler,
-		CheckRetry:   retryablehttp.DefaultRetryPolicy,
-		Backoff:      retryablehttp.DefaultBackoff,
+		CheckRetry:   checkRetry(cfg),
+		Backoff:      backoff,
 	}
+	countRetries(retryableClient, cfg.Metrics)
 	return retryableClient.StandardClient()
 }
 
-func PooledTransport() *http.Transport {
+// errWarehouseStarting stops the retries of the requests rejected while the warehouse is starting
+var errWarehouseStarting = errors.New("warehouse is starting")
+
+// checkRetry retries requests like retryablehttp.DefaultRetryPolicy, except the requests rejected while the
+// warehouse is starting when the driver waits for the warehouse itself, see WarehouseStartTimeout, the requests
+// failed fast by an open circuit breaker, and the requests of NewContextWithoutUnsafeRetries which may have
+// reached the server
+func checkRetry(cfg *config.Config) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if cfg.WarehouseStartTimeout > 0 && isWarehouseStarting(resp) {
+			return false, errWarehouseStarting
+		}
+		var openErr *dbsqlerr.CircuitOpenError
+		if errors.As(err, &openErr) {
+			return false, err
+		}
+		if noUnsafeRetries, _ := ctx.Value(noUnsafeRetriesKey{}).(bool); noUnsafeRetries && ctx.Err() == nil && !isRateLimited(resp) {
+			return false, err
+		}
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
+}
+
+type noUnsafeRetriesKey struct{}
+
+// NewContextWithoutUnsafeRetries returns a context whose requests are only retried when the server rejected them
+// without processing them, e.g. the request executing a statement which isn't idempotent. Requests failing with
+// a transport error or a server error may have been processed and are not retried.
+func NewContextWithoutUnsafeRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noUnsafeRetriesKey{}, true)
+}
+
+// PooledTransport returns a transport with the TLS, proxy and connection pool settings of cfg. Its idle
+// connections per host are enough for the concurrent cloud fetch downloads.
+func PooledTransport(cfg *config.Config) *http.Transport {
 	transport := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: proxyFunc(cfg),
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
 			DualStack: true,
 		}).DialContext,
-		ForceAttemptHTTP2:     true,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       180 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
+		TLSClientConfig:       cfg.TLSConfig,
+		ForceAttemptHTTP2:     cfg.UseHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
 		ExpectContinueTimeout: 1 * time.Second,
-		MaxIdleConnsPerHost:   10, // this client is only used for one host
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
 		MaxConnsPerHost:       100,
 	}
+	if cfg.MaxDownloadThreads > transport.MaxIdleConnsPerHost {
+		transport.MaxIdleConnsPerHost = cfg.MaxDownloadThreads
+	}
 	return transport
 }
 
+// transport returns the transport shared by the clients of the connector of cfg, a new one when there is none.
+// The transport of the client of the user is used when it is set, see config.HTTPClient.
+func transport(cfg *config.Config) http.RoundTripper {
+	if cfg.HTTPClient != nil {
+		if cfg.HTTPClient.Transport != nil {
+			return cfg.HTTPClient.Transport
+		}
+		return http.DefaultTransport
+	}
+	if cfg.Transport != nil {
+		return cfg.Transport
+	}
+	return PooledTransport(cfg)
+}
+
 func PooledClient(cfg *config.Config) *http.Client {
 	if cfg.Authenticator == nil {
 		return nil
 	}
+	var base http.RoundTripper = &compressionTransport{Base: wrapTransport(cfg, transport(cfg)), Gzip: cfg.UseGzipCompression}
+	if breaker := getCircuitBreaker(cfg); breaker != nil {
+		base = &circuitBreakerTransport{Base: base, Breaker: breaker}
+	}
 	tr := &Transport{
-		Base:  PooledTransport(),
+		Base:  base,
 		Authr: cfg.Authenticator,
+		cfg:   cfg,
+	}
+	if cfg.HTTPClient != nil {
+		// the cookie jar, redirect policy and timeout of the client of the user are kept
+		httpclient := *cfg.HTTPClient
+		httpclient.Transport = tr
+		return &httpclient
 	}
 	return &http.Client{
 		Transport: tr,
@@ -361,6 +702,118 @@ func PooledClient(cfg *config.Config) *http.Client {
 	}
 }
 
+// CloudFetchClient returns a client for downloading cloud fetch results from presigned URLs.
+// Requests are not authenticated, the URLs carry their own credentials.
+func CloudFetchClient(cfg *config.Config) *http.Client {
+	retryableClient := &retryablehttp.Client{
+		HTTPClient: &http.Client{
+			Transport: wrapTransport(cfg, transport(cfg)),
+			Timeout:   cfg.ClientTimeout,
+		},
+		Logger:       &leveledLogger{},
+		RetryWaitMin: cfg.RetryWaitMin,
+		RetryWaitMax: cfg.RetryWaitMax,
+		RetryMax:     cfg.RetryMax,
+		ErrorHandler: errorHandler,
+		CheckRetry:   retryablehttp.DefaultRetryPolicy,
+		Backoff:      backoff,
+	}
+	countRetries(retryableClient, cfg.Metrics)
+	return retryableClient.StandardClient()
+}
+
+// wrapTransport returns the transport of the requests: base passed to cfg.WrapTransport, base if it is not set.
+// The requests reach it after authentication.
+func wrapTransport(cfg *config.Config, base http.RoundTripper) http.RoundTripper {
+	if cfg.WrapTransport == nil {
+		return base
+	}
+	if rt := cfg.WrapTransport(base); rt != nil {
+		return rt
+	}
+	return base
+}
+
+// compressionTransport negotiates the compression of the responses. With Gzip it asks for gzip compressed
+// responses and decompresses them, whatever the base transport, otherwise it asks for uncompressed responses.
+type compressionTransport struct {
+	Base http.RoundTripper
+	Gzip bool
+}
+
+func (t *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" {
+		return t.Base.RoundTrip(req)
+	}
+	req2 := cloneRequest(req) // per RoundTripper contract
+	if t.Gzip {
+		req2.Header.Set("Accept-Encoding", "gzip")
+	} else {
+		req2.Header.Set("Accept-Encoding", "identity")
+	}
+	resp, err := t.Base.RoundTrip(req2)
+	if err != nil || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp, err
+	}
+	resp.Body = &gzipReader{body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// gzipReader decompresses a response body, the gzip header is read on the first Read
+type gzipReader struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	err  error
+}
+
+func (r *gzipReader) Read(p []byte) (int, error) {
+	if r.zr == nil && r.err == nil {
+		r.zr, r.err = gzip.NewReader(r.body)
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.zr.Read(p)
+}
+
+func (r *gzipReader) Close() error {
+	return r.body.Close()
+}
+
+// countRetries reports the retried requests of client to collector
+func countRetries(client *retryablehttp.Client, collector metrics.Collector) {
+	if collector == nil {
+		return
+	}
+	client.RequestLogHook = func(_ retryablehttp.Logger, _ *http.Request, attempt int) {
+		if attempt > 0 {
+			collector.Counter(metrics.Retries, 1)
+		}
+	}
+}
+
+// proxyFunc returns the proxy of the requests: cfg.Proxy, except for the hosts of the NO_PROXY
+// environment variable, or the proxy of the HTTPS_PROXY and HTTP_PROXY environment variables.
+// Requests to HTTPS servers go through a CONNECT tunnel authenticated with the credentials of the proxy URL.
+func proxyFunc(cfg *config.Config) func(*http.Request) (*url.URL, error) {
+	if cfg.Proxy == nil {
+		return http.ProxyFromEnvironment
+	}
+	env := httpproxy.FromEnvironment()
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  cfg.Proxy.String(),
+		HTTPSProxy: cfg.Proxy.String(),
+		NoProxy:    env.NoProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
 // cloneRequest returns a clone of the provided *http.Request.
 // The clone is a shallow copy of the struct and its Header map.
 func cloneRequest(r *http.Request) *http.Request {
@@ -397,18 +850,82 @@ func errorHandler(resp *http.Response, err error, numTries int) (*http.Response,
 		err = errors.New(fmt.Sprintf("request error after %d attempt(s)", numTries))
 	}
 	if resp != nil && resp.Header != nil {
-
-		// TODO @mattdeekay: convert these to specific error types
-
 		orgid := resp.Header.Get("X-Databricks-Org-Id")
 		reason := resp.Header.Get("X-Databricks-Reason-Phrase")
 		terrmsg := resp.Header.Get("X-Thriftserver-Error-Message")
 		errmsg := resp.Header.Get("x-databricks-error-or-redirect-message")
 
-		werr = errors.Wrapf(err, fmt.Sprintf("orgId: %s, reason: %s, thriftErr: %s, err: %s", orgid, reason, terrmsg, errmsg))
+		reqErr := &dbsqlerr.RequestError{
+			Msg:            fmt.Sprintf("orgId: %s, reason: %s, thriftErr: %s, err: %s", orgid, reason, terrmsg, errmsg),
+			HTTPStatusCode: resp.StatusCode,
+			Err:            err,
+		}
+		werr = reqErr
+		if isWarehouseStarting(resp) {
+			werr = &dbsqlerr.WarehouseStartingError{Err: reqErr}
+		} else if isRateLimited(resp) {
+			retryAfter, _ := parseRetryAfter(resp)
+			werr = &dbsqlerr.RateLimitError{RetryAfter: retryAfter, Err: reqErr}
+		}
 	} else {
 		werr = err
 	}
 
 	return resp, werr
 }
+
+// isRateLimited returns true for the responses of a server rate limiting requests or scaling up,
+// which are retried after the wait of their Retry-After header
+func isRateLimited(resp *http.Response) bool {
+	return resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable)
+}
+
+// isWarehouseStarting returns true for the responses of a server rejecting requests while the warehouse is starting
+func isWarehouseStarting(resp *http.Response) bool {
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	for _, header := range []string{"X-Databricks-Reason-Phrase", "X-Thriftserver-Error-Message", "X-Databricks-Error-Or-Redirect-Message"} {
+		if strings.Contains(resp.Header.Get(header), "TEMPORARILY_UNAVAILABLE") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter returns the wait of the Retry-After header of resp, given in seconds or as an HTTP date
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// backoff waits as long as the Retry-After header of rate limited responses asks, up to max,
+// and exponentially longer between min and max otherwise
+func backoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if isRateLimited(resp) {
+		if wait, ok := parseRetryAfter(resp); ok {
+			if wait > max {
+				return max
+			}
+			return wait
+		}
+	}
+	// the response is not passed so retryablehttp doesn't use Retry-After without the max
+	return retryablehttp.DefaultBackoff(min, max, attemptNum, nil)
+}
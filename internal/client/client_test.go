@@ -1,6 +1,30 @@
 package client
 
-import "testing"
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/databricks/databricks-sql-go/auth/pat"
+	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/databricks/databricks-sql-go/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 func TestSprintByteId(t *testing.T) {
 	type args struct {
@@ -35,3 +59,518 @@ func TestSprintByteId(t *testing.T) {
 		})
 	}
 }
+
+func TestPooledTransport(t *testing.T) {
+	cfg := config.WithDefaults()
+	cfg.MaxIdleConns = 200
+	cfg.MaxIdleConnsPerHost = 20
+	cfg.IdleConnTimeout = time.Minute
+	cfg.TLSHandshakeTimeout = 5 * time.Second
+	cfg.UseHTTP2 = false
+	cfg.MaxDownloadThreads = 32
+
+	transport := PooledTransport(cfg)
+	assert.Equal(t, 200, transport.MaxIdleConns)
+	assert.Equal(t, 32, transport.MaxIdleConnsPerHost, "the idle connections per host are enough for the downloads")
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	assert.Equal(t, 5*time.Second, transport.TLSHandshakeTimeout)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.Same(t, cfg.TLSConfig, transport.TLSClientConfig)
+
+	// the clients of a connector share its transport
+	cfg.Transport = transport
+	cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+	pooled, ok := PooledClient(cfg).Transport.(*Transport)
+	require.True(t, ok)
+	compression, ok := pooled.Base.(*compressionTransport)
+	require.True(t, ok)
+	assert.Same(t, transport, compression.Base)
+}
+
+func TestPooledClientUsesTLSConfig(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.Organization[0]))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(server.Certificate())
+
+	cfg := config.WithDefaults()
+	cfg.TLSConfig.RootCAs = serverCAs
+	cfg.TLSConfig.Certificates = server.TLS.Certificates
+
+	resp, err := PooledClient(cfg).Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Acme Co", string(body))
+
+	// without the client certificate the handshake fails
+	cfg.TLSConfig = &tls.Config{RootCAs: serverCAs, MinVersion: tls.VersionTLS12}
+	_, err = PooledClient(cfg).Get(server.URL)
+	assert.Error(t, err)
+}
+
+type retryCollector struct {
+	retries float64
+}
+
+func (c *retryCollector) Counter(name string, value float64) {
+	if name == metrics.Retries {
+		c.retries += value
+	}
+}
+func (c *retryCollector) Histogram(name string, value float64) {}
+func (c *retryCollector) Gauge(name string, delta float64)     {}
+
+func TestCloudFetchClientCountsRetries(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	collector := &retryCollector{}
+	cfg := config.WithDefaults()
+	cfg.RetryWaitMin = time.Millisecond
+	cfg.RetryWaitMax = time.Millisecond
+	cfg.Metrics = collector
+
+	resp, err := CloudFetchClient(cfg).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, float64(2), collector.retries)
+}
+
+func TestRequestErrors(t *testing.T) {
+	t.Run("error statuses are request errors", func(t *testing.T) {
+		err := CheckStatus(&cli_service.TGetOperationStatusResp{Status: &cli_service.TStatus{
+			StatusCode:   cli_service.TStatusCode_ERROR_STATUS,
+			ErrorMessage: thrift.StringPtr("session expired"),
+			SqlState:     thrift.StringPtr("08003"),
+		}})
+		var reqErr *dbsqlerr.RequestError
+		require.ErrorAs(t, err, &reqErr)
+		assert.Equal(t, "session expired", reqErr.Error())
+		assert.Equal(t, "08003", reqErr.SQLState)
+		assert.Equal(t, "ERROR_STATUS", reqErr.StatusCode)
+		assert.NoError(t, CheckStatus(&cli_service.TGetOperationStatusResp{Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS}}))
+	})
+
+	t.Run("errors of the statement are execution errors", func(t *testing.T) {
+		ctx := driverctx.NewContextWithConnId(context.Background(), "conn1")
+		err := executionErrorContext(ctx, "q1", CheckStatus(&cli_service.TExecuteStatementResp{Status: &cli_service.TStatus{
+			StatusCode:   cli_service.TStatusCode_ERROR_STATUS,
+			ErrorMessage: thrift.StringPtr("[PARSE_SYNTAX_ERROR] Syntax error at or near 'selec'"),
+			SqlState:     thrift.StringPtr("42601"),
+		}}))
+		var execErr *dbsqlerr.ExecutionError
+		require.ErrorAs(t, err, &execErr)
+		assert.Equal(t, "PARSE_SYNTAX_ERROR", execErr.ErrorClass)
+		assert.Equal(t, "42601", execErr.SQLState)
+		assert.Equal(t, "conn1", execErr.ConnectionId)
+		assert.Equal(t, "q1", execErr.QueryId)
+	})
+
+	t.Run("the stack trace of the server is kept in the details", func(t *testing.T) {
+		err := executionErrorContext(context.Background(), "q1", CheckStatus(&cli_service.TExecuteStatementResp{Status: &cli_service.TStatus{
+			StatusCode:   cli_service.TStatusCode_ERROR_STATUS,
+			ErrorMessage: thrift.StringPtr("[DIVIDE_BY_ZERO] Division by zero\n\tat org.apache.spark.sql.errors.QueryExecutionErrors$.divideByZeroError(QueryExecutionErrors.scala:203)"),
+			InfoMessages: []string{"*org.apache.hive.service.cli.HiveSQLException:Error running query:12:11", "org.apache.spark.sql.hive.thriftserver.SparkExecuteStatementOperation:execute:SparkExecuteStatementOperation.scala:48"},
+		}}))
+		var execErr *dbsqlerr.ExecutionError
+		require.ErrorAs(t, err, &execErr)
+		assert.Equal(t, "[DIVIDE_BY_ZERO] Division by zero", execErr.Error())
+		assert.Equal(t, "DIVIDE_BY_ZERO", execErr.ErrorClass)
+		require.NotNil(t, execErr.Server)
+		assert.Contains(t, execErr.Server.ErrorMessage, "divideByZeroError")
+		assert.Len(t, execErr.Server.InfoMessages, 2)
+		assert.Contains(t, execErr.Server.StackTrace(), "SparkExecuteStatementOperation")
+	})
+
+	t.Run("request errors keep the HTTP status code", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		cfg := config.WithDefaults()
+		cfg.RetryMax = 0
+		_, err := CloudFetchClient(cfg).Get(server.URL)
+		err = newRequestError(context.Background(), "fetch results request error", "q1", err)
+		var reqErr *dbsqlerr.RequestError
+		require.ErrorAs(t, err, &reqErr)
+		assert.Equal(t, http.StatusServiceUnavailable, reqErr.HTTPStatusCode)
+		assert.Equal(t, "q1", reqErr.QueryId)
+		assert.True(t, dbsqlerr.IsRetryable(err))
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	response := func(status int, retryAfter string) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp
+	}
+
+	t.Run("backoff honors Retry-After up to the max wait", func(t *testing.T) {
+		assert.Equal(t, 5*time.Second, backoff(time.Second, 30*time.Second, 0, response(http.StatusTooManyRequests, "5")))
+		assert.Equal(t, 30*time.Second, backoff(time.Second, 30*time.Second, 0, response(http.StatusServiceUnavailable, "120")))
+		date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+		wait := backoff(time.Second, 30*time.Second, 0, response(http.StatusServiceUnavailable, date))
+		assert.InDelta(t, 10*time.Second, wait, float64(2*time.Second))
+	})
+
+	t.Run("backoff is exponential without Retry-After", func(t *testing.T) {
+		assert.Equal(t, time.Second, backoff(time.Second, 30*time.Second, 0, response(http.StatusTooManyRequests, "")))
+		assert.Equal(t, 4*time.Second, backoff(time.Second, 30*time.Second, 2, response(http.StatusTooManyRequests, "soon")))
+		assert.Equal(t, 4*time.Second, backoff(time.Second, 30*time.Second, 2, response(http.StatusInternalServerError, "5")))
+		assert.Equal(t, 30*time.Second, backoff(time.Second, 30*time.Second, 10, nil))
+	})
+
+	t.Run("rate limited requests return a RateLimitError when the retries ran out", func(t *testing.T) {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		cfg := config.WithDefaults()
+		cfg.RetryMax = 2
+		_, err := CloudFetchClient(cfg).Get(server.URL)
+		assert.Equal(t, 3, requests)
+
+		var rateLimitErr *dbsqlerr.RateLimitError
+		require.ErrorAs(t, err, &rateLimitErr)
+		assert.Equal(t, time.Duration(0), rateLimitErr.RetryAfter)
+		var reqErr *dbsqlerr.RequestError
+		require.ErrorAs(t, err, &reqErr)
+		assert.Equal(t, http.StatusTooManyRequests, reqErr.HTTPStatusCode)
+		assert.True(t, dbsqlerr.IsRetryable(err))
+	})
+}
+
+func TestWarehouseStarting(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Databricks-Reason-Phrase", "TEMPORARILY_UNAVAILABLE: warehouse is starting")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	for _, timeout := range []time.Duration{0, time.Minute} {
+		requests = 0
+		cfg := config.WithDefaults()
+		cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+		cfg.RetryMax = 2
+		cfg.RetryWaitMin = time.Millisecond
+		cfg.RetryWaitMax = time.Millisecond
+		cfg.WarehouseStartTimeout = timeout
+		_, err := RetryableClient(cfg).Get(server.URL)
+
+		var startErr *dbsqlerr.WarehouseStartingError
+		require.ErrorAs(t, err, &startErr)
+		var reqErr *dbsqlerr.RequestError
+		require.ErrorAs(t, err, &reqErr)
+		assert.Equal(t, http.StatusServiceUnavailable, reqErr.HTTPStatusCode)
+		if timeout > 0 {
+			assert.Equal(t, 1, requests, "the driver waits for the warehouse instead of retrying")
+		} else {
+			assert.Equal(t, 3, requests)
+		}
+	}
+
+	assert.False(t, isWarehouseStarting(&http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}))
+	assert.False(t, isWarehouseStarting(nil))
+}
+
+func TestNoUnsafeRetries(t *testing.T) {
+	var requests int
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	cfg := config.WithDefaults()
+	cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+	cfg.RetryMax = 2
+	cfg.RetryWaitMin = time.Millisecond
+	cfg.RetryWaitMax = time.Millisecond
+	httpClient := RetryableClient(cfg)
+	get := func(ctx context.Context) {
+		requests = 0
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := httpClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	get(context.Background())
+	assert.Equal(t, 3, requests)
+
+	get(NewContextWithoutUnsafeRetries(context.Background()))
+	assert.Equal(t, 1, requests, "the server may have processed the request")
+
+	status = http.StatusTooManyRequests
+	get(NewContextWithoutUnsafeRetries(context.Background()))
+	assert.Equal(t, 3, requests, "the server rejected the request")
+
+	assert.False(t, isRateLimited(nil))
+
+	t.Run("through the thrift client", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		serverURL, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		cfg.Protocol = serverURL.Scheme
+		cfg.Host = serverURL.Hostname()
+		cfg.Port, err = strconv.Atoi(serverURL.Port())
+		require.NoError(t, err)
+		cfg.HTTPPath = "/sql/1.0/warehouses/abc"
+		tclient, err := InitThriftClient(cfg, nil)
+		require.NoError(t, err)
+		execute := func(ctx context.Context) {
+			requests = 0
+			_, err := tclient.ExecuteStatement(ctx, &cli_service.TExecuteStatementReq{Statement: "INSERT INTO t VALUES (1)"})
+			require.Error(t, err)
+		}
+
+		execute(context.Background())
+		assert.Equal(t, 3, requests)
+
+		execute(NewContextWithoutUnsafeRetries(context.Background()))
+		assert.Equal(t, 1, requests, "the context of the statement reaches the retry policy")
+	})
+}
+
+func TestProxyFunc(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.cloud.databricks.com/sql/1.0/warehouses/abc", nil)
+
+	t.Run("explicit proxy", func(t *testing.T) {
+		t.Setenv("HTTPS_PROXY", "http://env-proxy:8080")
+		t.Setenv("NO_PROXY", "")
+		cfg := config.WithDefaults()
+		cfg.Proxy = &url.URL{Scheme: "http", Host: "proxy.internal:3128", User: url.UserPassword("user", "password")}
+
+		proxy, err := proxyFunc(cfg)(req)
+		require.NoError(t, err)
+		assert.Equal(t, cfg.Proxy.String(), proxy.String())
+	})
+
+	t.Run("explicit proxy honors NO_PROXY", func(t *testing.T) {
+		t.Setenv("NO_PROXY", ".cloud.databricks.com")
+		cfg := config.WithDefaults()
+		cfg.Proxy = &url.URL{Scheme: "socks5", Host: "proxy.internal:1080"}
+
+		proxy, err := proxyFunc(cfg)(req)
+		require.NoError(t, err)
+		assert.Nil(t, proxy)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWrapTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization") + " " + r.Header.Get("X-Corp-Auth")))
+	}))
+	defer server.Close()
+
+	var wrapped http.RoundTripper
+	cfg := config.WithDefaults()
+	cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+	cfg.WrapTransport = func(base http.RoundTripper) http.RoundTripper {
+		wrapped = base
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Corp-Auth", "corp")
+			return base.RoundTrip(req)
+		})
+	}
+
+	for name, client := range map[string]*http.Client{"pooled": PooledClient(cfg), "cloud fetch": CloudFetchClient(cfg)} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if name == "pooled" {
+				assert.Equal(t, "Bearer token corp", string(body))
+			} else {
+				assert.Equal(t, " corp", string(body))
+			}
+			require.IsType(t, &http.Transport{}, wrapped)
+			assert.Equal(t, cfg.TLSConfig, wrapped.(*http.Transport).TLSClientConfig)
+		})
+	}
+}
+
+func TestHTTPClientOfTheUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err == nil {
+			_, _ = w.Write([]byte(r.Header.Get("Authorization") + " " + cookie.Value))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+	}))
+	defer server.Close()
+
+	var requests int
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	hc := &http.Client{
+		Jar:     jar,
+		Timeout: time.Minute,
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+	cfg := config.WithDefaults()
+	cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+	cfg.HTTPClient = hc
+
+	client := PooledClient(cfg)
+	assert.Same(t, jar, client.Jar)
+	assert.Equal(t, time.Minute, client.Timeout)
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if i == 1 {
+			assert.Equal(t, "Bearer token abc", string(body), "the cookie of the jar is sent")
+		}
+	}
+	assert.Equal(t, 2, requests)
+	assert.NotSame(t, hc, client)
+	assert.IsType(t, roundTripperFunc(nil), hc.Transport, "the client of the user isn't changed")
+
+	resp, err := CloudFetchClient(cfg).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 3, requests, "the cloud fetch downloads use the transport of the client")
+}
+
+func TestUserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("User-Agent")))
+	}))
+	defer server.Close()
+
+	cfg := config.WithDefaults()
+	cfg.DriverVersion = "1.0.0"
+	assert.Equal(t, "godatabrickssqlconnector/1.0.0", userAgent(cfg, ""))
+	cfg.UserAgentEntry = "acme+bi"
+	assert.Equal(t, "godatabrickssqlconnector/1.0.0 (acme+bi)", userAgent(cfg, ""))
+	assert.Equal(t, "godatabrickssqlconnector/1.0.0 (acme+bi; dashboards)", userAgent(cfg, "dashboards"))
+	assert.Equal(t, "godatabrickssqlconnector/1.0.0 (acme+bi; dash_boards_ _x_)", userAgent(cfg, " dash(boards) \\x\n"))
+
+	cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+	client := PooledClient(cfg)
+	get := func(ctx context.Context) string {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("User-Agent", userAgent(cfg, ""))
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	assert.Equal(t, "godatabrickssqlconnector/1.0.0 (acme+bi)", get(context.Background()))
+	assert.Equal(t, "godatabrickssqlconnector/1.0.0 (acme+bi; exports)", get(driverctx.NewContextWithUserAgentTag(context.Background(), "exports")))
+}
+
+func TestRequestHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization") + " " + r.Header.Get("X-Request-ID") + " " + r.Header.Get("X-Gateway") + " " + r.Header.Get("X-Correlation-ID")))
+	}))
+	defer server.Close()
+
+	type requestIdKey struct{}
+	cfg := config.WithDefaults()
+	cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+	cfg.RequestHeaders = []config.HeaderFunc{
+		func(ctx context.Context, header http.Header) {
+			header.Set("X-Gateway", "corp")
+			header.Set("Authorization", "overridden")
+		},
+		func(ctx context.Context, header http.Header) {
+			if id, ok := ctx.Value(requestIdKey{}).(string); ok {
+				header.Set("X-Request-ID", id)
+			}
+			header.Set("X-Gateway", header.Get("X-Gateway")+"-2")
+		},
+	}
+
+	ctx := driverctx.NewContextWithCorrelationId(context.WithValue(context.Background(), requestIdKey{}, "req-1"), "trace-1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := PooledClient(cfg).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "Bearer token req-1 corp-2 trace-1", string(body))
+	assert.Empty(t, req.Header, "the request of the caller isn't changed")
+}
+
+func TestGzipCompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			_, _ = w.Write([]byte("plain " + r.Header.Get("Accept-Encoding")))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		_, _ = zw.Write([]byte("compressed"))
+		_ = zw.Close()
+	}))
+	defer server.Close()
+
+	get := func(cfg *config.Config) (*http.Response, string) {
+		resp, err := PooledClient(cfg).Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	cfg := config.WithDefaults()
+	cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+	resp, body := get(cfg)
+	assert.Equal(t, "compressed", body)
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.True(t, resp.Uncompressed)
+
+	cfg.UseGzipCompression = false
+	_, body = get(cfg)
+	assert.Equal(t, "plain identity", body)
+}
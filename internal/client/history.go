@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/databricks/databricks-sql-go/internal/config"
+)
+
+// queryHistoryPath is the path of the Query History API
+const queryHistoryPath = "/api/2.0/sql/history/queries"
+
+// QueryMetrics are the metrics of a finished query in the Query History
+type QueryMetrics struct {
+	RowsReadCount     int64 `json:"rows_read_count"`
+	ReadBytes         int64 `json:"read_bytes"`
+	RowsProducedCount int64 `json:"rows_produced_count"`
+	TaskTotalTimeMs   int64 `json:"task_total_time_ms"`
+	ExecutionTimeMs   int64 `json:"execution_time_ms"`
+	TotalTimeMs       int64 `json:"total_time_ms"`
+}
+
+type queryHistoryResponse struct {
+	Res []struct {
+		QueryID string        `json:"query_id"`
+		Status  string        `json:"status"`
+		Metrics *QueryMetrics `json:"metrics"`
+	} `json:"res"`
+}
+
+// GetQueryMetrics returns the metrics of a query from the Query History of the workspace of cfg, sending the
+// request with httpclient. The metrics are nil until the query finished and was added to the history.
+func GetQueryMetrics(ctx context.Context, cfg *config.Config, httpclient *http.Client, queryID string) (*QueryMetrics, error) {
+	c := &RESTServiceClient{
+		cfg:     cfg,
+		client:  httpclient,
+		baseURL: fmt.Sprintf("%s://%s:%d", cfg.Protocol, cfg.Host, cfg.Port),
+	}
+	params := url.Values{}
+	params.Set("filter_by.statement_ids", queryID)
+	params.Set("include_metrics", "true")
+
+	var resp queryHistoryResponse
+	if err := c.do(ctx, http.MethodGet, queryHistoryPath+"?"+params.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	for _, q := range resp.Res {
+		if q.QueryID == queryID && q.Status == "FINISHED" {
+			return q.Metrics, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,828 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/pkg/errors"
+)
+
+// restStatementsPath is the path of the Statement Execution API
+const restStatementsPath = "/api/2.0/sql/statements"
+
+// restWaitTimeout is how long the server runs a statement before answering with its running state
+const restWaitTimeout = "10s"
+
+// restTimestampFormat is the format of the TIMESTAMP values in the Thrift columnar results
+const restTimestampFormat = "2006-01-02 15:04:05.999999999"
+
+var errRESTMetadata = errors.New("databricks: metadata operations are not supported by the Statement Execution API")
+
+// RESTServiceClient runs statements with the Databricks SQL Statement Execution REST API instead of Thrift.
+// It implements the Thrift service so that connections and rows work the same with both APIs: sessions only
+// exist in the client and hold the catalog and schema of their statements, which run statelessly, and the
+// metadata operations are not supported.
+type RESTServiceClient struct {
+	cfg         *config.Config
+	client      *http.Client
+	baseURL     string
+	warehouseID string
+
+	mu         sync.Mutex
+	sessions   map[string]*cli_service.TNamespace // namespace of the sessions by session id
+	statements map[string]*restStatement          // statements by operation id
+}
+
+// restStatement is the state of a statement between the requests of a connection and its rows
+type restStatement struct {
+	id       string
+	manifest *restManifest
+	result   *restResult // first chunk of the results, until it is fetched
+	next     *int64      // index of the next chunk to fetch, nil after the last one
+}
+
+type restStatementRequest struct {
+	WarehouseID   string          `json:"warehouse_id"`
+	Statement     string          `json:"statement"`
+	Catalog       string          `json:"catalog,omitempty"`
+	Schema        string          `json:"schema,omitempty"`
+	Parameters    []restParameter `json:"parameters,omitempty"`
+	Disposition   string          `json:"disposition"`
+	Format        string          `json:"format"`
+	WaitTimeout   string          `json:"wait_timeout"`
+	OnWaitTimeout string          `json:"on_wait_timeout"`
+}
+
+type restParameter struct {
+	Name  string  `json:"name"`
+	Value *string `json:"value,omitempty"`
+	Type  string  `json:"type,omitempty"`
+}
+
+type restStatementResponse struct {
+	StatementID string        `json:"statement_id"`
+	Status      restStatus    `json:"status"`
+	Manifest    *restManifest `json:"manifest"`
+	Result      *restResult   `json:"result"`
+}
+
+type restStatus struct {
+	State string     `json:"state"`
+	Error *restError `json:"error"`
+}
+
+type restError struct {
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+type restManifest struct {
+	Format string `json:"format"`
+	Schema struct {
+		Columns []restColumn `json:"columns"`
+	} `json:"schema"`
+	TotalChunkCount int64       `json:"total_chunk_count"`
+	Chunks          []restChunk `json:"chunks"`
+}
+
+type restColumn struct {
+	Name          string `json:"name"`
+	Position      int32  `json:"position"`
+	TypeName      string `json:"type_name"`
+	TypeText      string `json:"type_text"`
+	TypePrecision int32  `json:"type_precision"`
+	TypeScale     int32  `json:"type_scale"`
+}
+
+type restChunk struct {
+	ChunkIndex int64 `json:"chunk_index"`
+	RowOffset  int64 `json:"row_offset"`
+	RowCount   int64 `json:"row_count"`
+}
+
+type restResult struct {
+	restChunk
+	NextChunkIndex *int64             `json:"next_chunk_index"`
+	DataArray      [][]*string        `json:"data_array"`
+	ExternalLinks  []restExternalLink `json:"external_links"`
+}
+
+type restExternalLink struct {
+	restChunk
+	ByteCount      int64  `json:"byte_count"`
+	ExternalLink   string `json:"external_link"`
+	Expiration     string `json:"expiration"`
+	NextChunkIndex *int64 `json:"next_chunk_index"`
+}
+
+// InitRESTClient returns a client running the statements of cfg with the Statement Execution API of the
+// warehouse of the HTTP path, sending the requests with httpclient
+func InitRESTClient(cfg *config.Config, httpclient *http.Client) (*RESTServiceClient, error) {
+	warehouseID, err := restWarehouseID(cfg.HTTPPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.SessionParams) > 0 {
+		return nil, errors.New("databricks: session params are not supported by the Statement Execution API")
+	}
+	if len(cfg.StatementTags) > 0 {
+		return nil, errors.New("databricks: query tags are not supported by the Statement Execution API")
+	}
+	if cfg.StatementConf != nil {
+		return nil, errors.New("databricks: the conf of statements is not supported by the Statement Execution API")
+	}
+	if httpclient == nil {
+		if cfg.Authenticator == nil {
+			return nil, errors.New("databricks: no authentication method set")
+		}
+		httpclient = RetryableClient(cfg)
+	}
+
+	return &RESTServiceClient{
+		cfg:         cfg,
+		client:      httpclient,
+		baseURL:     fmt.Sprintf("%s://%s:%d", cfg.Protocol, cfg.Host, cfg.Port),
+		warehouseID: warehouseID,
+		sessions:    make(map[string]*cli_service.TNamespace),
+		statements:  make(map[string]*restStatement),
+	}, nil
+}
+
+// restWarehouseID returns the id of the warehouse of an HTTP path, e.g. /sql/1.0/warehouses/<id>
+func restWarehouseID(httpPath string) (string, error) {
+	parts := strings.Split(strings.Trim(httpPath, "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if (parts[i] == "warehouses" || parts[i] == "endpoints") && parts[i+1] != "" {
+			return parts[i+1], nil
+		}
+	}
+	return "", errors.Errorf("databricks: the Statement Execution API needs the HTTP path of a SQL warehouse, got %s", httpPath)
+}
+
+// OpenSession starts a session in the client, no request is sent
+func (c *RESTServiceClient) OpenSession(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+	guid := make([]byte, 16)
+	if _, err := rand.Read(guid); err != nil {
+		return nil, errors.Wrap(err, "databricks: failed to generate session id")
+	}
+	namespace := req.GetInitialNamespace()
+	if namespace == nil {
+		namespace = &cli_service.TNamespace{}
+	}
+
+	c.mu.Lock()
+	c.sessions[string(guid)] = namespace
+	c.mu.Unlock()
+
+	return &cli_service.TOpenSessionResp{
+		Status:                restSuccess(),
+		ServerProtocolVersion: cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8,
+		SessionHandle: &cli_service.TSessionHandle{
+			SessionId: &cli_service.THandleIdentifier{GUID: guid, Secret: []byte{}},
+		},
+	}, nil
+}
+
+// CloseSession ends a session in the client, no request is sent
+func (c *RESTServiceClient) CloseSession(ctx context.Context, req *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+	c.mu.Lock()
+	delete(c.sessions, string(req.GetSessionHandle().GetSessionId().GetGUID()))
+	c.mu.Unlock()
+	return &cli_service.TCloseSessionResp{Status: restSuccess()}, nil
+}
+
+// GetInfo answers for the client, there is no server session to keep alive
+func (c *RESTServiceClient) GetInfo(ctx context.Context, req *cli_service.TGetInfoReq) (*cli_service.TGetInfoResp, error) {
+	return &cli_service.TGetInfoResp{Status: restSuccess(), InfoValue: &cli_service.TGetInfoValue{}}, nil
+}
+
+// ExecuteStatement submits a statement in the catalog and schema of its session. It returns the results
+// of the statements finishing within the wait timeout as direct results.
+func (c *RESTServiceClient) ExecuteStatement(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+	msg, start := logger.Track("ExecuteStatement")
+
+	c.mu.Lock()
+	namespace, ok := c.sessions[string(req.GetSessionHandle().GetSessionId().GetGUID())]
+	c.mu.Unlock()
+	if !ok {
+		return nil, requestErrorContext(ctx, "", errors.WithStack(&dbsqlerr.RequestError{
+			Msg:        "invalid SessionHandle",
+			StatusCode: cli_service.TStatusCode_INVALID_HANDLE_STATUS.String(),
+		}))
+	}
+
+	body := restStatementRequest{
+		WarehouseID:   c.warehouseID,
+		Statement:     req.Statement,
+		Disposition:   "INLINE",
+		Format:        "JSON_ARRAY",
+		WaitTimeout:   restWaitTimeout,
+		OnWaitTimeout: "CONTINUE",
+	}
+	if namespace.CatalogName != nil {
+		body.Catalog = string(*namespace.CatalogName)
+	}
+	if namespace.SchemaName != nil {
+		body.Schema = string(*namespace.SchemaName)
+	}
+	if conf := req.SessionConf; conf != nil {
+		if conf.CurrentCatalog != nil {
+			body.Catalog = *conf.CurrentCatalog
+		}
+		if conf.CurrentDatabase != nil {
+			body.Schema = *conf.CurrentDatabase
+		}
+	}
+	if c.cfg.UseCloudFetch {
+		// large results are downloaded from cloud storage, in Arrow format as with Thrift
+		body.Disposition = "EXTERNAL_LINKS"
+		body.Format = "ARROW_STREAM"
+	}
+	for _, param := range req.Parameters {
+		if param.Name == nil {
+			return nil, errors.New("databricks: the Statement Execution API only supports named parameters")
+		}
+		restParam := restParameter{Name: *param.Name}
+		if param.Value != nil {
+			restParam.Value = param.Value.StringValue
+		}
+		if param.Type != nil && *param.Type != "VOID" {
+			restParam.Type = *param.Type
+		}
+		body.Parameters = append(body.Parameters, restParam)
+	}
+
+	var resp restStatementResponse
+	if err := c.do(ctx, http.MethodPost, restStatementsPath, body, &resp); err != nil {
+		return nil, newRequestError(ctx, "execute statement request error", "", err)
+	}
+
+	guid := restGuid(resp.StatementID)
+	stmt := &restStatement{id: resp.StatementID, next: new(int64)}
+	c.mu.Lock()
+	c.statements[string(guid)] = stmt
+	c.mu.Unlock()
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), resp.StatementID)
+	defer log.Duration(msg, start)
+
+	directResults, err := c.directResults(stmt, &resp)
+	if err != nil {
+		return nil, requestErrorContext(ctx, resp.StatementID, err)
+	}
+	return &cli_service.TExecuteStatementResp{
+		Status: restSuccess(),
+		OperationHandle: &cli_service.TOperationHandle{
+			OperationId:   &cli_service.THandleIdentifier{GUID: guid, Secret: []byte{}},
+			OperationType: cli_service.TOperationType_EXECUTE_STATEMENT,
+			HasResultSet:  true,
+		},
+		DirectResults: directResults,
+	}, nil
+}
+
+// directResults returns the status of a submitted statement, with its metadata and first result chunk
+// when it finished
+func (c *RESTServiceClient) directResults(stmt *restStatement, resp *restStatementResponse) (*cli_service.TSparkDirectResults, error) {
+	status := c.updateStatement(stmt, resp)
+	directResults := &cli_service.TSparkDirectResults{OperationStatus: status}
+	if status.GetOperationState() != cli_service.TOperationState_FINISHED_STATE {
+		return directResults, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	directResults.ResultSetMetadata = restMetadata(stmt.manifest)
+	if stmt.result != nil {
+		resultSet, err := c.takeResult(stmt, stmt.result)
+		if err != nil {
+			return nil, err
+		}
+		stmt.result = nil
+		directResults.ResultSet = resultSet
+	}
+	return directResults, nil
+}
+
+// updateStatement keeps the manifest and the first result chunk of a statement response and returns its status
+func (c *RESTServiceClient) updateStatement(stmt *restStatement, resp *restStatementResponse) *cli_service.TGetOperationStatusResp {
+	c.mu.Lock()
+	if resp.Manifest != nil {
+		stmt.manifest = resp.Manifest
+	}
+	if resp.Result != nil && stmt.next != nil && *stmt.next == 0 {
+		stmt.result = resp.Result
+	}
+	c.mu.Unlock()
+
+	status := &cli_service.TGetOperationStatusResp{
+		Status:         restSuccess(),
+		OperationState: cli_service.TOperationStatePtr(restState(resp.Status.State)),
+		HasResultSet:   thrift.BoolPtr(true),
+	}
+	if resp.Status.Error != nil {
+		msg := resp.Status.Error.Message
+		if msg == "" {
+			msg = resp.Status.Error.ErrorCode
+		}
+		status.ErrorMessage = &msg
+	}
+	if rows, ok := restAffectedRows(resp); ok {
+		status.NumModifiedRows = &rows
+	}
+	return status
+}
+
+// restState returns the Thrift operation state of a statement state
+func restState(state string) cli_service.TOperationState {
+	switch state {
+	case "PENDING":
+		return cli_service.TOperationState_PENDING_STATE
+	case "RUNNING":
+		return cli_service.TOperationState_RUNNING_STATE
+	case "SUCCEEDED":
+		return cli_service.TOperationState_FINISHED_STATE
+	case "FAILED":
+		return cli_service.TOperationState_ERROR_STATE
+	case "CANCELED":
+		return cli_service.TOperationState_CANCELED_STATE
+	case "CLOSED":
+		return cli_service.TOperationState_CLOSED_STATE
+	default:
+		return cli_service.TOperationState_UKNOWN_STATE
+	}
+}
+
+// restAffectedRows returns the rows modified by a DML statement, which returns them in its first column
+func restAffectedRows(resp *restStatementResponse) (int64, bool) {
+	if resp.Manifest == nil || resp.Result == nil || len(resp.Result.DataArray) == 0 {
+		return 0, false
+	}
+	columns := resp.Manifest.Schema.Columns
+	row := resp.Result.DataArray[0]
+	if len(columns) == 0 || columns[0].Name != "num_affected_rows" || len(row) == 0 || row[0] == nil {
+		return 0, false
+	}
+	rows, err := strconv.ParseInt(*row[0], 10, 64)
+	return rows, err == nil
+}
+
+// GetOperationStatus requests the state of a statement
+func (c *RESTServiceClient) GetOperationStatus(ctx context.Context, req *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
+	stmt, err := c.statement(ctx, req.OperationHandle)
+	if err != nil {
+		return nil, err
+	}
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), stmt.id)
+	defer log.Duration(logger.Track("GetOperationStatus"))
+
+	var resp restStatementResponse
+	if err := c.do(ctx, http.MethodGet, restStatementsPath+"/"+stmt.id, nil, &resp); err != nil {
+		return nil, newRequestError(ctx, "get operation status request error", stmt.id, err)
+	}
+	return c.updateStatement(stmt, &resp), nil
+}
+
+// CancelOperation cancels a running statement
+func (c *RESTServiceClient) CancelOperation(ctx context.Context, req *cli_service.TCancelOperationReq) (*cli_service.TCancelOperationResp, error) {
+	stmt, err := c.statement(ctx, req.OperationHandle)
+	if err != nil {
+		return nil, err
+	}
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), stmt.id)
+	defer log.Duration(logger.Track("CancelOperation"))
+
+	if err := c.do(ctx, http.MethodPost, restStatementsPath+"/"+stmt.id+"/cancel", nil, nil); err != nil {
+		return nil, newRequestError(ctx, "cancel operation request error", stmt.id, err)
+	}
+	return &cli_service.TCancelOperationResp{Status: restSuccess()}, nil
+}
+
+// CloseOperation forgets a statement, its results expire on the server
+func (c *RESTServiceClient) CloseOperation(ctx context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+	c.mu.Lock()
+	delete(c.statements, string(req.GetOperationHandle().GetOperationId().GetGUID()))
+	c.mu.Unlock()
+	return &cli_service.TCloseOperationResp{Status: restSuccess()}, nil
+}
+
+// GetResultSetMetadata returns the schema of the results of a finished statement
+func (c *RESTServiceClient) GetResultSetMetadata(ctx context.Context, req *cli_service.TGetResultSetMetadataReq) (*cli_service.TGetResultSetMetadataResp, error) {
+	stmt, err := c.statement(ctx, req.OperationHandle)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	manifest := stmt.manifest
+	c.mu.Unlock()
+	if manifest == nil {
+		if _, err := c.GetOperationStatus(ctx, &cli_service.TGetOperationStatusReq{OperationHandle: req.OperationHandle}); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		manifest = stmt.manifest
+		c.mu.Unlock()
+	}
+	return restMetadata(manifest), nil
+}
+
+// FetchResults returns the next result chunk of a statement, or with FETCH_ABSOLUTE the chunk holding the
+// start row offset, which renews its external links
+func (c *RESTServiceClient) FetchResults(ctx context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+	stmt, err := c.statement(ctx, req.OperationHandle)
+	if err != nil {
+		return nil, err
+	}
+	log := logger.WithContext(driverctx.ConnIdFromContext(ctx), driverctx.CorrelationIdFromContext(ctx), stmt.id)
+	defer log.Duration(logger.Track("FetchResults"))
+
+	c.mu.Lock()
+	var chunkIndex *int64
+	switch req.Orientation {
+	case cli_service.TFetchOrientation_FETCH_NEXT:
+		if result := stmt.result; result != nil {
+			stmt.result = nil
+			defer c.mu.Unlock()
+			return c.takeResult(stmt, result)
+		}
+		if stmt.next != nil && (stmt.manifest == nil || *stmt.next < stmt.manifest.TotalChunkCount) {
+			chunkIndex = stmt.next
+		}
+	case cli_service.TFetchOrientation_FETCH_ABSOLUTE:
+		if stmt.manifest != nil {
+			for i := range stmt.manifest.Chunks {
+				chunk := stmt.manifest.Chunks[i]
+				if req.GetStartRowOffset() >= chunk.RowOffset && req.GetStartRowOffset() < chunk.RowOffset+chunk.RowCount {
+					chunkIndex = &chunk.ChunkIndex
+					break
+				}
+			}
+		}
+		if chunkIndex == nil {
+			c.mu.Unlock()
+			return nil, errors.Errorf("databricks: no result chunk holds row %d", req.GetStartRowOffset())
+		}
+	default:
+		c.mu.Unlock()
+		return nil, errors.Errorf("databricks: unsupported fetch orientation %s", req.Orientation)
+	}
+	c.mu.Unlock()
+
+	if chunkIndex == nil {
+		return &cli_service.TFetchResultsResp{
+			Status:      restSuccess(),
+			HasMoreRows: thrift.BoolPtr(false),
+			Results:     &cli_service.TRowSet{},
+		}, nil
+	}
+
+	var result restResult
+	path := fmt.Sprintf("%s/%s/result/chunks/%d", restStatementsPath, stmt.id, *chunkIndex)
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, newRequestError(ctx, "fetch results request error", stmt.id, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := stmt.next
+	resp, err := c.takeResult(stmt, &result)
+	if req.Orientation == cli_service.TFetchOrientation_FETCH_ABSOLUTE {
+		// fetching a chunk again doesn't move the statement to the chunk after it
+		stmt.next = next
+	}
+	return resp, err
+}
+
+// takeResult converts a result chunk of a statement to a Thrift row set and moves the statement to the
+// next chunk. c.mu must be held.
+func (c *RESTServiceClient) takeResult(stmt *restStatement, result *restResult) (*cli_service.TFetchResultsResp, error) {
+	rowSet := &cli_service.TRowSet{StartRowOffset: result.RowOffset}
+	next := result.NextChunkIndex
+	if len(result.ExternalLinks) > 0 {
+		rowSet.StartRowOffset = result.ExternalLinks[0].RowOffset
+		for _, link := range result.ExternalLinks {
+			var expiryTime int64
+			if t, err := time.Parse(time.RFC3339, link.Expiration); err == nil {
+				expiryTime = t.Unix()
+			}
+			rowSet.ResultLinks = append(rowSet.ResultLinks, &cli_service.TSparkArrowResultLink{
+				FileLink:       link.ExternalLink,
+				ExpiryTime:     expiryTime,
+				StartRowOffset: link.RowOffset,
+				RowCount:       link.RowCount,
+				BytesNum:       link.ByteCount,
+			})
+			next = link.NextChunkIndex
+		}
+	} else {
+		var columns []restColumn
+		if stmt.manifest != nil {
+			columns = stmt.manifest.Schema.Columns
+		}
+		for i := range columns {
+			column, err := restColumnValues(result.DataArray, i, restTypeID(columns[i]), c.cfg.Location)
+			if err != nil {
+				return nil, errors.Wrapf(err, "databricks: invalid value in column %s", columns[i].Name)
+			}
+			rowSet.Columns = append(rowSet.Columns, column)
+		}
+	}
+
+	stmt.next = next
+	return &cli_service.TFetchResultsResp{
+		Status:      restSuccess(),
+		HasMoreRows: thrift.BoolPtr(next != nil),
+		Results:     rowSet,
+	}, nil
+}
+
+// restMetadata returns the Thrift result set metadata of a statement manifest
+func restMetadata(manifest *restManifest) *cli_service.TGetResultSetMetadataResp {
+	schema := &cli_service.TTableSchema{}
+	if manifest != nil {
+		for _, column := range manifest.Schema.Columns {
+			entry := &cli_service.TPrimitiveTypeEntry{Type: restTypeID(column)}
+			if entry.Type == cli_service.TTypeId_DECIMAL_TYPE {
+				precision, scale := column.TypePrecision, column.TypeScale
+				entry.TypeQualifiers = &cli_service.TTypeQualifiers{Qualifiers: map[string]*cli_service.TTypeQualifierValue{
+					cli_service.PRECISION: {I32Value: &precision},
+					cli_service.SCALE:     {I32Value: &scale},
+				}}
+			}
+			schema.Columns = append(schema.Columns, &cli_service.TColumnDesc{
+				ColumnName: column.Name,
+				Position:   column.Position,
+				TypeDesc: &cli_service.TTypeDesc{
+					Types: []*cli_service.TTypeEntry{{PrimitiveEntry: entry}},
+				},
+			})
+		}
+	}
+	resultFormat := cli_service.TSparkRowSetType_COLUMN_BASED_SET
+	if manifest != nil && manifest.Format == "ARROW_STREAM" {
+		resultFormat = cli_service.TSparkRowSetType_URL_BASED_SET
+	}
+	return &cli_service.TGetResultSetMetadataResp{
+		Status:       restSuccess(),
+		Schema:       schema,
+		ResultFormat: &resultFormat,
+	}
+}
+
+// restTypeID returns the Thrift type of a column
+func restTypeID(column restColumn) cli_service.TTypeId {
+	switch column.TypeName {
+	case "BOOLEAN":
+		return cli_service.TTypeId_BOOLEAN_TYPE
+	case "BYTE":
+		return cli_service.TTypeId_TINYINT_TYPE
+	case "SHORT":
+		return cli_service.TTypeId_SMALLINT_TYPE
+	case "INT":
+		return cli_service.TTypeId_INT_TYPE
+	case "LONG":
+		return cli_service.TTypeId_BIGINT_TYPE
+	case "FLOAT":
+		return cli_service.TTypeId_FLOAT_TYPE
+	case "DOUBLE":
+		return cli_service.TTypeId_DOUBLE_TYPE
+	case "DECIMAL":
+		return cli_service.TTypeId_DECIMAL_TYPE
+	case "DATE":
+		return cli_service.TTypeId_DATE_TYPE
+	case "TIMESTAMP", "TIMESTAMP_NTZ":
+		return cli_service.TTypeId_TIMESTAMP_TYPE
+	case "BINARY":
+		return cli_service.TTypeId_BINARY_TYPE
+	case "CHAR":
+		return cli_service.TTypeId_CHAR_TYPE
+	case "ARRAY":
+		return cli_service.TTypeId_ARRAY_TYPE
+	case "MAP":
+		return cli_service.TTypeId_MAP_TYPE
+	case "STRUCT":
+		return cli_service.TTypeId_STRUCT_TYPE
+	case "INTERVAL":
+		if strings.Contains(strings.ToUpper(column.TypeText), "MONTH") || strings.Contains(strings.ToUpper(column.TypeText), "YEAR") {
+			return cli_service.TTypeId_INTERVAL_YEAR_MONTH_TYPE
+		}
+		return cli_service.TTypeId_INTERVAL_DAY_TIME_TYPE
+	case "NULL":
+		return cli_service.TTypeId_NULL_TYPE
+	case "USER_DEFINED_TYPE":
+		return cli_service.TTypeId_USER_DEFINED_TYPE
+	default:
+		return cli_service.TTypeId_STRING_TYPE
+	}
+}
+
+// restColumnValues returns the Thrift column of the values at index of the JSON rows, in the Go type the
+// Thrift columnar results hold for typeID. TIMESTAMP values are converted to the Thrift format in loc.
+func restColumnValues(data [][]*string, index int, typeID cli_service.TTypeId, loc *time.Location) (*cli_service.TColumn, error) {
+	nulls := make([]byte, (len(data)+7)/8)
+	column := &cli_service.TColumn{}
+	switch typeID {
+	case cli_service.TTypeId_BOOLEAN_TYPE:
+		column.BoolVal = &cli_service.TBoolColumn{Values: make([]bool, len(data)), Nulls: nulls}
+	case cli_service.TTypeId_TINYINT_TYPE:
+		column.ByteVal = &cli_service.TByteColumn{Values: make([]int8, len(data)), Nulls: nulls}
+	case cli_service.TTypeId_SMALLINT_TYPE:
+		column.I16Val = &cli_service.TI16Column{Values: make([]int16, len(data)), Nulls: nulls}
+	case cli_service.TTypeId_INT_TYPE:
+		column.I32Val = &cli_service.TI32Column{Values: make([]int32, len(data)), Nulls: nulls}
+	case cli_service.TTypeId_BIGINT_TYPE:
+		column.I64Val = &cli_service.TI64Column{Values: make([]int64, len(data)), Nulls: nulls}
+	case cli_service.TTypeId_FLOAT_TYPE, cli_service.TTypeId_DOUBLE_TYPE:
+		column.DoubleVal = &cli_service.TDoubleColumn{Values: make([]float64, len(data)), Nulls: nulls}
+	case cli_service.TTypeId_BINARY_TYPE:
+		column.BinaryVal = &cli_service.TBinaryColumn{Values: make([][]byte, len(data)), Nulls: nulls}
+	default:
+		column.StringVal = &cli_service.TStringColumn{Values: make([]string, len(data)), Nulls: nulls}
+	}
+
+	for i, row := range data {
+		if index >= len(row) || row[index] == nil {
+			nulls[i/8] |= 1 << (i % 8)
+			continue
+		}
+		s := *row[index]
+		var err error
+		var n int64
+		switch {
+		case column.BoolVal != nil:
+			column.BoolVal.Values[i], err = strconv.ParseBool(s)
+		case column.ByteVal != nil:
+			n, err = strconv.ParseInt(s, 10, 8)
+			column.ByteVal.Values[i] = int8(n)
+		case column.I16Val != nil:
+			n, err = strconv.ParseInt(s, 10, 16)
+			column.I16Val.Values[i] = int16(n)
+		case column.I32Val != nil:
+			n, err = strconv.ParseInt(s, 10, 32)
+			column.I32Val.Values[i] = int32(n)
+		case column.I64Val != nil:
+			column.I64Val.Values[i], err = strconv.ParseInt(s, 10, 64)
+		case column.DoubleVal != nil:
+			column.DoubleVal.Values[i], err = strconv.ParseFloat(s, 64)
+		case column.BinaryVal != nil:
+			column.BinaryVal.Values[i], err = base64.StdEncoding.DecodeString(s)
+		case typeID == cli_service.TTypeId_TIMESTAMP_TYPE:
+			column.StringVal.Values[i] = restTimestamp(s, loc)
+		default:
+			column.StringVal.Values[i] = s
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s value %q", typeID, s)
+		}
+	}
+	return column, nil
+}
+
+// restTimestamp converts an ISO 8601 timestamp to the format of the Thrift results, in loc when it has a
+// time zone
+func restTimestamp(s string, loc *time.Location) string {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		if loc == nil {
+			loc = time.UTC
+		}
+		return t.In(loc).Format(restTimestampFormat)
+	}
+	return strings.Replace(s, "T", " ", 1)
+}
+
+// restGuid returns the operation id of a statement, the bytes of its UUID
+func restGuid(statementID string) []byte {
+	guid, err := hex.DecodeString(strings.ReplaceAll(statementID, "-", ""))
+	if err != nil || len(guid) != 16 {
+		return []byte(statementID)
+	}
+	return guid
+}
+
+// statement returns the statement of an operation handle
+func (c *RESTServiceClient) statement(ctx context.Context, opHandle *cli_service.TOperationHandle) (*restStatement, error) {
+	guid := opHandle.GetOperationId().GetGUID()
+	c.mu.Lock()
+	stmt, ok := c.statements[string(guid)]
+	c.mu.Unlock()
+	if !ok {
+		return nil, requestErrorContext(ctx, SprintGuid(guid), errors.WithStack(&dbsqlerr.RequestError{
+			Msg:        "invalid OperationHandle",
+			StatusCode: cli_service.TStatusCode_INVALID_HANDLE_STATUS.String(),
+		}))
+	}
+	return stmt, nil
+}
+
+// do sends a request with the JSON of body, when not nil, and decodes the JSON response into result, when
+// not nil. Error responses are returned as request errors with their message.
+func (c *RESTServiceClient) do(ctx context.Context, method, path string, body, result any) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return errors.Wrap(err, "databricks: failed to encode request")
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, &reqBody)
+	if err != nil {
+		return errors.Wrap(err, "databricks: invalid request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent(c.cfg, driverctx.UserAgentTagFromContext(ctx)))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr restError
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		msg := resp.Status
+		if apiErr.Message != "" {
+			msg = apiErr.Message
+			if apiErr.ErrorCode != "" {
+				msg = apiErr.ErrorCode + ": " + msg
+			}
+		}
+		return errors.WithStack(&dbsqlerr.RequestError{Msg: msg, HTTPStatusCode: resp.StatusCode})
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return errors.Wrap(err, "databricks: invalid response")
+	}
+	return nil
+}
+
+func restSuccess() *cli_service.TStatus {
+	return &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS}
+}
+
+func (c *RESTServiceClient) GetTypeInfo(ctx context.Context, req *cli_service.TGetTypeInfoReq) (*cli_service.TGetTypeInfoResp, error) {
+	return nil, errRESTMetadata
+}
+
+func (c *RESTServiceClient) GetCatalogs(ctx context.Context, req *cli_service.TGetCatalogsReq) (*cli_service.TGetCatalogsResp, error) {
+	return nil, errRESTMetadata
+}
+
+func (c *RESTServiceClient) GetSchemas(ctx context.Context, req *cli_service.TGetSchemasReq) (*cli_service.TGetSchemasResp, error) {
+	return nil, errRESTMetadata
+}
+
+func (c *RESTServiceClient) GetTables(ctx context.Context, req *cli_service.TGetTablesReq) (*cli_service.TGetTablesResp, error) {
+	return nil, errRESTMetadata
+}
+
+func (c *RESTServiceClient) GetTableTypes(ctx context.Context, req *cli_service.TGetTableTypesReq) (*cli_service.TGetTableTypesResp, error) {
+	return nil, errRESTMetadata
+}
+
+func (c *RESTServiceClient) GetColumns(ctx context.Context, req *cli_service.TGetColumnsReq) (*cli_service.TGetColumnsResp, error) {
+	return nil, errRESTMetadata
+}
+
+func (c *RESTServiceClient) GetFunctions(ctx context.Context, req *cli_service.TGetFunctionsReq) (*cli_service.TGetFunctionsResp, error) {
+	return nil, errRESTMetadata
+}
+
+func (c *RESTServiceClient) GetPrimaryKeys(ctx context.Context, req *cli_service.TGetPrimaryKeysReq) (*cli_service.TGetPrimaryKeysResp, error) {
+	return nil, errRESTMetadata
+}
+
+func (c *RESTServiceClient) GetCrossReference(ctx context.Context, req *cli_service.TGetCrossReferenceReq) (*cli_service.TGetCrossReferenceResp, error) {
+	return nil, errRESTMetadata
+}
+
+func (c *RESTServiceClient) GetDelegationToken(ctx context.Context, req *cli_service.TGetDelegationTokenReq) (*cli_service.TGetDelegationTokenResp, error) {
+	return nil, ErrNotImplemented
+}
+
+func (c *RESTServiceClient) CancelDelegationToken(ctx context.Context, req *cli_service.TCancelDelegationTokenReq) (*cli_service.TCancelDelegationTokenResp, error) {
+	return nil, ErrNotImplemented
+}
+
+func (c *RESTServiceClient) RenewDelegationToken(ctx context.Context, req *cli_service.TRenewDelegationTokenReq) (*cli_service.TRenewDelegationTokenResp, error) {
+	return nil, ErrNotImplemented
+}
+
+var _ cli_service.TCLIService = (*RESTServiceClient)(nil)
@@ -0,0 +1,364 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth/pat"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const restStatementID = "01ed9db9-24c4-1cb6-a320-fb6ba623bdd2"
+
+// newTestRESTClient returns a REST client sending its requests to handler
+func newTestRESTClient(t *testing.T, handler http.HandlerFunc, options ...func(*config.Config)) *RESTServiceClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cfg := config.WithDefaults()
+	cfg.Protocol = "http"
+	cfg.Host = serverURL.Hostname()
+	cfg.Port, _ = strconv.Atoi(serverURL.Port())
+	cfg.HTTPPath = "/sql/1.0/warehouses/abc123"
+	for _, option := range options {
+		option(cfg)
+	}
+	c, err := InitRESTClient(cfg, server.Client())
+	require.NoError(t, err)
+	return c
+}
+
+// openTestSession opens a session of c in the main catalog and the sales schema
+func openTestSession(t *testing.T, c *RESTServiceClient) *cli_service.TSessionHandle {
+	catalog, schema := cli_service.TIdentifier("main"), cli_service.TIdentifier("sales")
+	session, err := c.OpenSession(context.Background(), &cli_service.TOpenSessionReq{
+		InitialNamespace: &cli_service.TNamespace{CatalogName: &catalog, SchemaName: &schema},
+	})
+	require.NoError(t, err)
+	return session.SessionHandle
+}
+
+func writeJSON(w http.ResponseWriter, v string) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(v))
+}
+
+const restTestManifest = `{
+	"format": "JSON_ARRAY",
+	"schema": {"columns": [
+		{"name": "id", "position": 0, "type_name": "LONG", "type_text": "BIGINT"},
+		{"name": "amount", "position": 1, "type_name": "DECIMAL", "type_text": "DECIMAL(10,2)", "type_precision": 10, "type_scale": 2},
+		{"name": "paid", "position": 2, "type_name": "BOOLEAN", "type_text": "BOOLEAN"},
+		{"name": "created", "position": 3, "type_name": "TIMESTAMP", "type_text": "TIMESTAMP"}
+	]},
+	"total_chunk_count": 2,
+	"chunks": [{"chunk_index": 0, "row_offset": 0, "row_count": 2}, {"chunk_index": 1, "row_offset": 2, "row_count": 1}]
+}`
+
+func TestRESTServiceClient(t *testing.T) {
+	t.Run("statements run in the namespace of their session and return the first chunk", func(t *testing.T) {
+		var body map[string]any
+		c := newTestRESTClient(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case restStatementsPath:
+				assert.Equal(t, http.MethodPost, r.Method)
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				writeJSON(w, `{
+					"statement_id": "`+restStatementID+`",
+					"status": {"state": "SUCCEEDED"},
+					"manifest": `+restTestManifest+`,
+					"result": {"chunk_index": 0, "row_offset": 0, "row_count": 2, "next_chunk_index": 1, "data_array": [
+						["1", "10.50", "true", "2023-01-30T22:23:23.140Z"],
+						["2", null, "false", null]
+					]}
+				}`)
+			case restStatementsPath + "/" + restStatementID + "/result/chunks/1":
+				writeJSON(w, `{"chunk_index": 1, "row_offset": 2, "row_count": 1, "data_array": [["3", "1.00", null, "2023-01-31T00:00:00Z"]]}`)
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}
+		})
+		name := "id"
+		value := "1"
+		sqlType := "BIGINT"
+		resp, err := c.ExecuteStatement(context.Background(), &cli_service.TExecuteStatementReq{
+			SessionHandle: openTestSession(t, c),
+			Statement:     "SELECT * FROM orders WHERE id >= :id",
+			Parameters:    []*cli_service.TSparkParameter{{Name: &name, Type: &sqlType, Value: &cli_service.TSparkParameterValue{StringValue: &value}}},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]any{
+			"warehouse_id":    "abc123",
+			"statement":       "SELECT * FROM orders WHERE id >= :id",
+			"catalog":         "main",
+			"schema":          "sales",
+			"parameters":      []any{map[string]any{"name": "id", "value": "1", "type": "BIGINT"}},
+			"disposition":     "INLINE",
+			"format":          "JSON_ARRAY",
+			"wait_timeout":    "10s",
+			"on_wait_timeout": "CONTINUE",
+		}, body)
+		assert.Equal(t, restStatementID, SprintGuid(resp.OperationHandle.OperationId.GUID))
+
+		direct := resp.DirectResults
+		assert.Equal(t, cli_service.TOperationState_FINISHED_STATE, direct.OperationStatus.GetOperationState())
+		columns := direct.ResultSetMetadata.Schema.Columns
+		require.Len(t, columns, 4)
+		assert.Equal(t, "amount", columns[1].ColumnName)
+		amountType := columns[1].TypeDesc.Types[0].PrimitiveEntry
+		assert.Equal(t, cli_service.TTypeId_DECIMAL_TYPE, amountType.Type)
+		assert.Equal(t, int32(10), amountType.TypeQualifiers.Qualifiers[cli_service.PRECISION].GetI32Value())
+		assert.Equal(t, cli_service.TTypeId_TIMESTAMP_TYPE, columns[3].TypeDesc.Types[0].PrimitiveEntry.Type)
+
+		results := direct.ResultSet.Results
+		assert.True(t, direct.ResultSet.GetHasMoreRows())
+		assert.Equal(t, int64(0), results.StartRowOffset)
+		assert.Equal(t, []int64{1, 2}, results.Columns[0].I64Val.Values)
+		assert.Equal(t, []string{"10.50", ""}, results.Columns[1].StringVal.Values)
+		assert.Equal(t, []byte{2}, results.Columns[1].StringVal.Nulls)
+		assert.Equal(t, []bool{true, false}, results.Columns[2].BoolVal.Values)
+		assert.Equal(t, "2023-01-30 22:23:23.14", results.Columns[3].StringVal.Values[0])
+
+		next, err := c.FetchResults(context.Background(), &cli_service.TFetchResultsReq{
+			OperationHandle: resp.OperationHandle,
+			Orientation:     cli_service.TFetchOrientation_FETCH_NEXT,
+		})
+		require.NoError(t, err)
+		assert.False(t, next.GetHasMoreRows())
+		assert.Equal(t, int64(2), next.Results.StartRowOffset)
+		assert.Equal(t, []int64{3}, next.Results.Columns[0].I64Val.Values)
+		assert.Equal(t, []byte{1}, next.Results.Columns[2].BoolVal.Nulls)
+
+		last, err := c.FetchResults(context.Background(), &cli_service.TFetchResultsReq{
+			OperationHandle: resp.OperationHandle,
+			Orientation:     cli_service.TFetchOrientation_FETCH_NEXT,
+		})
+		require.NoError(t, err)
+		assert.False(t, last.GetHasMoreRows())
+		assert.Empty(t, last.Results.Columns)
+
+		_, err = c.CloseOperation(context.Background(), &cli_service.TCloseOperationReq{OperationHandle: resp.OperationHandle})
+		require.NoError(t, err)
+		_, err = c.GetOperationStatus(context.Background(), &cli_service.TGetOperationStatusReq{OperationHandle: resp.OperationHandle})
+		var reqErr *dbsqlerr.RequestError
+		require.True(t, errors.As(err, &reqErr))
+		assert.Equal(t, cli_service.TStatusCode_INVALID_HANDLE_STATUS.String(), reqErr.StatusCode)
+	})
+
+	t.Run("running statements are polled and canceled", func(t *testing.T) {
+		var canceled bool
+		c := newTestRESTClient(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case restStatementsPath:
+				writeJSON(w, `{"statement_id": "`+restStatementID+`", "status": {"state": "RUNNING"}}`)
+			case restStatementsPath + "/" + restStatementID:
+				writeJSON(w, `{"statement_id": "`+restStatementID+`", "status": {"state": "FAILED", "error": {"error_code": "BAD_REQUEST", "message": "[TABLE_OR_VIEW_NOT_FOUND] missing"}}}`)
+			case restStatementsPath + "/" + restStatementID + "/cancel":
+				assert.Equal(t, http.MethodPost, r.Method)
+				canceled = true
+				writeJSON(w, `{}`)
+			}
+		})
+
+		resp, err := c.ExecuteStatement(context.Background(), &cli_service.TExecuteStatementReq{SessionHandle: openTestSession(t, c), Statement: "SELECT * FROM missing"})
+		require.NoError(t, err)
+		assert.Equal(t, cli_service.TOperationState_RUNNING_STATE, resp.DirectResults.OperationStatus.GetOperationState())
+		assert.Nil(t, resp.DirectResults.ResultSet)
+
+		status, err := c.GetOperationStatus(context.Background(), &cli_service.TGetOperationStatusReq{OperationHandle: resp.OperationHandle})
+		require.NoError(t, err)
+		assert.Equal(t, cli_service.TOperationState_ERROR_STATE, status.GetOperationState())
+		assert.Equal(t, "[TABLE_OR_VIEW_NOT_FOUND] missing", status.GetErrorMessage())
+
+		_, err = c.CancelOperation(context.Background(), &cli_service.TCancelOperationReq{OperationHandle: resp.OperationHandle})
+		require.NoError(t, err)
+		assert.True(t, canceled)
+	})
+
+	t.Run("cloud fetch results are external links", func(t *testing.T) {
+		var body map[string]any
+		var chunkRequests int
+		c := newTestRESTClient(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case restStatementsPath:
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				writeJSON(w, `{
+					"statement_id": "`+restStatementID+`",
+					"status": {"state": "SUCCEEDED"},
+					"manifest": {"format": "ARROW_STREAM", "schema": {"columns": [{"name": "id", "position": 0, "type_name": "LONG"}]}, "total_chunk_count": 2,
+						"chunks": [{"chunk_index": 0, "row_offset": 0, "row_count": 100}, {"chunk_index": 1, "row_offset": 100, "row_count": 50}]},
+					"result": {"external_links": [{"chunk_index": 0, "row_offset": 0, "row_count": 100, "byte_count": 800,
+						"external_link": "https://storage/0", "expiration": "2030-01-01T00:00:00Z", "next_chunk_index": 1}]}
+				}`)
+			case restStatementsPath + "/" + restStatementID + "/result/chunks/0":
+				chunkRequests++
+				writeJSON(w, `{"external_links": [{"chunk_index": 0, "row_offset": 0, "row_count": 100, "byte_count": 800,
+					"external_link": "https://storage/0-renewed", "expiration": "2030-01-01T00:00:00Z", "next_chunk_index": 1}]}`)
+			case restStatementsPath + "/" + restStatementID + "/result/chunks/1":
+				chunkRequests++
+				writeJSON(w, `{"external_links": [{"chunk_index": 1, "row_offset": 100, "row_count": 50, "byte_count": 400,
+					"external_link": "https://storage/1", "expiration": "2030-01-01T00:00:00Z"}]}`)
+			}
+		}, func(cfg *config.Config) { cfg.UseCloudFetch = true })
+
+		resp, err := c.ExecuteStatement(context.Background(), &cli_service.TExecuteStatementReq{SessionHandle: openTestSession(t, c), Statement: "SELECT * FROM events"})
+		require.NoError(t, err)
+		assert.Equal(t, "EXTERNAL_LINKS", body["disposition"])
+		assert.Equal(t, "ARROW_STREAM", body["format"])
+		assert.Equal(t, []*cli_service.TSparkArrowResultLink{{
+			FileLink:       "https://storage/0",
+			ExpiryTime:     time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+			StartRowOffset: 0,
+			RowCount:       100,
+			BytesNum:       800,
+		}}, resp.DirectResults.ResultSet.Results.ResultLinks)
+		assert.True(t, resp.DirectResults.ResultSet.GetHasMoreRows())
+
+		// an expired link is renewed by fetching its rows again
+		startRowOffset := int64(10)
+		renewed, err := c.FetchResults(context.Background(), &cli_service.TFetchResultsReq{
+			OperationHandle: resp.OperationHandle,
+			Orientation:     cli_service.TFetchOrientation_FETCH_ABSOLUTE,
+			StartRowOffset:  &startRowOffset,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "https://storage/0-renewed", renewed.Results.ResultLinks[0].FileLink)
+
+		next, err := c.FetchResults(context.Background(), &cli_service.TFetchResultsReq{
+			OperationHandle: resp.OperationHandle,
+			Orientation:     cli_service.TFetchOrientation_FETCH_NEXT,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), next.Results.StartRowOffset)
+		assert.Equal(t, "https://storage/1", next.Results.ResultLinks[0].FileLink)
+		assert.False(t, next.GetHasMoreRows())
+		assert.Equal(t, 2, chunkRequests)
+	})
+
+	t.Run("the namespace of a statement overrides the namespace of its session", func(t *testing.T) {
+		var body map[string]any
+		c := newTestRESTClient(t, func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			writeJSON(w, `{"statement_id": "`+restStatementID+`", "status": {"state": "SUCCEEDED"},
+				"manifest": {"format": "JSON_ARRAY", "schema": {"columns": []}, "total_chunk_count": 0}}`)
+		})
+		catalog := "tenant_a"
+		_, err := c.ExecuteStatement(context.Background(), &cli_service.TExecuteStatementReq{
+			SessionHandle: openTestSession(t, c),
+			Statement:     "SELECT * FROM orders",
+			SessionConf:   &cli_service.TDBSqlSessionConf{CurrentCatalog: &catalog},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "tenant_a", body["catalog"])
+		assert.Equal(t, "sales", body["schema"])
+	})
+
+	t.Run("error responses are request errors", func(t *testing.T) {
+		c := newTestRESTClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			writeJSON(w, `{"error_code": "RESOURCE_DOES_NOT_EXIST", "message": "No warehouse found"}`)
+		})
+		_, err := c.ExecuteStatement(context.Background(), &cli_service.TExecuteStatementReq{SessionHandle: openTestSession(t, c), Statement: "SELECT 1"})
+		var reqErr *dbsqlerr.RequestError
+		require.True(t, errors.As(err, &reqErr))
+		assert.Equal(t, http.StatusNotFound, reqErr.HTTPStatusCode)
+		assert.ErrorContains(t, err, "RESOURCE_DOES_NOT_EXIST: No warehouse found")
+	})
+
+	t.Run("positional parameters and closed sessions are rejected", func(t *testing.T) {
+		c := newTestRESTClient(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		})
+		session := openTestSession(t, c)
+		ordinal := int32(1)
+		_, err := c.ExecuteStatement(context.Background(), &cli_service.TExecuteStatementReq{
+			SessionHandle: session,
+			Statement:     "SELECT ?",
+			Parameters:    []*cli_service.TSparkParameter{{Ordinal: &ordinal}},
+		})
+		assert.ErrorContains(t, err, "only supports named parameters")
+
+		_, err = c.CloseSession(context.Background(), &cli_service.TCloseSessionReq{SessionHandle: session})
+		require.NoError(t, err)
+		_, err = c.ExecuteStatement(context.Background(), &cli_service.TExecuteStatementReq{SessionHandle: session, Statement: "SELECT 1"})
+		var reqErr *dbsqlerr.RequestError
+		require.True(t, errors.As(err, &reqErr))
+		assert.Equal(t, cli_service.TStatusCode_INVALID_HANDLE_STATUS.String(), reqErr.StatusCode)
+	})
+}
+
+func TestRESTWarehouseID(t *testing.T) {
+	for path, id := range map[string]string{
+		"/sql/1.0/warehouses/abc123": "abc123",
+		"sql/1.0/endpoints/def456/":  "def456",
+		"/sql/protocolv1/o/1/0123":   "",
+		"":                           "",
+	} {
+		got, err := restWarehouseID(path)
+		assert.Equal(t, id, got, path)
+		assert.Equal(t, id == "", err != nil, path)
+	}
+}
+
+func TestInitRESTClient(t *testing.T) {
+	cfg := config.WithDefaults()
+	cfg.HTTPPath = "/sql/1.0/warehouses/abc123"
+	cfg.Authenticator = &pat.PATAuth{AccessToken: "token"}
+	_, err := InitRESTClient(cfg, nil)
+	require.NoError(t, err)
+
+	cfg.StatementConf = func(ctx context.Context) map[string]string { return nil }
+	_, err = InitRESTClient(cfg, nil)
+	assert.ErrorContains(t, err, "not supported by the Statement Execution API")
+
+	cfg.StatementConf = nil
+	cfg.StatementTags = map[string]string{"service": "billing"}
+	_, err = InitRESTClient(cfg, nil)
+	assert.ErrorContains(t, err, "query tags are not supported by the Statement Execution API")
+}
+
+func TestGetQueryMetrics(t *testing.T) {
+	finished := false
+	c := newTestRESTClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, queryHistoryPath, r.URL.Path)
+		assert.Equal(t, restStatementID, r.URL.Query().Get("filter_by.statement_ids"))
+		assert.Equal(t, "true", r.URL.Query().Get("include_metrics"))
+		if !finished {
+			writeJSON(w, `{"res": []}`)
+			return
+		}
+		writeJSON(w, `{"res": [{"query_id": "`+restStatementID+`", "status": "FINISHED", "metrics": {
+			"rows_read_count": 1000, "read_bytes": 65536, "rows_produced_count": 10,
+			"task_total_time_ms": 2500, "execution_time_ms": 800, "total_time_ms": 900}}]}`)
+	})
+
+	metrics, err := GetQueryMetrics(context.Background(), c.cfg, c.client, restStatementID)
+	require.NoError(t, err)
+	assert.Nil(t, metrics, "the query is not in the history yet")
+
+	finished = true
+	metrics, err = GetQueryMetrics(context.Background(), c.cfg, c.client, restStatementID)
+	require.NoError(t, err)
+	assert.Equal(t, &QueryMetrics{
+		RowsReadCount:     1000,
+		ReadBytes:         65536,
+		RowsProducedCount: 10,
+		TaskTotalTimeMs:   2500,
+		ExecutionTimeMs:   800,
+		TotalTimeMs:       900,
+	}, metrics)
+}
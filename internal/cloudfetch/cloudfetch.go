@@ -0,0 +1,359 @@
+// Package cloudfetch downloads result files from the presigned cloud storage links
+// returned by the server for large results, in parallel and optionally bandwidth limited.
+// Interrupted downloads are retried, expired links renewed and the files are checked
+// against the checksums sent by the storage.
+package cloudfetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/metrics"
+	"github.com/pkg/errors"
+)
+
+var errDownloadFailed = "databricks: cloud fetch download failed with status %s"
+var errLinkExpired = "databricks: cloud fetch result link has expired"
+var errChecksumMismatch = "databricks: cloud fetch download does not match its checksum"
+
+const (
+	DefaultMaxDownloads    = 10
+	DefaultMinTimeToExpiry = 10 * time.Second
+	DefaultMaxRetries      = 3
+	DefaultRetryWaitMin    = 1 * time.Second
+	DefaultRetryWaitMax    = 30 * time.Second
+	// how much is read between two bandwidth limit checks
+	readChunkSize = 32 * 1024
+)
+
+// Config holds the download settings.
+type Config struct {
+	MaxDownloads    int           // max number of concurrent downloads
+	BandwidthLimit  int64         // max bytes per second over all downloads, 0 is unlimited
+	MinTimeToExpiry time.Duration // links expiring sooner are refreshed before downloading
+	MaxRetries      int           // times a link is downloaded again after an interrupted transfer or a checksum mismatch, negative disables retries
+	RetryWaitMin    time.Duration // wait before the first retry of a link, doubled for each following retry
+	RetryWaitMax    time.Duration // max wait between two retries of a link
+	HTTPClient      *http.Client
+	Metrics         metrics.Collector // receives the downloaded bytes, retries and throughput, may be nil
+}
+
+// RefreshFunc returns a new link for the rows of an expired link.
+type RefreshFunc func(ctx context.Context, link *cli_service.TSparkArrowResultLink) (*cli_service.TSparkArrowResultLink, error)
+
+// Downloader downloads result files. The bandwidth limit and the max number of concurrent
+// downloads apply to all downloads of a Downloader.
+type Downloader struct {
+	cfg       Config
+	refresh   RefreshFunc
+	limiter   *limiter
+	slots     chan struct{} // one slot per running download
+	refreshMx sync.Mutex
+}
+
+// NewDownloader returns a downloader using cfg. Expired links are renewed with refresh,
+// when refresh is nil downloading an expired link fails.
+func NewDownloader(cfg Config, refresh RefreshFunc) *Downloader {
+	if cfg.MaxDownloads <= 0 {
+		cfg.MaxDownloads = DefaultMaxDownloads
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if cfg.RetryWaitMin <= 0 {
+		cfg.RetryWaitMin = DefaultRetryWaitMin
+	}
+	if cfg.RetryWaitMax < cfg.RetryWaitMin {
+		cfg.RetryWaitMax = DefaultRetryWaitMax
+		if cfg.RetryWaitMax < cfg.RetryWaitMin {
+			cfg.RetryWaitMax = cfg.RetryWaitMin
+		}
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	d := &Downloader{cfg: cfg, refresh: refresh, slots: make(chan struct{}, cfg.MaxDownloads)}
+	if cfg.BandwidthLimit > 0 {
+		d.limiter = &limiter{bytesPerSecond: cfg.BandwidthLimit}
+	}
+	return d
+}
+
+// Download fetches the files of links in parallel and returns their contents in the order of links.
+// The first failed download cancels the others.
+func (d *Downloader) Download(ctx context.Context, links []*cli_service.TSparkArrowResultLink) ([][]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	files := make([][]byte, len(links))
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+
+	for i := range links {
+		select {
+		case d.slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-d.slots
+				wg.Done()
+			}()
+
+			data, err := d.downloadLink(ctx, links[i])
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			files[i] = data
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// downloadLink downloads the file of link, refreshing the link once when it
+// is about to expire or the storage rejects it as expired, and retrying
+// interrupted transfers and checksum mismatches
+func (d *Downloader) downloadLink(ctx context.Context, link *cli_service.TSparkArrowResultLink) ([]byte, error) {
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		if d.isExpiring(link) && !refreshed {
+			var err error
+			if link, err = d.refreshLink(ctx, link); err != nil {
+				return nil, err
+			}
+			refreshed = true
+		}
+
+		data, err := d.get(ctx, link.FileLink)
+		if errors.Is(err, errForbidden) && !refreshed {
+			if link, err = d.refreshLink(ctx, link); err != nil {
+				return nil, err
+			}
+			refreshed = true
+			data, err = d.get(ctx, link.FileLink)
+		}
+		if err == nil || !errors.Is(err, errRetryable) || attempt >= d.cfg.MaxRetries || ctx.Err() != nil {
+			return data, err
+		}
+
+		metrics.Counter(d.cfg.Metrics, metrics.Retries, 1)
+		if err := d.wait(ctx, attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait sleeps before retrying a download for the attempt-th time
+func (d *Downloader) wait(ctx context.Context, attempt int) error {
+	wait := d.cfg.RetryWaitMin
+	for i := 0; i < attempt && wait < d.cfg.RetryWaitMax; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.RetryWaitMax {
+		wait = d.cfg.RetryWaitMax
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Downloader) isExpiring(link *cli_service.TSparkArrowResultLink) bool {
+	if link.ExpiryTime == 0 {
+		return false
+	}
+	minTimeToExpiry := d.cfg.MinTimeToExpiry
+	if minTimeToExpiry <= 0 {
+		minTimeToExpiry = DefaultMinTimeToExpiry
+	}
+	return time.Unix(link.ExpiryTime, 0).Before(time.Now().Add(minTimeToExpiry))
+}
+
+func (d *Downloader) refreshLink(ctx context.Context, link *cli_service.TSparkArrowResultLink) (*cli_service.TSparkArrowResultLink, error) {
+	if d.refresh == nil {
+		return nil, errors.New(errLinkExpired)
+	}
+
+	// refreshing goes through the Thrift client, one request at a time
+	d.refreshMx.Lock()
+	defer d.refreshMx.Unlock()
+
+	newLink, err := d.refresh(ctx, link)
+	if err != nil {
+		return nil, errors.Wrap(err, errLinkExpired)
+	}
+	return newLink, nil
+}
+
+// errForbidden is returned for the 403 responses cloud storage sends for expired links
+var errForbidden = errors.New("forbidden")
+
+// errRetryable is returned when the download of a file can be tried again: the transfer
+// was interrupted or the file doesn't match its checksum. The HTTP client already retries
+// the requests failing before the response.
+var errRetryable = errors.New("retryable")
+
+func (d *Downloader) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "databricks: invalid cloud fetch link")
+	}
+
+	res, err := d.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "databricks: cloud fetch download failed")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusForbidden {
+		return nil, errors.Wrapf(errForbidden, errDownloadFailed, res.Status)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf(errDownloadFailed, res.Status)
+	}
+
+	start := time.Now()
+	var body io.Reader = res.Body
+	if d.limiter != nil {
+		body = &limitedReader{ctx: ctx, r: body, limiter: d.limiter}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		if ctx.Err() == nil {
+			err = &retryableError{err}
+		}
+		return nil, errors.Wrap(err, "databricks: cloud fetch download failed")
+	}
+	metrics.Counter(d.cfg.Metrics, metrics.BytesDownloaded, float64(len(data)))
+	// the checksum is the one of the encoded file, which the transport decoded
+	if !res.Uncompressed {
+		if err := verifyChecksum(res.Header, data); err != nil {
+			return nil, err
+		}
+	}
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 && d.cfg.Metrics != nil {
+		d.cfg.Metrics.Histogram(metrics.DownloadThroughput, float64(len(data))/elapsed)
+	}
+	return data, nil
+}
+
+// retryableError marks an error as retryable, keeping its message
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string        { return e.err.Error() }
+func (e *retryableError) Unwrap() error        { return e.err }
+func (e *retryableError) Is(target error) bool { return target == errRetryable }
+
+// verifyChecksum compares data with the MD5 checksum sent by the storage in the
+// Content-MD5 header (Azure, S3 when requested) or the x-goog-hash header (GCS).
+// Files without a checksum are not verified.
+func verifyChecksum(header http.Header, data []byte) error {
+	checksum := header.Get("Content-MD5")
+	if checksum == "" {
+		for _, hashes := range header.Values("X-Goog-Hash") {
+			for _, hash := range strings.Split(hashes, ",") {
+				if name, value, ok := strings.Cut(strings.TrimSpace(hash), "="); ok && name == "md5" {
+					checksum = value
+				}
+			}
+		}
+	}
+	if checksum == "" {
+		return nil
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil {
+		// not a checksum the driver understands
+		return nil
+	}
+	sum := md5.Sum(data)
+	if !bytes.Equal(expected, sum[:]) {
+		return errors.Wrap(&retryableError{errors.New(errChecksumMismatch)}, errChecksumMismatch)
+	}
+	return nil
+}
+
+// limiter spaces reads out so their total rate stays under bytesPerSecond
+type limiter struct {
+	bytesPerSecond int64
+
+	mx   sync.Mutex
+	next time.Time // when the next read may start
+}
+
+// wait blocks until the bandwidth for n more bytes is available
+func (l *limiter) wait(ctx context.Context, n int) error {
+	l.mx.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.bytesPerSecond))
+	l.mx.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *limiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > readChunkSize {
+		p = p[:readChunkSize]
+	}
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		if waitErr := lr.limiter.wait(lr.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,206 @@
+package cloudfetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloader(t *testing.T) {
+	var mx sync.Mutex
+	var current, maxConcurrent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		current++
+		if current > maxConcurrent {
+			maxConcurrent = current
+		}
+		mx.Unlock()
+		defer func() {
+			mx.Lock()
+			current--
+			mx.Unlock()
+		}()
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/expired"):
+			w.WriteHeader(http.StatusForbidden)
+		case strings.HasPrefix(r.URL.Path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			time.Sleep(10 * time.Millisecond)
+			fmt.Fprint(w, "data"+r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	link := func(path string, startRowOffset int64) *cli_service.TSparkArrowResultLink {
+		return &cli_service.TSparkArrowResultLink{
+			FileLink:       server.URL + path,
+			ExpiryTime:     time.Now().Add(time.Hour).Unix(),
+			StartRowOffset: startRowOffset,
+		}
+	}
+
+	t.Run("downloads links in parallel and keeps their order", func(t *testing.T) {
+		mx.Lock()
+		maxConcurrent = 0
+		mx.Unlock()
+
+		links := make([]*cli_service.TSparkArrowResultLink, 8)
+		for i := range links {
+			links[i] = link(fmt.Sprintf("/%d", i), int64(i))
+		}
+		files, err := NewDownloader(Config{MaxDownloads: 3}, nil).Download(context.Background(), links)
+		require.NoError(t, err)
+		require.Len(t, files, len(links))
+		for i := range files {
+			assert.Equal(t, fmt.Sprintf("data/%d", i), string(files[i]))
+		}
+		assert.LessOrEqual(t, maxConcurrent, 3)
+		assert.Greater(t, maxConcurrent, 1)
+	})
+
+	t.Run("counts downloaded bytes", func(t *testing.T) {
+		collector := &bytesCollector{}
+		_, err := NewDownloader(Config{Metrics: collector}, nil).Download(context.Background(), []*cli_service.TSparkArrowResultLink{link("/a", 0), link("/bc", 1)})
+		require.NoError(t, err)
+		assert.Equal(t, float64(len("data/a")+len("data/bc")), collector.bytes)
+	})
+
+	t.Run("refreshes links about to expire", func(t *testing.T) {
+		expiring := link("/old", 10)
+		expiring.ExpiryTime = time.Now().Add(time.Second).Unix()
+		var refreshed int32
+		refresh := func(ctx context.Context, l *cli_service.TSparkArrowResultLink) (*cli_service.TSparkArrowResultLink, error) {
+			atomic.AddInt32(&refreshed, 1)
+			assert.Equal(t, int64(10), l.StartRowOffset)
+			return link("/new", 10), nil
+		}
+
+		files, err := NewDownloader(Config{}, refresh).Download(context.Background(), []*cli_service.TSparkArrowResultLink{expiring, link("/other", 20)})
+		require.NoError(t, err)
+		assert.Equal(t, "data/new", string(files[0]))
+		assert.Equal(t, "data/other", string(files[1]))
+		assert.Equal(t, int32(1), refreshed)
+	})
+
+	t.Run("refreshes links rejected as expired", func(t *testing.T) {
+		refresh := func(ctx context.Context, l *cli_service.TSparkArrowResultLink) (*cli_service.TSparkArrowResultLink, error) {
+			return link("/new", l.StartRowOffset), nil
+		}
+		files, err := NewDownloader(Config{}, refresh).Download(context.Background(), []*cli_service.TSparkArrowResultLink{link("/expired", 0)})
+		require.NoError(t, err)
+		assert.Equal(t, "data/new", string(files[0]))
+
+		_, err = NewDownloader(Config{}, nil).Download(context.Background(), []*cli_service.TSparkArrowResultLink{link("/expired", 0)})
+		assert.ErrorContains(t, err, errLinkExpired)
+	})
+
+	t.Run("fails on download errors", func(t *testing.T) {
+		_, err := NewDownloader(Config{}, nil).Download(context.Background(), []*cli_service.TSparkArrowResultLink{link("/1", 0), link("/missing", 1)})
+		assert.ErrorContains(t, err, "404")
+	})
+
+	t.Run("limits bandwidth", func(t *testing.T) {
+		payload := bytes.Repeat([]byte("x"), 4000)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(payload)
+		}))
+		defer server.Close()
+
+		links := []*cli_service.TSparkArrowResultLink{{FileLink: server.URL}, {FileLink: server.URL}}
+		start := time.Now()
+		files, err := NewDownloader(Config{BandwidthLimit: 20000}, nil).Download(context.Background(), links)
+		require.NoError(t, err)
+		assert.Equal(t, payload, files[1])
+		// 8000 bytes at 20000 bytes per second, less the first read which isn't delayed
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	})
+
+	t.Run("retries interrupted downloads and checksum mismatches", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&calls, 1) {
+			case 1:
+				// the connection is closed before the announced length is sent
+				w.Header().Set("Content-Length", "100")
+				fmt.Fprint(w, "partial")
+			case 2:
+				w.Header().Set("Content-MD5", checksum("other"))
+				fmt.Fprint(w, "data")
+			default:
+				w.Header().Set("X-Goog-Hash", "crc32c=n03x6A==, md5="+checksum("data"))
+				fmt.Fprint(w, "data")
+			}
+		}))
+		defer server.Close()
+
+		collector := &bytesCollector{}
+		d := NewDownloader(Config{RetryWaitMin: time.Millisecond, Metrics: collector}, nil)
+		files, err := d.Download(context.Background(), []*cli_service.TSparkArrowResultLink{{FileLink: server.URL}})
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(files[0]))
+		assert.Equal(t, int32(3), calls)
+		assert.Equal(t, float64(2), collector.retries)
+		assert.Equal(t, 1, collector.throughputs)
+
+		atomic.StoreInt32(&calls, 1)
+		d = NewDownloader(Config{MaxRetries: -1}, nil)
+		_, err = d.Download(context.Background(), []*cli_service.TSparkArrowResultLink{{FileLink: server.URL}})
+		assert.ErrorContains(t, err, errChecksumMismatch)
+	})
+
+	t.Run("does not retry missing files", func(t *testing.T) {
+		collector := &bytesCollector{}
+		d := NewDownloader(Config{RetryWaitMin: time.Millisecond, Metrics: collector}, nil)
+		_, err := d.Download(context.Background(), []*cli_service.TSparkArrowResultLink{link("/missing", 0)})
+		assert.ErrorContains(t, err, "404")
+		assert.Zero(t, collector.retries)
+	})
+}
+
+func checksum(data string) string {
+	sum := md5.Sum([]byte(data))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+type bytesCollector struct {
+	mx          sync.Mutex
+	bytes       float64
+	retries     float64
+	throughputs int
+}
+
+func (c *bytesCollector) Counter(name string, value float64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	switch name {
+	case metrics.BytesDownloaded:
+		c.bytes += value
+	case metrics.Retries:
+		c.retries += value
+	}
+}
+func (c *bytesCollector) Histogram(name string, value float64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if name == metrics.DownloadThroughput && value > 0 {
+		c.throughputs++
+	}
+}
+func (c *bytesCollector) Gauge(name string, delta float64) {}
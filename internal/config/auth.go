@@ -0,0 +1,141 @@
+package config
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/auth"
+	"github.com/databricks/databricks-sql-go/auth/azure"
+	"github.com/databricks/databricks-sql-go/auth/chain"
+	"github.com/databricks/databricks-sql-go/auth/gcp"
+	"github.com/databricks/databricks-sql-go/auth/oauth/m2m"
+	"github.com/databricks/databricks-sql-go/auth/oauth/tokencache"
+	"github.com/databricks/databricks-sql-go/auth/oauth/u2m"
+	"github.com/pkg/errors"
+)
+
+// max time to wait for the user to complete a browser login
+var defaultOAuthLoginTimeout = 2 * time.Minute
+
+// DSN parameters used to set up authentication, they are never passed on as session params
+var authParams = []string{
+	"authType",
+	"clientId",
+	"clientSecret",
+	"azureTenantId",
+	"azureClientCertificate",
+	"gcpCredentials",
+	"tokenCache",
+	"tokenCachePath",
+	"tokenCacheKey",
+}
+
+// parseAuthParams sets up the authenticator selected by the authType DSN parameter
+// and removes all authentication parameters from params.
+func parseAuthParams(ucfg *UserConfig, params url.Values) error {
+	defer func() {
+		for _, p := range authParams {
+			params.Del(p)
+		}
+	}()
+
+	if err := parseTokenCacheParams(ucfg, params); err != nil {
+		return err
+	}
+
+	if !params.Has("authType") {
+		return nil
+	}
+
+	authType, err := auth.ParseAuthType(params.Get("authType"))
+	if err != nil {
+		return errors.Wrap(err, "invalid DSN: invalid authType")
+	}
+
+	var authr auth.Authenticator
+	switch authType {
+	case auth.AuthTypePat:
+		if ucfg.AccessToken == "" {
+			return errors.New("invalid DSN: authType pat requires a token")
+		}
+		return nil
+	case auth.AuthTypeOauthU2M:
+		authr, err = u2mAuthenticator(ucfg)
+	case auth.AuthTypeOauthM2M:
+		clientID, clientSecret := params.Get("clientId"), params.Get("clientSecret")
+		if clientID == "" || clientSecret == "" {
+			return errors.New("invalid DSN: authType oauth-m2m requires clientId and clientSecret")
+		}
+		authr = m2m.NewAuthenticator(clientID, clientSecret, ucfg.Host)
+	case auth.AuthTypeAzureServicePrincipal:
+		authr, err = azureServicePrincipal(params)
+	case auth.AuthTypeAzureManagedIdentity:
+		// clientId selects a user-assigned identity and is optional
+		authr = azure.NewManagedIdentityAuthenticator(params.Get("clientId"))
+	case auth.AuthTypeGCP:
+		// without gcpCredentials the ambient identity of the host is used
+		authr, err = gcp.NewAuthenticator(ucfg.Host, params.Get("gcpCredentials"))
+	case auth.AuthTypeDefault:
+		authr = chain.NewAuthenticator(ucfg.Host)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "invalid DSN: unable to create %s authenticator", authType)
+	}
+
+	ucfg.AccessToken = ""
+	ucfg.Authenticator = authr
+	return nil
+}
+
+// parseTokenCacheParams enables the OAuth token cache with tokenCache=true or tokenCachePath=<path>,
+// tokenCacheKey is the base64 encoded key used to encrypt the cache file.
+func parseTokenCacheParams(ucfg *UserConfig, params url.Values) error {
+	if params.Has("tokenCache") {
+		enabled, err := strconv.ParseBool(params.Get("tokenCache"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: tokenCache param is not a boolean")
+		}
+		if enabled {
+			if ucfg.TokenCachePath, err = tokencache.DefaultPath(); err != nil {
+				return errors.Wrap(err, "invalid DSN")
+			}
+		}
+	}
+	if path := params.Get("tokenCachePath"); path != "" {
+		ucfg.TokenCachePath = path
+	}
+	if params.Has("tokenCacheKey") {
+		key, err := base64.StdEncoding.DecodeString(params.Get("tokenCacheKey"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: tokenCacheKey param is not base64 encoded")
+		}
+		ucfg.TokenCacheKey = key
+	}
+	return nil
+}
+
+func u2mAuthenticator(ucfg *UserConfig) (auth.Authenticator, error) {
+	var opts []u2m.Option
+	if ucfg.TokenCachePath != "" {
+		cache, err := tokencache.NewFileCache(ucfg.TokenCachePath, ucfg.TokenCacheKey)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, u2m.WithTokenCache(cache))
+	}
+	return u2m.NewAuthenticator(ucfg.Host, defaultOAuthLoginTimeout, opts...)
+}
+
+func azureServicePrincipal(params url.Values) (auth.Authenticator, error) {
+	tenantID, clientID := params.Get("azureTenantId"), params.Get("clientId")
+	if certPath := params.Get("azureClientCertificate"); certPath != "" {
+		cert, key, err := azure.LoadCertificate(certPath)
+		if err != nil {
+			return nil, err
+		}
+		return azure.NewCertificateAuthenticator(tenantID, clientID, cert, key)
+	}
+	return azure.NewServicePrincipalAuthenticator(tenantID, clientID, params.Get("clientSecret"))
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"context"
 	"crypto/tls"
+	"database/sql/driver"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
@@ -13,6 +16,8 @@ import (
 	"github.com/databricks/databricks-sql-go/auth/pat"
 	"github.com/databricks/databricks-sql-go/internal/cli_service"
 	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/databricks/databricks-sql-go/metrics"
+	"github.com/databricks/databricks-sql-go/resultcache"
 	"github.com/pkg/errors"
 )
 
@@ -21,10 +26,27 @@ import (
 type Config struct {
 	UserConfig
 	TLSConfig                 *tls.Config // nil disables TLS
+	Proxy                     *url.URL    // proxy of the requests, nil uses the HTTPS_PROXY and NO_PROXY environment variables
 	RunAsync                  bool
 	PollInterval              time.Duration
-	ClientTimeout             time.Duration // max time the http request can last
-	PingTimeout               time.Duration // max time allowed for ping
+	ClientTimeout             time.Duration   // max time the http request can last
+	ConnectTimeout            time.Duration   // max time opening a session: resolving, dialing and the TLS handshake included, 0 is no limit
+	MaxIdleConns              int             // max idle HTTP connections over all hosts
+	MaxIdleConnsPerHost       int             // max idle HTTP connections per host
+	IdleConnTimeout           time.Duration   // idle HTTP connections are closed after this time, 0 keeps them open
+	TLSHandshakeTimeout       time.Duration   // max time of a TLS handshake, 0 is no limit
+	UseHTTP2                  bool            // attempt HTTP/2 connections
+	Transport                 *http.Transport // HTTP transport shared by the clients of a connector, nil creates one per client
+	PingTimeout               time.Duration   // max time allowed for ping
+	CancelGracePeriod         time.Duration   // max time spent canceling a query when its context is done
+	HeartbeatInterval         time.Duration   // interval of the requests keeping the session of an idle connection alive, 0 disables them
+	SessionIdleTimeout        time.Duration   // close the session of a connection idle in the pool for longer, 0 keeps it open
+	WarehouseStartTimeout     time.Duration   // max time waited for a starting warehouse when opening a session, 0 doesn't wait
+	CircuitBreakerThreshold   int             // consecutive failed requests to the warehouse opening the circuit breaker, 0 disables it
+	CircuitBreakerCooldown    time.Duration   // time an open circuit breaker fails the requests fast before letting a probe through
+	Failover                  []Endpoint      // standby warehouses, connected to in order when the warehouse is unreachable
+	LoadBalanced              []Endpoint      // warehouses sharing the new sessions with the warehouse
+	LoadBalancing             string          // policy picking the warehouse of a new session, RoundRobin or LeastOutstanding
 	CanUseMultipleCatalogs    bool
 	DriverName                string
 	DriverVersion             string
@@ -32,8 +54,76 @@ type Config struct {
 	ThriftTransport           string
 	ThriftProtocolVersion     cli_service.TProtocolVersion
 	ThriftDebugClientProtocol bool
+	UseRESTAPI                bool              // run statements with the Statement Execution REST API instead of Thrift
+	UseArrowBatches           bool              // fetch results as Arrow record batches instead of Thrift columns
+	UseCloudFetch             bool              // download large Arrow results directly from cloud storage
+	MaxDownloadThreads        int               // max number of concurrent cloud fetch downloads
+	DownloadBandwidthLimit    int64             // max bytes per second downloaded by cloud fetch, 0 is unlimited
+	UseLz4Compression         bool              // accept LZ4 compressed Arrow results
+	UseGzipCompression        bool              // accept gzip compressed responses of the Thrift and REST requests
+	MaxPrefetchPages          int               // max number of result pages fetched ahead of the reader, 0 disables prefetching
+	PrefetchMemoryLimit       int64             // max bytes used by prefetched pages, 0 is unlimited
+	MaxPageBytes              int64             // max bytes of a result page, fewer rows are fetched per page when rows are wide, 0 is unlimited
+	MaxRowsTotal              int64             // max rows read from the results of a query, 0 is unlimited
+	MaxBytesPerQuery          int64             // max bytes fetched for the results of a query, 0 is unlimited
+	ReadOnly                  bool              // reject the statements which may change data before they are sent
+	RetryNonIdempotent        bool              // retry the requests executing statements which may change data after transport and server errors
+	LastInsertId              bool              // read the identity column of the table of an INSERT for the last insert id
+	ResultCache               resultcache.Store // stores the results of the read-only queries, nil disables result caching
+	ResultCacheTTL            time.Duration     // time the results are cached
+	ResultCacheMaxBytes       int64             // max bytes of a cached result, larger results are not cached, 0 is unlimited
+	DecodeComplexTypes        bool              // decode ARRAY, MAP, STRUCT and VARIANT values to Go values instead of returning JSON strings
+	NaiveTimestampLocation    *time.Location    // location of the wall clock of TIMESTAMP_NTZ values, nil uses Location
+	TimestampConversion       string            // Go value of TIMESTAMP and TIMESTAMP_NTZ values, TimestampInLocation, TimestampInUTC or TimestampAsMicros
+	DateConversion            string            // Go value of DATE values, DateAsTime or DateAsCivil
+	ZeroCopyScan              bool              // return STRING and BINARY values as []byte views of reused buffers, valid until the next row
+	MaxPreparedStatements     int               // max number of prepared statements cached per connection, 0 disables caching
+	ResetSessions             bool              // replace the session changed by the statements of a connection before it is reused
+	InterpolateParams         bool              // bind the query parameters on the client when the server doesn't support them
+	LazySession               bool              // open the session of a connection with its first statement
+	StatementTags             map[string]string // query tags of all the statements, the statement tags of a context override them
+	StatementConf             StatementConfFunc // computes the conf of each statement from its context, nil sends no conf
+	LogHandler                logger.Handler    // receives the logs of the connections instead of the global logger
+	LogLevel                  string            // log level of the connections, empty uses the global log level
+	Metrics                   metrics.Collector // receives the metrics of the connections, nil disables metrics
+	WrapTransport             TransportWrapper  // wraps or replaces the transport of the requests, nil uses the default transport
+	HTTPClient                *http.Client      // client of the user whose transport, cookie jar and timeout send the requests, nil uses the default client
+	StatementInterceptors     []StatementInterceptor
+	RequestHeaders            []HeaderFunc  // add headers to the requests to the warehouse, called in order with the context of each request
+	SlowQueryThreshold        time.Duration // queries taking longer are reported to SlowQueryHook or logged, 0 disables the slow query log
+	SlowQueryHook             SlowQueryHook // receives the slow queries, nil logs them at warn level
+	OptionErr                 error         // first error of the options, returned by NewConnector
 }
 
+// TransportWrapper returns the transport of the requests from the default one, see dbsql.WithTransportWrapper
+type TransportWrapper func(base http.RoundTripper) http.RoundTripper
+
+// HeaderFunc adds headers to a request to the warehouse, see dbsql.WithHeaderFunc
+type HeaderFunc func(ctx context.Context, header http.Header)
+
+// StatementConfFunc returns the conf of a statement from its context, see dbsql.WithStatementConf
+type StatementConfFunc func(ctx context.Context) map[string]string
+
+// SlowQuery is a query slower than the slow query threshold, see dbsql.SlowQuery
+type SlowQuery struct {
+	QueryId       string
+	Query         string
+	Duration      time.Duration
+	QueueTime     time.Duration
+	ExecutionTime time.Duration
+	FetchTime     time.Duration
+	Err           error
+}
+
+// SlowQueryHook receives the slow queries, see dbsql.WithSlowQueryLog
+type SlowQueryHook func(SlowQuery)
+
+// StatementHandler runs a statement, see dbsql.StatementHandler
+type StatementHandler func(ctx context.Context, query string, args []driver.NamedValue) error
+
+// StatementInterceptor wraps the execution of statements, see dbsql.StatementInterceptor
+type StatementInterceptor func(ctx context.Context, query string, args []driver.NamedValue, next StatementHandler) error
+
 // ToEndpointURL generates the endpoint URL from Config that a Thrift client will connect to
 func (c *Config) ToEndpointURL() string {
 	var userInfo string
@@ -41,6 +131,45 @@ func (c *Config) ToEndpointURL() string {
 	return endpointUrl
 }
 
+// Policies of LoadBalancing
+const (
+	RoundRobin       = "roundRobin"       // the warehouses take turns
+	LeastOutstanding = "leastOutstanding" // the warehouse with the fewest running queries of the connector
+)
+
+// Conversions of TimestampConversion
+const (
+	TimestampInLocation = "location" // time.Time in Location, or NaiveTimestampLocation for TIMESTAMP_NTZ values
+	TimestampInUTC      = "utc"      // time.Time in UTC, TIMESTAMP_NTZ values keep their wall clock
+	TimestampAsMicros   = "micros"   // int64 microseconds since the Unix epoch, of the wall clock in UTC for TIMESTAMP_NTZ values
+)
+
+// Conversions of DateConversion
+const (
+	DateAsTime  = "time"  // time.Time of the midnight starting the day in Location
+	DateAsCivil = "civil" // dbsql.Date
+)
+
+// Endpoint is the host, port and HTTP path of a warehouse, the host and port default to the ones of the config
+type Endpoint struct {
+	Host     string
+	Port     int
+	HTTPPath string
+}
+
+// WithEndpoint returns a copy of the config connecting to the warehouse of e
+func (c *Config) WithEndpoint(e Endpoint) *Config {
+	cfg := c.DeepCopy()
+	if e.Host != "" {
+		cfg.Host = e.Host
+	}
+	if e.Port != 0 {
+		cfg.Port = e.Port
+	}
+	cfg.HTTPPath = e.HTTPPath
+	return cfg
+}
+
 // DeepCopy returns a true deep copy of Config
 func (c *Config) DeepCopy() *Config {
 	if c == nil {
@@ -50,10 +179,27 @@ func (c *Config) DeepCopy() *Config {
 	return &Config{
 		UserConfig:                c.UserConfig.DeepCopy(),
 		TLSConfig:                 c.TLSConfig.Clone(),
+		Proxy:                     copyURL(c.Proxy),
 		RunAsync:                  c.RunAsync,
 		PollInterval:              c.PollInterval,
 		ClientTimeout:             c.ClientTimeout,
+		ConnectTimeout:            c.ConnectTimeout,
+		MaxIdleConns:              c.MaxIdleConns,
+		MaxIdleConnsPerHost:       c.MaxIdleConnsPerHost,
+		IdleConnTimeout:           c.IdleConnTimeout,
+		TLSHandshakeTimeout:       c.TLSHandshakeTimeout,
+		UseHTTP2:                  c.UseHTTP2,
+		Transport:                 c.Transport,
 		PingTimeout:               c.PingTimeout,
+		CancelGracePeriod:         c.CancelGracePeriod,
+		HeartbeatInterval:         c.HeartbeatInterval,
+		SessionIdleTimeout:        c.SessionIdleTimeout,
+		WarehouseStartTimeout:     c.WarehouseStartTimeout,
+		CircuitBreakerThreshold:   c.CircuitBreakerThreshold,
+		CircuitBreakerCooldown:    c.CircuitBreakerCooldown,
+		Failover:                  append([]Endpoint(nil), c.Failover...),
+		LoadBalanced:              append([]Endpoint(nil), c.LoadBalanced...),
+		LoadBalancing:             c.LoadBalancing,
 		CanUseMultipleCatalogs:    c.CanUseMultipleCatalogs,
 		DriverName:                c.DriverName,
 		DriverVersion:             c.DriverVersion,
@@ -61,7 +207,104 @@ func (c *Config) DeepCopy() *Config {
 		ThriftTransport:           c.ThriftTransport,
 		ThriftProtocolVersion:     c.ThriftProtocolVersion,
 		ThriftDebugClientProtocol: c.ThriftDebugClientProtocol,
+		UseRESTAPI:                c.UseRESTAPI,
+		UseArrowBatches:           c.UseArrowBatches,
+		UseCloudFetch:             c.UseCloudFetch,
+		MaxDownloadThreads:        c.MaxDownloadThreads,
+		DownloadBandwidthLimit:    c.DownloadBandwidthLimit,
+		UseLz4Compression:         c.UseLz4Compression,
+		UseGzipCompression:        c.UseGzipCompression,
+		MaxPrefetchPages:          c.MaxPrefetchPages,
+		PrefetchMemoryLimit:       c.PrefetchMemoryLimit,
+		MaxPageBytes:              c.MaxPageBytes,
+		MaxRowsTotal:              c.MaxRowsTotal,
+		MaxBytesPerQuery:          c.MaxBytesPerQuery,
+		ReadOnly:                  c.ReadOnly,
+		RetryNonIdempotent:        c.RetryNonIdempotent,
+		LastInsertId:              c.LastInsertId,
+		ResultCache:               c.ResultCache,
+		ResultCacheTTL:            c.ResultCacheTTL,
+		ResultCacheMaxBytes:       c.ResultCacheMaxBytes,
+		DecodeComplexTypes:        c.DecodeComplexTypes,
+		NaiveTimestampLocation:    c.NaiveTimestampLocation,
+		TimestampConversion:       c.TimestampConversion,
+		DateConversion:            c.DateConversion,
+		ZeroCopyScan:              c.ZeroCopyScan,
+		MaxPreparedStatements:     c.MaxPreparedStatements,
+		ResetSessions:             c.ResetSessions,
+		InterpolateParams:         c.InterpolateParams,
+		LazySession:               c.LazySession,
+		StatementTags:             copyTags(c.StatementTags),
+		StatementConf:             c.StatementConf,
+		LogHandler:                c.LogHandler,
+		LogLevel:                  c.LogLevel,
+		Metrics:                   c.Metrics,
+		WrapTransport:             c.WrapTransport,
+		HTTPClient:                c.HTTPClient,
+		StatementInterceptors:     append([]StatementInterceptor(nil), c.StatementInterceptors...),
+		RequestHeaders:            append([]HeaderFunc(nil), c.RequestHeaders...),
+		SlowQueryThreshold:        c.SlowQueryThreshold,
+		SlowQueryHook:             c.SlowQueryHook,
+		OptionErr:                 c.OptionErr,
+	}
+}
+
+// copyTags returns a copy of the statement tags, nil if there are none
+func copyTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(tags))
+	for k, v := range tags {
+		copied[k] = v
+	}
+	return copied
+}
+
+// parseQueryTags parses tags formatted like the query_tags conf, e.g. team:growth,workload:batch,adhoc. A backslash
+// escapes the next character, e.g. a comma or a colon of a value.
+func parseQueryTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	if s == "" {
+		return tags, nil
+	}
+	var key, value strings.Builder
+	inValue := false
+	addTag := func() error {
+		if key.Len() == 0 {
+			return errors.Errorf("invalid DSN: queryTags param has a tag without a key in %q", s)
+		}
+		tags[key.String()] = value.String()
+		key.Reset()
+		value.Reset()
+		inValue = false
+		return nil
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			c = s[i]
+		case c == ',':
+			if err := addTag(); err != nil {
+				return nil, err
+			}
+			continue
+		case c == ':' && !inValue:
+			inValue = true
+			continue
+		}
+		if inValue {
+			value.WriteByte(c)
+		} else {
+			key.WriteByte(c)
+		}
+	}
+	if err := addTag(); err != nil {
+		return nil, err
 	}
+	return tags, nil
 }
 
 // UserConfig is the set of configurations exposed to users
@@ -82,6 +325,8 @@ type UserConfig struct {
 	RetryWaitMin   time.Duration
 	RetryWaitMax   time.Duration
 	RetryMax       int
+	TokenCachePath string // file for persisting OAuth tokens, caching is disabled when empty
+	TokenCacheKey  []byte // optional AES key to encrypt the token cache
 }
 
 // DeepCopy returns a true deep copy of UserConfig
@@ -93,6 +338,11 @@ func (ucfg UserConfig) DeepCopy() UserConfig {
 			sessionParams[k] = v
 		}
 	}
+	var tokenCacheKey []byte
+	if ucfg.TokenCacheKey != nil {
+		tokenCacheKey = make([]byte, len(ucfg.TokenCacheKey))
+		copy(tokenCacheKey, ucfg.TokenCacheKey)
+	}
 	var loccp *time.Location
 	if ucfg.Location != nil {
 		var err error
@@ -120,6 +370,8 @@ func (ucfg UserConfig) DeepCopy() UserConfig {
 		RetryWaitMin:   ucfg.RetryWaitMin,
 		RetryWaitMax:   ucfg.RetryWaitMax,
 		RetryMax:       ucfg.RetryMax,
+		TokenCachePath: ucfg.TokenCachePath,
+		TokenCacheKey:  tokenCacheKey,
 	}
 }
 
@@ -156,6 +408,32 @@ func (ucfg UserConfig) WithDefaults() UserConfig {
 	return ucfg
 }
 
+// WithFallback returns a copy of ucfg with its unset connection fields filled in from fallback,
+// e.g. from environment variables or a CLI profile, which never override values that are set.
+func (ucfg UserConfig) WithFallback(fallback UserConfig) UserConfig {
+	if ucfg.Host == "" && fallback.Host != "" {
+		ucfg.Host = fallback.Host
+		ucfg.Protocol = fallback.Protocol
+		if fallback.Port != 0 {
+			ucfg.Port = fallback.Port
+		}
+	}
+	if ucfg.HTTPPath == "" {
+		ucfg.HTTPPath = fallback.HTTPPath
+	}
+	if _, isNoop := ucfg.Authenticator.(*noop.NoopAuth); (ucfg.Authenticator == nil || isNoop) && fallback.Authenticator != nil {
+		ucfg.AccessToken = fallback.AccessToken
+		ucfg.Authenticator = fallback.Authenticator
+	}
+	if ucfg.Catalog == "" {
+		ucfg.Catalog = fallback.Catalog
+	}
+	if ucfg.Schema == "" {
+		ucfg.Schema = fallback.Schema
+	}
+	return ucfg
+}
+
 // WithDefaults provides default settings for Config
 func WithDefaults() *Config {
 	return &Config{
@@ -164,41 +442,65 @@ func WithDefaults() *Config {
 		RunAsync:                  true,
 		PollInterval:              1 * time.Second,
 		ClientTimeout:             900 * time.Second,
+		MaxIdleConns:              100,
+		MaxIdleConnsPerHost:       10,
+		IdleConnTimeout:           180 * time.Second,
+		TLSHandshakeTimeout:       10 * time.Second,
+		UseHTTP2:                  true,
 		PingTimeout:               60 * time.Second,
+		CancelGracePeriod:         15 * time.Second,
+		CircuitBreakerCooldown:    30 * time.Second,
+		LoadBalancing:             RoundRobin,
 		CanUseMultipleCatalogs:    true,
 		DriverName:                "godatabrickssqlconnector", // important. Do not change
 		DriverVersion:             "0.9.0",
 		ThriftProtocol:            "binary",
 		ThriftTransport:           "http",
-		ThriftProtocolVersion:     cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V6,
+		ThriftProtocolVersion:     cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8,
 		ThriftDebugClientProtocol: false,
+		UseArrowBatches:           true,
+		UseCloudFetch:             false,
+		MaxDownloadThreads:        10,
+		DownloadBandwidthLimit:    0,
+		UseLz4Compression:         true,
+		UseGzipCompression:        true,
+		MaxPrefetchPages:          2,
+		PrefetchMemoryLimit:       256 * 1024 * 1024,
+		RetryNonIdempotent:        true,
+		DecodeComplexTypes:        false,
+		TimestampConversion:       TimestampInLocation,
+		DateConversion:            DateAsTime,
+		MaxPreparedStatements:     100,
 	}
 
 }
 
-// ParseDSN constructs UserConfig by parsing DSN string supplied to `sql.Open()`
-func ParseDSN(dsn string) (UserConfig, error) {
+// ParseDSN constructs Config by parsing DSN string supplied to `sql.Open()`
+func ParseDSN(dsn string) (*Config, error) {
 	fullDSN := dsn
 	if !strings.HasPrefix(dsn, "https://") && !strings.HasPrefix(dsn, "http://") {
 		fullDSN = "https://" + dsn
 	}
 	parsedURL, err := url.Parse(fullDSN)
 	if err != nil {
-		return UserConfig{}, errors.Wrap(err, "invalid DSN: invalid format")
+		return nil, errors.Wrap(err, "invalid DSN: invalid format")
 	}
 	ucfg := UserConfig{}.WithDefaults()
 	ucfg.Protocol = parsedURL.Scheme
 	ucfg.Host = parsedURL.Hostname()
-	port, err := strconv.Atoi(parsedURL.Port())
-	if err != nil {
-		return UserConfig{}, errors.Wrap(err, "invalid DSN: invalid DSN port")
+	// host and port can be left out when they come from a profile
+	if ucfg.Host != "" || parsedURL.Port() != "" {
+		port, err := strconv.Atoi(parsedURL.Port())
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid DSN: invalid DSN port")
+		}
+		ucfg.Port = port
 	}
-	ucfg.Port = port
 	name := parsedURL.User.Username()
 	if name == "token" {
 		pass, ok := parsedURL.User.Password()
 		if pass == "" {
-			return UserConfig{}, errors.New("invalid DSN: empty token")
+			return nil, errors.New("invalid DSN: empty token")
 		}
 		if ok {
 			ucfg.AccessToken = pass
@@ -209,16 +511,31 @@ func ParseDSN(dsn string) (UserConfig, error) {
 		}
 	} else {
 		if name != "" {
-			return UserConfig{}, errors.New("invalid DSN: basic auth not enabled")
+			return nil, errors.New("invalid DSN: basic auth not enabled")
 		}
 	}
 	ucfg.HTTPPath = parsedURL.Path
 	params := parsedURL.Query()
+	profile := params.Get("profile")
+	params.Del("profile")
+	if err := parseAuthParams(&ucfg, params); err != nil {
+		return nil, err
+	}
+	cfg := WithDefaults()
+	if err := parseProxyParams(cfg, params); err != nil {
+		return nil, err
+	}
+	if err := parseTLSParams(cfg, params); err != nil {
+		return nil, err
+	}
+	if err := parseTuningParams(cfg, &ucfg, params); err != nil {
+		return nil, err
+	}
 	maxRowsStr := params.Get("maxRows")
 	if maxRowsStr != "" {
 		maxRows, err := strconv.Atoi(maxRowsStr)
 		if err != nil {
-			return UserConfig{}, errors.Wrap(err, "invalid DSN: maxRows param is not an integer")
+			return nil, errors.Wrap(err, "invalid DSN: maxRows param is not an integer")
 		}
 		// we should always have at least some page size
 		if maxRows != 0 {
@@ -231,7 +548,7 @@ func ParseDSN(dsn string) (UserConfig, error) {
 	if timeoutStr != "" {
 		timeoutSeconds, err := strconv.Atoi(timeoutStr)
 		if err != nil {
-			return UserConfig{}, errors.Wrap(err, "invalid DSN: timeout param is not an integer")
+			return nil, errors.Wrap(err, "invalid DSN: timeout param is not an integer")
 		}
 		ucfg.QueryTimeout = time.Duration(timeoutSeconds) * time.Second
 	}
@@ -260,6 +577,405 @@ func ParseDSN(dsn string) (UserConfig, error) {
 		}
 		ucfg.SessionParams = sessionParams
 	}
+	if err != nil {
+		return nil, err
+	}
 
-	return ucfg, err
+	if profile != "" {
+		profileCfg, err := LoadProfile(profile)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid DSN")
+		}
+		ucfg = ucfg.WithFallback(profileCfg)
+	}
+
+	cfg.UserConfig = ucfg
+	return cfg, nil
+}
+
+// parseTuningParams sets the retry, polling and timeout settings from the DSN and removes them from params.
+// Durations are given in seconds or as Go duration strings like "500ms".
+func parseTuningParams(cfg *Config, ucfg *UserConfig, params url.Values) error {
+	if params.Has("retryMax") {
+		retryMax, err := strconv.Atoi(params.Get("retryMax"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: retryMax param is not an integer")
+		}
+		ucfg.RetryMax = retryMax
+		params.Del("retryMax")
+	}
+	if params.Has("runAsync") {
+		runAsync, err := strconv.ParseBool(params.Get("runAsync"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: runAsync param is not a boolean")
+		}
+		cfg.RunAsync = runAsync
+		params.Del("runAsync")
+	}
+	if params.Has("useArrowBatches") {
+		useArrowBatches, err := strconv.ParseBool(params.Get("useArrowBatches"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: useArrowBatches param is not a boolean")
+		}
+		cfg.UseArrowBatches = useArrowBatches
+		params.Del("useArrowBatches")
+	}
+	if params.Has("useCloudFetch") {
+		useCloudFetch, err := strconv.ParseBool(params.Get("useCloudFetch"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: useCloudFetch param is not a boolean")
+		}
+		cfg.UseCloudFetch = useCloudFetch
+		params.Del("useCloudFetch")
+	}
+	if params.Has("useRestApi") {
+		useRESTAPI, err := strconv.ParseBool(params.Get("useRestApi"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: useRestApi param is not a boolean")
+		}
+		cfg.UseRESTAPI = useRESTAPI
+		params.Del("useRestApi")
+	}
+	if params.Has("useLz4Compression") {
+		useLz4Compression, err := strconv.ParseBool(params.Get("useLz4Compression"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: useLz4Compression param is not a boolean")
+		}
+		cfg.UseLz4Compression = useLz4Compression
+		params.Del("useLz4Compression")
+	}
+	if params.Has("useGzipCompression") {
+		useGzipCompression, err := strconv.ParseBool(params.Get("useGzipCompression"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: useGzipCompression param is not a boolean")
+		}
+		cfg.UseGzipCompression = useGzipCompression
+		params.Del("useGzipCompression")
+	}
+	if params.Has("maxDownloadThreads") {
+		maxDownloadThreads, err := strconv.Atoi(params.Get("maxDownloadThreads"))
+		if err != nil || maxDownloadThreads < 1 {
+			return errors.New("invalid DSN: maxDownloadThreads param is not a positive integer")
+		}
+		cfg.MaxDownloadThreads = maxDownloadThreads
+		params.Del("maxDownloadThreads")
+	}
+	poolSizes := []struct {
+		name  string
+		field *int
+	}{
+		{"maxIdleConns", &cfg.MaxIdleConns},
+		{"maxIdleConnsPerHost", &cfg.MaxIdleConnsPerHost},
+	}
+	for _, p := range poolSizes {
+		if !params.Has(p.name) {
+			continue
+		}
+		n, err := strconv.Atoi(params.Get(p.name))
+		if err != nil || n < 0 {
+			return errors.Errorf("invalid DSN: %s param is not a non-negative integer", p.name)
+		}
+		*p.field = n
+		params.Del(p.name)
+	}
+	if params.Has("useHttp2") {
+		useHTTP2, err := strconv.ParseBool(params.Get("useHttp2"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: useHttp2 param is not a boolean")
+		}
+		cfg.UseHTTP2 = useHTTP2
+		params.Del("useHttp2")
+	}
+	if params.Has("downloadBandwidthLimit") {
+		limit, err := strconv.ParseInt(params.Get("downloadBandwidthLimit"), 10, 64)
+		if err != nil || limit < 0 {
+			return errors.New("invalid DSN: downloadBandwidthLimit param is not a non-negative integer")
+		}
+		cfg.DownloadBandwidthLimit = limit
+		params.Del("downloadBandwidthLimit")
+	}
+	if params.Has("prefetchPages") {
+		prefetchPages, err := strconv.Atoi(params.Get("prefetchPages"))
+		if err != nil || prefetchPages < 0 {
+			return errors.New("invalid DSN: prefetchPages param is not a non-negative integer")
+		}
+		cfg.MaxPrefetchPages = prefetchPages
+		params.Del("prefetchPages")
+	}
+	if params.Has("prefetchMemoryLimit") {
+		limit, err := strconv.ParseInt(params.Get("prefetchMemoryLimit"), 10, 64)
+		if err != nil || limit < 0 {
+			return errors.New("invalid DSN: prefetchMemoryLimit param is not a non-negative integer")
+		}
+		cfg.PrefetchMemoryLimit = limit
+		params.Del("prefetchMemoryLimit")
+	}
+	if params.Has("maxPageBytes") {
+		limit, err := strconv.ParseInt(params.Get("maxPageBytes"), 10, 64)
+		if err != nil || limit < 0 {
+			return errors.New("invalid DSN: maxPageBytes param is not a non-negative integer")
+		}
+		cfg.MaxPageBytes = limit
+		params.Del("maxPageBytes")
+	}
+	if params.Has("maxRowsTotal") {
+		limit, err := strconv.ParseInt(params.Get("maxRowsTotal"), 10, 64)
+		if err != nil || limit < 0 {
+			return errors.New("invalid DSN: maxRowsTotal param is not a non-negative integer")
+		}
+		cfg.MaxRowsTotal = limit
+		params.Del("maxRowsTotal")
+	}
+	if params.Has("maxBytesPerQuery") {
+		limit, err := strconv.ParseInt(params.Get("maxBytesPerQuery"), 10, 64)
+		if err != nil || limit < 0 {
+			return errors.New("invalid DSN: maxBytesPerQuery param is not a non-negative integer")
+		}
+		cfg.MaxBytesPerQuery = limit
+		params.Del("maxBytesPerQuery")
+	}
+	if params.Has("readOnly") {
+		readOnly, err := strconv.ParseBool(params.Get("readOnly"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: readOnly param is not a boolean")
+		}
+		cfg.ReadOnly = readOnly
+		params.Del("readOnly")
+	}
+	endpointLists := []struct {
+		name  string
+		field *[]Endpoint
+	}{
+		{"failover", &cfg.Failover},
+		{"loadBalanced", &cfg.LoadBalanced},
+	}
+	for _, l := range endpointLists {
+		if !params.Has(l.name) {
+			continue
+		}
+		for _, s := range strings.Split(params.Get(l.name), ",") {
+			endpoint, err := parseEndpoint(s)
+			if err != nil {
+				return errors.Wrapf(err, "invalid DSN: %s param has an invalid warehouse", l.name)
+			}
+			*l.field = append(*l.field, endpoint)
+		}
+		params.Del(l.name)
+	}
+	if params.Has("loadBalancing") {
+		switch policy := params.Get("loadBalancing"); policy {
+		case RoundRobin, LeastOutstanding:
+			cfg.LoadBalancing = policy
+		default:
+			return errors.New("invalid DSN: loadBalancing param must be roundRobin or leastOutstanding")
+		}
+		params.Del("loadBalancing")
+	}
+	if params.Has("circuitBreakerThreshold") {
+		threshold, err := strconv.Atoi(params.Get("circuitBreakerThreshold"))
+		if err != nil || threshold < 0 {
+			return errors.New("invalid DSN: circuitBreakerThreshold param is not a non-negative integer")
+		}
+		cfg.CircuitBreakerThreshold = threshold
+		params.Del("circuitBreakerThreshold")
+	}
+	if params.Has("retryNonIdempotent") {
+		retryNonIdempotent, err := strconv.ParseBool(params.Get("retryNonIdempotent"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: retryNonIdempotent param is not a boolean")
+		}
+		cfg.RetryNonIdempotent = retryNonIdempotent
+		params.Del("retryNonIdempotent")
+	}
+	if params.Has("lastInsertId") {
+		lastInsertId, err := strconv.ParseBool(params.Get("lastInsertId"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: lastInsertId param is not a boolean")
+		}
+		cfg.LastInsertId = lastInsertId
+		params.Del("lastInsertId")
+	}
+	resultCacheSize := int64(64 << 20)
+	if params.Has("resultCacheSize") {
+		size, err := strconv.ParseInt(params.Get("resultCacheSize"), 10, 64)
+		if err != nil || size < 0 {
+			return errors.New("invalid DSN: resultCacheSize param is not a non-negative integer")
+		}
+		resultCacheSize = size
+		params.Del("resultCacheSize")
+	}
+	if params.Has("preparedStatementCacheSize") {
+		size, err := strconv.Atoi(params.Get("preparedStatementCacheSize"))
+		if err != nil || size < 0 {
+			return errors.New("invalid DSN: preparedStatementCacheSize param is not a non-negative integer")
+		}
+		cfg.MaxPreparedStatements = size
+		params.Del("preparedStatementCacheSize")
+	}
+	if params.Has("resetSession") {
+		resetSession, err := strconv.ParseBool(params.Get("resetSession"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: resetSession param is not a boolean")
+		}
+		cfg.ResetSessions = resetSession
+		params.Del("resetSession")
+	}
+	if params.Has("interpolateParams") {
+		interpolateParams, err := strconv.ParseBool(params.Get("interpolateParams"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: interpolateParams param is not a boolean")
+		}
+		cfg.InterpolateParams = interpolateParams
+		params.Del("interpolateParams")
+	}
+	if params.Has("lazySession") {
+		lazySession, err := strconv.ParseBool(params.Get("lazySession"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: lazySession param is not a boolean")
+		}
+		cfg.LazySession = lazySession
+		params.Del("lazySession")
+	}
+	if params.Has("queryTags") {
+		tags, err := parseQueryTags(params.Get("queryTags"))
+		if err != nil {
+			return err
+		}
+		cfg.StatementTags = tags
+		params.Del("queryTags")
+	}
+	if params.Has("logLevel") {
+		if _, err := logger.ParseLevel(params.Get("logLevel")); err != nil {
+			return errors.Wrap(err, "invalid DSN: logLevel param is not a valid log level")
+		}
+		cfg.LogLevel = params.Get("logLevel")
+		params.Del("logLevel")
+	}
+	if params.Has("ntzTimezone") {
+		loc, err := time.LoadLocation(params.Get("ntzTimezone"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: ntzTimezone param is not a valid timezone")
+		}
+		cfg.NaiveTimestampLocation = loc
+		params.Del("ntzTimezone")
+	}
+	if params.Has("complexTypeScanner") {
+		switch params.Get("complexTypeScanner") {
+		case "string":
+			cfg.DecodeComplexTypes = false
+		case "structured":
+			cfg.DecodeComplexTypes = true
+		default:
+			return errors.New("invalid DSN: complexTypeScanner param must be string or structured")
+		}
+		params.Del("complexTypeScanner")
+	}
+	if params.Has("zeroCopyScan") {
+		zeroCopyScan, err := strconv.ParseBool(params.Get("zeroCopyScan"))
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: zeroCopyScan param is not a boolean")
+		}
+		cfg.ZeroCopyScan = zeroCopyScan
+		params.Del("zeroCopyScan")
+	}
+	if params.Has("timestampConversion") {
+		switch conversion := params.Get("timestampConversion"); conversion {
+		case TimestampInLocation, TimestampInUTC, TimestampAsMicros:
+			cfg.TimestampConversion = conversion
+		default:
+			return errors.New("invalid DSN: timestampConversion param must be location, utc or micros")
+		}
+		params.Del("timestampConversion")
+	}
+	if params.Has("dateConversion") {
+		switch conversion := params.Get("dateConversion"); conversion {
+		case DateAsTime, DateAsCivil:
+			cfg.DateConversion = conversion
+		default:
+			return errors.New("invalid DSN: dateConversion param must be time or civil")
+		}
+		params.Del("dateConversion")
+	}
+
+	durations := []struct {
+		name  string
+		field *time.Duration
+	}{
+		{"retryWaitMin", &ucfg.RetryWaitMin},
+		{"retryWaitMax", &ucfg.RetryWaitMax},
+		{"pollInterval", &cfg.PollInterval},
+		{"clientTimeout", &cfg.ClientTimeout},
+		{"connectTimeout", &cfg.ConnectTimeout},
+		{"pingTimeout", &cfg.PingTimeout},
+		{"cancelGracePeriod", &cfg.CancelGracePeriod},
+		{"heartbeatInterval", &cfg.HeartbeatInterval},
+		{"sessionIdleTimeout", &cfg.SessionIdleTimeout},
+		{"warehouseStartTimeout", &cfg.WarehouseStartTimeout},
+		{"circuitBreakerCooldown", &cfg.CircuitBreakerCooldown},
+		{"resultCacheTTL", &cfg.ResultCacheTTL},
+		{"idleConnTimeout", &cfg.IdleConnTimeout},
+		{"tlsHandshakeTimeout", &cfg.TLSHandshakeTimeout},
+		{"slowQueryThreshold", &cfg.SlowQueryThreshold},
+	}
+	for _, d := range durations {
+		if !params.Has(d.name) {
+			continue
+		}
+		value, err := parseDuration(params.Get(d.name))
+		if err != nil {
+			return errors.Wrapf(err, "invalid DSN: %s param is not a duration", d.name)
+		}
+		*d.field = value
+		params.Del(d.name)
+	}
+
+	// the results are cached in memory when the DSN enables caching
+	if cfg.ResultCacheTTL > 0 && cfg.ResultCache == nil {
+		cfg.ResultCache = resultcache.NewMemoryStore(resultCacheSize)
+		cfg.ResultCacheMaxBytes = resultCacheSize
+	}
+
+	if ucfg.RetryWaitMin > ucfg.RetryWaitMax && ucfg.RetryMax > 0 {
+		return errors.New("invalid DSN: retryWaitMin is greater than retryWaitMax")
+	}
+	return nil
+}
+
+// IsClusterPath returns true for the HTTP path of an all-purpose cluster, /sql/protocolv1/o/<workspace id>/<cluster id>,
+// rather than of a SQL warehouse, /sql/1.0/warehouses/<warehouse id>
+func IsClusterPath(httpPath string) bool {
+	return strings.HasPrefix(strings.TrimLeft(httpPath, "/"), "sql/protocolv1/")
+}
+
+// parseEndpoint parses a warehouse of a list of warehouses, an HTTP path optionally preceded by a host and port,
+// e.g. /sql/1.0/warehouses/abc or standby.cloud.databricks.com:443/sql/1.0/warehouses/abc
+func parseEndpoint(s string) (Endpoint, error) {
+	i := strings.Index(s, "/")
+	if i < 0 || i == len(s)-1 {
+		return Endpoint{}, errors.Errorf("%q is not an HTTP path", s)
+	}
+	endpoint := Endpoint{HTTPPath: s[i:]}
+	host, port, found := strings.Cut(s[:i], ":")
+	endpoint.Host = host
+	if found {
+		var err error
+		if endpoint.Port, err = strconv.Atoi(port); err != nil || endpoint.Port <= 0 {
+			return Endpoint{}, errors.Errorf("%q has an invalid port", s)
+		}
+	}
+	return endpoint, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if seconds, convErr := strconv.Atoi(s); convErr == nil {
+		d, err = time.Duration(seconds)*time.Second, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("negative duration")
+	}
+	return d, nil
 }
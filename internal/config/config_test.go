@@ -2,13 +2,24 @@ package config
 
 import (
 	"crypto/tls"
+	"net/http"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/databricks/databricks-sql-go/auth/azure"
+	"github.com/databricks/databricks-sql-go/auth/chain"
 	"github.com/databricks/databricks-sql-go/auth/noop"
+	"github.com/databricks/databricks-sql-go/auth/oauth/m2m"
+	"github.com/databricks/databricks-sql-go/auth/oauth/tokencache"
+	"github.com/databricks/databricks-sql-go/auth/oauth/u2m"
 	"github.com/databricks/databricks-sql-go/auth/pat"
 	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/databricks/databricks-sql-go/resultcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseConfig(t *testing.T) {
@@ -16,6 +27,12 @@ func TestParseConfig(t *testing.T) {
 		dsn string
 	}
 	tz, _ := time.LoadLocation("America/Vancouver")
+	u2mAuth, _ := u2m.NewAuthenticator("example.cloud.databricks.com", 2*time.Minute)
+	tokenCache, _ := tokencache.NewFileCache("/tmp/tokens.json", []byte("0123456789abcdef"))
+	u2mCachedAuth, _ := u2m.NewAuthenticator("example.cloud.databricks.com", 2*time.Minute, u2m.WithTokenCache(tokenCache))
+	azureSPAuth, _ := azure.NewServicePrincipalAuthenticator("tenant", "id", "secret")
+	azureMSIAuth := azure.NewManagedIdentityAuthenticator("identity")
+	chainAuth := chain.NewAuthenticator("example.cloud.databricks.com")
 	tests := []struct {
 		name    string
 		args    args
@@ -277,6 +294,158 @@ func TestParseConfig(t *testing.T) {
 			wantURL: "https://example.cloud.databricks.com:443",
 			wantErr: false,
 		},
+		{
+			name: "with authType oauth-u2m",
+			args: args{dsn: "example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=OAuth-U2M"},
+			wantCfg: UserConfig{
+				Protocol:      "https",
+				Host:          "example.cloud.databricks.com",
+				Port:          443,
+				MaxRows:       defaultMaxRows,
+				Authenticator: u2mAuth,
+				HTTPPath:      "/sql/1.0/endpoints/12346a5b5b0e123a",
+				SessionParams: make(map[string]string),
+				RetryMax:      4,
+				RetryWaitMin:  1 * time.Second,
+				RetryWaitMax:  30 * time.Second,
+			},
+			wantURL: "https://example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a",
+			wantErr: false,
+		},
+		{
+			name: "with authType oauth-u2m and token cache",
+			args: args{dsn: "example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=oauth-u2m&tokenCachePath=/tmp/tokens.json&tokenCacheKey=MDEyMzQ1Njc4OWFiY2RlZg=="},
+			wantCfg: UserConfig{
+				Protocol:       "https",
+				Host:           "example.cloud.databricks.com",
+				Port:           443,
+				MaxRows:        defaultMaxRows,
+				Authenticator:  u2mCachedAuth,
+				HTTPPath:       "/sql/1.0/endpoints/12346a5b5b0e123a",
+				SessionParams:  make(map[string]string),
+				RetryMax:       4,
+				RetryWaitMin:   1 * time.Second,
+				RetryWaitMax:   30 * time.Second,
+				TokenCachePath: "/tmp/tokens.json",
+				TokenCacheKey:  []byte("0123456789abcdef"),
+			},
+			wantURL: "https://example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a",
+			wantErr: false,
+		},
+		{
+			name:    "with invalid tokenCacheKey",
+			args:    args{dsn: "example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=oauth-u2m&tokenCacheKey=not-base64!"},
+			wantCfg: UserConfig{},
+			wantErr: true,
+		},
+		{
+			name: "with authType oauth-m2m",
+			args: args{dsn: "example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=oauth-m2m&clientId=id&clientSecret=secret"},
+			wantCfg: UserConfig{
+				Protocol:      "https",
+				Host:          "example.cloud.databricks.com",
+				Port:          443,
+				MaxRows:       defaultMaxRows,
+				Authenticator: m2m.NewAuthenticator("id", "secret", "example.cloud.databricks.com"),
+				HTTPPath:      "/sql/1.0/endpoints/12346a5b5b0e123a",
+				SessionParams: make(map[string]string),
+				RetryMax:      4,
+				RetryWaitMin:  1 * time.Second,
+				RetryWaitMax:  30 * time.Second,
+			},
+			wantURL: "https://example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a",
+			wantErr: false,
+		},
+		{
+			name:    "with authType oauth-m2m but no client secret",
+			args:    args{dsn: "example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=oauth-m2m&clientId=id"},
+			wantCfg: UserConfig{},
+			wantErr: true,
+		},
+		{
+			name: "with authType azure-sp",
+			args: args{dsn: "adb-123.azuredatabricks.net:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=azure-sp&azureTenantId=tenant&clientId=id&clientSecret=secret"},
+			wantCfg: UserConfig{
+				Protocol:      "https",
+				Host:          "adb-123.azuredatabricks.net",
+				Port:          443,
+				MaxRows:       defaultMaxRows,
+				Authenticator: azureSPAuth,
+				HTTPPath:      "/sql/1.0/endpoints/12346a5b5b0e123a",
+				SessionParams: make(map[string]string),
+				RetryMax:      4,
+				RetryWaitMin:  1 * time.Second,
+				RetryWaitMax:  30 * time.Second,
+			},
+			wantURL: "https://adb-123.azuredatabricks.net:443/sql/1.0/endpoints/12346a5b5b0e123a",
+			wantErr: false,
+		},
+		{
+			name: "with authType azure-msi",
+			args: args{dsn: "adb-123.azuredatabricks.net:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=azure-msi&clientId=identity"},
+			wantCfg: UserConfig{
+				Protocol:      "https",
+				Host:          "adb-123.azuredatabricks.net",
+				Port:          443,
+				MaxRows:       defaultMaxRows,
+				Authenticator: azureMSIAuth,
+				HTTPPath:      "/sql/1.0/endpoints/12346a5b5b0e123a",
+				SessionParams: make(map[string]string),
+				RetryMax:      4,
+				RetryWaitMin:  1 * time.Second,
+				RetryWaitMax:  30 * time.Second,
+			},
+			wantURL: "https://adb-123.azuredatabricks.net:443/sql/1.0/endpoints/12346a5b5b0e123a",
+			wantErr: false,
+		},
+		{
+			name:    "with authType gcp and missing credentials file",
+			args:    args{dsn: "123.4.gcp.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=gcp&gcpCredentials=/does/not/exist.json"},
+			wantCfg: UserConfig{},
+			wantErr: true,
+		},
+		{
+			name: "with authType default",
+			args: args{dsn: "example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=default"},
+			wantCfg: UserConfig{
+				Protocol:      "https",
+				Host:          "example.cloud.databricks.com",
+				Port:          443,
+				MaxRows:       defaultMaxRows,
+				Authenticator: chainAuth,
+				HTTPPath:      "/sql/1.0/endpoints/12346a5b5b0e123a",
+				SessionParams: make(map[string]string),
+				RetryMax:      4,
+				RetryWaitMin:  1 * time.Second,
+				RetryWaitMax:  30 * time.Second,
+			},
+			wantURL: "https://example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a",
+			wantErr: false,
+		},
+		{
+			name:    "with authType azure-sp but no tenant",
+			args:    args{dsn: "adb-123.azuredatabricks.net:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=azure-sp&clientId=id&clientSecret=secret"},
+			wantCfg: UserConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "with authType azure-sp and missing certificate",
+			args:    args{dsn: "adb-123.azuredatabricks.net:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=azure-sp&azureTenantId=t&clientId=id&azureClientCertificate=/does/not/exist.pem"},
+			wantCfg: UserConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "with unknown authType",
+			args:    args{dsn: "example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=kerberos"},
+			wantCfg: UserConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "with authType pat but no token",
+			args:    args{dsn: "example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?authType=pat"},
+			wantCfg: UserConfig{},
+			wantErr: true,
+		},
 		{
 			name:    "with wrong port",
 			args:    args{dsn: "token:supersecret2@example.cloud.databricks.com:foo/sql/1.0/endpoints/12346a5b5b0e123a?catalog=default&schema=system&timeout=100&maxRows=1000"},
@@ -342,17 +511,17 @@ func TestParseConfig(t *testing.T) {
 				t.Errorf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.wantCfg) {
-				t.Errorf("ParseConfig() = %v, want %v", got, tt.wantCfg)
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got.UserConfig, tt.wantCfg) {
+				t.Errorf("ParseConfig() = %v, want %v", got.UserConfig, tt.wantCfg)
 				return
 			}
-			if err == nil {
-				cfg := &Config{UserConfig: got}
-				gotUrl := cfg.ToEndpointURL()
-				if gotUrl != tt.wantURL {
-					t.Errorf("ToEndpointURL() = %v, want %v", got, tt.wantErr)
-					return
-				}
+			gotUrl := got.ToEndpointURL()
+			if gotUrl != tt.wantURL {
+				t.Errorf("ToEndpointURL() = %v, want %v", gotUrl, tt.wantURL)
+				return
 			}
 		})
 	}
@@ -390,6 +559,8 @@ func TestUserConfig_DeepCopy(t *testing.T) {
 			UserAgentEntry: "test",
 			Location:       location,
 			SessionParams:  map[string]string{"a": "32", "b": "4"},
+			TokenCachePath: "/tmp/tokens.json",
+			TokenCacheKey:  []byte("key"),
 		}
 
 		cfg_copy := cfg.DeepCopy()
@@ -428,17 +599,67 @@ func TestConfig_DeepCopy(t *testing.T) {
 		cfg := &Config{
 			UserConfig:                UserConfig{}.WithDefaults(),
 			TLSConfig:                 &tls.Config{MinVersion: tls.VersionTLS12},
+			Proxy:                     &url.URL{Scheme: "http", Host: "proxy.internal:3128", User: url.UserPassword("user", "password")},
 			RunAsync:                  true,
 			PollInterval:              1 * time.Second,
 			ClientTimeout:             900 * time.Second,
+			ConnectTimeout:            10 * time.Second,
+			SlowQueryThreshold:        time.Second,
+			MaxIdleConns:              200,
+			MaxIdleConnsPerHost:       50,
+			IdleConnTimeout:           time.Minute,
+			TLSHandshakeTimeout:       5 * time.Second,
+			UseHTTP2:                  true,
+			Transport:                 &http.Transport{MaxIdleConns: 200},
+			HTTPClient:                &http.Client{Timeout: time.Minute},
 			PingTimeout:               15 * time.Second,
+			CancelGracePeriod:         5 * time.Second,
+			HeartbeatInterval:         5 * time.Minute,
+			SessionIdleTimeout:        30 * time.Minute,
+			WarehouseStartTimeout:     10 * time.Minute,
+			CircuitBreakerThreshold:   5,
+			CircuitBreakerCooldown:    time.Minute,
+			Failover:                  []Endpoint{{Host: "standby", Port: 443, HTTPPath: "/sql/1.0/warehouses/b"}},
+			LoadBalanced:              []Endpoint{{HTTPPath: "/sql/1.0/warehouses/c"}},
+			LoadBalancing:             LeastOutstanding,
 			CanUseMultipleCatalogs:    true,
 			DriverName:                "godatabrickssqlconnector", //important. Do not change
 			DriverVersion:             "0.9.0",
 			ThriftProtocol:            "binary",
 			ThriftTransport:           "http",
-			ThriftProtocolVersion:     cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V6,
+			ThriftProtocolVersion:     cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8,
 			ThriftDebugClientProtocol: false,
+			UseRESTAPI:                true,
+			UseArrowBatches:           true,
+			UseCloudFetch:             true,
+			MaxDownloadThreads:        10,
+			DownloadBandwidthLimit:    1024,
+			UseLz4Compression:         true,
+			UseGzipCompression:        true,
+			MaxPrefetchPages:          2,
+			PrefetchMemoryLimit:       1 << 20,
+			MaxPageBytes:              64 << 20,
+			MaxRowsTotal:              1000000,
+			MaxBytesPerQuery:          1 << 30,
+			ReadOnly:                  true,
+			RetryNonIdempotent:        true,
+			LastInsertId:              true,
+			ResultCache:               resultcache.NewMemoryStore(1 << 20),
+			ResultCacheTTL:            time.Minute,
+			ResultCacheMaxBytes:       1 << 10,
+			DecodeComplexTypes:        true,
+			NaiveTimestampLocation:    time.UTC,
+			TimestampConversion:       TimestampAsMicros,
+			DateConversion:            DateAsCivil,
+			ZeroCopyScan:              true,
+			MaxPreparedStatements:     10,
+			ResetSessions:             true,
+			InterpolateParams:         true,
+			LazySession:               true,
+			StatementTags:             map[string]string{"team": "growth"},
+			LogHandler:                nopHandler{},
+			LogLevel:                  "debug",
+			Metrics:                   nopCollector{},
 		}
 
 		cfg_copy := cfg.DeepCopy()
@@ -447,3 +668,204 @@ func TestConfig_DeepCopy(t *testing.T) {
 		}
 	})
 }
+
+type nopHandler struct{}
+
+func (nopHandler) Log(level logger.Level, msg string, fields map[string]any) {}
+
+type nopCollector struct{}
+
+func (nopCollector) Counter(name string, value float64)   {}
+func (nopCollector) Histogram(name string, value float64) {}
+func (nopCollector) Gauge(name string, delta float64)     {}
+
+func TestParseDSNWithTuningParams(t *testing.T) {
+	base := "token:supersecret@example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a"
+
+	t.Run("all params", func(t *testing.T) {
+		cfg, err := ParseDSN(base + "?retryMax=10&retryWaitMin=2&retryWaitMax=1m&pollInterval=500ms&clientTimeout=120&connectTimeout=10s&pingTimeout=15s&cancelGracePeriod=3&heartbeatInterval=10m&sessionIdleTimeout=30m&warehouseStartTimeout=5m&circuitBreakerThreshold=5&circuitBreakerCooldown=10s&failover=/sql/1.0/warehouses/b,standby.cloud.databricks.com:8443/sql/1.0/warehouses/c&loadBalanced=/sql/1.0/warehouses/d&loadBalancing=leastOutstanding&idleConnTimeout=1m&tlsHandshakeTimeout=5s&runAsync=false&useArrowBatches=false&useCloudFetch=true&useLz4Compression=false&useGzipCompression=false&useRestApi=true&prefetchPages=0&prefetchMemoryLimit=1024&maxPageBytes=4096&maxRowsTotal=1000&maxBytesPerQuery=1048576&readOnly=true&retryNonIdempotent=false&lastInsertId=true&resultCacheTTL=5m&resultCacheSize=1048576&maxDownloadThreads=3&maxIdleConns=200&maxIdleConnsPerHost=50&useHttp2=false&downloadBandwidthLimit=1048576&complexTypeScanner=structured&ntzTimezone=UTC&timestampConversion=micros&dateConversion=civil&zeroCopyScan=true&preparedStatementCacheSize=0&resetSession=true&interpolateParams=true&lazySession=true&queryTags=team:growth,workload:a\\,b,adhoc&logLevel=debug&minTLSVersion=1.3&insecureSkipVerify=true&slowQueryThreshold=2s")
+		require.NoError(t, err)
+		assert.Equal(t, 10, cfg.RetryMax)
+		assert.Equal(t, 2*time.Second, cfg.RetryWaitMin)
+		assert.Equal(t, time.Minute, cfg.RetryWaitMax)
+		assert.Equal(t, 500*time.Millisecond, cfg.PollInterval)
+		assert.Equal(t, 120*time.Second, cfg.ClientTimeout)
+		assert.Equal(t, 10*time.Second, cfg.ConnectTimeout)
+		assert.Equal(t, 2*time.Second, cfg.SlowQueryThreshold)
+		assert.Equal(t, 15*time.Second, cfg.PingTimeout)
+		assert.Equal(t, 3*time.Second, cfg.CancelGracePeriod)
+		assert.Equal(t, 10*time.Minute, cfg.HeartbeatInterval)
+		assert.Equal(t, 30*time.Minute, cfg.SessionIdleTimeout)
+		assert.Equal(t, 5*time.Minute, cfg.WarehouseStartTimeout)
+		assert.Equal(t, 5, cfg.CircuitBreakerThreshold)
+		assert.Equal(t, 10*time.Second, cfg.CircuitBreakerCooldown)
+		assert.Equal(t, []Endpoint{
+			{HTTPPath: "/sql/1.0/warehouses/b"},
+			{Host: "standby.cloud.databricks.com", Port: 8443, HTTPPath: "/sql/1.0/warehouses/c"},
+		}, cfg.Failover)
+		assert.Equal(t, []Endpoint{{HTTPPath: "/sql/1.0/warehouses/d"}}, cfg.LoadBalanced)
+		assert.Equal(t, LeastOutstanding, cfg.LoadBalancing)
+		assert.False(t, cfg.RunAsync)
+		assert.False(t, cfg.UseArrowBatches)
+		assert.True(t, cfg.UseCloudFetch)
+		assert.False(t, cfg.UseLz4Compression)
+		assert.False(t, cfg.UseGzipCompression)
+		assert.True(t, cfg.UseRESTAPI)
+		assert.Equal(t, 0, cfg.MaxPrefetchPages)
+		assert.Equal(t, int64(1024), cfg.PrefetchMemoryLimit)
+		assert.Equal(t, int64(4096), cfg.MaxPageBytes)
+		assert.Equal(t, int64(1000), cfg.MaxRowsTotal)
+		assert.Equal(t, int64(1048576), cfg.MaxBytesPerQuery)
+		assert.True(t, cfg.ReadOnly)
+		assert.False(t, cfg.RetryNonIdempotent)
+		assert.True(t, cfg.LastInsertId)
+		assert.Equal(t, resultcache.NewMemoryStore(1048576), cfg.ResultCache)
+		assert.Equal(t, 5*time.Minute, cfg.ResultCacheTTL)
+		assert.Equal(t, int64(1048576), cfg.ResultCacheMaxBytes)
+		assert.Equal(t, 3, cfg.MaxDownloadThreads)
+		assert.Equal(t, int64(1048576), cfg.DownloadBandwidthLimit)
+		assert.Equal(t, 200, cfg.MaxIdleConns)
+		assert.Equal(t, 50, cfg.MaxIdleConnsPerHost)
+		assert.Equal(t, time.Minute, cfg.IdleConnTimeout)
+		assert.Equal(t, 5*time.Second, cfg.TLSHandshakeTimeout)
+		assert.False(t, cfg.UseHTTP2)
+		assert.True(t, cfg.DecodeComplexTypes)
+		assert.Equal(t, time.UTC, cfg.NaiveTimestampLocation)
+		assert.Equal(t, TimestampAsMicros, cfg.TimestampConversion)
+		assert.Equal(t, DateAsCivil, cfg.DateConversion)
+		assert.True(t, cfg.ZeroCopyScan)
+		assert.Equal(t, 0, cfg.MaxPreparedStatements)
+		assert.True(t, cfg.ResetSessions)
+		assert.True(t, cfg.InterpolateParams)
+		assert.True(t, cfg.LazySession)
+		assert.Equal(t, map[string]string{"team": "growth", "workload": "a,b", "adhoc": ""}, cfg.StatementTags)
+		assert.Equal(t, "debug", cfg.LogLevel)
+		assert.Equal(t, uint16(tls.VersionTLS13), cfg.TLSConfig.MinVersion)
+		assert.True(t, cfg.TLSConfig.InsecureSkipVerify)
+		assert.Empty(t, cfg.SessionParams)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := ParseDSN(base)
+		require.NoError(t, err)
+		defaults := WithDefaults()
+		assert.Equal(t, defaults.RunAsync, cfg.RunAsync)
+		assert.Equal(t, defaults.UseArrowBatches, cfg.UseArrowBatches)
+		assert.Equal(t, defaults.UseCloudFetch, cfg.UseCloudFetch)
+		assert.Equal(t, defaults.UseLz4Compression, cfg.UseLz4Compression)
+		assert.True(t, cfg.UseGzipCompression)
+		assert.False(t, cfg.UseRESTAPI)
+		assert.Equal(t, defaults.MaxPrefetchPages, cfg.MaxPrefetchPages)
+		assert.Equal(t, defaults.PrefetchMemoryLimit, cfg.PrefetchMemoryLimit)
+		assert.Zero(t, cfg.MaxPageBytes)
+		assert.Zero(t, cfg.MaxRowsTotal)
+		assert.Zero(t, cfg.MaxBytesPerQuery)
+		assert.False(t, cfg.ReadOnly)
+		assert.True(t, cfg.RetryNonIdempotent)
+		assert.False(t, cfg.LastInsertId)
+		assert.Nil(t, cfg.ResultCache)
+		assert.Zero(t, cfg.ResultCacheTTL)
+		assert.Equal(t, defaults.MaxDownloadThreads, cfg.MaxDownloadThreads)
+		assert.Equal(t, defaults.DecodeComplexTypes, cfg.DecodeComplexTypes)
+		assert.Nil(t, cfg.NaiveTimestampLocation)
+		assert.Equal(t, TimestampInLocation, cfg.TimestampConversion)
+		assert.Equal(t, DateAsTime, cfg.DateConversion)
+		assert.False(t, cfg.ZeroCopyScan)
+		assert.Equal(t, defaults.MaxPreparedStatements, cfg.MaxPreparedStatements)
+		assert.False(t, cfg.ResetSessions)
+		assert.False(t, cfg.InterpolateParams)
+		assert.False(t, cfg.LazySession)
+		assert.Nil(t, cfg.StatementTags)
+		assert.Empty(t, cfg.LogLevel)
+		assert.Equal(t, defaults.PollInterval, cfg.PollInterval)
+		assert.Equal(t, defaults.ClientTimeout, cfg.ClientTimeout)
+		assert.Zero(t, cfg.ConnectTimeout)
+		assert.Zero(t, cfg.SlowQueryThreshold)
+		assert.Equal(t, defaults.PingTimeout, cfg.PingTimeout)
+		assert.Equal(t, defaults.CancelGracePeriod, cfg.CancelGracePeriod)
+		assert.Zero(t, cfg.HeartbeatInterval)
+		assert.Zero(t, cfg.SessionIdleTimeout)
+		assert.Zero(t, cfg.WarehouseStartTimeout)
+		assert.Zero(t, cfg.CircuitBreakerThreshold)
+		assert.Empty(t, cfg.Failover)
+		assert.Empty(t, cfg.LoadBalanced)
+		assert.Equal(t, RoundRobin, cfg.LoadBalancing)
+		assert.Equal(t, defaults.CircuitBreakerCooldown, cfg.CircuitBreakerCooldown)
+		assert.Equal(t, defaults.MaxIdleConns, cfg.MaxIdleConns)
+		assert.Equal(t, defaults.MaxIdleConnsPerHost, cfg.MaxIdleConnsPerHost)
+		assert.Equal(t, defaults.IdleConnTimeout, cfg.IdleConnTimeout)
+		assert.Equal(t, defaults.TLSHandshakeTimeout, cfg.TLSHandshakeTimeout)
+		assert.True(t, cfg.UseHTTP2)
+		assert.Equal(t, defaults.TLSConfig, cfg.TLSConfig)
+	})
+
+	invalid := []string{
+		"retryMax=many",
+		"retryWaitMin=soon",
+		"pollInterval=-1s",
+		"clientTimeout=-5",
+		"cancelGracePeriod=soon",
+		"heartbeatInterval=-1m",
+		"sessionIdleTimeout=-1m",
+		"circuitBreakerThreshold=-1",
+		"circuitBreakerCooldown=soon",
+		"failover=standby",
+		"failover=/sql/1.0/warehouses/b,",
+		"failover=standby:https/sql/1.0/warehouses/b",
+		"loadBalanced=standby",
+		"loadBalancing=random",
+		"warehouseStartTimeout=later",
+		"connectTimeout=soon",
+		"slowQueryThreshold=slow",
+		"runAsync=maybe",
+		"useArrowBatches=sometimes",
+		"maxDownloadThreads=0",
+		"useLz4Compression=often",
+		"useGzipCompression=yes",
+		"useRestApi=maybe",
+		"prefetchPages=-1",
+		"prefetchMemoryLimit=lots",
+		"downloadBandwidthLimit=-1",
+		"maxPageBytes=-1",
+		"maxRowsTotal=-1",
+		"maxBytesPerQuery=1GB",
+		"readOnly=sometimes",
+		"retryNonIdempotent=once",
+		"lastInsertId=always",
+		"resultCacheTTL=soon",
+		"resultCacheSize=64MB",
+		"maxIdleConns=-1",
+		"maxIdleConnsPerHost=some",
+		"idleConnTimeout=-1m",
+		"tlsHandshakeTimeout=soon",
+		"useHttp2=maybe",
+		"complexTypeScanner=json",
+		"ntzTimezone=Mars/Olympus_Mons",
+		"timestampConversion=local",
+		"dateConversion=string",
+		"zeroCopyScan=maybe",
+		"preparedStatementCacheSize=-1",
+		"resetSession=always",
+		"interpolateParams=maybe",
+		"lazySession=maybe",
+		"queryTags=team:growth,:batch",
+		"logLevel=verbose",
+		"minTLSVersion=2.0",
+		"insecureSkipVerify=perhaps",
+		"retryWaitMin=1m&retryWaitMax=1s",
+	}
+	for _, params := range invalid {
+		t.Run("invalid "+params, func(t *testing.T) {
+			_, err := ParseDSN(base + "?" + params)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIsClusterPath(t *testing.T) {
+	assert.True(t, IsClusterPath("/sql/protocolv1/o/1234567890123456/0123-456789-abcdefgh"))
+	assert.True(t, IsClusterPath("sql/protocolv1/o/1234567890123456/0123-456789-abcdefgh"))
+	assert.False(t, IsClusterPath("/sql/1.0/warehouses/abc123"))
+	assert.False(t, IsClusterPath("/sql/1.0/endpoints/abc123"))
+	assert.False(t, IsClusterPath(""))
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the placeholder for the token in redacted DSNs
+const redactedToken = "********"
+
+// ToDSN returns the canonical DSN of the config, the inverse of ParseDSN.
+// Settings equal to their defaults are left out and params are sorted.
+// Authenticators other than personal access tokens have no DSN form and are not included.
+func (ucfg UserConfig) ToDSN() string {
+	return ucfg.toDSN(false)
+}
+
+// ToRedactedDSN returns the canonical DSN of the config with the token replaced by a placeholder,
+// safe to write to logs.
+func (ucfg UserConfig) ToRedactedDSN() string {
+	return ucfg.toDSN(true)
+}
+
+func (ucfg UserConfig) toDSN(redact bool) string {
+	var sb strings.Builder
+
+	if ucfg.Protocol == "http" {
+		sb.WriteString("http://")
+	}
+	if ucfg.AccessToken != "" {
+		if redact {
+			sb.WriteString("token:" + redactedToken)
+		} else {
+			sb.WriteString(url.UserPassword("token", ucfg.AccessToken).String())
+		}
+		sb.WriteString("@")
+	}
+	sb.WriteString(ucfg.Host)
+	if ucfg.Port != 0 {
+		sb.WriteString(":" + strconv.Itoa(ucfg.Port))
+	}
+	if ucfg.HTTPPath != "" {
+		sb.WriteString("/" + strings.TrimPrefix(ucfg.HTTPPath, "/"))
+	}
+
+	if params := ucfg.dsnParams(); len(params) > 0 {
+		// url.Values.Encode sorts by key
+		sb.WriteString("?" + params.Encode())
+	}
+	return sb.String()
+}
+
+func (ucfg UserConfig) dsnParams() url.Values {
+	defaults := UserConfig{}.WithDefaults()
+	params := url.Values{}
+
+	if ucfg.Catalog != "" {
+		params.Set("catalog", ucfg.Catalog)
+	}
+	if ucfg.Schema != "" {
+		params.Set("schema", ucfg.Schema)
+	}
+	if ucfg.MaxRows != 0 && ucfg.MaxRows != defaults.MaxRows {
+		params.Set("maxRows", strconv.Itoa(ucfg.MaxRows))
+	}
+	if ucfg.QueryTimeout > 0 {
+		params.Set("timeout", strconv.Itoa(int(ucfg.QueryTimeout/time.Second)))
+	}
+	if ucfg.UserAgentEntry != "" {
+		params.Set("userAgentEntry", ucfg.UserAgentEntry)
+	}
+	if ucfg.RetryMax != 0 && ucfg.RetryMax != defaults.RetryMax {
+		params.Set("retryMax", strconv.Itoa(ucfg.RetryMax))
+	}
+	if ucfg.RetryWaitMin != 0 && ucfg.RetryWaitMin != defaults.RetryWaitMin {
+		params.Set("retryWaitMin", ucfg.RetryWaitMin.String())
+	}
+	if ucfg.RetryWaitMax != 0 && ucfg.RetryWaitMax != defaults.RetryWaitMax {
+		params.Set("retryWaitMax", ucfg.RetryWaitMax.String())
+	}
+	if ucfg.TokenCachePath != "" {
+		params.Set("tokenCachePath", ucfg.TokenCachePath)
+	}
+	if len(ucfg.TokenCacheKey) > 0 {
+		params.Set("tokenCacheKey", base64.StdEncoding.EncodeToString(ucfg.TokenCacheKey))
+	}
+
+	for k, v := range ucfg.SessionParams {
+		params.Set(k, v)
+	}
+	// ParseDSN keeps the timezone as a session param as well, only add it when it is missing there
+	if ucfg.Location != nil && ucfg.Location != time.UTC && !hasParam(params, "timezone") {
+		params.Set("timezone", ucfg.Location.String())
+	}
+	return params
+}
+
+func hasParam(params url.Values, name string) bool {
+	for k := range params {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
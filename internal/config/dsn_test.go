@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserConfig_ToDSN(t *testing.T) {
+	tests := []struct {
+		name        string
+		dsn         string
+		wantDSN     string
+		wantRedacts string
+	}{
+		{
+			name:        "minimal",
+			dsn:         "token:supersecret@example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a",
+			wantDSN:     "token:supersecret@example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a",
+			wantRedacts: "token:********@example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a",
+		},
+		{
+			name:        "params are sorted and defaults left out",
+			dsn:         "token:supersecret@example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?timeout=100&maxRows=100000&schema=s&catalog=c&ANSI_MODE=true&retryWaitMax=1m&userAgentEntry=partner",
+			wantDSN:     "token:supersecret@example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?ANSI_MODE=true&catalog=c&retryWaitMax=1m0s&schema=s&timeout=100&userAgentEntry=partner",
+			wantRedacts: "token:********@example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a?ANSI_MODE=true&catalog=c&retryWaitMax=1m0s&schema=s&timeout=100&userAgentEntry=partner",
+		},
+		{
+			name:        "http without token",
+			dsn:         "http://localhost:8080/sql/1.0/endpoints/12346a5b5b0e123a?timezone=Asia%2FSeoul",
+			wantDSN:     "http://localhost:8080/sql/1.0/endpoints/12346a5b5b0e123a?timezone=Asia%2FSeoul",
+			wantRedacts: "http://localhost:8080/sql/1.0/endpoints/12346a5b5b0e123a?timezone=Asia%2FSeoul",
+		},
+		{
+			name:        "token with special characters",
+			dsn:         "token:a%2Fb%40c@example.cloud.databricks.com:443/sql",
+			wantDSN:     "token:a%2Fb%40c@example.cloud.databricks.com:443/sql",
+			wantRedacts: "token:********@example.cloud.databricks.com:443/sql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseDSN(tt.dsn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDSN, cfg.ToDSN())
+			assert.Equal(t, tt.wantRedacts, cfg.ToRedactedDSN())
+
+			// parsing the canonical DSN gives back the same config
+			roundTrip, err := ParseDSN(cfg.ToDSN())
+			require.NoError(t, err)
+			assert.Equal(t, cfg.UserConfig, roundTrip.UserConfig)
+		})
+	}
+
+	t.Run("location without timezone session param", func(t *testing.T) {
+		location, _ := time.LoadLocation("Europe/Paris")
+		ucfg := UserConfig{Host: "example.cloud.databricks.com", Port: 443, Location: location}
+		assert.Equal(t, "example.cloud.databricks.com:443?timezone=Europe%2FParis", ucfg.ToDSN())
+	})
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/databricks/databricks-sql-go/auth/pat"
+	"github.com/pkg/errors"
+)
+
+// Environment variables read by FromEnv, named after the Databricks CLI and SDK conventions.
+const (
+	EnvHost     = "DATABRICKS_HOST"
+	EnvHTTPPath = "DATABRICKS_HTTP_PATH"
+	EnvToken    = "DATABRICKS_TOKEN"
+	EnvCatalog  = "DATABRICKS_CATALOG"
+	EnvSchema   = "DATABRICKS_SCHEMA"
+)
+
+// FromEnv returns a UserConfig populated from the Databricks environment variables.
+// Fields of unset variables are left empty. DATABRICKS_HOST may include a scheme and a port.
+func FromEnv() (UserConfig, error) {
+	var ucfg UserConfig
+
+	if host := os.Getenv(EnvHost); host != "" {
+		var err error
+		if ucfg.Protocol, ucfg.Host, ucfg.Port, err = parseHost(host); err != nil {
+			return UserConfig{}, errors.Wrapf(err, "invalid %s", EnvHost)
+		}
+	}
+
+	if path := os.Getenv(EnvHTTPPath); path != "" {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		ucfg.HTTPPath = path
+	}
+
+	if token := os.Getenv(EnvToken); token != "" {
+		ucfg.AccessToken = token
+		ucfg.Authenticator = &pat.PATAuth{AccessToken: token}
+	}
+
+	ucfg.Catalog = os.Getenv(EnvCatalog)
+	ucfg.Schema = os.Getenv(EnvSchema)
+
+	return ucfg, nil
+}
+
+// parseHost splits a workspace host that may include a scheme and a port, as used by the
+// Databricks CLI and SDKs. The port is 0 when not given.
+func parseHost(host string) (protocol, hostName string, port int, err error) {
+	if !strings.HasPrefix(host, "https://") && !strings.HasPrefix(host, "http://") {
+		host = "https://" + host
+	}
+	parsedURL, err := url.Parse(host)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if parsedURL.Port() != "" {
+		if port, err = strconv.Atoi(parsedURL.Port()); err != nil {
+			return "", "", 0, errors.Wrap(err, "invalid port")
+		}
+	}
+	return parsedURL.Scheme, parsedURL.Hostname(), port, nil
+}
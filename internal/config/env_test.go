@@ -0,0 +1,109 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/databricks/databricks-sql-go/auth/pat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    UserConfig
+		wantErr bool
+	}{
+		{
+			name: "no variables",
+			env:  map[string]string{},
+			want: UserConfig{},
+		},
+		{
+			name: "all variables",
+			env: map[string]string{
+				EnvHost:     "example.cloud.databricks.com",
+				EnvHTTPPath: "sql/1.0/warehouses/abc",
+				EnvToken:    "supersecret",
+				EnvCatalog:  "main",
+				EnvSchema:   "default",
+			},
+			want: UserConfig{
+				Protocol:      "https",
+				Host:          "example.cloud.databricks.com",
+				HTTPPath:      "/sql/1.0/warehouses/abc",
+				AccessToken:   "supersecret",
+				Authenticator: &pat.PATAuth{AccessToken: "supersecret"},
+				Catalog:       "main",
+				Schema:        "default",
+			},
+		},
+		{
+			name: "host with scheme and port",
+			env:  map[string]string{EnvHost: "http://localhost:8080/"},
+			want: UserConfig{
+				Protocol: "http",
+				Host:     "localhost",
+				Port:     8080,
+			},
+		},
+		{
+			name:    "invalid port",
+			env:     map[string]string{EnvHost: "https://example.cloud.databricks.com:port"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{EnvHost, EnvHTTPPath, EnvToken, EnvCatalog, EnvSchema} {
+				t.Setenv(name, tt.env[name])
+			}
+			got, err := FromEnv()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUserConfig_WithFallback(t *testing.T) {
+	env := UserConfig{
+		Protocol:      "https",
+		Host:          "env-host",
+		Port:          8443,
+		HTTPPath:      "/env-path",
+		AccessToken:   "env-token",
+		Authenticator: &pat.PATAuth{AccessToken: "env-token"},
+		Catalog:       "env-catalog",
+		Schema:        "env-schema",
+	}
+
+	t.Run("fills unset values", func(t *testing.T) {
+		got := UserConfig{}.WithDefaults().WithFallback(env)
+		assert.Equal(t, "env-host", got.Host)
+		assert.Equal(t, 8443, got.Port)
+		assert.Equal(t, "/env-path", got.HTTPPath)
+		assert.Equal(t, "env-token", got.AccessToken)
+		assert.Equal(t, env.Authenticator, got.Authenticator)
+		assert.Equal(t, "env-catalog", got.Catalog)
+		assert.Equal(t, "env-schema", got.Schema)
+	})
+
+	t.Run("keeps set values", func(t *testing.T) {
+		ucfg := UserConfig{
+			Protocol:      "https",
+			Host:          "dsn-host",
+			Port:          443,
+			HTTPPath:      "/dsn-path",
+			AccessToken:   "dsn-token",
+			Authenticator: &pat.PATAuth{AccessToken: "dsn-token"},
+			Catalog:       "dsn-catalog",
+			Schema:        "dsn-schema",
+		}
+		assert.Equal(t, ucfg, ucfg.WithFallback(env))
+	})
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/databricks/databricks-sql-go/auth/pat"
+	"github.com/pkg/errors"
+)
+
+// DefaultProfile is the profile used by the Databricks CLI when none is given.
+const DefaultProfile = "DEFAULT"
+
+// profile attributes mapped to the DSN auth params, so profiles set up authentication the same way as a DSN
+var profileAuthParams = map[string]string{
+	"client_id":           "clientId",
+	"client_secret":       "clientSecret",
+	"azure_tenant_id":     "azureTenantId",
+	"azure_client_id":     "clientId",
+	"azure_client_secret": "clientSecret",
+	"google_credentials":  "gcpCredentials",
+}
+
+// profile auth_type values of the Databricks CLI and SDKs mapped to the driver's auth types
+var profileAuthTypes = map[string]string{
+	"external-browser":    "oauth-u2m",
+	"azure-client-secret": "azure-sp",
+	"google-credentials":  "gcp",
+	"azure-cli":           "default",
+	"databricks-cli":      "default",
+}
+
+// ConfigFilePath returns the path of the Databricks CLI config file,
+// DATABRICKS_CONFIG_FILE if set and ~/.databrickscfg otherwise.
+func ConfigFilePath() (string, error) {
+	if path := os.Getenv("DATABRICKS_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to find home directory")
+	}
+	return filepath.Join(home, ".databrickscfg"), nil
+}
+
+// LoadProfile returns the connection settings of a profile in the Databricks CLI config file.
+// host, token, http_path or warehouse_id, catalog, schema and the auth_type attributes are supported.
+func LoadProfile(name string) (UserConfig, error) {
+	path, err := ConfigFilePath()
+	if err != nil {
+		return UserConfig{}, err
+	}
+	profiles, err := readConfigFile(path)
+	if err != nil {
+		return UserConfig{}, err
+	}
+	attrs, ok := profiles[name]
+	if !ok {
+		return UserConfig{}, errors.Errorf("profile %s not found in %s", name, path)
+	}
+	return profileConfig(attrs)
+}
+
+func profileConfig(attrs map[string]string) (UserConfig, error) {
+	var ucfg UserConfig
+
+	if host := attrs["host"]; host != "" {
+		var err error
+		if ucfg.Protocol, ucfg.Host, ucfg.Port, err = parseHost(host); err != nil {
+			return UserConfig{}, errors.Wrap(err, "invalid profile host")
+		}
+	}
+
+	switch {
+	case attrs["http_path"] != "":
+		ucfg.HTTPPath = "/" + strings.TrimPrefix(attrs["http_path"], "/")
+	case attrs["warehouse_id"] != "":
+		ucfg.HTTPPath = "/sql/1.0/warehouses/" + attrs["warehouse_id"]
+	}
+	ucfg.Catalog = attrs["catalog"]
+	ucfg.Schema = attrs["schema"]
+	ucfg.AccessToken = attrs["token"]
+
+	params := url.Values{}
+	for attr, param := range profileAuthParams {
+		if v := attrs[attr]; v != "" {
+			params.Set(param, v)
+		}
+	}
+	authType := attrs["auth_type"]
+	if mapped, ok := profileAuthTypes[authType]; ok {
+		authType = mapped
+	}
+	if authType == "" {
+		switch {
+		case ucfg.AccessToken != "":
+			authType = "pat"
+		case attrs["azure_client_secret"] != "":
+			authType = "azure-sp"
+		case attrs["client_id"] != "" && attrs["client_secret"] != "":
+			authType = "oauth-m2m"
+		}
+	}
+	if authType == "" {
+		return ucfg, nil
+	}
+
+	params.Set("authType", authType)
+	if authType == "pat" {
+		ucfg.Authenticator = &pat.PATAuth{AccessToken: ucfg.AccessToken}
+	}
+	if err := parseAuthParams(&ucfg, params); err != nil {
+		return UserConfig{}, errors.Wrap(err, "invalid profile")
+	}
+	return ucfg, nil
+}
+
+// readConfigFile parses the INI formatted config file into attributes by profile
+func readConfigFile(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is the user's own config file
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read Databricks config file")
+	}
+	defer f.Close()
+
+	profiles := map[string]map[string]string{}
+	var current map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if profiles[name] == nil {
+				profiles[name] = map[string]string{}
+			}
+			current = profiles[name]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || current == nil {
+			continue
+		}
+		current[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to read Databricks config file")
+	}
+	return profiles, nil
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/databricks/databricks-sql-go/auth/oauth/m2m"
+	"github.com/databricks/databricks-sql-go/auth/pat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `
+; profiles shared with the Databricks CLI
+[DEFAULT]
+host  = https://example.cloud.databricks.com
+token = dapi123
+warehouse_id = abc123
+
+[sp]
+host          = adb-123.azuredatabricks.net:8443
+http_path     = sql/1.0/warehouses/def456
+client_id     = id
+client_secret = secret
+catalog       = main
+
+# no credentials
+[empty]
+host = https://other.cloud.databricks.com
+
+[bad]
+host      = https://example.cloud.databricks.com
+auth_type = nope
+`
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".databrickscfg")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0600))
+	t.Setenv("DATABRICKS_CONFIG_FILE", path)
+
+	tests := []struct {
+		name    string
+		profile string
+		want    UserConfig
+		wantErr bool
+	}{
+		{
+			name:    "pat with warehouse id",
+			profile: DefaultProfile,
+			want: UserConfig{
+				Protocol:      "https",
+				Host:          "example.cloud.databricks.com",
+				HTTPPath:      "/sql/1.0/warehouses/abc123",
+				AccessToken:   "dapi123",
+				Authenticator: &pat.PATAuth{AccessToken: "dapi123"},
+			},
+		},
+		{
+			name:    "oauth m2m with http path",
+			profile: "sp",
+			want: UserConfig{
+				Protocol:      "https",
+				Host:          "adb-123.azuredatabricks.net",
+				Port:          8443,
+				HTTPPath:      "/sql/1.0/warehouses/def456",
+				Catalog:       "main",
+				Authenticator: m2m.NewAuthenticator("id", "secret", "adb-123.azuredatabricks.net"),
+			},
+		},
+		{
+			name:    "no credentials",
+			profile: "empty",
+			want: UserConfig{
+				Protocol: "https",
+				Host:     "other.cloud.databricks.com",
+			},
+		},
+		{
+			name:    "unknown auth type",
+			profile: "bad",
+			wantErr: true,
+		},
+		{
+			name:    "missing profile",
+			profile: "missing",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadProfile(tt.profile)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseDSNWithProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".databrickscfg")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0600))
+	t.Setenv("DATABRICKS_CONFIG_FILE", path)
+
+	t.Run("profile only", func(t *testing.T) {
+		cfg, err := ParseDSN("?profile=DEFAULT")
+		require.NoError(t, err)
+		assert.Equal(t, "example.cloud.databricks.com", cfg.Host)
+		assert.Equal(t, 443, cfg.Port)
+		assert.Equal(t, "/sql/1.0/warehouses/abc123", cfg.HTTPPath)
+		assert.Equal(t, &pat.PATAuth{AccessToken: "dapi123"}, cfg.Authenticator)
+		assert.Empty(t, cfg.SessionParams)
+	})
+
+	t.Run("DSN values take precedence", func(t *testing.T) {
+		cfg, err := ParseDSN("token:dsn-token@dsn-host:443/sql/1.0/warehouses/dsn?profile=DEFAULT")
+		require.NoError(t, err)
+		assert.Equal(t, "dsn-host", cfg.Host)
+		assert.Equal(t, "/sql/1.0/warehouses/dsn", cfg.HTTPPath)
+		assert.Equal(t, &pat.PATAuth{AccessToken: "dsn-token"}, cfg.Authenticator)
+	})
+
+	t.Run("missing profile", func(t *testing.T) {
+		_, err := ParseDSN("?profile=missing")
+		assert.Error(t, err)
+	})
+}
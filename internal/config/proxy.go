@@ -0,0 +1,87 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DSN parameters used to set up the proxy, they are never passed on as session params
+var proxyParams = []string{
+	"proxyHost",
+	"proxyPort",
+	"proxyAuth",
+}
+
+var proxySchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// parseProxyParams sets cfg.Proxy from the proxy DSN parameters and removes them from params.
+// proxyHost may include the scheme of the proxy, http by default, proxyAuth is user:password.
+func parseProxyParams(cfg *Config, params url.Values) error {
+	defer func() {
+		for _, p := range proxyParams {
+			params.Del(p)
+		}
+	}()
+
+	host, port, auth := params.Get("proxyHost"), params.Get("proxyPort"), params.Get("proxyAuth")
+	if host == "" {
+		if port != "" || auth != "" {
+			return errors.New("invalid DSN: proxyPort and proxyAuth require proxyHost")
+		}
+		return nil
+	}
+
+	if port != "" {
+		if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+			return errors.New("invalid DSN: proxyPort param is not a valid port")
+		}
+	}
+	proxy, err := NewProxyURL(host, port, auth)
+	if err != nil {
+		return errors.Wrap(err, "invalid DSN")
+	}
+	cfg.Proxy = proxy
+	return nil
+}
+
+func copyURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	c := *u
+	return &c
+}
+
+// NewProxyURL returns the URL of a proxy from its host, which may include the scheme, its port, which may be empty,
+// and the user:password credentials, which may be empty.
+func NewProxyURL(host, port, auth string) (*url.URL, error) {
+	scheme := "http"
+	if s, h, ok := strings.Cut(host, "://"); ok {
+		scheme, host = strings.ToLower(s), h
+	}
+	if !proxySchemes[scheme] {
+		return nil, errors.Errorf("proxy scheme must be one of http, https, socks5 or socks5h, got %s", scheme)
+	}
+	if host == "" || strings.ContainsAny(host, "/@?#") {
+		return nil, errors.Errorf("invalid proxy host %s", host)
+	}
+	if port != "" {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+
+	proxy := &url.URL{Scheme: scheme, Host: host}
+	if auth != "" {
+		user, password, _ := strings.Cut(auth, ":")
+		proxy.User = url.UserPassword(user, password)
+	}
+	return proxy, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDSNWithProxyParams(t *testing.T) {
+	base := "token:supersecret@example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a"
+
+	t.Run("http proxy with authentication", func(t *testing.T) {
+		cfg, err := ParseDSN(base + "?proxyHost=proxy.internal&proxyPort=3128&proxyAuth=user:p%40ss&catalog=main")
+		require.NoError(t, err)
+		assert.Equal(t, &url.URL{Scheme: "http", Host: "proxy.internal:3128", User: url.UserPassword("user", "p@ss")}, cfg.Proxy)
+		assert.Equal(t, "main", cfg.Catalog)
+		assert.Empty(t, cfg.SessionParams)
+	})
+
+	t.Run("socks proxy", func(t *testing.T) {
+		cfg, err := ParseDSN(base + "?proxyHost=socks5://10.0.0.1&proxyPort=1080")
+		require.NoError(t, err)
+		assert.Equal(t, "socks5://10.0.0.1:1080", cfg.Proxy.String())
+	})
+
+	t.Run("no proxy", func(t *testing.T) {
+		cfg, err := ParseDSN(base)
+		require.NoError(t, err)
+		assert.Nil(t, cfg.Proxy)
+	})
+
+	invalid := []string{
+		"proxyPort=3128",
+		"proxyAuth=user:password",
+		"proxyHost=proxy.internal&proxyPort=http",
+		"proxyHost=proxy.internal&proxyPort=70000",
+		"proxyHost=ftp://proxy.internal",
+		"proxyHost=http://",
+	}
+	for _, params := range invalid {
+		t.Run("invalid "+params, func(t *testing.T) {
+			_, err := ParseDSN(base + "?" + params)
+			assert.Error(t, err)
+		})
+	}
+}
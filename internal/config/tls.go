@@ -0,0 +1,111 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/pkg/errors"
+)
+
+// DSN parameters used to set up TLS, they are never passed on as session params
+var tlsParams = []string{
+	"tlsClientCert",
+	"tlsClientKey",
+	"tlsCACert",
+	"tlsServerName",
+	"minTLSVersion",
+	"insecureSkipVerify",
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSParams applies the TLS DSN parameters to cfg.TLSConfig and removes them from params.
+// tlsClientCert and tlsClientKey are PEM files of the client certificate used for mutual TLS,
+// tlsCACert is a PEM bundle of CAs trusted in addition to the system roots.
+func parseTLSParams(cfg *Config, params url.Values) error {
+	defer func() {
+		for _, p := range tlsParams {
+			params.Del(p)
+		}
+	}()
+
+	certFile, keyFile := params.Get("tlsClientCert"), params.Get("tlsClientKey")
+	caFile, serverName := params.Get("tlsCACert"), params.Get("tlsServerName")
+	minVersion, insecure := params.Get("minTLSVersion"), params.Get("insecureSkipVerify")
+	if certFile == "" && keyFile == "" && caFile == "" && serverName == "" && minVersion == "" && insecure == "" {
+		return nil
+	}
+
+	if cfg.TLSConfig == nil {
+		cfg.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if minVersion != "" {
+		version, ok := tlsVersions[minVersion]
+		if !ok {
+			return errors.Errorf("invalid DSN: minTLSVersion must be one of 1.0, 1.1, 1.2 or 1.3, got %s", minVersion)
+		}
+		cfg.TLSConfig.MinVersion = version
+	}
+
+	if insecure != "" {
+		skip, err := strconv.ParseBool(insecure)
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: insecureSkipVerify param is not a boolean")
+		}
+		if skip {
+			logger.Warn().Msg("insecureSkipVerify is set, the server certificate will not be verified")
+		}
+		cfg.TLSConfig.InsecureSkipVerify = skip // #nosec G402 -- explicitly requested by the user
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return errors.New("invalid DSN: tlsClientCert and tlsClientKey must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN: unable to load client certificate")
+		}
+		cfg.TLSConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		pool, err := LoadCertPool(caFile)
+		if err != nil {
+			return errors.Wrap(err, "invalid DSN")
+		}
+		cfg.TLSConfig.RootCAs = pool
+	}
+
+	if serverName != "" {
+		cfg.TLSConfig.ServerName = serverName
+	}
+	return nil
+}
+
+// LoadCertPool returns the system cert pool with the certificates of the PEM bundle at path added.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is provided by the user
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read CA bundle")
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
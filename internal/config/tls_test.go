@@ -0,0 +1,69 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "client"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600))
+	return certFile, keyFile
+}
+
+func TestParseDSNWithTLSParams(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+	base := "token:supersecret@example.cloud.databricks.com:443/sql/1.0/endpoints/12346a5b5b0e123a"
+
+	t.Run("client certificate and CA bundle", func(t *testing.T) {
+		cfg, err := ParseDSN(base + "?tlsClientCert=" + certFile + "&tlsClientKey=" + keyFile + "&tlsCACert=" + certFile + "&tlsServerName=proxy.internal&catalog=main")
+		require.NoError(t, err)
+		require.Len(t, cfg.TLSConfig.Certificates, 1)
+		assert.NotNil(t, cfg.TLSConfig.RootCAs)
+		assert.Equal(t, "proxy.internal", cfg.TLSConfig.ServerName)
+		assert.Equal(t, uint16(0x0303), cfg.TLSConfig.MinVersion)
+		// TLS params are not session params
+		assert.Empty(t, cfg.SessionParams)
+		assert.Equal(t, "main", cfg.Catalog)
+	})
+
+	t.Run("defaults without TLS params", func(t *testing.T) {
+		cfg, err := ParseDSN(base)
+		require.NoError(t, err)
+		assert.Equal(t, WithDefaults().TLSConfig, cfg.TLSConfig)
+	})
+
+	t.Run("invalid TLS params", func(t *testing.T) {
+		_, err := ParseDSN(base + "?tlsClientCert=" + certFile)
+		assert.Error(t, err)
+		_, err = ParseDSN(base + "?tlsClientCert=" + certFile + "&tlsClientKey=/does/not/exist")
+		assert.Error(t, err)
+		_, err = ParseDSN(base + "?tlsCACert=" + keyFile)
+		assert.Error(t, err)
+	})
+}
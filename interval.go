@@ -0,0 +1,232 @@
+package dbsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var errIntervalInvalid = "databricks: invalid interval value %q"
+var errIntervalScan = "databricks: unable to scan type %T into Interval"
+
+// Interval is the value of an INTERVAL column. Year-month intervals only have Months and
+// day-time intervals only have Duration. Scan INTERVAL columns into an Interval to get their value:
+//
+//	var ttl dbsql.Interval
+//	err := db.QueryRowContext(ctx, "select ttl from jobs where id = 1").Scan(&ttl)
+//	deadline := start.Add(ttl.Duration)
+//
+// Day-time intervals longer than about 292 years don't fit a time.Duration and can't be scanned.
+type Interval struct {
+	Months   int64
+	Duration time.Duration
+}
+
+var _ sql.Scanner = (*Interval)(nil)
+var _ driver.Valuer = Interval{}
+
+// interval fields, from the largest to the smallest unit
+var yearMonthFields = []string{"YEAR", "MONTH"}
+var dayTimeFields = []string{"DAY", "HOUR", "MINUTE", "SECOND"}
+var dayTimeUnits = []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+// ParseInterval parses an interval in the format returned by the server, e.g. "1-2" for
+// 1 year and 2 months or "3 04:05:06.5" for 3 days and 4:05:06.5 hours, or an ANSI interval
+// literal like INTERVAL '1-2' YEAR TO MONTH.
+func ParseInterval(s string) (Interval, error) {
+	value := strings.TrimSpace(s)
+	var start, end string
+	negative := false
+
+	if len(value) > 9 && strings.EqualFold(value[:9], "INTERVAL ") {
+		// INTERVAL [-]'<value>' <start field> [TO <end field>]
+		literal := strings.TrimSpace(value[9:])
+		if strings.HasPrefix(literal, "-") {
+			negative = true
+			literal = strings.TrimSpace(literal[1:])
+		}
+		closing := strings.LastIndexByte(literal, '\'')
+		if !strings.HasPrefix(literal, "'") || closing < 1 {
+			return Interval{}, errors.Errorf(errIntervalInvalid, s)
+		}
+		value = literal[1:closing]
+
+		fields := strings.Fields(strings.ToUpper(literal[closing+1:]))
+		switch {
+		case len(fields) == 1:
+			start, end = fields[0], fields[0]
+		case len(fields) == 3 && fields[1] == "TO":
+			start, end = fields[0], fields[2]
+		default:
+			return Interval{}, errors.Errorf(errIntervalInvalid, s)
+		}
+	}
+
+	if strings.HasPrefix(value, "-") {
+		negative = !negative
+		value = value[1:]
+	} else if strings.HasPrefix(value, "+") {
+		value = value[1:]
+	}
+
+	if start == "" {
+		// server format, day-time intervals have a time part
+		if strings.ContainsAny(value, " :") {
+			start, end = "DAY", "SECOND"
+		} else {
+			start, end = "YEAR", "MONTH"
+		}
+	}
+
+	var interval Interval
+	var err error
+	if indexOf(yearMonthFields, start) >= 0 {
+		interval.Months, err = parseYearMonth(value, start, end)
+	} else {
+		interval.Duration, err = parseDayTime(value, start, end)
+	}
+	if err != nil {
+		return Interval{}, errors.Errorf(errIntervalInvalid, s)
+	}
+
+	if negative {
+		interval.Months = -interval.Months
+		interval.Duration = -interval.Duration
+	}
+	return interval, nil
+}
+
+// parseYearMonth returns the number of months of a year-month interval value like "1-2"
+func parseYearMonth(value, start, end string) (int64, error) {
+	from, to := indexOf(yearMonthFields, start), indexOf(yearMonthFields, end)
+	parts := strings.Split(value, "-")
+	if from < 0 || to < from || len(parts) != to-from+1 {
+		return 0, errors.New("invalid year-month interval")
+	}
+
+	var months int64
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || n < 0 {
+			return 0, errors.New("invalid year-month interval")
+		}
+		if yearMonthFields[from+i] == "YEAR" {
+			n *= 12
+		}
+		months += n
+	}
+	return months, nil
+}
+
+// parseDayTime returns the duration of a day-time interval value like "3 04:05:06.5"
+func parseDayTime(value, start, end string) (time.Duration, error) {
+	from, to := indexOf(dayTimeFields, start), indexOf(dayTimeFields, end)
+	parts := strings.Split(strings.Replace(value, " ", ":", 1), ":")
+	if from < 0 || to < from || len(parts) != to-from+1 {
+		return 0, errors.New("invalid day-time interval")
+	}
+
+	var d time.Duration
+	for i, part := range parts {
+		unit := dayTimeUnits[from+i]
+		var fraction string
+		if unit == time.Second {
+			part, fraction, _ = strings.Cut(part, ".")
+		}
+
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || n < 0 || n > math.MaxInt64/int64(unit) {
+			return 0, errors.New("invalid day-time interval")
+		}
+		d += time.Duration(n) * unit
+
+		if fraction != "" {
+			if len(fraction) > 9 {
+				fraction = fraction[:9]
+			}
+			nanos, err := strconv.ParseInt(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+			if err != nil || nanos < 0 {
+				return 0, errors.New("invalid day-time interval")
+			}
+			d += time.Duration(nanos)
+		}
+		if d < 0 {
+			return 0, errors.New("day-time interval overflows time.Duration")
+		}
+	}
+	return d, nil
+}
+
+func indexOf(fields []string, field string) int {
+	for i := range fields {
+		if fields[i] == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// String returns i as an ANSI interval literal, e.g. INTERVAL '1-2' YEAR TO MONTH
+func (i Interval) String() string {
+	if i.Months != 0 {
+		sign, months := "", i.Months
+		if months < 0 {
+			sign, months = "-", -months
+		}
+		return fmt.Sprintf("INTERVAL '%s%d-%d' YEAR TO MONTH", sign, months/12, months%12)
+	}
+
+	sign, d := "", i.Duration
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	seconds := d / time.Second
+	nanos := d - seconds*time.Second
+
+	var fraction string
+	if nanos > 0 {
+		fraction = strings.TrimRight(fmt.Sprintf(".%09d", nanos), "0")
+	}
+	return fmt.Sprintf("INTERVAL '%s%d %02d:%02d:%02d%s' DAY TO SECOND", sign, days, hours, minutes, seconds, fraction)
+}
+
+// Scan implements sql.Scanner, INTERVAL values are returned by the driver as strings
+func (i *Interval) Scan(src any) error {
+	var val Interval
+	var err error
+	switch v := src.(type) {
+	case string:
+		val, err = ParseInterval(v)
+	case []byte:
+		val, err = ParseInterval(string(v))
+	case Interval:
+		val = v
+	case time.Duration:
+		val = Interval{Duration: v}
+	default:
+		err = errors.Errorf(errIntervalScan, src)
+	}
+	if err != nil {
+		return err
+	}
+
+	*i = val
+	return nil
+}
+
+// Value implements driver.Valuer, the interval is passed as an ANSI interval literal string
+func (i Interval) Value() (driver.Value, error) {
+	return i.String(), nil
+}
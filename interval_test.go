@@ -0,0 +1,76 @@
+package dbsql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterval(t *testing.T) {
+	day := 24 * time.Hour
+
+	t.Run("should parse intervals", func(t *testing.T) {
+		cases := []struct {
+			in       string
+			expected Interval
+		}{
+			{"1-2", Interval{Months: 14}},
+			{"-1-2", Interval{Months: -14}},
+			{"0-0", Interval{}},
+			{"3 04:05:06.5", Interval{Duration: 3*day + 4*time.Hour + 5*time.Minute + 6*time.Second + 500*time.Millisecond}},
+			{"-3 04:05:06.000000000", Interval{Duration: -(3*day + 4*time.Hour + 5*time.Minute + 6*time.Second)}},
+			{"0 00:00:00.000000001", Interval{Duration: time.Nanosecond}},
+			{"INTERVAL '1-2' YEAR TO MONTH", Interval{Months: 14}},
+			{"INTERVAL -'1-2' YEAR TO MONTH", Interval{Months: -14}},
+			{"interval '10' month", Interval{Months: 10}},
+			{"INTERVAL '2' YEAR", Interval{Months: 24}},
+			{"INTERVAL '3 04:05:06.5' DAY TO SECOND", Interval{Duration: 3*day + 4*time.Hour + 5*time.Minute + 6*time.Second + 500*time.Millisecond}},
+			{"INTERVAL '-01:30' HOUR TO MINUTE", Interval{Duration: -90 * time.Minute}},
+			{"INTERVAL '90.25' SECOND", Interval{Duration: 90*time.Second + 250*time.Millisecond}},
+		}
+		for _, c := range cases {
+			i, err := ParseInterval(c.in)
+			require.NoError(t, err, c.in)
+			assert.Equal(t, c.expected, i, c.in)
+		}
+
+		for _, in := range []string{"", "abc", "1", "1-2-3", "1--2", "1 02:03", "INTERVAL '1-2' DAY TO SECOND", "INTERVAL 1 DAY", "INTERVAL '1' WEEK", "200000 00:00:00"} {
+			_, err := ParseInterval(in)
+			assert.Error(t, err, in)
+		}
+	})
+
+	t.Run("should format intervals", func(t *testing.T) {
+		assert.Equal(t, "INTERVAL '1-2' YEAR TO MONTH", Interval{Months: 14}.String())
+		assert.Equal(t, "INTERVAL '-0-3' YEAR TO MONTH", Interval{Months: -3}.String())
+		assert.Equal(t, "INTERVAL '3 04:05:06.5' DAY TO SECOND", Interval{Duration: 3*day + 4*time.Hour + 5*time.Minute + 6*time.Second + 500*time.Millisecond}.String())
+		assert.Equal(t, "INTERVAL '-0 00:01:00' DAY TO SECOND", Interval{Duration: -time.Minute}.String())
+		assert.Equal(t, "INTERVAL '0 00:00:00' DAY TO SECOND", Interval{}.String())
+
+		for _, i := range []Interval{{Months: -14}, {Duration: -(day + time.Nanosecond)}} {
+			parsed, err := ParseInterval(i.String())
+			require.NoError(t, err)
+			assert.Equal(t, i, parsed)
+		}
+	})
+
+	t.Run("should scan values", func(t *testing.T) {
+		var i Interval
+		require.NoError(t, i.Scan("1-2"))
+		assert.Equal(t, Interval{Months: 14}, i)
+		require.NoError(t, i.Scan([]byte("0 01:00:00")))
+		assert.Equal(t, Interval{Duration: time.Hour}, i)
+		require.NoError(t, i.Scan(time.Minute))
+		assert.Equal(t, Interval{Duration: time.Minute}, i)
+
+		assert.EqualError(t, i.Scan(int64(1)), "databricks: unable to scan type int64 into Interval")
+		assert.Error(t, i.Scan("x"))
+		assert.Equal(t, Interval{Duration: time.Minute}, i)
+
+		v, err := i.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "INTERVAL '0 00:01:00' DAY TO SECOND", v)
+	})
+}
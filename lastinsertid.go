@@ -0,0 +1,122 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"strings"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/databricks/databricks-sql-go/logger"
+)
+
+// insertTable returns the parts of the qualified name of the table of an INSERT INTO statement, nil for other
+// statements
+func insertTable(query string) []string {
+	start := -1
+	scanSQL(query, func(i int) {
+		if start < 0 && !isSpace(query[i]) {
+			start = i
+		}
+	})
+	if start < 0 {
+		return nil
+	}
+	rest := query[start:]
+	for _, keyword := range []string{"INSERT", "INTO"} {
+		if len(rest) <= len(keyword) || !strings.EqualFold(rest[:len(keyword)], keyword) || !isSpace(rest[len(keyword)]) {
+			return nil
+		}
+		rest = strings.TrimLeft(rest[len(keyword):], " \t\r\n")
+	}
+	if len(rest) > 5 && strings.EqualFold(rest[:5], "TABLE") && isSpace(rest[5]) {
+		rest = strings.TrimLeft(rest[5:], " \t\r\n")
+	}
+	// the name ends at the first space or parenthesis which is not in a backquoted part
+	quoted := false
+	for i := 0; i < len(rest); i++ {
+		if rest[i] == '`' {
+			quoted = !quoted
+		} else if !quoted && (isSpace(rest[i]) || rest[i] == '(') {
+			rest = rest[:i]
+			break
+		}
+	}
+	return splitQualifiedName(rest)
+}
+
+// quoteName returns the parts of a qualified name as backquoted identifiers
+func quoteName(parts []string) string {
+	quoted := make([]string, len(parts))
+	for i, p := range parts {
+		quoted[i] = "`" + strings.ReplaceAll(p, "`", "``") + "`"
+	}
+	return strings.Join(quoted, ".")
+}
+
+// lastInsertId returns the largest value of the identity column of a table, after an INSERT into it. It is 0 when
+// the table has no identity column or the value can't be read, the INSERT succeeded anyway.
+func (c *conn) lastInsertId(ctx context.Context, table []string) int64 {
+	log := logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "")
+	// the identity values are read from the table, not from a cached result
+	ctx = driverctx.NewContextWithResultCacheBypass(ctx)
+	name := quoteName(table)
+	column, ok := c.identityColumns[name]
+	if !ok {
+		var err error
+		if column, err = c.identityColumn(ctx, table); err != nil {
+			log.Warn().Msgf("databricks: failed to find the identity column of %s: %v", name, err)
+		}
+		if c.identityColumns == nil {
+			c.identityColumns = map[string]string{}
+		}
+		c.identityColumns[name] = column
+	}
+	if column == "" {
+		return 0
+	}
+
+	value, err := c.queryValue(ctx, "SELECT max("+quoteName([]string{column})+") FROM "+name)
+	if err != nil {
+		log.Warn().Msgf("databricks: failed to read the last identity value of %s: %v", name, err)
+		return 0
+	}
+	id, _ := value.(int64)
+	return id
+}
+
+// identityColumn returns the identity column of a table from the information schema, empty when it has none
+func (c *conn) identityColumn(ctx context.Context, table []string) (string, error) {
+	columns, schema := "information_schema.columns", "current_schema()"
+	switch len(table) {
+	case 3:
+		columns, schema = quoteName(table[:1])+".information_schema.columns", quoteLiteral(strings.ToLower(table[1]))
+	case 2:
+		schema = quoteLiteral(strings.ToLower(table[0]))
+	}
+	query := "SELECT column_name FROM " + columns + " WHERE table_schema = " + schema +
+		" AND table_name = " + quoteLiteral(strings.ToLower(table[len(table)-1])) + " AND is_identity = 'YES'"
+	value, err := c.queryValue(ctx, query)
+	column, _ := value.(string)
+	return column, err
+}
+
+// queryValue returns the first value of the first row of the results of a query, nil when there are no rows
+func (c *conn) queryValue(ctx context.Context, query string) (driver.Value, error) {
+	rows, err := c.QueryContext(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, len(rows.Columns()))
+	if err := rows.Next(dest); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(dest) == 0 {
+		return nil, nil
+	}
+	return dest[0], nil
+}
@@ -0,0 +1,112 @@
+package dbsql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertTable(t *testing.T) {
+	cases := map[string][]string{
+		"insert into orders values (1)":                            {"orders"},
+		"  -- new order\n INSERT INTO sales.orders (a) VALUES (1)": {"sales", "orders"},
+		"insert into table main.sales.orders select * from s":      {"main", "sales", "orders"},
+		"insert into `my cat`.sales.`or.ders`(a) values (1)":       {"my cat", "sales", "or.ders"},
+		"insert overwrite orders select * from s":                  nil,
+		"select * from orders":                                     nil,
+		"insertinto orders values (1)":                             nil,
+	}
+	for query, table := range cases {
+		assert.Equal(t, table, insertTable(query), query)
+	}
+}
+
+func TestConn_LastInsertId(t *testing.T) {
+	// returns the direct results of a statement with a single column
+	results := func(name string, typeID cli_service.TTypeId, column *cli_service.TColumn) *cli_service.TSparkDirectResults {
+		return &cli_service.TSparkDirectResults{
+			OperationStatus: &cli_service.TGetOperationStatusResp{
+				OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+			},
+			ResultSetMetadata: &cli_service.TGetResultSetMetadataResp{
+				Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{{
+					ColumnName: name,
+					TypeDesc: &cli_service.TTypeDesc{
+						Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: typeID}}},
+					},
+				}}},
+			},
+			ResultSet:      &cli_service.TFetchResultsResp{Results: &cli_service.TRowSet{Columns: []*cli_service.TColumn{column}}},
+			CloseOperation: &cli_service.TCloseOperationResp{},
+		}
+	}
+
+	var queries []string
+	testClient := &client.TestClient{
+		FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+			queries = append(queries, req.Statement)
+			resp := &cli_service.TExecuteStatementResp{
+				Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+				OperationHandle: &cli_service.TOperationHandle{
+					OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54}, Secret: []byte("b")},
+				},
+			}
+			switch {
+			case strings.Contains(req.Statement, "information_schema"):
+				columns := []string{"id"}
+				if strings.Contains(req.Statement, "'events'") {
+					columns = nil
+				}
+				resp.DirectResults = results("column_name", cli_service.TTypeId_STRING_TYPE, &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: columns}})
+			case strings.HasPrefix(req.Statement, "SELECT max"):
+				resp.DirectResults = results("max(id)", cli_service.TTypeId_BIGINT_TYPE, &cli_service.TColumn{I64Val: &cli_service.TI64Column{Values: []int64{42}}})
+			default:
+				resp.DirectResults = &cli_service.TSparkDirectResults{
+					OperationStatus: &cli_service.TGetOperationStatusResp{
+						OperationState:  cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+						NumModifiedRows: thrift.Int64Ptr(1),
+					},
+					CloseOperation: &cli_service.TCloseOperationResp{},
+				}
+			}
+			return resp, nil
+		},
+	}
+	cfg := config.WithDefaults()
+	cfg.LastInsertId = true
+	testConn := &conn{
+		session: getTestSession(),
+		client:  testClient,
+		cfg:     cfg,
+	}
+
+	res, err := testConn.ExecContext(context.Background(), "insert into sales.orders (total) values (10)", nil)
+	require.NoError(t, err)
+	id, err := res.LastInsertId()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+	assert.Equal(t, []string{
+		"insert into sales.orders (total) values (10)",
+		"SELECT column_name FROM information_schema.columns WHERE table_schema = 'sales' AND table_name = 'orders' AND is_identity = 'YES'",
+		"SELECT max(`id`) FROM `sales`.`orders`",
+	}, queries)
+
+	queries = nil
+	_, err = testConn.ExecContext(context.Background(), "insert into sales.orders (total) values (20)", nil)
+	require.NoError(t, err)
+	assert.Len(t, queries, 2, "the identity column is looked up once")
+
+	queries = nil
+	res, err = testConn.ExecContext(context.Background(), "insert into events values ('click')", nil)
+	require.NoError(t, err)
+	id, _ = res.LastInsertId()
+	assert.Zero(t, id, "the table has no identity column")
+	assert.Len(t, queries, 2)
+}
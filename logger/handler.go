@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Level is the severity of a log message
+type Level int8
+
+const (
+	TraceLevel Level = iota - 1
+	DebugLevel
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	FatalLevel
+	PanicLevel
+	NoLevel Level = Level(zerolog.NoLevel) // messages logged without a level
+)
+
+func (l Level) String() string {
+	return zerolog.Level(l).String()
+}
+
+// ParseLevel returns the level of its name: "trace" "debug" "info" "warn" "error" "fatal" or "panic"
+func ParseLevel(level string) (Level, error) {
+	lv, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return 0, err
+	}
+	return Level(lv), nil
+}
+
+// Handler receives the log messages of the driver, e.g. to route them to zap, slog or logrus.
+// The fields include connId, corrId and queryId when the message is about a connection or a query.
+// Secrets are redacted from the message and the fields before Log is called.
+type Handler interface {
+	Log(level Level, msg string, fields map[string]any)
+}
+
+// HandlerFunc is a function used as a Handler
+type HandlerFunc func(level Level, msg string, fields map[string]any)
+
+func (f HandlerFunc) Log(level Level, msg string, fields map[string]any) {
+	f(level, msg, fields)
+}
+
+// handlerWriter decodes the events written by zerolog and passes them to a Handler
+type handlerWriter struct {
+	h Handler
+}
+
+func (w handlerWriter) Write(p []byte) (int, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return 0, err
+	}
+
+	level := NoLevel
+	if s, ok := fields[zerolog.LevelFieldName].(string); ok {
+		if lv, err := zerolog.ParseLevel(s); err == nil {
+			level = Level(lv)
+		}
+	}
+	msg, _ := fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.LevelFieldName)
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.TimestampFieldName)
+
+	w.h.Log(level, msg, fields)
+	return len(p), nil
+}
+
+// New returns a logger sending the messages of level or above to h
+func New(h Handler, level Level) *DBSQLLogger {
+	return &DBSQLLogger{zerolog.New(redactWriter{handlerWriter{h}}).Level(zerolog.Level(level))}
+}
+
+// AtLevel returns a copy of l logging the messages of level or above
+func (l *DBSQLLogger) AtLevel(level Level) *DBSQLLogger {
+	return &DBSQLLogger{l.Level(zerolog.Level(level))}
+}
+
+var connLoggers sync.Map // connection id -> *DBSQLLogger
+
+// RegisterConnection sets the logger used by WithContext for the messages of a connection,
+// instead of the global Logger. It is called by the driver when a connection is opened with its
+// own handler or level.
+func RegisterConnection(connectionId string, l *DBSQLLogger) {
+	connLoggers.Store(connectionId, l)
+}
+
+// UnregisterConnection removes the logger of a closed connection
+func UnregisterConnection(connectionId string) {
+	connLoggers.Delete(connectionId)
+}
+
+// forConnection returns the logger of a connection, the global Logger if it has none
+func forConnection(connectionId string) *DBSQLLogger {
+	if connectionId != "" {
+		if l, ok := connLoggers.Load(connectionId); ok {
+			return l.(*DBSQLLogger)
+		}
+	}
+	return Logger
+}
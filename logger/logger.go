@@ -37,14 +37,14 @@ func (l *DBSQLLogger) Duration(msg string, start time.Time) {
 }
 
 var Logger = &DBSQLLogger{
-	zerolog.New(os.Stderr).With().Timestamp().Logger(),
+	zerolog.New(redactWriter{os.Stderr}).With().Timestamp().Logger(),
 }
 
 // Enable pretty printing for interactive terminals and json for production.
 func init() {
 	// for tty terminal enable pretty logs
 	if isatty.IsTerminal(os.Stdout.Fd()) && runtime.GOOS != "windows" {
-		Logger = &DBSQLLogger{Logger.Output(zerolog.ConsoleWriter{Out: os.Stderr})}
+		Logger = &DBSQLLogger{Logger.Output(redactWriter{zerolog.ConsoleWriter{Out: os.Stderr}})}
 	} else {
 		// UNIX Time is faster and smaller than most timestamps
 		// If you set zerolog.TimeFieldFormat to an empty string,
@@ -75,9 +75,27 @@ func SetLogLevel(l string) error {
 	}
 }
 
+// GetLevel returns the log level
+func GetLevel() Level {
+	return Level(Logger.GetLevel())
+}
+
 // Sets logging output. Default is os.Stderr. If in terminal, pretty logs are enabled.
+// Secrets are redacted from the logs written to w.
 func SetLogOutput(w io.Writer) {
-	Logger.Logger = Logger.Output(w)
+	Logger.Logger = Logger.Output(redactWriter{w})
+}
+
+// SetHandler sends the logs to h instead of the log output, e.g. to route them to zap, slog or logrus.
+// The log level is kept.
+func SetHandler(h Handler) {
+	Logger.Logger = Logger.Output(redactWriter{handlerWriter{h}})
+}
+
+// AddHook adds a hook run with the level and message of every logged event, e.g. to count the
+// "databricks: query canceled" and "databricks: failed to cancel query" events.
+func AddHook(h zerolog.Hook) {
+	Logger.Logger = Logger.Hook(h)
 }
 
 // Sets log to trace. -1
@@ -129,8 +147,9 @@ func Err(err error) *zerolog.Event {
 }
 
 // WithContext sets connectionId, correlationId, and queryId to be used as fields.
+// It uses the logger registered for the connection, if any.
 func WithContext(connectionId string, correlationId string, queryId string) *DBSQLLogger {
-	return &DBSQLLogger{Logger.With().Str("connId", connectionId).Str("corrId", correlationId).Str("queryId", queryId).Logger()}
+	return &DBSQLLogger{forConnection(connectionId).With().Str("connId", connectionId).Str("corrId", correlationId).Str("queryId", queryId).Logger()}
 }
 
 // Track is a convenience function to track time spent
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"io"
+	"regexp"
+)
+
+const redacted = "[REDACTED]"
+
+// secretPatterns match the secrets that may end up in log messages, e.g. in a DSN or an error of the HTTP client.
+// The groups of each pattern are kept.
+var secretPatterns = []*regexp.Regexp{
+	// DSN credentials: token:<token>@host
+	regexp.MustCompile(`(token:)[^@\s"]+(@)`),
+	// authorization headers
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+	// URL and form parameters
+	regexp.MustCompile(`(?i)((?:access_token|refresh_token|client_secret|clientSecret|password|tokenCacheKey|proxyAuth)=)[^&\s"]+`),
+	// JSON fields, quotes are escaped in the log message of an event
+	regexp.MustCompile(`(?i)((?:\\?")(?:access_token|refresh_token|client_secret|password)(?:\\?")\s*:\s*\\?")[^"\\]+`),
+	// credentials of URLs, e.g. of a proxy
+	regexp.MustCompile(`(://[^:/@\s"]+:)[^@\s"/]+(@)`),
+	// personal access tokens
+	regexp.MustCompile(`()dapi[0-9a-f]{32}(?:-\d+)?`),
+}
+
+// Redact replaces the secrets of s with [REDACTED]
+func Redact(s string) string {
+	for _, p := range secretPatterns {
+		s = p.ReplaceAllString(s, "${1}"+redacted+"${2}")
+	}
+	return s
+}
+
+// redactWriter removes the secrets of the events written by zerolog before writing them to w
+type redactWriter struct {
+	w io.Writer
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	if _, err := w.w.Write([]byte(Redact(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
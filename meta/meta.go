@@ -0,0 +1,368 @@
+// Package dbsqlmeta lists the catalogs, schemas, tables, columns, keys, data types and functions of a warehouse with the metadata
+// operations of the databricks driver, without querying the information_schema of each catalog.
+//
+//	meta := dbsqlmeta.New(db)
+//	tables, err := meta.Tables(ctx, "main", "sales%", "%", "TABLE", "VIEW")
+//	columns, err := meta.Columns(ctx, "main", "sales", "orders", "%")
+//
+// Patterns use the SQL LIKE wildcards % and _, an empty pattern matches everything.
+package dbsqlmeta
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	"github.com/pkg/errors"
+)
+
+// Catalog is a catalog of the warehouse
+type Catalog struct {
+	Name string
+}
+
+// Schema is a schema of a catalog
+type Schema struct {
+	Catalog string
+	Name    string
+}
+
+// Table is a table or a view
+type Table struct {
+	Catalog string
+	Schema  string
+	Name    string
+	Type    string // e.g. TABLE or VIEW
+	Remarks string
+}
+
+// Column is a column of a table
+type Column struct {
+	Catalog       string
+	Schema        string
+	Table         string
+	Name          string
+	DataType      int    // java.sql.Types code of the type
+	TypeName      string // SQL type, e.g. DECIMAL(10,2)
+	ColumnSize    int
+	DecimalDigits int
+	Nullable      bool // false when the type has no nulls
+	Remarks       string
+	Position      int // position of the column in the table, starting at 1
+}
+
+// PrimaryKey is a column of the primary key of a table
+type PrimaryKey struct {
+	Catalog string
+	Schema  string
+	Table   string
+	Column  string
+	KeySeq  int // position of the column in the key, starting at 1
+	Name    string
+}
+
+// ForeignKey is a column of a foreign key and the column of the primary key it references
+type ForeignKey struct {
+	PKCatalog string
+	PKSchema  string
+	PKTable   string
+	PKColumn  string
+	FKCatalog string
+	FKSchema  string
+	FKTable   string
+	FKColumn  string
+	KeySeq    int // position of the column in the key, starting at 1
+	FKName    string
+	PKName    string
+}
+
+// TypeInfo is a data type supported by the warehouse
+type TypeInfo struct {
+	Name          string
+	DataType      int // java.sql.Types code of the type
+	Precision     int // maximum precision, or length of the type
+	LiteralPrefix string
+	LiteralSuffix string
+	CreateParams  string // parameters of the type, e.g. precision,scale
+	Nullable      bool   // false when the type has no nulls
+	CaseSensitive bool
+	Searchable    bool // whether the type can be used in a WHERE clause
+	Unsigned      bool
+	MinimumScale  int
+	MaximumScale  int
+	Radix         int
+}
+
+// FunctionType tells whether a function returns a table
+type FunctionType int
+
+const (
+	FunctionTypeUnknown FunctionType = iota
+	FunctionNoTable
+	FunctionReturnsTable
+)
+
+// Function is a built-in or user defined function
+type Function struct {
+	Catalog      string
+	Schema       string
+	Name         string
+	Remarks      string
+	Type         FunctionType
+	SpecificName string
+}
+
+// Client lists the metadata of a warehouse
+type Client struct {
+	raw func(ctx context.Context, f func(driverConn any) error) error
+}
+
+// New returns a client running the metadata operations on connections of db, opened with the databricks driver
+func New(db *sql.DB) *Client {
+	return &Client{raw: func(ctx context.Context, f func(driverConn any) error) error {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return conn.Raw(f)
+	}}
+}
+
+// NewConn returns a client running the metadata operations on conn, opened with the databricks driver
+func NewConn(conn *sql.Conn) *Client {
+	return &Client{raw: func(ctx context.Context, f func(driverConn any) error) error {
+		return conn.Raw(f)
+	}}
+}
+
+// Catalogs returns the catalogs
+func (c *Client) Catalogs(ctx context.Context) ([]Catalog, error) {
+	var catalogs []Catalog
+	err := c.read(ctx, func(mc dbsql.MetadataConn) (driver.Rows, error) {
+		return mc.GetCatalogs(ctx)
+	}, func(row record) {
+		catalogs = append(catalogs, Catalog{Name: row.str("TABLE_CAT")})
+	})
+	return catalogs, errors.Wrap(err, "databricks: failed to list catalogs")
+}
+
+// Schemas returns the schemas of catalog matching schemaPattern
+func (c *Client) Schemas(ctx context.Context, catalog, schemaPattern string) ([]Schema, error) {
+	var schemas []Schema
+	err := c.read(ctx, func(mc dbsql.MetadataConn) (driver.Rows, error) {
+		return mc.GetSchemas(ctx, catalog, schemaPattern)
+	}, func(row record) {
+		schemas = append(schemas, Schema{Catalog: row.str("TABLE_CATALOG"), Name: row.str("TABLE_SCHEM")})
+	})
+	return schemas, errors.Wrap(err, "databricks: failed to list schemas")
+}
+
+// Tables returns the tables matching the patterns with one of tableTypes, e.g. TABLE or VIEW, all types when
+// there are none
+func (c *Client) Tables(ctx context.Context, catalog, schemaPattern, tablePattern string, tableTypes ...string) ([]Table, error) {
+	var tables []Table
+	err := c.read(ctx, func(mc dbsql.MetadataConn) (driver.Rows, error) {
+		return mc.GetTables(ctx, catalog, schemaPattern, tablePattern, tableTypes)
+	}, func(row record) {
+		tables = append(tables, Table{
+			Catalog: row.str("TABLE_CAT"),
+			Schema:  row.str("TABLE_SCHEM"),
+			Name:    row.str("TABLE_NAME"),
+			Type:    row.str("TABLE_TYPE"),
+			Remarks: row.str("REMARKS"),
+		})
+	})
+	return tables, errors.Wrap(err, "databricks: failed to list tables")
+}
+
+// Columns returns the columns matching the patterns
+func (c *Client) Columns(ctx context.Context, catalog, schemaPattern, tablePattern, columnPattern string) ([]Column, error) {
+	var columns []Column
+	err := c.read(ctx, func(mc dbsql.MetadataConn) (driver.Rows, error) {
+		return mc.GetColumns(ctx, catalog, schemaPattern, tablePattern, columnPattern)
+	}, func(row record) {
+		columns = append(columns, Column{
+			Catalog:       row.str("TABLE_CAT"),
+			Schema:        row.str("TABLE_SCHEM"),
+			Table:         row.str("TABLE_NAME"),
+			Name:          row.str("COLUMN_NAME"),
+			DataType:      row.int("DATA_TYPE"),
+			TypeName:      row.str("TYPE_NAME"),
+			ColumnSize:    row.int("COLUMN_SIZE"),
+			DecimalDigits: row.int("DECIMAL_DIGITS"),
+			Nullable:      row.str("IS_NULLABLE") != "NO",
+			Remarks:       row.str("REMARKS"),
+			Position:      row.int("ORDINAL_POSITION"),
+		})
+	})
+	return columns, errors.Wrap(err, "databricks: failed to list columns")
+}
+
+// PrimaryKeys returns the columns of the primary key of a table
+func (c *Client) PrimaryKeys(ctx context.Context, catalog, schema, table string) ([]PrimaryKey, error) {
+	var keys []PrimaryKey
+	err := c.read(ctx, func(mc dbsql.MetadataConn) (driver.Rows, error) {
+		return mc.GetPrimaryKeys(ctx, catalog, schema, table)
+	}, func(row record) {
+		keys = append(keys, PrimaryKey{
+			Catalog: row.str("TABLE_CAT"),
+			Schema:  row.str("TABLE_SCHEM"),
+			Table:   row.str("TABLE_NAME"),
+			Column:  row.str("COLUMN_NAME"),
+			KeySeq:  row.int("KEY_SEQ"),
+			Name:    row.str("PK_NAME"),
+		})
+	})
+	return keys, errors.Wrap(err, "databricks: failed to list primary keys")
+}
+
+// ForeignKeys returns the foreign keys of a table
+func (c *Client) ForeignKeys(ctx context.Context, catalog, schema, table string) ([]ForeignKey, error) {
+	return c.crossReference(ctx, "", "", "", catalog, schema, table)
+}
+
+// ExportedKeys returns the foreign keys referencing the primary key of a table
+func (c *Client) ExportedKeys(ctx context.Context, catalog, schema, table string) ([]ForeignKey, error) {
+	return c.crossReference(ctx, catalog, schema, table, "", "", "")
+}
+
+func (c *Client) crossReference(ctx context.Context, parentCatalog, parentSchema, parentTable, foreignCatalog, foreignSchema, foreignTable string) ([]ForeignKey, error) {
+	var keys []ForeignKey
+	err := c.read(ctx, func(mc dbsql.MetadataConn) (driver.Rows, error) {
+		return mc.GetCrossReference(ctx, parentCatalog, parentSchema, parentTable, foreignCatalog, foreignSchema, foreignTable)
+	}, func(row record) {
+		keys = append(keys, ForeignKey{
+			PKCatalog: row.str("PKTABLE_CAT"),
+			PKSchema:  row.str("PKTABLE_SCHEM"),
+			PKTable:   row.str("PKTABLE_NAME"),
+			PKColumn:  row.str("PKCOLUMN_NAME"),
+			FKCatalog: row.str("FKTABLE_CAT"),
+			FKSchema:  row.str("FKTABLE_SCHEM"),
+			FKTable:   row.str("FKTABLE_NAME"),
+			FKColumn:  row.str("FKCOLUMN_NAME"),
+			KeySeq:    row.int("KEY_SEQ"),
+			FKName:    row.str("FK_NAME"),
+			PKName:    row.str("PK_NAME"),
+		})
+	})
+	return keys, errors.Wrap(err, "databricks: failed to list foreign keys")
+}
+
+// TypeInfo returns the data types supported by the warehouse
+func (c *Client) TypeInfo(ctx context.Context) ([]TypeInfo, error) {
+	var types []TypeInfo
+	err := c.read(ctx, func(mc dbsql.MetadataConn) (driver.Rows, error) {
+		return mc.GetTypeInfo(ctx)
+	}, func(row record) {
+		types = append(types, TypeInfo{
+			Name:          row.str("TYPE_NAME"),
+			DataType:      row.int("DATA_TYPE"),
+			Precision:     row.int("PRECISION"),
+			LiteralPrefix: row.str("LITERAL_PREFIX"),
+			LiteralSuffix: row.str("LITERAL_SUFFIX"),
+			CreateParams:  row.str("CREATE_PARAMS"),
+			Nullable:      row.int("NULLABLE") != 0,
+			CaseSensitive: row.bool("CASE_SENSITIVE"),
+			Searchable:    row.int("SEARCHABLE") != 0,
+			Unsigned:      row.bool("UNSIGNED_ATTRIBUTE"),
+			MinimumScale:  row.int("MINIMUM_SCALE"),
+			MaximumScale:  row.int("MAXIMUM_SCALE"),
+			Radix:         row.int("NUM_PREC_RADIX"),
+		})
+	})
+	return types, errors.Wrap(err, "databricks: failed to list data types")
+}
+
+// Functions returns the built-in and user defined functions matching the patterns
+func (c *Client) Functions(ctx context.Context, catalog, schemaPattern, functionPattern string) ([]Function, error) {
+	var functions []Function
+	err := c.read(ctx, func(mc dbsql.MetadataConn) (driver.Rows, error) {
+		return mc.GetFunctions(ctx, catalog, schemaPattern, functionPattern)
+	}, func(row record) {
+		functions = append(functions, Function{
+			Catalog:      row.str("FUNCTION_CAT"),
+			Schema:       row.str("FUNCTION_SCHEM"),
+			Name:         row.str("FUNCTION_NAME"),
+			Remarks:      row.str("REMARKS"),
+			Type:         FunctionType(row.int("FUNCTION_TYPE")),
+			SpecificName: row.str("SPECIFIC_NAME"),
+		})
+	})
+	return functions, errors.Wrap(err, "databricks: failed to list functions")
+}
+
+// record is a row of a metadata operation, by column name
+type record map[string]driver.Value
+
+func (r record) str(column string) string {
+	switch v := r[column].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (r record) int(column string) int {
+	switch v := r[column].(type) {
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case int16:
+		return int(v)
+	case int8:
+		return int(v)
+	default:
+		n, _ := strconv.Atoi(r.str(column))
+		return n
+	}
+}
+
+func (r record) bool(column string) bool {
+	if v, ok := r[column].(bool); ok {
+		return v
+	}
+	b, _ := strconv.ParseBool(r.str(column))
+	return b
+}
+
+// read runs a metadata operation on a connection and passes its rows to scan
+func (c *Client) read(ctx context.Context, op func(dbsql.MetadataConn) (driver.Rows, error), scan func(record)) error {
+	return c.raw(ctx, func(driverConn any) error {
+		mc, ok := driverConn.(dbsql.MetadataConn)
+		if !ok {
+			return errors.Errorf("databricks: %T is not a connection of the databricks driver", driverConn)
+		}
+		rows, err := op(mc)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		columns := rows.Columns()
+		values := make([]driver.Value, len(columns))
+		for {
+			if err := rows.Next(values); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			row := make(record, len(columns))
+			for i, column := range columns {
+				row[column] = values[i]
+			}
+			scan(row)
+		}
+	})
+}
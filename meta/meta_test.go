@@ -0,0 +1,179 @@
+package dbsqlmeta
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConn answers the metadata operations with fixed rows
+type testConn struct {
+	calls [][]string
+}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *testConn) Close() error                              { return nil }
+func (c *testConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (c *testConn) GetCatalogs(ctx context.Context) (driver.Rows, error) {
+	c.calls = append(c.calls, []string{"catalogs"})
+	return &testRows{columns: []string{"TABLE_CAT"}, values: [][]driver.Value{{"main"}, {"samples"}}}, nil
+}
+
+func (c *testConn) GetSchemas(ctx context.Context, catalog, schemaPattern string) (driver.Rows, error) {
+	c.calls = append(c.calls, []string{"schemas", catalog, schemaPattern})
+	return &testRows{columns: []string{"TABLE_SCHEM", "TABLE_CATALOG"}, values: [][]driver.Value{{"sales", "main"}}}, nil
+}
+
+func (c *testConn) GetTables(ctx context.Context, catalog, schemaPattern, tablePattern string, tableTypes []string) (driver.Rows, error) {
+	c.calls = append(c.calls, append([]string{"tables", catalog, schemaPattern, tablePattern}, tableTypes...))
+	return &testRows{
+		columns: []string{"TABLE_CAT", "TABLE_SCHEM", "TABLE_NAME", "TABLE_TYPE", "REMARKS"},
+		values:  [][]driver.Value{{"main", "sales", "orders", "TABLE", nil}},
+	}, nil
+}
+
+func (c *testConn) GetColumns(ctx context.Context, catalog, schemaPattern, tablePattern, columnPattern string) (driver.Rows, error) {
+	c.calls = append(c.calls, []string{"columns", catalog, schemaPattern, tablePattern, columnPattern})
+	return &testRows{
+		columns: []string{"TABLE_CAT", "TABLE_SCHEM", "TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "TYPE_NAME", "COLUMN_SIZE", "DECIMAL_DIGITS", "IS_NULLABLE", "REMARKS", "ORDINAL_POSITION"},
+		values: [][]driver.Value{
+			{"main", "sales", "orders", "id", int32(-5), "BIGINT", int32(8), int32(0), "NO", "order id", int32(1)},
+			{"main", "sales", "orders", "amount", int32(3), "DECIMAL(10,2)", int32(10), int32(2), "YES", nil, int32(2)},
+		},
+	}, nil
+}
+
+func (c *testConn) GetPrimaryKeys(ctx context.Context, catalog, schema, table string) (driver.Rows, error) {
+	c.calls = append(c.calls, []string{"primary keys", catalog, schema, table})
+	return &testRows{
+		columns: []string{"TABLE_CAT", "TABLE_SCHEM", "TABLE_NAME", "COLUMN_NAME", "KEY_SEQ", "PK_NAME"},
+		values:  [][]driver.Value{{"main", "sales", "orders", "id", int32(1), "orders_pk"}},
+	}, nil
+}
+
+func (c *testConn) GetCrossReference(ctx context.Context, parentCatalog, parentSchema, parentTable, foreignCatalog, foreignSchema, foreignTable string) (driver.Rows, error) {
+	c.calls = append(c.calls, []string{"cross reference", parentCatalog, parentSchema, parentTable, foreignCatalog, foreignSchema, foreignTable})
+	return &testRows{
+		columns: []string{"PKTABLE_CAT", "PKTABLE_SCHEM", "PKTABLE_NAME", "PKCOLUMN_NAME", "FKTABLE_CAT", "FKTABLE_SCHEM", "FKTABLE_NAME", "FKCOLUMN_NAME", "KEY_SEQ", "FK_NAME", "PK_NAME"},
+		values:  [][]driver.Value{{"main", "sales", "customers", "id", "main", "sales", "orders", "customer_id", int32(1), "orders_customers_fk", "customers_pk"}},
+	}, nil
+}
+
+func (c *testConn) GetTypeInfo(ctx context.Context) (driver.Rows, error) {
+	c.calls = append(c.calls, []string{"type info"})
+	return &testRows{
+		columns: []string{"TYPE_NAME", "DATA_TYPE", "PRECISION", "LITERAL_PREFIX", "LITERAL_SUFFIX", "CREATE_PARAMS", "NULLABLE", "CASE_SENSITIVE", "SEARCHABLE", "UNSIGNED_ATTRIBUTE", "MINIMUM_SCALE", "MAXIMUM_SCALE", "NUM_PREC_RADIX"},
+		values: [][]driver.Value{
+			{"STRING", int32(12), int32(0), "'", "'", nil, int16(1), true, int16(3), nil, int16(0), int16(0), nil},
+			{"DECIMAL", int32(3), int32(38), nil, nil, "precision,scale", int16(1), false, int16(2), false, int16(0), int16(38), int32(10)},
+		},
+	}, nil
+}
+
+func (c *testConn) GetFunctions(ctx context.Context, catalog, schemaPattern, functionPattern string) (driver.Rows, error) {
+	c.calls = append(c.calls, []string{"functions", catalog, schemaPattern, functionPattern})
+	return &testRows{
+		columns: []string{"FUNCTION_CAT", "FUNCTION_SCHEM", "FUNCTION_NAME", "REMARKS", "FUNCTION_TYPE", "SPECIFIC_NAME"},
+		values: [][]driver.Value{
+			{nil, nil, "abs", "Returns the absolute value", int32(1), "abs"},
+			{"main", "sales", "top_orders", nil, int32(2), "top_orders"},
+		},
+	}, nil
+}
+
+type testRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *testRows) Columns() []string { return r.columns }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+type testConnector struct {
+	conn *testConn
+}
+
+func (c testConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c testConnector) Driver() driver.Driver                        { return nil }
+
+func newTestClient(t *testing.T) (*Client, *testConn) {
+	conn := &testConn{}
+	db := sql.OpenDB(testConnector{conn})
+	t.Cleanup(func() { db.Close() })
+	return New(db), conn
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	client, conn := newTestClient(t)
+
+	catalogs, err := client.Catalogs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Catalog{{Name: "main"}, {Name: "samples"}}, catalogs)
+
+	schemas, err := client.Schemas(ctx, "main", "sa%")
+	require.NoError(t, err)
+	assert.Equal(t, []Schema{{Catalog: "main", Name: "sales"}}, schemas)
+
+	tables, err := client.Tables(ctx, "main", "sales", "%", "TABLE", "VIEW")
+	require.NoError(t, err)
+	assert.Equal(t, []Table{{Catalog: "main", Schema: "sales", Name: "orders", Type: "TABLE"}}, tables)
+
+	columns, err := client.Columns(ctx, "main", "sales", "orders", "")
+	require.NoError(t, err)
+	assert.Equal(t, []Column{
+		{Catalog: "main", Schema: "sales", Table: "orders", Name: "id", DataType: -5, TypeName: "BIGINT", ColumnSize: 8, Remarks: "order id", Position: 1},
+		{Catalog: "main", Schema: "sales", Table: "orders", Name: "amount", DataType: 3, TypeName: "DECIMAL(10,2)", ColumnSize: 10, DecimalDigits: 2, Nullable: true, Position: 2},
+	}, columns)
+
+	keys, err := client.PrimaryKeys(ctx, "main", "sales", "orders")
+	require.NoError(t, err)
+	assert.Equal(t, []PrimaryKey{{Catalog: "main", Schema: "sales", Table: "orders", Column: "id", KeySeq: 1, Name: "orders_pk"}}, keys)
+
+	foreignKeys, err := client.ForeignKeys(ctx, "main", "sales", "orders")
+	require.NoError(t, err)
+	assert.Equal(t, []ForeignKey{{
+		PKCatalog: "main", PKSchema: "sales", PKTable: "customers", PKColumn: "id",
+		FKCatalog: "main", FKSchema: "sales", FKTable: "orders", FKColumn: "customer_id",
+		KeySeq: 1, FKName: "orders_customers_fk", PKName: "customers_pk",
+	}}, foreignKeys)
+
+	types, err := client.TypeInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []TypeInfo{
+		{Name: "STRING", DataType: 12, LiteralPrefix: "'", LiteralSuffix: "'", Nullable: true, CaseSensitive: true, Searchable: true},
+		{Name: "DECIMAL", DataType: 3, Precision: 38, CreateParams: "precision,scale", Nullable: true, Searchable: true, MaximumScale: 38, Radix: 10},
+	}, types)
+
+	functions, err := client.Functions(ctx, "main", "", "%o%")
+	require.NoError(t, err)
+	assert.Equal(t, []Function{
+		{Name: "abs", Remarks: "Returns the absolute value", Type: FunctionNoTable, SpecificName: "abs"},
+		{Catalog: "main", Schema: "sales", Name: "top_orders", Type: FunctionReturnsTable, SpecificName: "top_orders"},
+	}, functions)
+
+	assert.Equal(t, [][]string{
+		{"catalogs"},
+		{"schemas", "main", "sa%"},
+		{"tables", "main", "sales", "%", "TABLE", "VIEW"},
+		{"columns", "main", "sales", "orders", ""},
+		{"primary keys", "main", "sales", "orders"},
+		{"cross reference", "", "", "", "main", "sales", "orders"},
+		{"type info"},
+		{"functions", "main", "", "%o%"},
+	}, conn.calls)
+}
@@ -0,0 +1,245 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/pkg/errors"
+)
+
+// MetadataConn is implemented by the connections of the driver, to list the catalogs, schemas, tables, columns,
+// keys, data types and functions of the warehouse with the metadata operations of the server. The rows have the columns of the JDBC
+// DatabaseMetaData methods, e.g. TABLE_CAT, TABLE_SCHEM and TABLE_NAME. Patterns use the SQL LIKE wildcards
+// % and _, an empty pattern matches everything. The dbsqlmeta package reads them into typed values.
+type MetadataConn interface {
+	GetCatalogs(ctx context.Context) (driver.Rows, error)
+	GetSchemas(ctx context.Context, catalog, schemaPattern string) (driver.Rows, error)
+	GetTables(ctx context.Context, catalog, schemaPattern, tablePattern string, tableTypes []string) (driver.Rows, error)
+	GetColumns(ctx context.Context, catalog, schemaPattern, tablePattern, columnPattern string) (driver.Rows, error)
+	GetPrimaryKeys(ctx context.Context, catalog, schema, table string) (driver.Rows, error)
+	GetCrossReference(ctx context.Context, parentCatalog, parentSchema, parentTable, foreignCatalog, foreignSchema, foreignTable string) (driver.Rows, error)
+	GetTypeInfo(ctx context.Context) (driver.Rows, error)
+	GetFunctions(ctx context.Context, catalog, schemaPattern, functionPattern string) (driver.Rows, error)
+}
+
+var _ MetadataConn = (*conn)(nil)
+
+// GetCatalogs returns the catalogs, with the column TABLE_CAT
+func (c *conn) GetCatalogs(ctx context.Context) (driver.Rows, error) {
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	resp, err := c.client.GetCatalogs(ctx, &cli_service.TGetCatalogsReq{
+		SessionHandle:    c.session.SessionHandle,
+		GetDirectResults: c.directResults(),
+	})
+	if err != nil {
+		return nil, wrapErr(err, "failed to get catalogs")
+	}
+	return c.metadataRows(ctx, resp.GetOperationHandle(), resp.DirectResults)
+}
+
+// GetSchemas returns the schemas of catalog matching schemaPattern, with the columns TABLE_SCHEM and TABLE_CATALOG
+func (c *conn) GetSchemas(ctx context.Context, catalog, schemaPattern string) (driver.Rows, error) {
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	resp, err := c.client.GetSchemas(ctx, &cli_service.TGetSchemasReq{
+		SessionHandle:    c.session.SessionHandle,
+		CatalogName:      identifier(catalog),
+		SchemaName:       pattern(schemaPattern),
+		GetDirectResults: c.directResults(),
+	})
+	if err != nil {
+		return nil, wrapErr(err, "failed to get schemas")
+	}
+	return c.metadataRows(ctx, resp.GetOperationHandle(), resp.DirectResults)
+}
+
+// GetTables returns the tables matching the patterns with one of tableTypes, e.g. TABLE or VIEW, all types when
+// tableTypes is empty
+func (c *conn) GetTables(ctx context.Context, catalog, schemaPattern, tablePattern string, tableTypes []string) (driver.Rows, error) {
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	resp, err := c.client.GetTables(ctx, &cli_service.TGetTablesReq{
+		SessionHandle:    c.session.SessionHandle,
+		CatalogName:      pattern(catalog),
+		SchemaName:       pattern(schemaPattern),
+		TableName:        pattern(tablePattern),
+		TableTypes:       tableTypes,
+		GetDirectResults: c.directResults(),
+	})
+	if err != nil {
+		return nil, wrapErr(err, "failed to get tables")
+	}
+	return c.metadataRows(ctx, resp.GetOperationHandle(), resp.DirectResults)
+}
+
+// GetColumns returns the columns matching the patterns
+func (c *conn) GetColumns(ctx context.Context, catalog, schemaPattern, tablePattern, columnPattern string) (driver.Rows, error) {
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	resp, err := c.client.GetColumns(ctx, &cli_service.TGetColumnsReq{
+		SessionHandle:    c.session.SessionHandle,
+		CatalogName:      identifier(catalog),
+		SchemaName:       pattern(schemaPattern),
+		TableName:        pattern(tablePattern),
+		ColumnName:       pattern(columnPattern),
+		GetDirectResults: c.directResults(),
+	})
+	if err != nil {
+		return nil, wrapErr(err, "failed to get columns")
+	}
+	return c.metadataRows(ctx, resp.GetOperationHandle(), resp.DirectResults)
+}
+
+// GetPrimaryKeys returns the columns of the primary key of a table
+func (c *conn) GetPrimaryKeys(ctx context.Context, catalog, schema, table string) (driver.Rows, error) {
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	resp, err := c.client.GetPrimaryKeys(ctx, &cli_service.TGetPrimaryKeysReq{
+		SessionHandle:    c.session.SessionHandle,
+		CatalogName:      identifier(catalog),
+		SchemaName:       identifier(schema),
+		TableName:        identifier(table),
+		GetDirectResults: c.directResults(),
+	})
+	if err != nil {
+		return nil, wrapErr(err, "failed to get primary keys")
+	}
+	return c.metadataRows(ctx, resp.GetOperationHandle(), resp.DirectResults)
+}
+
+// GetCrossReference returns the foreign keys of the foreign table referencing the parent table. Either table
+// may be empty to get all the foreign keys referencing the parent table, or all the foreign keys of the foreign table.
+func (c *conn) GetCrossReference(ctx context.Context, parentCatalog, parentSchema, parentTable, foreignCatalog, foreignSchema, foreignTable string) (driver.Rows, error) {
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	resp, err := c.client.GetCrossReference(ctx, &cli_service.TGetCrossReferenceReq{
+		SessionHandle:      c.session.SessionHandle,
+		ParentCatalogName:  identifier(parentCatalog),
+		ParentSchemaName:   identifier(parentSchema),
+		ParentTableName:    identifier(parentTable),
+		ForeignCatalogName: identifier(foreignCatalog),
+		ForeignSchemaName:  identifier(foreignSchema),
+		ForeignTableName:   identifier(foreignTable),
+		GetDirectResults:   c.directResults(),
+	})
+	if err != nil {
+		return nil, wrapErr(err, "failed to get cross reference")
+	}
+	return c.metadataRows(ctx, resp.GetOperationHandle(), resp.DirectResults)
+}
+
+// GetTypeInfo returns the data types supported by the warehouse, with the columns of the JDBC getTypeInfo method,
+// e.g. TYPE_NAME, DATA_TYPE and PRECISION
+func (c *conn) GetTypeInfo(ctx context.Context) (driver.Rows, error) {
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	resp, err := c.client.GetTypeInfo(ctx, &cli_service.TGetTypeInfoReq{
+		SessionHandle:    c.session.SessionHandle,
+		GetDirectResults: c.directResults(),
+	})
+	if err != nil {
+		return nil, wrapErr(err, "failed to get type info")
+	}
+	return c.metadataRows(ctx, resp.GetOperationHandle(), resp.DirectResults)
+}
+
+// GetFunctions returns the built-in and user defined functions matching the patterns, with the columns
+// FUNCTION_CAT, FUNCTION_SCHEM, FUNCTION_NAME, REMARKS, FUNCTION_TYPE and SPECIFIC_NAME
+func (c *conn) GetFunctions(ctx context.Context, catalog, schemaPattern, functionPattern string) (driver.Rows, error) {
+	// the function name is required by the request
+	if functionPattern == "" {
+		functionPattern = "%"
+	}
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	ctx = driverctx.NewContextWithConnId(ctx, c.id)
+	resp, err := c.client.GetFunctions(ctx, &cli_service.TGetFunctionsReq{
+		SessionHandle:    c.session.SessionHandle,
+		CatalogName:      identifier(catalog),
+		SchemaName:       pattern(schemaPattern),
+		FunctionName:     cli_service.TPatternOrIdentifier(functionPattern),
+		GetDirectResults: c.directResults(),
+	})
+	if err != nil {
+		return nil, wrapErr(err, "failed to get functions")
+	}
+	return c.metadataRows(ctx, resp.GetOperationHandle(), resp.DirectResults)
+}
+
+func (c *conn) directResults() *cli_service.TSparkGetDirectResults {
+	if !c.ServerCapabilities().DirectResults {
+		return nil
+	}
+	return &cli_service.TSparkGetDirectResults{MaxRows: int64(c.cfg.MaxRows)}
+}
+
+// metadataRows waits for a metadata operation to finish and returns its rows
+func (c *conn) metadataRows(ctx context.Context, opHandle *cli_service.TOperationHandle, directResults *cli_service.TSparkDirectResults) (driver.Rows, error) {
+	if opHandle == nil || opHandle.OperationId == nil {
+		return nil, errors.New("databricks: metadata operation has no operation handle")
+	}
+	corrId := driverctx.CorrelationIdFromContext(ctx)
+	log := logger.WithContext(c.id, corrId, client.SprintGuid(opHandle.OperationId.GUID))
+
+	status := &cli_service.TGetOperationStatusResp{
+		OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_RUNNING_STATE),
+	}
+	if directResults != nil && directResults.OperationStatus != nil {
+		status = directResults.OperationStatus
+	}
+	switch status.GetOperationState() {
+	case cli_service.TOperationState_INITIALIZED_STATE,
+		cli_service.TOperationState_PENDING_STATE,
+		cli_service.TOperationState_RUNNING_STATE:
+		var err error
+		if status, err = c.pollOperation(ctx, opHandle); err != nil {
+			return nil, err
+		}
+	}
+	if status.GetOperationState() != cli_service.TOperationState_FINISHED_STATE {
+		logBadQueryState(log, status)
+		return nil, c.newExecutionError(ctx, opHandle, status)
+	}
+	rows := NewRows(c.id, corrId, c.client, opHandle, c.cfg, directResults)
+	setRequestContext(rows, ctx)
+	setPrefetchClient(rows, c.prefetchClient())
+	return rows, nil
+}
+
+// identifier returns name as an identifier of a metadata request, nil when it is empty
+func identifier(name string) *cli_service.TIdentifier {
+	if name == "" {
+		return nil
+	}
+	id := cli_service.TIdentifier(name)
+	return &id
+}
+
+// pattern returns p as a pattern of a metadata request, nil when it is empty
+func pattern(p string) *cli_service.TPatternOrIdentifier {
+	if p == "" {
+		return nil
+	}
+	pt := cli_service.TPatternOrIdentifier(p)
+	return &pt
+}
@@ -0,0 +1,157 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metadataResults returns the direct results of a metadata operation with a string column per name
+func metadataResults(state cli_service.TOperationState, names []string, values ...[]string) *cli_service.TSparkDirectResults {
+	columns := make([]*cli_service.TColumnDesc, len(names))
+	for i, name := range names {
+		columns[i] = &cli_service.TColumnDesc{
+			ColumnName: name,
+			TypeDesc: &cli_service.TTypeDesc{
+				Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: cli_service.TTypeId_STRING_TYPE}}},
+			},
+		}
+	}
+	rows := make([]*cli_service.TColumn, len(values))
+	for i := range values {
+		rows[i] = &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: values[i]}}
+	}
+	noMoreRows := false
+	return &cli_service.TSparkDirectResults{
+		OperationStatus: &cli_service.TGetOperationStatusResp{
+			Status:         &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			OperationState: cli_service.TOperationStatePtr(state),
+		},
+		ResultSetMetadata: &cli_service.TGetResultSetMetadataResp{
+			Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			Schema: &cli_service.TTableSchema{Columns: columns},
+		},
+		ResultSet: &cli_service.TFetchResultsResp{
+			Status:      &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			HasMoreRows: &noMoreRows,
+			Results:     &cli_service.TRowSet{Columns: rows},
+		},
+		CloseOperation: &cli_service.TCloseOperationResp{},
+	}
+}
+
+func TestConn_GetTables(t *testing.T) {
+	opHandle := &cli_service.TOperationHandle{
+		OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 1, 2, 3, 4, 4, 223, 34}},
+	}
+
+	t.Run("tables are read from the direct results", func(t *testing.T) {
+		var req *cli_service.TGetTablesReq
+		c := &conn{
+			session: getTestSession(),
+			cfg:     config.WithDefaults(),
+			client: &client.TestClient{
+				FnGetTables: func(ctx context.Context, r *cli_service.TGetTablesReq) (*cli_service.TGetTablesResp, error) {
+					req = r
+					return &cli_service.TGetTablesResp{
+						Status:          &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+						OperationHandle: opHandle,
+						DirectResults: metadataResults(cli_service.TOperationState_FINISHED_STATE,
+							[]string{"TABLE_CAT", "TABLE_SCHEM", "TABLE_NAME", "TABLE_TYPE"},
+							[]string{"main", "main"}, []string{"sales", "sales"}, []string{"orders", "customers"}, []string{"TABLE", "VIEW"}),
+					}, nil
+				},
+			},
+		}
+
+		rows, err := c.GetTables(context.Background(), "main", "sales", "", []string{"TABLE", "VIEW"})
+		require.NoError(t, err)
+		assert.Equal(t, "main", string(*req.CatalogName))
+		assert.Equal(t, "sales", string(*req.SchemaName))
+		assert.Nil(t, req.TableName)
+		assert.Equal(t, []string{"TABLE", "VIEW"}, req.TableTypes)
+
+		assert.Equal(t, []string{"TABLE_CAT", "TABLE_SCHEM", "TABLE_NAME", "TABLE_TYPE"}, rows.Columns())
+		values := make([]driver.Value, 4)
+		require.NoError(t, rows.Next(values))
+		assert.Equal(t, []driver.Value{"main", "sales", "orders", "TABLE"}, values)
+		require.NoError(t, rows.Next(values))
+		assert.Equal(t, []driver.Value{"main", "sales", "customers", "VIEW"}, values)
+		assert.Equal(t, io.EOF, rows.Next(values))
+	})
+
+	t.Run("running operations are polled", func(t *testing.T) {
+		var polls int
+		cfg := config.WithDefaults()
+		cfg.PollInterval = time.Millisecond
+		c := &conn{
+			session: getTestSession(),
+			cfg:     cfg,
+			client: &client.TestClient{
+				FnGetTables: func(ctx context.Context, r *cli_service.TGetTablesReq) (*cli_service.TGetTablesResp, error) {
+					return &cli_service.TGetTablesResp{
+						Status:          &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+						OperationHandle: opHandle,
+						DirectResults: &cli_service.TSparkDirectResults{
+							OperationStatus: &cli_service.TGetOperationStatusResp{
+								OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_RUNNING_STATE),
+							},
+						},
+					}, nil
+				},
+				FnGetOperationStatus: func(ctx context.Context, req *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
+					polls++
+					return &cli_service.TGetOperationStatusResp{
+						OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_ERROR_STATE),
+						ErrorMessage:   strPtr("[SCHEMA_NOT_FOUND] schema not found"),
+					}, nil
+				},
+			},
+		}
+
+		_, err := c.GetTables(context.Background(), "main", "missing", "", nil)
+		assert.Equal(t, 1, polls)
+		assert.ErrorContains(t, err, "SCHEMA_NOT_FOUND")
+	})
+}
+
+func TestConn_GetFunctions(t *testing.T) {
+	var req *cli_service.TGetFunctionsReq
+	c := &conn{
+		session: getTestSession(),
+		cfg:     config.WithDefaults(),
+		client: &client.TestClient{
+			FnGetFunctions: func(ctx context.Context, r *cli_service.TGetFunctionsReq) (*cli_service.TGetFunctionsResp, error) {
+				req = r
+				return &cli_service.TGetFunctionsResp{
+					Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+					OperationHandle: &cli_service.TOperationHandle{
+						OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 1, 2, 3, 4, 4, 223, 34}},
+					},
+					DirectResults: metadataResults(cli_service.TOperationState_FINISHED_STATE,
+						[]string{"FUNCTION_CAT", "FUNCTION_SCHEM", "FUNCTION_NAME"},
+						[]string{"main"}, []string{"sales"}, []string{"top_orders"}),
+				}, nil
+			},
+		},
+	}
+
+	rows, err := c.GetFunctions(context.Background(), "main", "sales", "")
+	require.NoError(t, err)
+	assert.Equal(t, "main", string(*req.CatalogName))
+	assert.Equal(t, "sales", string(*req.SchemaName))
+	assert.Equal(t, "%", string(req.FunctionName), "the function name is required and defaults to all functions")
+
+	values := make([]driver.Value, 3)
+	require.NoError(t, rows.Next(values))
+	assert.Equal(t, []driver.Value{"main", "sales", "top_orders"}, values)
+	assert.Equal(t, io.EOF, rows.Next(values))
+}
@@ -0,0 +1,51 @@
+// Package metrics defines the metrics reported by the driver. Set a Collector with dbsql.WithMetricsCollector
+// to export them, e.g. to Prometheus.
+package metrics
+
+import "time"
+
+// Names of the metrics. They follow the Prometheus naming conventions, durations are in seconds.
+const (
+	QueryDuration      = "databricks_sql_query_duration_seconds"               // histogram of the time queries take to run, until their results can be read
+	FetchDuration      = "databricks_sql_fetch_duration_seconds"               // histogram of the time taken to fetch result pages
+	RowsFetched        = "databricks_sql_rows_fetched_total"                   // counter of the rows of the result pages received
+	BytesDownloaded    = "databricks_sql_cloudfetch_downloaded_bytes_total"    // counter of the bytes of the result files downloaded with cloud fetch
+	DownloadThroughput = "databricks_sql_cloudfetch_download_bytes_per_second" // histogram of the download rate of the result files of cloud fetch
+	Retries            = "databricks_sql_request_retries_total"                // counter of the retried HTTP requests
+	OpenSessions       = "databricks_sql_open_sessions"                        // gauge of the open sessions
+	ActiveQueries      = "databricks_sql_active_queries"                       // gauge of the queries running, until their results can be read
+	WarehouseStarts    = "databricks_sql_warehouse_start_wait_seconds"         // histogram of the time waited for starting warehouses
+	CacheHits          = "databricks_sql_result_cache_hits_total"              // counter of the queries answered from the result cache
+	CacheMisses        = "databricks_sql_result_cache_misses_total"            // counter of the cacheable queries run on the warehouse
+)
+
+// Collector receives the metrics of the driver. It is called concurrently by the connections of a connector.
+type Collector interface {
+	// Counter adds value to a counter
+	Counter(name string, value float64)
+	// Histogram records an observation of a histogram
+	Histogram(name string, value float64)
+	// Gauge adds delta to a gauge, delta can be negative
+	Gauge(name string, delta float64)
+}
+
+// Counter adds value to a counter of c, if not nil
+func Counter(c Collector, name string, value float64) {
+	if c != nil {
+		c.Counter(name, value)
+	}
+}
+
+// Gauge adds delta to a gauge of c, if not nil
+func Gauge(c Collector, name string, delta float64) {
+	if c != nil {
+		c.Gauge(name, delta)
+	}
+}
+
+// Duration records the time elapsed since start in a histogram of c, if not nil
+func Duration(c Collector, name string, start time.Time) {
+	if c != nil {
+		c.Histogram(name, time.Since(start).Seconds())
+	}
+}
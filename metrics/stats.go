@@ -0,0 +1,128 @@
+package metrics
+
+import "sync"
+
+// Stats is a Collector aggregating the metrics in memory: the total of the counters, the value of the gauges and
+// the count and sum of the observations of the histograms. The metrics are also recorded in its parent, if not nil.
+type Stats struct {
+	parent     *Stats
+	mu         sync.Mutex
+	counters   map[string]float64
+	gauges     map[string]float64
+	histograms map[string]Summary
+}
+
+// Summary is the count and the sum of the observations of a histogram
+type Summary struct {
+	Count int64
+	Sum   float64
+}
+
+// Snapshot is the state of Stats at a point in time
+type Snapshot struct {
+	Counters   map[string]float64
+	Gauges     map[string]float64
+	Histograms map[string]Summary
+}
+
+var global = NewStats(nil)
+
+// Global returns the Stats of all the connectors of the process
+func Global() *Stats {
+	return global
+}
+
+// NewStats returns empty Stats recording the metrics in parent as well
+func NewStats(parent *Stats) *Stats {
+	return &Stats{
+		parent:     parent,
+		counters:   map[string]float64{},
+		gauges:     map[string]float64{},
+		histograms: map[string]Summary{},
+	}
+}
+
+func (s *Stats) Counter(name string, value float64) {
+	s.mu.Lock()
+	s.counters[name] += value
+	s.mu.Unlock()
+	if s.parent != nil {
+		s.parent.Counter(name, value)
+	}
+}
+
+func (s *Stats) Histogram(name string, value float64) {
+	s.mu.Lock()
+	h := s.histograms[name]
+	h.Count++
+	h.Sum += value
+	s.histograms[name] = h
+	s.mu.Unlock()
+	if s.parent != nil {
+		s.parent.Histogram(name, value)
+	}
+}
+
+func (s *Stats) Gauge(name string, delta float64) {
+	s.mu.Lock()
+	s.gauges[name] += delta
+	s.mu.Unlock()
+	if s.parent != nil {
+		s.parent.Gauge(name, delta)
+	}
+}
+
+// Snapshot returns a copy of the current metrics
+func (s *Stats) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := Snapshot{
+		Counters:   make(map[string]float64, len(s.counters)),
+		Gauges:     make(map[string]float64, len(s.gauges)),
+		Histograms: make(map[string]Summary, len(s.histograms)),
+	}
+	for name, v := range s.counters {
+		snapshot.Counters[name] = v
+	}
+	for name, v := range s.gauges {
+		snapshot.Gauges[name] = v
+	}
+	for name, v := range s.histograms {
+		snapshot.Histograms[name] = v
+	}
+	return snapshot
+}
+
+// Tee returns a Collector passing the metrics to each of the collectors which isn't nil
+func Tee(collectors ...Collector) Collector {
+	var t tee
+	for _, c := range collectors {
+		if c != nil {
+			t = append(t, c)
+		}
+	}
+	if len(t) == 1 {
+		return t[0]
+	}
+	return t
+}
+
+type tee []Collector
+
+func (t tee) Counter(name string, value float64) {
+	for _, c := range t {
+		c.Counter(name, value)
+	}
+}
+
+func (t tee) Histogram(name string, value float64) {
+	for _, c := range t {
+		c.Histogram(name, value)
+	}
+}
+
+func (t tee) Gauge(name string, delta float64) {
+	for _, c := range t {
+		c.Gauge(name, delta)
+	}
+}
@@ -0,0 +1,156 @@
+// Package dbsqlpaginate runs a query a page at a time, for the APIs returning the results of a query to their own
+// clients in pages. Each page is a query of its own, ordered by unique columns and limited with LIMIT and OFFSET,
+// so no connection or cursor is held between pages. A page carries the token of the next page, which a client
+// sends back to get it:
+//
+//	p, err := dbsqlpaginate.New(db, "select id, name, amount from sales.orders where region = ?", []string{"id"}, 100, region)
+//	if err := p.Resume(req.PageToken); err != nil {
+//		return err
+//	}
+//	page, err := p.NextPage(ctx)
+//	// respond with page.Rows and page.NextToken, empty on the last page
+//
+// The order must be stable: the columns of orderBy have to identify the rows, e.g. the primary key, otherwise rows
+// may be repeated or skipped across pages. Rows inserted or deleted between pages shift the following pages.
+package dbsqlpaginate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidToken = "databricks: invalid page token"
+
+// Queryer runs the queries of the pages, it is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Paginator returns the pages of the results of a query
+type Paginator struct {
+	q        Queryer
+	query    string
+	args     []any
+	orderBy  []string
+	pageSize int
+	offset   int64
+	done     bool
+}
+
+// Page is a page of the results of a query
+type Page struct {
+	Columns   []string // names of the columns
+	Rows      [][]any  // values of the rows, as scanned into an any by database/sql
+	Offset    int64    // index of the first row of the page in the results
+	NextToken string   // token of the next page, see Paginator.Resume, empty on the last page
+}
+
+// RowCount returns the number of rows of the page
+func (p *Page) RowCount() int {
+	return len(p.Rows)
+}
+
+// New returns a paginator of query, whose results are ordered by the SQL expressions of orderBy, e.g. "id" or
+// "created_at DESC", and returned in pages of pageSize rows. args are the parameters of query.
+func New(q Queryer, query string, orderBy []string, pageSize int, args ...any) (*Paginator, error) {
+	if len(orderBy) == 0 {
+		return nil, errors.New("databricks: pages need an order, the columns identifying the rows")
+	}
+	if pageSize <= 0 {
+		return nil, errors.Errorf("databricks: invalid page size %d", pageSize)
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\n")
+	return &Paginator{q: q, query: query, args: args, orderBy: orderBy, pageSize: pageSize}, nil
+}
+
+// Resume makes the next page the page of token, a NextToken of a page of the same query, order, page size and args.
+// An empty token is the first page.
+func (p *Paginator) Resume(token string) error {
+	if token == "" {
+		p.offset, p.done = 0, false
+		return nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != 16 {
+		return errors.New(errInvalidToken)
+	}
+	offset := int64(binary.BigEndian.Uint64(b[8:]))
+	if string(b[:8]) != string(p.checksum()) || offset < 0 {
+		return errors.Errorf("%s, it is of another query", errInvalidToken)
+	}
+	p.offset, p.done = offset, false
+	return nil
+}
+
+// HasNext returns false once the last page was returned
+func (p *Paginator) HasNext() bool {
+	return !p.done
+}
+
+// NextPage runs the query of the next page and returns its rows. The page after the last one is empty.
+func (p *Paginator) NextPage(ctx context.Context) (*Page, error) {
+	page := &Page{Offset: p.offset}
+	if p.done {
+		return page, nil
+	}
+	// one more row than the page tells whether there is a next page
+	query := fmt.Sprintf("SELECT * FROM (%s) AS dbsql_page ORDER BY %s LIMIT %d OFFSET %d",
+		p.query, strings.Join(p.orderBy, ", "), p.pageSize+1, p.offset)
+	rows, err := p.q.QueryContext(ctx, query, p.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if page.Columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	hasNext := false
+	for rows.Next() {
+		if len(page.Rows) == p.pageSize {
+			hasNext = true
+			break
+		}
+		values := make([]any, len(page.Columns))
+		dest := make([]any, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		page.Rows = append(page.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	p.offset += int64(len(page.Rows))
+	p.done = !hasNext
+	if hasNext {
+		page.NextToken = p.token()
+	}
+	return page, nil
+}
+
+// token returns the token of the next page: the checksum of the paginator followed by the offset of the page
+func (p *Paginator) token() string {
+	b := make([]byte, 16)
+	copy(b, p.checksum())
+	binary.BigEndian.PutUint64(b[8:], uint64(p.offset))
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// checksum identifies the query, the order, the page size and the args of the tokens of the paginator
+func (p *Paginator) checksum() []byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%v", p.query, strings.Join(p.orderBy, "\x00"), p.pageSize, p.args)
+	return h.Sum(nil)[:8]
+}
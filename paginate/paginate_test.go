@@ -0,0 +1,67 @@
+package dbsqlpaginate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	dbsqltesting "github.com/databricks/databricks-sql-go/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginator(t *testing.T) {
+	server := dbsqltesting.NewServer()
+	defer server.Close()
+	columns := []dbsqltesting.Column{{Name: "id", Type: "BIGINT"}, {Name: "name", Type: "STRING"}}
+	for offset, rows := range map[int][][]any{
+		0: {{1, "a"}, {2, "b"}, {3, "c"}},
+		2: {{3, "c"}, {4, "d"}, {5, "e"}},
+		4: {{5, "e"}},
+	} {
+		server.AddResult(
+			fmt.Sprintf("SELECT * FROM (select id, name from orders) AS dbsql_page ORDER BY id LIMIT 3 OFFSET %d", offset),
+			dbsqltesting.Result{Columns: columns, Rows: rows},
+		)
+	}
+	db, err := sql.Open("databricks", server.DSN())
+	require.NoError(t, err)
+	defer db.Close()
+
+	p, err := New(db, "select id, name from orders;", []string{"id"}, 2)
+	require.NoError(t, err)
+
+	var pages []*Page
+	for p.HasNext() {
+		page, err := p.NextPage(context.Background())
+		require.NoError(t, err)
+		pages = append(pages, page)
+	}
+	require.Len(t, pages, 3)
+	assert.Equal(t, []string{"id", "name"}, pages[0].Columns)
+	assert.Equal(t, [][]any{{int64(1), "a"}, {int64(2), "b"}}, pages[0].Rows)
+	assert.Equal(t, 2, pages[1].RowCount())
+	assert.Equal(t, int64(2), pages[1].Offset)
+	assert.Equal(t, [][]any{{int64(5), "e"}}, pages[2].Rows)
+	assert.NotEmpty(t, pages[1].NextToken)
+	assert.Empty(t, pages[2].NextToken, "the last page has no next page")
+
+	// a client resumes with the token of a page
+	p, err = New(db, "select id, name from orders", []string{"id"}, 2)
+	require.NoError(t, err)
+	require.NoError(t, p.Resume(pages[0].NextToken))
+	page, err := p.NextPage(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, pages[1], page)
+
+	other, err := New(db, "select id, name from orders", []string{"id"}, 10)
+	require.NoError(t, err)
+	assert.ErrorContains(t, other.Resume(pages[0].NextToken), "invalid page token, it is of another query")
+	assert.ErrorContains(t, other.Resume("not a token"), "invalid page token")
+
+	_, err = New(db, "select * from orders", nil, 10)
+	assert.Error(t, err)
+	_, err = New(db, "select * from orders", []string{"id"}, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,419 @@
+package dbsql
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+)
+
+var errParametersMixed = "databricks: query parameters must be either all named or all positional"
+var errParameterType = "databricks: unsupported query parameter type %T"
+var errParameterNestedList = "databricks: query parameter lists can't contain lists"
+var errParameterMissing = "databricks: no value for query parameter %s"
+var errParameterUnused = "databricks: query parameter %s has no marker"
+
+// Parameter is a query parameter with an explicit SQL type, for values the driver can't infer the type of.
+// Value is sent as a string and cast to Type by the server, the type is inferred from Value when Type is empty:
+//
+//	rows, err := db.QueryContext(ctx, "select * from events where day = :day",
+//		sql.Named("day", dbsql.Parameter{Type: "DATE", Value: "2023-01-31"}))
+type Parameter struct {
+	Type  string
+	Value any
+}
+
+// parameterList is the value of a slice query parameter, its marker is expanded to the list of the markers of its
+// elements, e.g. for IN-lists, see expandListParameters
+type parameterList []driver.Value
+
+var _ driver.NamedValueChecker = (*conn)(nil)
+
+// CheckNamedValue keeps the types that are sent with their own SQL type, instead of letting
+// database/sql convert them to one of the default driver.Value types. The values of a driver.Valuer
+// are checked the same way. Slices, other than []byte, are kept as lists of checked values.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch v := nv.Value.(type) {
+	case nil, bool, int64, int32, int16, int8, float64, float32, string, []byte, time.Time, Date, Decimal, Interval, Parameter:
+		return nil
+	case int:
+		nv.Value = int64(v)
+		return nil
+	case driver.Valuer:
+		// a Valuer may return one of the types above, e.g. a Decimal, which the default conversion rejects
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return driver.ErrSkip
+		}
+		val, err := v.Value()
+		if err != nil {
+			return err
+		}
+		nv.Value = val
+		return c.CheckNamedValue(nv)
+	default:
+		// the byte slices of other types, e.g. json.RawMessage, are converted to []byte
+		rv := reflect.ValueOf(v)
+		if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+			return c.checkParameterList(nv, rv)
+		}
+		// use the default conversion, which also calls driver.Valuer
+		return driver.ErrSkip
+	}
+}
+
+// checkParameterList replaces the slice value of nv with the parameterList of its checked elements
+func (c *conn) checkParameterList(nv *driver.NamedValue, rv reflect.Value) error {
+	list := make(parameterList, rv.Len())
+	for i := range list {
+		elem := driver.NamedValue{Value: rv.Index(i).Interface()}
+		err := c.CheckNamedValue(&elem)
+		if err == driver.ErrSkip {
+			elem.Value, err = driver.DefaultParameterConverter.ConvertValue(elem.Value)
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := elem.Value.(parameterList); ok {
+			return newDriverError(errParameterNestedList, nil)
+		}
+		list[i] = elem.Value
+	}
+	nv.Value = list
+	return nil
+}
+
+// expandListParameters replaces the markers of the list parameters of query with the markers of their elements,
+// e.g. "id in (?)" with "id in (?, ?, ?)" for a list of 3 elements, and the list parameters with their elements.
+// The elements of a named list :ids are named ids__1, ids__2... The marker of an empty list is replaced with NULL,
+// which matches no value in an IN-list.
+func expandListParameters(query string, args []driver.NamedValue) (string, []driver.NamedValue) {
+	hasList := false
+	for _, arg := range args {
+		if _, ok := arg.Value.(parameterList); ok {
+			hasList = true
+			break
+		}
+	}
+	if !hasList {
+		return query, args
+	}
+
+	positional := map[int]driver.NamedValue{}
+	named := map[string]parameterList{}
+	var expanded []driver.NamedValue
+	for _, arg := range args {
+		if arg.Name == "" {
+			positional[arg.Ordinal] = arg
+			continue
+		}
+		if list, ok := arg.Value.(parameterList); ok {
+			named[arg.Name] = list
+			for i, v := range list {
+				expanded = append(expanded, driver.NamedValue{Name: fmt.Sprintf("%s__%d", arg.Name, i+1), Value: v})
+			}
+		} else {
+			expanded = append(expanded, arg)
+		}
+	}
+
+	var b strings.Builder
+	last, n, ordinal := 0, 0, 0
+	replace := func(i, end int, markers []string) {
+		b.WriteString(query[last:i])
+		if len(markers) == 0 {
+			b.WriteString("NULL")
+		} else {
+			b.WriteString(strings.Join(markers, ", "))
+		}
+		last = end
+	}
+	scanSQL(query, func(i int) {
+		switch query[i] {
+		case '?':
+			n++
+			arg, ok := positional[n]
+			if !ok {
+				return
+			}
+			delete(positional, n)
+			list, isList := arg.Value.(parameterList)
+			if !isList {
+				ordinal++
+				arg.Ordinal = ordinal
+				expanded = append(expanded, arg)
+				return
+			}
+			markers := make([]string, len(list))
+			for j, v := range list {
+				ordinal++
+				markers[j] = "?"
+				expanded = append(expanded, driver.NamedValue{Ordinal: ordinal, Value: v})
+			}
+			replace(i, i+1, markers)
+		case ':':
+			name := namedMarkerAt(query, i)
+			list, ok := named[name]
+			if name == "" || !ok {
+				return
+			}
+			markers := make([]string, len(list))
+			for j := range list {
+				markers[j] = fmt.Sprintf(":%s__%d", name, j+1)
+			}
+			replace(i, i+1+len(name), markers)
+		}
+	})
+	b.WriteString(query[last:])
+
+	// the positional arguments without a marker are left for the server to reject
+	rest := make([]int, 0, len(positional))
+	for j := range positional {
+		rest = append(rest, j)
+	}
+	sort.Ints(rest)
+	for _, j := range rest {
+		arg := positional[j]
+		ordinal++
+		arg.Ordinal = ordinal
+		expanded = append(expanded, arg)
+	}
+	return b.String(), expanded
+}
+
+// convertParameters converts the query arguments to the parameters of an execute statement request
+func convertParameters(args []driver.NamedValue) ([]*cli_service.TSparkParameter, error) {
+	params := make([]*cli_service.TSparkParameter, len(args))
+	for i := range args {
+		arg := args[i]
+		if (arg.Name == "") != (args[0].Name == "") {
+			return nil, newDriverError(errParametersMixed, nil)
+		}
+
+		sqlType, value, err := convertParameterValue(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := binaryParameter(arg.Value); ok {
+			// sent hex encoded, see bindBinaryParameters
+			hexValue := hex.EncodeToString(b)
+			sqlType, value = "STRING", &hexValue
+		}
+
+		param := &cli_service.TSparkParameter{Type: &sqlType}
+		if arg.Name != "" {
+			param.Name = &arg.Name
+		} else {
+			ordinal := int32(arg.Ordinal)
+			param.Ordinal = &ordinal
+		}
+		if value != nil {
+			param.Value = &cli_service.TSparkParameterValue{StringValue: value}
+		}
+		params[i] = param
+	}
+
+	return params, nil
+}
+
+// binaryParameter returns the bytes of a BINARY parameter value, ok is false for the other values
+func binaryParameter(val any) (b []byte, ok bool) {
+	switch v := val.(type) {
+	case []byte:
+		return v, v != nil
+	case Parameter:
+		if v.Type == "" || strings.EqualFold(v.Type, "BINARY") {
+			b, ok = v.Value.([]byte)
+			return b, ok && b != nil
+		}
+	}
+	return nil, false
+}
+
+// bindBinaryParameters wraps the markers of the BINARY parameters of query with unhex. The server reads the
+// values of the parameters as UTF-8 strings, which would mangle arbitrary bytes, so BINARY values are sent
+// hex encoded as STRING parameters and decoded by the query.
+func bindBinaryParameters(query string, args []driver.NamedValue) string {
+	positional := map[int]bool{}
+	named := map[string]bool{}
+	for _, arg := range args {
+		if _, ok := binaryParameter(arg.Value); !ok {
+			continue
+		}
+		if arg.Name != "" {
+			named[arg.Name] = true
+		} else {
+			positional[arg.Ordinal] = true
+		}
+	}
+	if len(positional) == 0 && len(named) == 0 {
+		return query
+	}
+
+	var b strings.Builder
+	last, n := 0, 0
+	scanSQL(query, func(i int) {
+		end := i
+		switch query[i] {
+		case '?':
+			n++
+			if positional[n] {
+				end = i + 1
+			}
+		case ':':
+			if name := namedMarkerAt(query, i); named[name] {
+				end = i + 1 + len(name)
+			}
+		}
+		if end > i {
+			b.WriteString(query[last:i])
+			b.WriteString("unhex(" + query[i:end] + ")")
+			last = end
+		}
+	})
+	b.WriteString(query[last:])
+	return b.String()
+}
+
+// convertParameterValue returns the SQL type of a parameter value and its string value, nil for NULL
+func convertParameterValue(val any) (string, *string, error) {
+	var sqlType, s string
+	switch v := val.(type) {
+	case nil:
+		return "VOID", nil, nil
+	case bool:
+		sqlType, s = "BOOLEAN", strconv.FormatBool(v)
+	case int64:
+		sqlType, s = "BIGINT", strconv.FormatInt(v, 10)
+	case int32:
+		sqlType, s = "INT", strconv.FormatInt(int64(v), 10)
+	case int16:
+		sqlType, s = "SMALLINT", strconv.FormatInt(int64(v), 10)
+	case int8:
+		sqlType, s = "TINYINT", strconv.FormatInt(int64(v), 10)
+	case float64:
+		sqlType, s = "DOUBLE", strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		sqlType, s = "FLOAT", strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case string:
+		sqlType, s = "STRING", v
+	case []byte:
+		if v == nil {
+			return "VOID", nil, nil
+		}
+		sqlType, s = "BINARY", string(v)
+	case time.Time:
+		sqlType, s = "TIMESTAMP", v.Format(time.RFC3339Nano)
+	case Date:
+		sqlType, s = "DATE", v.String()
+	case Decimal:
+		s = v.String()
+		precision := len(v.Unscaled().String())
+		if v.Unscaled().Sign() < 0 {
+			precision--
+		}
+		if precision < int(v.Scale()) {
+			precision = int(v.Scale())
+		}
+		sqlType = fmt.Sprintf("DECIMAL(%d,%d)", precision, v.Scale())
+	case Interval:
+		sqlType, s = "INTERVAL DAY TO SECOND", v.String()
+		if v.Months != 0 {
+			sqlType = "INTERVAL YEAR TO MONTH"
+		}
+	case Parameter:
+		inferred, value, err := convertParameterValue(v.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		if v.Type == "" {
+			return inferred, value, nil
+		}
+		return v.Type, value, nil
+	default:
+		return "", nil, newDriverError(fmt.Sprintf(errParameterType, val), nil)
+	}
+
+	return sqlType, &s, nil
+}
+
+// interpolateParameters replaces the parameter markers of query with the SQL literals of their values, see sqlLiteral,
+// for servers which don't support query parameters. The values without a marker and the markers without a value
+// are rejected, as are the values which have no literal.
+func interpolateParameters(query string, args []driver.NamedValue) (string, error) {
+	positional := map[int]driver.Value{}
+	named := map[string]driver.Value{}
+	for _, arg := range args {
+		if (arg.Name == "") != (args[0].Name == "") {
+			return "", newDriverError(errParametersMixed, nil)
+		}
+		if arg.Name != "" {
+			named[arg.Name] = arg.Value
+		} else {
+			positional[arg.Ordinal] = arg.Value
+		}
+	}
+
+	var b strings.Builder
+	var err error
+	last, n := 0, 0
+	used := map[string]bool{}
+	scanSQL(query, func(i int) {
+		if err != nil {
+			return
+		}
+		var marker string
+		var value driver.Value
+		var ok bool
+		switch query[i] {
+		case '?':
+			n++
+			marker = strconv.Itoa(n)
+			value, ok = positional[n]
+		case ':':
+			name := namedMarkerAt(query, i)
+			if name == "" {
+				return
+			}
+			marker = ":" + name
+			value, ok = named[name]
+		default:
+			return
+		}
+		if !ok {
+			err = newDriverError(fmt.Sprintf(errParameterMissing, marker), nil)
+			return
+		}
+		used[marker] = true
+		var literal string
+		if literal, err = sqlLiteral(value); err != nil {
+			return
+		}
+		b.WriteString(query[last:i])
+		b.WriteString(literal)
+		if query[i] == '?' {
+			last = i + 1
+		} else {
+			last = i + len(marker)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, arg := range args {
+		marker := strconv.Itoa(arg.Ordinal)
+		if arg.Name != "" {
+			marker = ":" + arg.Name
+		}
+		if !used[marker] {
+			return "", newDriverError(fmt.Sprintf(errParameterUnused, marker), nil)
+		}
+	}
+	b.WriteString(query[last:])
+	return b.String(), nil
+}
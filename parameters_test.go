@@ -0,0 +1,264 @@
+package dbsql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertParameters(t *testing.T) {
+	t.Run("values are sent with their SQL type", func(t *testing.T) {
+		ts := time.Date(2023, 1, 31, 10, 20, 30, 500, time.UTC)
+		cases := []struct {
+			value    any
+			sqlType  string
+			strValue *string
+		}{
+			{nil, "VOID", nil},
+			{true, "BOOLEAN", strPtr("true")},
+			{int64(-5), "BIGINT", strPtr("-5")},
+			{int32(5), "INT", strPtr("5")},
+			{int16(5), "SMALLINT", strPtr("5")},
+			{int8(5), "TINYINT", strPtr("5")},
+			{1.5, "DOUBLE", strPtr("1.5")},
+			{float32(1.5), "FLOAT", strPtr("1.5")},
+			{"abc", "STRING", strPtr("abc")},
+			{[]byte{0x01, 0xff}, "STRING", strPtr("01ff")},
+			{Parameter{Type: "binary", Value: []byte{0xfe}}, "STRING", strPtr("fe")},
+			{Parameter{Type: "STRING", Value: []byte("abc")}, "STRING", strPtr("abc")},
+			{ts, "TIMESTAMP", strPtr("2023-01-31T10:20:30.0000005Z")},
+			{NewDecimal(big.NewInt(-12345), 2), "DECIMAL(5,2)", strPtr("-123.45")},
+			{NewDecimal(big.NewInt(5), 3), "DECIMAL(3,3)", strPtr("0.005")},
+			{Interval{Months: 14}, "INTERVAL YEAR TO MONTH", strPtr("INTERVAL '1-2' YEAR TO MONTH")},
+			{Interval{Duration: time.Hour}, "INTERVAL DAY TO SECOND", strPtr("INTERVAL '0 01:00:00' DAY TO SECOND")},
+			{Date{Year: 2023, Month: time.January, Day: 31}, "DATE", strPtr("2023-01-31")},
+			{Parameter{Type: "DATE", Value: "2023-01-31"}, "DATE", strPtr("2023-01-31")},
+			{Parameter{Value: int64(1)}, "BIGINT", strPtr("1")},
+		}
+
+		for _, c := range cases {
+			params, err := convertParameters([]driver.NamedValue{{Ordinal: 1, Value: c.value}})
+			assert.NoError(t, err)
+			assert.Len(t, params, 1)
+			assert.Equal(t, int32(1), params[0].GetOrdinal())
+			assert.False(t, params[0].IsSetName())
+			assert.Equal(t, c.sqlType, params[0].GetType(), "%v", c.value)
+			if c.strValue == nil {
+				assert.Nil(t, params[0].Value)
+			} else {
+				assert.Equal(t, *c.strValue, params[0].GetValue().GetStringValue(), "%v", c.value)
+			}
+		}
+	})
+
+	t.Run("named parameters are sent by name", func(t *testing.T) {
+		params, err := convertParameters([]driver.NamedValue{{Name: "a", Ordinal: 1, Value: "x"}, {Name: "b", Ordinal: 2, Value: int64(2)}})
+		assert.NoError(t, err)
+		assert.Equal(t, "a", params[0].GetName())
+		assert.Equal(t, "b", params[1].GetName())
+		assert.False(t, params[0].IsSetOrdinal())
+	})
+
+	t.Run("named and positional parameters can't be mixed", func(t *testing.T) {
+		_, err := convertParameters([]driver.NamedValue{{Ordinal: 1, Value: "x"}, {Name: "b", Ordinal: 2, Value: "y"}})
+		assert.EqualError(t, err, errParametersMixed)
+	})
+
+	t.Run("unsupported values return an error", func(t *testing.T) {
+		_, err := convertParameters([]driver.NamedValue{{Ordinal: 1, Value: struct{}{}}})
+		assert.EqualError(t, err, "databricks: unsupported query parameter type struct {}")
+	})
+}
+
+func TestBindBinaryParameters(t *testing.T) {
+	blob := []byte{0x00, 0xff}
+	assert.Equal(t, "select ?, unhex(?) from t where c = '?'",
+		bindBinaryParameters("select ?, ? from t where c = '?'", []driver.NamedValue{{Ordinal: 1, Value: "x"}, {Ordinal: 2, Value: blob}}))
+	assert.Equal(t, "insert into t values (unhex(:data), :id, unhex(:data)) -- :data",
+		bindBinaryParameters("insert into t values (:data, :id, :data) -- :data", []driver.NamedValue{
+			{Name: "data", Ordinal: 1, Value: Parameter{Value: blob}},
+			{Name: "id", Ordinal: 2, Value: int64(1)},
+		}))
+	assert.Equal(t, "select unhex(:data)::string, :database",
+		bindBinaryParameters("select :data::string, :database", []driver.NamedValue{{Name: "data", Ordinal: 1, Value: blob}}))
+	assert.Equal(t, "select :data", bindBinaryParameters("select :data", []driver.NamedValue{{Name: "data", Ordinal: 1, Value: []byte(nil)}}))
+}
+
+func TestConn_CheckNamedValue(t *testing.T) {
+	c := &conn{}
+	for _, v := range []any{nil, int32(1), float32(1), time.Now(), NewDecimal(big.NewInt(1), 0), Interval{}, Date{}, Parameter{}} {
+		nv := driver.NamedValue{Value: v}
+		assert.NoError(t, c.CheckNamedValue(&nv))
+		assert.Equal(t, v, nv.Value)
+	}
+
+	nv := driver.NamedValue{Value: 1}
+	assert.NoError(t, c.CheckNamedValue(&nv))
+	assert.Equal(t, int64(1), nv.Value)
+
+	nv = driver.NamedValue{Value: uint(1)}
+	assert.Equal(t, driver.ErrSkip, c.CheckNamedValue(&nv))
+
+	nv = driver.NamedValue{Value: decimalValuer{NewDecimal(big.NewInt(5), 1)}}
+	assert.NoError(t, c.CheckNamedValue(&nv))
+	assert.Equal(t, NewDecimal(big.NewInt(5), 1), nv.Value, "Valuers can return the types of the driver")
+
+	nv = driver.NamedValue{Value: (*decimalValuer)(nil)}
+	assert.Equal(t, driver.ErrSkip, c.CheckNamedValue(&nv))
+
+	nv = driver.NamedValue{Value: []int{1, 2}}
+	assert.NoError(t, c.CheckNamedValue(&nv))
+	assert.Equal(t, parameterList{int64(1), int64(2)}, nv.Value, "slices are lists of checked values")
+
+	nv = driver.NamedValue{Value: [2]any{"a", uint8(1)}}
+	assert.NoError(t, c.CheckNamedValue(&nv))
+	assert.Equal(t, parameterList{"a", int64(1)}, nv.Value)
+
+	nv = driver.NamedValue{Value: json.RawMessage(`{}`)}
+	assert.Equal(t, driver.ErrSkip, c.CheckNamedValue(&nv), "byte slices aren't lists")
+
+	nv = driver.NamedValue{Value: [][]string{{"a"}}}
+	assert.EqualError(t, c.CheckNamedValue(&nv), errParameterNestedList)
+}
+
+func TestInterpolateParameters(t *testing.T) {
+	t.Run("markers are replaced with the literals of their values", func(t *testing.T) {
+		ts := time.Date(2023, 1, 31, 10, 30, 0, 123456000, time.UTC)
+		query, err := interpolateParameters("select ?, ?, ?, ?, ? from t where c = '?' -- ?", []driver.NamedValue{
+			{Ordinal: 1, Value: `it's a \ test`},
+			{Ordinal: 2, Value: ts},
+			{Ordinal: 3, Value: NewDecimal(big.NewInt(-12345), 2)},
+			{Ordinal: 4, Value: nil},
+			{Ordinal: 5, Value: []byte{0x00, 0xff}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `select 'it\'s a \\ test', CAST('2023-01-31T10:30:00.123456Z' AS TIMESTAMP), CAST('-123.45' AS DECIMAL(5,2)), NULL, X'00ff' from t where c = '?' -- ?`, query)
+
+		query, err = interpolateParameters("select :id::string, :day, :id", []driver.NamedValue{
+			{Name: "id", Ordinal: 1, Value: int64(7)},
+			{Name: "day", Ordinal: 2, Value: Parameter{Type: "DATE", Value: "2023-01-31"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "select CAST('7' AS BIGINT)::string, CAST('2023-01-31' AS DATE), CAST('7' AS BIGINT)", query)
+	})
+
+	t.Run("invalid parameters return an error", func(t *testing.T) {
+		_, err := interpolateParameters("select ?, ?", []driver.NamedValue{{Ordinal: 1, Value: int64(1)}})
+		assert.EqualError(t, err, "databricks: no value for query parameter 2")
+
+		_, err = interpolateParameters("select :a", []driver.NamedValue{{Name: "a", Value: int64(1)}, {Name: "b", Value: int64(2)}})
+		assert.EqualError(t, err, "databricks: query parameter :b has no marker")
+
+		_, err = interpolateParameters("select ?", []driver.NamedValue{{Ordinal: 1, Value: struct{}{}}})
+		assert.EqualError(t, err, "databricks: unsupported query parameter type struct {}")
+
+		_, err = interpolateParameters("select ?", []driver.NamedValue{{Ordinal: 1, Value: Parameter{Type: "INT) --", Value: "1"}}})
+		assert.Error(t, err)
+
+		_, err = interpolateParameters("select ?, :a", []driver.NamedValue{{Ordinal: 1, Value: int64(1)}, {Name: "a", Ordinal: 2, Value: int64(1)}})
+		assert.EqualError(t, err, errParametersMixed)
+	})
+}
+
+func TestInterpolateParametersInjection(t *testing.T) {
+	// sqlCode returns the bytes of query outside of its literals and comments
+	sqlCode := func(query string) string {
+		var b strings.Builder
+		scanSQL(query, func(i int) { b.WriteByte(query[i]) })
+		return b.String()
+	}
+	values := []string{
+		`'`, `\`, `\'`, `'); DROP TABLE t --`, `\'; DROP TABLE t --`, `'' OR 1=1`, "x' --\n; DROP TABLE t",
+		"INTERVAL 1 DAY; DROP TABLE t --", "INTERVAL '1' DAY) OR (1=1", `*/ DROP TABLE t /*`, `$$; DROP TABLE t; $$`,
+	}
+
+	t.Run("values can't leave their literal", func(t *testing.T) {
+		for _, v := range values {
+			for _, arg := range []driver.NamedValue{
+				{Ordinal: 1, Value: v},
+				{Ordinal: 1, Value: Parameter{Type: "DATE", Value: v}},
+				{Ordinal: 1, Value: Parameter{Type: "INTERVAL DAY", Value: v}},
+				{Ordinal: 1, Value: Parameter{Type: "ARRAY<STRING>", Value: v}},
+			} {
+				query, err := interpolateParameters("select * from t where c = ?", []driver.NamedValue{arg})
+				require.NoError(t, err)
+				want := "select * from t where c = "
+				if p, ok := arg.Value.(Parameter); ok {
+					want += "CAST( AS " + p.Type + ")"
+				}
+				assert.Equal(t, want, sqlCode(query), query)
+			}
+		}
+	})
+
+	t.Run("types can't leave the cast", func(t *testing.T) {
+		for _, sqlType := range []string{
+			"DATE) --", "DATE); DROP TABLE t --", "INTERVAL DAY) OR (1=1", "DECIMAL(10,2)) --", "STRING' --",
+			"INT /* */", "ARRAY<INT>) UNION SELECT (1", "INTERVAL DAY; DROP TABLE t",
+		} {
+			_, err := interpolateParameters("select ?", []driver.NamedValue{{Ordinal: 1, Value: Parameter{Type: sqlType, Value: "1"}}})
+			assert.Error(t, err, sqlType)
+		}
+	})
+}
+
+func TestExpandListParameters(t *testing.T) {
+	t.Run("positional lists are expanded to their elements", func(t *testing.T) {
+		query, args := expandListParameters("select * from t where a = ? and id in (?) and b = '?' and c = ?", []driver.NamedValue{
+			{Ordinal: 1, Value: "x"},
+			{Ordinal: 2, Value: parameterList{int64(1), int64(2), int64(3)}},
+			{Ordinal: 3, Value: true},
+		})
+		assert.Equal(t, "select * from t where a = ? and id in (?, ?, ?) and b = '?' and c = ?", query)
+		assert.Equal(t, []driver.NamedValue{
+			{Ordinal: 1, Value: "x"},
+			{Ordinal: 2, Value: int64(1)},
+			{Ordinal: 3, Value: int64(2)},
+			{Ordinal: 4, Value: int64(3)},
+			{Ordinal: 5, Value: true},
+		}, args)
+	})
+
+	t.Run("named lists are expanded to their elements", func(t *testing.T) {
+		query, args := expandListParameters("select * from t where id in (:ids) or parent in (:ids) and c = :c -- :ids", []driver.NamedValue{
+			{Name: "ids", Ordinal: 1, Value: parameterList{"a", "b"}},
+			{Name: "c", Ordinal: 2, Value: int64(1)},
+		})
+		assert.Equal(t, "select * from t where id in (:ids__1, :ids__2) or parent in (:ids__1, :ids__2) and c = :c -- :ids", query)
+		assert.Equal(t, []driver.NamedValue{
+			{Name: "ids__1", Value: "a"},
+			{Name: "ids__2", Value: "b"},
+			{Name: "c", Ordinal: 2, Value: int64(1)},
+		}, args)
+	})
+
+	t.Run("empty lists are replaced with NULL", func(t *testing.T) {
+		query, args := expandListParameters("select * from t where id in (?) and c = ?", []driver.NamedValue{
+			{Ordinal: 1, Value: parameterList{}},
+			{Ordinal: 2, Value: int64(1)},
+		})
+		assert.Equal(t, "select * from t where id in (NULL) and c = ?", query)
+		assert.Equal(t, []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}, args)
+	})
+
+	t.Run("queries without lists are unchanged", func(t *testing.T) {
+		args := []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}
+		query, expanded := expandListParameters("select ?", args)
+		assert.Equal(t, "select ?", query)
+		assert.Equal(t, args, expanded)
+	})
+}
+
+type decimalValuer struct {
+	d Decimal
+}
+
+func (v decimalValuer) Value() (driver.Value, error) {
+	return v.d, nil
+}
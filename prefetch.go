@@ -0,0 +1,237 @@
+package dbsql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+)
+
+// prefetchedPage is a result page fetched ahead of the reader, with its Arrow batches
+// decoded for Arrow results
+type prefetchedPage struct {
+	resp *cli_service.TFetchResultsResp
+	page *arrowPage
+	size int64
+	err  error
+}
+
+// prefetcher fetches the result pages following the current one in the background, so the
+// reader doesn't wait for the server at the end of each page. Pages are fetched in order
+// because the server returns them from a cursor. At most maxPages pages are kept ahead of
+// the reader and fetching stops while they use more memory than the budget.
+type prefetcher struct {
+	pages  chan prefetchedPage
+	budget *memoryBudget
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startPrefetch starts fetching the pages following the current page
+func (r *rows) startPrefetch() error {
+	// the metadata and the cloud fetch downloader are needed to decode pages, load them
+	// now so the background goroutine doesn't race the reader
+	metadata, err := r.getResultMetadata()
+	if err != nil {
+		return err
+	}
+	r.getDownloader()
+	if r.newClient != nil {
+		// the prefetcher fetches in the background while the connection runs other statements, the rows
+		// use their own client from now on
+		tclient, err := r.newClient()
+		if err != nil {
+			return err
+		}
+		r.client = tclient
+	}
+
+	ctx := r.requestContext()
+	// the pages are fetched in the foreground once the connector is closed
+	ctx, done, err := r.lifecycle.begin(ctx)
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+
+	bufferSize := r.cfg.MaxPrefetchPages - 1
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	p := &prefetcher{
+		// the goroutine holds one page while waiting to hand it over
+		pages:  make(chan prefetchedPage, bufferSize),
+		budget: newMemoryBudget(r.cfg.PrefetchMemoryLimit),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	r.prefetcher = p
+
+	go func() {
+		defer done()
+		p.run(ctx, r, metadata)
+	}()
+	return nil
+}
+
+func (p *prefetcher) run(ctx context.Context, r *rows, metadata *cli_service.TGetResultSetMetadataResp) {
+	defer close(p.done)
+	defer close(p.pages)
+
+	for {
+		resp, err := r.fetchPage(ctx, cli_service.TFetchOrientation_FETCH_NEXT)
+		next := prefetchedPage{resp: resp, err: err}
+		if err == nil && isArrowRowSet(resp.Results) {
+			next.page, next.err = r.decodeArrowPage(ctx, resp.Results, metadata)
+		}
+		if next.err == nil {
+			next.size = pageSize(resp.Results, next.page)
+			r.adaptPageSize(next.size, getNRows(resp.Results))
+			if !p.budget.acquire(next.size) {
+				next.page.release()
+				return
+			}
+		}
+
+		select {
+		case p.pages <- next:
+		case <-ctx.Done():
+			next.page.release()
+			p.budget.release(next.size)
+			return
+		}
+
+		if next.err != nil || !resp.GetHasMoreRows() {
+			return
+		}
+	}
+}
+
+// next returns the next page, ok is false when the prefetcher has stopped
+func (p *prefetcher) next() (page prefetchedPage, ok bool) {
+	page, ok = <-p.pages
+	return page, ok
+}
+
+// stop cancels fetching and frees the pages not handed over yet
+func (p *prefetcher) stop() {
+	p.cancel()
+	p.budget.close()
+	for page := range p.pages {
+		page.page.release()
+	}
+	<-p.done
+}
+
+// memoryBudget limits the memory used by the prefetched pages. A page is always
+// accepted when no other page is held, so pages larger than the budget don't block.
+type memoryBudget struct {
+	limit int64
+
+	mx     sync.Mutex
+	cond   *sync.Cond
+	used   int64
+	closed bool
+}
+
+func newMemoryBudget(limit int64) *memoryBudget {
+	b := &memoryBudget{limit: limit}
+	b.cond = sync.NewCond(&b.mx)
+	return b
+}
+
+// acquire waits until n bytes fit in the budget, it returns false if the budget was closed
+func (b *memoryBudget) acquire(n int64) bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	for b.limit > 0 && b.used > 0 && b.used+n > b.limit && !b.closed {
+		b.cond.Wait()
+	}
+	if b.closed {
+		return false
+	}
+	b.used += n
+	return true
+}
+
+func (b *memoryBudget) release(n int64) {
+	if n == 0 {
+		return
+	}
+	b.mx.Lock()
+	b.used -= n
+	b.mx.Unlock()
+	b.cond.Broadcast()
+}
+
+func (b *memoryBudget) close() {
+	b.mx.Lock()
+	b.closed = true
+	b.mx.Unlock()
+	b.cond.Broadcast()
+}
+
+// pageSize estimates the memory used by a result page
+func pageSize(rs *cli_service.TRowSet, page *arrowPage) int64 {
+	var size int64
+	if page != nil {
+		for _, record := range page.records {
+			size += arrowRecordSize(record)
+		}
+		return size
+	}
+
+	if rs == nil {
+		return 0
+	}
+	for _, col := range rs.Columns {
+		switch {
+		case col.BoolVal != nil:
+			size += int64(len(col.BoolVal.Values))
+		case col.ByteVal != nil:
+			size += int64(len(col.ByteVal.Values))
+		case col.I16Val != nil:
+			size += int64(len(col.I16Val.Values)) * 2
+		case col.I32Val != nil:
+			size += int64(len(col.I32Val.Values)) * 4
+		case col.I64Val != nil:
+			size += int64(len(col.I64Val.Values)) * 8
+		case col.DoubleVal != nil:
+			size += int64(len(col.DoubleVal.Values)) * 8
+		case col.StringVal != nil:
+			for _, v := range col.StringVal.Values {
+				// string header and data
+				size += 16 + int64(len(v))
+			}
+		case col.BinaryVal != nil:
+			for _, v := range col.BinaryVal.Values {
+				// slice header and data
+				size += 24 + int64(len(v))
+			}
+		}
+	}
+	return size
+}
+
+func arrowRecordSize(record arrow.Record) int64 {
+	var size int64
+	for _, col := range record.Columns() {
+		size += arrowDataSize(col.Data())
+	}
+	return size
+}
+
+func arrowDataSize(data arrow.ArrayData) int64 {
+	var size int64
+	for _, buf := range data.Buffers() {
+		if buf != nil {
+			size += int64(buf.Len())
+		}
+	}
+	for _, child := range data.Children() {
+		size += arrowDataSize(child)
+	}
+	return size
+}
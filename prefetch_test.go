@@ -0,0 +1,228 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefetch(t *testing.T) {
+	const nPages, pageRows = 5, 3
+
+	// returns a client serving nPages pages of an int column holding the row numbers,
+	// failing on page failPage if it is set
+	getClient := func(fetchCount *int32, failPage int32) cli_service.TCLIService {
+		return &client.TestClient{
+			FnGetResultSetMetadata: func(ctx context.Context, req *cli_service.TGetResultSetMetadataReq) (*cli_service.TGetResultSetMetadataResp, error) {
+				return &cli_service.TGetResultSetMetadataResp{
+					Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{{
+						ColumnName: "id",
+						TypeDesc: &cli_service.TTypeDesc{
+							Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: cli_service.TTypeId_BIGINT_TYPE}}},
+						},
+					}}},
+				}, nil
+			},
+			FnFetchResults: func(ctx context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+				page := atomic.AddInt32(fetchCount, 1) - 1
+				if page == failPage {
+					return nil, errors.New("fetch failed")
+				}
+				values := make([]int64, pageRows)
+				for i := range values {
+					values[i] = int64(page)*pageRows + int64(i)
+				}
+				return &cli_service.TFetchResultsResp{
+					Results: &cli_service.TRowSet{
+						StartRowOffset: int64(page) * pageRows,
+						Columns:        []*cli_service.TColumn{{I64Val: &cli_service.TI64Column{Values: values}}},
+					},
+					HasMoreRows: boolPtr(page < nPages-1),
+				}, nil
+			},
+			FnCloseOperation: func(ctx context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+				return &cli_service.TCloseOperationResp{}, nil
+			},
+		}
+	}
+	opHandle := &cli_service.TOperationHandle{OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4}}}
+	getConfig := func(pages int) *config.Config {
+		cfg := config.WithDefaults()
+		cfg.MaxRows = pageRows
+		cfg.MaxPrefetchPages = pages
+		return cfg
+	}
+
+	t.Run("should fetch pages ahead of the reader", func(t *testing.T) {
+		var fetchCount int32
+		r := NewRows("", "", getClient(&fetchCount, -1), opHandle, getConfig(2), nil)
+
+		row := make([]driver.Value, 1)
+		require.NoError(t, r.Next(row))
+		// the page being read and two more pages
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&fetchCount) == 3 }, time.Second, time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&fetchCount))
+
+		for i := int64(1); i < nPages*pageRows; i++ {
+			require.NoError(t, r.Next(row))
+			assert.Equal(t, i, row[0])
+		}
+		assert.Equal(t, io.EOF, r.Next(row))
+		assert.Equal(t, int32(nPages), atomic.LoadInt32(&fetchCount))
+		assert.NoError(t, r.Close())
+	})
+
+	t.Run("should prefetch with a client of its own", func(t *testing.T) {
+		var connFetchCount, fetchCount int32
+		r := NewRows("", "", getClient(&connFetchCount, -1), opHandle, getConfig(2), nil)
+		setPrefetchClient(r, func() (cli_service.TCLIService, error) {
+			return getClient(&fetchCount, -1), nil
+		})
+
+		row := make([]driver.Value, 1)
+		for i := int64(0); i < nPages*pageRows; i++ {
+			require.NoError(t, r.Next(row))
+			assert.Equal(t, i, row[0])
+		}
+		assert.Equal(t, io.EOF, r.Next(row))
+		assert.Equal(t, int32(nPages), atomic.LoadInt32(&fetchCount))
+		assert.Zero(t, atomic.LoadInt32(&connFetchCount), "the client of the connection runs its own statements")
+		assert.NoError(t, r.Close())
+
+		r = NewRows("", "", getClient(&connFetchCount, -1), opHandle, getConfig(2), nil)
+		setPrefetchClient(r, func() (cli_service.TCLIService, error) {
+			return nil, errors.New("client failed")
+		})
+		assert.EqualError(t, r.Next(row), "client failed")
+	})
+
+	t.Run("should not prefetch when disabled", func(t *testing.T) {
+		var fetchCount int32
+		r := NewRows("", "", getClient(&fetchCount, -1), opHandle, getConfig(0), nil)
+
+		row := make([]driver.Value, 1)
+		require.NoError(t, r.Next(row))
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&fetchCount))
+		assert.NoError(t, r.Close())
+	})
+
+	t.Run("should return fetch errors in order", func(t *testing.T) {
+		var fetchCount int32
+		r := NewRows("", "", getClient(&fetchCount, 2), opHandle, getConfig(2), nil)
+
+		row := make([]driver.Value, 1)
+		for i := 0; i < 2*pageRows; i++ {
+			require.NoError(t, r.Next(row))
+		}
+		assert.EqualError(t, r.Next(row), "fetch failed")
+		assert.NoError(t, r.Close())
+	})
+
+	t.Run("should stop prefetching on close", func(t *testing.T) {
+		var fetchCount int32
+		r := NewRows("", "", getClient(&fetchCount, -1), opHandle, getConfig(2), nil).(*rows)
+
+		row := make([]driver.Value, 1)
+		require.NoError(t, r.Next(row))
+		p := r.prefetcher
+		require.NotNil(t, p)
+		assert.NoError(t, r.Close())
+
+		select {
+		case <-p.done:
+		default:
+			t.Fatal("prefetcher still running")
+		}
+		assert.Nil(t, r.prefetcher)
+	})
+}
+
+func TestAdaptPageSize(t *testing.T) {
+	const valueSize = 256 << 10
+	var requests []*cli_service.TFetchResultsReq
+	testClient := &client.TestClient{
+		FnGetResultSetMetadata: func(ctx context.Context, req *cli_service.TGetResultSetMetadataReq) (*cli_service.TGetResultSetMetadataResp, error) {
+			return &cli_service.TGetResultSetMetadataResp{
+				Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{{
+					ColumnName: "payload",
+					TypeDesc: &cli_service.TTypeDesc{
+						Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: cli_service.TTypeId_STRING_TYPE}}},
+					},
+				}}},
+			}, nil
+		},
+		FnFetchResults: func(ctx context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+			requests = append(requests, req)
+			values := []string{strings.Repeat("a", valueSize), strings.Repeat("b", valueSize)}
+			return &cli_service.TFetchResultsResp{
+				Results: &cli_service.TRowSet{
+					StartRowOffset: int64(len(requests)-1) * 2,
+					Columns:        []*cli_service.TColumn{{StringVal: &cli_service.TStringColumn{Values: values}}},
+				},
+				HasMoreRows: boolPtr(len(requests) < 2),
+			}, nil
+		},
+	}
+	cfg := config.WithDefaults()
+	cfg.MaxRows = 1000
+	cfg.MaxPrefetchPages = 0
+	cfg.MaxPageBytes = 1 << 20
+	opHandle := &cli_service.TOperationHandle{OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4}}}
+	r := NewRows("", "", testClient, opHandle, cfg, nil)
+
+	row := make([]driver.Value, 1)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, r.Next(row))
+	}
+	assert.Equal(t, io.EOF, r.Next(row))
+	require.Len(t, requests, 2)
+	assert.Equal(t, int64(1000), requests[0].MaxRows)
+	assert.Equal(t, int64(3), requests[1].MaxRows, "pages of wide rows are fetched with fewer rows")
+	assert.Equal(t, int64(1<<20), requests[1].GetMaxBytes())
+
+	narrow := &rows{cfg: cfg, pageSize: 3}
+	narrow.adaptPageSize(64, 8)
+	assert.Equal(t, int64(1000), narrow.pageSize, "the max rows per page still apply")
+}
+
+func TestMemoryBudget(t *testing.T) {
+	b := newMemoryBudget(100)
+
+	// a page larger than the budget is accepted when nothing else is held
+	assert.True(t, b.acquire(150))
+
+	acquired := make(chan bool)
+	go func() { acquired <- b.acquire(10) }()
+	select {
+	case <-acquired:
+		t.Fatal("acquired memory over the budget")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	b.release(150)
+	assert.True(t, <-acquired)
+	assert.True(t, b.acquire(90))
+
+	go func() { acquired <- b.acquire(10) }()
+	time.Sleep(10 * time.Millisecond)
+	b.close()
+	assert.False(t, <-acquired)
+
+	// no limit
+	b = newMemoryBudget(0)
+	assert.True(t, b.acquire(1<<40))
+	assert.True(t, b.acquire(1<<40))
+}
@@ -0,0 +1,76 @@
+package dbsql
+
+import (
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/config"
+)
+
+// ServerCapabilities are the features supported by the server of a connection, derived from the protocol version
+// negotiated when its session was opened. The driver doesn't use the features the server doesn't support: the
+// results are fetched without Arrow, cloud fetch or compression, and query parameters fail unless they are
+// interpolated, see WithParameterInterpolation. The server is an all-purpose cluster or a SQL warehouse, told
+// apart by the HTTP path.
+type ServerCapabilities struct {
+	Cluster          bool // the server is an all-purpose cluster rather than a SQL warehouse
+	ProtocolVersion  int  // negotiated version of the Spark protocol, from 1 to 8, 0 for a Hive server
+	DirectResults    bool // the status and first rows of a statement are returned with its response, from version 1
+	CloudFetch       bool // large results are downloaded from cloud storage, from version 3
+	MultipleCatalogs bool // statements and metadata operations use other catalogs than the current one, from version 4
+	ArrowResults     bool // results are sent as Arrow record batches, from version 5
+	LZ4Compression   bool // Arrow results are compressed with LZ4, from version 6
+	Parameters       bool // query parameters are bound by the server, from version 8
+}
+
+// negotiateProtocolVersion returns the protocol version of a session, the version of the server capped by the
+// version requested by the client
+func negotiateProtocolVersion(client, server cli_service.TProtocolVersion) cli_service.TProtocolVersion {
+	if server > client {
+		return client
+	}
+	return server
+}
+
+// newServerCapabilities returns the capabilities of a session, the server may disable the use of multiple
+// catalogs explicitly
+func newServerCapabilities(version cli_service.TProtocolVersion, canUseMultipleCatalogs *bool) ServerCapabilities {
+	supports := func(v cli_service.TProtocolVersion) bool {
+		return version >= v
+	}
+	caps := ServerCapabilities{
+		DirectResults:    supports(cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V1),
+		CloudFetch:       supports(cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V3),
+		MultipleCatalogs: supports(cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V4),
+		ArrowResults:     supports(cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V5),
+		LZ4Compression:   supports(cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V6),
+		Parameters:       supports(cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8),
+	}
+	if caps.DirectResults {
+		caps.ProtocolVersion = int(version-cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V1) + 1
+	}
+	if canUseMultipleCatalogs != nil && !*canUseMultipleCatalogs {
+		caps.MultipleCatalogs = false
+	}
+	return caps
+}
+
+// ServerCapabilities returns the capabilities of the server of the connection, all false but Cluster for a lazy
+// connection which didn't open its session yet
+func (c *conn) ServerCapabilities() ServerCapabilities {
+	c.mu.Lock()
+	session := c.session
+	c.mu.Unlock()
+	var caps ServerCapabilities
+	if session != nil {
+		caps = newServerCapabilities(negotiateProtocolVersion(c.cfg.ThriftProtocolVersion, session.ServerProtocolVersion), session.CanUseMultipleCatalogs)
+	}
+	caps.Cluster = config.IsClusterPath(c.cfg.HTTPPath)
+	return caps
+}
+
+// computeName returns the kind of compute of the connection for the logs, cluster or warehouse
+func (c *conn) computeName() string {
+	if config.IsClusterPath(c.cfg.HTTPPath) {
+		return "cluster"
+	}
+	return "warehouse"
+}
@@ -0,0 +1,52 @@
+package dbsql
+
+import (
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServerCapabilities(t *testing.T) {
+	assert.Equal(t, ServerCapabilities{
+		ProtocolVersion:  8,
+		DirectResults:    true,
+		CloudFetch:       true,
+		MultipleCatalogs: true,
+		ArrowResults:     true,
+		LZ4Compression:   true,
+		Parameters:       true,
+	}, newServerCapabilities(cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8, nil))
+
+	assert.Equal(t, ServerCapabilities{
+		ProtocolVersion: 3,
+		DirectResults:   true,
+		CloudFetch:      true,
+	}, newServerCapabilities(cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V3, nil))
+
+	assert.Equal(t, ServerCapabilities{}, newServerCapabilities(cli_service.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V10, nil))
+
+	caps := newServerCapabilities(cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8, thrift.BoolPtr(false))
+	assert.False(t, caps.MultipleCatalogs, "the server disabled multiple catalogs")
+	assert.True(t, caps.Parameters)
+}
+
+func TestConn_ServerCapabilities(t *testing.T) {
+	c := &conn{cfg: config.WithDefaults()}
+	assert.Equal(t, ServerCapabilities{}, c.ServerCapabilities(), "a lazy connection without a session")
+
+	c = &conn{session: getTestSessionV8(), cfg: config.WithDefaults()}
+	c.cfg.ThriftProtocolVersion = cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V6
+	caps := c.ServerCapabilities()
+	assert.Equal(t, 6, caps.ProtocolVersion, "the version is capped by the version of the client")
+	assert.False(t, caps.Parameters)
+	assert.True(t, caps.LZ4Compression)
+	assert.False(t, caps.Cluster)
+
+	c.cfg.HTTPPath = "/sql/protocolv1/o/1234567890123456/0123-456789-abcdefgh"
+	assert.True(t, c.ServerCapabilities().Cluster)
+	assert.Equal(t, "cluster", c.computeName())
+	assert.Equal(t, ServerCapabilities{Cluster: true}, (&conn{cfg: c.cfg}).ServerCapabilities(), "the HTTP path tells a cluster without a session")
+}
@@ -0,0 +1,91 @@
+package dbsql
+
+import "strings"
+
+// checkReadOnly returns an error when the connection is read-only and one of the statements may change data
+func (c *conn) checkReadOnly(statements ...string) error {
+	if !c.cfg.ReadOnly {
+		return nil
+	}
+	for _, statement := range statements {
+		if !isReadOnlyStatement(statement) {
+			return newDriverError(ErrReadOnlyStatement, nil)
+		}
+	}
+	return nil
+}
+
+// readOnlyKeywords are the first keywords of the statements allowed on a read-only connection. SET, RESET and USE
+// only change the state of the session.
+var readOnlyKeywords = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"VALUES":   true,
+	"TABLE":    true,
+	"FROM":     true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"EXPLAIN":  true,
+	"LIST":     true,
+	"SET":      true,
+	"RESET":    true,
+	"USE":      true,
+}
+
+// mutatingKeywords are the keywords which reject a statement anywhere in it, e.g. the INSERT of
+// "WITH s AS (...) INSERT INTO t SELECT * FROM s" or the multi-insert "FROM s INSERT INTO t SELECT *"
+var mutatingKeywords = map[string]bool{
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"CREATE":   true,
+	"DROP":     true,
+	"ALTER":    true,
+	"TRUNCATE": true,
+	"COPY":     true,
+	"GRANT":    true,
+	"REVOKE":   true,
+	"OPTIMIZE": true,
+	"VACUUM":   true,
+	"RESTORE":  true,
+}
+
+// isReadOnlyStatement returns true when query only reads data: its first keyword is in readOnlyKeywords and none
+// of its keywords are in mutatingKeywords. Keywords in string literals, comments and backquoted identifiers are
+// ignored, so an unqualified column named like a mutating keyword must be backquoted.
+func isReadOnlyStatement(query string) bool {
+	keywords := statementKeywords(query)
+	if len(keywords) == 0 || !readOnlyKeywords[strings.ToUpper(keywords[0])] {
+		return false
+	}
+	for _, k := range keywords[1:] {
+		if mutatingKeywords[strings.ToUpper(k)] {
+			return false
+		}
+	}
+	return true
+}
+
+// statementKeywords returns the words of query which may be keywords, outside of string literals, comments and
+// backquoted identifiers
+func statementKeywords(query string) []string {
+	var keywords []string
+	start := -1
+	scanSQL(query, func(i int) {
+		if start >= 0 {
+			if isIdentifierChar(query[i]) && i == start+len(keywords[len(keywords)-1]) {
+				keywords[len(keywords)-1] = query[start : i+1]
+				return
+			}
+			start = -1
+		}
+		// the names of parameter markers and qualified names, e.g. :delete or t.update, aren't keywords
+		if isIdentifierStart(query[i]) && (i == 0 || !isIdentifierChar(query[i-1]) && query[i-1] != ':' && query[i-1] != '.') {
+			start = i
+			keywords = append(keywords, query[i:i+1])
+		}
+	})
+	return keywords
+}
@@ -0,0 +1,118 @@
+package dbsql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReadOnlyStatement(t *testing.T) {
+	readOnly := []string{
+		"select * from orders",
+		"  -- top orders\n SELECT * FROM orders ORDER BY total DESC",
+		"/* report */ with s as (select 1) select * from s",
+		"(select 1) union all (select 2)",
+		"show tables in sales",
+		"describe table extended orders",
+		"explain select 1",
+		"values (1), (2)",
+		"set ansi_mode = true",
+		"use catalog main",
+		"select 'drop table orders' as msg",
+		"select `update`, t.delete, a.merge.create from t",
+		"select * from t where id = :delete",
+		"select replace(name, 'a', 'b') from t",
+		`select r'\d+ insert', R"\" from t`,
+		`select regexp_like(name, r'^drop\s') from t`,
+		`select bar'\' drop' from t`,
+	}
+	for _, query := range readOnly {
+		assert.True(t, isReadOnlyStatement(query), query)
+	}
+
+	mutating := []string{
+		"",
+		"-- select",
+		"insert into t values (1)",
+		"update t set a = 1",
+		"DELETE FROM t",
+		"merge into t using s on t.id = s.id when matched then delete",
+		"create or replace table t as select 1",
+		"drop table orders",
+		"alter table t add column c int",
+		"truncate table t",
+		"copy into t from '/tmp'",
+		"grant select on t to `bob`",
+		"optimize t",
+		"vacuum t",
+		"with s as (select 1) insert into t select * from s",
+		"from s insert into t select *",
+		"explain delete from t",
+		"select 1; drop table orders",
+		"put '/tmp/f' into '/Volumes/v/f'",
+		"remove '/Volumes/v/f'",
+		"cache table t",
+		`with x as (select r'\') insert into t select 1 --'`,
+		`select R"\" ; drop table t; select "x"`,
+		`select r'\', 1 from t; delete from t -- '`,
+	}
+	for _, query := range mutating {
+		assert.False(t, isReadOnlyStatement(query), query)
+	}
+}
+
+func TestConn_ReadOnly(t *testing.T) {
+	var queries []string
+	testClient := &client.TestClient{
+		FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+			queries = append(queries, req.Statement)
+			return &cli_service.TExecuteStatementResp{
+				Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+				OperationHandle: &cli_service.TOperationHandle{
+					OperationId: &cli_service.THandleIdentifier{
+						GUID:   []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54},
+						Secret: []byte("b"),
+					},
+				},
+			}, nil
+		},
+		FnGetOperationStatus: func(ctx context.Context, req *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
+			return &cli_service.TGetOperationStatusResp{
+				OperationState:  cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+				NumModifiedRows: thrift.Int64Ptr(0),
+			}, nil
+		},
+		FnCloseOperation: func(ctx context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+			return &cli_service.TCloseOperationResp{}, nil
+		},
+	}
+	cfg := config.WithDefaults()
+	cfg.ReadOnly = true
+	testConn := &conn{
+		session: getTestSession(),
+		client:  testClient,
+		cfg:     cfg,
+	}
+
+	_, err := testConn.ExecContext(context.Background(), "set ansi_mode = true", nil)
+	assert.NoError(t, err)
+
+	_, err = testConn.ExecContext(context.Background(), "drop table orders", nil)
+	assert.ErrorContains(t, err, ErrReadOnlyStatement)
+
+	_, err = testConn.QueryContext(context.Background(), "select 1; delete from orders", nil)
+	assert.ErrorContains(t, err, ErrReadOnlyStatement)
+
+	_, err = testConn.ExecContext(context.Background(), "put '/tmp/orders.csv' into '/Volumes/main/default/v/orders.csv'", nil)
+	assert.ErrorContains(t, err, ErrReadOnlyStatement)
+
+	_, err = testConn.ExecContext(context.Background(), `with x as (select r'\') insert into orders select 1 --'`, nil)
+	assert.ErrorContains(t, err, ErrReadOnlyStatement, "a raw string has no escapes")
+
+	assert.Equal(t, []string{"set ansi_mode = true"}, queries)
+}
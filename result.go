@@ -1,16 +1,58 @@
 package dbsql
 
-import "database/sql/driver"
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+)
+
+// Result is implemented by the results of the statements run by the driver. database/sql hides the result of
+// the driver behind its own, so the statement has to be run on the driver connection to get it:
+//
+//	err = conn.Raw(func(driverConn any) error {
+//		res, err := driverConn.(driver.ExecerContext).ExecContext(ctx, "delete from orders where id < 100", nil)
+//		if err != nil {
+//			return err
+//		}
+//		stats, err := res.(dbsql.Result).Stats(ctx)
+//		...
+//	})
+type Result interface {
+	driver.Result
+	// QueryId returns the id of the statement, empty for the results of scripts and batches
+	QueryId() string
+	// Stats returns the statistics of the statement from the Query History. They are added to the history
+	// shortly after the statement finished, an error is returned until they are available.
+	Stats(ctx context.Context) (*StatementStats, error)
+}
+
+// StatementStats are the statistics of a statement
+type StatementStats struct {
+	RowsRead      int64         // rows read from the tables
+	BytesRead     int64         // bytes scanned from the tables
+	RowsProduced  int64         // rows returned or written
+	TaskTime      time.Duration // time spent by all the tasks of the statement
+	ExecutionTime time.Duration // time spent running the statement
+	TotalTime     time.Duration // time between the submission and the end of the statement
+}
 
 type result struct {
 	AffectedRows int64
 	InsertId     int64
+
+	queryId    string
+	cfg        *config.Config
+	httpClient *http.Client
 }
 
-var _ driver.Result = (*result)(nil)
+var _ Result = (*result)(nil)
 
-// LastInsertId returns the database's auto-generated ID after an insert into a table.
-// This is currently not really implemented for this driver and will always return 0.
+// LastInsertId returns the largest value of the identity column of the table after an INSERT when it is enabled
+// with WithLastInsertId, 0 otherwise.
 func (res *result) LastInsertId() (int64, error) {
 	return res.InsertId, nil
 }
@@ -19,3 +61,28 @@ func (res *result) LastInsertId() (int64, error) {
 func (res *result) RowsAffected() (int64, error) {
 	return res.AffectedRows, nil
 }
+
+func (res *result) QueryId() string {
+	return res.queryId
+}
+
+func (res *result) Stats(ctx context.Context) (*StatementStats, error) {
+	if res.queryId == "" || res.httpClient == nil {
+		return nil, newDriverError(ErrStatsNotAvailable, nil)
+	}
+	m, err := client.GetQueryMetrics(ctx, res.cfg, res.httpClient, res.queryId)
+	if err != nil {
+		return nil, wrapErr(err, "failed to get statement stats")
+	}
+	if m == nil {
+		return nil, newDriverError(ErrStatsNotAvailable, nil)
+	}
+	return &StatementStats{
+		RowsRead:      m.RowsReadCount,
+		BytesRead:     m.ReadBytes,
+		RowsProduced:  m.RowsProducedCount,
+		TaskTime:      time.Duration(m.TaskTotalTimeMs) * time.Millisecond,
+		ExecutionTime: time.Duration(m.ExecutionTimeMs) * time.Millisecond,
+		TotalTime:     time.Duration(m.TotalTimeMs) * time.Millisecond,
+	}, nil
+}
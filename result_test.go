@@ -0,0 +1,49 @@
+package dbsql
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResult_Stats(t *testing.T) {
+	const queryId = "01ed9db9-24c4-1cb6-a320-fb6ba623bdd2"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"res": [{"query_id": "` + queryId + `", "status": "FINISHED", "metrics": {
+			"rows_read_count": 1000, "read_bytes": 65536, "rows_produced_count": 10,
+			"task_total_time_ms": 2500, "execution_time_ms": 800, "total_time_ms": 900}}]}`))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cfg := config.WithDefaults()
+	cfg.Protocol = "http"
+	cfg.Host = serverURL.Hostname()
+	cfg.Port, _ = strconv.Atoi(serverURL.Port())
+	res := &result{AffectedRows: 10, queryId: queryId, cfg: cfg, httpClient: server.Client()}
+
+	stats, err := res.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &StatementStats{
+		RowsRead:      1000,
+		BytesRead:     65536,
+		RowsProduced:  10,
+		TaskTime:      2500 * time.Millisecond,
+		ExecutionTime: 800 * time.Millisecond,
+		TotalTime:     900 * time.Millisecond,
+	}, stats)
+
+	res.queryId = "01ed9db9-24c4-1cb6-a320-000000000000"
+	_, err = res.Stats(context.Background())
+	assert.ErrorContains(t, err, ErrStatsNotAvailable, "the statement is not in the history")
+}
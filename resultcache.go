@@ -0,0 +1,288 @@
+package dbsql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/databricks/databricks-sql-go/resultcache"
+	dbsqlrows "github.com/databricks/databricks-sql-go/rows"
+)
+
+// isCacheableQuery returns true when the result of query can be cached: it only reads data and doesn't change
+// the state of the session
+func isCacheableQuery(query string) bool {
+	if !isReadOnlyStatement(query) {
+		return false
+	}
+	switch strings.ToUpper(statementKeywords(query)[0]) {
+	case "SET", "RESET", "USE":
+		return false
+	}
+	return true
+}
+
+// normalizeQuery collapses the whitespace of query outside of string literals, quoted identifiers and comments,
+// and drops its trailing semicolon, so queries which only differ in their formatting share their cached results
+func normalizeQuery(query string) string {
+	var b strings.Builder
+	space := false
+	write := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(s)
+	}
+	next := 0 // index of the first byte not written
+	scanSQL(query, func(i int) {
+		if next < i {
+			// literals and comments are kept as is
+			write(query[next:i])
+		}
+		next = i + 1
+		if isSpace(query[i]) {
+			space = true
+			return
+		}
+		write(query[i : i+1])
+	})
+	if next < len(query) {
+		write(query[next:])
+	}
+	return strings.TrimRight(b.String(), "; ")
+}
+
+// resultCacheKey returns the key of the cached result of query, made of the workspace and warehouse, the principal
+// running the query, the catalog and schema the query runs in, the normalized query, its arguments, its conf and
+// the settings changing the values of the results. It returns "" when the principal is unknown, the result isn't
+// cached then.
+func (c *conn) resultCacheKey(ctx context.Context, query string, args []driver.NamedValue) string {
+	principal, err := c.principal()
+	if err != nil {
+		logger.Debug().Err(err).Msg("databricks: result not cached, failed to authenticate")
+		return ""
+	}
+	catalog, schema := c.catalog, c.schema
+	if s := driverctx.CatalogFromContext(ctx); s != "" {
+		catalog = s
+	}
+	if s := driverctx.SchemaFromContext(ctx); s != "" {
+		schema = s
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00", c.cfg.Host, c.cfg.HTTPPath, principal, catalog, schema, normalizeQuery(query))
+	for _, a := range args {
+		fmt.Fprintf(h, "%d\x00%s\x00%T\x00%v\x00", a.Ordinal, a.Name, a.Value, a.Value)
+	}
+
+	var conf map[string]string
+	if c.cfg.StatementConf != nil {
+		conf = c.cfg.StatementConf(ctx)
+	}
+	params := make([]string, 0, len(c.cfg.SessionParams)+len(c.params)+len(conf))
+	for k, v := range c.cfg.SessionParams {
+		params = append(params, k+"="+v)
+	}
+	for k, v := range c.params {
+		params = append(params, k+"="+v)
+	}
+	// the conf of the statement overrides the session params
+	for k, v := range conf {
+		params = append(params, "statement."+k+"="+v)
+	}
+	sort.Strings(params)
+	fmt.Fprintf(h, "%s\x00%v\x00%v\x00%t\x00%s\x00%s\x00%t", strings.Join(params, "\x00"), c.cfg.Location, c.cfg.NaiveTimestampLocation,
+		c.cfg.DecodeComplexTypes, c.cfg.TimestampConversion, c.cfg.DateConversion, c.cfg.ZeroCopyScan)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// principal returns the credentials the requests of the connection are sent with, which identify the principal
+// running its queries. The connectors of different principals may share a store, e.g. the instances of a service,
+// but not the results of their queries. The credentials are only part of the hash of a key.
+func (c *conn) principal() (string, error) {
+	if c.cfg.Authenticator == nil {
+		return "", nil
+	}
+	req, err := http.NewRequest(http.MethodPost, c.cfg.ToEndpointURL(), nil)
+	if err != nil {
+		return "", err
+	}
+	if err := c.cfg.Authenticator.Authenticate(req); err != nil {
+		return "", err
+	}
+	return req.Header.Get("Authorization"), nil
+}
+
+// cachingRows records the rows read from the results of a query, and caches them when the results are read to
+// the end. The results larger than the max bytes of a cached result, or read as Arrow batches, are not cached.
+type cachingRows struct {
+	*rows
+	ctx    context.Context
+	key    string
+	result *resultcache.Result // nil when the results are not cached
+}
+
+func (c *conn) newCachingRows(ctx context.Context, key string, r driver.Rows) driver.Rows {
+	dbsqlRows, ok := r.(*rows)
+	if !ok {
+		return r
+	}
+	return &cachingRows{rows: dbsqlRows, ctx: ctx, key: key, result: &resultcache.Result{}}
+}
+
+func (r *cachingRows) Next(dest []driver.Value) error {
+	err := r.rows.Next(dest)
+	if r.result == nil {
+		return err
+	}
+	if err != nil {
+		if err == io.EOF {
+			r.store()
+		}
+		r.result = nil
+		return err
+	}
+
+	row := make([]driver.Value, len(dest))
+	for i, v := range dest {
+		row[i] = copyValue(v)
+		r.result.Size += valueSize(v)
+	}
+	r.result.Rows = append(r.result.Rows, row)
+	if r.cfg.ResultCacheMaxBytes > 0 && r.result.Size > r.cfg.ResultCacheMaxBytes {
+		r.result = nil
+	}
+	return nil
+}
+
+// Close caches the results when the rows left are in the current page and there are no more pages, e.g. when
+// database/sql closes the rows after reading the single row of QueryRow
+func (r *cachingRows) Close() error {
+	if r.result != nil && r.fetchResults != nil && !r.fetchResults.GetHasMoreRows() {
+		dest := make([]driver.Value, len(r.rows.Columns()))
+		for r.result != nil && r.isNextRowInPage() {
+			if err := r.Next(dest); err != nil {
+				break
+			}
+		}
+		if r.result != nil {
+			r.store()
+		}
+	}
+	r.result = nil
+	return r.rows.Close()
+}
+
+func (r *cachingRows) GetArrowBatches(ctx context.Context) (dbsqlrows.ArrowBatchIterator, error) {
+	r.result = nil
+	return r.rows.GetArrowBatches(ctx)
+}
+
+// store caches the recorded result with the columns of the results
+func (r *cachingRows) store() {
+	names := r.rows.Columns()
+	r.result.Columns = make([]resultcache.Column, len(names))
+	for i, name := range names {
+		col := &r.result.Columns[i]
+		col.Name = name
+		col.DatabaseTypeName = r.rows.ColumnTypeDatabaseTypeName(i)
+		col.ScanType = r.rows.ColumnTypeScanType(i)
+		col.Length, col.HasLength = r.rows.ColumnTypeLength(i)
+		col.Precision, col.Scale, col.HasPrecisionScale = r.rows.ColumnTypePrecisionScale(i)
+	}
+	r.cfg.ResultCache.Set(r.ctx, r.key, r.result, r.cfg.ResultCacheTTL)
+}
+
+// cachedRows returns the rows of a cached result
+type cachedRows struct {
+	result *resultcache.Result
+	next   int
+}
+
+var _ driver.Rows = (*cachedRows)(nil)
+var _ driver.RowsColumnTypeScanType = (*cachedRows)(nil)
+var _ driver.RowsColumnTypeDatabaseTypeName = (*cachedRows)(nil)
+var _ driver.RowsColumnTypeNullable = (*cachedRows)(nil)
+var _ driver.RowsColumnTypeLength = (*cachedRows)(nil)
+var _ driver.RowsColumnTypePrecisionScale = (*cachedRows)(nil)
+var _ dbsqlrows.Rows = (*cachedRows)(nil)
+
+func (r *cachedRows) Columns() []string {
+	names := make([]string, len(r.result.Columns))
+	for i, col := range r.result.Columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+func (r *cachedRows) Close() error {
+	return nil
+}
+
+func (r *cachedRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.Rows) {
+		return io.EOF
+	}
+	// the values are copied, the caller may modify the byte slices it is given
+	for i, v := range r.result.Rows[r.next] {
+		dest[i] = copyValue(v)
+	}
+	r.next++
+	return nil
+}
+
+func (r *cachedRows) ColumnTypeScanType(index int) reflect.Type {
+	return r.result.Columns[index].ScanType
+}
+
+func (r *cachedRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.result.Columns[index].DatabaseTypeName
+}
+
+func (r *cachedRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return false, false
+}
+
+func (r *cachedRows) ColumnTypeLength(index int) (length int64, ok bool) {
+	col := r.result.Columns[index]
+	return col.Length, col.HasLength
+}
+
+func (r *cachedRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	col := r.result.Columns[index]
+	return col.Precision, col.Scale, col.HasPrecisionScale
+}
+
+func (r *cachedRows) GetArrowBatches(ctx context.Context) (dbsqlrows.ArrowBatchIterator, error) {
+	return nil, newDriverError(ErrCachedResultArrowBatches, nil)
+}
+
+func copyValue(v driver.Value) driver.Value {
+	if b, ok := v.([]byte); ok {
+		return append([]byte(nil), b...)
+	}
+	return v
+}
+
+// valueSize returns the estimated bytes used by a value of a cached result
+func valueSize(v driver.Value) int64 {
+	switch v := v.(type) {
+	case string:
+		return int64(len(v)) + 16
+	case []byte:
+		return int64(len(v)) + 24
+	default:
+		return 16
+	}
+}
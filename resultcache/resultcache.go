@@ -0,0 +1,110 @@
+// Package resultcache stores the results of queries on the client, so repeated queries, e.g. the ones of a
+// dashboard, are answered without running them on the warehouse. Set a Store with dbsql.WithResultCache:
+//
+//	connector, _ := dbsql.NewConnector(
+//		...
+//		dbsql.WithResultCache(resultcache.NewMemoryStore(64<<20), time.Minute, 8<<20),
+//	)
+//
+// Implement Store to keep the results elsewhere, e.g. in a cache shared by the instances of a service.
+package resultcache
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Column describes a column of a cached result
+type Column struct {
+	Name              string
+	DatabaseTypeName  string
+	ScanType          reflect.Type
+	Length            int64
+	HasLength         bool // Length is known
+	Precision         int64
+	Scale             int64
+	HasPrecisionScale bool // Precision and Scale are known
+}
+
+// Result is the result of a query, read to the end
+type Result struct {
+	Columns []Column
+	Rows    [][]driver.Value
+	Size    int64 // estimated bytes of the rows
+}
+
+// Store stores the results of queries by key. It is called concurrently by the connections of a connector,
+// and must not modify the results it is given or returns.
+type Store interface {
+	// Get returns the result stored with key, ok is false if there is none or it expired
+	Get(ctx context.Context, key string) (result *Result, ok bool)
+	// Set stores result with key for ttl
+	Set(ctx context.Context, key string, result *Result, ttl time.Duration)
+}
+
+type memoryEntry struct {
+	key     string
+	result  *Result
+	expires time.Time
+}
+
+type memoryStore struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List // of *memoryEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+// NewMemoryStore returns a Store keeping up to maxBytes of results in memory. The least recently used results
+// are evicted to make room for new ones, results larger than maxBytes are not stored.
+func NewMemoryStore(maxBytes int64) Store {
+	return &memoryStore{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (*Result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*memoryEntry)
+	if time.Now().After(entry.expires) {
+		s.remove(e)
+		return nil, false
+	}
+	s.lru.MoveToFront(e)
+	return entry.result, true
+}
+
+func (s *memoryStore) Set(ctx context.Context, key string, result *Result, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		s.remove(e)
+	}
+	if result.Size > s.maxBytes || ttl <= 0 {
+		return
+	}
+	for s.size+result.Size > s.maxBytes {
+		s.remove(s.lru.Back())
+	}
+	s.entries[key] = s.lru.PushFront(&memoryEntry{key: key, result: result, expires: time.Now().Add(ttl)})
+	s.size += result.Size
+}
+
+func (s *memoryStore) remove(e *list.Element) {
+	entry := s.lru.Remove(e).(*memoryEntry)
+	delete(s.entries, entry.key)
+	s.size -= entry.result.Size
+}
@@ -0,0 +1,62 @@
+package resultcache
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	result := func(size int64) *Result {
+		return &Result{Rows: [][]driver.Value{{size}}, Size: size}
+	}
+
+	t.Run("results expire after their ttl", func(t *testing.T) {
+		s := NewMemoryStore(100)
+		s.Set(ctx, "a", result(10), time.Hour)
+		s.Set(ctx, "b", result(10), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+
+		r, ok := s.Get(ctx, "a")
+		assert.True(t, ok)
+		assert.Equal(t, int64(10), r.Size)
+		_, ok = s.Get(ctx, "b")
+		assert.False(t, ok)
+		assert.Equal(t, int64(10), s.(*memoryStore).size)
+	})
+
+	t.Run("the least recently used results are evicted", func(t *testing.T) {
+		s := NewMemoryStore(100)
+		s.Set(ctx, "a", result(40), time.Hour)
+		s.Set(ctx, "b", result(40), time.Hour)
+		s.Get(ctx, "a")
+		s.Set(ctx, "c", result(40), time.Hour)
+
+		_, ok := s.Get(ctx, "a")
+		assert.True(t, ok)
+		_, ok = s.Get(ctx, "b")
+		assert.False(t, ok)
+		_, ok = s.Get(ctx, "c")
+		assert.True(t, ok)
+
+		s.Set(ctx, "d", result(101), time.Hour)
+		_, ok = s.Get(ctx, "d")
+		assert.False(t, ok, "the result is larger than the store")
+		assert.Equal(t, int64(80), s.(*memoryStore).size)
+	})
+
+	t.Run("a result replaces the one with the same key", func(t *testing.T) {
+		s := NewMemoryStore(100)
+		s.Set(ctx, "a", result(60), time.Hour)
+		s.Set(ctx, "a", result(70), time.Hour)
+
+		r, ok := s.Get(ctx, "a")
+		assert.True(t, ok)
+		assert.Equal(t, int64(70), r.Size)
+		assert.Equal(t, int64(70), s.(*memoryStore).size)
+	})
+}
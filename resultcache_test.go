@@ -0,0 +1,136 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/databricks/databricks-sql-go/resultcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	cases := map[string]string{
+		"select 1":                               "select 1",
+		"  select\n\t1 ;  ":                      "select 1",
+		"select  'a  b',\t\"c  d\" from  `t  u`": "select 'a  b', \"c  d\" from `t  u`",
+		"select 1 -- one\n  from t":              "select 1 -- one\n from t",
+		"select /* a  b */  1":                   "select /* a  b */ 1",
+		"select 'x'  'y'":                        "select 'x' 'y'",
+	}
+	for query, normalized := range cases {
+		assert.Equal(t, normalized, normalizeQuery(query), query)
+	}
+}
+
+func TestIsCacheableQuery(t *testing.T) {
+	assert.True(t, isCacheableQuery("select * from orders"))
+	assert.True(t, isCacheableQuery("with s as (select 1) select * from s"))
+	assert.True(t, isCacheableQuery("show tables"))
+	assert.False(t, isCacheableQuery("set ansi_mode = true"))
+	assert.False(t, isCacheableQuery("use catalog main"))
+	assert.False(t, isCacheableQuery("insert into t values (1)"))
+}
+
+func TestResultCache(t *testing.T) {
+	state := &callState{}
+	loadTestData(t, "OpenSessionSuccess.json", &state.openSessionResp)
+	loadTestData(t, "CloseSessionSuccess.json", &state.closeSessionResp)
+	loadTestData(t, "CloseOperationSuccess.json", &state.closeOperationResp)
+	loadTestData(t, "ExecuteStatement5.json", &state.executeStatementResp)
+
+	ts := getServer(state)
+	t.Cleanup(ts.Close)
+	r, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(r.Port())
+	require.NoError(t, err)
+
+	store := resultcache.NewMemoryStore(1 << 20)
+	newDB := func(httpPath, token string) *sql.DB {
+		connector, err := NewConnector(
+			WithServerHostname("localhost"),
+			WithPort(port),
+			WithHTTPPath(httpPath),
+			WithAccessToken(token),
+			WithResultCache(store, time.Minute, 0),
+		)
+		require.NoError(t, err)
+		db := sql.OpenDB(connector)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	connector, err := NewConnector(
+		WithServerHostname("localhost"),
+		WithPort(port),
+		WithHTTPPath("/sql/1.0/warehouses/abc"),
+		WithAccessToken("token"),
+		WithResultCache(store, time.Minute, 0),
+	)
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	maxCarat := func(ctx context.Context, query string) float64 {
+		var max float64
+		require.NoError(t, db.QueryRowContext(ctx, query).Scan(&max))
+		return max
+	}
+
+	assert.Equal(t, 5.01, maxCarat(context.Background(), "select max(carat) from diamonds"))
+	assert.Equal(t, 1, state.executeStatementCalls)
+
+	assert.Equal(t, 5.01, maxCarat(context.Background(), "select max(carat)\n  from diamonds;"))
+	assert.Equal(t, 1, state.executeStatementCalls, "the result is cached")
+
+	rows, err := db.Query("select max(carat) from diamonds")
+	require.NoError(t, err)
+	types, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	assert.Equal(t, "DOUBLE", types[0].DatabaseTypeName())
+	require.NoError(t, rows.Close())
+	assert.Equal(t, 1, state.executeStatementCalls)
+
+	assert.Equal(t, 5.01, maxCarat(driverctx.NewContextWithResultCacheBypass(context.Background()), "select max(carat) from diamonds"))
+	assert.Equal(t, 2, state.executeStatementCalls, "the cache is bypassed")
+
+	assert.Equal(t, 5.01, maxCarat(driverctx.NewContextWithCatalog(context.Background(), "other"), "select max(carat) from diamonds"))
+	assert.Equal(t, 3, state.executeStatementCalls, "the query runs in another catalog")
+
+	assert.Equal(t, 5.01, maxCarat(context.Background(), "select max(carat) from diamonds where cut = 'Ideal'"))
+	assert.Equal(t, 4, state.executeStatementCalls)
+
+	var max float64
+	require.NoError(t, newDB("/sql/1.0/warehouses/abc", "token").QueryRow("select max(carat) from diamonds").Scan(&max))
+	assert.Equal(t, 4, state.executeStatementCalls, "the connectors of a principal share the store")
+	require.NoError(t, newDB("/sql/1.0/warehouses/abc", "other").QueryRow("select max(carat) from diamonds").Scan(&max))
+	assert.Equal(t, 5, state.executeStatementCalls, "the query runs as another principal")
+	require.NoError(t, newDB("/sql/1.0/warehouses/def", "token").QueryRow("select max(carat) from diamonds").Scan(&max))
+	assert.Equal(t, 6, state.executeStatementCalls, "the query runs on another warehouse")
+
+	type tenantKey struct{}
+	connector, err = NewConnector(
+		WithServerHostname("localhost"),
+		WithPort(port),
+		WithHTTPPath("/sql/1.0/warehouses/abc"),
+		WithAccessToken("token"),
+		WithResultCache(store, time.Minute, 0),
+		WithStatementConf(func(ctx context.Context) map[string]string {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			return map[string]string{"tenant": tenant}
+		}),
+	)
+	require.NoError(t, err)
+	confDB := sql.OpenDB(connector)
+	defer confDB.Close()
+	for _, tenant := range []string{"acme", "acme", "globex"} {
+		ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+		require.NoError(t, confDB.QueryRowContext(ctx, "select max(carat) from diamonds").Scan(&max))
+	}
+	assert.Equal(t, 8, state.executeStatementCalls, "the results are cached by the conf of their statement")
+}
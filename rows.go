@@ -8,12 +8,18 @@ import (
 	"math"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/apache/thrift/lib/go/thrift"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
 	"github.com/databricks/databricks-sql-go/internal/cli_service"
 	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/cloudfetch"
+	"github.com/databricks/databricks-sql-go/internal/config"
 	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/databricks/databricks-sql-go/metrics"
+	dbsqlrows "github.com/databricks/databricks-sql-go/rows"
 	"github.com/pkg/errors"
 )
 
@@ -21,14 +27,33 @@ type rows struct {
 	client               cli_service.TCLIService
 	connId               string
 	correlationId        string
+	queryCtx             context.Context // context of the query, its values are sent with the requests of the rows
 	opHandle             *cli_service.TOperationHandle
 	pageSize             int64
 	location             *time.Location
+	ntzLocation          *time.Location // location of TIMESTAMP_NTZ values, nil uses location
+	ntzColumns           []bool         // TIMESTAMP_NTZ columns, loaded with the metadata
+	variants             []bool         // VARIANT columns, loaded with the metadata
+	spatialTypes         []string       // GEOMETRY and GEOGRAPHY columns, loaded with the metadata
 	fetchResults         *cli_service.TFetchResultsResp
 	fetchResultsMetadata *cli_service.TGetResultSetMetadataResp
 	nextRowIndex         int64
 	nextRowNumber        int64
 	closed               bool
+	cfg                  *config.Config
+	arrowPage            *arrowPage
+	downloader           *cloudfetch.Downloader
+	prefetcher           *prefetcher
+	prefetchedSize       int64        // budgeted memory of the current page
+	fetchedBytes         int64        // bytes of the result pages fetched, checked against the byte limit
+	scanBuffer           []byte       // holds the STRING values of the current row of zero-copy scans
+	timing               *queryTiming // reports the query to the slow query log when the rows are closed, nil if disabled
+	lifecycle            *lifecycle   // stops the background fetches when the connector is closed, nil if they aren't stopped
+	// opens the client of the rows once they prefetch, the requests of a thrift client can't run concurrently with
+	// the requests of the connection. nil keeps the client of the connection, which is then safe for concurrent use.
+	newClient func() (cli_service.TCLIService, error)
+	// serializes the client requests of the reader and the prefetcher
+	clientMx sync.Mutex
 }
 
 var _ driver.Rows = (*rows)(nil)
@@ -36,26 +61,41 @@ var _ driver.RowsColumnTypeScanType = (*rows)(nil)
 var _ driver.RowsColumnTypeDatabaseTypeName = (*rows)(nil)
 var _ driver.RowsColumnTypeNullable = (*rows)(nil)
 var _ driver.RowsColumnTypeLength = (*rows)(nil)
+var _ driver.RowsColumnTypePrecisionScale = (*rows)(nil)
+var _ dbsqlrows.Rows = (*rows)(nil)
 
 var errRowsFetchPriorToStart = "databricks: unable to fetch row page prior to start of results"
 var errRowsNoSchemaAvailable = "databricks: no schema in result set metadata response"
 var errRowsNoClient = "databricks: instance of Rows missing client"
 var errRowsNilRows = "databricks: nil Rows instance"
+var errRowsRefreshLink = "databricks: no result link for row %d in refreshed results"
 var errRowsParseValue = "databricks: unable to parse %s value '%s' from column %s"
 
 // NewRows generates a new rows object given the rows' fields.
 // NewRows will also parse directResults if it is available for some rows' fields.
-func NewRows(connID string, corrId string, client cli_service.TCLIService, opHandle *cli_service.TOperationHandle, pageSize int64, location *time.Location, directResults *cli_service.TSparkDirectResults) driver.Rows {
+func NewRows(connID string, corrId string, client cli_service.TCLIService, opHandle *cli_service.TOperationHandle, cfg *config.Config, directResults *cli_service.TSparkDirectResults) driver.Rows {
+	if cfg == nil {
+		cfg = config.WithDefaults()
+	}
+
 	r := &rows{
 		connId:        connID,
 		correlationId: corrId,
 		client:        client,
 		opHandle:      opHandle,
-		pageSize:      pageSize,
-		location:      location,
+		pageSize:      int64(cfg.MaxRows),
+		location:      cfg.Location,
+		ntzLocation:   cfg.NaiveTimestampLocation,
+		cfg:           cfg,
 	}
 
 	if directResults != nil {
+		if directResults.ResultSet != nil {
+			metrics.Counter(cfg.Metrics, metrics.RowsFetched, float64(getNRows(directResults.ResultSet.Results)))
+			r.fetchedBytes = resultBytes(directResults.ResultSet.Results)
+			rs := directResults.ResultSet.Results
+			r.adaptPageSize(estimatedPageSize(rs), getNRows(rs))
+		}
 		r.fetchResults = directResults.ResultSet
 		r.fetchResultsMetadata = directResults.ResultSetMetadata
 		if directResults.CloseOperation != nil {
@@ -66,6 +106,25 @@ func NewRows(connID string, corrId string, client cli_service.TCLIService, opHan
 	return r
 }
 
+// setRequestContext makes the requests of the rows of a query carry the values of ctx, the context of the query
+func setRequestContext(r driver.Rows, ctx context.Context) {
+	if rs, ok := r.(*rows); ok {
+		rs.queryCtx = ctx
+	}
+}
+
+// setPrefetchClient makes the rows of a query open their own client with newClient once they prefetch
+func setPrefetchClient(r driver.Rows, newClient func() (cli_service.TCLIService, error)) {
+	if rs, ok := r.(*rows); ok {
+		rs.newClient = newClient
+	}
+}
+
+// requestContext returns the context of the requests fetching the results and closing the query
+func (r *rows) requestContext() context.Context {
+	return newRequestContext(r.queryCtx, r.connId, r.correlationId)
+}
+
 // Columns returns the names of the columns. The number of
 // columns of the result is inferred from the length of the
 // slice. If a particular column name isn't known, an empty
@@ -97,6 +156,15 @@ func (r *rows) Columns() []string {
 
 // Close closes the rows iterator.
 func (r *rows) Close() error {
+	if r.prefetcher != nil {
+		r.prefetcher.stop()
+		r.prefetcher = nil
+	}
+	r.timing.report(nil)
+	r.timing = nil
+	r.arrowPage.release()
+	r.arrowPage = nil
+
 	if !r.closed {
 		err := isValidRows(r)
 		if err != nil {
@@ -106,9 +174,11 @@ func (r *rows) Close() error {
 		req := cli_service.TCloseOperationReq{
 			OperationHandle: r.opHandle,
 		}
-		ctx := driverctx.NewContextWithCorrelationId(driverctx.NewContextWithConnId(context.Background(), r.connId), r.correlationId)
+		ctx := r.requestContext()
 
+		r.clientMx.Lock()
 		_, err1 := r.client.CloseOperation(ctx, &req)
+		r.clientMx.Unlock()
 		if err1 != nil {
 			return err1
 		}
@@ -132,6 +202,18 @@ func (r *rows) Next(dest []driver.Value) error {
 		return err
 	}
 
+	if r.shouldPrefetch() {
+		err := r.startPrefetch()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.checkResultLimits()
+	if err != nil {
+		return err
+	}
+
 	// if the next row is not in the current result page
 	// fetch the containing page
 	if !r.isNextRowInPage() {
@@ -147,9 +229,30 @@ func (r *rows) Next(dest []driver.Value) error {
 		return err
 	}
 
+	if isArrowRowSet(r.fetchResults.Results) {
+		err = r.scanArrowRow(dest, metadata)
+		if err != nil {
+			return err
+		}
+		err = r.decodeComplexTypes(dest, metadata)
+		if err != nil {
+			return err
+		}
+		r.convertDateTimes(dest, metadata)
+
+		r.nextRowIndex++
+		r.nextRowNumber++
+
+		return nil
+	}
+
 	// populate the destination slice
 	for i := range dest {
-		val, err := value(r.fetchResults.Results.Columns[i], metadata.Schema.Columns[i], r.nextRowIndex, r.location)
+		location := r.location
+		if r.isTimestampNTZ(metadata, i) {
+			location = r.getNTZLocation()
+		}
+		val, err := value(r.fetchResults.Results.Columns[i], metadata.Schema.Columns[i], r.nextRowIndex, location)
 
 		if err != nil {
 			return err
@@ -157,6 +260,12 @@ func (r *rows) Next(dest []driver.Value) error {
 
 		dest[i] = val
 	}
+	err = r.decodeComplexTypes(dest, metadata)
+	if err != nil {
+		return err
+	}
+	r.convertDateTimes(dest, metadata)
+	r.reuseStrings(dest, metadata)
 
 	r.nextRowIndex++
 	r.nextRowNumber++
@@ -164,6 +273,227 @@ func (r *rows) Next(dest []driver.Value) error {
 	return nil
 }
 
+// scanArrowRow populates dest from the Arrow batches of the current page,
+// decoding the page on first use
+func (r *rows) scanArrowRow(dest []driver.Value, metadata *cli_service.TGetResultSetMetadataResp) error {
+	if r.arrowPage == nil || r.arrowPage.rowSet != r.fetchResults.Results {
+		r.arrowPage.release()
+		r.arrowPage = nil
+
+		ctx := r.requestContext()
+		page, err := r.decodeArrowPage(ctx, r.fetchResults.Results, metadata)
+		if err != nil {
+			return err
+		}
+		r.arrowPage = page
+	}
+
+	var columns []*cli_service.TColumnDesc
+	if metadata.Schema != nil {
+		columns = metadata.Schema.Columns
+	}
+
+	return r.arrowPage.scanRow(dest, r.nextRowIndex, columns, r.location, r.getNTZLocation(), r.zeroCopy())
+}
+
+// isTimestampNTZ returns true if the column at index holds TIMESTAMP_NTZ values
+func (r *rows) isTimestampNTZ(metadata *cli_service.TGetResultSetMetadataResp, index int) bool {
+	if r.ntzColumns == nil {
+		r.ntzColumns = timestampNTZColumns(metadata)
+	}
+	return index < len(r.ntzColumns) && r.ntzColumns[index]
+}
+
+// isVariant returns true if the column at index holds VARIANT values
+func (r *rows) isVariant(metadata *cli_service.TGetResultSetMetadataResp, index int) bool {
+	if r.variants == nil {
+		r.variants = variantColumns(metadata)
+	}
+	return index < len(r.variants) && r.variants[index]
+}
+
+// spatialType returns GEOMETRY or GEOGRAPHY if the column at index holds values of these types, an empty string
+// otherwise
+func (r *rows) spatialType(metadata *cli_service.TGetResultSetMetadataResp, index int) string {
+	if r.spatialTypes == nil {
+		r.spatialTypes = spatialTypes(metadata)
+	}
+	if index < len(r.spatialTypes) {
+		return r.spatialTypes[index]
+	}
+	return ""
+}
+
+// getNTZLocation returns the location in which TIMESTAMP_NTZ values are materialized
+func (r *rows) getNTZLocation() *time.Location {
+	if r.ntzLocation != nil {
+		return r.ntzLocation
+	}
+	return r.location
+}
+
+// decodeComplexTypes decodes the ARRAY, MAP, STRUCT and VARIANT values of dest when structured decoding is enabled
+func (r *rows) decodeComplexTypes(dest []driver.Value, metadata *cli_service.TGetResultSetMetadataResp) error {
+	if r.cfg == nil || !r.cfg.DecodeComplexTypes {
+		return nil
+	}
+	if r.variants == nil {
+		r.variants = variantColumns(metadata)
+	}
+	return decodeComplexValues(dest, metadata.GetSchema().GetColumns(), r.variants)
+}
+
+// zeroCopy returns true if the STRING and BINARY values are returned as []byte views valid until the next row
+func (r *rows) zeroCopy() bool {
+	return r.cfg != nil && r.cfg.ZeroCopyScan
+}
+
+// reuseStrings replaces the string values of the STRING columns of dest with []byte copies in the scan buffer of
+// the rows, which is reused for each row of a zero-copy scan
+func (r *rows) reuseStrings(dest []driver.Value, metadata *cli_service.TGetResultSetMetadataResp) {
+	if !r.zeroCopy() {
+		return
+	}
+	columns := metadata.GetSchema().GetColumns()
+	r.scanBuffer = r.scanBuffer[:0]
+	for i := range dest {
+		s, ok := dest[i].(string)
+		if !ok || i >= len(columns) || !isStringColumn(columns[i]) {
+			continue
+		}
+		start := len(r.scanBuffer)
+		r.scanBuffer = append(r.scanBuffer, s...)
+		dest[i] = r.scanBuffer[start:len(r.scanBuffer):len(r.scanBuffer)]
+	}
+}
+
+// convertDateTimes converts the time.Time values of the DATE and TIMESTAMP columns of dest to the Go values of the
+// timestamp and date conversions of the config
+func (r *rows) convertDateTimes(dest []driver.Value, metadata *cli_service.TGetResultSetMetadataResp) {
+	if r.cfg == nil || !convertsDateTimes(r.cfg) {
+		return
+	}
+	columns := metadata.GetSchema().GetColumns()
+	for i := range dest {
+		t, ok := dest[i].(time.Time)
+		if !ok || i >= len(columns) {
+			continue
+		}
+		switch getDBTypeID(columns[i]) {
+		case cli_service.TTypeId_DATE_TYPE:
+			if r.cfg.DateConversion == config.DateAsCivil {
+				dest[i] = DateOf(t)
+			}
+		case cli_service.TTypeId_TIMESTAMP_TYPE:
+			dest[i] = convertTimestamp(t, r.isTimestampNTZ(metadata, i), r.ntzLocation, r.cfg.TimestampConversion)
+		}
+	}
+}
+
+// convertsDateTimes returns true when the DATE or TIMESTAMP values aren't returned as the default time.Time
+func convertsDateTimes(cfg *config.Config) bool {
+	return cfg.DateConversion == config.DateAsCivil ||
+		cfg.TimestampConversion == config.TimestampInUTC || cfg.TimestampConversion == config.TimestampAsMicros
+}
+
+// convertTimestamp converts a TIMESTAMP value, or the wall clock of a TIMESTAMP_NTZ value when ntz is set. The
+// wall clock of TIMESTAMP_NTZ values is in UTC, unless their location was set with ntzLocation.
+func convertTimestamp(t time.Time, ntz bool, ntzLocation *time.Location, conversion string) any {
+	if ntz && (ntzLocation == nil || conversion == config.TimestampAsMicros) {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+	switch conversion {
+	case config.TimestampInUTC:
+		if ntz {
+			return t
+		}
+		return t.UTC()
+	case config.TimestampAsMicros:
+		return t.UnixMicro()
+	default:
+		return t
+	}
+}
+
+// decodeArrowPage decodes the Arrow batches of rowSet, downloading them first for cloud fetch results
+func (r *rows) decodeArrowPage(ctx context.Context, rowSet *cli_service.TRowSet, metadata *cli_service.TGetResultSetMetadataResp) (*arrowPage, error) {
+	if len(rowSet.ResultLinks) > 0 {
+		return r.downloadArrowPage(ctx, rowSet, metadata.GetLz4Compressed())
+	}
+	return newArrowPage(rowSet, metadata.ArrowSchema, metadata.GetLz4Compressed())
+}
+
+// downloadArrowPage downloads and decodes the Arrow files of a cloud fetch result page.
+// When lz4Compressed is set each file is an LZ4 frame.
+func (r *rows) downloadArrowPage(ctx context.Context, rowSet *cli_service.TRowSet, lz4Compressed bool) (*arrowPage, error) {
+	files, err := r.getDownloader().Download(ctx, rowSet.ResultLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &arrowPage{rowSet: rowSet}
+	for _, file := range files {
+		records, err := readArrowRecords(decompress(file, lz4Compressed))
+		if err != nil {
+			page.release()
+			return nil, err
+		}
+		page.addRecords(records)
+	}
+
+	return page, nil
+}
+
+// getDownloader returns the cloud fetch downloader of the rows, creating it on first use
+func (r *rows) getDownloader() *cloudfetch.Downloader {
+	if r.downloader == nil {
+		cfg := r.cfg
+		if cfg == nil {
+			cfg = config.WithDefaults()
+		}
+		// interrupted downloads are retried like the requests
+		maxRetries := cfg.RetryMax
+		if maxRetries == 0 {
+			maxRetries = -1
+		}
+		r.downloader = cloudfetch.NewDownloader(cloudfetch.Config{
+			MaxDownloads:   cfg.MaxDownloadThreads,
+			BandwidthLimit: cfg.DownloadBandwidthLimit,
+			MaxRetries:     maxRetries,
+			RetryWaitMin:   cfg.RetryWaitMin,
+			RetryWaitMax:   cfg.RetryWaitMax,
+			HTTPClient:     client.CloudFetchClient(cfg),
+			Metrics:        cfg.Metrics,
+		}, r.refreshResultLink)
+	}
+	return r.downloader
+}
+
+// refreshResultLink fetches the results again starting at the first row of an
+// expired link to get a new link for the same rows
+func (r *rows) refreshResultLink(ctx context.Context, link *cli_service.TSparkArrowResultLink) (*cli_service.TSparkArrowResultLink, error) {
+	startRowOffset := link.StartRowOffset
+	req := cli_service.TFetchResultsReq{
+		OperationHandle: r.opHandle,
+		MaxRows:         r.pageSize,
+		Orientation:     cli_service.TFetchOrientation_FETCH_ABSOLUTE,
+		StartRowOffset:  &startRowOffset,
+	}
+	r.clientMx.Lock()
+	resp, err := r.client.FetchResults(ctx, &req)
+	r.clientMx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, newLink := range resp.GetResults().GetResultLinks() {
+		if newLink.StartRowOffset == startRowOffset {
+			return newLink, nil
+		}
+	}
+	return nil, errors.Errorf(errRowsRefreshLink, startRowOffset)
+}
+
 // ColumnTypeScanType returns column's native type
 func (r *rows) ColumnTypeScanType(index int) reflect.Type {
 	err := isValidRows(r)
@@ -178,6 +508,37 @@ func (r *rows) ColumnTypeScanType(index int) reflect.Type {
 		return nil
 	}
 
+	if r.cfg != nil && r.cfg.DecodeComplexTypes {
+		switch getDBTypeID(column) {
+		case cli_service.TTypeId_ARRAY_TYPE:
+			return scanTypeArray
+		case cli_service.TTypeId_MAP_TYPE:
+			return scanTypeMap
+		case cli_service.TTypeId_STRUCT_TYPE:
+			return scanTypeStruct
+		}
+		if metadata, err := r.getResultMetadata(); err == nil && r.isVariant(metadata, index) {
+			return scanTypeVariant
+		}
+	}
+
+	if r.zeroCopy() && isStringColumn(column) {
+		return scanTypeRawBytes
+	}
+
+	if r.cfg != nil {
+		switch getDBTypeID(column) {
+		case cli_service.TTypeId_DATE_TYPE:
+			if r.cfg.DateConversion == config.DateAsCivil {
+				return scanTypeDate
+			}
+		case cli_service.TTypeId_TIMESTAMP_TYPE:
+			if r.cfg.TimestampConversion == config.TimestampAsMicros {
+				return scanTypeInt64
+			}
+		}
+	}
+
 	scanType := getScanType(column)
 	return scanType
 }
@@ -197,6 +558,23 @@ func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
 	}
 
 	dbtype := getDBTypeName(column)
+	switch dbtype {
+	case "TIMESTAMP":
+		metadata, err := r.getResultMetadata()
+		if err == nil && r.isTimestampNTZ(metadata, index) {
+			dbtype = "TIMESTAMP_NTZ"
+		}
+	case "STRING", "BINARY":
+		metadata, err := r.getResultMetadata()
+		if err != nil {
+			break
+		}
+		if r.isVariant(metadata, index) {
+			dbtype = "VARIANT"
+		} else if spatialType := r.spatialType(metadata, index); spatialType != "" {
+			dbtype = spatialType
+		}
+	}
 
 	return dbtype
 }
@@ -228,6 +606,24 @@ func (r *rows) ColumnTypeLength(index int) (length int64, ok bool) {
 	}
 }
 
+// ColumnTypePrecisionScale returns the precision and scale of DECIMAL columns,
+// ok is false for other columns
+func (r *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	columnInfo, err := r.getColumnMetadataByIndex(index)
+	if err != nil || getDBTypeID(columnInfo) != cli_service.TTypeId_DECIMAL_TYPE {
+		return 0, 0, false
+	}
+
+	qualifiers := columnInfo.TypeDesc.Types[0].PrimitiveEntry.GetTypeQualifiers().GetQualifiers()
+	p, hasPrecision := qualifiers[cli_service.PRECISION]
+	s, hasScale := qualifiers[cli_service.SCALE]
+	if !hasPrecision || !hasScale {
+		return 0, 0, false
+	}
+
+	return int64(p.GetI32Value()), int64(s.GetI32Value()), true
+}
+
 var (
 	scanTypeNull     = reflect.TypeOf(nil)
 	scanTypeBoolean  = reflect.TypeOf(true)
@@ -239,7 +635,15 @@ var (
 	scanTypeInt64    = reflect.TypeOf(int64(0))
 	scanTypeString   = reflect.TypeOf("")
 	scanTypeDateTime = reflect.TypeOf(time.Time{})
+	scanTypeDate     = reflect.TypeOf(Date{})
 	scanTypeRawBytes = reflect.TypeOf(sql.RawBytes{})
+	scanTypeBytes    = reflect.TypeOf([]byte{})
+	scanTypeDecimal  = reflect.TypeOf(Decimal{})
+	scanTypeInterval = reflect.TypeOf(Interval{})
+	scanTypeArray    = reflect.TypeOf([]any{})
+	scanTypeMap      = reflect.TypeOf(map[any]any{})
+	scanTypeStruct   = reflect.TypeOf(map[string]any{})
+	scanTypeVariant  = reflect.TypeOf((*any)(nil)).Elem()
 	scanTypeUnknown  = reflect.TypeOf(new(any))
 )
 
@@ -272,13 +676,17 @@ func getScanType(column *cli_service.TColumnDesc) reflect.Type {
 		return scanTypeString
 	case cli_service.TTypeId_DATE_TYPE, cli_service.TTypeId_TIMESTAMP_TYPE:
 		return scanTypeDateTime
-	case cli_service.TTypeId_DECIMAL_TYPE, cli_service.TTypeId_BINARY_TYPE, cli_service.TTypeId_ARRAY_TYPE,
+	case cli_service.TTypeId_DECIMAL_TYPE:
+		return scanTypeDecimal
+	case cli_service.TTypeId_BINARY_TYPE:
+		return scanTypeBytes
+	case cli_service.TTypeId_ARRAY_TYPE,
 		cli_service.TTypeId_STRUCT_TYPE, cli_service.TTypeId_MAP_TYPE, cli_service.TTypeId_UNION_TYPE:
 		return scanTypeRawBytes
 	case cli_service.TTypeId_USER_DEFINED_TYPE:
 		return scanTypeUnknown
 	case cli_service.TTypeId_INTERVAL_DAY_TIME_TYPE, cli_service.TTypeId_INTERVAL_YEAR_MONTH_TYPE:
-		return scanTypeString
+		return scanTypeInterval
 	default:
 		return scanTypeUnknown
 	}
@@ -296,6 +704,16 @@ func getDBTypeID(column *cli_service.TColumnDesc) cli_service.TTypeId {
 	return entry.Type
 }
 
+// isStringColumn returns true for the STRING, VARCHAR and CHAR columns
+func isStringColumn(column *cli_service.TColumnDesc) bool {
+	switch getDBTypeID(column) {
+	case cli_service.TTypeId_STRING_TYPE, cli_service.TTypeId_VARCHAR_TYPE, cli_service.TTypeId_CHAR_TYPE:
+		return true
+	default:
+		return false
+	}
+}
+
 // isValidRows checks that the row instance is not nil
 // and that it has a client
 func isValidRows(r *rows) error {
@@ -359,9 +777,11 @@ func (r *rows) getResultMetadata() (*cli_service.TGetResultSetMetadataResp, erro
 		req := cli_service.TGetResultSetMetadataReq{
 			OperationHandle: r.opHandle,
 		}
-		ctx := driverctx.NewContextWithCorrelationId(driverctx.NewContextWithConnId(context.Background(), r.connId), r.correlationId)
+		ctx := r.requestContext()
 
+		r.clientMx.Lock()
 		resp, err := r.client.GetResultSetMetadata(ctx, &req)
+		r.clientMx.Unlock()
 		if err != nil {
 			return nil, err
 		}
@@ -402,19 +822,38 @@ func (r *rows) fetchResultPage() error {
 			return errors.Errorf("unhandled fetch result orientation: %s", direction)
 		}
 
-		req := cli_service.TFetchResultsReq{
-			OperationHandle: r.opHandle,
-			MaxRows:         r.pageSize,
-			Orientation:     direction,
+		if direction == cli_service.TFetchOrientation_FETCH_NEXT && r.prefetcher != nil {
+			next, ok := r.prefetcher.next()
+			if !ok {
+				return io.EOF
+			}
+			if next.err != nil {
+				return next.err
+			}
+			r.setPage(next.resp, next.page, next.size)
+			r.fetchedBytes += resultBytes(next.resp.GetResults())
+			if err := r.checkResultLimits(); err != nil {
+				return err
+			}
+			continue
 		}
-		ctx := driverctx.NewContextWithCorrelationId(driverctx.NewContextWithConnId(context.Background(), r.connId), r.correlationId)
+
+		ctx := r.requestContext()
 		log.Debug().Msgf("fetching next batch of %d rows", r.pageSize)
-		fetchResult, err := r.client.FetchResults(ctx, &req)
+		fetchResult, err := r.fetchPage(ctx, direction)
 		if err != nil {
 			return err
 		}
 
-		r.fetchResults = fetchResult
+		r.setPage(fetchResult, nil, 0)
+		if direction == cli_service.TFetchOrientation_FETCH_NEXT {
+			rs := fetchResult.GetResults()
+			r.adaptPageSize(estimatedPageSize(rs), getNRows(rs))
+			r.fetchedBytes += resultBytes(rs)
+			if err := r.checkResultLimits(); err != nil {
+				return err
+			}
+		}
 	}
 
 	// don't assume the next row is the first row in the page
@@ -423,6 +862,127 @@ func (r *rows) fetchResultPage() error {
 	return nil
 }
 
+// fetchPage requests the result page in the given direction from the server
+func (r *rows) fetchPage(ctx context.Context, direction cli_service.TFetchOrientation) (*cli_service.TFetchResultsResp, error) {
+	r.clientMx.Lock()
+	defer r.clientMx.Unlock()
+
+	req := cli_service.TFetchResultsReq{
+		OperationHandle: r.opHandle,
+		MaxRows:         r.pageSize,
+		Orientation:     direction,
+	}
+	if r.cfg != nil && r.cfg.MaxPageBytes > 0 {
+		req.MaxBytes = thrift.Int64Ptr(r.cfg.MaxPageBytes)
+	}
+	start := time.Now()
+	resp, err := r.client.FetchResults(ctx, &req)
+	r.timing.addFetch(time.Since(start))
+	if err == nil {
+		metrics.Duration(r.metricsCollector(), metrics.FetchDuration, start)
+		metrics.Counter(r.metricsCollector(), metrics.RowsFetched, float64(getNRows(resp.GetResults())))
+	}
+	return resp, err
+}
+
+func (r *rows) metricsCollector() metrics.Collector {
+	if r.cfg == nil {
+		return nil
+	}
+	return r.cfg.Metrics
+}
+
+// setPage makes fetchResult the current page, page is its decoded Arrow batches if
+// already available and size the memory budgeted for it by the prefetcher
+func (r *rows) setPage(fetchResult *cli_service.TFetchResultsResp, page *arrowPage, size int64) {
+	if r.prefetcher != nil {
+		r.prefetcher.budget.release(r.prefetchedSize)
+	}
+	r.prefetchedSize = size
+
+	r.arrowPage.release()
+	r.arrowPage = page
+	r.fetchResults = fetchResult
+}
+
+// adaptPageSize sets the rows requested per page so the pages of rows as wide as the rows of a page
+// of nRows rows and size bytes stay under the page byte limit, without exceeding the max rows per page
+func (r *rows) adaptPageSize(size, nRows int64) {
+	if r.cfg == nil || r.cfg.MaxPageBytes <= 0 || size <= 0 || nRows <= 0 {
+		return
+	}
+	rowSize := (size + nRows - 1) / nRows
+	pageSize := r.cfg.MaxPageBytes / rowSize
+	if pageSize < 1 {
+		pageSize = 1
+	}
+	if maxRows := int64(r.cfg.MaxRows); maxRows > 0 && pageSize > maxRows {
+		pageSize = maxRows
+	}
+
+	r.clientMx.Lock()
+	r.pageSize = pageSize
+	r.clientMx.Unlock()
+}
+
+// estimatedPageSize estimates the memory used by a result page before its Arrow batches are decoded
+func estimatedPageSize(rs *cli_service.TRowSet) int64 {
+	if size := pageSize(rs, nil); size > 0 {
+		return size
+	}
+	return resultBytes(rs)
+}
+
+// checkResultLimits returns a ResultTruncatedError when the results fetched exceed the byte limit, or the rows
+// read reached the row limit and there are more rows
+func (r *rows) checkResultLimits() error {
+	if r.cfg == nil {
+		return nil
+	}
+	truncatedErr := &dbsqlerr.ResultTruncatedError{Rows: r.nextRowNumber}
+	if r.opHandle != nil {
+		truncatedErr.QueryId = client.SprintGuid(r.opHandle.GetOperationId().GetGUID())
+	}
+	if r.cfg.MaxBytesPerQuery > 0 && r.fetchedBytes > r.cfg.MaxBytesPerQuery {
+		truncatedErr.MaxBytesPerQuery = r.cfg.MaxBytesPerQuery
+		return truncatedErr
+	}
+	if r.cfg.MaxRowsTotal > 0 && r.nextRowNumber >= r.cfg.MaxRowsTotal &&
+		(r.isNextRowInPage() || r.fetchResults == nil || r.fetchResults.GetHasMoreRows()) {
+		truncatedErr.MaxRowsTotal = r.cfg.MaxRowsTotal
+		return truncatedErr
+	}
+	return nil
+}
+
+// resultBytes returns the size of a result page as sent by the server, the size of its Arrow
+// batches or cloud fetch files, or the estimated size of its columns
+func resultBytes(rs *cli_service.TRowSet) int64 {
+	if rs == nil {
+		return 0
+	}
+	var size int64
+	for _, batch := range rs.ArrowBatches {
+		size += int64(len(batch.Batch))
+	}
+	for _, link := range rs.ResultLinks {
+		size += link.BytesNum
+	}
+	if size > 0 {
+		return size
+	}
+	return pageSize(rs, nil)
+}
+
+// shouldPrefetch returns true if prefetching is enabled and not started yet
+// and there are pages after the current one
+func (r *rows) shouldPrefetch() bool {
+	if r.prefetcher != nil || r.cfg == nil || r.cfg.MaxPrefetchPages <= 0 || r.opHandle == nil {
+		return false
+	}
+	return r.fetchResults == nil || r.fetchResults.GetHasMoreRows()
+}
+
 // getPageFetchDirection returns the cli_service.TFetchOrientation
 // necessary to fetch a result page containing the next row number.
 // Note: if the next row number is in the current page TFetchOrientation_FETCH_NEXT
@@ -520,6 +1080,16 @@ func getNRows(rs *cli_service.TRowSet) int64 {
 	if rs == nil {
 		return 0
 	}
+	if isArrowRowSet(rs) {
+		var n int64
+		for _, batch := range rs.ArrowBatches {
+			n += batch.RowCount
+		}
+		for _, link := range rs.ResultLinks {
+			n += link.RowCount
+		}
+		return n
+	}
 	for _, col := range rs.Columns {
 		if col.BoolVal != nil {
 			return int64(len(col.BoolVal.Values))
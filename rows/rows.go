@@ -0,0 +1,62 @@
+// Package rows gives access to query results as Apache Arrow record batches, without converting
+// them to driver values, e.g. to feed Parquet writers or data frames.
+//
+// database/sql doesn't expose the driver's rows, so the query has to be run on the driver connection:
+//
+//	conn, _ := db.Conn(ctx)
+//	defer conn.Close()
+//
+//	err := conn.Raw(func(driverConn any) error {
+//		r, err := driverConn.(driver.QueryerContext).QueryContext(ctx, "select * from samples.nyctaxi.trips", nil)
+//		if err != nil {
+//			return err
+//		}
+//		defer r.Close()
+//
+//		batches, err := r.(dbsqlrows.Rows).GetArrowBatches(ctx)
+//		if err != nil {
+//			return err
+//		}
+//		defer batches.Close()
+//
+//		for batches.HasNext() {
+//			record, err := batches.Next()
+//			if err != nil {
+//				return err
+//			}
+//			// use record
+//			record.Release()
+//		}
+//		return nil
+//	})
+package rows
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/apache/arrow/go/v12/arrow"
+)
+
+// Rows is implemented by the rows returned by the driver.
+type Rows interface {
+	driver.Rows
+	// GetArrowBatches returns an iterator over the Arrow record batches of the results that
+	// have not been read yet. Rows must not be read with Next while the iterator is used.
+	// Results are only available as Arrow batches when Arrow batches are enabled, which is the default.
+	GetArrowBatches(ctx context.Context) (ArrowBatchIterator, error)
+}
+
+// ArrowBatchIterator iterates over the Arrow record batches of a result set, fetching
+// result pages as needed.
+type ArrowBatchIterator interface {
+	// Next returns the next record batch, or io.EOF when there are no more batches.
+	// The caller owns the record and must call Release on it when done.
+	Next() (arrow.Record, error)
+	// HasNext returns true if Next will return a record batch, or the error that occurred fetching it.
+	HasNext() bool
+	// Close releases the record batches fetched but not returned by Next.
+	Close()
+	// Schema returns the Arrow schema of the record batches.
+	Schema() (*arrow.Schema, error)
+}
@@ -12,11 +12,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/databricks/databricks-sql-go/metrics"
 
 	"github.com/databricks/databricks-sql-go/internal/cli_service"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRowsNextRowInPage(t *testing.T) {
@@ -359,6 +363,22 @@ func TestRowsFetchResultPageNoDirectResults(t *testing.T) {
 	}.validatePaging(t, rowSet, err, fetchResultsCount, getMetadataCount)
 }
 
+func TestRowsFetchResultPageMetrics(t *testing.T) {
+	t.Parallel()
+
+	var getMetadataCount, fetchResultsCount int
+	collector := newTestCollector()
+	cfg := config.WithDefaults()
+	cfg.Metrics = collector
+	rowSet := &rows{client: getRowsTestSimpleClient(&getMetadataCount, &fetchResultsCount), cfg: cfg}
+
+	rowSet.nextRowNumber = 6
+	assert.NoError(t, rowSet.fetchResultPage())
+	assert.Equal(t, 2, fetchResultsCount)
+	assert.Len(t, collector.histograms[metrics.FetchDuration], 2)
+	assert.Equal(t, float64(10), collector.counters[metrics.RowsFetched])
+}
+
 func TestRowsFetchResultPageWithDirectResults(t *testing.T) {
 	t.Parallel()
 
@@ -710,14 +730,14 @@ func TestGetScanType(t *testing.T) {
 		scanTypeFloat64,
 		scanTypeString,
 		scanTypeDateTime,
+		scanTypeBytes,
 		scanTypeRawBytes,
 		scanTypeRawBytes,
 		scanTypeRawBytes,
-		scanTypeRawBytes,
-		scanTypeRawBytes,
+		scanTypeDecimal,
 		scanTypeDateTime,
-		scanTypeString,
-		scanTypeString,
+		scanTypeInterval,
+		scanTypeInterval,
 	}
 
 	assert.Equal(t, len(expectedScanTypes), len(cols))
@@ -817,6 +837,30 @@ func TestColumnTypeLength(t *testing.T) {
 	}
 }
 
+func TestColumnTypePrecisionScale(t *testing.T) {
+	var getMetadataCount, fetchResultsCount int
+
+	var rowSet *rows
+	_, _, ok := rowSet.ColumnTypePrecisionScale(0)
+	assert.False(t, ok)
+
+	rowSet = &rows{}
+	client := getRowsTestSimpleClient(&getMetadataCount, &fetchResultsCount)
+	rowSet.client = client
+
+	colNames := rowSet.Columns()
+	for i := range colNames {
+		precision, scale, ok := rowSet.ColumnTypePrecisionScale(i)
+		if colNames[i] == "decimal_col" {
+			assert.Equal(t, int64(10), precision)
+			assert.Equal(t, int64(2), scale)
+			assert.True(t, ok)
+		} else {
+			assert.False(t, ok)
+		}
+	}
+}
+
 func TestColumnTypeDatabaseTypeName(t *testing.T) {
 	var getMetadataCount, fetchResultsCount int
 
@@ -838,14 +882,14 @@ func TestColumnTypeDatabaseTypeName(t *testing.T) {
 		scanTypeFloat64,
 		scanTypeString,
 		scanTypeDateTime,
+		scanTypeBytes,
 		scanTypeRawBytes,
 		scanTypeRawBytes,
 		scanTypeRawBytes,
-		scanTypeRawBytes,
-		scanTypeRawBytes,
+		scanTypeDecimal,
 		scanTypeDateTime,
-		scanTypeString,
-		scanTypeString,
+		scanTypeInterval,
+		scanTypeInterval,
 	}
 
 	assert.Equal(t, len(expectedScanTypes), len(cols))
@@ -869,7 +913,7 @@ func TestRowsCloseOptimization(t *testing.T) {
 		},
 	}
 
-	rowSet := NewRows("", "", client, &cli_service.TOperationHandle{}, 1, nil, nil)
+	rowSet := NewRows("", "", client, &cli_service.TOperationHandle{}, &config.Config{UserConfig: config.UserConfig{MaxRows: 1}}, nil)
 
 	// rowSet has no direct results calling Close should result in call to client to close operation
 	err := rowSet.Close()
@@ -878,7 +922,7 @@ func TestRowsCloseOptimization(t *testing.T) {
 
 	// rowSet has direct results, but operation was not closed so it should call client to close operation
 	closeCount = 0
-	rowSet = NewRows("", "", client, &cli_service.TOperationHandle{}, 1, nil, &cli_service.TSparkDirectResults{})
+	rowSet = NewRows("", "", client, &cli_service.TOperationHandle{}, &config.Config{UserConfig: config.UserConfig{MaxRows: 1}}, &cli_service.TSparkDirectResults{})
 	err = rowSet.Close()
 	assert.Nil(t, err, "rows.Close should not throw an error")
 	assert.Equal(t, 1, closeCount)
@@ -886,7 +930,7 @@ func TestRowsCloseOptimization(t *testing.T) {
 	// rowSet has direct results which include a close operation response.  rowSet should be marked as closed
 	// and calling Close should not call into the client.
 	closeCount = 0
-	rowSet = NewRows("", "", client, &cli_service.TOperationHandle{}, 1, nil, &cli_service.TSparkDirectResults{CloseOperation: &cli_service.TCloseOperationResp{}})
+	rowSet = NewRows("", "", client, &cli_service.TOperationHandle{}, &config.Config{UserConfig: config.UserConfig{MaxRows: 1}}, &cli_service.TSparkDirectResults{CloseOperation: &cli_service.TCloseOperationResp{}})
 	err = rowSet.Close()
 	assert.Nil(t, err, "rows.Close should not throw an error")
 	assert.Equal(t, 0, closeCount)
@@ -1088,6 +1132,12 @@ func getRowsTestSimpleClient(getMetadataCount, fetchResultsCount *int) cli_servi
 							{
 								PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{
 									Type: cli_service.TTypeId_DECIMAL_TYPE,
+									TypeQualifiers: &cli_service.TTypeQualifiers{
+										Qualifiers: map[string]*cli_service.TTypeQualifierValue{
+											cli_service.PRECISION: {I32Value: thrift.Int32Ptr(10)},
+											cli_service.SCALE:     {I32Value: thrift.Int32Ptr(2)},
+										},
+									},
 								},
 							},
 						},
@@ -1306,3 +1356,43 @@ func getRowsTestSimpleClient(getMetadataCount, fetchResultsCount *int) cli_servi
 
 	return client
 }
+
+func TestZeroCopyScan(t *testing.T) {
+	column := func(name string, typeID cli_service.TTypeId) *cli_service.TColumnDesc {
+		return &cli_service.TColumnDesc{
+			ColumnName: name,
+			TypeDesc: &cli_service.TTypeDesc{
+				Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: typeID}}},
+			},
+		}
+	}
+	cfg := config.WithDefaults()
+	cfg.ZeroCopyScan = true
+	r := &rows{
+		client: &client.TestClient{},
+		cfg:    cfg,
+		fetchResults: &cli_service.TFetchResultsResp{Results: &cli_service.TRowSet{Columns: []*cli_service.TColumn{
+			{StringVal: &cli_service.TStringColumn{Values: []string{"alice", "bob"}}},
+			{StringVal: &cli_service.TStringColumn{Values: []string{"paris", ""}, Nulls: []byte{2}}},
+			{StringVal: &cli_service.TStringColumn{Values: []string{"2021-07-01", "2021-07-02"}}},
+		}}},
+		fetchResultsMetadata: &cli_service.TGetResultSetMetadataResp{
+			Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{
+				column("name", cli_service.TTypeId_STRING_TYPE),
+				column("city", cli_service.TTypeId_VARCHAR_TYPE),
+				column("day", cli_service.TTypeId_DATE_TYPE),
+			}},
+		},
+	}
+	assert.Equal(t, scanTypeRawBytes, r.ColumnTypeScanType(0))
+	assert.Equal(t, scanTypeDateTime, r.ColumnTypeScanType(2))
+
+	row := make([]driver.Value, 3)
+	require.NoError(t, r.Next(row))
+	assert.Equal(t, []driver.Value{[]byte("alice"), []byte("paris"), time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)}, row)
+	buffer := &r.scanBuffer[0]
+
+	require.NoError(t, r.Next(row))
+	assert.Equal(t, []driver.Value{[]byte("bob"), nil, time.Date(2021, 7, 2, 0, 0, 0, 0, time.UTC)}, row)
+	assert.Same(t, buffer, &r.scanBuffer[0], "the buffer is reused")
+}
@@ -0,0 +1,324 @@
+// Package dbsqlscan scans query results into structs, matching the columns of the rows to the fields by name.
+// Values are converted according to their Databricks type: DECIMAL columns can be scanned into dbsql.Decimal
+// fields without losing precision, INTERVAL DAY TO SECOND columns into time.Duration fields, and ARRAY, MAP and
+// STRUCT columns into slice, map and struct fields, whichever complex type scanner the connector uses.
+//
+//	type Order struct {
+//		ID       int64         `db:"id"`
+//		Amount   dbsql.Decimal `db:"amount"`
+//		Tags     []string      `db:"tags"`
+//		Shipping *Address      `db:"shipping"`
+//		Created  time.Time     `db:"created_at"`
+//	}
+//
+//	var orders []Order
+//	err := dbsqlscan.Select(ctx, db, &orders, "select * from orders where region = ?", region)
+//
+// Large results can be scanned a row at a time with a Scanner instead of being loaded at once:
+//
+//	rows, err := db.QueryContext(ctx, "select * from orders")
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//
+//	scanner, err := dbsqlscan.NewScanner(rows)
+//	if err != nil {
+//		return err
+//	}
+//	for rows.Next() {
+//		var order Order
+//		if err := scanner.Scan(&order); err != nil {
+//			return err
+//		}
+//		// use order
+//	}
+//	return rows.Err()
+//
+// Fields are matched by their db tag, or else by their name ignoring case and underscores, so CreatedAt matches
+// the created_at column. Unexported fields and fields tagged db:"-" are skipped, the fields of embedded structs are
+// matched like the fields of the struct. Each column must match a field. Nullable columns need pointer fields,
+// or field types scanning NULL like sql.NullString.
+package dbsqlscan
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"time"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	"github.com/pkg/errors"
+)
+
+// Queryer runs queries, it is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Get runs query and scans its first row into dest, a pointer to a struct, or to a value when the query returns
+// a single column. It returns sql.ErrNoRows when the query returns no rows.
+func Get(ctx context.Context, q Queryer, dest any, query string, args ...any) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	scanner, err := NewScanner(rows)
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := scanner.Scan(dest); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// Select runs query and scans its rows into dest, see ScanAll
+func Select(ctx context.Context, q Queryer, dest any, query string, args ...any) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return ScanAll(rows, dest)
+}
+
+// ScanAll scans the remaining rows into dest and closes rows. dest is a pointer to a slice of structs or of
+// pointers to structs, or a slice of values when the rows have a single column.
+func ScanAll(rows *sql.Rows, dest any) error {
+	defer rows.Close()
+
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Pointer || slice.IsNil() || slice.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("databricks: scan destination must be a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+	structPtr := elemType.Kind() == reflect.Pointer && isRowStruct(elemType.Elem())
+	if structPtr {
+		elemType = elemType.Elem()
+	}
+
+	scanner, err := NewScanner(rows)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		if err := scanner.Scan(elem.Interface()); err != nil {
+			return err
+		}
+		if structPtr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// Scanner scans the rows of a result set into structs. The fields matching the columns are looked up once per
+// struct type. A Scanner is not safe for concurrent use, like the rows it scans.
+type Scanner struct {
+	rows    *sql.Rows
+	columns []string
+	types   []string                 // database type names of the columns, e.g. DECIMAL or ARRAY
+	fields  map[reflect.Type][][]int // index of the field of each column by struct type
+}
+
+// NewScanner returns a scanner of the rows
+func NewScanner(rows *sql.Rows) (*Scanner, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	s := &Scanner{
+		rows:    rows,
+		columns: make([]string, len(columnTypes)),
+		types:   make([]string, len(columnTypes)),
+		fields:  map[reflect.Type][][]int{},
+	}
+	for i, columnType := range columnTypes {
+		s.columns[i] = columnType.Name()
+		s.types[i] = columnType.DatabaseTypeName()
+	}
+	return s, nil
+}
+
+// Scan scans the current row into dest, a pointer to a struct, or to a value when the rows have a single column
+func (s *Scanner) Scan(dest any) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.Errorf("databricks: scan destination must be a non-nil pointer, got %T", dest)
+	}
+	v := rv.Elem()
+
+	if !isRowStruct(v.Type()) {
+		if len(s.columns) != 1 {
+			return errors.Errorf("databricks: %d columns can't be scanned into %T, use a struct", len(s.columns), dest)
+		}
+		return s.rows.Scan(s.target(v, 0))
+	}
+
+	fields, err := s.fieldsOf(v.Type())
+	if err != nil {
+		return err
+	}
+	targets := make([]any, len(fields))
+	for i, index := range fields {
+		targets[i] = s.target(fieldByIndex(v, index), i)
+	}
+	return s.rows.Scan(targets...)
+}
+
+// fieldsOf returns the index of the field of t matching each column
+func (s *Scanner) fieldsOf(t reflect.Type) ([][]int, error) {
+	if fields, ok := s.fields[t]; ok {
+		return fields, nil
+	}
+
+	byName := map[string][]int{}
+	collectFields(t, nil, byName)
+	fields := make([][]int, len(s.columns))
+	for i, column := range s.columns {
+		index, ok := byName[normalizeName(column)]
+		if !ok {
+			return nil, errors.Errorf("databricks: no field of %s matches column %s", t, column)
+		}
+		fields[i] = index
+	}
+	s.fields[t] = fields
+	return fields, nil
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// target returns the destination of the value of a column passed to rows.Scan for the field v
+func (s *Scanner) target(v reflect.Value, column int) any {
+	ptr := v.Addr().Interface()
+	if _, ok := ptr.(sql.Scanner); ok {
+		return ptr
+	}
+
+	t := v.Type()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch s.types[column] {
+	case "ARRAY", "MAP", "STRUCT":
+		// complex values are sent as JSON strings, or decoded to []any and maps by the structured scanner
+		if t.Kind() == reflect.Map || t.Kind() == reflect.Struct || (t.Kind() == reflect.Slice && t != bytesType) {
+			return dbsql.ScanComplex(ptr)
+		}
+	case "INTERVAL_DAY_TIME":
+		if t == durationType {
+			return durationScanner{dest: v}
+		}
+	}
+	return ptr
+}
+
+// durationScanner scans a day-time interval into a time.Duration or *time.Duration, NULL is scanned as zero or nil
+type durationScanner struct {
+	dest reflect.Value
+}
+
+func (s durationScanner) Scan(src any) error {
+	if src == nil {
+		s.dest.Set(reflect.Zero(s.dest.Type()))
+		return nil
+	}
+	var interval dbsql.Interval
+	if err := interval.Scan(src); err != nil {
+		return err
+	}
+	d := reflect.ValueOf(interval.Duration)
+	if s.dest.Kind() == reflect.Pointer {
+		p := reflect.New(durationType)
+		p.Elem().Set(d)
+		d = p
+	}
+	s.dest.Set(d)
+	return nil
+}
+
+// isRowStruct returns true when the columns of a row are scanned into the fields of t, false when t is a value
+// scanned from a single column, e.g. time.Time or dbsql.Decimal
+func isRowStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(scannerType)
+}
+
+// collectFields adds the fields of t to byName by normalized name. The fields of embedded structs are added
+// after the fields of t, which take precedence.
+func collectFields(t reflect.Type, index []int, byName map[string][]int) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("db"), ",")
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && isRowStruct(ft) {
+			// the fields of an unexported embedded struct can't be set when it has to be allocated
+			if f.IsExported() || f.Type.Kind() != reflect.Pointer {
+				embedded = append(embedded, f)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		key := normalizeName(name)
+		if _, ok := byName[key]; !ok {
+			byName[key] = append(append([]int{}, index...), i)
+		}
+	}
+
+	for _, f := range embedded {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		collectFields(ft, append(append([]int{}, index...), f.Index...), byName)
+	}
+}
+
+// fieldByIndex returns the field of v with index, allocating the nil pointers to embedded structs on the way
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// normalizeName returns the name matched with the names of the columns, e.g. createdat for CreatedAt and created_at
+func normalizeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
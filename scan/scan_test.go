@@ -0,0 +1,166 @@
+package dbsqlscan
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConn answers every query with fixed rows
+type testConn struct {
+	rows *testRows
+}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *testConn) Close() error                              { return nil }
+func (c *testConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (c *testConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows := *c.rows
+	return &rows, nil
+}
+
+type testRows struct {
+	columns []string
+	types   []string
+	values  [][]driver.Value
+}
+
+func (r *testRows) Columns() []string                           { return r.columns }
+func (r *testRows) ColumnTypeDatabaseTypeName(index int) string { return r.types[index] }
+func (r *testRows) Close() error                                { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+type testConnector struct {
+	conn *testConn
+}
+
+func (c testConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c testConnector) Driver() driver.Driver                        { return nil }
+
+func newTestDB(t *testing.T, rows *testRows) *sql.DB {
+	db := sql.OpenDB(testConnector{&testConn{rows: rows}})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type audit struct {
+	CreatedAt time.Time
+}
+
+type order struct {
+	ID       int64 `db:"id"`
+	Amount   dbsql.Decimal
+	Tags     []string
+	Shipping *address
+	Note     *string
+	TTL      time.Duration `db:"retention"`
+	Internal string        `db:"-"`
+	audit
+}
+
+func ordersRows() *testRows {
+	created := time.Date(2023, 1, 30, 10, 0, 0, 0, time.UTC)
+	return &testRows{
+		columns: []string{"id", "amount", "tags", "shipping", "note", "retention", "created_at"},
+		types:   []string{"BIGINT", "DECIMAL", "ARRAY", "STRUCT", "STRING", "INTERVAL_DAY_TIME", "TIMESTAMP"},
+		values: [][]driver.Value{
+			{int64(1), "12345678901234567.89", `["new","gift"]`, `{"city":"Amsterdam","zip":"1011"}`, "leave at door", "1 02:00:00.000000000", created},
+			{int64(2), "0.50", []any{"returned"}, nil, nil, nil, created},
+		},
+	}
+}
+
+func TestSelect(t *testing.T) {
+	db := newTestDB(t, ordersRows())
+
+	var orders []order
+	require.NoError(t, Select(context.Background(), db, &orders, "select * from orders"))
+	require.Len(t, orders, 2)
+
+	note := "leave at door"
+	amount, _ := dbsql.ParseDecimal("12345678901234567.89")
+	assert.Equal(t, order{
+		ID:       1,
+		Amount:   amount,
+		Tags:     []string{"new", "gift"},
+		Shipping: &address{City: "Amsterdam", Zip: "1011"},
+		Note:     &note,
+		TTL:      26 * time.Hour,
+		audit:    audit{CreatedAt: time.Date(2023, 1, 30, 10, 0, 0, 0, time.UTC)},
+	}, orders[0])
+	assert.Equal(t, "12345678901234567.89", orders[0].Amount.String(), "decimals keep their precision")
+
+	// structured complex values and NULLs
+	assert.Equal(t, []string{"returned"}, orders[1].Tags)
+	assert.Nil(t, orders[1].Shipping)
+	assert.Nil(t, orders[1].Note)
+	assert.Zero(t, orders[1].TTL)
+
+	var ptrs []*order
+	require.NoError(t, Select(context.Background(), db, &ptrs, "select * from orders"))
+	require.Len(t, ptrs, 2)
+	assert.Equal(t, orders[1], *ptrs[1])
+}
+
+func TestGet(t *testing.T) {
+	db := newTestDB(t, ordersRows())
+
+	var o order
+	require.NoError(t, Get(context.Background(), db, &o, "select * from orders where id = 1"))
+	assert.Equal(t, int64(1), o.ID)
+
+	empty := newTestDB(t, &testRows{columns: []string{"id"}, types: []string{"BIGINT"}})
+	assert.ErrorIs(t, Get(context.Background(), empty, &o, "select id from orders where id = 3"), sql.ErrNoRows)
+}
+
+func TestSingleColumn(t *testing.T) {
+	db := newTestDB(t, &testRows{
+		columns: []string{"amount"},
+		types:   []string{"DECIMAL"},
+		values:  [][]driver.Value{{"1.10"}, {"2.25"}},
+	})
+
+	var amounts []dbsql.Decimal
+	require.NoError(t, Select(context.Background(), db, &amounts, "select amount from orders"))
+	require.Len(t, amounts, 2)
+	assert.Equal(t, "2.25", amounts[1].String())
+
+	var strs []string
+	require.NoError(t, Select(context.Background(), db, &strs, "select amount from orders"))
+	assert.Equal(t, []string{"1.10", "2.25"}, strs)
+}
+
+func TestScanErrors(t *testing.T) {
+	db := newTestDB(t, ordersRows())
+	ctx := context.Background()
+
+	var unmatched []struct{ ID int64 }
+	assert.ErrorContains(t, Select(ctx, db, &unmatched, "select * from orders"), "no field of struct { ID int64 } matches column amount")
+
+	var ids []int64
+	assert.ErrorContains(t, Select(ctx, db, &ids, "select * from orders"), "7 columns can't be scanned into *int64")
+
+	var o order
+	assert.ErrorContains(t, Select(ctx, db, &o, "select * from orders"), "must be a pointer to a slice")
+	assert.ErrorContains(t, Get(ctx, db, o, "select * from orders"), "must be a non-nil pointer")
+}
@@ -0,0 +1,186 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// scanSQL calls fn with the index of each byte of query that is not in a string literal, a raw string literal like
+// r'\d+', a quoted identifier, a $$ quoted string or a comment
+func scanSQL(query string, fn func(i int)) {
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// skip to the closing quote, string literals can have backslash escapes unless they are raw
+			escapes := c != '`' && !isRawStringPrefix(query, i)
+			for i++; i < len(query) && query[i] != c; i++ {
+				if query[i] == '\\' && escapes {
+					i++
+				}
+			}
+		case c == '$' && strings.HasPrefix(query[i:], "$$"):
+			end := strings.Index(query[i+2:], "$$")
+			if end < 0 {
+				return
+			}
+			i += end + 3
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return
+			}
+			i += end + 3
+		default:
+			fn(i)
+		}
+	}
+}
+
+// isRawStringPrefix returns true when the quote at i starts a raw string literal, i.e. it follows an r or R which is
+// not the end of an identifier
+func isRawStringPrefix(query string, i int) bool {
+	return i > 0 && (query[i-1] == 'r' || query[i-1] == 'R') && (i == 1 || !isIdentifierChar(query[i-2]))
+}
+
+// splitStatements splits a script into its semicolon separated statements, empty statements are dropped
+func splitStatements(script string) []string {
+	var statements []string
+	start := 0
+	add := func(end int) {
+		if statement := strings.TrimSpace(script[start:end]); statement != "" && !isCommentOnly(statement) {
+			statements = append(statements, statement)
+		}
+		start = end + 1
+	}
+	scanSQL(script, func(i int) {
+		if script[i] == ';' {
+			add(i)
+		}
+	})
+	add(len(script))
+	return statements
+}
+
+// isCommentOnly returns true when statement only has comments
+func isCommentOnly(statement string) bool {
+	onlyComments := true
+	scanSQL(statement, func(i int) {
+		if !isSpace(statement[i]) {
+			onlyComments = false
+		}
+	})
+	return onlyComments
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// splitArgs returns the arguments of each statement of a script. Positional arguments are given to the statements
+// in the order of their parameter markers, named arguments are given to every statement.
+func splitArgs(statements []string, args []driver.NamedValue) [][]driver.NamedValue {
+	var named []driver.NamedValue
+	var positional []driver.NamedValue
+	for _, arg := range args {
+		if arg.Name != "" {
+			named = append(named, arg)
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	statementArgs := make([][]driver.NamedValue, len(statements))
+	for i, statement := range statements {
+		n, _ := parseParameterMarkers(statement)
+		if n > len(positional) {
+			n = len(positional)
+		}
+		for j, arg := range positional[:n] {
+			arg.Ordinal = j + 1
+			statementArgs[i] = append(statementArgs[i], arg)
+		}
+		positional = positional[n:]
+		statementArgs[i] = append(statementArgs[i], named...)
+	}
+	return statementArgs
+}
+
+// execScript runs the statements of a script one after the other, the result has the total number of modified rows
+func (c *conn) execScript(ctx context.Context, statements []string, args []driver.NamedValue) (driver.Result, error) {
+	statementArgs := splitArgs(statements, args)
+	res := result{}
+	for i := range statements {
+		r, err := c.ExecContext(ctx, statements[i], statementArgs[i])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "databricks: statement %d of script failed", i+1)
+		}
+		affectedRows, _ := r.RowsAffected()
+		res.AffectedRows += affectedRows
+		if insertId, _ := r.LastInsertId(); insertId != 0 {
+			res.InsertId = insertId
+		}
+	}
+	return &res, nil
+}
+
+// queryScript runs the first statement of a script, the next statements run when moving to their result set
+func (c *conn) queryScript(ctx context.Context, statements []string, args []driver.NamedValue) (driver.Rows, error) {
+	statementArgs := splitArgs(statements, args)
+	r, err := c.QueryContext(ctx, statements[0], statementArgs[0])
+	if err != nil {
+		return nil, errors.WithMessage(err, "databricks: statement 1 of script failed")
+	}
+	return &scriptRows{
+		rows:          r.(*rows),
+		conn:          c,
+		ctx:           ctx,
+		statements:    statements[1:],
+		statementArgs: statementArgs[1:],
+		statementNum:  1,
+	}, nil
+}
+
+// scriptRows are the results of the statements of a script, one result set per statement
+type scriptRows struct {
+	*rows
+	conn          *conn
+	ctx           context.Context
+	statements    []string // statements that have not run yet
+	statementArgs [][]driver.NamedValue
+	statementNum  int // number of the statement of the current result set
+}
+
+var _ driver.RowsNextResultSet = (*scriptRows)(nil)
+
+// HasNextResultSet returns true while there are statements left to run
+func (r *scriptRows) HasNextResultSet() bool {
+	return len(r.statements) > 0
+}
+
+// NextResultSet closes the current result set and runs the next statement
+func (r *scriptRows) NextResultSet() error {
+	if len(r.statements) == 0 {
+		return io.EOF
+	}
+	if err := r.rows.Close(); err != nil {
+		return err
+	}
+
+	statement, args := r.statements[0], r.statementArgs[0]
+	r.statements, r.statementArgs = r.statements[1:], r.statementArgs[1:]
+	r.statementNum++
+	next, err := r.conn.QueryContext(r.ctx, statement, args)
+	if err != nil {
+		return errors.WithMessagef(err, "databricks: statement %d of script failed", r.statementNum)
+	}
+	r.rows = next.(*rows)
+	return nil
+}
@@ -0,0 +1,122 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := map[string][]string{
+		"select 1":                                 {"select 1"},
+		"select 1;":                                {"select 1"},
+		"select 1; select 2":                       {"select 1", "select 2"},
+		"select ';'; select \"a;b\";;":             {"select ';'", "select \"a;b\""},
+		"select 'it\\'s;' ; select `;`":            {"select 'it\\'s;'", "select `;`"},
+		"select 1 -- one; two\n; select 2 /* ; */": {"select 1 -- one; two", "select 2 /* ; */"},
+		"create function f() returns int language python as $$ a = 1; return a $$; select f()": {
+			"create function f() returns int language python as $$ a = 1; return a $$", "select f()",
+		},
+		"select 1;\n-- done\n":                    {"select 1"},
+		`select r'\'; select R"\"; select 'r\';'`: {`select r'\'`, `select R"\"`, `select 'r\';'`},
+		"": nil,
+	}
+	for script, statements := range cases {
+		assert.Equal(t, statements, splitStatements(script), script)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	statements := []string{"insert into t values (?, ?)", "select :name", "select * from t where a = ?"}
+	args := []driver.NamedValue{
+		{Ordinal: 1, Value: int64(1)},
+		{Ordinal: 2, Value: int64(2)},
+		{Ordinal: 3, Value: int64(3)},
+	}
+	statementArgs := splitArgs(statements, args)
+	assert.Equal(t, [][]driver.NamedValue{
+		{{Ordinal: 1, Value: int64(1)}, {Ordinal: 2, Value: int64(2)}},
+		nil,
+		{{Ordinal: 1, Value: int64(3)}},
+	}, statementArgs)
+
+	named := []driver.NamedValue{{Name: "name", Ordinal: 1, Value: "a"}}
+	statementArgs = splitArgs(statements, named)
+	for i := range statements {
+		assert.Equal(t, named, statementArgs[i])
+	}
+}
+
+func TestConn_Script(t *testing.T) {
+	getTestConn := func(queries *[]string) *conn {
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				*queries = append(*queries, req.Statement)
+				return &cli_service.TExecuteStatementResp{
+					Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+					OperationHandle: &cli_service.TOperationHandle{
+						OperationId: &cli_service.THandleIdentifier{
+							GUID:   []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 2, 3, 4, 4, 223, 34, 54},
+							Secret: []byte("b"),
+						},
+					},
+				}, nil
+			},
+			FnGetOperationStatus: func(ctx context.Context, req *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
+				return &cli_service.TGetOperationStatusResp{
+					OperationState:  cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+					NumModifiedRows: thrift.Int64Ptr(2),
+				}, nil
+			},
+			FnCloseOperation: func(ctx context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+				return &cli_service.TCloseOperationResp{}, nil
+			},
+		}
+		return &conn{
+			session: getTestSession(),
+			client:  testClient,
+			cfg:     config.WithDefaults(),
+		}
+	}
+
+	t.Run("ExecContext runs each statement of a script", func(t *testing.T) {
+		var queries []string
+		res, err := getTestConn(&queries).ExecContext(context.Background(), "create table t (a int); insert into t values (1), (2); insert into t values (3), (4);", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"create table t (a int)", "insert into t values (1), (2)", "insert into t values (3), (4)"}, queries)
+		rowsAffected, _ := res.RowsAffected()
+		assert.Equal(t, int64(6), rowsAffected)
+	})
+
+	t.Run("QueryContext returns a result set for each statement of a script", func(t *testing.T) {
+		var queries []string
+		rows, err := getTestConn(&queries).QueryContext(context.Background(), "select 1; select 2", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"select 1"}, queries)
+
+		scriptRows, ok := rows.(driver.RowsNextResultSet)
+		assert.True(t, ok)
+		assert.True(t, scriptRows.HasNextResultSet())
+		assert.NoError(t, scriptRows.NextResultSet())
+		assert.Equal(t, []string{"select 1", "select 2"}, queries)
+		assert.False(t, scriptRows.HasNextResultSet())
+		assert.Equal(t, io.EOF, scriptRows.NextResultSet())
+		assert.NoError(t, rows.Close())
+	})
+
+	t.Run("QueryContext returns a single result set for a single statement", func(t *testing.T) {
+		var queries []string
+		rows, err := getTestConn(&queries).QueryContext(context.Background(), "select 1;", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"select 1;"}, queries)
+		_, ok := rows.(driver.RowsNextResultSet)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,513 @@
+package dbsql
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/databricks/databricks-sql-go/metrics"
+	"github.com/pkg/errors"
+)
+
+// startSession opens the session of the connection, sets its session params and starts its heartbeat. The session
+// is closed when its params can't be set.
+func (c *conn) startSession(ctx context.Context) error {
+	if err := c.openSession(ctx); err != nil {
+		return wrapErrf(err, "error connecting: host=%s port=%d, httpPath=%s", c.cfg.Host, c.cfg.Port, c.cfg.HTTPPath)
+	}
+	registerLogger(c.id, c.cfg)
+	metrics.Gauge(c.cfg.Metrics, metrics.OpenSessions, 1)
+	log := logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "")
+
+	log.Info().Msgf("connect: host=%s port=%d httpPath=%s", c.cfg.Host, c.cfg.Port, c.cfg.HTTPPath)
+
+	if err := c.setSessionParams(ctx); err != nil {
+		// the session isn't left open on the warehouse
+		_, closeErr := c.client.CloseSession(driverctx.NewContextWithConnId(context.Background(), c.id), &cli_service.TCloseSessionReq{
+			SessionHandle: c.session.SessionHandle,
+		})
+		if closeErr != nil {
+			log.Warn().Err(closeErr).Msg("databricks: failed to close session")
+		}
+		logger.UnregisterConnection(c.id)
+		metrics.Gauge(c.cfg.Metrics, metrics.OpenSessions, -1)
+		c.mu.Lock()
+		c.session = nil
+		c.mu.Unlock()
+		return err
+	}
+
+	if c.heartbeatClient != nil {
+		c.startHeartbeat(c.heartbeatClient)
+	}
+	return nil
+}
+
+// ensureSession opens the session of a lazy connection with its first statement, see WithLazySession
+func (c *conn) ensureSession(ctx context.Context) error {
+	if c.session != nil {
+		return nil
+	}
+	if c.closed.Load() {
+		return driver.ErrBadConn
+	}
+	return c.startSession(ctx)
+}
+
+// openSession opens a new session of the connection in its current catalog and schema
+func (c *conn) openSession(ctx context.Context) error {
+	var catalogName *cli_service.TIdentifier
+	var schemaName *cli_service.TIdentifier
+	if c.catalog != "" {
+		catalogName = cli_service.TIdentifierPtr(cli_service.TIdentifier(c.catalog))
+	}
+	if c.schema != "" {
+		schemaName = cli_service.TIdentifierPtr(cli_service.TIdentifier(c.schema))
+	}
+
+	req := &cli_service.TOpenSessionReq{
+		ClientProtocol: c.cfg.ThriftProtocolVersion,
+		Configuration:  make(map[string]string),
+		InitialNamespace: &cli_service.TNamespace{
+			CatalogName: catalogName,
+			SchemaName:  schemaName,
+		},
+		CanUseMultipleCatalogs: &c.cfg.CanUseMultipleCatalogs,
+	}
+	// the session id generated by the driver makes the request idempotent, a request retried after a transport
+	// error opens the same session instead of leaving another one open on the warehouse
+	guid := make([]byte, 16)
+	if _, err := rand.Read(guid); err != nil {
+		return errors.Wrap(err, "databricks: failed to generate session id")
+	}
+	req.SessionId = &cli_service.THandleIdentifier{GUID: guid, Secret: []byte{}}
+
+	session, err := c.openSessionRequest(ctx, req)
+	if err != nil && c.cfg.WarehouseStartTimeout > 0 {
+		session, err = c.waitForWarehouse(ctx, req, err)
+	}
+	if err != nil {
+		c.closeOrphanedSession(ctx, req.SessionId, err)
+		return err
+	}
+
+	c.mu.Lock()
+	c.session = session
+	c.id = client.SprintGuid(session.SessionHandle.GetSessionId().GUID)
+	c.mu.Unlock()
+	c.expired.Store(false)
+	if version := negotiateProtocolVersion(req.ClientProtocol, session.ServerProtocolVersion); version < req.ClientProtocol {
+		logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "").Info().Msgf(
+			"databricks: server protocol version %s, the features of later versions are disabled: %+v", version, c.ServerCapabilities())
+	}
+	return nil
+}
+
+// closeOrphanedSession closes the session which a request interrupted midway may have opened on the warehouse, e.g.
+// when the connect timeout expired while the server was responding. A request which failed otherwise opened none.
+func (c *conn) closeOrphanedSession(ctx context.Context, sessionId *cli_service.THandleIdentifier, err error) {
+	var netErr net.Error
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) && !(errors.As(err, &netErr) && netErr.Timeout()) {
+		return
+	}
+	log := logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "")
+	closeCtx, cancel := context.WithTimeout(context.Background(), c.cfg.PingTimeout)
+	defer cancel()
+	_, closeErr := c.client.CloseSession(closeCtx, &cli_service.TCloseSessionReq{
+		SessionHandle: &cli_service.TSessionHandle{SessionId: sessionId},
+	})
+	if closeErr != nil {
+		// the session most likely wasn't opened
+		log.Debug().Err(closeErr).Msg("databricks: failed to close the session of an interrupted request")
+		return
+	}
+	log.Info().Msg("databricks: closed the session of an interrupted request")
+}
+
+// openSessionRequest sends a request opening a session, which fails after the connect timeout. The timeout covers
+// resolving the host, dialing and the TLS handshake of a new HTTP connection as well as the request itself.
+func (c *conn) openSessionRequest(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+	if c.cfg.ConnectTimeout <= 0 {
+		return c.client.OpenSession(ctx, req)
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, c.cfg.ConnectTimeout)
+	defer cancel()
+	session, err := c.client.OpenSession(connectCtx, req)
+	if err != nil && ctx.Err() == nil && connectCtx.Err() == context.DeadlineExceeded {
+		return nil, newDriverError(fmt.Sprintf(ErrConnectTimeout, c.cfg.ConnectTimeout), context.DeadlineExceeded)
+	}
+	return session, err
+}
+
+// warehouseStartPollInterval is the max wait between the attempts to open a session while the warehouse is starting
+var warehouseStartPollInterval = 10 * time.Second
+
+// waitForWarehouse opens a session again until the warehouse started, up to the warehouse start timeout, when
+// err tells that it is starting
+func (c *conn) waitForWarehouse(ctx context.Context, req *cli_service.TOpenSessionReq, err error) (*cli_service.TOpenSessionResp, error) {
+	log := logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "")
+	start := time.Now()
+	for {
+		var startErr *dbsqlerr.WarehouseStartingError
+		if !errors.As(err, &startErr) {
+			return nil, err
+		}
+		waited := time.Since(start)
+		remaining := c.cfg.WarehouseStartTimeout - waited
+		if remaining <= 0 {
+			startErr.Waited = waited
+			log.Warn().Dur("waited", waited).Msgf("databricks: %s did not start within the warehouse start timeout", c.computeName())
+			return nil, err
+		}
+		log.Info().Dur("waited", waited).Msgf("databricks: %s is starting", c.computeName())
+
+		wait := warehouseStartPollInterval
+		if wait > remaining {
+			wait = remaining
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		session, err1 := c.openSessionRequest(ctx, req)
+		if err1 == nil {
+			log.Info().Dur("waited", time.Since(start)).Msgf("databricks: %s started", c.computeName())
+			metrics.Duration(c.cfg.Metrics, metrics.WarehouseStarts, start)
+			return session, nil
+		}
+		err = err1
+	}
+}
+
+// setSessionParams sets the session parameters of the settings with SET statements, then the session parameters
+// set by the statements of the connection in the previous session
+func (c *conn) setSessionParams(ctx context.Context) error {
+	log := logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "")
+	replay := c.params
+	c.params = nil
+	for k, v := range c.cfg.SessionParams {
+		if _, err := c.ExecContext(ctx, setStatement(k, v), nil); err != nil {
+			return err
+		}
+		log.Info().Msgf("set session parameter: param=%s value=%s", k, v)
+	}
+	c.params = nil
+
+	keys := make([]string, 0, len(replay))
+	for k := range replay {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := c.ExecContext(ctx, replay[k], nil); err != nil {
+			return err
+		}
+		log.Info().Msgf("set session parameter again: param=%s", k)
+	}
+	// the session params of the settings are the initial state of the session
+	c.stateChanged = len(c.params) > 0
+	return nil
+}
+
+// setStatement returns the SET statement of a session param
+func setStatement(key, value string) string {
+	return fmt.Sprintf("SET `%s` = `%s`;", strings.ReplaceAll(key, "`", "``"), strings.ReplaceAll(value, "`", "``"))
+}
+
+// SetSessionParams sets session params of a connection after it is opened, e.g. ansi_mode or timezone, with
+// SET statements. Like the params of SET statements run on the connection, they are set again when the
+// session is reopened after it expired.
+func SetSessionParams(ctx context.Context, conn *sql.Conn, params map[string]string) error {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := conn.ExecContext(ctx, setStatement(k, params[k])); err != nil {
+			return errors.Wrapf(err, "databricks: failed to set session param %s", k)
+		}
+	}
+	return nil
+}
+
+// reopenSession replaces an expired session with a new one in the same catalog and schema, with the same
+// session parameters
+func (c *conn) reopenSession(ctx context.Context) error {
+	if c.reopening {
+		return errors.New("databricks: session expired while it was reopened")
+	}
+	c.reopening = true
+	defer func() { c.reopening = false }()
+
+	oldId := c.id
+	log := logger.WithContext(oldId, driverctx.CorrelationIdFromContext(ctx), "")
+	if err := c.openSession(ctx); err != nil {
+		c.expired.Store(true)
+		log.Err(err).Msg("databricks: failed to reopen session")
+		return err
+	}
+	logger.UnregisterConnection(oldId)
+	registerLogger(c.id, c.cfg)
+	log.Info().Msgf("databricks: reopened session: new session=%s", c.id)
+
+	return c.setSessionParams(driverctx.NewContextWithConnId(ctx, c.id))
+}
+
+// resetSession replaces a session whose state was changed by the statements of the connection with a new session
+// in the catalog and schema of the connection settings
+func (c *conn) resetSession(ctx context.Context) error {
+	if !c.stateChanged && c.catalog == c.cfg.Catalog && c.schema == c.cfg.Schema {
+		return nil
+	}
+	oldSession := c.session
+	c.catalog, c.schema, c.params = c.cfg.Catalog, c.cfg.Schema, nil
+	if err := c.reopenSession(ctx); err != nil {
+		return err
+	}
+
+	_, err := c.client.CloseSession(driverctx.NewContextWithConnId(ctx, c.id), &cli_service.TCloseSessionReq{
+		SessionHandle: oldSession.SessionHandle,
+	})
+	if err != nil {
+		logger.WithContext(c.id, driverctx.CorrelationIdFromContext(ctx), "").Warn().Err(err).Msg("databricks: failed to close replaced session")
+	}
+	return nil
+}
+
+// isInvalidSession returns true when err is caused by a session that expired or was closed by the server,
+// e.g. after the warehouse restarted
+func isInvalidSession(err error) bool {
+	if err == nil {
+		return false
+	}
+	var reqErr *dbsqlerr.RequestError
+	if errors.As(err, &reqErr) && reqErr.StatusCode == cli_service.TStatusCode_INVALID_HANDLE_STATUS.String() {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "invalid sessionhandle")
+}
+
+// trackStatement records the changes of the session state made by a statement which ran successfully
+func (c *conn) trackStatement(query string) {
+	// the first words are enough to know the kind of statement
+	start := strings.TrimSpace(query)
+	if len(start) > 64 {
+		start = start[:64]
+	}
+	words := strings.Fields(strings.ToUpper(start))
+	if len(words) == 0 {
+		return
+	}
+	switch strings.TrimSuffix(words[0], ";") {
+	case "USE":
+		c.trackNamespace(query)
+	case "SET":
+		c.trackSet(query)
+	case "RESET":
+		c.trackReset(query)
+	case "DECLARE":
+		c.stateChanged = true
+	case "CREATE":
+		// temporary views, functions and tables
+		for i := 1; i < len(words) && i < 4; i++ {
+			if words[i] == "TEMP" || words[i] == "TEMPORARY" {
+				c.stateChanged = true
+			}
+		}
+	}
+}
+
+// trackSet keeps the statement setting a session param, to set it again when the session is reopened
+func (c *conn) trackSet(query string) {
+	statement := strings.TrimSpace(query)
+	rest := strings.TrimSpace(strings.TrimSuffix(statement, ";"))[3:]
+	upper := strings.ToUpper(rest)
+	var key string
+	switch {
+	case strings.HasPrefix(upper, " VAR ") || strings.HasPrefix(upper, " VARIABLE "):
+		// session variables are lost with the session
+		c.stateChanged = true
+		return
+	case strings.HasPrefix(upper, " TIME ZONE "):
+		key = "TIME ZONE"
+	default:
+		i := indexOutsideQuotes(rest, '=')
+		if i < 0 {
+			// SET and SET key show the params
+			return
+		}
+		key = unquoteKey(rest[:i])
+	}
+	if c.params == nil {
+		c.params = make(map[string]string)
+	}
+	c.params[key] = statement
+	c.stateChanged = true
+}
+
+// trackReset forgets the session params reset by a RESET statement
+func (c *conn) trackReset(query string) {
+	key := unquoteKey(strings.TrimSuffix(strings.TrimSpace(query), ";")[5:])
+	if key == "" {
+		c.params = nil
+	} else {
+		delete(c.params, key)
+	}
+	c.stateChanged = true
+}
+
+// unquoteKey returns the name of a session param, which may be quoted with backticks
+func unquoteKey(key string) string {
+	key = strings.TrimSpace(key)
+	if len(key) >= 2 && key[0] == '`' && key[len(key)-1] == '`' {
+		key = strings.ReplaceAll(key[1:len(key)-1], "``", "`")
+	}
+	return key
+}
+
+// indexOutsideQuotes returns the index of the first ch of s which isn't in a quoted name or string, -1 if there is none
+func indexOutsideQuotes(s string, ch byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '`' || s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == ch:
+			return i
+		}
+	}
+	return -1
+}
+
+// trackNamespace keeps the catalog and schema set by a USE statement, to open the session in them again
+// when it's reopened
+func (c *conn) trackNamespace(query string) {
+	query = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	if len(query) < 4 || !strings.EqualFold(query[:3], "USE") || !isSpace(query[3]) {
+		return
+	}
+	name := strings.TrimSpace(query[4:])
+	if i := strings.IndexFunc(name, func(r rune) bool { return r < 128 && isSpace(byte(r)) }); i > 0 {
+		switch strings.ToUpper(name[:i]) {
+		case "CATALOG":
+			if parts := splitQualifiedName(strings.TrimSpace(name[i:])); len(parts) == 1 {
+				c.catalog, c.schema = parts[0], ""
+			}
+			return
+		case "SCHEMA", "DATABASE", "NAMESPACE":
+			name = strings.TrimSpace(name[i:])
+		}
+	}
+	switch parts := splitQualifiedName(name); len(parts) {
+	case 1:
+		c.schema = parts[0]
+	case 2:
+		c.catalog, c.schema = parts[0], parts[1]
+	}
+}
+
+// splitQualifiedName splits a dotted name like `my catalog`.schema into unquoted parts, nil when it is not a name
+func splitQualifiedName(name string) []string {
+	var parts []string
+	var part strings.Builder
+	quoted := false
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		switch {
+		case ch == '`' && quoted && i+1 < len(name) && name[i+1] == '`':
+			part.WriteByte('`')
+			i++
+		case ch == '`':
+			quoted = !quoted
+		case ch == '.' && !quoted:
+			parts = append(parts, part.String())
+			part.Reset()
+		case isSpace(ch) && !quoted:
+			return nil
+		default:
+			part.WriteByte(ch)
+		}
+	}
+	if quoted {
+		return nil
+	}
+	parts = append(parts, part.String())
+	for _, p := range parts {
+		if p == "" {
+			return nil
+		}
+	}
+	return parts
+}
+
+// startHeartbeat sends a heartbeat request with tclient at each heartbeat interval the connection was idle,
+// until the connection is closed. tclient is not shared with the connection, whose client may be in use meanwhile.
+func (c *conn) startHeartbeat(tclient cli_service.TCLIService) {
+	interval := c.cfg.HeartbeatInterval
+	if interval <= 0 {
+		return
+	}
+	c.lastUsed.Store(time.Now().UnixNano())
+	stop := make(chan struct{})
+	c.stopHeartbeat = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if time.Since(time.Unix(0, c.lastUsed.Load())) < interval || c.expired.Load() {
+					continue
+				}
+				c.heartbeat(tclient)
+			}
+		}
+	}()
+}
+
+// heartbeat sends a GetInfo request on the session, which keeps it alive. The next statement reopens the
+// session when it has expired.
+func (c *conn) heartbeat(tclient cli_service.TCLIService) {
+	c.mu.Lock()
+	connId, sessionHandle := c.id, c.session.SessionHandle
+	c.mu.Unlock()
+
+	log := logger.WithContext(connId, "", "")
+	ctx, cancel := context.WithTimeout(driverctx.NewContextWithConnId(context.Background(), connId), c.cfg.PingTimeout)
+	defer cancel()
+	_, err := tclient.GetInfo(ctx, &cli_service.TGetInfoReq{
+		SessionHandle: sessionHandle,
+		InfoType:      cli_service.TGetInfoType_CLI_SERVER_NAME,
+	})
+	if err == nil {
+		log.Debug().Msg("databricks: session heartbeat")
+		return
+	}
+	if isInvalidSession(err) {
+		c.expired.Store(true)
+	}
+	log.Warn().Err(err).Msg("databricks: session heartbeat failed")
+}
@@ -0,0 +1,611 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/databricks/databricks-sql-go/metrics"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func finishedStatement() *cli_service.TExecuteStatementResp {
+	return &cli_service.TExecuteStatementResp{
+		Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+		OperationHandle: &cli_service.TOperationHandle{
+			OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 1, 2, 3, 4, 4, 223, 34}},
+		},
+		DirectResults: &cli_service.TSparkDirectResults{
+			OperationStatus: &cli_service.TGetOperationStatusResp{
+				OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+			},
+			CloseOperation: &cli_service.TCloseOperationResp{},
+		},
+	}
+}
+
+func newTestSession(guid byte) *cli_service.TOpenSessionResp {
+	return &cli_service.TOpenSessionResp{
+		Status:        &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+		SessionHandle: &cli_service.TSessionHandle{SessionId: &cli_service.THandleIdentifier{GUID: []byte{guid, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}}},
+	}
+}
+
+func TestConn_reopenSession(t *testing.T) {
+	invalidSession := errors.WithStack(&dbsqlerr.RequestError{
+		Msg:        "Invalid SessionHandle: SessionHandle [01020304-0506-0708-090a-0b0c0d0e0f10]",
+		StatusCode: cli_service.TStatusCode_ERROR_STATUS.String(),
+	})
+
+	t.Run("expired session is reopened and the statement runs again", func(t *testing.T) {
+		var statements []string
+		var openReq *cli_service.TOpenSessionReq
+		oldSession := newTestSession(1)
+		cfg := config.WithDefaults()
+		cfg.SessionParams = map[string]string{"ansi_mode": "false"}
+		c := &conn{
+			id:      client.SprintGuid(oldSession.SessionHandle.SessionId.GUID),
+			session: oldSession,
+			cfg:     cfg,
+			catalog: "main",
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					openReq = req
+					return newTestSession(2), nil
+				},
+				FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+					if req.SessionHandle == oldSession.SessionHandle {
+						return nil, invalidSession
+					}
+					statements = append(statements, req.Statement)
+					return finishedStatement(), nil
+				},
+			},
+		}
+
+		_, err := c.ExecContext(context.Background(), "USE SCHEMA sales", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "main", string(*openReq.InitialNamespace.CatalogName))
+		assert.Nil(t, openReq.InitialNamespace.SchemaName, "the schema is only set once USE ran")
+		assert.Equal(t, []string{"SET `ansi_mode` = `false`;", "USE SCHEMA sales"}, statements)
+		assert.Equal(t, "02020304-0506-0708-090a-0b0c0d0e0f10", c.id)
+		assert.Equal(t, "sales", c.schema)
+	})
+
+	t.Run("session expired by a heartbeat is reopened before the statement", func(t *testing.T) {
+		var opened int
+		c := &conn{
+			session: newTestSession(1),
+			cfg:     config.WithDefaults(),
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					opened++
+					return newTestSession(2), nil
+				},
+				FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+					return finishedStatement(), nil
+				},
+			},
+		}
+		c.expired.Store(true)
+
+		_, err := c.ExecContext(context.Background(), "select 1", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, opened)
+		assert.False(t, c.expired.Load())
+	})
+
+	t.Run("statement fails when the session can't be reopened", func(t *testing.T) {
+		var executed int
+		c := &conn{
+			session: newTestSession(1),
+			cfg:     config.WithDefaults(),
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					return nil, errors.New("warehouse is stopped")
+				},
+				FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+					executed++
+					return nil, invalidSession
+				},
+			},
+		}
+
+		_, err := c.ExecContext(context.Background(), "select 1", nil)
+		assert.ErrorContains(t, err, "Invalid SessionHandle")
+		assert.Equal(t, 1, executed)
+	})
+}
+
+func TestConn_trackNamespace(t *testing.T) {
+	cases := []struct {
+		query   string
+		catalog string
+		schema  string
+	}{
+		{"USE sales", "main", "sales"},
+		{"use schema sales;", "main", "sales"},
+		{"USE DATABASE `my sales`", "main", "my sales"},
+		{"USE hive_metastore.default", "hive_metastore", "default"},
+		{"USE `cat``1`.`s.1`", "cat`1", "s.1"},
+		{"USE CATALOG samples", "samples", ""},
+		{"USE CATALOG a.b", "main", "default"},
+		{"USE", "main", "default"},
+		{"SELECT 1", "main", "default"},
+		{"USE `unterminated", "main", "default"},
+	}
+	for _, tc := range cases {
+		c := &conn{catalog: "main", schema: "default"}
+		c.trackNamespace(tc.query)
+		assert.Equal(t, tc.catalog, c.catalog, tc.query)
+		assert.Equal(t, tc.schema, c.schema, tc.query)
+	}
+}
+
+func TestConn_trackStatement(t *testing.T) {
+	cases := map[string]bool{
+		"SET spark.sql.ansi.enabled = false":                 true,
+		"SELECT * FROM sales":                                false,
+		"CREATE TABLE sales (id INT)":                        false,
+		"create temporary view recent as select 1":           true,
+		"CREATE OR REPLACE TEMP VIEW recent AS SELECT 1":     true,
+		"CREATE TEMPORARY FUNCTION f() RETURNS INT RETURN 1": true,
+		"RESET":                  true,
+		"DECLARE VARIABLE x INT": true,
+		"  set timezone = UTC;":  true,
+	}
+	for query, changed := range cases {
+		c := &conn{}
+		c.trackStatement(query)
+		assert.Equal(t, changed, c.stateChanged, query)
+	}
+
+	c := &conn{}
+	c.trackStatement("USE `" + strings.Repeat("a", 100) + "`")
+	assert.Equal(t, strings.Repeat("a", 100), c.schema)
+	assert.False(t, c.stateChanged)
+}
+
+func TestConn_trackSet(t *testing.T) {
+	c := &conn{}
+	c.trackStatement("SET spark.sql.ansi.enabled = false")
+	c.trackStatement("set `a.b` = 'x=y';")
+	c.trackStatement("SET TIME ZONE 'America/New_York'")
+	c.trackStatement("SET spark.sql.ansi.enabled=true")
+	c.trackStatement("SET -v")
+	c.trackStatement("SET spark.sql.shuffle.partitions")
+	assert.Equal(t, map[string]string{
+		"spark.sql.ansi.enabled": "SET spark.sql.ansi.enabled=true",
+		"a.b":                    "set `a.b` = 'x=y';",
+		"TIME ZONE":              "SET TIME ZONE 'America/New_York'",
+	}, c.params)
+
+	c.trackStatement("RESET `a.b`;")
+	assert.NotContains(t, c.params, "a.b")
+	c.trackStatement("RESET")
+	assert.Empty(t, c.params)
+
+	c = &conn{}
+	c.trackStatement("SET VAR x = 1")
+	assert.Empty(t, c.params)
+	assert.True(t, c.stateChanged)
+}
+
+func TestConn_setSessionParams(t *testing.T) {
+	var statements []string
+	cfg := config.WithDefaults()
+	cfg.SessionParams = map[string]string{"ansi_mode": "false"}
+	c := &conn{
+		session: newTestSession(1),
+		cfg:     cfg,
+		client: &client.TestClient{
+			FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+				return newTestSession(2), nil
+			},
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				statements = append(statements, req.Statement)
+				return finishedStatement(), nil
+			},
+		},
+	}
+
+	_, err := c.ExecContext(context.Background(), "SET TIME ZONE 'UTC'", nil)
+	require.NoError(t, err)
+	_, err = c.ExecContext(context.Background(), "SET ansi_mode = true", nil)
+	require.NoError(t, err)
+
+	statements = nil
+	require.NoError(t, c.reopenSession(context.Background()))
+	assert.Equal(t, []string{"SET `ansi_mode` = `false`;", "SET TIME ZONE 'UTC'", "SET ansi_mode = true"}, statements)
+	assert.Len(t, c.params, 2)
+	assert.True(t, c.stateChanged)
+}
+
+// connConnector connects to a test connection
+type connConnector struct {
+	conn *conn
+}
+
+func (c connConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c connConnector) Driver() driver.Driver                        { return &databricksDriver{} }
+
+func TestSetSessionParams(t *testing.T) {
+	var statements []string
+	c := &conn{
+		session: newTestSession(1),
+		cfg:     config.WithDefaults(),
+		client: &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				statements = append(statements, req.Statement)
+				return finishedStatement(), nil
+			},
+		},
+	}
+	db := sql.OpenDB(connConnector{c})
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	err = SetSessionParams(context.Background(), conn, map[string]string{"timezone": "UTC", "ansi_mode": "false"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SET `ansi_mode` = `false`;", "SET `timezone` = `UTC`;"}, statements)
+	assert.Equal(t, map[string]string{"ansi_mode": "SET `ansi_mode` = `false`;", "timezone": "SET `timezone` = `UTC`;"}, c.params)
+}
+
+func TestConn_waitForWarehouse(t *testing.T) {
+	defer func(interval time.Duration) { warehouseStartPollInterval = interval }(warehouseStartPollInterval)
+	warehouseStartPollInterval = time.Millisecond
+	starting := &dbsqlerr.WarehouseStartingError{Err: &dbsqlerr.RequestError{Msg: "TEMPORARILY_UNAVAILABLE", HTTPStatusCode: 503}}
+
+	t.Run("session is opened once the warehouse started", func(t *testing.T) {
+		var attempts int
+		collector := newTestCollector()
+		cfg := config.WithDefaults()
+		cfg.WarehouseStartTimeout = time.Minute
+		cfg.Metrics = collector
+		c := &conn{
+			cfg: cfg,
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					attempts++
+					if attempts < 3 {
+						return nil, errors.WithStack(starting)
+					}
+					return newTestSession(1), nil
+				},
+			},
+		}
+		require.NoError(t, c.openSession(context.Background()))
+		assert.Equal(t, 3, attempts)
+		assert.Len(t, collector.histograms[metrics.WarehouseStarts], 1)
+	})
+
+	t.Run("error tells how long the driver waited", func(t *testing.T) {
+		cfg := config.WithDefaults()
+		cfg.WarehouseStartTimeout = 20 * time.Millisecond
+		c := &conn{
+			cfg: cfg,
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					return nil, errors.WithStack(&dbsqlerr.WarehouseStartingError{Err: starting.Err})
+				},
+			},
+		}
+		err := c.openSession(context.Background())
+		var startErr *dbsqlerr.WarehouseStartingError
+		require.ErrorAs(t, err, &startErr)
+		assert.GreaterOrEqual(t, startErr.Waited, 20*time.Millisecond)
+	})
+
+	t.Run("other errors are returned without waiting", func(t *testing.T) {
+		var attempts int
+		cfg := config.WithDefaults()
+		cfg.WarehouseStartTimeout = time.Minute
+		c := &conn{
+			cfg: cfg,
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					attempts++
+					return nil, errors.New("invalid token")
+				},
+			},
+		}
+		assert.ErrorContains(t, c.openSession(context.Background()), "invalid token")
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestConn_connectTimeout(t *testing.T) {
+	hanging := &client.TestClient{
+		FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+			<-ctx.Done()
+			return nil, errors.WithStack(ctx.Err())
+		},
+	}
+
+	t.Run("opening the session fails after the connect timeout", func(t *testing.T) {
+		cfg := config.WithDefaults()
+		cfg.ConnectTimeout = 20 * time.Millisecond
+		c := &conn{cfg: cfg, client: hanging}
+		start := time.Now()
+		err := c.openSession(context.Background())
+		assert.ErrorContains(t, err, "connect timeout of 20ms")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.True(t, isUnreachable(err), "the connection fails over")
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("the context of the caller takes precedence", func(t *testing.T) {
+		cfg := config.WithDefaults()
+		cfg.ConnectTimeout = time.Minute
+		c := &conn{cfg: cfg, client: hanging}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, c.openSession(ctx), context.Canceled)
+	})
+
+	t.Run("applies to each attempt while the warehouse starts", func(t *testing.T) {
+		defer func(interval time.Duration) { warehouseStartPollInterval = interval }(warehouseStartPollInterval)
+		warehouseStartPollInterval = time.Millisecond
+		var attempts int
+		cfg := config.WithDefaults()
+		cfg.ConnectTimeout = 20 * time.Millisecond
+		cfg.WarehouseStartTimeout = time.Minute
+		c := &conn{
+			cfg: cfg,
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					attempts++
+					if attempts < 3 {
+						return nil, errors.WithStack(&dbsqlerr.WarehouseStartingError{Err: &dbsqlerr.RequestError{Msg: "TEMPORARILY_UNAVAILABLE", HTTPStatusCode: 503}})
+					}
+					return newTestSession(1), nil
+				},
+			},
+		}
+		require.NoError(t, c.openSession(context.Background()))
+		assert.Equal(t, 3, attempts)
+	})
+}
+
+func TestConn_orphanedSessions(t *testing.T) {
+	t.Run("the session id of a request is generated by the driver", func(t *testing.T) {
+		var sessionIds [][]byte
+		c := &conn{
+			cfg: config.WithDefaults(),
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					sessionIds = append(sessionIds, req.SessionId.GUID)
+					return newTestSession(1), nil
+				},
+			},
+		}
+		require.NoError(t, c.openSession(context.Background()))
+		require.NoError(t, c.openSession(context.Background()))
+		require.Len(t, sessionIds, 2)
+		assert.Len(t, sessionIds[0], 16)
+		assert.NotEqual(t, sessionIds[0], sessionIds[1])
+	})
+
+	t.Run("the session of an interrupted request is closed", func(t *testing.T) {
+		var opened, closed *cli_service.THandleIdentifier
+		cfg := config.WithDefaults()
+		cfg.ConnectTimeout = 20 * time.Millisecond
+		c := &conn{
+			cfg: cfg,
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					opened = req.SessionId
+					<-ctx.Done()
+					return nil, errors.WithStack(ctx.Err())
+				},
+				FnCloseSession: func(ctx context.Context, req *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+					require.NoError(t, ctx.Err())
+					closed = req.SessionHandle.SessionId
+					return &cli_service.TCloseSessionResp{}, nil
+				},
+			},
+		}
+		assert.ErrorIs(t, c.openSession(context.Background()), context.DeadlineExceeded)
+		require.NotNil(t, closed)
+		assert.Equal(t, opened, closed)
+	})
+
+	t.Run("no session is closed when the request was rejected", func(t *testing.T) {
+		var closeCalls int
+		c := &conn{
+			cfg: config.WithDefaults(),
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					return nil, errors.New("invalid token")
+				},
+				FnCloseSession: func(ctx context.Context, req *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+					closeCalls++
+					return &cli_service.TCloseSessionResp{}, nil
+				},
+			},
+		}
+		assert.Error(t, c.openSession(context.Background()))
+		assert.Zero(t, closeCalls)
+	})
+
+	t.Run("the session is closed when its params can't be set", func(t *testing.T) {
+		var closeCalls int
+		collector := newTestCollector()
+		cfg := config.WithDefaults()
+		cfg.SessionParams = map[string]string{"ansi_mode": "false"}
+		cfg.Metrics = collector
+		c := &conn{
+			cfg: cfg,
+			client: &client.TestClient{
+				FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+					return newTestSession(1), nil
+				},
+				FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+					return nil, errors.New("unknown param")
+				},
+				FnCloseSession: func(ctx context.Context, req *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+					closeCalls++
+					return &cli_service.TCloseSessionResp{}, nil
+				},
+			},
+		}
+		assert.ErrorContains(t, c.startSession(context.Background()), "unknown param")
+		assert.Equal(t, 1, closeCalls)
+		assert.Nil(t, c.session)
+		assert.Zero(t, collector.gauges[metrics.OpenSessions])
+	})
+}
+
+func TestConn_lazySession(t *testing.T) {
+	var openCalls, closeCalls int
+	cfg := config.WithDefaults()
+	cfg.LazySession = true
+	c := &conn{
+		cfg: cfg,
+		client: &client.TestClient{
+			FnOpenSession: func(ctx context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+				openCalls++
+				return newTestSession(1), nil
+			},
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				assert.Equal(t, newTestSession(1).SessionHandle, req.SessionHandle)
+				return finishedStatement(), nil
+			},
+			FnCloseSession: func(ctx context.Context, req *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+				closeCalls++
+				return &cli_service.TCloseSessionResp{}, nil
+			},
+		},
+	}
+	assert.True(t, c.IsValid(), "a connection without a session is valid")
+	require.NoError(t, c.ResetSession(context.Background()))
+	assert.Zero(t, openCalls)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.ExecContext(context.Background(), "insert into orders values (1)", nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, openCalls, "the session is opened by the first statement")
+	require.NoError(t, c.Close())
+	assert.Equal(t, 1, closeCalls)
+
+	t.Run("a connection closed without a session doesn't open one", func(t *testing.T) {
+		openCalls, closeCalls = 0, 0
+		c := &conn{cfg: cfg, client: c.client}
+		require.NoError(t, c.Close())
+		assert.Zero(t, closeCalls)
+		_, err := c.ExecContext(context.Background(), "insert into orders values (1)", nil)
+		assert.Error(t, err)
+		assert.Zero(t, openCalls)
+	})
+}
+
+func TestConn_sessionIdleTimeout(t *testing.T) {
+	closed := make(chan *cli_service.TCloseSessionReq, 1)
+	cfg := config.WithDefaults()
+	cfg.SessionIdleTimeout = 20 * time.Millisecond
+	newConn := func() *conn {
+		return &conn{
+			session: newTestSession(1),
+			cfg:     cfg,
+			client: &client.TestClient{
+				FnCloseSession: func(ctx context.Context, req *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+					closed <- req
+					return &cli_service.TCloseSessionResp{}, nil
+				},
+			},
+		}
+	}
+
+	t.Run("the session of a connection idle in the pool is closed", func(t *testing.T) {
+		c := newConn()
+		// database/sql validates the connection when it is returned to the pool
+		assert.True(t, c.IsValid())
+		select {
+		case req := <-closed:
+			assert.Equal(t, newTestSession(1).SessionHandle, req.SessionHandle)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the idle session was not closed")
+		}
+		assert.Eventually(t, c.closed.Load, 5*time.Second, time.Millisecond)
+		assert.False(t, c.IsValid())
+		assert.ErrorIs(t, c.ResetSession(context.Background()), driver.ErrBadConn, "the pool replaces the connection")
+		require.NoError(t, c.Close())
+		assert.Empty(t, closed, "the session is closed once")
+	})
+
+	t.Run("the session of a connection taken from the pool stays open", func(t *testing.T) {
+		c := newConn()
+		assert.True(t, c.IsValid())
+		require.NoError(t, c.ResetSession(context.Background()))
+		time.Sleep(5 * cfg.SessionIdleTimeout)
+		assert.Empty(t, closed)
+		assert.False(t, c.closed.Load())
+		require.NoError(t, c.Close())
+		<-closed
+	})
+
+	t.Run("without the timeout the session of an idle connection stays open", func(t *testing.T) {
+		c := newConn()
+		c.cfg = config.WithDefaults()
+		assert.True(t, c.IsValid())
+		assert.Nil(t, c.idleTimer)
+		require.NoError(t, c.ResetSession(context.Background()))
+	})
+}
+
+func TestSetStatement(t *testing.T) {
+	assert.Equal(t, "SET `ansi_mode` = `false`;", setStatement("ansi_mode", "false"))
+	assert.Equal(t, "SET `a``b` = `c``d`;", setStatement("a`b", "c`d"))
+}
+
+func TestIsInvalidSession(t *testing.T) {
+	assert.False(t, isInvalidSession(nil))
+	assert.False(t, isInvalidSession(errors.New("table not found")))
+	assert.True(t, isInvalidSession(errors.New("Invalid SessionHandle: SessionHandle [abc]")))
+	assert.True(t, isInvalidSession(errors.Wrap(&dbsqlerr.RequestError{
+		Msg:        "thrift: invalid handle",
+		StatusCode: cli_service.TStatusCode_INVALID_HANDLE_STATUS.String(),
+	}, "failed to execute query")))
+}
+
+func TestConn_heartbeat(t *testing.T) {
+	heartbeats := make(chan *cli_service.TGetInfoReq, 10)
+	cfg := config.WithDefaults()
+	cfg.HeartbeatInterval = time.Millisecond
+	session := newTestSession(1)
+	c := &conn{
+		session: session,
+		cfg:     cfg,
+		client: &client.TestClient{
+			FnCloseSession: func(ctx context.Context, req *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+				return &cli_service.TCloseSessionResp{}, nil
+			},
+		},
+	}
+	c.startHeartbeat(&client.TestClient{
+		FnGetInfo: func(ctx context.Context, req *cli_service.TGetInfoReq) (*cli_service.TGetInfoResp, error) {
+			heartbeats <- req
+			return nil, errors.New("Invalid SessionHandle: SessionHandle [abc]")
+		},
+	})
+
+	select {
+	case req := <-heartbeats:
+		assert.Equal(t, session.SessionHandle, req.SessionHandle)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no heartbeat was sent")
+	}
+	assert.Eventually(t, c.expired.Load, 5*time.Second, time.Millisecond)
+	assert.NoError(t, c.Close())
+	assert.Nil(t, c.stopHeartbeat)
+}
@@ -0,0 +1,196 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/logger"
+)
+
+// lifecycle is shared by a connector and its standby and load balanced connectors to shut their connections down
+// together. Closing it cancels the running queries, waits for them and for the background fetches of result pages,
+// then closes the sessions of the connections still open.
+type lifecycle struct {
+	mu      sync.Mutex
+	closed  bool
+	closing chan struct{}      // closed with the lifecycle, cancels the running tasks
+	conns   map[*conn]struct{} // open connections
+	tasks   sync.WaitGroup     // running queries and background fetches
+}
+
+// lifecycles are the lifecycles with open connections, closed by Shutdown. A lifecycle is only registered while it
+// has open connections so the connectors which are never closed, e.g. of databricksDriver.Open, aren't leaked.
+var lifecycles = struct {
+	sync.Mutex
+	m map[*lifecycle]struct{}
+}{m: map[*lifecycle]struct{}{}}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{closing: make(chan struct{}), conns: map[*conn]struct{}{}}
+}
+
+// add registers an open connection, it fails once the lifecycle is closed
+func (l *lifecycle) add(c *conn) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return newDriverError(ErrConnectorClosed, nil)
+	}
+	l.conns[c] = struct{}{}
+	if len(l.conns) == 1 {
+		lifecycles.Lock()
+		lifecycles.m[l] = struct{}{}
+		lifecycles.Unlock()
+	}
+	return nil
+}
+
+// remove unregisters a closed connection
+func (l *lifecycle) remove(c *conn) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.conns[c]; !ok {
+		return
+	}
+	delete(l.conns, c)
+	if len(l.conns) == 0 {
+		lifecycles.Lock()
+		delete(lifecycles.m, l)
+		lifecycles.Unlock()
+	}
+}
+
+// begin starts a task, the returned context is canceled when the lifecycle is closed and done must be called when
+// the task is finished. It fails once the lifecycle is closed.
+func (l *lifecycle) begin(ctx context.Context) (context.Context, func(), error) {
+	if l == nil {
+		return ctx, func() {}, nil
+	}
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ctx, func() {}, newDriverError(ErrConnectorClosed, nil)
+	}
+	l.tasks.Add(1)
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-l.closing:
+			cancel()
+		case <-finished:
+		}
+	}()
+	return ctx, func() {
+		close(finished)
+		cancel()
+		l.tasks.Done()
+	}, nil
+}
+
+// setLifecycle makes the background fetches of the rows of a query stop when the connector is closed
+func setLifecycle(r driver.Rows, l *lifecycle) {
+	if rs, ok := r.(*rows); ok {
+		rs.lifecycle = l
+	}
+}
+
+// close cancels the running tasks and waits for them until ctx is done, then closes the sessions of the open
+// connections. The sessions are closed even when ctx is done, the error of ctx is returned then.
+func (l *lifecycle) close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.closing)
+	}
+	l.mu.Unlock()
+
+	var err error
+	finished := make(chan struct{})
+	go func() {
+		l.tasks.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	conns := make([]*conn, 0, len(l.conns))
+	for c := range l.conns {
+		conns = append(conns, c)
+	}
+	l.mu.Unlock()
+	for _, c := range conns {
+		if closeErr := c.closeConcurrently(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// connectorCloseTimeout bounds the wait of Close for the canceled tasks, unless the cancel grace period is longer
+var connectorCloseTimeout = 30 * time.Second
+
+// Close closes the connector: the running queries are canceled, the background fetches of result pages are
+// stopped and the sessions of the open connections are closed on the warehouse. The connections of the
+// connector can't be used afterward, new connections fail with ErrConnectorClosed. DB.Close closes the connector
+// of the DB. Close waits up to 30 seconds, or the cancel grace period if longer, for the canceled queries and
+// fetches to stop, the sessions are closed then and context.DeadlineExceeded is returned.
+func (c *connector) Close() error {
+	timeout := connectorCloseTimeout
+	if c.cfg != nil && c.cfg.CancelGracePeriod > timeout {
+		timeout = c.cfg.CancelGracePeriod
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.lifecycle.close(ctx)
+}
+
+var _ io.Closer = (*connector)(nil)
+
+// Shutdown closes the connectors of the process with open connections, see connector.Close, e.g. before exiting
+// on a deploy so the sessions of the connections aren't left open on the warehouses until they expire. Shutdown
+// waits for the running queries and background fetches to stop until ctx is done, the sessions are closed then
+// and the error of ctx is returned.
+func Shutdown(ctx context.Context) error {
+	lifecycles.Lock()
+	closing := make([]*lifecycle, 0, len(lifecycles.m))
+	for l := range lifecycles.m {
+		closing = append(closing, l)
+	}
+	lifecycles.Unlock()
+
+	errs := make(chan error, len(closing))
+	for _, l := range closing {
+		go func(l *lifecycle) {
+			errs <- l.close(ctx)
+		}(l)
+	}
+	var err error
+	for range closing {
+		if closeErr := <-errs; closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		logger.Err(err).Msg("databricks: failed to shut down")
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	newConnector := func(t *testing.T, state *callState) *connector {
+		loadTestData(t, "OpenSessionSuccess.json", &state.openSessionResp)
+		loadTestData(t, "CloseSessionSuccess.json", &state.closeSessionResp)
+		loadTestData(t, "CloseOperationSuccess.json", &state.closeOperationResp)
+		ts := getServer(state)
+		t.Cleanup(ts.Close)
+
+		r, err := url.Parse(ts.URL)
+		require.NoError(t, err)
+		port, err := strconv.Atoi(r.Port())
+		require.NoError(t, err)
+		c, err := NewConnector(
+			WithServerHostname("localhost"),
+			WithPort(port),
+			WithHTTPPath(""),
+			WithAccessToken(""),
+		)
+		require.NoError(t, err)
+		return c.(*connector)
+	}
+
+	t.Run("closing the connector cancels the running queries and closes the sessions", func(t *testing.T) {
+		state := &callState{}
+		loadTestData(t, "ExecuteStatement21.json", &state.executeStatementResp)
+		loadTestData(t, "GetOperationStatusRunning.json", &state.getOperationStatusResp)
+		loadTestData(t, "CancelOperationSuccess.json", &state.cancelOperationResp)
+		c := newConnector(t, state)
+		db := sql.OpenDB(c)
+
+		queryErr := make(chan error)
+		go func() {
+			ctx := driverctx.NewContextWithCorrelationId(context.Background(), "shutdown")
+			_, err := db.QueryContext(ctx, "select * from orders")
+			queryErr <- err
+		}()
+		// the query is running once it is polled, its operation handle is known
+		assert.Eventually(t, func() bool {
+			state.mu.Lock()
+			defer state.mu.Unlock()
+			return state.getOperationStatusCalls > 0
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, int64(1), ConnectorStats(c).ActiveQueries)
+
+		require.NoError(t, db.Close())
+		err := <-queryErr
+		assert.ErrorIs(t, err, context.Canceled)
+		state.mu.Lock()
+		assert.Equal(t, 1, state.cancelOperationCalls)
+		assert.Equal(t, 1, state.closeSessionCalls, "the session is closed once")
+		state.mu.Unlock()
+		assert.Zero(t, ConnectorStats(c).OpenSessions)
+
+		_, err = c.Connect(context.Background())
+		assert.ErrorContains(t, err, ErrConnectorClosed)
+	})
+
+	t.Run("Shutdown closes the connectors with open connections", func(t *testing.T) {
+		state := &callState{}
+		loadTestData(t, "ExecuteStatement1.json", &state.executeStatementResp)
+		c := newConnector(t, state)
+		db := sql.OpenDB(c)
+		defer db.Close()
+
+		conn, err := db.Conn(context.Background())
+		require.NoError(t, err)
+		_, err = conn.ExecContext(context.Background(), "insert into orders values (1)")
+		require.NoError(t, err)
+
+		require.NoError(t, Shutdown(context.Background()))
+		assert.Equal(t, 1, state.closeSessionCalls)
+		assert.Zero(t, ConnectorStats(c).OpenSessions)
+
+		_, err = conn.ExecContext(context.Background(), "insert into orders values (1)")
+		assert.ErrorContains(t, err, ErrConnectorClosed)
+		assert.NoError(t, conn.Close())
+		assert.Equal(t, 1, state.closeSessionCalls, "the session is closed once")
+	})
+
+	t.Run("the sessions are closed when the tasks don't stop in time", func(t *testing.T) {
+		state := &callState{}
+		c := newConnector(t, state)
+		dc, err := c.Connect(context.Background())
+		require.NoError(t, err)
+
+		_, done, err := c.lifecycle.begin(context.Background())
+		require.NoError(t, err)
+		defer done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, c.lifecycle.close(ctx), context.DeadlineExceeded)
+		assert.Equal(t, 1, state.closeSessionCalls)
+		assert.False(t, dc.(*conn).IsValid())
+	})
+
+	t.Run("Close stops waiting for the canceled tasks after a timeout", func(t *testing.T) {
+		defer func(timeout time.Duration) { connectorCloseTimeout = timeout }(connectorCloseTimeout)
+		connectorCloseTimeout = 10 * time.Millisecond
+
+		state := &callState{}
+		c := newConnector(t, state)
+		c.cfg.CancelGracePeriod = 0
+		dc, err := c.Connect(context.Background())
+		require.NoError(t, err)
+
+		// a task which doesn't stop when its context is canceled
+		ctx, done, err := c.lifecycle.begin(context.Background())
+		require.NoError(t, err)
+		defer done()
+
+		assert.ErrorIs(t, c.Close(), context.DeadlineExceeded)
+		assert.ErrorIs(t, ctx.Err(), context.Canceled, "the context of the task is canceled")
+		assert.Equal(t, 1, state.closeSessionCalls)
+		assert.False(t, dc.(*conn).IsValid())
+	})
+}
@@ -0,0 +1,140 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/databricks/databricks-sql-go/logger"
+)
+
+// SlowQuery is a query which took longer than the slow query threshold, see WithSlowQueryLog. Its duration is
+// the time spent waiting for the warehouse: the time the query was queued, the time it ran and the time spent
+// fetching its results. The time spent by the application reading the rows isn't part of it.
+type SlowQuery struct {
+	QueryId       string        // id of the query in the Query History, empty when it failed before running
+	Query         string        // text of the query, truncated to 1000 bytes
+	Duration      time.Duration // sum of the queue, execution and fetch times
+	QueueTime     time.Duration // time the query was observed waiting for capacity on the warehouse
+	ExecutionTime time.Duration // time until the query finished, after it left the queue
+	FetchTime     time.Duration // time spent fetching the result pages
+	Err           error         // error of the query, nil when it succeeded
+}
+
+// slowQueryMaxLength is the max length of the text of a slow query, longer queries are truncated
+const slowQueryMaxLength = 1000
+
+// queryTiming measures the phases of a query for the slow query log
+type queryTiming struct {
+	cfg           *config.Config
+	connId        string
+	correlationId string
+	queryId       string
+	query         string
+	start         time.Time
+	finished      time.Time
+	queuedUntil   atomic.Int64 // unix time in nanoseconds the query was last observed pending, 0 if never
+	fetch         atomic.Int64 // nanoseconds spent fetching result pages
+}
+
+type queryTimingKey struct{}
+
+// startQueryTiming starts measuring the query run with the returned context, the timing is nil when the slow
+// query log is disabled
+func (c *conn) startQueryTiming(ctx context.Context, query string) (context.Context, *queryTiming) {
+	if c.cfg.SlowQueryThreshold <= 0 {
+		return ctx, nil
+	}
+	t := &queryTiming{
+		cfg:           c.cfg,
+		connId:        c.id,
+		correlationId: driverctx.CorrelationIdFromContext(ctx),
+		query:         query,
+		start:         time.Now(),
+	}
+	return context.WithValue(ctx, queryTimingKey{}, t), t
+}
+
+// observeQueryState records the state of the query run with ctx, the query is queued while it is pending
+func observeQueryState(ctx context.Context, state cli_service.TOperationState) {
+	t, _ := ctx.Value(queryTimingKey{}).(*queryTiming)
+	if t == nil {
+		return
+	}
+	if state == cli_service.TOperationState_INITIALIZED_STATE || state == cli_service.TOperationState_PENDING_STATE {
+		t.queuedUntil.Store(time.Now().UnixNano())
+	}
+}
+
+// finish records the end of the execution of the query and its id
+func (t *queryTiming) finish(exStmtResp *cli_service.TExecuteStatementResp) {
+	if t == nil {
+		return
+	}
+	t.finished = time.Now()
+	if exStmtResp.GetOperationHandle() != nil {
+		t.queryId = client.SprintGuid(exStmtResp.OperationHandle.OperationId.GUID)
+	}
+}
+
+// addFetch adds the time spent fetching a result page
+func (t *queryTiming) addFetch(d time.Duration) {
+	if t != nil {
+		t.fetch.Add(int64(d))
+	}
+}
+
+// report passes the query to the slow query hook, or logs it, when it took longer than the threshold
+func (t *queryTiming) report(err error) {
+	if t == nil {
+		return
+	}
+	var queue time.Duration
+	if queuedUntil := t.queuedUntil.Load(); queuedUntil != 0 {
+		queue = time.Unix(0, queuedUntil).Sub(t.start)
+	}
+	sq := config.SlowQuery{
+		QueryId:       t.queryId,
+		Query:         truncateQuery(t.query),
+		QueueTime:     queue,
+		ExecutionTime: t.finished.Sub(t.start) - queue,
+		FetchTime:     time.Duration(t.fetch.Load()),
+		Err:           err,
+	}
+	sq.Duration = sq.QueueTime + sq.ExecutionTime + sq.FetchTime
+	if sq.Duration < t.cfg.SlowQueryThreshold {
+		return
+	}
+	if t.cfg.SlowQueryHook != nil {
+		t.cfg.SlowQueryHook(sq)
+		return
+	}
+	logger.WithContext(t.connId, t.correlationId, sq.QueryId).Warn().Err(err).Msgf(
+		"databricks: slow query took %s (queue %s, execution %s, fetch %s): %s",
+		sq.Duration, sq.QueueTime, sq.ExecutionTime, sq.FetchTime, sq.Query)
+}
+
+// setQueryTiming makes the rows of a query report it to the slow query log when they are closed
+func setQueryTiming(r driver.Rows, t *queryTiming) {
+	if rs, ok := r.(*rows); ok && t != nil {
+		rs.timing = t
+	}
+}
+
+// truncateQuery truncates the text of a query longer than slowQueryMaxLength on a character boundary
+func truncateQuery(query string) string {
+	if len(query) <= slowQueryMaxLength {
+		return query
+	}
+	cut := slowQueryMaxLength
+	for cut > 0 && !utf8.RuneStart(query[cut]) {
+		cut--
+	}
+	return query[:cut] + "..."
+}
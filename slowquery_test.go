@@ -0,0 +1,102 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	opHandle := &cli_service.TOperationHandle{OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}}}
+	states := []cli_service.TOperationState{
+		cli_service.TOperationState_PENDING_STATE,
+		cli_service.TOperationState_PENDING_STATE,
+		cli_service.TOperationState_RUNNING_STATE,
+		cli_service.TOperationState_RUNNING_STATE,
+		cli_service.TOperationState_FINISHED_STATE,
+	}
+	newConn := func(threshold time.Duration, reported *[]SlowQuery) *conn {
+		var polls int
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				polls = 0
+				return &cli_service.TExecuteStatementResp{Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS}, OperationHandle: opHandle}, nil
+			},
+			FnGetOperationStatus: func(ctx context.Context, req *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
+				state := states[polls]
+				polls++
+				return &cli_service.TGetOperationStatusResp{Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS}, OperationState: &state}, nil
+			},
+			FnFetchResults: func(ctx context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+				time.Sleep(20 * time.Millisecond)
+				return &cli_service.TFetchResultsResp{Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS}}, nil
+			},
+			FnCloseOperation: func(ctx context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+				return &cli_service.TCloseOperationResp{}, nil
+			},
+		}
+		cfg := config.WithDefaults()
+		cfg.PollInterval = 10 * time.Millisecond
+		WithSlowQueryLog(threshold, func(q SlowQuery) { *reported = append(*reported, q) })(cfg)
+		return &conn{id: "conn", session: getTestSession(), client: testClient, cfg: cfg}
+	}
+
+	t.Run("statements are reported with their queue and execution time", func(t *testing.T) {
+		var reported []SlowQuery
+		testConn := newConn(time.Nanosecond, &reported)
+		_, err := testConn.ExecContext(context.Background(), "update orders set total = 0", []driver.NamedValue{})
+		require.NoError(t, err)
+
+		require.Len(t, reported, 1)
+		q := reported[0]
+		assert.Equal(t, "01020304-0506-0708-090a-0b0c0d0e0f10", q.QueryId)
+		assert.Equal(t, "update orders set total = 0", q.Query)
+		assert.Greater(t, q.QueueTime, time.Duration(0))
+		assert.Greater(t, q.ExecutionTime, time.Duration(0))
+		assert.Zero(t, q.FetchTime)
+		assert.Equal(t, q.QueueTime+q.ExecutionTime, q.Duration)
+		assert.NoError(t, q.Err)
+	})
+
+	t.Run("queries are reported with their fetch time when their rows are closed", func(t *testing.T) {
+		var reported []SlowQuery
+		testConn := newConn(time.Nanosecond, &reported)
+		res, err := testConn.QueryContext(context.Background(), "select * from orders", []driver.NamedValue{})
+		require.NoError(t, err)
+		r := res.(*rows)
+		_, err = r.fetchPage(context.Background(), cli_service.TFetchOrientation_FETCH_NEXT)
+		require.NoError(t, err)
+		assert.Empty(t, reported, "the query is reported when its rows are closed")
+
+		require.NoError(t, r.Close())
+		require.NoError(t, r.Close())
+		require.Len(t, reported, 1)
+		assert.GreaterOrEqual(t, reported[0].FetchTime, 20*time.Millisecond)
+		assert.Equal(t, reported[0].QueueTime+reported[0].ExecutionTime+reported[0].FetchTime, reported[0].Duration)
+	})
+
+	t.Run("queries faster than the threshold are not reported", func(t *testing.T) {
+		var reported []SlowQuery
+		testConn := newConn(time.Hour, &reported)
+		_, err := testConn.ExecContext(context.Background(), "update orders set total = 0", []driver.NamedValue{})
+		require.NoError(t, err)
+		assert.Empty(t, reported)
+	})
+
+	t.Run("long queries are truncated", func(t *testing.T) {
+		query := "select " + strings.Repeat("é", 600)
+		// the 1000th byte is in the middle of a character
+		truncated := truncateQuery(query)
+		assert.Len(t, truncated, 1000-1+len("..."))
+		assert.True(t, strings.HasPrefix(query, strings.TrimSuffix(truncated, "...")))
+		assert.Equal(t, "select 1", truncateQuery("select 1"))
+	})
+}
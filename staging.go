@@ -0,0 +1,237 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/logger"
+	"github.com/pkg/errors"
+)
+
+// stagingOperation is the file transfer returned by the server for a PUT, GET or REMOVE statement
+type stagingOperation struct {
+	operation    string
+	presignedURL string
+	headers      map[string]string
+	localFile    string
+}
+
+// isStagingStatement returns true when the first keyword of query is PUT, GET or REMOVE
+func isStagingStatement(query string) bool {
+	start := -1
+	scanSQL(query, func(i int) {
+		if start < 0 && !isSpace(query[i]) {
+			start = i
+		}
+	})
+	if start < 0 {
+		return false
+	}
+	end := start
+	for end < len(query) && (query[end] >= 'a' && query[end] <= 'z' || query[end] >= 'A' && query[end] <= 'Z') {
+		end++
+	}
+	switch strings.ToUpper(query[start:end]) {
+	case "PUT", "GET", "REMOVE":
+		return true
+	}
+	return false
+}
+
+// execStagingOperation reads the file transfer returned by a staging statement and runs it with the presigned URL
+// of the server. Local files must be in the paths allowed by driverctx.NewContextWithStagingInfo, the reader and
+// writer of the context are used instead of the local file when they are set.
+func (c *conn) execStagingOperation(ctx context.Context, exStmtResp *cli_service.TExecuteStatementResp) (driver.Result, error) {
+	corrId := driverctx.CorrelationIdFromContext(ctx)
+	log := logger.WithContext(c.id, corrId, client.SprintGuid(exStmtResp.GetOperationHandle().GetOperationId().GetGUID()))
+
+	rows := NewRows(c.id, corrId, c.client, exStmtResp.OperationHandle, c.cfg, exStmtResp.DirectResults)
+	op, err := readStagingOperation(rows)
+	if err1 := rows.Close(); err1 != nil {
+		log.Err(err1).Msg("databricks: failed to close operation after executing staging statement")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug().Msgf("databricks: staging %s of %s", op.operation, op.localFile)
+	switch strings.ToUpper(op.operation) {
+	case "PUT":
+		if r, size := driverctx.StagingReaderFromContext(ctx); r != nil {
+			err = c.stagingUpload(ctx, op, r, size)
+		} else {
+			err = c.stagingPut(ctx, op, driverctx.StagingPathsFromContext(ctx))
+		}
+	case "GET":
+		if w := driverctx.StagingWriterFromContext(ctx); w != nil {
+			err = c.stagingDownload(ctx, op, w)
+		} else {
+			err = c.stagingGet(ctx, op, driverctx.StagingPathsFromContext(ctx))
+		}
+	case "REMOVE":
+		err = c.stagingRequest(ctx, op, http.MethodDelete, nil, 0, nil)
+	default:
+		err = newDriverError(fmt.Sprintf("databricks: unknown staging operation %s", op.operation), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result{}, nil
+}
+
+// readStagingOperation reads the operation, presignedUrl, headers and localFile columns of the row of a staging statement
+func readStagingOperation(rows driver.Rows) (*stagingOperation, error) {
+	columns := rows.Columns()
+	values := make([]driver.Value, len(columns))
+	if err := rows.Next(values); err != nil {
+		return nil, wrapErr(err, "failed to read staging operation")
+	}
+
+	op := &stagingOperation{}
+	for i, column := range columns {
+		value, _ := values[i].(string)
+		switch column {
+		case "operation":
+			op.operation = value
+		case "presignedUrl":
+			op.presignedURL = value
+		case "localFile":
+			op.localFile = value
+		case "headers":
+			if value != "" {
+				if err := json.Unmarshal([]byte(value), &op.headers); err != nil {
+					return nil, newDriverError("databricks: invalid staging headers", err)
+				}
+			}
+		}
+	}
+	if op.operation == "" || op.presignedURL == "" {
+		return nil, newDriverError("databricks: statement did not return a staging operation", nil)
+	}
+	return op, nil
+}
+
+// stagingPut uploads the local file of op
+func (c *conn) stagingPut(ctx context.Context, op *stagingOperation, allowedPaths []string) error {
+	if err := checkStagingPath(op.localFile, allowedPaths); err != nil {
+		return err
+	}
+	f, err := os.Open(op.localFile)
+	if err != nil {
+		return newDriverError("databricks: failed to open staging file", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return newDriverError("databricks: failed to open staging file", err)
+	}
+	return c.stagingUpload(ctx, op, f, info.Size())
+}
+
+// stagingUpload uploads size bytes of r
+func (c *conn) stagingUpload(ctx context.Context, op *stagingOperation, r io.Reader, size int64) error {
+	if size == 0 {
+		r = http.NoBody
+	}
+	return c.stagingRequest(ctx, op, http.MethodPut, r, size, nil)
+}
+
+// stagingGet downloads the file of op to its local file
+func (c *conn) stagingGet(ctx context.Context, op *stagingOperation, allowedPaths []string) error {
+	if err := checkStagingPath(op.localFile, allowedPaths); err != nil {
+		return err
+	}
+	return c.stagingRequest(ctx, op, http.MethodGet, nil, 0, func(body io.Reader) error {
+		f, err := os.Create(op.localFile)
+		if err != nil {
+			return newDriverError("databricks: failed to create staging file", err)
+		}
+		if _, err = io.Copy(f, body); err != nil {
+			f.Close()
+			return wrapErr(err, "failed to download staging file")
+		}
+		if err = f.Close(); err != nil {
+			return newDriverError("databricks: failed to write staging file", err)
+		}
+		return nil
+	})
+}
+
+// stagingDownload writes the downloaded file of op to w
+func (c *conn) stagingDownload(ctx context.Context, op *stagingOperation, w io.Writer) error {
+	return c.stagingRequest(ctx, op, http.MethodGet, nil, 0, func(body io.Reader) error {
+		if _, err := io.Copy(w, body); err != nil {
+			return wrapErr(err, "failed to download staging file")
+		}
+		return nil
+	})
+}
+
+// stagingRequest sends a request to the presigned URL of op and passes the body of the response to read
+func (c *conn) stagingRequest(ctx context.Context, op *stagingOperation, method string, body io.Reader, size int64, read func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, method, op.presignedURL, body)
+	if err != nil {
+		return newDriverError("databricks: invalid staging URL", err)
+	}
+	req.ContentLength = size
+	for k, v := range op.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.CloudFetchClient(c.cfg).Do(req)
+	if err != nil {
+		return wrapErrf(err, "staging %s request failed", op.operation)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var cause error
+		if msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024)); len(strings.TrimSpace(string(msg))) > 0 {
+			cause = errors.New(strings.TrimSpace(string(msg)))
+		}
+		return errors.WithStack(&dbsqlerr.RequestError{
+			Msg:            fmt.Sprintf("databricks: staging %s request failed with HTTP %d", op.operation, resp.StatusCode),
+			HTTPStatusCode: resp.StatusCode,
+			ConnectionId:   c.id,
+			CorrelationId:  driverctx.CorrelationIdFromContext(ctx),
+			Err:            cause,
+		})
+	}
+	if read != nil {
+		return read(resp.Body)
+	}
+	return nil
+}
+
+// checkStagingPath returns an error when localFile is not in one of the allowed paths
+func checkStagingPath(localFile string, allowedPaths []string) error {
+	if localFile == "" {
+		return newDriverError("databricks: staging operation has no local file", nil)
+	}
+	file, err := filepath.Abs(localFile)
+	if err != nil {
+		return newDriverError(ErrStagingPathNotAllowed, err)
+	}
+	for _, path := range allowedPaths {
+		base, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(base, file)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return newDriverError(ErrStagingPathNotAllowed, nil)
+}
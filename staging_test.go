@@ -0,0 +1,199 @@
+package dbsql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/databricks/databricks-sql-go/internal/client"
+	"github.com/databricks/databricks-sql-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsStagingStatement(t *testing.T) {
+	tests := map[string]bool{
+		"PUT '/tmp/data.csv' INTO '/Volumes/main/default/files/data.csv' OVERWRITE": true,
+		"get '/Volumes/main/default/files/data.csv' TO '/tmp/data.csv'":             true,
+		"  -- remove the file\n REMOVE '/Volumes/main/default/files/data.csv'":      true,
+		"/* PUT */ select 1": false,
+		"PUTS":               false,
+		"select 'PUT'":       false,
+		"":                   false,
+	}
+	for query, want := range tests {
+		assert.Equal(t, want, isStagingStatement(query), query)
+	}
+}
+
+func TestCheckStagingPath(t *testing.T) {
+	dir := t.TempDir()
+	allowed := []string{dir}
+
+	assert.NoError(t, checkStagingPath(filepath.Join(dir, "data.csv"), allowed))
+	assert.NoError(t, checkStagingPath(filepath.Join(dir, "nested", "data.csv"), allowed))
+	assert.Error(t, checkStagingPath(filepath.Join(dir, "..", "data.csv"), allowed))
+	assert.Error(t, checkStagingPath(dir+"-other/data.csv", allowed))
+	assert.Error(t, checkStagingPath(filepath.Join(dir, "data.csv"), nil))
+	assert.Error(t, checkStagingPath("", allowed))
+}
+
+// stagingResponse returns the response of a staging statement with its operation in the direct results
+func stagingResponse(operation, presignedURL, headers, localFile string) *cli_service.TExecuteStatementResp {
+	column := func(name string) *cli_service.TColumnDesc {
+		return &cli_service.TColumnDesc{
+			ColumnName: name,
+			TypeDesc: &cli_service.TTypeDesc{
+				Types: []*cli_service.TTypeEntry{{PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: cli_service.TTypeId_STRING_TYPE}}},
+			},
+		}
+	}
+	value := func(v string) *cli_service.TColumn {
+		return &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: []string{v}}}
+	}
+	noMoreRows := false
+	return &cli_service.TExecuteStatementResp{
+		Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+		OperationHandle: &cli_service.TOperationHandle{
+			OperationId: &cli_service.THandleIdentifier{GUID: []byte{1, 2, 3, 4, 2, 23, 4, 2, 3, 1, 2, 3, 4, 4, 223, 34}},
+		},
+		DirectResults: &cli_service.TSparkDirectResults{
+			OperationStatus: &cli_service.TGetOperationStatusResp{
+				Status:         &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+				OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+			},
+			ResultSetMetadata: &cli_service.TGetResultSetMetadataResp{
+				Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+				Schema: &cli_service.TTableSchema{
+					Columns: []*cli_service.TColumnDesc{column("operation"), column("presignedUrl"), column("headers"), column("localFile")},
+				},
+			},
+			ResultSet: &cli_service.TFetchResultsResp{
+				Status:      &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+				HasMoreRows: &noMoreRows,
+				Results: &cli_service.TRowSet{
+					Columns: []*cli_service.TColumn{value(operation), value(presignedURL), value(headers), value(localFile)},
+				},
+			},
+		},
+	}
+}
+
+func TestConn_execStagingOperation(t *testing.T) {
+	var uploaded []byte
+	var uploadHeader string
+	var removed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			uploaded, _ = io.ReadAll(r.Body)
+			uploadHeader = r.Header.Get("x-amz-server-side-encryption")
+		case http.MethodGet:
+			_, _ = w.Write([]byte("a,b\n1,2\n"))
+		case http.MethodDelete:
+			removed = true
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	localFile := filepath.Join(dir, "data.csv")
+	ctx := driverctx.NewContextWithStagingInfo(context.Background(), []string{dir})
+
+	newConn := func(resp *cli_service.TExecuteStatementResp, closeCount *int) *conn {
+		return &conn{
+			session: getTestSession(),
+			client: &client.TestClient{
+				FnExecuteStatement: func(ctx context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+					return resp, nil
+				},
+				FnCloseOperation: func(ctx context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+					*closeCount++
+					return &cli_service.TCloseOperationResp{}, nil
+				},
+			},
+			cfg: config.WithDefaults(),
+		}
+	}
+
+	t.Run("PUT uploads the local file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(localFile, []byte("x,y\n3,4\n"), 0600))
+		var closeCount int
+		c := newConn(stagingResponse("PUT", server.URL, `{"x-amz-server-side-encryption":"AES256"}`, localFile), &closeCount)
+
+		_, err := c.ExecContext(ctx, "PUT '"+localFile+"' INTO '/Volumes/main/default/files/data.csv'", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "x,y\n3,4\n", string(uploaded))
+		assert.Equal(t, "AES256", uploadHeader)
+		assert.Equal(t, 1, closeCount)
+	})
+
+	t.Run("GET downloads to the local file", func(t *testing.T) {
+		var closeCount int
+		c := newConn(stagingResponse("GET", server.URL, "", localFile), &closeCount)
+
+		_, err := c.ExecContext(ctx, "GET '/Volumes/main/default/files/data.csv' TO '"+localFile+"'", nil)
+		require.NoError(t, err)
+		content, err := os.ReadFile(localFile)
+		require.NoError(t, err)
+		assert.Equal(t, "a,b\n1,2\n", string(content))
+	})
+
+	t.Run("PUT and GET use the reader and writer of the context", func(t *testing.T) {
+		var closeCount int
+		c := newConn(stagingResponse("PUT", server.URL, "", "__input_stream__"), &closeCount)
+		_, err := c.ExecContext(driverctx.NewContextWithStagingReader(context.Background(), strings.NewReader("5,6\n"), 4), "PUT '__input_stream__' INTO '/Volumes/main/default/files/data.csv'", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "5,6\n", string(uploaded))
+
+		var buf bytes.Buffer
+		c = newConn(stagingResponse("GET", server.URL, "", "__input_stream__"), &closeCount)
+		_, err = c.ExecContext(driverctx.NewContextWithStagingWriter(context.Background(), &buf), "GET '/Volumes/main/default/files/data.csv' TO '__input_stream__'", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "a,b\n1,2\n", buf.String())
+	})
+
+	t.Run("REMOVE deletes the file", func(t *testing.T) {
+		var closeCount int
+		c := newConn(stagingResponse("REMOVE", server.URL, "", ""), &closeCount)
+
+		_, err := c.ExecContext(context.Background(), "REMOVE '/Volumes/main/default/files/data.csv'", nil)
+		require.NoError(t, err)
+		assert.True(t, removed)
+	})
+
+	t.Run("local files outside of the allowed paths are rejected", func(t *testing.T) {
+		var closeCount int
+		c := newConn(stagingResponse("GET", server.URL, "", localFile), &closeCount)
+
+		_, err := c.ExecContext(context.Background(), "GET '/Volumes/main/default/files/data.csv' TO '"+localFile+"'", nil)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, ErrStagingPathNotAllowed)
+		assert.True(t, errors.Is(err, dbsqlerr.ErrDriver))
+		assert.Equal(t, 1, closeCount)
+	})
+
+	t.Run("HTTP errors are returned", func(t *testing.T) {
+		forbidden := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer forbidden.Close()
+		var closeCount int
+		c := newConn(stagingResponse("REMOVE", forbidden.URL, "", ""), &closeCount)
+
+		_, err := c.ExecContext(context.Background(), "REMOVE '/Volumes/main/default/files/data.csv'", nil)
+		var reqErr *dbsqlerr.RequestError
+		require.True(t, errors.As(err, &reqErr))
+		assert.Equal(t, http.StatusForbidden, reqErr.HTTPStatusCode)
+	})
+}
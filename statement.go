@@ -1,39 +1,53 @@
 package dbsql
 
 import (
+	"container/list"
 	"context"
 	"database/sql/driver"
-	"errors"
+	"sync"
 )
 
 type stmt struct {
-	conn  *conn
-	query string
+	conn     *conn
+	query    string
+	numInput int
+}
+
+// newStmt parses the parameter markers of query to prepare a statement
+func newStmt(c *conn, query string) *stmt {
+	positional, named := parseParameterMarkers(query)
+	numInput := positional
+	if named {
+		// the sql package can't check the number of named arguments
+		numInput = -1
+	}
+	return &stmt{conn: c, query: query, numInput: numInput}
 }
 
 // Close closes the statement.
+// Prepared statements hold no server resources and stay in the connection's cache, so Close is a no-op.
 func (s *stmt) Close() error {
-	// no-op
 	return nil
 }
 
-// NumInput returns -1 and the sql package will not sanity check Exec or Query argument counts.
+// NumInput returns the number of positional parameter markers of the query, so the sql package checks
+// the number of arguments. It returns -1 when the query has named parameter markers.
 func (s *stmt) NumInput() int {
-	return -1
+	return s.numInput
 }
 
-// Exec is not implemented.
+// Exec executes a query that doesn't return rows, such as an INSERT or UPDATE.
 //
 // Deprecated: Use StmtExecContext instead.
 func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
-	return nil, errors.New(ErrNotImplemented)
+	return s.ExecContext(context.Background(), toNamedValues(args))
 }
 
-// Query is not implemented.
+// Query executes a query that may return rows, such as a SELECT.
 //
 // Deprecated: Use StmtQueryContext instead.
 func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
-	return nil, errors.New(ErrNotImplemented)
+	return s.QueryContext(context.Background(), toNamedValues(args))
 }
 
 // ExecContext executes a query that doesn't return rows, such
@@ -54,4 +68,91 @@ func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driv
 	return s.conn.QueryContext(ctx, s.query, args)
 }
 
+func toNamedValues(args []driver.Value) []driver.NamedValue {
+	namedArgs := make([]driver.NamedValue, len(args))
+	for i := range args {
+		namedArgs[i] = driver.NamedValue{Ordinal: i + 1, Value: args[i]}
+	}
+	return namedArgs
+}
+
+// parseParameterMarkers returns the number of positional parameter markers (?) of query and whether it
+// has named parameter markers (:name). Markers in string literals, quoted identifiers and comments are ignored.
+func parseParameterMarkers(query string) (positional int, named bool) {
+	scanSQL(query, func(i int) {
+		switch query[i] {
+		case '?':
+			positional++
+		case ':':
+			if namedMarkerAt(query, i) != "" {
+				named = true
+			}
+		}
+	})
+	return positional, named
+}
+
+// namedMarkerAt returns the name of the named parameter marker at index i of query, empty if there is none
+func namedMarkerAt(query string, i int) string {
+	// not a :: cast
+	isCast := (i > 0 && query[i-1] == ':') || (i+1 < len(query) && query[i+1] == ':')
+	if query[i] != ':' || isCast || i+1 >= len(query) || !isIdentifierStart(query[i+1]) {
+		return ""
+	}
+	end := i + 1
+	for end < len(query) && isIdentifierChar(query[end]) {
+		end++
+	}
+	return query[i+1 : end]
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// stmtCache is a LRU cache of the statements prepared on a connection, keyed by query
+type stmtCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached statement of query, or prepares it with prepare and caches it
+func (c *stmtCache) get(query string, prepare func() *stmt) *stmt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[query]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*stmt)
+	}
+
+	s := prepare()
+	c.entries[query] = c.order.PushFront(s)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*stmt).query)
+	}
+	return s
+}
+
+// len returns the number of cached statements
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
 var _ driver.Stmt = (*stmt)(nil)
+var _ driver.StmtExecContext = (*stmt)(nil)
+var _ driver.StmtQueryContext = (*stmt)(nil)
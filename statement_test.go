@@ -3,6 +3,7 @@ package dbsql
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/databricks/databricks-sql-go/internal/cli_service"
 	"github.com/databricks/databricks-sql-go/internal/client"
@@ -23,37 +24,57 @@ func TestStmt_Close(t *testing.T) {
 }
 
 func TestStmt_NumInput(t *testing.T) {
-	t.Run("NumInput is not applicable", func(t *testing.T) {
-		testStmt := stmt{
-			conn:  &conn{},
-			query: "query string",
+	t.Run("NumInput returns the number of positional parameters", func(t *testing.T) {
+		cases := map[string]int{
+			"select 1":                                    0,
+			"select * from t where a = ? and b = ?":       2,
+			"select '?', `a?`, \"?\" from t where a = ?":  1,
+			"select 'it\\'s ?' -- why?\nwhere a = ?":      1,
+			"select /* ? */ a::string from t where b = ?": 1,
+			"select * from t where a = :a and b = ?":      -1,
+			"select raw:store from t":                     -1,
+		}
+		for query, numInput := range cases {
+			testStmt := newStmt(&conn{}, query)
+			assert.Equal(t, numInput, testStmt.NumInput(), query)
 		}
-		numInput := testStmt.NumInput()
-		assert.Equal(t, -1, numInput)
 	})
 }
 
 func TestStmt_Exec(t *testing.T) {
-	t.Run("Exec is not implemented", func(t *testing.T) {
-		testStmt := stmt{
-			conn:  &conn{},
-			query: "query string",
+	t.Run("Exec sends positional parameters", func(t *testing.T) {
+		var req *cli_service.TExecuteStatementReq
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				req = r
+				return nil, errors.New("failed")
+			},
 		}
-		res, err := testStmt.Exec([]driver.Value{})
+		session := getTestSession()
+		session.ServerProtocolVersion = cli_service.TProtocolVersion_SPARK_CLI_SERVICE_PROTOCOL_V8
+		testStmt := newStmt(&conn{session: session, client: testClient, cfg: config.WithDefaults()}, "insert into t values (?)")
+		res, err := testStmt.Exec([]driver.Value{int64(5)})
 		assert.Nil(t, res)
 		assert.Error(t, err)
+		assert.Equal(t, int32(1), req.Parameters[0].GetOrdinal())
+		assert.Equal(t, "5", req.Parameters[0].GetValue().GetStringValue())
 	})
 }
 
 func TestStmt_Query(t *testing.T) {
-	t.Run("Query is not implemented", func(t *testing.T) {
-		testStmt := stmt{
-			conn:  &conn{},
-			query: "query string",
+	t.Run("Query runs the query", func(t *testing.T) {
+		var savedQueryString string
+		testClient := &client.TestClient{
+			FnExecuteStatement: func(ctx context.Context, r *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+				savedQueryString = r.Statement
+				return nil, errors.New("failed")
+			},
 		}
+		testStmt := newStmt(&conn{session: getTestSession(), client: testClient, cfg: config.WithDefaults()}, "select 1")
 		res, err := testStmt.Query([]driver.Value{})
 		assert.Nil(t, res)
 		assert.Error(t, err)
+		assert.Equal(t, "select 1", savedQueryString)
 	})
 }
 
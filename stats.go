@@ -0,0 +1,63 @@
+package dbsql
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/metrics"
+)
+
+// DriverStats is a snapshot of the statistics of the connections of the driver, e.g. for health endpoints or to
+// debug connection leaks. Unlike sql.DBStats it covers the sessions on the warehouse and the queries they run.
+type DriverStats struct {
+	OpenSessions     int64         // sessions open on the warehouse, one per connection of the pools
+	ActiveQueries    int64         // queries running, until their results can be read
+	Queries          int64         // queries run since the start
+	RowsFetched      int64         // rows of the result pages received
+	BytesDownloaded  int64         // bytes of the result files downloaded with cloud fetch
+	Retries          int64         // retried requests and cloud fetch downloads
+	CacheHits        int64         // queries answered from the result cache
+	CacheMisses      int64         // cacheable queries run on the warehouse
+	AvgQueryDuration time.Duration // average time the queries took to run, until their results could be read
+	AvgFetchDuration time.Duration // average time taken to fetch a result page
+}
+
+// Stats returns the statistics of the connections of all the connectors of the process, opened with sql.Open or
+// NewConnector.
+func Stats() DriverStats {
+	return newDriverStats(metrics.Global().Snapshot())
+}
+
+// ConnectorStats returns the statistics of the connections of a connector created with NewConnector. The
+// statistics of another connector are zero.
+func ConnectorStats(c driver.Connector) DriverStats {
+	dbc, ok := c.(*connector)
+	if !ok || dbc.stats == nil {
+		return DriverStats{}
+	}
+	return newDriverStats(dbc.stats.Snapshot())
+}
+
+func newDriverStats(snapshot metrics.Snapshot) DriverStats {
+	queries, fetches := snapshot.Histograms[metrics.QueryDuration], snapshot.Histograms[metrics.FetchDuration]
+	return DriverStats{
+		OpenSessions:     int64(snapshot.Gauges[metrics.OpenSessions]),
+		ActiveQueries:    int64(snapshot.Gauges[metrics.ActiveQueries]),
+		Queries:          queries.Count,
+		RowsFetched:      int64(snapshot.Counters[metrics.RowsFetched]),
+		BytesDownloaded:  int64(snapshot.Counters[metrics.BytesDownloaded]),
+		Retries:          int64(snapshot.Counters[metrics.Retries]),
+		CacheHits:        int64(snapshot.Counters[metrics.CacheHits]),
+		CacheMisses:      int64(snapshot.Counters[metrics.CacheMisses]),
+		AvgQueryDuration: average(queries),
+		AvgFetchDuration: average(fetches),
+	}
+}
+
+// average returns the average of the observations of a histogram of durations in seconds
+func average(summary metrics.Summary) time.Duration {
+	if summary.Count == 0 {
+		return 0
+	}
+	return time.Duration(summary.Sum / float64(summary.Count) * float64(time.Second))
+}
@@ -0,0 +1,97 @@
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	state := &callState{}
+	loadTestData(t, "OpenSessionSuccess.json", &state.openSessionResp)
+	loadTestData(t, "CloseSessionSuccess.json", &state.closeSessionResp)
+	loadTestData(t, "CloseOperationSuccess.json", &state.closeOperationResp)
+	loadTestData(t, "ExecuteStatement1.json", &state.executeStatementResp)
+	ts := getServer(state)
+	defer ts.Close()
+
+	r, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(r.Port())
+	require.NoError(t, err)
+
+	collector := newTestCollector()
+	connector, err := NewConnector(
+		WithServerHostname("localhost"),
+		WithPort(port),
+		WithHTTPPath(""),
+		WithAccessToken(""),
+		WithMetricsCollector(collector),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, DriverStats{}, ConnectorStats(connector))
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxIdleConns(1)
+	for i := 0; i < 3; i++ {
+		_, err = db.ExecContext(context.Background(), "insert into orders values (1)")
+		require.NoError(t, err)
+	}
+
+	stats := ConnectorStats(connector)
+	assert.Equal(t, int64(1), stats.OpenSessions)
+	assert.Zero(t, stats.ActiveQueries)
+	assert.Equal(t, int64(3), stats.Queries)
+	assert.Greater(t, stats.AvgQueryDuration, time.Duration(0))
+	// the metrics are still passed to the collector of the connector
+	assert.Equal(t, float64(1), collector.gauges[metrics.OpenSessions])
+
+	// the global stats cover the connectors of the other tests as well
+	global := Stats()
+	assert.GreaterOrEqual(t, global.Queries, stats.Queries)
+
+	require.NoError(t, db.Close())
+	assert.Zero(t, ConnectorStats(connector).OpenSessions)
+	assert.Equal(t, DriverStats{}, ConnectorStats(nil))
+}
+
+func TestNewDriverStats(t *testing.T) {
+	s := metrics.NewStats(nil)
+	s.Gauge(metrics.OpenSessions, 2)
+	s.Gauge(metrics.ActiveQueries, 1)
+	s.Counter(metrics.RowsFetched, 100)
+	s.Counter(metrics.BytesDownloaded, 2048)
+	s.Counter(metrics.Retries, 3)
+	s.Counter(metrics.CacheHits, 4)
+	s.Counter(metrics.CacheMisses, 5)
+	s.Histogram(metrics.QueryDuration, 1)
+	s.Histogram(metrics.QueryDuration, 2)
+	s.Histogram(metrics.FetchDuration, 0.25)
+
+	assert.Equal(t, DriverStats{
+		OpenSessions:     2,
+		ActiveQueries:    1,
+		Queries:          2,
+		RowsFetched:      100,
+		BytesDownloaded:  2048,
+		Retries:          3,
+		CacheHits:        4,
+		CacheMisses:      5,
+		AvgQueryDuration: 1500 * time.Millisecond,
+		AvgFetchDuration: 250 * time.Millisecond,
+	}, newDriverStats(s.Snapshot()))
+
+	// the metrics are recorded in the parent stats
+	child := metrics.NewStats(s)
+	child.Counter(metrics.Retries, 1)
+	assert.Equal(t, float64(1), child.Snapshot().Counters[metrics.Retries])
+	assert.Equal(t, float64(4), s.Snapshot().Counters[metrics.Retries])
+}
@@ -0,0 +1,71 @@
+package dbsql
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+)
+
+// queryTagsConf is the statement conf with the tags of a query, shown in the Query History
+const queryTagsConf = "query_tags"
+
+// correlationIdTag is the query tag with the correlation id of the context of a query
+const correlationIdTag = "correlation_id"
+
+// statementTags returns the tags of the queries run with ctx: the tags of the connector, overridden by the statement
+// tags of ctx, and the correlation id of ctx unless a tag has the same key
+func statementTags(ctx context.Context, connectorTags map[string]string) map[string]string {
+	tags := driverctx.StatementTagsFromContext(ctx)
+	corrId := driverctx.CorrelationIdFromContext(ctx)
+	_, hasCorrId := tags[correlationIdTag]
+	if len(connectorTags) == 0 && (corrId == "" || hasCorrId) {
+		return tags
+	}
+	merged := make(map[string]string, len(connectorTags)+len(tags)+1)
+	for k, v := range connectorTags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	if _, ok := merged[correlationIdTag]; corrId != "" && !ok {
+		merged[correlationIdTag] = corrId
+	}
+	return merged
+}
+
+var queryTagsEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`, `:`, `\:`)
+
+// formatQueryTags formats tags as the query_tags conf, e.g. "team:growth,workload:batch". Tags are sorted
+// by key, and the backslashes, commas and colons of keys and values are escaped with a backslash.
+func formatQueryTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(queryTagsEscaper.Replace(k))
+		if v := tags[k]; v != "" {
+			sb.WriteByte(':')
+			sb.WriteString(queryTagsEscaper.Replace(v))
+		}
+	}
+	return sb.String()
+}
+
+// timeoutSeconds returns the query timeout sent to the server, timeouts under a second are rounded up
+func timeoutSeconds(timeout time.Duration) int64 {
+	if timeout <= 0 {
+		return 0
+	}
+	return int64((timeout + time.Second - 1) / time.Second)
+}
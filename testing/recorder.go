@@ -0,0 +1,418 @@
+package dbsqltesting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/databricks/databricks-sql-go/internal/cli_service"
+	"github.com/pkg/errors"
+)
+
+// Mode is whether a Recorder records or replays the Thrift traffic
+type Mode int
+
+const (
+	// ModeRecord sends the requests to the warehouse and records them with their responses
+	ModeRecord Mode = iota
+	// ModeReplay answers the requests with the recorded responses, without a warehouse
+	ModeReplay
+)
+
+// redacted replaces the secrets in the recordings
+const redacted = "REDACTED"
+
+// Recorder records the Thrift requests of the driver and their responses to a golden file, and replays them,
+// for deterministic tests of code running statements on a warehouse. The requests are recorded against a
+// warehouse once, the tests then replay the golden file in CI:
+//
+//	recorder, err := dbsqltesting.NewRecorder("testdata/orders.json", dbsqltesting.ModeReplay)
+//	defer recorder.Close()
+//
+//	connector, err := dbsql.NewConnector(
+//		dbsql.WithServerHostname(host),
+//		dbsql.WithHTTPPath(httpPath),
+//		dbsql.WithAccessToken(token),
+//		dbsql.WithTransportWrapper(recorder.Wrap),
+//	)
+//
+// The golden file is a JSON array of the Thrift calls with their request and response, in order. HTTP headers,
+// and with them the credentials, are not recorded. The secrets of the session and operation handles, the presigned
+// URLs of the cloud fetch results and the strings passed to Redact are replaced with REDACTED. The values of the
+// results are recorded as they are.
+//
+// A replay answers the nth request with the nth recorded response, the requests must be the same calls in the
+// same order, ExecuteStatement requests with the same statements. Record with the binary Thrift protocol, which
+// is the default, and without cloud fetch, whose downloads are not Thrift calls and are not recorded.
+type Recorder struct {
+	path string
+	mode Mode
+
+	mu      sync.Mutex
+	calls   []*call
+	next    int
+	secrets []string
+}
+
+// call is a recorded Thrift call
+type call struct {
+	Method   string          `json:"method"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// NewRecorder returns a recorder of the golden file at path. In ModeReplay the recorded calls are read from the
+// file, in ModeRecord they are written to the file by Close.
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+	if mode == ModeReplay {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "dbsqltesting: failed to read recording")
+		}
+		if err := json.Unmarshal(b, &r.calls); err != nil {
+			return nil, errors.Wrapf(err, "dbsqltesting: invalid recording %s", path)
+		}
+	}
+	return r, nil
+}
+
+// Redact replaces secrets, e.g. passwords in statements, with REDACTED in the recording
+func (r *Recorder) Redact(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets = append(r.secrets, secrets...)
+}
+
+// Wrap returns the transport recording or replaying the requests sent to base, see dbsql.WithTransportWrapper
+func (r *Recorder) Wrap(base http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if r.mode == ModeReplay {
+			return r.replay(req)
+		}
+		return r.record(base, req)
+	})
+}
+
+// Close writes the recorded calls to the golden file in ModeRecord
+func (r *Recorder) Close() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := json.MarshalIndent(r.calls, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "dbsqltesting: failed to encode recording")
+	}
+	return errors.Wrap(os.WriteFile(r.path, b, 0600), "dbsqltesting: failed to write recording")
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (r *Recorder) record(base http.RoundTripper, req *http.Request) (*http.Response, error) {
+	if !isThrift(req) {
+		return base.RoundTrip(req)
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	req2 := req.Clone(req.Context())
+	req2.Body = io.NopCloser(bytes.NewReader(body))
+	// the responses are recorded uncompressed
+	req2.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := base.RoundTrip(req2)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		// failed requests are retried by the driver, only the calls which got a response are recorded
+		return resp, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	method, args, _, err := decodeRequest(body)
+	if err != nil {
+		return nil, err
+	}
+	result, err := decodeResponse(method, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := &call{Method: method}
+	if c.Request, err = r.encode(args); err != nil {
+		return nil, err
+	}
+	if c.Response, err = r.encode(result); err != nil {
+		return nil, err
+	}
+	r.calls = append(r.calls, c)
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	if !isThrift(req) {
+		msg := fmt.Sprintf("dbsqltesting: request to %s can't be replayed, only Thrift calls are recorded", req.URL.Redacted())
+		return newResponse(req, http.StatusBadRequest, http.Header{}, []byte(msg)), nil
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	method, args, seqId, err := decodeRequest(body)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.replayCall(method, args)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := encodeResponse(method, seqId, result)
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(req, http.StatusOK, http.Header{"Content-Type": {"application/x-thrift"}}, respBody), nil
+}
+
+// replayCall returns the recorded result of the next call, or a result with the error status when the call is
+// not the recorded one, which the driver doesn't retry
+func (r *Recorder) replayCall(method string, args thrift.TStruct) (thrift.TStruct, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next >= len(r.calls) {
+		return errorResult(method, "%s call %d was not recorded", method, r.next+1), nil
+	}
+	c := r.calls[r.next]
+	if c.Method != method {
+		return errorResult(method, "call %d is %s, %s was recorded", r.next+1, method, c.Method), nil
+	}
+	if a, ok := args.(*cli_service.TCLIServiceExecuteStatementArgs); ok {
+		var recorded cli_service.TCLIServiceExecuteStatementArgs
+		if err := json.Unmarshal(c.Request, &recorded); err != nil {
+			return nil, errors.Wrap(err, "dbsqltesting: invalid recording")
+		}
+		if statement := r.redact(a.GetReq().GetStatement()); statement != recorded.GetReq().GetStatement() {
+			return errorResult(method, "call %d runs %q, %q was recorded", r.next+1, statement, recorded.GetReq().GetStatement()), nil
+		}
+	}
+	r.next++
+
+	_, result := serviceCall(method)
+	if err := json.Unmarshal(c.Response, result); err != nil {
+		return nil, errors.Wrap(err, "dbsqltesting: invalid recording")
+	}
+	return result, nil
+}
+
+// errorResult returns the result of method with the error status and message
+func errorResult(method string, format string, args ...any) thrift.TStruct {
+	msg := "dbsqltesting: " + fmt.Sprintf(format, args...)
+	_, result := serviceCall(method)
+	// the results of all the methods have a Success response with a Status
+	success := reflect.ValueOf(result).Elem().FieldByName("Success")
+	resp := reflect.New(success.Type().Elem())
+	resp.Elem().FieldByName("Status").Set(reflect.ValueOf(&cli_service.TStatus{
+		StatusCode:   cli_service.TStatusCode_ERROR_STATUS,
+		ErrorMessage: &msg,
+	}))
+	success.Set(resp)
+	return result
+}
+
+func newResponse(req *http.Request, statusCode int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// encode returns the JSON of a Thrift struct without its secrets
+func (r *Recorder) encode(v thrift.TStruct) (json.RawMessage, error) {
+	scrub(reflect.ValueOf(v))
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "dbsqltesting: failed to encode call")
+	}
+	for _, secret := range r.secrets {
+		// the secret as it is encoded in JSON strings
+		quoted, _ := json.Marshal(secret)
+		b = bytes.ReplaceAll(b, quoted[1:len(quoted)-1], []byte(redacted))
+	}
+	return b, nil
+}
+
+// redact returns s with the secrets replaced
+func (r *Recorder) redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+// scrub replaces the secrets of the handles and the presigned URLs of v
+func scrub(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			scrub(v.Elem())
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			scrub(v.Index(i))
+		}
+	case reflect.Struct:
+		switch s := v.Addr().Interface().(type) {
+		case *cli_service.THandleIdentifier:
+			s.Secret = []byte(redacted)
+		case *cli_service.TSparkArrowResultLink:
+			s.FileLink = redacted
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				scrub(v.Field(i))
+			}
+		}
+	}
+}
+
+func isThrift(req *http.Request) bool {
+	return req.Method == http.MethodPost && strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-thrift")
+}
+
+// readBody reads and closes the body of req
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return io.ReadAll(req.Body)
+}
+
+// decodeRequest returns the method, the arguments and the sequence id of a Thrift request
+func decodeRequest(body []byte) (string, thrift.TStruct, int32, error) {
+	ctx := context.Background()
+	protocol := thrift.NewTBinaryProtocolConf(thrift.NewStreamTransportR(bytes.NewReader(body)), &thrift.TConfiguration{})
+	method, _, seqId, err := protocol.ReadMessageBegin(ctx)
+	if err != nil {
+		return "", nil, 0, errors.Wrap(err, "dbsqltesting: invalid Thrift request")
+	}
+	args, _ := serviceCall(method)
+	if args == nil {
+		return "", nil, 0, errors.Errorf("dbsqltesting: unknown Thrift method %s", method)
+	}
+	if err := args.Read(ctx, protocol); err != nil {
+		return "", nil, 0, errors.Wrapf(err, "dbsqltesting: invalid %s request", method)
+	}
+	return method, args, seqId, nil
+}
+
+// decodeResponse returns the result of the Thrift response of method
+func decodeResponse(method string, body []byte) (thrift.TStruct, error) {
+	ctx := context.Background()
+	protocol := thrift.NewTBinaryProtocolConf(thrift.NewStreamTransportR(bytes.NewReader(body)), &thrift.TConfiguration{})
+	_, typeId, _, err := protocol.ReadMessageBegin(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "dbsqltesting: invalid Thrift response")
+	}
+	if typeId == thrift.EXCEPTION {
+		return nil, errors.Errorf("dbsqltesting: %s failed with a Thrift exception, which can't be recorded", method)
+	}
+	_, result := serviceCall(method)
+	if err := result.Read(ctx, protocol); err != nil {
+		return nil, errors.Wrapf(err, "dbsqltesting: invalid %s response", method)
+	}
+	return result, nil
+}
+
+// encodeResponse returns the Thrift response of method with result
+func encodeResponse(method string, seqId int32, result thrift.TStruct) ([]byte, error) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	protocol := thrift.NewTBinaryProtocolConf(thrift.NewStreamTransportW(&buf), &thrift.TConfiguration{})
+	if err := protocol.WriteMessageBegin(ctx, method, thrift.REPLY, seqId); err != nil {
+		return nil, err
+	}
+	if err := result.Write(ctx, protocol); err != nil {
+		return nil, err
+	}
+	if err := protocol.WriteMessageEnd(ctx); err != nil {
+		return nil, err
+	}
+	if err := protocol.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// serviceCall returns the arguments and the result of a method of TCLIService, nil for an unknown method
+func serviceCall(method string) (args, result thrift.TStruct) {
+	switch method {
+	case "OpenSession":
+		return &cli_service.TCLIServiceOpenSessionArgs{}, &cli_service.TCLIServiceOpenSessionResult{}
+	case "CloseSession":
+		return &cli_service.TCLIServiceCloseSessionArgs{}, &cli_service.TCLIServiceCloseSessionResult{}
+	case "GetInfo":
+		return &cli_service.TCLIServiceGetInfoArgs{}, &cli_service.TCLIServiceGetInfoResult{}
+	case "ExecuteStatement":
+		return &cli_service.TCLIServiceExecuteStatementArgs{}, &cli_service.TCLIServiceExecuteStatementResult{}
+	case "GetTypeInfo":
+		return &cli_service.TCLIServiceGetTypeInfoArgs{}, &cli_service.TCLIServiceGetTypeInfoResult{}
+	case "GetCatalogs":
+		return &cli_service.TCLIServiceGetCatalogsArgs{}, &cli_service.TCLIServiceGetCatalogsResult{}
+	case "GetSchemas":
+		return &cli_service.TCLIServiceGetSchemasArgs{}, &cli_service.TCLIServiceGetSchemasResult{}
+	case "GetTables":
+		return &cli_service.TCLIServiceGetTablesArgs{}, &cli_service.TCLIServiceGetTablesResult{}
+	case "GetTableTypes":
+		return &cli_service.TCLIServiceGetTableTypesArgs{}, &cli_service.TCLIServiceGetTableTypesResult{}
+	case "GetColumns":
+		return &cli_service.TCLIServiceGetColumnsArgs{}, &cli_service.TCLIServiceGetColumnsResult{}
+	case "GetFunctions":
+		return &cli_service.TCLIServiceGetFunctionsArgs{}, &cli_service.TCLIServiceGetFunctionsResult{}
+	case "GetPrimaryKeys":
+		return &cli_service.TCLIServiceGetPrimaryKeysArgs{}, &cli_service.TCLIServiceGetPrimaryKeysResult{}
+	case "GetCrossReference":
+		return &cli_service.TCLIServiceGetCrossReferenceArgs{}, &cli_service.TCLIServiceGetCrossReferenceResult{}
+	case "GetOperationStatus":
+		return &cli_service.TCLIServiceGetOperationStatusArgs{}, &cli_service.TCLIServiceGetOperationStatusResult{}
+	case "CancelOperation":
+		return &cli_service.TCLIServiceCancelOperationArgs{}, &cli_service.TCLIServiceCancelOperationResult{}
+	case "CloseOperation":
+		return &cli_service.TCLIServiceCloseOperationArgs{}, &cli_service.TCLIServiceCloseOperationResult{}
+	case "GetResultSetMetadata":
+		return &cli_service.TCLIServiceGetResultSetMetadataArgs{}, &cli_service.TCLIServiceGetResultSetMetadataResult{}
+	case "FetchResults":
+		return &cli_service.TCLIServiceFetchResultsArgs{}, &cli_service.TCLIServiceFetchResultsResult{}
+	}
+	return nil, nil
+}
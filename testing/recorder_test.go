@@ -0,0 +1,88 @@
+package dbsqltesting
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "orders.json")
+
+	server := newServer(t)
+	server.AddResult("select id, region from orders", Result{
+		Columns: []Column{{Name: "id", Type: "BIGINT"}, {Name: "region", Type: "STRING"}},
+		Rows:    [][]any{{int64(1), "emea"}, {int64(2), nil}},
+	})
+	server.AddResult("create user bob with password 'hunter2'", Result{})
+
+	// run is the code under test
+	run := func(connector *sql.DB) ([]sql.NullString, error) {
+		if _, err := connector.ExecContext(ctx, "create user bob with password 'hunter2'"); err != nil {
+			return nil, err
+		}
+		rows, err := connector.QueryContext(ctx, "select id, region from orders")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var regions []sql.NullString
+		for rows.Next() {
+			var id int64
+			var region sql.NullString
+			if err := rows.Scan(&id, &region); err != nil {
+				return nil, err
+			}
+			regions = append(regions, region)
+		}
+		return regions, rows.Err()
+	}
+	openDB := func(recorder *Recorder) *sql.DB {
+		connector, err := server.NewConnector(dbsql.WithTransportWrapper(recorder.Wrap))
+		require.NoError(t, err)
+		return sql.OpenDB(connector)
+	}
+
+	recorder, err := NewRecorder(path, ModeRecord)
+	require.NoError(t, err)
+	recorder.Redact("hunter2")
+	db := openDB(recorder)
+	recorded, err := run(db)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	require.NoError(t, recorder.Close())
+
+	golden, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(golden), `"method": "OpenSession"`)
+	assert.Contains(t, string(golden), `create user bob with password 'REDACTED'`)
+	assert.NotContains(t, string(golden), "hunter2")
+	assert.NotContains(t, string(golden), "fake", "no credentials")
+
+	// the replay doesn't need the warehouse
+	server.Close()
+
+	recorder, err = NewRecorder(path, ModeReplay)
+	require.NoError(t, err)
+	recorder.Redact("hunter2")
+	db = openDB(recorder)
+	replayed, err := run(db)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	assert.Equal(t, []sql.NullString{{String: "emea", Valid: true}, {}}, replayed)
+	assert.Equal(t, recorded, replayed)
+
+	recorder, err = NewRecorder(path, ModeReplay)
+	require.NoError(t, err)
+	db = openDB(recorder)
+	defer db.Close()
+	_, err = db.ExecContext(ctx, "drop table orders")
+	assert.ErrorContains(t, err, `runs "drop table orders", "create user bob with password 'REDACTED'" was recorded`)
+}
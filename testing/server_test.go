@@ -0,0 +1,166 @@
+package dbsqltesting
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	dbsqlerr "github.com/databricks/databricks-sql-go/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T) *Server {
+	server := NewServer()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func openDB(t *testing.T, server *Server, opts ...dbsql.ConnOption) *sql.DB {
+	connector, err := server.NewConnector(opts...)
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2023, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	t.Run("results", func(t *testing.T) {
+		server := newServer(t)
+		server.AddResult("select * from orders where region = ?", Result{
+			Columns: []Column{
+				{Name: "id", Type: "BIGINT"},
+				{Name: "amount", Type: "DECIMAL(10,2)"},
+				{Name: "express", Type: "BOOLEAN"},
+				{Name: "created_at", Type: "TIMESTAMP"},
+				{Name: "tags", Type: "ARRAY<STRING>"},
+			},
+			Rows: [][]any{
+				{1, "12.50", true, created, []string{"new"}},
+				{2, nil, false, "2023-01-31 08:30:00", nil},
+				{3, "1.00", nil, created, `["gift"]`},
+			},
+		})
+
+		db, err := sql.Open("databricks", server.DSN()+"?maxRows=2")
+		require.NoError(t, err)
+		defer db.Close()
+		require.NoError(t, db.PingContext(ctx))
+
+		rows, err := db.QueryContext(ctx, "select *\n  from orders where region = ?", "emea")
+		require.NoError(t, err)
+		defer rows.Close()
+		types, err := rows.ColumnTypes()
+		require.NoError(t, err)
+		assert.Equal(t, "DECIMAL", types[1].DatabaseTypeName())
+		precision, scale, ok := types[1].DecimalSize()
+		assert.True(t, ok)
+		assert.Equal(t, []int64{10, 2}, []int64{precision, scale})
+
+		type order struct {
+			id        int64
+			amount    sql.NullString
+			express   sql.NullBool
+			createdAt time.Time
+			tags      sql.NullString
+		}
+		var orders []order
+		for rows.Next() {
+			var o order
+			require.NoError(t, rows.Scan(&o.id, &o.amount, &o.express, &o.createdAt, &o.tags))
+			orders = append(orders, o)
+		}
+		require.NoError(t, rows.Err())
+		require.Len(t, orders, 3, "rows of all the pages")
+		assert.Equal(t, order{1, sql.NullString{String: "12.50", Valid: true}, sql.NullBool{Bool: true, Valid: true}, created,
+			sql.NullString{String: `["new"]`, Valid: true}}, orders[0])
+		assert.False(t, orders[1].amount.Valid)
+		assert.Equal(t, time.Date(2023, 1, 31, 8, 30, 0, 0, time.UTC), orders[1].createdAt)
+		assert.False(t, orders[2].express.Valid)
+
+		// Ping validates the session with GetInfo, it doesn't run a statement
+		assert.Equal(t, []string{"select *\n  from orders where region = ?"}, server.Statements())
+	})
+
+	t.Run("statements", func(t *testing.T) {
+		server := newServer(t)
+		server.AddResult("delete from orders", Result{RowsAffected: 3})
+		server.AddResult("select * from missing", Result{Error: &Error{
+			Message:  "[TABLE_OR_VIEW_NOT_FOUND] The table or view `missing` cannot be found.",
+			SQLState: "42P01",
+		}})
+		db := openDB(t, server)
+
+		res, err := db.ExecContext(ctx, "delete from orders")
+		require.NoError(t, err)
+		n, err := res.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+
+		_, err = db.QueryContext(ctx, "select * from missing")
+		var execErr *dbsqlerr.ExecutionError
+		require.ErrorAs(t, err, &execErr)
+		assert.Equal(t, "42P01", execErr.SQLState)
+		assert.Equal(t, "TABLE_OR_VIEW_NOT_FOUND", execErr.ErrorClass)
+
+		_, err = db.ExecContext(ctx, "drop table orders")
+		assert.ErrorContains(t, err, "no result for statement: drop table orders")
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		server := newServer(t)
+		result := Result{Columns: []Column{{Name: "n", Type: "INT"}}, Rows: [][]any{{1}}, Latency: 100 * time.Millisecond}
+		server.AddResult("select slow", result)
+		result.Latency = time.Minute
+		server.AddResult("select slower", result)
+		db, err := sql.Open("databricks", server.DSN()+"?pollInterval=10ms")
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		start := time.Now()
+		var n int
+		require.NoError(t, db.QueryRowContext(ctx, "select slow").Scan(&n))
+		assert.Equal(t, 1, n)
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err = db.QueryContext(timeoutCtx, "select slower")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("rate limits are retried", func(t *testing.T) {
+		server := newServer(t)
+		db := openDB(t, server, dbsql.WithRetries(3, time.Millisecond, 10*time.Millisecond))
+		require.NoError(t, db.PingContext(ctx))
+
+		server.FailRequests(2, http.StatusTooManyRequests)
+		require.NoError(t, db.PingContext(ctx))
+
+		server.FailRequests(10, http.StatusTooManyRequests)
+		var n int
+		err := db.QueryRowContext(ctx, "select 1").Scan(&n)
+		var rateErr *dbsqlerr.RateLimitError
+		assert.ErrorAs(t, err, &rateErr)
+		server.FailRequests(0, 0)
+	})
+
+	t.Run("expired sessions are reopened", func(t *testing.T) {
+		server := newServer(t)
+		server.AddResult("select count(*) from orders", Result{Columns: []Column{{Name: "count(1)", Type: "BIGINT"}}, Rows: [][]any{{int64(42)}}})
+		db := openDB(t, server)
+		db.SetMaxOpenConns(1)
+		require.NoError(t, db.PingContext(ctx))
+
+		server.ExpireSessions()
+		var count int64
+		require.NoError(t, db.QueryRowContext(ctx, "select count(*) from orders").Scan(&count))
+		assert.Equal(t, int64(42), count)
+	})
+}
@@ -1,6 +1,7 @@
 package dbsql
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 
@@ -47,7 +48,15 @@ func (h *thriftHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	thriftHandler := thrift.NewThriftHandlerFunc(h.processor, h.inPfactory, h.outPfactory)
-	thriftHandler(w, r)
+	thriftHandler(w, r.WithContext(context.WithValue(r.Context(), requestHeaderKey{}, r.Header)))
+}
+
+type requestHeaderKey struct{}
+
+// requestHeaderFromContext returns the HTTP header of the request handled by the test server with ctx
+func requestHeaderFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(requestHeaderKey{}).(http.Header)
+	return header
 }
 
 func initThriftTestServer(handler cli_service.TCLIService) *httptest.Server {
@@ -0,0 +1,424 @@
+// Package dbsqltypes provides nullable types for the Databricks types that the database/sql Null types don't cover.
+// Each type scans the values in the formats the driver returns them, e.g. DECIMAL values as strings and ARRAY values
+// as JSON strings or decoded slices, and NULL as an invalid value:
+//
+//	var price dbsqltypes.NullDecimal
+//	var tags dbsqltypes.NullArray
+//	err := db.QueryRowContext(ctx, "select price, tags from orders where id = ?", id).Scan(&price, &tags)
+//	if price.Valid {
+//		total.Add(total, price.Decimal.Rat())
+//	}
+//
+// The types are query parameters as well, a NullDecimal or NullInterval is sent with its DECIMAL or INTERVAL type,
+// and an invalid value is sent as NULL:
+//
+//	_, err := db.ExecContext(ctx, "update orders set discount = ? where id = ?", dbsqltypes.NullDecimal{}, id)
+package dbsqltypes
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	"github.com/pkg/errors"
+)
+
+var errScan = "dbsqltypes: unable to scan type %T into %s"
+
+// NullDecimal is a DECIMAL value which may be NULL
+type NullDecimal struct {
+	Decimal dbsql.Decimal
+	Valid   bool // Valid is true if Decimal is not NULL
+}
+
+var _ sql.Scanner = (*NullDecimal)(nil)
+var _ driver.Valuer = NullDecimal{}
+
+// Scan implements sql.Scanner
+func (n *NullDecimal) Scan(src any) error {
+	if src == nil {
+		*n = NullDecimal{}
+		return nil
+	}
+	if err := n.Decimal.Scan(src); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// Value implements driver.Valuer, the decimal is sent with its DECIMAL type
+func (n NullDecimal) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Decimal, nil
+}
+
+// NullInterval is an INTERVAL value which may be NULL
+type NullInterval struct {
+	Interval dbsql.Interval
+	Valid    bool // Valid is true if Interval is not NULL
+}
+
+var _ sql.Scanner = (*NullInterval)(nil)
+var _ driver.Valuer = NullInterval{}
+
+// Scan implements sql.Scanner
+func (n *NullInterval) Scan(src any) error {
+	if src == nil {
+		*n = NullInterval{}
+		return nil
+	}
+	if err := n.Interval.Scan(src); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// Value implements driver.Valuer, the interval is sent with its INTERVAL type
+func (n NullInterval) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Interval, nil
+}
+
+// timestampFormats are the formats of the TIMESTAMP and DATE values returned as strings, e.g. in the
+// JSON of complex values or in the results of the Statement Execution API
+var timestampFormats = []string{
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// NullTimestamp is a TIMESTAMP, TIMESTAMP_NTZ or DATE value which may be NULL. Unlike sql.NullTime it also
+// scans the timestamps formatted as strings, e.g. the fields of a STRUCT value, strings without a time
+// zone are in UTC.
+type NullTimestamp struct {
+	Time  time.Time
+	Valid bool // Valid is true if Time is not NULL
+}
+
+var _ sql.Scanner = (*NullTimestamp)(nil)
+var _ driver.Valuer = NullTimestamp{}
+
+// Scan implements sql.Scanner
+func (n *NullTimestamp) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*n = NullTimestamp{}
+		return nil
+	case time.Time:
+		*n = NullTimestamp{Time: v, Valid: true}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return errors.Errorf(errScan, src, "NullTimestamp")
+	}
+
+	for _, format := range timestampFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			*n = NullTimestamp{Time: t, Valid: true}
+			return nil
+		}
+	}
+	return errors.Errorf("dbsqltypes: invalid timestamp value %q", s)
+}
+
+// Value implements driver.Valuer, the time is sent as a TIMESTAMP
+func (n NullTimestamp) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Time, nil
+}
+
+// NullArray is an ARRAY value which may be NULL. Elements are decoded like with the structured complex type
+// scanner: numbers to int64 or float64, STRUCT elements to map[string]any and MAP elements to map[any]any.
+type NullArray struct {
+	Array []any
+	Valid bool // Valid is true if Array is not NULL
+}
+
+var _ sql.Scanner = (*NullArray)(nil)
+var _ driver.Valuer = NullArray{}
+
+// Scan implements sql.Scanner
+func (n *NullArray) Scan(src any) error {
+	v, err := scanComplex(src, "NullArray")
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		*n = NullArray{}
+		return nil
+	}
+	array, ok := v.([]any)
+	if !ok {
+		return errors.Errorf(errScan, v, "NullArray")
+	}
+	*n = NullArray{Array: array, Valid: true}
+	return nil
+}
+
+// Value implements driver.Valuer, the array is sent as a JSON string, e.g. to be decoded with from_json
+func (n NullArray) Value() (driver.Value, error) {
+	return complexValue(n.Array, n.Valid)
+}
+
+// NullMap is a MAP value which may be NULL. Keys are strings when the map is decoded from JSON.
+type NullMap struct {
+	Map   map[any]any
+	Valid bool // Valid is true if Map is not NULL
+}
+
+var _ sql.Scanner = (*NullMap)(nil)
+var _ driver.Valuer = NullMap{}
+
+// Scan implements sql.Scanner
+func (n *NullMap) Scan(src any) error {
+	v, err := scanComplex(src, "NullMap")
+	if err != nil {
+		return err
+	}
+	switch m := v.(type) {
+	case nil:
+		*n = NullMap{}
+	case map[any]any:
+		*n = NullMap{Map: m, Valid: true}
+	case map[string]any:
+		converted := make(map[any]any, len(m))
+		for k, e := range m {
+			converted[k] = e
+		}
+		*n = NullMap{Map: converted, Valid: true}
+	default:
+		return errors.Errorf(errScan, v, "NullMap")
+	}
+	return nil
+}
+
+// Value implements driver.Valuer, the map is sent as a JSON string, e.g. to be decoded with from_json
+func (n NullMap) Value() (driver.Value, error) {
+	return complexValue(n.Map, n.Valid)
+}
+
+// NullStruct is a STRUCT value which may be NULL, its fields by name
+type NullStruct struct {
+	Struct map[string]any
+	Valid  bool // Valid is true if Struct is not NULL
+}
+
+var _ sql.Scanner = (*NullStruct)(nil)
+var _ driver.Valuer = NullStruct{}
+
+// Scan implements sql.Scanner
+func (n *NullStruct) Scan(src any) error {
+	v, err := scanComplex(src, "NullStruct")
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		*n = NullStruct{}
+		return nil
+	}
+	fields, ok := v.(map[string]any)
+	if !ok {
+		return errors.Errorf(errScan, v, "NullStruct")
+	}
+	*n = NullStruct{Struct: fields, Valid: true}
+	return nil
+}
+
+// Value implements driver.Valuer, the struct is sent as a JSON string, e.g. to be decoded with from_json
+func (n NullStruct) Value() (driver.Value, error) {
+	return complexValue(n.Struct, n.Valid)
+}
+
+// Geometry is a GEOMETRY or GEOGRAPHY value which may be NULL, in the representation the server sends it: a
+// string value is well-known text (WKT), e.g. POINT(4.9 52.37), and a binary value well-known binary (WKB). The
+// SRID of an extended value, EWKT like SRID=4326;POINT(4.9 52.37) or EWKB, is split from the geometry and is 0
+// for a value without one. The geometry isn't parsed any further, a GIS library decodes WKT or WKB:
+//
+//	var location dbsqltypes.Geometry
+//	err := db.QueryRowContext(ctx, "select location from stores where id = ?", id).Scan(&location)
+//	if location.Valid && location.WKB != nil {
+//		g, err := wkb.Unmarshal(location.WKB)
+//	}
+type Geometry struct {
+	WKT   string // well-known text of a string value
+	WKB   []byte // well-known binary of a binary value
+	SRID  int    // spatial reference system of an extended value, 0 if the value has none
+	Valid bool   // Valid is true if the value is not NULL
+}
+
+var _ sql.Scanner = (*Geometry)(nil)
+var _ driver.Valuer = Geometry{}
+
+// ewkbSRIDFlag is set in the geometry type of an EWKB value followed by its SRID
+const ewkbSRIDFlag = 0x20000000
+
+// Scan implements sql.Scanner, the bytes of a binary value are copied
+func (g *Geometry) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*g = Geometry{}
+		return nil
+	case string:
+		return g.scanText(v)
+	case []byte:
+		return g.scanBinary(v)
+	default:
+		return errors.Errorf(errScan, src, "Geometry")
+	}
+}
+
+// scanText scans a WKT or EWKT value
+func (g *Geometry) scanText(s string) error {
+	var srid int
+	if prefix, wkt, ok := strings.Cut(s, ";"); ok && strings.HasPrefix(strings.ToUpper(prefix), "SRID=") {
+		n, err := strconv.Atoi(prefix[len("SRID="):])
+		if err != nil {
+			return errors.Errorf("dbsqltypes: invalid SRID of geometry %q", s)
+		}
+		s, srid = wkt, n
+	}
+	*g = Geometry{WKT: s, SRID: srid, Valid: true}
+	return nil
+}
+
+// scanBinary scans a WKB or EWKB value, the SRID of EWKB is removed to return WKB
+func (g *Geometry) scanBinary(b []byte) error {
+	// byte order and geometry type
+	if len(b) < 5 || b[0] > 1 {
+		return errors.New("dbsqltypes: invalid WKB geometry")
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if b[0] == 1 {
+		order = binary.LittleEndian
+	}
+
+	geometryType := order.Uint32(b[1:5])
+	if geometryType&ewkbSRIDFlag == 0 {
+		*g = Geometry{WKB: append([]byte{}, b...), Valid: true}
+		return nil
+	}
+	if len(b) < 9 {
+		return errors.New("dbsqltypes: invalid EWKB geometry, its SRID is missing")
+	}
+	srid := int(int32(order.Uint32(b[5:9])))
+	wkb := make([]byte, 0, len(b)-4)
+	wkb = append(wkb, b[0], 0, 0, 0, 0)
+	order.PutUint32(wkb[1:5], geometryType&^ewkbSRIDFlag)
+	wkb = append(wkb, b[9:]...)
+	*g = Geometry{WKB: wkb, SRID: srid, Valid: true}
+	return nil
+}
+
+// Value implements driver.Valuer, the geometry is sent as its WKT string or its WKB bytes, e.g. to be decoded with
+// st_geomfromtext or st_geomfromwkb. The SRID is not sent, it is a parameter of its own.
+func (g Geometry) Value() (driver.Value, error) {
+	switch {
+	case !g.Valid:
+		return nil, nil
+	case g.WKB != nil:
+		return g.WKB, nil
+	default:
+		return g.WKT, nil
+	}
+}
+
+// scanComplex returns the decoded value of a complex value, src is either the JSON string returned by the
+// default complex type scanner or the value decoded by the structured complex type scanner
+func scanComplex(src any, typeName string) (any, error) {
+	var data string
+	switch v := src.(type) {
+	case nil, []any, map[string]any, map[any]any:
+		return v, nil
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		return nil, errors.Errorf(errScan, src, typeName)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.Wrapf(err, "dbsqltypes: invalid %s value", typeName)
+	}
+	return convertJSONNumbers(v), nil
+}
+
+// convertJSONNumbers converts the numbers of a decoded JSON value to int64, or float64 when they are not integers
+func convertJSONNumbers(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case []any:
+		for i := range t {
+			t[i] = convertJSONNumbers(t[i])
+		}
+	case map[string]any:
+		for k := range t {
+			t[k] = convertJSONNumbers(t[k])
+		}
+	}
+	return v
+}
+
+// complexValue encodes a complex value to JSON, nil if it is not valid
+func complexValue(v any, valid bool) (driver.Value, error) {
+	if !valid {
+		return nil, nil
+	}
+	b, err := json.Marshal(jsonCompatible(v))
+	if err != nil {
+		return nil, errors.Wrap(err, "dbsqltypes: unable to encode complex value")
+	}
+	return string(b), nil
+}
+
+// jsonCompatible converts the map[any]any values of MAP values to map[string]any so they can be encoded to JSON
+func jsonCompatible(v any) any {
+	switch t := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = jsonCompatible(e)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[k] = jsonCompatible(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			s[i] = jsonCompatible(e)
+		}
+		return s
+	}
+	return v
+}
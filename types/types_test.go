@@ -0,0 +1,152 @@
+package dbsqltypes
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	dbsql "github.com/databricks/databricks-sql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullDecimal(t *testing.T) {
+	var n NullDecimal
+	require.NoError(t, n.Scan("-123.45"))
+	assert.True(t, n.Valid)
+	assert.Equal(t, "-123.45", n.Decimal.String())
+	v, err := n.Value()
+	require.NoError(t, err)
+	assert.Equal(t, dbsql.NewDecimal(big.NewInt(-12345), 2), v)
+
+	require.NoError(t, n.Scan(nil))
+	assert.False(t, n.Valid)
+	v, err = n.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	assert.Error(t, n.Scan(true))
+}
+
+func TestNullInterval(t *testing.T) {
+	var n NullInterval
+	require.NoError(t, n.Scan("1-2"))
+	assert.Equal(t, NullInterval{Interval: dbsql.Interval{Months: 14}, Valid: true}, n)
+	v, err := n.Value()
+	require.NoError(t, err)
+	assert.Equal(t, dbsql.Interval{Months: 14}, v)
+
+	require.NoError(t, n.Scan(nil))
+	assert.False(t, n.Valid)
+}
+
+func TestNullTimestamp(t *testing.T) {
+	ts := time.Date(2023, 1, 31, 10, 20, 30, 500000000, time.UTC)
+	cases := []any{
+		ts,
+		"2023-01-31 10:20:30.5",
+		"2023-01-31T10:20:30.5Z",
+		"2023-01-31T11:20:30.5+01:00",
+		[]byte("2023-01-31T10:20:30.5"),
+	}
+	for _, src := range cases {
+		var n NullTimestamp
+		require.NoError(t, n.Scan(src), "%v", src)
+		assert.True(t, n.Valid)
+		assert.True(t, ts.Equal(n.Time), "%v", src)
+	}
+
+	var n NullTimestamp
+	require.NoError(t, n.Scan("2023-01-31"))
+	assert.Equal(t, time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC), n.Time)
+	require.NoError(t, n.Scan(nil))
+	assert.False(t, n.Valid)
+	assert.EqualError(t, n.Scan("yesterday"), `dbsqltypes: invalid timestamp value "yesterday"`)
+}
+
+func TestNullComplex(t *testing.T) {
+	t.Run("arrays", func(t *testing.T) {
+		var n NullArray
+		require.NoError(t, n.Scan(`[1, 2.5, "a", {"b": null}]`))
+		assert.Equal(t, NullArray{Array: []any{int64(1), 2.5, "a", map[string]any{"b": nil}}, Valid: true}, n)
+		v, err := n.Value()
+		require.NoError(t, err)
+		assert.Equal(t, `[1,2.5,"a",{"b":null}]`, v)
+
+		require.NoError(t, n.Scan([]any{int64(1)}), "structured values")
+		assert.Equal(t, []any{int64(1)}, n.Array)
+
+		require.NoError(t, n.Scan(nil))
+		assert.False(t, n.Valid)
+		assert.Error(t, n.Scan(`{"a": 1}`))
+	})
+
+	t.Run("maps", func(t *testing.T) {
+		var n NullMap
+		require.NoError(t, n.Scan(`{"a": 1}`))
+		assert.Equal(t, NullMap{Map: map[any]any{"a": int64(1)}, Valid: true}, n)
+
+		require.NoError(t, n.Scan(map[any]any{int64(1): []any{map[any]any{"x": "y"}}}))
+		v, err := n.Value()
+		require.NoError(t, err)
+		assert.Equal(t, `{"1":[{"x":"y"}]}`, v)
+
+		require.NoError(t, n.Scan(nil))
+		v, err = n.Value()
+		require.NoError(t, err)
+		assert.Nil(t, v)
+	})
+
+	t.Run("structs", func(t *testing.T) {
+		var n NullStruct
+		require.NoError(t, n.Scan([]byte(`{"city": "Paris", "zip": 75001}`)))
+		assert.Equal(t, map[string]any{"city": "Paris", "zip": int64(75001)}, n.Struct)
+
+		require.NoError(t, n.Scan("null"))
+		assert.False(t, n.Valid)
+		assert.EqualError(t, n.Scan(`{"city"`), "dbsqltypes: invalid NullStruct value: unexpected EOF")
+		assert.EqualError(t, n.Scan(1), "dbsqltypes: unable to scan type int into NullStruct")
+	})
+}
+
+func TestGeometry(t *testing.T) {
+	var g Geometry
+	require.NoError(t, g.Scan("POINT(4.9 52.37)"))
+	assert.Equal(t, Geometry{WKT: "POINT(4.9 52.37)", Valid: true}, g)
+	v, err := g.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "POINT(4.9 52.37)", v)
+
+	require.NoError(t, g.Scan("SRID=4326;POINT(4.9 52.37)"))
+	assert.Equal(t, Geometry{WKT: "POINT(4.9 52.37)", SRID: 4326, Valid: true}, g)
+	assert.Error(t, g.Scan("SRID=wgs84;POINT(4.9 52.37)"))
+
+	// POINT(1 2) in little endian WKB, and as EWKB with SRID 4326
+	wkb, _ := hex.DecodeString("0101000000000000000000f03f0000000000000040")
+	ewkb, _ := hex.DecodeString("0101000020e6100000000000000000f03f0000000000000040")
+	require.NoError(t, g.Scan(wkb))
+	assert.Equal(t, Geometry{WKB: wkb, Valid: true}, g)
+	v, err = g.Value()
+	require.NoError(t, err)
+	assert.Equal(t, wkb, v)
+
+	require.NoError(t, g.Scan(ewkb))
+	assert.Equal(t, Geometry{WKB: wkb, SRID: 4326, Valid: true}, g)
+
+	// the bytes are copied
+	src := append([]byte{}, wkb...)
+	require.NoError(t, g.Scan(src))
+	src[1] = 2
+	assert.Equal(t, wkb, g.WKB)
+
+	assert.Error(t, g.Scan([]byte{1, 1}))
+	assert.Error(t, g.Scan(ewkb[:7]))
+	assert.Error(t, g.Scan(4326))
+
+	require.NoError(t, g.Scan(nil))
+	assert.False(t, g.Valid)
+	v, err = g.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+}
@@ -0,0 +1,84 @@
+package dbsql
+
+import (
+	"bytes"
+	"database/sql"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var errValueReaderScan = "databricks: unable to scan type %T into ValueReader"
+
+// ValueReader reads a STRING or BINARY value scanned into it, in chunks, without copying the value into a new
+// string or []byte. With the zeroCopyScan DSN param or WithZeroCopyScan(true) the value is read from the buffer of
+// the result page, e.g. of a downloaded cloud fetch file, so a value of hundreds of MB is streamed to its
+// destination without being materialized again:
+//
+//	var doc dbsql.ValueReader
+//	for rows.Next() {
+//		if err := rows.Scan(&id, &doc); err != nil {
+//			log.Fatal(err)
+//		}
+//		if _, err := io.Copy(w, &doc); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// Like a sql.RawBytes, the value is only valid until the next call to Next, Scan or Close of the rows. Valid is
+// false for a NULL value, which reads as empty.
+type ValueReader struct {
+	Valid bool
+
+	b        bytes.Reader
+	s        strings.Reader
+	isString bool
+}
+
+var _ sql.Scanner = (*ValueReader)(nil)
+var _ io.Reader = (*ValueReader)(nil)
+var _ io.WriterTo = (*ValueReader)(nil)
+
+// Scan implements sql.Scanner, the value is read from src without copying it
+func (v *ValueReader) Scan(src any) error {
+	v.b.Reset(nil)
+	v.s.Reset("")
+	v.isString, v.Valid = false, src != nil
+	switch val := src.(type) {
+	case nil:
+	case []byte:
+		v.b.Reset(val)
+	case string:
+		v.s.Reset(val)
+		v.isString = true
+	default:
+		v.Valid = false
+		return errors.Errorf(errValueReaderScan, src)
+	}
+	return nil
+}
+
+// Read reads the next bytes of the value
+func (v *ValueReader) Read(p []byte) (int, error) {
+	if v.isString {
+		return v.s.Read(p)
+	}
+	return v.b.Read(p)
+}
+
+// WriteTo writes the unread bytes of the value to w, it is used by io.Copy
+func (v *ValueReader) WriteTo(w io.Writer) (int64, error) {
+	if v.isString {
+		return v.s.WriteTo(w)
+	}
+	return v.b.WriteTo(w)
+}
+
+// Size returns the length of the value in bytes
+func (v *ValueReader) Size() int64 {
+	if v.isString {
+		return v.s.Size()
+	}
+	return v.b.Size()
+}
@@ -0,0 +1,47 @@
+package dbsql
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueReader(t *testing.T) {
+	var v ValueReader
+
+	view := []byte("a large binary value")
+	require.NoError(t, v.Scan(view))
+	assert.True(t, v.Valid)
+	assert.Equal(t, int64(len(view)), v.Size())
+	// the value is read from the buffer it was scanned from
+	view[0] = 'A'
+	chunk := make([]byte, 7)
+	n, err := v.Read(chunk)
+	require.NoError(t, err)
+	assert.Equal(t, "A large", string(chunk[:n]))
+	var rest bytes.Buffer
+	_, err = io.Copy(&rest, &v)
+	require.NoError(t, err)
+	assert.Equal(t, " binary value", rest.String())
+
+	text := strings.Repeat("x", 1<<20)
+	require.NoError(t, v.Scan(text))
+	var buf bytes.Buffer
+	copied, err := io.Copy(&buf, &v)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<20), copied)
+	assert.Equal(t, text, buf.String())
+
+	require.NoError(t, v.Scan(nil))
+	assert.False(t, v.Valid)
+	n, err = v.Read(chunk)
+	assert.Zero(t, n)
+	assert.Equal(t, io.EOF, err)
+
+	assert.EqualError(t, v.Scan(int64(1)), "databricks: unable to scan type int64 into ValueReader")
+	assert.False(t, v.Valid)
+}
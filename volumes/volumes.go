@@ -0,0 +1,229 @@
+// Package dbsqlvolumes uploads, downloads, lists and deletes the files of Unity Catalog Volumes through a SQL
+// warehouse, with the PUT, GET, LIST and REMOVE statements run by the databricks driver.
+//
+//	volumes := dbsqlvolumes.New(db)
+//	err := volumes.Upload(ctx, "/Volumes/main/default/files/sales.csv", file, size, true)
+//	err = volumes.Download(ctx, "/Volumes/main/default/files/sales.csv", os.Stdout)
+//	files, err := volumes.List(ctx, "/Volumes/main/default/files")
+//	err = volumes.Delete(ctx, "/Volumes/main/default/files/sales.csv")
+//
+// Files are transferred with a single request to the presigned URL returned by the warehouse, which is limited
+// to the maximum object size of a single upload of the cloud storage, e.g. 5 GB on S3. The staging protocol of
+// the warehouse has no multi-part uploads, larger files must be split.
+package dbsqlvolumes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/pkg/errors"
+)
+
+// inputStream is the local file of the PUT statements uploading a reader
+const inputStream = "__input_stream__"
+
+// Querier runs the statements of the volume operations, e.g. a *sql.DB, *sql.Conn or *sql.Tx opened with the
+// databricks driver
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Client runs the file operations of Unity Catalog Volumes
+type Client struct {
+	db Querier
+}
+
+// FileInfo describes a file or a directory of a volume
+type FileInfo struct {
+	Path    string
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// New returns a client running the volume operations with db
+func New(db Querier) *Client {
+	return &Client{db: db}
+}
+
+// Upload writes the content of r to the file at path, e.g. /Volumes/main/default/files/sales.csv.
+// size is the number of bytes of r, -1 when it is unknown. Readers of unknown size and readers which can't seek,
+// which could not be sent again when the upload is retried, are spooled to a temporary file first.
+// An existing file is replaced when overwrite is true, otherwise the upload fails.
+func (c *Client) Upload(ctx context.Context, path string, r io.Reader, size int64, overwrite bool) error {
+	if err := checkPath(path); err != nil {
+		return err
+	}
+
+	if _, ok := r.(io.ReadSeeker); !ok || size < 0 {
+		f, err := spool(r)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return errors.Wrap(err, "databricks: failed to spool upload")
+		}
+		r, size = f, info.Size()
+	}
+
+	query := fmt.Sprintf("PUT %s INTO %s", quote(inputStream), quote(path))
+	if overwrite {
+		query += " OVERWRITE"
+	}
+	_, err := c.db.ExecContext(driverctx.NewContextWithStagingReader(ctx, r, size), query)
+	return errors.Wrapf(err, "databricks: failed to upload %s", path)
+}
+
+// UploadFile uploads the local file to the file at path
+func (c *Client) UploadFile(ctx context.Context, path string, localFile string, overwrite bool) error {
+	f, err := os.Open(localFile)
+	if err != nil {
+		return errors.Wrap(err, "databricks: failed to open file to upload")
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "databricks: failed to open file to upload")
+	}
+	return c.Upload(ctx, path, f, info.Size(), overwrite)
+}
+
+// Download writes the content of the file at path to w
+func (c *Client) Download(ctx context.Context, path string, w io.Writer) error {
+	if err := checkPath(path); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("GET %s TO %s", quote(path), quote(inputStream))
+	_, err := c.db.ExecContext(driverctx.NewContextWithStagingWriter(ctx, w), query)
+	return errors.Wrapf(err, "databricks: failed to download %s", path)
+}
+
+// List returns the files and directories in the directory at path
+func (c *Client) List(ctx context.Context, path string) ([]FileInfo, error) {
+	if err := checkPath(path); err != nil {
+		return nil, err
+	}
+	rows, err := c.db.QueryContext(ctx, "LIST "+quote(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "databricks: failed to list %s", path)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errors.Wrapf(err, "databricks: failed to list %s", path)
+	}
+	var files []FileInfo
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, errors.Wrapf(err, "databricks: failed to list %s", path)
+		}
+
+		var file FileInfo
+		for i, column := range columns {
+			switch column {
+			case "path":
+				file.Path = toString(values[i])
+			case "name":
+				file.Name = toString(values[i])
+			case "size":
+				file.Size = toInt64(values[i])
+			case "modification_time":
+				if ms := toInt64(values[i]); ms > 0 {
+					file.ModTime = time.UnixMilli(ms)
+				}
+			}
+		}
+		file.IsDir = strings.HasSuffix(file.Path, "/") || strings.HasSuffix(file.Name, "/")
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "databricks: failed to list %s", path)
+	}
+	return files, nil
+}
+
+// Delete removes the file at path
+func (c *Client) Delete(ctx context.Context, path string) error {
+	if err := checkPath(path); err != nil {
+		return err
+	}
+	_, err := c.db.ExecContext(ctx, "REMOVE "+quote(path))
+	return errors.Wrapf(err, "databricks: failed to delete %s", path)
+}
+
+// checkPath returns an error when path is not in a volume
+func checkPath(path string) error {
+	if !strings.HasPrefix(path, "/Volumes/") {
+		return errors.Errorf("databricks: %s is not a volume path, it must start with /Volumes/", path)
+	}
+	return nil
+}
+
+// quote returns path as a SQL string literal
+func quote(path string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(path) + "'"
+}
+
+// spool copies r to a temporary file and returns the file rewound
+func spool(r io.Reader) (*os.File, error) {
+	f, err := os.CreateTemp("", "dbsql-upload-*")
+	if err != nil {
+		return nil, errors.Wrap(err, "databricks: failed to spool upload")
+	}
+	if _, err = io.Copy(f, r); err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, errors.Wrap(err, "databricks: failed to spool upload")
+	}
+	return f, nil
+}
+
+func toString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toInt64(v any) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	default:
+		n, _ := strconv.ParseInt(toString(v), 10, 64)
+		return n
+	}
+}
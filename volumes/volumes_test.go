@@ -0,0 +1,137 @@
+package dbsqlvolumes
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/databricks/databricks-sql-go/driverctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDriver records the statements run by the volume client and answers them like a warehouse
+type testDriver struct {
+	queries  []string
+	uploaded []byte
+	files    [][]driver.Value
+}
+
+func (d *testDriver) Open(name string) (driver.Conn, error) {
+	return &testConn{d}, nil
+}
+
+type testConn struct {
+	d *testDriver
+}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *testConn) Close() error                              { return nil }
+func (c *testConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (c *testConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.queries = append(c.d.queries, query)
+	if r, size := driverctx.StagingReaderFromContext(ctx); r != nil {
+		c.d.uploaded, _ = io.ReadAll(io.LimitReader(r, size))
+	}
+	if w := driverctx.StagingWriterFromContext(ctx); w != nil {
+		_, _ = w.Write([]byte("a,b\n1,2\n"))
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *testConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.queries = append(c.d.queries, query)
+	return &testRows{values: c.d.files}, nil
+}
+
+type testRows struct {
+	values [][]driver.Value
+}
+
+func (r *testRows) Columns() []string {
+	return []string{"path", "name", "size", "modification_time"}
+}
+func (r *testRows) Close() error { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newTestClient(t *testing.T) (*Client, *testDriver) {
+	d := &testDriver{}
+	db := sql.OpenDB(connector{d})
+	t.Cleanup(func() { db.Close() })
+	return New(db), d
+}
+
+type connector struct {
+	d *testDriver
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return &testConn{c.d}, nil }
+func (c connector) Driver() driver.Driver                        { return c.d }
+
+func TestUpload(t *testing.T) {
+	t.Run("seekable reader", func(t *testing.T) {
+		client, d := newTestClient(t)
+		err := client.Upload(context.Background(), "/Volumes/main/default/files/it's.csv", strings.NewReader("x,y\n"), 4, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`PUT '__input_stream__' INTO '/Volumes/main/default/files/it\'s.csv' OVERWRITE`}, d.queries)
+		assert.Equal(t, "x,y\n", string(d.uploaded))
+	})
+
+	t.Run("reader of unknown size is spooled", func(t *testing.T) {
+		client, d := newTestClient(t)
+		err := client.Upload(context.Background(), "/Volumes/main/default/files/data.csv", io.MultiReader(strings.NewReader("x,y\n"), strings.NewReader("3,4\n")), -1, false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`PUT '__input_stream__' INTO '/Volumes/main/default/files/data.csv'`}, d.queries)
+		assert.Equal(t, "x,y\n3,4\n", string(d.uploaded))
+	})
+
+	t.Run("path outside of a volume", func(t *testing.T) {
+		client, d := newTestClient(t)
+		err := client.Upload(context.Background(), "/tmp/data.csv", strings.NewReader(""), 0, false)
+		assert.Error(t, err)
+		assert.Empty(t, d.queries)
+	})
+}
+
+func TestDownload(t *testing.T) {
+	client, d := newTestClient(t)
+	var buf bytes.Buffer
+	err := client.Download(context.Background(), "/Volumes/main/default/files/data.csv", &buf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`GET '/Volumes/main/default/files/data.csv' TO '__input_stream__'`}, d.queries)
+	assert.Equal(t, "a,b\n1,2\n", buf.String())
+}
+
+func TestList(t *testing.T) {
+	client, d := newTestClient(t)
+	d.files = [][]driver.Value{
+		{"/Volumes/main/default/files/data.csv", "data.csv", int64(8), int64(1700000000000)},
+		{"/Volumes/main/default/files/archive/", "archive/", int64(0), int64(0)},
+	}
+	files, err := client.List(context.Background(), "/Volumes/main/default/files")
+	require.NoError(t, err)
+	assert.Equal(t, []string{`LIST '/Volumes/main/default/files'`}, d.queries)
+	assert.Equal(t, []FileInfo{
+		{Path: "/Volumes/main/default/files/data.csv", Name: "data.csv", Size: 8, ModTime: time.UnixMilli(1700000000000)},
+		{Path: "/Volumes/main/default/files/archive/", Name: "archive/", IsDir: true},
+	}, files)
+}
+
+func TestDelete(t *testing.T) {
+	client, d := newTestClient(t)
+	require.NoError(t, client.Delete(context.Background(), "/Volumes/main/default/files/data.csv"))
+	assert.Equal(t, []string{`REMOVE '/Volumes/main/default/files/data.csv'`}, d.queries)
+}